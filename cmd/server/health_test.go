@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"easylab/internal/server"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth_Head(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", healthHandler)
+	h := server.HeadAndOptions(mux, nil)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "OK", w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String(), "HEAD has no body")
+}
+
+func TestDiagnostics(t *testing.T) {
+	w := httptest.NewRecorder()
+	diagnosticsHandler("3.150.0")(w, httptest.NewRequest(http.MethodGet, "/api/admin/diagnostics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"pulumi_version": "3.150.0"}`, w.Body.String())
+}
@@ -5,10 +5,10 @@ import (
 	"context"
 	_ "easylab/internal/providers/workspace/kube" // register the kube workspace backend
 	"easylab/internal/server"
-	"easylab/utils"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -57,7 +57,7 @@ func loadEnvFile(envFile string) error {
 		// Parse KEY=VALUE format
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
-			log.Printf("Warning: Skipping invalid line %d in env file %s: %s", lineNum, envFile, line)
+			slog.Warn("Skipping an invalid line of the env file", "line_number", lineNum, "env_file", envFile, "line", line)
 			continue
 		}
 
@@ -73,13 +73,13 @@ func loadEnvFile(envFile string) error {
 		}
 
 		if key == "" {
-			log.Printf("Warning: Skipping line %d in env file %s: empty key", lineNum, envFile)
+			slog.Warn("Skipping a line of the env file with an empty key", "line_number", lineNum, "env_file", envFile)
 			continue
 		}
 
 		// Set the environment variable
 		if err := os.Setenv(key, value); err != nil {
-			log.Printf("Warning: Failed to set environment variable %s from line %d: %v", key, lineNum, err)
+			slog.Warn("Failed to set an environment variable from the env file", "key", key, "line_number", lineNum, "error", err)
 			continue
 		}
 		loadedCount++
@@ -89,57 +89,122 @@ func loadEnvFile(envFile string) error {
 		return fmt.Errorf("error reading env file %s: %w", envFile, err)
 	}
 
-	log.Printf("[STARTUP] Loaded %d environment variables from %s", loadedCount, envFile)
+	slog.Info("Loaded environment variables", "component", "startup", "count", loadedCount, "env_file", envFile)
+	return nil
+}
+
+// fatal logs msg and its attributes at the error level, then exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// fatalConfig reports every problem of the configuration, one per line, then
+// exits.
+func fatalConfig(err error) {
+	fmt.Fprintln(os.Stderr, "Invalid configuration:")
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintln(os.Stderr, "  "+line)
+	}
+	os.Exit(1)
+}
+
+// configureLogging installs the server's log handler as slog's default.
+func configureLogging(level, format string) error {
+	lvl, err := server.ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	h, err := server.NewLogHandler(os.Stderr, lvl, format)
+	if err != nil {
+		return err
+	}
+	// Also routes what other packages write with the log package, at info.
+	slog.SetDefault(slog.New(h))
 	return nil
 }
 
 func main() {
 	// Pulumi SDK v3.243+ calls slog.SetDefault(discardHandler{}) in its init(),
-	// which silently swallows all standard log.Printf output. Restore stderr.
-	log.SetOutput(os.Stderr)
+	// which silently swallows all log output. Restore stderr until the flags and
+	// env file are read.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
 
 	startTime := time.Now()
 
+	// Every setting of server.ServerConfig that has a flag; see -print-config.
+	server.RegisterConfigFlags(flag.CommandLine)
 	var (
-		port    = flag.String("port", "8081", "Port to listen on")
-		workDir = flag.String("work-dir", utils.DEFAULT_WORK_DIR, "Directory for job workspaces")
-		dataDir = flag.String("data-dir", utils.DEFAULT_DATA_DIR, "Directory for persisting job data")
-		envFile = flag.String("env-file", "", "Path to environment file to load at startup")
+		configFile  = flag.String("config", "", "YAML file of server settings, overridden by flags and environment variables")
+		printConfig = flag.Bool("print-config", false, "Print the effective configuration, secrets masked, and exit")
+		envFile     = flag.String("env-file", "", "Path to environment file to load at startup")
 	)
 	flag.Parse()
 
 	// Load environment variables from file if specified
 	if *envFile != "" {
 		if err := loadEnvFile(*envFile); err != nil {
-			log.Fatalf("Failed to load env file: %v", err)
+			fatal("Failed to load env file", "error", err)
 		}
 	}
-
-	// Get default values from environment variables if set, otherwise use hardcoded defaults
-	defaultWorkDir := os.Getenv("WORK_DIR")
-	if (*workDir == "" || *workDir == utils.DEFAULT_WORK_DIR) && defaultWorkDir != "" {
-		*workDir = defaultWorkDir
+	// After the env file: environment variables win over flags, which win over
+	// the config file.
+	cfg, err := server.LoadServerConfig(*configFile, flag.CommandLine, os.Getenv)
+	if err != nil {
+		fatalConfig(err)
 	}
-
-	defaultDataDir := os.Getenv("DATA_DIR")
-	if (*dataDir == "" || *dataDir == utils.DEFAULT_DATA_DIR) && defaultDataDir != "" {
-		*dataDir = defaultDataDir
+	if *printConfig {
+		if err := cfg.WriteYAML(os.Stdout); err != nil {
+			fatal("Failed to print the configuration", "error", err)
+		}
+		if err := cfg.Validate(); err != nil {
+			fatalConfig(err)
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		fatalConfig(err)
+	}
+	if err := configureLogging(cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		fatal("Invalid logging settings", "error", err)
 	}
+	if *configFile != "" {
+		slog.Info("Loaded configuration file", "component", "startup", "config", *configFile)
+	}
+
+	workDir, dataDir := cfg.Server.WorkDir, cfg.Server.DataDir
+	tlsCert, tlsKey := cfg.TLS.Cert, cfg.TLS.Key
 
 	appCtx, appCancel := context.WithCancel(context.Background())
 	defer appCancel()
 
-	log.Printf("[STARTUP] Starting application initialization...")
+	slog.Info("Starting application initialization", "component", "startup")
 
 	// Create work directory if it doesn't exist
 	dirStart := time.Now()
-	if err := os.MkdirAll(*workDir, 0755); err != nil {
-		log.Fatalf("Failed to create work directory: %v", err)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		fatal("Failed to create work directory", "error", err)
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		fatal("Failed to create data directory", "error", err)
 	}
-	if err := os.MkdirAll(*dataDir, 0755); err != nil {
-		log.Fatalf("Failed to create data directory: %v", err)
+	slog.Info("Directory creation", "component", "startup", "duration", time.Since(dirStart))
+
+	var certs *server.CertReloader
+	if cfg.TLS.Enabled() {
+		if cfg.TLS.SelfSigned {
+			if tlsCert, tlsKey, err = server.EnsureSelfSignedCert(dataDir); err != nil {
+				fatal("Failed to set up the self-signed certificate", "error", err)
+			}
+		}
+		if certs, err = server.NewCertReloader(tlsCert, tlsKey); err != nil {
+			fatal("Failed to load the TLS certificate", "error", err)
+		}
 	}
-	log.Printf("[STARTUP] Directory creation took %v", time.Since(dirStart))
 
 	// Initialize independent components in parallel
 	var (
@@ -158,7 +223,7 @@ func main() {
 	// Initialize jobManager
 	go func() {
 		defer wg.Done()
-		jobManager = server.NewJobManager(*dataDir)
+		jobManager = server.NewJobManager(dataDir)
 	}()
 
 	// Initialize credentialsManager
@@ -171,53 +236,128 @@ func main() {
 	go func() {
 		defer wg.Done()
 		var err error
-		authHandler, err = server.NewAuthHandler()
+		authHandler, err = server.NewAuthHandlerFromConfig(cfg.Auth)
 		if err != nil {
-			log.Fatalf("Failed to initialize auth handler: %v", err)
+			fatal("Failed to initialize auth handler", "error", err)
 		}
 	}()
 
 	// Wait for independent components
 	parallelStart := time.Now()
 	wg.Wait()
-	log.Printf("[STARTUP] Parallel component initialization took %v", time.Since(parallelStart))
+	slog.Info("Parallel component initialization", "component", "startup", "duration", time.Since(parallelStart))
+
+	if cfg.Server.JobIDs == server.JobIDsSequential {
+		if err := jobManager.UseSequentialIDs(); err != nil {
+			fatal("Failed to set up sequential job IDs", "error", err)
+		}
+	}
+
+	// Sessions are in-memory unless auth.session_store selects a persistent backend.
+	adminSessions, studentSessions, err := server.NewSessionStores(cfg.Auth.SessionStore, cfg.Auth.SessionRedisURL, dataDir)
+	if err != nil {
+		fatal("Failed to initialize session store", "error", err)
+	}
+	authHandler.SetSessionStores(adminSessions, studentSessions)
+	// A lab's student access code logs students into that lab only.
+	authHandler.SetLabAccessCodes(jobManager.LabForAccessCode)
+
+	// Admin and student routes can each be limited to a list of networks. The
+	// lists go outside the auth checks: a refused client never sees a login.
+	// Validate checked the lists.
+	adminIPs, studentIPs, _ := server.NewIPAllowLists(cfg.Access)
+	if adminIPs.Enabled() {
+		slog.Info("Admin routes restricted by an IP allow-list", "component", "startup", "setting", "access.admin_allowed_cidrs")
+	}
+	if studentIPs.Enabled() {
+		slog.Info("Student routes restricted by an IP allow-list", "component", "startup", "setting", "access.student_allowed_cidrs")
+	}
+	requireAdmin := func(next http.HandlerFunc) http.HandlerFunc {
+		return adminIPs.Require(authHandler.RequireAuth(next))
+	}
+	requireStudent := func(next http.HandlerFunc) http.HandlerFunc {
+		return studentIPs.Require(authHandler.RequireStudentAuth(next))
+	}
+
+	// Validate checked the origins.
+	cors, _ := server.NewCORSPolicy(cfg.CORS.AllowedOrigins)
+
+	if err := credentialsManager.SetDefaultOVHEndpoint(cfg.Server.OVHEndpoint); err != nil {
+		fatal("Invalid default OVH endpoint", "error", err)
+	}
 
 	// Initialize OVH options manager (depends on credentialsManager)
 	ovhOptionsStart := time.Now()
-	ovhOptionsManager = server.NewOVHOptionsManager(*dataDir, credentialsManager)
-	log.Printf("[STARTUP] OVHOptionsManager initialization took %v", time.Since(ovhOptionsStart))
+	ovhOptionsManager = server.NewOVHOptionsManager(dataDir, credentialsManager)
+	slog.Info("OVHOptionsManager initialization", "component", "startup", "duration", time.Since(ovhOptionsStart))
 
 	// Initialize Azure options manager (depends on credentialsManager)
 	azureOptionsStart := time.Now()
-	azureOptionsManager = server.NewAzureOptionsManager(*dataDir, credentialsManager)
-	log.Printf("[STARTUP] AzureOptionsManager initialization took %v", time.Since(azureOptionsStart))
+	azureOptionsManager = server.NewAzureOptionsManager(dataDir, credentialsManager)
+	slog.Info("AzureOptionsManager initialization", "component", "startup", "duration", time.Since(azureOptionsStart))
 
 	// Initialize pulumiExec (depends on jobManager)
 	pulumiStart := time.Now()
-	pulumiExec = server.NewPulumiExecutor(jobManager, *workDir)
-	log.Printf("[STARTUP] PulumiExecutor initialization took %v", time.Since(pulumiStart))
+	pulumiExec = server.NewPulumiExecutor(jobManager, workDir)
+	pulumiExec.SetDeployConfig(cfg.Deploy)
+	slog.Info("PulumiExecutor initialization", "component", "startup", "duration", time.Since(pulumiStart))
+
+	// Fail fast on a missing or outdated Pulumi CLI: otherwise it only shows up as
+	// an Automation API error when the first lab is deployed.
+	pulumiCLI, pulumiErr := server.DetectPulumiCLI(appCtx)
+	if pulumiErr != nil {
+		if !cfg.Server.LenientPulumiCheck {
+			fatal("Pulumi CLI check failed", "component", "startup", "error", pulumiErr)
+		}
+		slog.Warn("Pulumi CLI check failed, continuing because of -lenient-pulumi-check", "component", "startup", "error", pulumiErr)
+	} else {
+		slog.Info("Using Pulumi CLI", "component", "startup", "version", pulumiCLI.Version, "path", pulumiCLI.Path)
+	}
 
 	// Check and install required Pulumi plugins at startup.
 	go func() {
 		pluginStart := time.Now()
 		pulumiExec.CheckAndInstallPlugins()
-		log.Printf("[STARTUP] Pulumi plugin check completed in %v", time.Since(pluginStart))
+		slog.Info("Pulumi plugin check completed", "component", "startup", "duration", time.Since(pluginStart))
 	}()
 
 	// Initialize feedback store (persists alongside job data)
-	feedbackStore, err := server.NewFeedbackStore(filepath.Join(*dataDir, "feedback"))
+	feedbackStore, err := server.NewFeedbackStore(filepath.Join(dataDir, "feedback"))
 	if err != nil {
-		log.Fatalf("Failed to initialize feedback store: %v", err)
+		fatal("Failed to initialize feedback store", "error", err)
 	}
 
 	// Initialize handler (depends on all components)
 	handlerStart := time.Now()
 	handler = server.NewHandler(jobManager, pulumiExec, credentialsManager, ovhOptionsManager, azureOptionsManager, feedbackStore)
+	if cfg.Server.WebDir != "" {
+		webFS := os.DirFS(cfg.Server.WebDir)
+		if err := handler.SetWebFS(webFS); err != nil {
+			fatal("Failed to load the web UI", "web_dir", cfg.Server.WebDir, "error", err)
+		}
+		if err := authHandler.SetWebFS(webFS); err != nil {
+			fatal("Failed to load the web UI", "web_dir", cfg.Server.WebDir, "error", err)
+		}
+		slog.Info("Serving the web UI from a directory instead of the embedded copy", "component", "startup", "web_dir", cfg.Server.WebDir)
+	}
 	handler.SetAzureADConfigurer(authHandler.ConfigureAzureAD)
 	handler.SetClassicLoginConfigurer(authHandler.SetClassicLoginDisabled)
 	handler.SetAdminGroupIDConfigurer(authHandler.SetAdminGroupID)
 	handler.SetClassicAdminLoginConfigurer(authHandler.SetClassicAdminLoginDisabled)
-	log.Printf("[STARTUP] Handler initialization took %v", time.Since(handlerStart))
+	handler.SetMaintenanceMode(server.NewMaintenanceMode(dataDir))
+	handler.SetBroadcastBoard(server.NewBroadcastBoard(dataDir))
+	// Validate checked the prices.
+	flavorPrices, _ := server.ParseFlavorPrices(cfg.Costs.FlavorPrices, cfg.Costs.Currency)
+	handler.SetFlavorPrices(flavorPrices)
+	server.SetNodeCountLimits(cfg.NodePools)
+	handler.SetCleanupConfig(cfg.Cleanup)
+	handler.SetMetricsToken(cfg.Metrics.Token)
+	if secret := cfg.Auth.ShareLinkSecret; secret != "" {
+		handler.SetShareLinkSecret(secret)
+	} else {
+		slog.Warn("Share links stop working when the server restarts", "component", "startup", "unset", server.EnvShareLinkSecret)
+	}
+	slog.Info("Handler initialization", "component", "startup", "duration", time.Since(handlerStart))
 
 	// Apply persisted Azure AD config (overrides env vars if set via UI)
 	if azureAD := azureOptionsManager.GetAzureADConfig(); azureAD.ClientID != "" && azureAD.ClientSecret != "" && azureAD.TenantID != "" {
@@ -227,69 +367,113 @@ func main() {
 			authHandler.SetAdminGroupID(azureAD.AdminGroupID)
 		}
 		authHandler.SetClassicAdminLoginDisabled(azureAD.DisableClassicAdminLogin)
-		log.Printf("[STARTUP] Azure AD config loaded from persisted storage")
+		slog.Info("Azure AD config loaded from persisted storage", "component", "startup")
 	}
 
+	// Lab status changes are POSTed to the webhooks; a lab may set its own URLs
+	// even when the server has none.
+	webhookSecret := cfg.Notifications.WebhookSecret
+	if webhookSecret == "" {
+		slog.Warn("Webhook payloads are not signed", "component", "startup", "unset", server.EnvWebhookSecret)
+	}
+	stopWebhooks := server.NewWebhookNotifier(jobManager, cfg.Notifications.WebhookURLs, webhookSecret, cfg.Server.PublicURL).Start()
+	defer stopWebhooks()
+
+	// Slack and Discord are told when a lab is ready, fails or is full.
+	notifications, err := server.NewNotificationsFromConfig(cfg.Server.PublicURL, cfg.Notifications)
+	if err != nil {
+		fatal("Invalid notification settings", "error", err)
+	}
+	if notifications.Enabled() {
+		slog.Info("Notifications enabled", "component", "startup", "notifiers", strings.Join(notifications.Names(), ", "))
+	}
+	handler.SetNotifications(notifications)
+	stopNotifications := notifications.Start(jobManager)
+	defer stopNotifications()
+
 	go handler.StartWorkspaceCleanup(appCtx)
+	// Keep the student catalog's view of which lab clusters answer fresh.
+	go handler.StartLabHealthChecks(appCtx)
+	// Warn when the pinned Helm charts fall behind upstream.
+	go handler.WarnOutdatedCharts()
 
 	// Setup routes
 	mux := http.NewServeMux()
+	lifecycle := server.NewLifecycle()
 
 	// Public routes (no auth required)
-	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/login", adminIPs.Require(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			authHandler.HandleLogin(w, r)
 		} else {
 			authHandler.ServeLogin(w, r)
 		}
-	})
+	}))
 	mux.HandleFunc("/logout", authHandler.HandleLogout)
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	mux.HandleFunc("/health", healthHandler)
+	// Kubernetes probes: /readyz fails while jobs load and once a shutdown began.
+	mux.HandleFunc("/livez", lifecycle.Livez)
+	mux.HandleFunc("/readyz", lifecycle.Readyz)
+	mux.Handle("/health/ready", server.NewReadinessChecker(dataDir, workDir, uint64(cfg.Server.MinFreeDiskMB)<<20, pulumiCLI.Version, credentialsManager, jobManager))
 	mux.HandleFunc("/static/", handler.ServeStatic) // Static files don't need auth
+	// Prometheus scrapes with the METRICS_TOKEN bearer token, not a session.
+	metricsHandler := adminIPs.Require(handler.Metrics)
+	if cfg.Server.MetricsPort == "" {
+		mux.HandleFunc("/metrics", metricsHandler)
+	}
 
 	// Student routes (public login, protected dashboard)
-	mux.HandleFunc("/student/login", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/student/login", studentIPs.Require(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			authHandler.HandleStudentLogin(w, r)
 		} else {
 			authHandler.ServeStudentLogin(w, r)
 		}
-	})
-	mux.HandleFunc("/student/auth/azure/login", authHandler.HandleAzureADLogin)
-	mux.HandleFunc("/student/auth/azure/callback", authHandler.HandleAzureADCallback)
+	}))
+	mux.HandleFunc("/student/auth/azure/login", studentIPs.Require(authHandler.HandleAzureADLogin))
+	mux.HandleFunc("/student/auth/azure/callback", studentIPs.Require(authHandler.HandleAzureADCallback))
 
 	// Admin Azure AD login routes (public — redirect to /admin on success)
-	mux.HandleFunc("/admin/auth/azure/login", authHandler.HandleAdminAzureADLogin)
-	mux.HandleFunc("/admin/auth/azure/callback", authHandler.HandleAdminAzureADCallback)
+	mux.HandleFunc("/admin/auth/azure/login", adminIPs.Require(authHandler.HandleAdminAzureADLogin))
+	mux.HandleFunc("/admin/auth/azure/callback", adminIPs.Require(authHandler.HandleAdminAzureADCallback))
 	mux.HandleFunc("/student/logout", authHandler.HandleStudentLogout)
-	mux.HandleFunc("/student/dashboard", authHandler.RequireStudentAuth(handler.ServeStudentDashboard))
-	mux.HandleFunc("/student/workspaces", authHandler.RequireStudentAuth(handler.ServeStudentWorkspaces))
-	mux.HandleFunc("/student/feedback", authHandler.RequireStudentAuth(handler.ServeFeedback))
-	mux.HandleFunc("/api/student/labs", authHandler.RequireStudentAuth(handler.ListLabs))
-	mux.HandleFunc("/api/student/labs/templates", authHandler.RequireStudentAuth(handler.ListLabTemplates))
-	mux.HandleFunc("/api/student/workspace/request", authHandler.RequireStudentAuth(handler.RequestWorkspace))
-	mux.HandleFunc("/api/student/workspace/status", authHandler.RequireStudentAuth(handler.WorkspaceStatus))
-	mux.HandleFunc("/api/student/workspace/open", authHandler.RequireStudentAuth(handler.OpenWorkspace))
-	mux.HandleFunc("/api/student/feedback", authHandler.RequireStudentAuth(handler.SubmitFeedback))
+	// Share links stand in for the student password (see server.Handler.Enroll).
+	mux.HandleFunc("/enroll", studentIPs.Require(handler.Enroll))
+	mux.HandleFunc("/student/dashboard", requireStudent(handler.ServeStudentDashboard))
+	mux.HandleFunc("/student/workspaces", requireStudent(handler.ServeStudentWorkspaces))
+	mux.HandleFunc("/student/feedback", requireStudent(handler.ServeFeedback))
+	mux.HandleFunc("/api/student/dashboard", requireStudent(handler.GetStudentDashboard))
+	mux.HandleFunc("/api/student/labs", requireStudent(handler.ListLabs))
+	mux.HandleFunc("/api/student/labs/templates", requireStudent(handler.ListLabTemplates))
+	mux.HandleFunc("/api/student/workspace/request", requireStudent(handler.RequestWorkspace))
+	mux.HandleFunc("/api/student/workspace/status", requireStudent(handler.WorkspaceStatus))
+	mux.HandleFunc("/api/student/workspace/open", requireStudent(handler.OpenWorkspace))
+	mux.HandleFunc("/api/student/workspace/resend", requireStudent(handler.ResendWorkspaceInfo))
+	mux.HandleFunc("/api/student/workspace/delete", requireStudent(handler.DeleteOwnWorkspace))
+	mux.HandleFunc("/api/student/workspace/reset-password", requireStudent(handler.ResetWorkspacePassword))
+	mux.HandleFunc("/api/student/feedback", requireStudent(handler.SubmitFeedback))
+
+	// JSON API, described by /api/v1/openapi.json
+	handler.RegisterAPIv1(mux, requireAdmin, requireStudent)
 
 	// Public homepage (no auth required)
 	mux.HandleFunc("/", handler.ServeUI)
 
 	// Protected routes (auth required)
-	mux.HandleFunc("/admin", authHandler.RequireAuth(handler.ServeAdminUI))
-	mux.HandleFunc("/admin/feedback", authHandler.RequireAuth(handler.ServeAdminLabFeedback))
-	mux.HandleFunc("/admin/stats", authHandler.RequireAuth(handler.ServeAdminStats))
-	mux.HandleFunc("/api/admin/stats", authHandler.RequireAuth(handler.GetProjectStats))
-	mux.HandleFunc("/labs", authHandler.RequireAuth(handler.ServeLabsList))
+	mux.HandleFunc("/admin", requireAdmin(handler.ServeAdminUI))
+	mux.HandleFunc("/admin/feedback", requireAdmin(handler.ServeAdminLabFeedback))
+	mux.HandleFunc("/admin/stats", requireAdmin(handler.ServeAdminStats))
+	mux.HandleFunc("/api/admin/stats", requireAdmin(handler.GetProjectStats))
+	mux.HandleFunc("/api/admin/diagnostics", requireAdmin(diagnosticsHandler(pulumiCLI.Version)))
+	mux.HandleFunc("/api/maintenance", requireAdmin(handler.Maintenance))
+	mux.HandleFunc("/api/broadcast", requireAdmin(handler.Broadcast))
+	mux.HandleFunc("/labs", requireAdmin(handler.ServeLabsList))
 	// Backward compatibility route
-	mux.HandleFunc("/jobs", authHandler.RequireAuth(handler.ServeLabsList))
+	mux.HandleFunc("/jobs", requireAdmin(handler.ServeLabsList))
 
 	// New generic credentials routes
-	mux.HandleFunc("/credentials", authHandler.RequireAuth(handler.ServeCredentials))
-	mux.HandleFunc("/api/credentials", authHandler.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/credentials", requireAdmin(handler.ServeCredentials))
+	mux.HandleFunc("/api/credentials", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			handler.SetCredentials(w, r)
 		} else if r.Method == http.MethodGet {
@@ -298,11 +482,11 @@ func main() {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))
-	mux.HandleFunc("/api/providers", authHandler.RequireAuth(handler.ListProviders))
+	mux.HandleFunc("/api/providers", requireAdmin(handler.ListProviders))
 
 	// Backward compatibility routes for OVH-specific endpoints
-	mux.HandleFunc("/ovh-credentials", authHandler.RequireAuth(handler.ServeOVHCredentials))
-	mux.HandleFunc("/api/ovh-credentials", authHandler.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/ovh-credentials", requireAdmin(handler.ServeOVHCredentials))
+	mux.HandleFunc("/api/ovh-credentials", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			handler.SetOVHCredentials(w, r)
 		} else if r.Method == http.MethodGet {
@@ -311,78 +495,162 @@ func main() {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))
-	mux.HandleFunc("/api/ovh/regions", authHandler.RequireAuth(handler.GetOVHRegions))
-	mux.HandleFunc("/api/ovh/flavors", authHandler.RequireAuth(handler.GetOVHFlavors))
-	mux.HandleFunc("/admin/ovh-options", authHandler.RequireAuth(handler.ServeOVHOptions))
-	mux.HandleFunc("/api/ovh-options", authHandler.RequireAuth(handler.SaveOVHOptions))
-	mux.HandleFunc("/api/ovh-options/refresh", authHandler.RequireAuth(handler.RefreshOVHOptions))
+	mux.HandleFunc("/api/ovh/regions", requireAdmin(handler.GetOVHRegions))
+	mux.HandleFunc("/api/ovh/flavors", requireAdmin(handler.GetOVHFlavors))
+	mux.HandleFunc("/api/ovh/kube-versions", requireAdmin(handler.GetOVHKubeVersions))
+	mux.HandleFunc("/api/ovh/gateway-models", requireAdmin(handler.GetOVHGatewayModels))
+	mux.HandleFunc("/admin/ovh-options", requireAdmin(handler.ServeOVHOptions))
+	mux.HandleFunc("/api/ovh-options", requireAdmin(handler.SaveOVHOptions))
+	mux.HandleFunc("/api/ovh-options/refresh", requireAdmin(handler.RefreshOVHOptions))
 
 	// Azure-specific routes
-	mux.HandleFunc("/azure-credentials", authHandler.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/azure-credentials", requireAdmin(func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/credentials?provider=azure", http.StatusMovedPermanently)
 	}))
-	mux.HandleFunc("/admin/azure-options", authHandler.RequireAuth(handler.ServeAzureOptions))
-	mux.HandleFunc("/admin/azure-provider", authHandler.RequireAuth(handler.ServeAzureProvider))
-	mux.HandleFunc("/admin/azure-ad", authHandler.RequireAuth(handler.ServeAzureAD))
-	mux.HandleFunc("/api/azure/locations", authHandler.RequireAuth(handler.GetAzureLocations))
-	mux.HandleFunc("/api/azure/vm-sizes", authHandler.RequireAuth(handler.GetAzureVMSizes))
-	mux.HandleFunc("/api/azure-options/region-vm-sizes", authHandler.RequireAuth(handler.GetAzureOptionsRegionVMSizeHTML))
-	mux.HandleFunc("/api/azure-options", authHandler.RequireAuth(handler.SaveAzureOptions))
-	mux.HandleFunc("/api/azure-options/refresh", authHandler.RequireAuth(handler.RefreshAzureOptions))
-	mux.HandleFunc("/api/azure-ad-config", authHandler.RequireAuth(handler.SaveAzureADConfig))
-	mux.HandleFunc("/api/templates/detect-variables", authHandler.RequireAuth(handler.DetectTemplateVariables))
-	mux.HandleFunc("/api/templates/detect-devcontainer", authHandler.RequireAuth(handler.DetectDevcontainer))
-	mux.HandleFunc("/api/labs", authHandler.RequireAuth(handler.CreateLab))
-	mux.HandleFunc("/api/labs/templates/yaml", authHandler.RequireAuth(handler.ServeWorkspaceTemplatesYAML))
-	mux.HandleFunc("/api/labs/templates/yaml/validate", authHandler.RequireAuth(handler.ValidateWorkspaceTemplatesYAML))
-	mux.HandleFunc("/api/labs/dry-run", authHandler.RequireAuth(handler.DryRunLab))
-	mux.HandleFunc("/api/labs/launch", authHandler.RequireAuth(handler.LaunchLab))
-	mux.HandleFunc("/api/labs/recreate", authHandler.RequireAuth(handler.RecreateLab))
-	mux.HandleFunc("/api/stacks/destroy", authHandler.RequireAuth(handler.DestroyStack))
+	mux.HandleFunc("/admin/azure-options", requireAdmin(handler.ServeAzureOptions))
+	mux.HandleFunc("/admin/azure-provider", requireAdmin(handler.ServeAzureProvider))
+	mux.HandleFunc("/admin/azure-ad", requireAdmin(handler.ServeAzureAD))
+	mux.HandleFunc("/api/azure/locations", requireAdmin(handler.GetAzureLocations))
+	mux.HandleFunc("/api/azure/vm-sizes", requireAdmin(handler.GetAzureVMSizes))
+	mux.HandleFunc("/api/azure-options/region-vm-sizes", requireAdmin(handler.GetAzureOptionsRegionVMSizeHTML))
+	mux.HandleFunc("/api/azure-options", requireAdmin(handler.SaveAzureOptions))
+	mux.HandleFunc("/api/azure-options/refresh", requireAdmin(handler.RefreshAzureOptions))
+	mux.HandleFunc("/api/azure-ad-config", requireAdmin(handler.SaveAzureADConfig))
+	mux.HandleFunc("/api/templates/detect-variables", requireAdmin(handler.DetectTemplateVariables))
+	mux.HandleFunc("/api/templates/detect-devcontainer", requireAdmin(handler.DetectDevcontainer))
+	mux.HandleFunc("/api/labs", requireAdmin(handler.CreateLab))
+	mux.HandleFunc("/api/labs/templates/yaml", requireAdmin(handler.ServeWorkspaceTemplatesYAML))
+	mux.HandleFunc("/api/labs/templates/yaml/validate", requireAdmin(handler.ValidateWorkspaceTemplatesYAML))
+	mux.HandleFunc("/api/labs/dry-run", requireAdmin(handler.DryRunLab))
+	mux.HandleFunc("/api/labs/validate", requireAdmin(handler.ValidateLab))
+	mux.HandleFunc("/api/labs/launch", requireAdmin(handler.LaunchLab))
+	mux.HandleFunc("/api/labs/recreate", requireAdmin(handler.RecreateLab))
+	mux.HandleFunc("/api/stacks/destroy", requireAdmin(handler.DestroyStack))
 	routeLabRequest := labRequestRouter(handler)
-	mux.HandleFunc("/api/labs/", authHandler.RequireAuth(routeLabRequest))
+	mux.HandleFunc("/api/labs/", requireAdmin(routeLabRequest))
 	// Backward compatibility route
-	mux.HandleFunc("/api/jobs/", authHandler.RequireAuth(routeLabRequest))
+	mux.HandleFunc("/api/jobs/", requireAdmin(routeLabRequest))
 	// Workspace management routes
-	mux.HandleFunc("/labs/", authHandler.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+	labPages := requireAdmin(func(w http.ResponseWriter, r *http.Request) {
 		// Check if this is a workspace page request
 		if strings.HasSuffix(r.URL.Path, "/workspaces") {
 			handler.ServeLabWorkspaces(w, r)
 			return
 		}
 		http.NotFound(w, r)
-	}))
+	})
+	// The workspace proxy is used by students, so it sits outside the admin check.
+	workspaceProxy := requireStudent(handler.ProxyWorkspace)
+	mux.HandleFunc("/labs/", func(w http.ResponseWriter, r *http.Request) {
+		if server.IsWorkspaceProxyPath(r.URL.Path) {
+			workspaceProxy(w, r)
+			return
+		}
+		labPages(w, r)
+	})
+
+	// Tell apart the clients with a valid session, so that a classroom
+	// behind one address does not share a limit.
+	rateLimiter := server.NewRateLimiter(cfg.RateLimits)
+	rateLimiter.SetAuthHandler(authHandler)
+	// Validate checked the proxies.
+	_ = rateLimiter.SetTrustedProxies(cfg.Access.TrustedProxyCIDRs)
 
 	// Configure server with timeouts
-	addr := fmt.Sprintf(":%s", *port)
+	addr := fmt.Sprintf(":%s", cfg.Server.Port)
 	srv := &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      server.RequestLogging(server.HeadAndOptions(server.RateLimit(server.InstrumentHTTP(mux), rateLimiter), cors)),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 5 * time.Minute, // long enough for log-streaming and kubeconfig responses
 		IdleTimeout:  60 * time.Second,
 	}
+	scheme := "http"
+	if certs != nil {
+		srv.TLSConfig = certs.TLSConfig()
+		scheme = "https"
+	}
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("[STARTUP] Total initialization time: %v", time.Since(startTime))
-		log.Printf("Starting server on http://localhost%s", addr)
-		log.Printf("Work directory: %s", *workDir)
-		log.Printf("Data directory: %s", *dataDir)
-		log.Printf("Set %s environment variable to configure admin password", server.EnvAdminPassword)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+		slog.Info("Total initialization time", "component", "startup", "duration", time.Since(startTime))
+		slog.Info("Starting server", "url", scheme+"://localhost"+addr)
+		slog.Info("Work directory", "work_dir", workDir)
+		slog.Info("Data directory", "data_dir", dataDir)
+		slog.Info("Set the admin password environment variable to configure the admin password", "env", server.EnvAdminPassword)
+		var err error
+		if certs != nil {
+			// The certificate comes from TLSConfig, so no files are passed.
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			fatal("Server failed", "error", err)
 		}
 	}()
 
-	// Load persisted jobs asynchronously after server starts (non-blocking)
-	if *dataDir != "" {
+	var redirectSrv *http.Server
+	if cfg.TLS.HTTPRedirectPort != "" {
+		redirectSrv = &http.Server{
+			Addr:         fmt.Sprintf(":%s", cfg.TLS.HTTPRedirectPort),
+			Handler:      server.RedirectToHTTPS(cfg.Server.Port),
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		}
 		go func() {
-			log.Printf("Loading persisted jobs from %s...", *dataDir)
+			slog.Info("Redirecting HTTP to HTTPS", "port", cfg.TLS.HTTPRedirectPort)
+			if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("HTTP redirect server failed", "error", err)
+			}
+		}()
+	}
+
+	// SIGHUP reloads the certificate, e.g. after a renewal.
+	if certs != nil {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := certs.Reload(); err != nil {
+					slog.Error("Failed to reload the TLS certificate, keeping the current one", "error", err)
+					continue
+				}
+				slog.Info("Reloaded the TLS certificate", "cert", tlsCert)
+			}
+		}()
+	}
+
+	var metricsSrv *http.Server
+	if cfg.Server.MetricsPort != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.HandleFunc("/metrics", metricsHandler)
+		metricsSrv = &http.Server{
+			Addr:         fmt.Sprintf(":%s", cfg.Server.MetricsPort),
+			Handler:      metricsMux,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+		go func() {
+			slog.Info("Serving /metrics", "port", cfg.Server.MetricsPort)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Metrics server failed", "error", err)
+			}
+		}()
+	}
+
+	// Load persisted jobs asynchronously after server starts (non-blocking).
+	// The server takes new work once they are in.
+	if dataDir != "" {
+		go func() {
+			slog.Info("Loading persisted jobs", "data_dir", dataDir)
 			if err := jobManager.LoadJobs(); err != nil {
-				log.Printf("Warning: failed to load persisted jobs: %v", err)
+				slog.Warn("Failed to load persisted jobs", "error", err)
 			}
+			lifecycle.Serving()
 		}()
+	} else {
+		lifecycle.Serving()
 	}
 
 	// Pre-populate OVH options cache asynchronously if credentials are available
@@ -390,9 +658,9 @@ func main() {
 		go func() {
 			refreshStart := time.Now()
 			if err := ovhOptionsManager.RefreshFromAPI(); err != nil {
-				log.Printf("[STARTUP] Warning: OVH options cache refresh failed: %v", err)
+				slog.Warn("OVH options cache refresh failed", "component", "startup", "error", err)
 			} else {
-				log.Printf("[STARTUP] OVH options cache refresh completed in %v", time.Since(refreshStart))
+				slog.Info("OVH options cache refresh completed", "component", "startup", "duration", time.Since(refreshStart))
 			}
 		}()
 	}
@@ -402,9 +670,9 @@ func main() {
 		go func() {
 			refreshStart := time.Now()
 			if err := azureOptionsManager.RefreshFromAPI(); err != nil {
-				log.Printf("[STARTUP] Warning: Azure options cache refresh failed: %v", err)
+				slog.Warn("Azure options cache refresh failed", "component", "startup", "error", err)
 			} else {
-				log.Printf("[STARTUP] Azure options cache refresh completed in %v", time.Since(refreshStart))
+				slog.Info("Azure options cache refresh completed", "component", "startup", "duration", time.Since(refreshStart))
 			}
 		}()
 	}
@@ -413,17 +681,62 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	slog.Info("Shutting down server")
+
+	// Fail /readyz first and keep serving for a while, so that load balancers
+	// stop sending requests here before the server stops listening.
+	lifecycle.StartDraining()
+	if delay := cfg.Shutdown.ReadinessDelayDuration(); delay > 0 {
+		slog.Info("Waiting for load balancers to stop sending requests", "readiness_delay", delay)
+		time.Sleep(delay)
+	}
 	appCancel()
-	log.Println("Shutting down server...")
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		// Connections still open are cut, but the deployments are still drained
+		// and the jobs saved below.
+		slog.Error("Server forced to shutdown", "error", err)
+	}
+	if redirectSrv != nil {
+		redirectSrv.Shutdown(ctx)
+	}
+	// No new deployment can start now: let the running ones finish, or
+	// interrupt them cleanly, before exiting. A separate metrics port keeps
+	// answering meanwhile, so the drain shows up in easylab_server_state.
+	pulumiExec.Drain(cfg.Shutdown.DrainTimeoutDuration())
+	// Let the jobs that just finished reach the disk.
+	jobManager.Close()
+	if metricsSrv != nil {
+		metricsCtx, metricsCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		metricsSrv.Shutdown(metricsCtx)
+		metricsCancel()
 	}
 
-	log.Println("Server exited")
+	slog.Info("Server exited")
+}
+
+// healthHandler serves /health, a liveness check: it only says the process
+// answers, see /health/ready for whether it can deploy labs and /readyz for
+// whether it takes new work.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// diagnosticsHandler serves /api/admin/diagnostics, the server's setup as
+// detected at startup. It sits behind the admin login, since a tool version
+// tells an attacker which flaws to try. An empty pulumi_version means no
+// usable CLI was found (lenient mode only).
+func diagnosticsHandler(pulumiVersion string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"pulumi_version": pulumiVersion,
+		})
+	}
 }
 
 // labRoute names one of the endpoints under /api/labs/{id}/... (and the
@@ -452,12 +765,31 @@ const (
 	routeCoderCredentials
 	routeKubeconfig
 	routeRecreateCredentials
+	routeJobHistory
+	routeDownloadLogs
+	routeSearchLogs
+	routeCancelDryRun
+	routeScaleNodePool
+	routeRotateKubeconfig
+	routeProtectLab
+	routeJobRoster
+	routeMarkTemplate
+	routeLabFromTemplate
+	routeRepinTemplates
+	routeShareLink
+	routeProvisionWorkspaces
 )
 
 // resolveLabRoute picks the endpoint for a request. The case order is the
 // contract; see labRoute.
 func resolveLabRoute(path, method, format string) labRoute {
 	switch {
+	// Matched first: the template ID ends the path, so it could end like any
+	// route below.
+	case strings.HasPrefix(path, "/api/labs/from-template/") && method == http.MethodPost:
+		return routeLabFromTemplate
+	case strings.HasSuffix(path, "/workspaces/provision") && method == http.MethodPost:
+		return routeProvisionWorkspaces
 	case strings.Contains(path, "/workspaces") && !strings.Contains(path, "/delete") && method == http.MethodGet:
 		return routeListWorkspaces
 	case strings.Contains(path, "/workspaces/") && strings.Contains(path, "delete") && method == http.MethodPost:
@@ -487,6 +819,28 @@ func resolveLabRoute(path, method, format string) labRoute {
 		return routeCoderCredentials
 	case strings.HasSuffix(path, "/kubeconfig"):
 		return routeKubeconfig
+	case strings.HasSuffix(path, "/history") && method == http.MethodGet:
+		return routeJobHistory
+	case strings.HasSuffix(path, "/logs/download") && method == http.MethodGet:
+		return routeDownloadLogs
+	case strings.HasSuffix(path, "/logs/search") && method == http.MethodGet:
+		return routeSearchLogs
+	case strings.HasSuffix(path, "/cancel") && method == http.MethodPost:
+		return routeCancelDryRun
+	case strings.HasSuffix(path, "/nodepool/scale") && method == http.MethodPost:
+		return routeScaleNodePool
+	case strings.HasSuffix(path, "/kubeconfig/rotate") && method == http.MethodPost:
+		return routeRotateKubeconfig
+	case strings.HasSuffix(path, "/protect") && method == http.MethodPatch:
+		return routeProtectLab
+	case strings.HasSuffix(path, "/roster") && method == http.MethodGet:
+		return routeJobRoster
+	case strings.HasSuffix(path, "/template") && method == http.MethodPatch:
+		return routeMarkTemplate
+	case strings.HasSuffix(path, "/pin") && method == http.MethodPost:
+		return routeRepinTemplates
+	case strings.HasSuffix(path, "/share-link") && method == http.MethodPost:
+		return routeShareLink
 	case format == "json":
 		return routeJobStatusJSON
 	default:
@@ -523,6 +877,32 @@ func labRequestRouter(h *server.Handler) http.HandlerFunc {
 			h.DownloadKubeconfig(w, r)
 		case routeJobStatusJSON:
 			h.GetJobStatusJSON(w, r)
+		case routeJobHistory:
+			h.GetJobHistory(w, r)
+		case routeDownloadLogs:
+			h.DownloadJobLogs(w, r)
+		case routeSearchLogs:
+			h.SearchJobLogs(w, r)
+		case routeCancelDryRun:
+			h.CancelDryRun(w, r)
+		case routeScaleNodePool:
+			h.ScaleNodePool(w, r)
+		case routeRotateKubeconfig:
+			h.RotateKubeconfig(w, r)
+		case routeProtectLab:
+			h.ProtectLab(w, r)
+		case routeJobRoster:
+			h.GetJobRoster(w, r)
+		case routeMarkTemplate:
+			h.MarkTemplate(w, r)
+		case routeLabFromTemplate:
+			h.CreateLabFromTemplate(w, r)
+		case routeRepinTemplates:
+			h.RepinTemplates(w, r)
+		case routeShareLink:
+			h.CreateShareLink(w, r)
+		case routeProvisionWorkspaces:
+			h.ProvisionWorkspaces(w, r)
 		default:
 			h.GetJobStatus(w, r)
 		}
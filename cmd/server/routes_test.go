@@ -83,6 +83,21 @@ func TestResolveLabRoute(t *testing.T) {
 			want:   routeCoderCredentials,
 		},
 		{name: "kubeconfig", path: "/api/labs/job-1/kubeconfig", method: http.MethodGet, want: routeKubeconfig},
+		{name: "status history", path: "/api/labs/job-1/history", method: http.MethodGet, want: routeJobHistory},
+		{name: "log download", path: "/api/jobs/job-1/logs/download", method: http.MethodGet, want: routeDownloadLogs},
+		{name: "log search", path: "/api/labs/job-1/logs/search", method: http.MethodGet, want: routeSearchLogs},
+		{name: "cancel dry run", path: "/api/labs/job-1/cancel", method: http.MethodPost, want: routeCancelDryRun},
+		{name: "scale node pool", path: "/api/jobs/job-1/nodepool/scale", method: http.MethodPost, want: routeScaleNodePool},
+		{name: "rotate kubeconfig", path: "/api/jobs/job-1/kubeconfig/rotate", method: http.MethodPost, want: routeRotateKubeconfig},
+		{name: "protect lab", path: "/api/jobs/job-1/protect", method: http.MethodPatch, want: routeProtectLab},
+		{name: "roster", path: "/api/jobs/job-1/roster", method: http.MethodGet, want: routeJobRoster},
+		{name: "mark template", path: "/api/labs/job-1/template", method: http.MethodPatch, want: routeMarkTemplate},
+		{name: "repin templates", path: "/api/labs/job-1/pin", method: http.MethodPost, want: routeRepinTemplates},
+		{name: "share link", path: "/api/labs/job-1/share-link", method: http.MethodPost, want: routeShareLink},
+		{name: "provision workspaces", path: "/api/labs/job-1/workspaces/provision", method: http.MethodPost, want: routeProvisionWorkspaces},
+		{name: "share link needs POST", path: "/api/labs/job-1/share-link", method: http.MethodGet, want: routeJobStatus},
+		{name: "lab from template", path: "/api/labs/from-template/job-1", method: http.MethodPost, want: routeLabFromTemplate},
+		{name: "lab from template named like a route", path: "/api/labs/from-template/retry", method: http.MethodPost, want: routeLabFromTemplate},
 
 		// Fallback.
 		{name: "job status", path: "/api/labs/job-1", method: http.MethodGet, want: routeJobStatus},
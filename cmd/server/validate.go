@@ -0,0 +1,54 @@
+package main
+
+import (
+	"easylab/internal/server"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runValidate implements "validate [file]": it validates a JSON or YAML lab
+// config, read from file or from stdin when the file is "-" or missing, and
+// prints the report as JSON. It returns the exit code: 0 when the config is
+// valid, 1 when it is not, 2 when it cannot be read.
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: easylab-server validate [file|-]")
+		fmt.Fprintln(stderr, "Validates a JSON or YAML lab config without creating a lab.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var data []byte
+	var err error
+	if path := fs.Arg(0); path == "" || path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to read the config: %v\n", err)
+		return 2
+	}
+
+	report := server.ValidateLabConfig(data)
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(stderr, "Failed to write the report: %v\n", err)
+		return 2
+	}
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunValidate(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "lab.yaml")
+	if err := os.WriteFile(valid, []byte("stack_name: ci-lab\nprovider: azure\nworkspace_templates:\n  - name: default\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "broken.yaml")
+	if err := os.WriteFile(invalid, []byte("provider: azure\nworkspace_templates: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		wantCode int
+		wantOut  string
+	}{
+		{name: "valid file", args: []string{valid}, wantCode: 0, wantOut: `"valid": true`},
+		{name: "invalid file", args: []string{invalid}, wantCode: 1, wantOut: "stack_name is required"},
+		{name: "stdin", args: []string{"-"}, stdin: "stack_name: ci-lab\nprovider: azure\nworkspace_templates:\n  - name: default\n", wantCode: 0, wantOut: `"valid": true`},
+		{name: "missing file", args: []string{filepath.Join(dir, "nope.yaml")}, wantCode: 2},
+		{name: "too many arguments", args: []string{valid, invalid}, wantCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runValidate(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("runValidate(%v) = %d, want %d (stderr: %s)", tt.args, code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantOut) {
+				t.Errorf("runValidate(%v) output = %s, want it to contain %q", tt.args, stdout.String(), tt.wantOut)
+			}
+		})
+	}
+}
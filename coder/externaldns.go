@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	internalK8s "easylab/k8s"
 	"easylab/utils"
 
 	k8s "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
@@ -107,14 +108,23 @@ func setupExternalDNS(
 	// on the credential secret as well as the namespace, and InitHelm only depends
 	// on the namespace. Same reason the OVH cert-manager webhook does it this way
 	// (internal/providers/dns/ovh/ovh.go).
+	repo, err := utils.HelmRepoURL(ctx, "external-dns", internalK8s.PinnedCharts["external-dns"].Repo)
+	if err != nil {
+		return err
+	}
+	version, err := internalK8s.ChartVersion(ctx, "external-dns")
+	if err != nil {
+		return err
+	}
 	if _, err := helmv3.NewRelease(ctx, "external-dns", &helmv3.ReleaseArgs{
 		Chart:           pulumi.String("external-dns"),
 		Name:            pulumi.String("external-dns"),
 		Namespace:       ns.Metadata.Name(),
 		CreateNamespace: pulumi.Bool(false),
 		RepositoryOpts: &helmv3.RepositoryOptsArgs{
-			Repo: pulumi.String("https://kubernetes-sigs.github.io/external-dns/"),
+			Repo: pulumi.String(repo),
 		},
+		Version: pulumi.String(version),
 		Values:  values,
 		Timeout: pulumi.Int(600),
 	}, pulumi.Provider(k8sProvider), pulumi.DependsOn([]pulumi.Resource{ns, secret})); err != nil {
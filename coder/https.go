@@ -2,7 +2,9 @@ package coder
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	dnsregistry "easylab/internal/providers/dns"
 	internalK8s "easylab/k8s"
@@ -62,6 +64,29 @@ func SetupHTTPS(
 		nginxServiceName = "ingress-nginx-controller"
 	}
 
+	// A tainted node pool repels every pod without a matching toleration, so the
+	// charts installed here must follow the workspaces onto it.
+	nodeLabels, nodeTaints, err := utils.NodePoolScheduling(ctx)
+	if err != nil {
+		return nil, pulumi.StringOutput{}, err
+	}
+	scheduling := schedulingValues(nodeLabels, nodeTaints)
+	lbAnnotations, err := utils.LoadBalancerAnnotations(ctx)
+	if err != nil {
+		return nil, pulumi.StringOutput{}, err
+	}
+	// A reserved IP is only requested from a controller the lab installs.
+	var reservedIP string
+	if installNginxIngress {
+		reservedIP = utils.CoderConfigOptional(ctx, utils.CoderLoadBalancerIP)
+	}
+	// The NodePort fallback only serves nip.io hosts: a DNS record cannot carry
+	// the port, so a lab with a domain always needs its LoadBalancer IP.
+	ipOpts := internalK8s.ServiceAddressOptions{
+		Timeout:          utils.IngressIPTimeoutConfig(ctx),
+		NodePortFallback: domain == "" && installNginxIngress && utils.NodePortFallbackConfig(ctx),
+	}
+
 	// ── cert-manager ────────────────────────────────────────────────────────
 	// Without a domain there is no ClusterIssuer and no certificate to request,
 	// so cert-manager would sit idle — skip it.
@@ -77,9 +102,8 @@ func SetupHTTPS(
 		certManagerRelease, err = internalK8s.InitHelm(ctx, k8sProvider, internalK8s.HelmChartInfo{
 			Name:        "cert-manager",
 			ChartName:   "cert-manager",
-			Url:         "https://charts.jetstack.io",
 			ReleaseName: "cert-manager",
-			Values:      pulumi.Map{"installCRDs": pulumi.Bool(true)},
+			Values:      certManagerValues(scheduling),
 		}, certManagerNs)
 		if err != nil {
 			return nil, pulumi.StringOutput{}, fmt.Errorf("failed to install cert-manager: %w", err)
@@ -99,21 +123,8 @@ func SetupHTTPS(
 		ingressRelease, err = internalK8s.InitHelm(ctx, k8sProvider, internalK8s.HelmChartInfo{
 			Name:        "ingress-nginx",
 			ChartName:   "ingress-nginx",
-			Url:         "https://kubernetes.github.io/ingress-nginx",
 			ReleaseName: "ingress-nginx",
-			// OVHcloud sets ipMode:VIP on LoadBalancer services, which causes the Pulumi
-			// Kubernetes provider's GetService await to block indefinitely. Adding the
-			// skipAwait annotation to the controller service tells the provider to skip
-			// the readiness check when reading it.
-			Values: pulumi.Map{
-				"controller": pulumi.Map{
-					"service": pulumi.Map{
-						"annotations": pulumi.StringMap{
-							"pulumi.kubernetes.io/skipAwait": pulumi.String("true"),
-						},
-					},
-				},
-			},
+			Values:      ingressNginxValues(scheduling, lbAnnotations, reservedIP, ipOpts.NodePortFallback),
 		}, ingressNs)
 		if err != nil {
 			return nil, pulumi.StringOutput{}, fmt.Errorf("failed to install ingress-nginx: %w", err)
@@ -124,7 +135,7 @@ func SetupHTTPS(
 	// LoadBalancer IP so the server can route workspaces at "{name}.{ip}.nip.io"
 	// over plain HTTP, and skip the ACME ClusterIssuer and DNS records entirely.
 	if domain == "" {
-		ingressIP, ipErr := GetIngressIP(kubeconfigOut, ingressRelease, nginxNsName, nginxServiceName)
+		ingressIP, ipErr := GetIngressIP(ctx, kubeconfigOut, ingressRelease, nginxNsName, nginxServiceName, reservedIP, ipOpts)
 		if ipErr != nil {
 			return nil, pulumi.StringOutput{}, fmt.Errorf("failed to get ingress-nginx IP: %w", ipErr)
 		}
@@ -219,7 +230,7 @@ func SetupHTTPS(
 		// Resolve the LoadBalancer IP here — after the webhook Helm install, which
 		// takes several minutes and gives the cloud provider time to assign the IP.
 		var ipErr error
-		ingressIP, ipErr = GetIngressIP(kubeconfigOut, ingressRelease, nginxNsName, nginxServiceName)
+		ingressIP, ipErr = GetIngressIP(ctx, kubeconfigOut, ingressRelease, nginxNsName, nginxServiceName, reservedIP, ipOpts)
 		if ipErr != nil {
 			return nil, pulumi.StringOutput{}, ipErr
 		}
@@ -318,7 +329,7 @@ func SetupHTTPS(
 	// time to assign the LoadBalancer IP during the cert-manager/ingress install.
 	if !ingressIPResolved {
 		var ipErr error
-		ingressIP, ipErr = GetIngressIP(kubeconfigOut, ingressRelease, nginxNsName, nginxServiceName)
+		ingressIP, ipErr = GetIngressIP(ctx, kubeconfigOut, ingressRelease, nginxNsName, nginxServiceName, reservedIP, ipOpts)
 		if ipErr != nil {
 			return nil, pulumi.StringOutput{}, fmt.Errorf("failed to get ingress-nginx IP: %w", ipErr)
 		}
@@ -358,9 +369,27 @@ func workspaceNamespace(ctx *pulumi.Context) string {
 	return "workshops"
 }
 
+// certificateReadyTimeout bounds how long pulumi up waits for the wildcard
+// certificate. A DNS-01 challenge normally completes in a minute or two; one
+// still pending after this is stuck (wrong zone, missing API rights) and the
+// deployment fails with the certificate named rather than reporting a lab
+// that serves an invalid certificate.
+const certificateReadyTimeout = 10 * time.Minute
+
+// certificateAwaitAnnotations makes the Pulumi Kubernetes provider wait for a
+// cert-manager Certificate to be issued. Without them a custom resource counts
+// as created as soon as the API server accepts it.
+func certificateAwaitAnnotations() pulumi.StringMap {
+	return pulumi.StringMap{
+		"pulumi.com/waitFor":        pulumi.String("condition=Ready"),
+		"pulumi.com/timeoutSeconds": pulumi.String(strconv.Itoa(int(certificateReadyTimeout.Seconds()))),
+	}
+}
+
 // createWildcardCertificate requests a single certificate covering the lab domain
 // and every host directly under it. It is created in the workspace namespace
 // because an ingress may only reference a TLS secret in its own namespace.
+// pulumi up waits until it is issued, up to certificateReadyTimeout.
 func createWildcardCertificate(
 	ctx *pulumi.Context,
 	k8sProvider *k8s.Provider,
@@ -372,8 +401,9 @@ func createWildcardCertificate(
 		ApiVersion: pulumi.String("cert-manager.io/v1"),
 		Kind:       pulumi.String("Certificate"),
 		Metadata: &metav1.ObjectMetaArgs{
-			Name:      pulumi.String(WildcardTLSSecretName),
-			Namespace: pulumi.String(nsName),
+			Name:        pulumi.String(WildcardTLSSecretName),
+			Namespace:   pulumi.String(nsName),
+			Annotations: certificateAwaitAnnotations(),
 		},
 		OtherFields: map[string]any{
 			"spec": map[string]any{
@@ -398,14 +428,21 @@ func createWildcardCertificate(
 // pending-initialisation await, which blocks indefinitely on OVHcloud clusters because
 // OVHcloud sets ipMode:VIP on LoadBalancer services (not recognised as ready by the provider).
 // ingressRelease may be nil when ingress-nginx is pre-installed on the cluster.
-func GetIngressIP(kubeconfigOut pulumi.StringOutput, ingressRelease *helmv3.Release, namespace, serviceName string) (pulumi.StringOutput, error) {
+// When the service was pinned to reservedIP, that IP is returned as is: it is
+// known before the load balancer is up, so DNS records need not wait for it.
+// opts sets the wait for the IP, and whether "{node IP}:{NodePort}" is
+// returned when it times out.
+func GetIngressIP(ctx *pulumi.Context, kubeconfigOut pulumi.StringOutput, ingressRelease *helmv3.Release, namespace, serviceName, reservedIP string, opts internalK8s.ServiceAddressOptions) (pulumi.StringOutput, error) {
+	if reservedIP != "" {
+		return pulumi.String(reservedIP).ToStringOutput(), nil
+	}
 	var trigger interface{}
 	if ingressRelease != nil {
 		trigger = ingressRelease.ResourceNames
 	} else {
 		trigger = pulumi.String("") // no-op: service is already present
 	}
-	return internalK8s.GetServiceIP(kubeconfigOut, trigger, namespace, serviceName), nil
+	return internalK8s.GetServiceIP(ctx, kubeconfigOut, trigger, namespace, serviceName, opts), nil
 }
 
 // createDNSCredentialSecret stores DNS provider API credentials in a Kubernetes Secret
@@ -458,3 +495,82 @@ func createDNSCredentialSecret(
 
 	return secretName, nil
 }
+
+// schedulingValues returns the nodeSelector and tolerations Helm values that put
+// a chart's pods on the lab's labelled and tainted node pool, or nil when the
+// pool has neither.
+func schedulingValues(labels map[string]string, taints []utils.NodeTaint) pulumi.Map {
+	if len(labels) == 0 && len(taints) == 0 {
+		return nil
+	}
+	values := pulumi.Map{}
+	if len(labels) > 0 {
+		values["nodeSelector"] = pulumi.ToStringMap(labels)
+	}
+	if len(taints) > 0 {
+		tolerations := pulumi.Array{}
+		for _, t := range taints {
+			tolerations = append(tolerations, pulumi.StringMap{
+				"key":      pulumi.String(t.Key),
+				"operator": pulumi.String("Equal"),
+				"value":    pulumi.String(t.Value),
+				"effect":   pulumi.String(t.Effect),
+			})
+		}
+		values["tolerations"] = tolerations
+	}
+	return values
+}
+
+// withScheduling adds the scheduling values to a chart (or sub-chart) values map.
+func withScheduling(values, scheduling pulumi.Map) pulumi.Map {
+	for k, v := range scheduling {
+		values[k] = v
+	}
+	return values
+}
+
+// certManagerValues are the cert-manager chart values. Each of its components
+// takes its own nodeSelector and tolerations.
+func certManagerValues(scheduling pulumi.Map) pulumi.Map {
+	values := withScheduling(pulumi.Map{"installCRDs": pulumi.Bool(true)}, scheduling)
+	if scheduling != nil {
+		for _, component := range []string{"webhook", "cainjector", "startupapicheck"} {
+			values[component] = withScheduling(pulumi.Map{}, scheduling)
+		}
+	}
+	return values
+}
+
+// SkipAwaitAnnotation is always set on the ingress-nginx controller service; see
+// ingressNginxValues.
+const SkipAwaitAnnotation = "pulumi.kubernetes.io/skipAwait"
+
+// ingressNginxValues are the ingress-nginx chart values. OVHcloud sets ipMode:VIP
+// on LoadBalancer services, which causes the Pulumi Kubernetes provider's
+// GetService await to block indefinitely. Adding the skipAwait annotation to the
+// controller service tells the provider to skip the readiness check when reading
+// it. The operator's annotations tune the load balancer itself; they cannot
+// override skipAwait. reservedIP, when set, pins the load balancer to that IP.
+// nodePortFallback marks the service so that the server routes workspaces to a
+// node's NodePort while the service has no LoadBalancer IP.
+func ingressNginxValues(scheduling pulumi.Map, lbAnnotations map[string]string, reservedIP string, nodePortFallback bool) pulumi.Map {
+	annotations := pulumi.StringMap{}
+	for k, v := range lbAnnotations {
+		annotations[k] = pulumi.String(v)
+	}
+	annotations[SkipAwaitAnnotation] = pulumi.String("true")
+	if nodePortFallback {
+		annotations[internalK8s.NodePortFallbackAnnotation] = pulumi.String("true")
+	}
+	service := pulumi.Map{"annotations": annotations}
+	if reservedIP != "" {
+		service["loadBalancerIP"] = pulumi.String(reservedIP)
+	}
+	controller := withScheduling(pulumi.Map{"service": service}, scheduling)
+	if scheduling != nil {
+		// The admission webhook's certificate jobs run outside the controller pod.
+		controller["admissionWebhooks"] = pulumi.Map{"patch": withScheduling(pulumi.Map{}, scheduling)}
+	}
+	return pulumi.Map{"controller": controller}
+}
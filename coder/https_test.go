@@ -3,6 +3,10 @@ package coder
 import (
 	"testing"
 
+	internalK8s "easylab/k8s"
+	"easylab/utils"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -87,3 +91,80 @@ func TestWildcardOf(t *testing.T) {
 		})
 	}
 }
+
+func TestSchedulingValues(t *testing.T) {
+	assert.Nil(t, schedulingValues(nil, nil), "no labels or taints: the charts keep their defaults")
+
+	values := schedulingValues(
+		map[string]string{"pool": "workspaces"},
+		[]utils.NodeTaint{{Key: "dedicated", Value: "workspaces", Effect: "NoSchedule"}},
+	)
+	assert.Equal(t, pulumi.StringMap{"pool": pulumi.String("workspaces")}, values["nodeSelector"])
+	assert.Equal(t, pulumi.Array{pulumi.StringMap{
+		"key":      pulumi.String("dedicated"),
+		"operator": pulumi.String("Equal"),
+		"value":    pulumi.String("workspaces"),
+		"effect":   pulumi.String("NoSchedule"),
+	}}, values["tolerations"])
+}
+
+func TestChartValuesWithScheduling(t *testing.T) {
+	scheduling := schedulingValues(map[string]string{"pool": "workspaces"}, []utils.NodeTaint{{Key: "dedicated", Effect: "NoSchedule"}})
+
+	certManager := certManagerValues(scheduling)
+	assert.Equal(t, pulumi.Bool(true), certManager["installCRDs"])
+	assert.Contains(t, certManager, "tolerations")
+	for _, component := range []string{"webhook", "cainjector", "startupapicheck"} {
+		sub, ok := certManager[component].(pulumi.Map)
+		if assert.True(t, ok, component) {
+			assert.Equal(t, scheduling["nodeSelector"], sub["nodeSelector"], component)
+			assert.Equal(t, scheduling["tolerations"], sub["tolerations"], component)
+		}
+	}
+
+	controller := ingressNginxValues(scheduling, nil, "", false)["controller"].(pulumi.Map)
+	assert.Contains(t, controller, "service", "the skipAwait annotation must survive")
+	assert.Equal(t, scheduling["tolerations"], controller["tolerations"])
+	patch := controller["admissionWebhooks"].(pulumi.Map)["patch"].(pulumi.Map)
+	assert.Equal(t, scheduling["nodeSelector"], patch["nodeSelector"])
+}
+
+func TestChartValuesWithoutScheduling(t *testing.T) {
+	assert.Equal(t, pulumi.Map{"installCRDs": pulumi.Bool(true)}, certManagerValues(nil))
+
+	controller := ingressNginxValues(nil, nil, "", false)["controller"].(pulumi.Map)
+	assert.NotContains(t, controller, "tolerations")
+	assert.NotContains(t, controller, "admissionWebhooks")
+}
+
+func TestIngressNginxValues_LoadBalancerAnnotations(t *testing.T) {
+	values := ingressNginxValues(nil, map[string]string{
+		"loadbalancer.ovhcloud.com/flavor": "medium",
+		SkipAwaitAnnotation:                "false",
+	}, "", false)
+	annotations := values["controller"].(pulumi.Map)["service"].(pulumi.Map)["annotations"].(pulumi.StringMap)
+	assert.Equal(t, pulumi.String("medium"), annotations["loadbalancer.ovhcloud.com/flavor"])
+	assert.Equal(t, pulumi.String("true"), annotations[SkipAwaitAnnotation], "skipAwait cannot be overridden")
+
+	annotations = ingressNginxValues(nil, nil, "", false)["controller"].(pulumi.Map)["service"].(pulumi.Map)["annotations"].(pulumi.StringMap)
+	assert.Equal(t, pulumi.StringMap{SkipAwaitAnnotation: pulumi.String("true")}, annotations)
+}
+
+func TestIngressNginxValues_ReservedIP(t *testing.T) {
+	service := ingressNginxValues(nil, nil, "51.178.10.20", false)["controller"].(pulumi.Map)["service"].(pulumi.Map)
+	assert.Equal(t, pulumi.String("51.178.10.20"), service["loadBalancerIP"])
+
+	service = ingressNginxValues(nil, nil, "", false)["controller"].(pulumi.Map)["service"].(pulumi.Map)
+	assert.NotContains(t, service, "loadBalancerIP")
+}
+
+func TestIngressNginxValues_NodePortFallback(t *testing.T) {
+	annotations := ingressNginxValues(nil, nil, "", true)["controller"].(pulumi.Map)["service"].(pulumi.Map)["annotations"].(pulumi.StringMap)
+	assert.Equal(t, pulumi.String("true"), annotations[internalK8s.NodePortFallbackAnnotation])
+}
+
+func TestCertificateAwaitAnnotations(t *testing.T) {
+	annotations := certificateAwaitAnnotations()
+	assert.Equal(t, pulumi.String("condition=Ready"), annotations["pulumi.com/waitFor"])
+	assert.Equal(t, pulumi.String("600"), annotations["pulumi.com/timeoutSeconds"])
+}
@@ -0,0 +1,159 @@
+package coder
+
+import (
+	"fmt"
+	"net"
+
+	internalK8s "easylab/k8s"
+	"easylab/utils"
+
+	k8s "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	k8score "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	networkingv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	monitoringNamespace = "monitoring"
+	monitoringRelease   = "kube-prometheus-stack"
+	// grafanaService is the service the chart names after its release.
+	grafanaService = monitoringRelease + "-grafana"
+	grafanaHost    = "grafana"
+	grafanaTLS     = "grafana-tls"
+)
+
+// SetupMonitoring installs kube-prometheus-stack into the monitoring namespace
+// and exposes Grafana the same way as the workspaces: on grafana.{domain} over
+// HTTPS, or on grafana.{ip}.nip.io over plain HTTP when the lab has no domain.
+// It returns Grafana's URL.
+//
+// The chart does not depend on anything SetupHTTPS creates, so Pulumi installs
+// both side by side; only the Grafana ingress waits for the ingress IP.
+func SetupMonitoring(ctx *pulumi.Context, k8sProvider *k8s.Provider, ingressIP pulumi.StringOutput) (pulumi.StringOutput, error) {
+	domain := utils.CoderConfigOptional(ctx, utils.CoderDomain)
+	nodeLabels, nodeTaints, err := utils.NodePoolScheduling(ctx)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	ns, err := k8score.NewNamespace(ctx, "monitoring-ns", &k8score.NamespaceArgs{
+		Metadata: &metav1.ObjectMetaArgs{Name: pulumi.String(monitoringNamespace)},
+	}, pulumi.Provider(k8sProvider))
+	if err != nil {
+		return pulumi.StringOutput{}, fmt.Errorf("failed to create monitoring namespace: %w", err)
+	}
+
+	release, err := internalK8s.InitHelm(ctx, k8sProvider, internalK8s.HelmChartInfo{
+		Name:        monitoringRelease,
+		ChartName:   "kube-prometheus-stack",
+		ReleaseName: monitoringRelease,
+		Values:      monitoringValues(schedulingValues(nodeLabels, nodeTaints), utils.GrafanaAdminPassword(ctx)),
+	}, ns)
+	if err != nil {
+		return pulumi.StringOutput{}, fmt.Errorf("failed to install kube-prometheus-stack: %w", err)
+	}
+
+	host := grafanaHostname(domain, ingressIP)
+	if _, err := networkingv1.NewIngress(ctx, "grafana-ingress", &networkingv1.IngressArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:        pulumi.String(grafanaHost),
+			Namespace:   pulumi.String(monitoringNamespace),
+			Annotations: grafanaIngressAnnotations(domain),
+		},
+		Spec: grafanaIngressSpec(domain, host),
+	}, pulumi.Provider(k8sProvider), pulumi.DependsOn([]pulumi.Resource{release})); err != nil {
+		return pulumi.StringOutput{}, fmt.Errorf("failed to create Grafana ingress: %w", err)
+	}
+
+	if domain != "" {
+		return pulumi.Sprintf("https://%s", host), nil
+	}
+	// Behind a NodePort fallback, the port is in the URL but not in the host.
+	port := ingressIP.ApplyT(func(address string) string {
+		if _, p, err := net.SplitHostPort(address); err == nil {
+			return ":" + p
+		}
+		return ""
+	}).(pulumi.StringOutput)
+	return pulumi.Sprintf("http://%s%s", host, port), nil
+}
+
+// grafanaHostname is grafana.{domain}, which the lab's wildcard DNS record
+// already covers, or grafana.{ip}.nip.io without a domain. ingressIP may be a
+// NodePort fallback's "{ip}:{port}".
+func grafanaHostname(domain string, ingressIP pulumi.StringOutput) pulumi.StringOutput {
+	if domain != "" {
+		return pulumi.String(grafanaHost + "." + domain).ToStringOutput()
+	}
+	return ingressIP.ApplyT(func(address string) string {
+		if ip, _, err := net.SplitHostPort(address); err == nil {
+			address = ip
+		}
+		return fmt.Sprintf("%s.%s.nip.io", grafanaHost, address)
+	}).(pulumi.StringOutput)
+}
+
+// grafanaIngressAnnotations requests a certificate from the lab's ClusterIssuer
+// when there is a domain. The Pulumi await is skipped either way: ingress-nginx
+// reports the load balancer address on the ingress, which never settles on
+// OVHcloud's ipMode:VIP services (see ingressNginxValues).
+func grafanaIngressAnnotations(domain string) pulumi.StringMap {
+	annotations := pulumi.StringMap{"pulumi.com/skipAwait": pulumi.String("true")}
+	if domain != "" {
+		annotations["cert-manager.io/cluster-issuer"] = pulumi.String("letsencrypt-prod")
+	}
+	return annotations
+}
+
+func grafanaIngressSpec(domain string, host pulumi.StringOutput) *networkingv1.IngressSpecArgs {
+	spec := &networkingv1.IngressSpecArgs{
+		IngressClassName: pulumi.String("nginx"),
+		Rules: networkingv1.IngressRuleArray{
+			networkingv1.IngressRuleArgs{
+				Host: host,
+				Http: networkingv1.HTTPIngressRuleValueArgs{
+					Paths: networkingv1.HTTPIngressPathArray{
+						networkingv1.HTTPIngressPathArgs{
+							Path:     pulumi.String("/"),
+							PathType: pulumi.String("Prefix"),
+							Backend: networkingv1.IngressBackendArgs{
+								Service: networkingv1.IngressServiceBackendArgs{
+									Name: pulumi.String(grafanaService),
+									Port: networkingv1.ServiceBackendPortArgs{Number: pulumi.Int(80)},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if domain != "" {
+		spec.Tls = networkingv1.IngressTLSArray{
+			networkingv1.IngressTLSArgs{
+				Hosts:      pulumi.StringArray{host},
+				SecretName: pulumi.String(grafanaTLS),
+			},
+		}
+	}
+	return spec
+}
+
+// monitoringValues are the kube-prometheus-stack chart values. Like the other
+// charts, each component has to be told about a tainted node pool; the node
+// exporter is left alone, as it already runs on every node.
+func monitoringValues(scheduling pulumi.Map, adminPassword pulumi.StringInput) pulumi.Map {
+	values := pulumi.Map{
+		"grafana": withScheduling(pulumi.Map{"adminPassword": adminPassword}, scheduling),
+	}
+	if scheduling != nil {
+		values["prometheusOperator"] = withScheduling(pulumi.Map{
+			"admissionWebhooks": pulumi.Map{"patch": withScheduling(pulumi.Map{}, scheduling)},
+		}, scheduling)
+		values["prometheus"] = pulumi.Map{"prometheusSpec": withScheduling(pulumi.Map{}, scheduling)}
+		values["alertmanager"] = pulumi.Map{"alertmanagerSpec": withScheduling(pulumi.Map{}, scheduling)}
+		values["kube-state-metrics"] = withScheduling(pulumi.Map{}, scheduling)
+	}
+	return values
+}
@@ -0,0 +1,50 @@
+package coder
+
+import (
+	"testing"
+
+	"easylab/utils"
+
+	networkingv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitoringValues(t *testing.T) {
+	password := pulumi.String("s3cret")
+
+	values := monitoringValues(nil, password)
+	assert.Equal(t, pulumi.Map{"grafana": pulumi.Map{"adminPassword": password}}, values)
+
+	scheduling := schedulingValues(map[string]string{"pool": "workspaces"}, []utils.NodeTaint{{Key: "dedicated", Effect: "NoSchedule"}})
+	values = monitoringValues(scheduling, password)
+	grafana := values["grafana"].(pulumi.Map)
+	assert.Equal(t, password, grafana["adminPassword"])
+	assert.Equal(t, scheduling["tolerations"], grafana["tolerations"])
+	assert.Equal(t, scheduling["nodeSelector"], values["prometheus"].(pulumi.Map)["prometheusSpec"].(pulumi.Map)["nodeSelector"])
+	assert.Equal(t, scheduling["tolerations"], values["alertmanager"].(pulumi.Map)["alertmanagerSpec"].(pulumi.Map)["tolerations"])
+	assert.Equal(t, scheduling["tolerations"], values["kube-state-metrics"].(pulumi.Map)["tolerations"])
+	patch := values["prometheusOperator"].(pulumi.Map)["admissionWebhooks"].(pulumi.Map)["patch"].(pulumi.Map)
+	assert.Equal(t, scheduling["nodeSelector"], patch["nodeSelector"])
+	assert.NotContains(t, values, "prometheus-node-exporter", "the node exporter already runs on every node")
+}
+
+func TestGrafanaIngress_WithDomain(t *testing.T) {
+	annotations := grafanaIngressAnnotations("lab.example.com")
+	assert.Equal(t, pulumi.String("letsencrypt-prod"), annotations["cert-manager.io/cluster-issuer"])
+	assert.Equal(t, pulumi.String("true"), annotations["pulumi.com/skipAwait"])
+
+	spec := grafanaIngressSpec("lab.example.com", pulumi.String("grafana.lab.example.com").ToStringOutput())
+	if assert.Len(t, spec.Tls, 1) {
+		assert.Equal(t, pulumi.String(grafanaTLS), spec.Tls.(networkingv1.IngressTLSArray)[0].(networkingv1.IngressTLSArgs).SecretName)
+	}
+}
+
+func TestGrafanaIngress_WithoutDomain(t *testing.T) {
+	annotations := grafanaIngressAnnotations("")
+	assert.NotContains(t, annotations, "cert-manager.io/cluster-issuer", "there is no issuer without a domain")
+
+	spec := grafanaIngressSpec("", pulumi.String("grafana.51.178.10.20.nip.io").ToStringOutput())
+	assert.Nil(t, spec.Tls, "nip.io hosts are served over plain HTTP")
+	assert.Equal(t, pulumi.String("nginx"), spec.IngressClassName)
+}
@@ -56,13 +56,17 @@ func (p *OVHDNSProvider) SetupCertManagerDNS01(
 
 	webhookDeps := append(deps, webhookNs)
 
+	webhookRepo, err := utils.HelmRepoURL(ctx, "cert-manager-webhook-ovh", "https://aureq.github.io/cert-manager-webhook-ovh")
+	if err != nil {
+		return nil, nil, err
+	}
 	webhookRelease, err := helmv3.NewRelease(ctx, "cert-manager-webhook-ovh", &helmv3.ReleaseArgs{
 		Chart:           pulumi.String("cert-manager-webhook-ovh"),
 		Name:            pulumi.String("cert-manager-webhook-ovh"),
 		Namespace:       webhookNs.Metadata.Name(),
 		CreateNamespace: pulumi.Bool(false),
 		RepositoryOpts: &helmv3.RepositoryOptsArgs{
-			Repo: pulumi.String("https://aureq.github.io/cert-manager-webhook-ovh"),
+			Repo: pulumi.String(webhookRepo),
 		},
 		Version: pulumi.String("0.9.10"),
 		Values: pulumi.Map{
@@ -10,7 +10,10 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,10 +23,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -67,6 +74,10 @@ const (
 	// elsewhere simply gets no nip.io fallback (workspace stays in-cluster).
 	ingressNginxNamespace = "ingress-nginx"
 	ingressNginxService   = "ingress-nginx-controller"
+	// nodePortFallbackAnnotation is set on the controller service of the labs
+	// that opt into serving on a NodePort when no LoadBalancer IP comes (the
+	// k8s.NodePortFallbackAnnotation the Pulumi program sets).
+	nodePortFallbackAnnotation = "easylab.io/node-port-fallback"
 
 	schemeHTTPS = "https"
 	schemeHTTP  = "http"
@@ -132,6 +143,11 @@ func profileFor(string) ideProfile {
 type Backend struct {
 	client    kubernetes.Interface
 	namespace string
+
+	// dynamic and mapper apply arbitrary manifests (see kube_manifests.go). They
+	// are nil in backends built on a fake clientset.
+	dynamic dynamic.Interface
+	mapper  meta.ResettableRESTMapper
 }
 
 // New builds a kube Backend from a kubeconfig file's contents.
@@ -147,7 +163,14 @@ func New(kubeconfig, namespace string) (workspace.Backend, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to build kubernetes client: %w", err)
 	}
-	return newBackend(cs, namespace), nil
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dynamic client: %w", err)
+	}
+	b := newBackend(cs, namespace)
+	b.dynamic = dyn
+	b.mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(cs.Discovery()))
+	return b, nil
 }
 
 // newBackend is the injectable constructor used by tests (with a fake clientset).
@@ -185,10 +208,15 @@ func sanitizeDNS(s string) string {
 	return s
 }
 
-// ingressIP returns the external IP assigned to the ingress-nginx controller
-// Service, or "" when the controller is absent or has no IP yet. Only an IP is
-// usable: nip.io encodes an address, so a hostname-only LoadBalancer yields "".
-func (b *Backend) ingressIP(ctx context.Context) string {
+// ingressAddress returns where the ingress-nginx controller Service is reached
+// from outside: its LoadBalancer IP, or "" when the controller is absent or has
+// no IP yet. Only an IP is usable: nip.io encodes an address, so a
+// hostname-only LoadBalancer yields "".
+//
+// A controller the lab marked for the NodePort fallback (see
+// nodePortFallbackAnnotation) is reached on "{node IP}:{NodePort}" while it has
+// no LoadBalancer IP.
+func (b *Backend) ingressAddress(ctx context.Context) string {
 	svc, err := b.client.CoreV1().Services(ingressNginxNamespace).Get(ctx, ingressNginxService, metav1.GetOptions{})
 	if err != nil {
 		return ""
@@ -198,9 +226,51 @@ func (b *Backend) ingressIP(ctx context.Context) string {
 			return ing.IP
 		}
 	}
+	if svc.Annotations[nodePortFallbackAnnotation] != "true" {
+		return ""
+	}
+	return b.nodePortAddress(ctx, svc)
+}
+
+// nodePortAddress returns "{ip}:{port}" for svc's HTTP NodePort on the first
+// Ready node, by name, that has an external IP, or "" when there is none.
+func (b *Backend) nodePortAddress(ctx context.Context, svc *corev1.Service) string {
+	var port int32
+	for _, p := range svc.Spec.Ports {
+		if p.Name == "http" || (p.Port == 80 && port == 0) {
+			port = p.NodePort
+		}
+	}
+	if port == 0 {
+		return ""
+	}
+	nodes, err := b.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return ""
+	}
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	for _, n := range nodes.Items {
+		if !nodeReady(n) {
+			continue
+		}
+		for _, a := range n.Status.Addresses {
+			if a.Type == corev1.NodeExternalIP && a.Address != "" {
+				return net.JoinHostPort(a.Address, strconv.Itoa(int(port)))
+			}
+		}
+	}
 	return ""
 }
 
+func nodeReady(n corev1.Node) bool {
+	for _, c := range n.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
 // resolveRouting decides how a workspace is exposed and returns the spec to build
 // from plus the URL scheme.
 //
@@ -209,15 +279,20 @@ func (b *Backend) ingressIP(ctx context.Context) string {
 // LoadBalancer IP, served on plain HTTP: that mode provisions no cert-manager
 // ClusterIssuer, so there is nothing to issue a certificate with. When no ingress
 // IP is available either, Domain stays empty and the workspace is in-cluster only.
+// Behind the NodePort fallback, Domain is "{ip}.nip.io:{port}": the port is part
+// of the workspace URL, not of its ingress host.
 func (b *Backend) resolveRouting(ctx context.Context, spec workspace.Spec) (workspace.Spec, string) {
 	if strings.TrimSpace(spec.Domain) != "" {
 		return spec, schemeHTTPS
 	}
-	ip := b.ingressIP(ctx)
-	if ip == "" {
+	address := b.ingressAddress(ctx)
+	if address == "" {
 		return spec, schemeHTTPS
 	}
-	spec.Domain = ip + "." + nipDomainSuffix
+	spec.Domain = address + "." + nipDomainSuffix
+	if ip, port, err := net.SplitHostPort(address); err == nil {
+		spec.Domain = ip + "." + nipDomainSuffix + ":" + port
+	}
 	spec.ClusterIssuer = ""
 	spec.WildcardTLSSecret = ""
 	return spec, schemeHTTP
@@ -243,9 +318,20 @@ func (b *Backend) labels(labID, owner, name string) map[string]string {
 // current workspace state. It is idempotent.
 func (b *Backend) EnsureWorkspace(ctx context.Context, spec workspace.Spec) (workspace.Workspace, error) {
 	name := workspaceName(spec.LabID, spec.Owner, spec.Template)
+	if spec.Name != "" {
+		if err := workspace.ValidateName(spec.Name); err != nil {
+			return workspace.Workspace{}, err
+		}
+		name = spec.Name
+	}
 
 	existing, err := b.client.AppsV1().Deployments(b.namespace).Get(ctx, name, metav1.GetOptions{})
 	if err == nil {
+		// A derived name is unique to (lab, owner, template), but a chosen one is
+		// not: only hand the workspace back when it is the one this spec describes.
+		if spec.Name != "" && !b.sameWorkspace(existing, spec) {
+			return workspace.Workspace{}, fmt.Errorf("%w: %s", workspace.ErrNameTaken, name)
+		}
 		// Workspace already exists — return its actual access token (a freshly
 		// generated spec.Token would not match the running pod, which keeps its
 		// original token/password) and the routing recorded at create time, which
@@ -268,6 +354,14 @@ func (b *Backend) EnsureWorkspace(ctx context.Context, spec workspace.Spec) (wor
 	return b.toWorkspace(dep, spec.Domain, spec.Token), nil
 }
 
+// sameWorkspace reports whether dep is the workspace spec asks for: same lab, same
+// student, same template.
+func (b *Backend) sameWorkspace(dep *appsv1.Deployment, spec workspace.Spec) bool {
+	return dep.Labels[labelLabID] == sanitizeDNS(spec.LabID) &&
+		dep.Labels[labelOwner] == sanitizeDNS(spec.Owner) &&
+		dep.Annotations[annotationTemplate] == spec.Template
+}
+
 // GetWorkspace returns the workspace with the given resource name.
 func (b *Backend) GetWorkspace(ctx context.Context, name string) (workspace.Workspace, error) {
 	dep, err := b.client.AppsV1().Deployments(b.namespace).Get(ctx, name, metav1.GetOptions{})
@@ -324,7 +418,7 @@ func (b *Backend) createResources(ctx context.Context, name string, spec workspa
 	hasPVC := strings.TrimSpace(spec.DiskSize) != ""
 
 	if hasPVC {
-		if err := b.createPVC(ctx, name, labels, spec.DiskSize); err != nil {
+		if err := b.createPVC(ctx, name, labels, spec.DiskSize, spec.StorageClass); err != nil {
 			return err
 		}
 	}
@@ -344,7 +438,7 @@ func (b *Backend) createResources(ctx context.Context, name string, spec workspa
 	return nil
 }
 
-func (b *Backend) createPVC(ctx context.Context, name string, labels map[string]string, size string) error {
+func (b *Backend) createPVC(ctx context.Context, name string, labels map[string]string, size, storageClass string) error {
 	qty, err := resource.ParseQuantity(size)
 	if err != nil {
 		return fmt.Errorf("invalid disk size %q: %w", size, err)
@@ -358,6 +452,9 @@ func (b *Backend) createPVC(ctx context.Context, name string, labels map[string]
 			},
 		},
 	}
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
 	if _, err := b.client.CoreV1().PersistentVolumeClaims(b.namespace).Create(ctx, pvc, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
 		return fmt.Errorf("failed to create PVC %s: %w", name, err)
 	}
@@ -390,7 +487,7 @@ func (b *Backend) createDeployment(ctx context.Context, name string, labels map[
 		Image:     image,
 		Ports:     []corev1.ContainerPort{{ContainerPort: p.port, Name: "http"}},
 		Env:       env,
-		Resources: buildResources(spec.CPU, spec.Memory),
+		Resources: buildResources(spec.CPU, spec.Memory, spec.GPU),
 		// Only mark the pod Ready once the IDE is actually listening — a wrapped
 		// startup script runs before the server exec, so the port opens late.
 		ReadinessProbe: &corev1.Probe{
@@ -434,7 +531,7 @@ func (b *Backend) createDeployment(ctx context.Context, name string, labels map[
 		// In devcontainer mode envbuilder does its own clone, so a git-clone init
 		// container would only race it for the same directory.
 		if strings.TrimSpace(spec.GitRepo) != "" && spec.Devcontainer == nil {
-			initContainers = append(initContainers, gitCloneInit(spec.GitRepo, spec.GitBranch, p.workspaceDir, spec.GitAuthSecret))
+			initContainers = append(initContainers, gitCloneInit(spec.GitRepo, spec.GitBranch, spec.GitCommit, p.workspaceDir, spec.GitAuthSecret))
 		}
 	}
 
@@ -494,6 +591,8 @@ func (b *Backend) createDeployment(ctx context.Context, name string, labels map[
 					// containers alike. The devcontainer build pulls from inside the pod
 					// with its own credentials and is unaffected by this.
 					ImagePullSecrets: imagePullSecrets(spec.ImagePullSecrets),
+					NodeSelector:     spec.NodeSelector,
+					Tolerations:      tolerations(spec.Tolerations),
 					InitContainers:   initContainers,
 					Containers:       containers,
 					Volumes:          volumes,
@@ -847,16 +946,39 @@ func imagePullSecrets(names []string) []corev1.LocalObjectReference {
 	return out
 }
 
+// tolerations converts the spec tolerations; each matches its taint exactly.
+func tolerations(in []workspace.Toleration) []corev1.Toleration {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]corev1.Toleration, 0, len(in))
+	for _, t := range in {
+		out = append(out, corev1.Toleration{
+			Key:      t.Key,
+			Operator: corev1.TolerationOpEqual,
+			Value:    t.Value,
+			Effect:   corev1.TaintEffect(t.Effect),
+		})
+	}
+	return out
+}
+
 // gitCloneInit returns an init container that clones repo into the workspace
 // volume on first start (skipped if the volume already has contents) and chowns
 // it to uid/gid 1000 (both IDE users) so the IDE can write. An optional branch
-// selects a single branch to clone. A non-empty authSecret names a basic-auth
-// Secret whose credentials are fed to git through a credential helper.
-func gitCloneInit(repo, branch, dir, authSecret string) corev1.Container {
+// selects a single branch to clone, and an optional commit is checked out
+// after the clone, so a pinned lab does not pick up later pushes. A non-empty
+// authSecret names a basic-auth Secret whose credentials are fed to git
+// through a credential helper.
+func gitCloneInit(repo, branch, commit, dir, authSecret string) corev1.Container {
 	branchFlag := ""
 	if b := strings.TrimSpace(branch); b != "" {
 		branchFlag = fmt.Sprintf("--branch %s --single-branch ", shellQuote(b))
 	}
+	checkout := ""
+	if c := strings.TrimSpace(commit); c != "" {
+		checkout = fmt.Sprintf(" && git -C %s checkout -q --detach %s", dir, shellQuote(c))
+	}
 
 	// gitCmd carries a literal %s (from the helper's printf). It is only ever
 	// passed to Sprintf as an argument, never as part of the format string, so it
@@ -868,8 +990,8 @@ func gitCloneInit(repo, branch, dir, authSecret string) corev1.Container {
 		env = basicAuthEnv(s, "GIT_USERNAME", "GIT_PASSWORD")
 	}
 
-	script := fmt.Sprintf(`if [ -z "$(ls -A %s 2>/dev/null)" ]; then %s clone %s%s %s && chown -R 1000:1000 %s; fi`,
-		dir, gitCmd, branchFlag, shellQuote(repo), dir, dir)
+	script := fmt.Sprintf(`if [ -z "$(ls -A %s 2>/dev/null)" ]; then %s clone %s%s %s%s && chown -R 1000:1000 %s; fi`,
+		dir, gitCmd, branchFlag, shellQuote(repo), dir, checkout, dir)
 	return corev1.Container{
 		Name:         "git-clone",
 		Image:        "alpine/git:latest",
@@ -899,6 +1021,10 @@ func (b *Backend) createService(ctx context.Context, name string, labels map[str
 
 func (b *Backend) createIngress(ctx context.Context, name string, labels map[string]string, spec workspace.Spec) error {
 	host := workspaceHost(name, spec.Domain)
+	// A NodePort fallback domain carries the port, which the ingress rule does not.
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
 	pathType := netv1.PathTypePrefix
 	ingressClass := "nginx"
 
@@ -1005,7 +1131,10 @@ func workspaceHost(name, domain string) string {
 	return name + "." + domain
 }
 
-func buildResources(cpu, mem string) corev1.ResourceRequirements {
+// resourceNvidiaGPU is the extended resource the NVIDIA device plugin advertises.
+const resourceNvidiaGPU corev1.ResourceName = "nvidia.com/gpu"
+
+func buildResources(cpu, mem string, gpu int) corev1.ResourceRequirements {
 	reqs := corev1.ResourceList{}
 	if q, err := resource.ParseQuantity(strings.TrimSpace(cpu)); err == nil && cpu != "" {
 		reqs[corev1.ResourceCPU] = q
@@ -1013,6 +1142,9 @@ func buildResources(cpu, mem string) corev1.ResourceRequirements {
 	if q, err := resource.ParseQuantity(strings.TrimSpace(mem)); err == nil && mem != "" {
 		reqs[corev1.ResourceMemory] = q
 	}
+	if gpu > 0 {
+		reqs[resourceNvidiaGPU] = *resource.NewQuantity(int64(gpu), resource.DecimalSI)
+	}
 	if len(reqs) == 0 {
 		return corev1.ResourceRequirements{}
 	}
@@ -129,7 +129,7 @@ func TestEnsureWorkspace_NoImagePullSecretsByDefault(t *testing.T) {
 func TestGitCloneInit_WithoutAuth(t *testing.T) {
 	t.Parallel()
 
-	c := gitCloneInit("https://gitlab.com/org/public.git", "main", "/home/workspace", "")
+	c := gitCloneInit("https://gitlab.com/org/public.git", "main", "", "/home/workspace", "")
 
 	assert.Empty(t, c.Env, "an anonymous clone needs no credentials in its environment")
 	require.Len(t, c.Command, 3)
@@ -140,7 +140,7 @@ func TestGitCloneInit_WithoutAuth(t *testing.T) {
 func TestGitCloneInit_WithAuth(t *testing.T) {
 	t.Parallel()
 
-	c := gitCloneInit("https://gitlab.com/org/private.git", "main", "/home/workspace", "gitcred")
+	c := gitCloneInit("https://gitlab.com/org/private.git", "main", "", "/home/workspace", "gitcred")
 
 	refs := envRefOf(c)
 	require.Contains(t, refs, "GIT_USERNAME")
@@ -165,6 +165,19 @@ func TestGitCloneInit_WithAuth(t *testing.T) {
 	assert.Contains(t, script, "${GIT_PASSWORD}")
 }
 
+func TestGitCloneInit_PinnedCommit(t *testing.T) {
+	t.Parallel()
+
+	c := gitCloneInit("https://gitlab.com/org/public.git", "main", "0123abc", "/home/workspace", "")
+	require.Len(t, c.Command, 3)
+	script := c.Command[2]
+	assert.Contains(t, script, "git -C /home/workspace checkout -q --detach '0123abc' && chown")
+	assert.Less(t, strings.Index(script, "clone"), strings.Index(script, "checkout"), "the commit is checked out after the clone")
+
+	c = gitCloneInit("https://gitlab.com/org/public.git", "main", "", "/home/workspace", "")
+	assert.NotContains(t, c.Command[2], "checkout", "an unpinned workspace stays on the branch head")
+}
+
 // The clone script is assembled by string formatting through two levels of shell
 // quoting, so its syntax is otherwise only verified by eye.
 func TestGitCloneInit_ScriptIsValidSh(t *testing.T) {
@@ -178,10 +191,12 @@ func TestGitCloneInit_ScriptIsValidSh(t *testing.T) {
 	tests := []struct {
 		name                     string
 		repo, branch, authSecret string
+		commit                   string
 	}{
 		{name: "anonymous", repo: "https://gitlab.com/org/p.git", branch: "main"},
 		{name: "authenticated", repo: "https://gitlab.com/org/p.git", branch: "main", authSecret: "gitcred"},
 		{name: "no branch", repo: "https://gitlab.com/org/p.git", authSecret: "gitcred"},
+		{name: "pinned commit", repo: "https://gitlab.com/org/p.git", branch: "main", commit: "0123456789abcdef0123456789abcdef01234567"},
 		// Shell metacharacters in admin-supplied values must not break out.
 		{name: "quote in branch", repo: "https://gitlab.com/org/p.git", branch: `it's; rm -rf /`, authSecret: "gitcred"},
 		{name: "quote in repo", repo: `https://gitlab.com/org/p.git'; touch /pwned; '`, branch: "main"},
@@ -190,7 +205,7 @@ func TestGitCloneInit_ScriptIsValidSh(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			c := gitCloneInit(tt.repo, tt.branch, "/home/workspace", tt.authSecret)
+			c := gitCloneInit(tt.repo, tt.branch, tt.commit, "/home/workspace", tt.authSecret)
 			require.Len(t, c.Command, 3)
 
 			cmd := exec.Command(sh, "-n", "-c", c.Command[2])
@@ -363,3 +378,21 @@ func TestGitCredentialHelper_Shape(t *testing.T) {
 	assert.Contains(t, gitCredentialHelper, "username=${GIT_USERNAME}")
 	assert.Contains(t, gitCredentialHelper, "password=${GIT_PASSWORD}")
 }
+
+func TestEnsureWorkspace_NodeSelectorAndTolerationsOnPodSpec(t *testing.T) {
+	b, cs := newTestBackend()
+
+	spec := plainGitSpec()
+	spec.NodeSelector = map[string]string{"pool": "workspaces"}
+	spec.Tolerations = []workspace.Toleration{{Key: "dedicated", Value: "workspaces", Effect: "NoSchedule"}}
+	ws, err := b.EnsureWorkspace(context.Background(), spec)
+	require.NoError(t, err)
+
+	dep, err := cs.AppsV1().Deployments("workshops").Get(context.Background(), ws.ID, metav1.GetOptions{})
+	require.NoError(t, err)
+	podSpec := dep.Spec.Template.Spec
+	assert.Equal(t, map[string]string{"pool": "workspaces"}, podSpec.NodeSelector)
+	assert.Equal(t, []corev1.Toleration{{
+		Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "workspaces", Effect: corev1.TaintEffectNoSchedule,
+	}}, podSpec.Tolerations)
+}
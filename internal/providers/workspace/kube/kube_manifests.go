@@ -0,0 +1,94 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"easylab/internal/providers/workspace"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// This file implements workspace.ManifestApplier: the baseline manifests an
+// admin applies to a lab's cluster once it is provisioned.
+
+var _ workspace.ManifestApplier = (*Backend)(nil)
+
+// manifestFieldManager owns the fields EasyLab sets through server-side apply.
+const manifestFieldManager = "easylab"
+
+// ApplyManifest applies each document of manifest in order, like kubectl apply
+// --server-side. It stops at the first object that fails: later documents often
+// depend on earlier ones (a namespace before its quota).
+func (b *Backend) ApplyManifest(ctx context.Context, manifest []byte) ([]string, error) {
+	if b.dynamic == nil || b.mapper == nil {
+		return nil, fmt.Errorf("this backend cannot apply manifests")
+	}
+
+	var applied []string
+	dec := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := dec.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return applied, nil
+			}
+			return applied, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue // empty document, e.g. a trailing "---"
+		}
+		if err := b.applyObject(ctx, obj); err != nil {
+			return applied, err
+		}
+		applied = append(applied, obj.GetKind()+"/"+obj.GetName())
+	}
+}
+
+func (b *Backend) applyObject(ctx context.Context, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	if gvk.Kind == "" || obj.GetName() == "" {
+		return fmt.Errorf("manifest object needs a kind and a metadata.name")
+	}
+	mapping, err := b.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// The kind may come from a CRD applied earlier in the same manifest,
+		// after discovery was cached.
+		b.mapper.Reset()
+		mapping, err = b.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("unknown kind %s: %w", gvk.String(), err)
+	}
+
+	var resource dynamic.ResourceInterface = b.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ns := obj.GetNamespace()
+		if ns == "" {
+			ns = b.namespace
+			obj.SetNamespace(ns)
+		}
+		resource = b.dynamic.Resource(mapping.Resource).Namespace(ns)
+	}
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	force := true
+	if _, err := resource.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: manifestFieldManager,
+		Force:        &force,
+	}); err != nil {
+		return fmt.Errorf("failed to apply %s/%s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// staticMapper is a fixed RESTMapper; Reset has no discovery cache to drop.
+type staticMapper struct{ meta.RESTMapper }
+
+func (staticMapper) Reset() {}
+
+type applyCall struct {
+	Resource, Namespace, Name string
+}
+
+// newManifestTestBackend returns a backend whose dynamic client records every
+// server-side apply instead of storing objects.
+func newManifestTestBackend() (*Backend, *[]applyCall) {
+	b, _ := newTestBackend()
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ResourceQuota"}, meta.RESTScopeNamespace)
+	b.mapper = staticMapper{mapper}
+
+	dyn := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	var calls []applyCall
+	dyn.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		calls = append(calls, applyCall{patch.GetResource().Resource, patch.GetNamespace(), patch.GetName()})
+		return true, nil, nil
+	})
+	b.dynamic = dyn
+	return b, &calls
+}
+
+func TestApplyManifest_AppliesDocumentsInOrder(t *testing.T) {
+	b, calls := newManifestTestBackend()
+
+	applied, err := b.ApplyManifest(context.Background(), []byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+---
+apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: quota
+  namespace: team-a
+---
+apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: default-quota
+---
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Namespace/team-a", "ResourceQuota/quota", "ResourceQuota/default-quota"}, applied)
+	assert.Equal(t, []applyCall{
+		{"namespaces", "", "team-a"},
+		{"resourcequotas", "team-a", "quota"},
+		{"resourcequotas", "workshops", "default-quota"},
+	}, *calls, "namespaced objects without a namespace go to the workspace namespace")
+}
+
+func TestApplyManifest_StopsAtUnknownKind(t *testing.T) {
+	b, calls := newManifestTestBackend()
+
+	applied, err := b.ApplyManifest(context.Background(), []byte(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-b
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Widget")
+	assert.Equal(t, []string{"Namespace/team-a"}, applied)
+	assert.Len(t, *calls, 1)
+}
+
+func TestApplyManifest_Unavailable(t *testing.T) {
+	b, _ := newTestBackend()
+	_, err := b.ApplyManifest(context.Background(), []byte("kind: Namespace"))
+	assert.Error(t, err, "a backend without a dynamic client cannot apply manifests")
+}
@@ -2,6 +2,7 @@ package kube
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -300,6 +301,32 @@ func TestEnsureWorkspace_CreatesResources(t *testing.T) {
 	}
 }
 
+func TestEnsureWorkspace_PVCStorageClass(t *testing.T) {
+	b, cs := newTestBackend()
+	ctx := context.Background()
+
+	ws, err := b.EnsureWorkspace(ctx, workspace.Spec{LabID: "job-1", Owner: "alice", DiskSize: "5Gi", StorageClass: "csi-cinder-high-speed"})
+	if err != nil {
+		t.Fatalf("EnsureWorkspace error: %v", err)
+	}
+	pvc, err := cs.CoreV1().PersistentVolumeClaims("workshops").Get(ctx, ws.ID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("pvc not created: %v", err)
+	}
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != "csi-cinder-high-speed" {
+		t.Errorf("pvc storage class = %v, want csi-cinder-high-speed", pvc.Spec.StorageClassName)
+	}
+
+	ws, err = b.EnsureWorkspace(ctx, workspace.Spec{LabID: "job-1", Owner: "bob", DiskSize: "5Gi"})
+	if err != nil {
+		t.Fatalf("EnsureWorkspace error: %v", err)
+	}
+	pvc, _ = cs.CoreV1().PersistentVolumeClaims("workshops").Get(ctx, ws.ID, metav1.GetOptions{})
+	if pvc.Spec.StorageClassName != nil {
+		t.Errorf("pvc storage class = %q, want the cluster default", *pvc.Spec.StorageClassName)
+	}
+}
+
 // TestEnsureWorkspace_AttributesTemplate pins that the template a workspace was
 // created from survives the round-trip: it is returned by EnsureWorkspace and
 // read back out of the cluster by ListWorkspaces, so the admin UI can correlate
@@ -423,6 +450,79 @@ func TestEnsureWorkspace_NoFallbackForHostnameOnlyLB(t *testing.T) {
 	}
 }
 
+// withNodePortIngress creates an ingress-nginx controller that never got a
+// LoadBalancer IP, with its HTTP NodePort, and a Ready node with an external IP.
+func withNodePortIngress(cs *fake.Clientset, fallback bool) {
+	annotations := map[string]string{}
+	if fallback {
+		annotations[nodePortFallbackAnnotation] = "true"
+	}
+	_, _ = cs.CoreV1().Services(ingressNginxNamespace).Create(context.Background(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: ingressNginxService, Namespace: ingressNginxNamespace, Annotations: annotations},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{
+			{Name: "http", Port: 80, NodePort: 31080},
+			{Name: "https", Port: 443, NodePort: 31443},
+		}},
+	}, metav1.CreateOptions{})
+	_, _ = cs.CoreV1().Nodes().Create(context.Background(), &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeExternalIP, Address: "57.128.1.2"}},
+		},
+	}, metav1.CreateOptions{})
+}
+
+// A lab that opted into the NodePort fallback is served on a node's IP and the
+// controller's NodePort while its load balancer has no IP.
+func TestEnsureWorkspace_NodePortFallback(t *testing.T) {
+	b, cs := newTestBackend()
+	withNodePortIngress(cs, true)
+	ctx := context.Background()
+
+	ws, err := b.EnsureWorkspace(ctx, workspace.Spec{LabID: "job-1", Owner: "dave", Token: "tok"})
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	wantHost := ws.ID + ".57.128.1.2.nip.io"
+	if ws.URL != "http://"+wantHost+":31080/" {
+		t.Errorf("expected the NodePort URL, got %q", ws.URL)
+	}
+	ing, err := cs.NetworkingV1().Ingresses("workshops").Get(ctx, ws.ID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected an ingress for the NodePort fallback: %v", err)
+	}
+	if ing.Spec.Rules[0].Host != wantHost {
+		t.Errorf("expected ingress host %q without the port, got %q", wantHost, ing.Spec.Rules[0].Host)
+	}
+
+	got, err := b.GetWorkspace(ctx, ws.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.URL != ws.URL {
+		t.Errorf("GetWorkspace URL %q does not match created URL %q", got.URL, ws.URL)
+	}
+	if domain, scheme := b.Routing(ctx, ""); domain != "57.128.1.2.nip.io:31080" || scheme != schemeHTTP {
+		t.Errorf("Routing() = (%q, %q), want the NodePort address over http", domain, scheme)
+	}
+}
+
+// Without the lab's opt-in, a controller with no LoadBalancer IP leaves the
+// workspace in-cluster, as before.
+func TestEnsureWorkspace_NoNodePortFallbackWithoutOptIn(t *testing.T) {
+	b, cs := newTestBackend()
+	withNodePortIngress(cs, false)
+
+	ws, err := b.EnsureWorkspace(context.Background(), workspace.Spec{LabID: "job-1", Owner: "dave", Token: "t"})
+	if err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if ws.URL != "" {
+		t.Errorf("expected no URL without the fallback, got %q", ws.URL)
+	}
+}
+
 // A configured domain keeps TLS: the fallback must not weaken real labs.
 func TestEnsureWorkspace_DomainKeepsHTTPSAndRequestsCert(t *testing.T) {
 	b, cs := newTestBackend()
@@ -701,6 +801,30 @@ func TestEnsureWorkspace_FSGroupAndGitBranch(t *testing.T) {
 	}
 }
 
+func TestEnsureWorkspace_GPU(t *testing.T) {
+	b, cs := newTestBackend()
+	ws, err := b.EnsureWorkspace(context.Background(), workspace.Spec{
+		LabID: "job-1", Owner: "hugo", Domain: "d", Token: "t", CPU: "2", Memory: "8Gi", GPU: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := ideContainer(t, cs, ws.ID)
+	if q, ok := c.Resources.Limits[resourceNvidiaGPU]; !ok || q.Value() != 1 {
+		t.Errorf("expected nvidia.com/gpu limit 1, got %v", c.Resources.Limits)
+	}
+
+	ws, err = b.EnsureWorkspace(context.Background(), workspace.Spec{
+		LabID: "job-1", Owner: "ines", Domain: "d", Token: "t", CPU: "2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ideContainer(t, cs, ws.ID).Resources.Limits[resourceNvidiaGPU]; ok {
+		t.Error("expected no GPU request when the template asks for none")
+	}
+}
+
 func TestEnsureWorkspace_ReadinessProbeAndProgressDeadline(t *testing.T) {
 	b, cs := newTestBackend()
 	ws, err := b.EnsureWorkspace(context.Background(), workspace.Spec{LabID: "job-1", Owner: "ivy", Domain: "d", Token: "t"})
@@ -768,3 +892,68 @@ func TestEnsureWorkspace_TwoTemplatesTwoWorkspaces(t *testing.T) {
 		t.Fatalf("expected 2 deployments, got %d", len(list.Items))
 	}
 }
+
+func TestEnsureWorkspace_ChosenName(t *testing.T) {
+	b, cs := newTestBackend()
+	ctx := context.Background()
+	spec := workspace.Spec{LabID: "job-1", Owner: "ivy", Template: "go", Name: "ivy-retry", Domain: "d", Token: "t"}
+
+	ws, err := b.EnsureWorkspace(ctx, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.ID != "ivy-retry" {
+		t.Fatalf("expected the chosen name as ID, got %q", ws.ID)
+	}
+	// Asking again for the same workspace is still idempotent.
+	if _, err := b.EnsureWorkspace(ctx, spec); err != nil {
+		t.Fatalf("second ensure should be a no-op: %v", err)
+	}
+	// The derived name sits alongside it, so the student can hold both.
+	spec.Name = ""
+	if _, err := b.EnsureWorkspace(ctx, spec); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := cs.AppsV1().Deployments("workshops").List(ctx, metav1.ListOptions{})
+	if len(list.Items) != 2 {
+		t.Fatalf("expected 2 deployments, got %d", len(list.Items))
+	}
+}
+
+func TestEnsureWorkspace_ChosenNameConflicts(t *testing.T) {
+	b, _ := newTestBackend()
+	ctx := context.Background()
+	if _, err := b.EnsureWorkspace(ctx, workspace.Spec{LabID: "job-1", Owner: "ivy", Template: "go", Name: "shared", Domain: "d", Token: "t"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		spec workspace.Spec
+	}{
+		{"another student", workspace.Spec{LabID: "job-1", Owner: "jack", Template: "go", Name: "shared"}},
+		{"another lab", workspace.Spec{LabID: "job-2", Owner: "ivy", Template: "go", Name: "shared"}},
+		{"another template", workspace.Spec{LabID: "job-1", Owner: "ivy", Template: "docker", Name: "shared"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := b.EnsureWorkspace(ctx, tt.spec)
+			if !errors.Is(err, workspace.ErrNameTaken) {
+				t.Fatalf("expected ErrNameTaken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEnsureWorkspace_RejectsInvalidName(t *testing.T) {
+	b, cs := newTestBackend()
+	for _, name := range []string{"Bad_Name", "-lead", "trail-", "double--hyphen", strings.Repeat("a", workspace.MaxNameLength+1)} {
+		if _, err := b.EnsureWorkspace(context.Background(), workspace.Spec{LabID: "job-1", Owner: "ivy", Name: name}); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+	list, _ := cs.AppsV1().Deployments("workshops").List(context.Background(), metav1.ListOptions{})
+	if len(list.Items) != 0 {
+		t.Fatalf("invalid names must not create anything, got %d deployments", len(list.Items))
+	}
+}
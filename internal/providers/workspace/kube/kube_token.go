@@ -0,0 +1,63 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	"easylab/internal/providers/workspace"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// This file implements workspace.TokenRotator: a new connection token for a
+// student who lost theirs.
+
+var _ workspace.TokenRotator = (*Backend)(nil)
+
+// RotateToken writes token to the workspace's PASSWORD env var and token
+// annotation. Changing the pod template rolls the IDE pod; a workspace with a
+// PVC keeps its files, one without starts over from its image.
+func (b *Backend) RotateToken(ctx context.Context, labID, id, token string) (workspace.Workspace, error) {
+	dep, err := b.client.AppsV1().Deployments(b.namespace).Get(ctx, id, metav1.GetOptions{})
+	if err != nil {
+		return workspace.Workspace{}, fmt.Errorf("failed to look up workspace %s: %w", id, err)
+	}
+	// The id comes from a lab's own listing, but a deployment of another lab must
+	// never be touched through this one.
+	if dep.Labels[labelLabID] != sanitizeDNS(labID) {
+		return workspace.Workspace{}, fmt.Errorf("workspace %s is not part of lab %s", id, labID)
+	}
+
+	found := false
+	containers := dep.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name != workspaceContainerName {
+			continue
+		}
+		found = true
+		set := false
+		for j := range containers[i].Env {
+			if containers[i].Env[j].Name == "PASSWORD" {
+				containers[i].Env[j].Value = token
+				set = true
+			}
+		}
+		if !set {
+			containers[i].Env = append(containers[i].Env, corev1.EnvVar{Name: "PASSWORD", Value: token})
+		}
+	}
+	if !found {
+		return workspace.Workspace{}, fmt.Errorf("workspace %s has no %s container", id, workspaceContainerName)
+	}
+	if dep.Annotations == nil {
+		dep.Annotations = map[string]string{}
+	}
+	dep.Annotations[annotationToken] = token
+
+	updated, err := b.client.AppsV1().Deployments(b.namespace).Update(ctx, dep, metav1.UpdateOptions{})
+	if err != nil {
+		return workspace.Workspace{}, fmt.Errorf("failed to rotate the token of workspace %s: %w", id, err)
+	}
+	return b.toWorkspace(updated, domainFromDeployment(updated), token), nil
+}
@@ -0,0 +1,53 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRotateToken(t *testing.T) {
+	b, cs := newTestBackend()
+	ctx := context.Background()
+	ws, err := b.EnsureWorkspace(ctx, workspace.Spec{LabID: "job-1", Owner: "alice", Domain: "lab.example.com", Token: "old"})
+	require.NoError(t, err)
+
+	rotated, err := b.RotateToken(ctx, "job-1", ws.ID, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "new", rotated.Token)
+	assert.Equal(t, ws.URL, rotated.URL, "the routing is kept")
+
+	var password []string
+	for _, e := range ideContainer(t, cs, ws.ID).Env {
+		if e.Name == "PASSWORD" {
+			password = append(password, e.Value)
+		}
+	}
+	assert.Equal(t, []string{"new"}, password)
+
+	// The workspace is handed back with the new token from now on.
+	again, err := b.EnsureWorkspace(ctx, workspace.Spec{LabID: "job-1", Owner: "alice", Domain: "lab.example.com", Token: "other"})
+	require.NoError(t, err)
+	assert.Equal(t, "new", again.Token)
+}
+
+func TestRotateToken_OtherLab(t *testing.T) {
+	b, cs := newTestBackend()
+	ctx := context.Background()
+	ws, err := b.EnsureWorkspace(ctx, workspace.Spec{LabID: "job-1", Owner: "alice", Domain: "d", Token: "old"})
+	require.NoError(t, err)
+
+	_, err = b.RotateToken(ctx, "job-2", ws.ID, "new")
+	require.Error(t, err)
+	dep, err := cs.AppsV1().Deployments("workshops").Get(ctx, ws.ID, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "old", tokenFromDeployment(dep))
+
+	_, err = b.RotateToken(ctx, "job-1", "missing", "new")
+	assert.Error(t, err)
+}
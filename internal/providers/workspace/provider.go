@@ -9,6 +9,9 @@ package workspace
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
 	"time"
 )
 
@@ -22,12 +25,40 @@ const (
 	PhaseFailed         = "agents_failed"
 )
 
+// MaxNameLength is the longest workspace name a student may choose. It follows
+// Coder's workspace naming rules, which students already know from other labs.
+const MaxNameLength = 32
+
+// validName is Coder's workspace name rule restricted to lowercase, since the name
+// is also the Kubernetes resource name and the workspace hostname.
+var validName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ErrNameTaken is returned by EnsureWorkspace when Spec.Name is already used by a
+// workspace that belongs to another student, lab or template.
+var ErrNameTaken = errors.New("workspace name is already taken")
+
+// ValidateName checks a student-chosen workspace name: lowercase letters, digits
+// and single hyphens, not starting or ending with a hyphen, at most
+// MaxNameLength characters.
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("workspace name cannot be empty")
+	}
+	if len(name) > MaxNameLength {
+		return fmt.Errorf("workspace name must be at most %d characters", MaxNameLength)
+	}
+	if !validName.MatchString(name) {
+		return fmt.Errorf("workspace name may only contain lowercase letters, digits and single hyphens, and must start and end with a letter or digit")
+	}
+	return nil
+}
+
 // Workspace is a single student IDE environment running on the cluster.
 type Workspace struct {
 	// ID uniquely identifies the workspace within its lab. It is also the
 	// Kubernetes resource name, so it is safe to use for deletion.
 	ID string `json:"id"`
-	// Name is the human-facing workspace name (currently equal to ID).
+	// Name is the human-facing workspace name (equal to ID).
 	Name string `json:"name"`
 	// Owner is the sanitized student username the workspace belongs to.
 	Owner string `json:"owner"`
@@ -81,6 +112,13 @@ type Mount struct {
 	Path string
 }
 
+// Toleration lets the workspace pod run on nodes with the matching taint.
+type Toleration struct {
+	Key    string
+	Value  string
+	Effect string // "NoSchedule" | "PreferNoSchedule" | "NoExecute"
+}
+
 // DevcontainerSpec builds the workspace image from the repo's devcontainer.json
 // rather than running Spec.Image directly. The build happens inside the
 // student's own pod on first start, which is what lets a workshop's Dockerfile
@@ -111,17 +149,25 @@ type Spec struct {
 	LabID    string // owning lab/job ID, stored as a label for selection
 	Owner    string // sanitized student username
 	Template string // template name — part of the workspace identity (one workspace per template)
+	// Name is the workspace name chosen by the student (see ValidateName). When
+	// empty, a name is derived from the lab, owner and template.
+	Name string
 
 	IDE       string            // IDE base; only "code-server" is supported (empty = default)
 	Image     string            // container image override (empty = IDE default)
 	GitRepo   string            // optional git repo cloned into the workspace on first start
 	GitBranch string            // optional branch to clone (default branch when empty)
+	GitCommit string            // optional commit checked out after the clone (branch head when empty)
 	GitFolder string            // optional subfolder the IDE opens (repo root when empty)
 	CPU       string            // optional CPU request/limit (e.g. "500m")
 	Memory    string            // optional memory request/limit (e.g. "1Gi")
 	DiskSize  string            // PVC size (e.g. "5Gi"); empty means no persistent volume
+	GPU       int               // NVIDIA GPUs requested (nvidia.com/gpu); 0 means none
 	Env       map[string]string // extra environment variables for the IDE container
 
+	// StorageClass is the StorageClass of the PVC; empty uses the cluster default.
+	StorageClass string
+
 	StartupScript string   // best-effort setup run before the IDE starts
 	DotfilesRepo  string   // dotfiles repo cloned + install script run
 	Extensions    []string // VS Code extensions installed on start
@@ -143,6 +189,11 @@ type Spec struct {
 	// devcontainer.json instead of using Image.
 	Devcontainer *DevcontainerSpec
 
+	// NodeSelector and Tolerations pin the workspace to the lab's node pool when
+	// that pool is labelled and tainted for workload isolation.
+	NodeSelector map[string]string
+	Tolerations  []Toleration
+
 	Domain            string // base domain; the workspace host is "{ID}.{Domain}"
 	WildcardTLSSecret string // pre-provisioned wildcard TLS secret; when empty a per-host cert is requested
 	ClusterIssuer     string // cert-manager ClusterIssuer used for per-host certs
@@ -189,6 +240,31 @@ type AuthSecret struct {
 	Username string   `json:"username,omitempty"`
 }
 
+// ManifestApplier applies an admin's own Kubernetes manifests to the lab's
+// cluster, such as the namespaces, quotas and RBAC a lab depends on.
+//
+// Like SecretManager it is optional and reached by type assertion: a backend
+// that cannot apply arbitrary objects does not implement it.
+type ManifestApplier interface {
+	// ApplyManifest server-side applies every object of a YAML or JSON manifest,
+	// which may hold several documents. Namespaced objects without a namespace
+	// go to the workspace namespace. It returns the "Kind/name" of each object
+	// applied, including those applied before an error stopped it.
+	ApplyManifest(ctx context.Context, manifest []byte) ([]string, error)
+}
+
+// TokenRotator replaces the connection token of an existing workspace, for a
+// student who lost the one shown when it was created. The old token stops
+// working; the workspace's files are kept.
+//
+// Like SecretManager it is optional and reached by type assertion: without it
+// a student who lost their token deletes the workspace and requests a new one.
+type TokenRotator interface {
+	// RotateToken sets token as the connection token of the workspace id of a
+	// lab and returns the workspace with it. The IDE restarts to pick it up.
+	RotateToken(ctx context.Context, labID, id, token string) (Workspace, error)
+}
+
 // SecretManager materializes the credential Secrets that templates reference by
 // name, so an admin can add a registry or git token without kubectl access to the
 // lab's cluster.
@@ -113,7 +113,7 @@ func CreateLabProgram(jobDir string) pulumi.RunFunc {
 					return fmt.Errorf("failed to initialize network infrastructure: %w", err)
 				}
 
-				kubeCluster, err := ovh.InitManagedKubernetesClusterWithNetwork(ctx, serviceName, netInfra)
+				kubeCluster, kubeconfig, err := ovh.InitManagedKubernetesClusterWithNetwork(ctx, serviceName, netInfra)
 				if err != nil {
 					return fmt.Errorf("failed to create Kubernetes cluster: %w", err)
 				}
@@ -122,20 +122,41 @@ func CreateLabProgram(jobDir string) pulumi.RunFunc {
 				if err != nil {
 					return fmt.Errorf("failed to create node pools: %w", err)
 				}
+				// Helm only starts once the nodes are Ready, so a node stuck
+				// installing fails here with its own condition.
+				expectedNodes := utils.NodePoolConfigInt(ctx, utils.NodePoolDesiredNodeCount) * len(nodepool)
+				kubeconfig = k8s.WaitForNodesReady(ctx, kubeconfig, expectedNodes,
+					utils.NodePoolReadyTimeoutOrDefault(ctx, k8s.DefaultNodeReadyTimeout), nodepool)
 
-				k8sProvider, err = k8s.InitK8sProvider(ctx, kubeCluster, nodepool)
+				k8sProvider, err = k8s.InitK8sProvider(ctx, kubeconfig, kubeCluster, nodepool)
 				if err != nil {
 					return fmt.Errorf("failed to create Kubernetes provider: %w", err)
 				}
-				kubeconfigOut = kubeCluster.Kubeconfig
+				kubeconfigOut = kubeconfig
+
+				if utils.NodePoolConfigBool(ctx, utils.NodePoolGPU) {
+					utils.LogInfo(ctx, "GPU flavor selected, installing the NVIDIA device plugin...")
+					if _, err := k8s.InitNvidiaDevicePlugin(ctx, k8sProvider); err != nil {
+						return err
+					}
+				}
 			}
 		}
 
+		// The server creates the workspaces' volumes later on: a storage class
+		// the cluster lacks fails the deployment here instead.
+		if storageClass := utils.K8sConfigOptional(ctx, utils.K8sStorageClass); storageClass != "" {
+			kubeconfigOut = k8s.CheckStorageClass(ctx, kubeconfigOut, storageClass)
+		}
+
 		utils.LogInfo(ctx, "Creating workspace namespace...")
 		workspaceNs, err := k8s.InitNamespace(ctx, k8sProvider)
 		if err != nil {
 			return fmt.Errorf("failed to create namespace: %w", err)
 		}
+		if err := k8s.InitWorkspaceQuota(ctx, k8sProvider, workspaceNs); err != nil {
+			return err
+		}
 
 		// Install the ingress controller so the per-student workspace ingresses the
 		// server creates at runtime can be routed. With a domain this also brings up
@@ -152,6 +173,15 @@ func CreateLabProgram(jobDir string) pulumi.RunFunc {
 			ctx.Export("domain", pulumi.String(domain))
 		}
 
+		if utils.MonitoringEnabledConfig(ctx) {
+			utils.LogInfo(ctx, "Installing the monitoring stack...")
+			grafanaURL, err := coder.SetupMonitoring(ctx, k8sProvider, ingressIP)
+			if err != nil {
+				return fmt.Errorf("failed to setup monitoring: %w", err)
+			}
+			ctx.Export("grafanaURL", grafanaURL)
+		}
+
 		utils.LogInfo(ctx, "Infrastructure setup completed!")
 		return nil
 	}
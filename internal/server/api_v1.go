@@ -0,0 +1,595 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// apiAccess is who may call a route of the JSON API.
+type apiAccess string
+
+const (
+	apiAdmin   apiAccess = "admin"
+	apiStudent apiAccess = "student"
+	apiPublic  apiAccess = "public"
+)
+
+// apiParam is a query parameter of an API route.
+type apiParam struct {
+	Name        string
+	Description string
+}
+
+// apiRoute is a route of the JSON API under /api/v1. RegisterAPIv1 registers
+// the routes and the OpenAPI document is generated from them, so the two cannot
+// drift apart.
+type apiRoute struct {
+	Method string
+	// Path is a ServeMux path, whose {wildcards} are the path parameters.
+	Path    string
+	ID      string // OpenAPI operationId
+	Summary string
+	Access  apiAccess
+	Query   []apiParam
+	// Request and Response are values of the JSON bodies, described in the
+	// OpenAPI document by reflection; nil for none.
+	Request  any
+	Response any
+	// OptionalBody accepts a call without the Request body.
+	OptionalBody bool
+	// Status is the status of a successful call, 200 when zero.
+	Status int
+	// ContentType is the response's, application/json when empty.
+	ContentType string
+	handle      func(h *Handler, w http.ResponseWriter, r *http.Request)
+}
+
+func (rt apiRoute) status() int {
+	if rt.Status == 0 {
+		return http.StatusOK
+	}
+	return rt.Status
+}
+
+// apiLab is a lab as the lab endpoints answer with, when the whole job is not
+// needed.
+type apiLab struct {
+	ID        string    `json:"id"`
+	Status    JobStatus `json:"status"`
+	StackName string    `json:"stack_name"`
+	Event     string    `json:"event,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Error     string    `json:"error,omitempty"`
+	// Warnings are about a configuration that deploys but will not work; only
+	// set on creation.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func newAPILab(job *Job) apiLab {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	lab := apiLab{ID: job.ID, Status: job.Status, CreatedAt: job.CreatedAt, UpdatedAt: job.UpdatedAt, Error: job.Error}
+	if job.Config != nil {
+		lab.StackName = job.Config.StackName
+		lab.Event = job.Config.Event
+		lab.Provider = job.Config.Provider
+	}
+	return lab
+}
+
+// apiLaunchRequest is the optional body of a launch.
+type apiLaunchRequest struct {
+	// OverrideQuota launches a lab the dry run found short of quota.
+	OverrideQuota bool `json:"override_quota,omitempty"`
+}
+
+// apiCredentials are the credentials of a provider, as PUT on
+// /api/v1/credentials/{provider} takes them: the fields of the provider's
+// credentials, the others left out.
+type apiCredentials struct {
+	// OVH
+	ApplicationKey    string `json:"application_key,omitempty"`
+	ApplicationSecret string `json:"application_secret,omitempty"`
+	ConsumerKey       string `json:"consumer_key,omitempty"`
+	ServiceName       string `json:"service_name,omitempty"`
+	Endpoint          string `json:"endpoint,omitempty"`
+	// ReplaceAll replaces every OVH key; otherwise a key left out keeps its
+	// stored value.
+	ReplaceAll bool `json:"replace_all,omitempty"`
+	// Azure
+	ClientID       string `json:"client_id,omitempty"`
+	ClientSecret   string `json:"client_secret,omitempty"`
+	TenantID       string `json:"tenant_id,omitempty"`
+	SubscriptionID string `json:"subscription_id,omitempty"`
+}
+
+// apiError is the body of every error of the JSON API.
+type apiError struct {
+	Error string `json:"error"`
+	Title string `json:"title,omitempty"`
+	// QuotaReport is why a launch was refused over quota.
+	QuotaReport *QuotaReport `json:"quota_report,omitempty"`
+}
+
+// apiV1Routes lists the JSON API. It is a function rather than a variable as
+// the OpenAPI route refers back to it.
+func apiV1Routes() []apiRoute {
+	return []apiRoute{
+		{Method: http.MethodGet, Path: "/api/v1/openapi.json", ID: "getOpenAPI", Access: apiPublic,
+			Summary: "This document", Response: map[string]any{}, handle: (*Handler).apiOpenAPI},
+
+		{Method: http.MethodGet, Path: "/api/v1/labs", ID: "listLabs", Access: apiAdmin,
+			Summary:  "List the labs, newest first, without the template jobs",
+			Query:    []apiParam{{"event", "Only the labs of this event"}},
+			Response: []apiLab{}, handle: (*Handler).apiListLabs},
+		{Method: http.MethodPost, Path: "/api/v1/labs", ID: "createLab", Access: apiAdmin,
+			Summary: "Create a lab from its config, in JSON or YAML",
+			Query:   []apiParam{{"force", "true creates the lab even when an active lab has the same config"}},
+			Request: LabConfig{}, Response: apiLab{}, Status: http.StatusAccepted, handle: (*Handler).apiCreateLab},
+		{Method: http.MethodPost, Path: "/api/v1/labs/dry-run", ID: "dryRunLab", Access: apiAdmin,
+			Summary: "Preview a lab from its config, to launch it once the dry run completes",
+			Query:   []apiParam{{"force", "true creates the dry run even when an active lab has the same config"}},
+			Request: LabConfig{}, Response: apiLab{}, Status: http.StatusAccepted, handle: (*Handler).apiDryRunLab},
+		{Method: http.MethodGet, Path: "/api/v1/labs/{id}", ID: "getLab", Access: apiAdmin,
+			Summary: "Get a lab, its config without secrets", Response: redactedJob{}, handle: (*Handler).apiGetLab},
+		{Method: http.MethodPost, Path: "/api/v1/labs/{id}/launch", ID: "launchLab", Access: apiAdmin,
+			Summary: "Launch a lab whose dry run completed",
+			Request: apiLaunchRequest{}, OptionalBody: true, Response: apiLab{}, Status: http.StatusAccepted, handle: (*Handler).apiLaunchLab},
+		{Method: http.MethodPost, Path: "/api/v1/labs/{id}/destroy", ID: "destroyLab", Access: apiAdmin,
+			Summary: "Destroy a lab's stack", Response: apiLab{}, Status: http.StatusAccepted, handle: (*Handler).apiDestroyLab},
+		{Method: http.MethodGet, Path: "/api/v1/jobs/export", ID: "exportJobs", Access: apiAdmin,
+			Summary: "Export every job as newline-delimited JSON, oldest first",
+			Query: []apiParam{
+				{"since", "Only the jobs updated at or after this RFC 3339 time"},
+				{"event", "Only the jobs of this event"},
+				{"fields", "Comma-separated top-level keys to keep"},
+			},
+			Response: redactedJob{}, ContentType: "application/x-ndjson", handle: (*Handler).ExportJobs},
+
+		{Method: http.MethodGet, Path: "/api/v1/credentials/{provider}", ID: "getCredentials", Access: apiAdmin,
+			Summary:  "Tell whether a provider's credentials are configured, without their secrets",
+			Query:    []apiParam{{"test", "true also tries the OVH keys against the OVH API"}},
+			Response: map[string]any{}, handle: (*Handler).apiGetCredentials},
+		{Method: http.MethodPut, Path: "/api/v1/credentials/{provider}", ID: "putCredentials", Access: apiAdmin,
+			Summary: "Set a provider's credentials",
+			Request: apiCredentials{}, Response: map[string]any{}, handle: (*Handler).apiPutCredentials},
+
+		{Method: http.MethodGet, Path: "/api/v1/student/dashboard", ID: "getStudentDashboard", Access: apiStudent,
+			Summary: "Get the student dashboard: the banner, the labs and the student's workspaces", Response: studentDashboard{}, handle: (*Handler).GetStudentDashboard},
+		{Method: http.MethodGet, Path: "/api/v1/student/labs", ID: "listStudentLabs", Access: apiStudent,
+			Summary: "List the labs a student can request a workspace in", Response: []studentLab{}, handle: (*Handler).ListLabs},
+		{Method: http.MethodPost, Path: "/api/v1/student/workspaces", ID: "createStudentWorkspace", Access: apiStudent,
+			Summary: "Create the student's workspace in a lab, or find it again",
+			Request: studentWorkspaceRequest{}, Response: studentWorkspace{}, Status: http.StatusCreated, handle: (*Handler).apiCreateStudentWorkspace},
+		{Method: http.MethodGet, Path: "/api/v1/student/workspaces/{lab}/{name}", ID: "getStudentWorkspace", Access: apiStudent,
+			Summary: "Get the readiness of one of the student's workspaces", Response: studentWorkspaceState{}, handle: (*Handler).apiStudentWorkspaceStatus},
+		{Method: http.MethodDelete, Path: "/api/v1/student/workspaces/{lab}/{name}", ID: "deleteStudentWorkspace", Access: apiStudent,
+			Summary: "Delete one of the student's workspaces", Status: http.StatusNoContent, handle: (*Handler).apiDeleteStudentWorkspace},
+	}
+}
+
+// RegisterAPIv1 registers the JSON API on mux, behind requireAdmin or
+// requireStudent as each route requires.
+func (h *Handler) RegisterAPIv1(mux *http.ServeMux, requireAdmin, requireStudent func(http.HandlerFunc) http.HandlerFunc) {
+	for _, rt := range apiV1Routes() {
+		handle := rt.handle
+		var fn http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) { handle(h, w, r) }
+		switch rt.Access {
+		case apiAdmin:
+			fn = requireAdmin(fn)
+		case apiStudent:
+			fn = requireStudent(fn)
+		}
+		mux.HandleFunc(rt.Method+" "+rt.Path, fn)
+	}
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, err *requestError) {
+	writeAPIJSON(w, err.Status, apiError{Error: err.Message, Title: err.Title})
+}
+
+// decodeAPIBody reads a JSON body into v. Unknown keys are errors, like in a
+// lab config; an empty body leaves v as it is.
+func decodeAPIBody(w http.ResponseWriter, r *http.Request, v any) *requestError {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return badRequest("Invalid Request Body", err)
+	}
+	return nil
+}
+
+func (h *Handler) apiOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, apiV1Document())
+}
+
+func (h *Handler) apiListLabs(w http.ResponseWriter, r *http.Request) {
+	labs := []apiLab{}
+	for _, job := range filterJobsByEvent(h.jobManager.GetLabs(), r.URL.Query().Get("event")) {
+		labs = append(labs, newAPILab(job))
+	}
+	writeAPIJSON(w, http.StatusOK, labs)
+}
+
+func (h *Handler) apiCreateLab(w http.ResponseWriter, r *http.Request) {
+	h.apiStartLab(w, r, false)
+}
+
+func (h *Handler) apiDryRunLab(w http.ResponseWriter, r *http.Request) {
+	h.apiStartLab(w, r, true)
+}
+
+// apiStartLab creates a lab from the config in the request body. The provider
+// credentials are the stored ones, as in the wizard.
+func (h *Handler) apiStartLab(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	if reqErr := h.maintenanceError(); reqErr != nil {
+		w.Header().Set("Retry-After", "600")
+		writeAPIError(w, reqErr)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLabConfigSize))
+	if err != nil {
+		writeAPIError(w, &requestError{Status: http.StatusRequestEntityTooLarge, Title: "Invalid Lab Config", Message: "Config too large or unreadable"})
+		return
+	}
+	cfg, err := ParseLabConfig(data)
+	if err != nil {
+		writeAPIError(w, badRequest("Invalid Lab Config", err))
+		return
+	}
+	if errs := cfg.Validate(); len(errs) > 0 {
+		writeAPIError(w, badRequest("Invalid Lab Config", errors.Join(errs...)))
+		return
+	}
+	if !cfg.UseExistingCluster {
+		if cfg.Provider == "" {
+			cfg.Provider = "ovh"
+		}
+		creds, err := h.credentialsManager.GetCredentials(cfg.Provider)
+		if err != nil {
+			writeAPIError(w, &requestError{
+				Status:  http.StatusConflict,
+				Title:   "Credentials Not Configured",
+				Message: fmt.Sprintf("Configure the %s credentials first (PUT /api/v1/credentials/%s).", cfg.Provider, cfg.Provider),
+			})
+			return
+		}
+		cfg.setProviderCredentials(creds)
+	}
+
+	started, reqErr := h.startLab(labRequest{Config: cfg, DryRun: dryRun, Force: r.URL.Query().Get("force") == "true"})
+	if reqErr != nil {
+		writeAPIError(w, reqErr)
+		return
+	}
+	job, _ := h.jobManager.GetJob(started.JobID)
+	lab := newAPILab(job)
+	lab.Warnings = started.Warnings
+	writeAPIJSON(w, http.StatusAccepted, lab)
+}
+
+func (h *Handler) apiGetLab(w http.ResponseWriter, r *http.Request) {
+	job, exists := h.jobManager.GetJob(r.PathValue("id"))
+	if !exists {
+		writeAPIError(w, &requestError{Status: http.StatusNotFound, Title: "Lab Not Found", Message: "Lab not found"})
+		return
+	}
+	costEstimate := h.labCostEstimate(job, time.Now())
+
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	writeAPIJSON(w, http.StatusOK, redactedJob{Job: job, Config: job.Config.redacted(), CostEstimate: costEstimate})
+}
+
+func (h *Handler) apiLaunchLab(w http.ResponseWriter, r *http.Request) {
+	if reqErr := h.maintenanceError(); reqErr != nil {
+		w.Header().Set("Retry-After", "600")
+		writeAPIError(w, reqErr)
+		return
+	}
+	var req apiLaunchRequest
+	if reqErr := decodeAPIBody(w, r, &req); reqErr != nil {
+		writeAPIError(w, reqErr)
+		return
+	}
+	jobID := r.PathValue("id")
+	quotaReport, reqErr := h.launchLab(jobID, req.OverrideQuota, r.RemoteAddr)
+	if reqErr != nil {
+		writeAPIJSON(w, reqErr.Status, apiError{Error: reqErr.Message, Title: reqErr.Title, QuotaReport: quotaReport})
+		return
+	}
+	job, _ := h.jobManager.GetJob(jobID)
+	writeAPIJSON(w, http.StatusAccepted, newAPILab(job))
+}
+
+func (h *Handler) apiDestroyLab(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if reqErr := h.destroyLab(jobID); reqErr != nil {
+		writeAPIError(w, reqErr)
+		return
+	}
+	job, _ := h.jobManager.GetJob(jobID)
+	writeAPIJSON(w, http.StatusAccepted, newAPILab(job))
+}
+
+func (h *Handler) apiGetCredentials(w http.ResponseWriter, r *http.Request) {
+	status, code := h.credentialsStatus(r.PathValue("provider"), r.URL.Query().Get("test") == "true")
+	writeAPIJSON(w, code, status)
+}
+
+// apiPutCredentials sets a provider's credentials and answers with their
+// status, along with the OVH keys that changed.
+func (h *Handler) apiPutCredentials(w http.ResponseWriter, r *http.Request) {
+	var req apiCredentials
+	if reqErr := decodeAPIBody(w, r, &req); reqErr != nil {
+		writeAPIError(w, reqErr)
+		return
+	}
+
+	provider := r.PathValue("provider")
+	var changed []string
+	var err error
+	switch provider {
+	case "ovh":
+		changed, err = h.credentialsManager.UpdateOVHCredentials(&OVHCredentials{
+			ApplicationKey:    req.ApplicationKey,
+			ApplicationSecret: req.ApplicationSecret,
+			ConsumerKey:       req.ConsumerKey,
+			ServiceName:       req.ServiceName,
+			Endpoint:          req.Endpoint,
+		}, req.ReplaceAll)
+	case "azure":
+		err = h.credentialsManager.SetCredentials(&AzureCredentials{
+			ClientID:       req.ClientID,
+			ClientSecret:   req.ClientSecret,
+			TenantID:       req.TenantID,
+			SubscriptionID: req.SubscriptionID,
+		})
+	default:
+		writeAPIError(w, &requestError{Status: http.StatusNotFound, Title: "Unsupported Provider", Message: fmt.Sprintf("Provider %q is not supported", provider)})
+		return
+	}
+	if err != nil {
+		writeAPIError(w, badRequest("Failed to Save Credentials", err))
+		return
+	}
+	slog.InfoContext(r.Context(), "Credentials saved through the API", "provider", provider)
+
+	status, code := h.credentialsStatus(provider, false)
+	if provider == "ovh" {
+		status["changed"] = append([]string{}, changed...)
+	}
+	writeAPIJSON(w, code, status)
+}
+
+func (h *Handler) apiCreateStudentWorkspace(w http.ResponseWriter, r *http.Request) {
+	email := studentEmailFromContext(r)
+	if email == "" {
+		writeAPIError(w, &requestError{Status: http.StatusUnauthorized, Title: "Not Logged In", Message: "Session email not found, please log in again"})
+		return
+	}
+	var req studentWorkspaceRequest
+	if reqErr := decodeAPIBody(w, r, &req); reqErr != nil {
+		writeAPIError(w, reqErr)
+		return
+	}
+	ws, reqErr := h.createStudentWorkspace(r.Context(), email, req)
+	if reqErr != nil {
+		writeAPIError(w, reqErr)
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, ws)
+}
+
+func (h *Handler) apiStudentWorkspaceStatus(w http.ResponseWriter, r *http.Request) {
+	owner := usernameFromEmail(studentEmailFromContext(r))
+	if owner == "" {
+		writeAPIError(w, &requestError{Status: http.StatusUnauthorized, Title: "Not Logged In", Message: "Session email not found, please log in again"})
+		return
+	}
+	st := h.studentWorkspaceStatus(r.Context(), owner, r.PathValue("lab"), r.PathValue("name"))
+	if st.Status == "unknown" {
+		writeAPIError(w, &requestError{Status: http.StatusNotFound, Title: "Lab Not Found", Message: "Lab not found"})
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, st)
+}
+
+func (h *Handler) apiDeleteStudentWorkspace(w http.ResponseWriter, r *http.Request) {
+	email := studentEmailFromContext(r)
+	if usernameFromEmail(email) == "" {
+		writeAPIError(w, &requestError{Status: http.StatusUnauthorized, Title: "Not Logged In", Message: "Session email not found, please log in again"})
+		return
+	}
+	if reqErr := h.deleteStudentWorkspace(r.Context(), email, r.PathValue("lab"), r.PathValue("name")); reqErr != nil {
+		writeAPIError(w, reqErr)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pathParamPattern matches the {wildcards} of a route path.
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// apiV1Document generates the OpenAPI 3 document of the JSON API from its
+// routes.
+func apiV1Document() map[string]any {
+	schemas := &apiSchemas{components: make(map[string]any)}
+	schemas.components["apiError"] = schemas.object(reflect.TypeFor[apiError]())
+
+	paths := make(map[string]map[string]any)
+	for _, rt := range apiV1Routes() {
+		var params []map[string]any
+		for _, m := range pathParamPattern.FindAllStringSubmatch(rt.Path, -1) {
+			params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		for _, q := range rt.Query {
+			params = append(params, map[string]any{"name": q.Name, "in": "query", "description": q.Description, "schema": map[string]any{"type": "string"}})
+		}
+
+		success := map[string]any{"description": http.StatusText(rt.status())}
+		if rt.Response != nil {
+			contentType := rt.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			success["content"] = map[string]any{contentType: map[string]any{"schema": schemas.schema(reflect.TypeOf(rt.Response))}}
+		}
+		op := map[string]any{
+			"operationId": rt.ID,
+			"summary":     rt.Summary,
+			"tags":        []string{string(rt.Access)},
+			"responses": map[string]any{
+				fmt.Sprint(rt.status()): success,
+				"default": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/apiError"}}},
+				},
+			},
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if rt.Request != nil {
+			op["requestBody"] = map[string]any{
+				"required": !rt.OptionalBody,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(rt.Request))}},
+			}
+		}
+		switch rt.Access {
+		case apiAdmin:
+			op["security"] = []map[string][]string{{"adminSession": {}}}
+		case apiStudent:
+			op["security"] = []map[string][]string{{"studentSession": {}}}
+		}
+
+		if paths[rt.Path] == nil {
+			paths[rt.Path] = make(map[string]any)
+		}
+		paths[rt.Path][strings.ToLower(rt.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "EasyLab API",
+			"version":     "1",
+			"description": "Labs, provider credentials and student workspaces. Calls are authenticated by the session cookie of the admin or student portal.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"adminSession":   map[string]any{"type": "apiKey", "in": "cookie", "name": SessionCookieName},
+				"studentSession": map[string]any{"type": "apiKey", "in": "cookie", "name": StudentSessionCookieName},
+			},
+		},
+	}
+}
+
+// apiSchemas builds the JSON schemas of Go types, the way encoding/json
+// encodes them. Named structs become components, referred to by name.
+type apiSchemas struct {
+	components map[string]any
+}
+
+func (s *apiSchemas) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeFor[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == reflect.TypeFor[json.RawMessage]():
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			s.components[t.Name()] = nil // a struct that refers to itself stops here
+			s.components[t.Name()] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+func (s *apiSchemas) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	s.addFields(t, props)
+	return map[string]any{"type": "object", "properties": props}
+}
+
+// addFields adds the JSON fields of struct t to props. The fields of an
+// embedded struct come last, so a field of t shadows them, as in encoding/json.
+func (s *apiSchemas) addFields(t reflect.Type, props map[string]any) {
+	var embedded []reflect.Type
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schema(f.Type)
+	}
+	for _, et := range embedded {
+		inner := make(map[string]any)
+		s.addFields(et, inner)
+		for name, schema := range inner {
+			if _, ok := props[name]; !ok {
+				props[name] = schema
+			}
+		}
+	}
+}
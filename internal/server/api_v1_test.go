@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"easylab/ovh"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAPIMux registers the JSON API with no authentication, the student routes
+// acting for student@example.com.
+func newAPIMux(h *Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	h.RegisterAPIv1(mux,
+		func(next http.HandlerFunc) http.HandlerFunc { return next },
+		func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				next(w, r.WithContext(context.WithValue(r.Context(), studentEmailContextKey, "student@example.com")))
+			}
+		})
+	return mux
+}
+
+func apiCall(t *testing.T, mux *http.ServeMux, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+// openAPIDocument fetches the document the way a client does.
+func openAPIDocument(t *testing.T, mux *http.ServeMux) map[string]any {
+	t.Helper()
+	rec := apiCall(t, mux, http.MethodGet, "/api/v1/openapi.json", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	return doc
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	mux := newAPIMux(h)
+	doc := openAPIDocument(t, mux)
+	assert.Equal(t, "3.0.3", doc["openapi"])
+
+	var documented []string
+	for path, ops := range doc["paths"].(map[string]any) {
+		for method, op := range ops.(map[string]any) {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+
+			// The operation is served by the route it documents, path parameters
+			// included.
+			var params []string
+			parameters, _ := op.(map[string]any)["parameters"].([]any)
+			for _, p := range parameters {
+				if p := p.(map[string]any); p["in"] == "path" {
+					params = append(params, p["name"].(string))
+				}
+			}
+			concrete := path
+			for _, p := range params {
+				concrete = strings.Replace(concrete, "{"+p+"}", "x", 1)
+			}
+			assert.NotContains(t, concrete, "{", "%s %s documents every path parameter", method, path)
+			_, pattern := mux.Handler(httptest.NewRequest(strings.ToUpper(method), concrete, nil))
+			assert.Equal(t, strings.ToUpper(method)+" "+path, pattern)
+		}
+	}
+
+	var routes []string
+	for _, rt := range apiV1Routes() {
+		routes = append(routes, rt.Method+" "+rt.Path)
+	}
+	slices.Sort(documented)
+	slices.Sort(routes)
+	assert.Equal(t, routes, documented)
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	doc := apiV1Document()
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+	lab := schemas["apiLab"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, lab["created_at"])
+	assert.Equal(t, map[string]any{"type": "string"}, lab["status"])
+
+	// The embedded job is flattened, and the redacted config shadows the job's.
+	job := schemas["redactedJob"].(map[string]any)["properties"].(map[string]any)
+	assert.Contains(t, job, "id")
+	assert.Contains(t, job, "cost_estimate")
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/LabConfig"}, job["config"])
+	assert.NotContains(t, job, "mu")
+	assert.Contains(t, schemas, "LabConfig")
+}
+
+func TestAPIv1_CreateGetAndListLab(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{workDir: t.TempDir()}, NewCredentialsManager(), nil, nil, nil)
+	mux := newAPIMux(h)
+
+	rec := apiCall(t, mux, http.MethodPost, "/api/v1/labs/dry-run", "stack_name: workshop\nevent: devoxx-2026\nuse_existing_cluster: true\nexternal_kubeconfig: kc\nworkspace_templates:\n  - name: default\n")
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var created apiLab
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "workshop", created.StackName)
+
+	rec = apiCall(t, mux, http.MethodGet, "/api/v1/labs/"+created.ID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"stack_name":"workshop"`)
+
+	rec = apiCall(t, mux, http.MethodGet, "/api/v1/labs?event=devoxx-2026", "")
+	var labs []apiLab
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &labs))
+	require.Len(t, labs, 1)
+	assert.Equal(t, created.ID, labs[0].ID)
+
+	rec = apiCall(t, mux, http.MethodGet, "/api/v1/labs?event=other", "")
+	assert.JSONEq(t, `[]`, rec.Body.String())
+}
+
+func TestAPIv1_CreateLabErrors(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	mux := newAPIMux(h)
+
+	for name, tt := range map[string]struct {
+		body   string
+		status int
+		want   string
+	}{
+		"unknown key":         {`{"stack_name":"a","stak":"b"}`, http.StatusBadRequest, "stak"},
+		"no stack name":       {`{"use_existing_cluster":true}`, http.StatusBadRequest, "stack_name is required"},
+		"missing kubeconfig":  {`{"stack_name":"a","use_existing_cluster":true,"workspace_templates":[{"name":"default"}]}`, http.StatusBadRequest, "Kubeconfig Required"},
+		"missing credentials": {validLabConfigYAML, http.StatusConflict, "ovh credentials"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := apiCall(t, mux, http.MethodPost, "/api/v1/labs", tt.body)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), tt.want)
+		})
+	}
+	assert.Empty(t, h.jobManager.GetAllJobs())
+}
+
+func TestAPIv1_RefusesDeploymentsDuringMaintenance(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	_, err := h.maintenance.Set(true, "")
+	require.NoError(t, err)
+	mux := newAPIMux(h)
+
+	rec := apiCall(t, mux, http.MethodPost, "/api/v1/labs", `{"stack_name":"a"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "maintenance")
+}
+
+func TestAPIv1_LaunchAndDestroy(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	mux := newAPIMux(h)
+	labID := jm.CreateJob(&LabConfig{StackName: "s"})
+	jm.UpdateJobStatus(labID, JobStatusRunning)
+
+	rec := apiCall(t, mux, http.MethodPost, "/api/v1/labs/"+labID+"/launch", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid Job Status")
+
+	rec = apiCall(t, mux, http.MethodPost, "/api/v1/labs/"+labID+"/launch", `{"override":true}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "unknown keys are refused")
+
+	rec = apiCall(t, mux, http.MethodPost, "/api/v1/labs/missing/launch", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = apiCall(t, mux, http.MethodPost, "/api/v1/labs/missing/destroy", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"Job not found","title":"Job Not Found"}`, rec.Body.String())
+}
+
+func TestAPIv1_LaunchOverQuota(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	mux := newAPIMux(h)
+	labID := jm.CreateJob(&LabConfig{StackName: "s"})
+	jm.UpdateJobStatus(labID, JobStatusDryRunCompleted)
+	jm.SetQuotaReport(labID, &QuotaReport{Region: "GRA9", Shortfalls: []ovh.QuotaShortfall{{Quota: "instances", Needed: 3, Available: 1}}})
+
+	rec := apiCall(t, mux, http.MethodPost, "/api/v1/labs/"+labID+"/launch", `{}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"quota_report":{"region":"GRA9"`)
+	job, _ := jm.GetJob(labID)
+	assert.Equal(t, JobStatusDryRunCompleted, job.Status)
+}
+
+func TestAPIv1_Credentials(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	mux := newAPIMux(h)
+
+	rec := apiCall(t, mux, http.MethodGet, "/api/v1/credentials/azure", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = apiCall(t, mux, http.MethodPut, "/api/v1/credentials/azure", `{"client_id":"c"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = apiCall(t, mux, http.MethodPut, "/api/v1/credentials/azure", `{"client_id":"c","client_secret":"s","tenant_id":"t","subscription_id":"sub"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), `"s"`, "the secret is not echoed")
+
+	rec = apiCall(t, mux, http.MethodGet, "/api/v1/credentials/azure", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"subscription_id":"sub"`)
+
+	rec = apiCall(t, mux, http.MethodPut, "/api/v1/credentials/ovh", `{"application_key":"ak","application_secret":"as","consumer_key":"ck","service_name":"sn"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"changed":["application_key"`)
+
+	rec = apiCall(t, mux, http.MethodPut, "/api/v1/credentials/aws", `{}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAPIv1_StudentWorkspaces(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{reachable: true, workspaces: labWorkspaces()}
+	useFakeBackend(h, fb)
+	mux := newAPIMux(h)
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.mu.Lock()
+	job.Config.WorkspaceTemplates = []WorkspaceTemplate{{Name: "default"}}
+	job.mu.Unlock()
+
+	rec := apiCall(t, mux, http.MethodGet, "/api/v1/student/labs", "")
+	assert.Contains(t, rec.Body.String(), labID)
+
+	rec = apiCall(t, mux, http.MethodPost, "/api/v1/student/workspaces", `{"lab_id":"`+labID+`","workspace_name":"retry"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var ws studentWorkspace
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ws))
+	assert.Equal(t, "student@example.com", ws.Email)
+	assert.Equal(t, labID, ws.LabID)
+
+	rec = apiCall(t, mux, http.MethodPost, "/api/v1/student/workspaces", `{"lab_id":"missing"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = apiCall(t, mux, http.MethodGet, "/api/v1/student/workspaces/missing/retry", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = apiCall(t, mux, http.MethodDelete, "/api/v1/student/workspaces/"+labID+"/other-docker", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "another student's workspace")
+	rec = apiCall(t, mux, http.MethodDelete, "/api/v1/student/workspaces/"+labID+"/student-docker", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, []string{"student-docker"}, fb.DeleteCalls)
+}
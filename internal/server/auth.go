@@ -10,7 +10,7 @@ import (
 	"fmt"
 	"html/template"
 	"io"
-	"log"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"os"
@@ -50,19 +50,27 @@ type contextKey string
 // studentEmailContextKey is the context key for the student email
 const studentEmailContextKey contextKey = "studentEmail"
 
+// studentLabContextKey is the context key for the lab a student session is
+// scoped to (see lab_access_codes.go).
+const studentLabContextKey contextKey = "studentLab"
+
 // Session represents a user session
 type Session struct {
-	Token     string
-	Email     string
-	ExpiresAt time.Time
+	Token string `json:"token"`
+	Email string `json:"email,omitempty"`
+	// LabID scopes a student session to one lab: the one whose access code
+	// the student logged in with. Empty is every lab.
+	LabID     string    `json:"lab_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // AuthHandler handles authentication
 type AuthHandler struct {
 	passwordHash              string
 	studentPasswordHash       string
-	sessions                  map[string]*Session
-	studentSessions           map[string]*Session
+	labAccessCodes            labAccessCodeLookup
+	sessions                  SessionStore
+	studentSessions           SessionStore
 	azureADEnabled            bool
 	azureADConfig             *oauth2.Config
 	azureOAuthStates          map[string]time.Time
@@ -89,11 +97,32 @@ func comparePassword(hashedPassword, plainPassword string) bool {
 	return err == nil
 }
 
-// NewAuthHandler creates a new auth handler
+// NewAuthHandler creates a new auth handler configured from the environment.
 func NewAuthHandler() (*AuthHandler, error) {
-	password := os.Getenv(EnvAdminPassword)
+	return NewAuthHandlerFromConfig(authConfigFromEnv())
+}
+
+// authConfigFromEnv reads the login settings from their environment variables.
+func authConfigFromEnv() AuthConfig {
+	return AuthConfig{
+		AdminPassword:       os.Getenv(EnvAdminPassword),
+		StudentPassword:     os.Getenv(EnvStudentPassword),
+		SessionStore:        os.Getenv(EnvSessionStore),
+		SessionRedisURL:     os.Getenv(EnvSessionRedisURL),
+		ShareLinkSecret:     os.Getenv(EnvShareLinkSecret),
+		AzureADClientID:     os.Getenv(EnvAzureADClientID),
+		AzureADClientSecret: os.Getenv(EnvAzureADClientSecret),
+		AzureADTenantID:     os.Getenv(EnvAzureADTenantID),
+		AzureADAdminGroupID: os.Getenv(EnvAzureADAdminGroupID),
+	}
+}
+
+// NewAuthHandlerFromConfig creates an auth handler with the passwords and Azure
+// AD settings of cfg. Sessions are kept in memory; see SetSessionStores.
+func NewAuthHandlerFromConfig(cfg AuthConfig) (*AuthHandler, error) {
+	password := cfg.AdminPassword
 	if password == "" {
-		return nil, fmt.Errorf("%s environment variable not set. Admin password is required", EnvAdminPassword)
+		return nil, fmt.Errorf("admin password not set (auth.admin_password or %s). Admin password is required", EnvAdminPassword)
 	}
 
 	// Hash password with SHA-256 first, then bcrypt for secure storage
@@ -102,13 +131,13 @@ func NewAuthHandler() (*AuthHandler, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash admin password: %w", err)
 	}
-	log.Printf("Admin password hash initialized")
+	slog.Info("Admin password hash initialized")
 
 	// Initialize student password
-	studentPassword := os.Getenv(EnvStudentPassword)
+	studentPassword := cfg.StudentPassword
 	var studentPasswordHash string
 	if studentPassword == "" {
-		log.Printf("WARNING: %s environment variable not set. Student login will be disabled.", EnvStudentPassword)
+		slog.Warn("No student password is set: students can only log in with a lab's access code or Azure AD", "env", EnvStudentPassword)
 		studentPasswordHash = ""
 	} else {
 		// Hash password with SHA-256 first, then bcrypt for secure storage
@@ -117,13 +146,13 @@ func NewAuthHandler() (*AuthHandler, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash student password: %w", err)
 		}
-		log.Printf("Student password hash initialized")
+		slog.Info("Student password hash initialized")
 	}
 
 	// Initialize Azure AD OAuth for student login
-	azureClientID := os.Getenv(EnvAzureADClientID)
-	azureClientSecret := os.Getenv(EnvAzureADClientSecret)
-	azureTenantID := os.Getenv(EnvAzureADTenantID)
+	azureClientID := cfg.AzureADClientID
+	azureClientSecret := cfg.AzureADClientSecret
+	azureTenantID := cfg.AzureADTenantID
 
 	var azureADEnabled bool
 	var azureADConfig *oauth2.Config
@@ -135,24 +164,24 @@ func NewAuthHandler() (*AuthHandler, error) {
 			Endpoint:     microsoft.AzureADEndpoint(azureTenantID),
 		}
 		azureADEnabled = true
-		log.Printf("Azure AD student login enabled (tenant: %s)", azureTenantID)
+		slog.Info("Azure AD student login enabled", "tenant", azureTenantID)
 	}
 
-	adminGroupID := os.Getenv(EnvAzureADAdminGroupID)
+	adminGroupID := cfg.AzureADAdminGroupID
 	if adminGroupID != "" && azureADEnabled {
-		log.Printf("Azure AD admin login enabled (group: %s)", adminGroupID)
+		slog.Info("Azure AD admin login enabled", "group", adminGroupID)
 	}
 
 	ah := &AuthHandler{
 		passwordHash:        passwordHash,
 		studentPasswordHash: studentPasswordHash,
-		sessions:            make(map[string]*Session),
-		studentSessions:     make(map[string]*Session),
+		sessions:            NewMemorySessionStore(),
+		studentSessions:     NewMemorySessionStore(),
 		azureADEnabled:      azureADEnabled,
 		azureADConfig:       azureADConfig,
 		azureOAuthStates:    make(map[string]time.Time),
 		adminGroupID:        adminGroupID,
-		templates:           make(map[string]*template.Template),
+		templates:           mustEmbedded(embeddedAuthTemplates()),
 	}
 
 	// Periodically evict expired sessions and OAuth states so the maps don't grow unboundedly.
@@ -161,17 +190,14 @@ func NewAuthHandler() (*AuthHandler, error) {
 		defer ticker.Stop()
 		for range ticker.C {
 			now := time.Now()
-			ah.mu.Lock()
-			for tok, s := range ah.sessions {
-				if now.After(s.ExpiresAt) {
-					delete(ah.sessions, tok)
-				}
+			sessions, studentSessions := ah.sessionStores()
+			if err := sessions.DeleteExpired(now); err != nil {
+				slog.Error("Failed to evict expired sessions", "error", err)
 			}
-			for tok, s := range ah.studentSessions {
-				if now.After(s.ExpiresAt) {
-					delete(ah.studentSessions, tok)
-				}
+			if err := studentSessions.DeleteExpired(now); err != nil {
+				slog.Error("Failed to evict expired student sessions", "error", err)
 			}
+			ah.mu.Lock()
 			for state, expiry := range ah.azureOAuthStates {
 				if now.After(expiry) {
 					delete(ah.azureOAuthStates, state)
@@ -184,6 +210,25 @@ func NewAuthHandler() (*AuthHandler, error) {
 	return ah, nil
 }
 
+// SetSessionStores replaces the admin and student session stores (in-memory by
+// default). Call it at startup, before serving requests: sessions held by the
+// previous stores are not carried over.
+func (ah *AuthHandler) SetSessionStores(admin, student SessionStore) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.sessions = admin
+	ah.studentSessions = student
+}
+
+// sessionStores returns the admin and student session stores. ah.mu only
+// guards which stores are in use: the stores synchronize themselves, and are
+// called without holding it since the file and Redis stores do I/O.
+func (ah *AuthHandler) sessionStores() (admin, student SessionStore) {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	return ah.sessions, ah.studentSessions
+}
+
 // ConfigureAzureAD updates the Azure AD OAuth config at runtime. Passing empty strings disables it.
 func (ah *AuthHandler) ConfigureAzureAD(clientID, clientSecret, tenantID string) {
 	ah.mu.Lock()
@@ -197,11 +242,11 @@ func (ah *AuthHandler) ConfigureAzureAD(clientID, clientSecret, tenantID string)
 			Endpoint:     microsoft.AzureADEndpoint(tenantID),
 		}
 		ah.azureADEnabled = true
-		log.Printf("Azure AD student login configured (tenant: %s)", tenantID)
+		slog.Info("Azure AD student login configured", "tenant", tenantID)
 	} else {
 		ah.azureADConfig = nil
 		ah.azureADEnabled = false
-		log.Printf("Azure AD student login disabled")
+		slog.Info("Azure AD student login disabled")
 	}
 }
 
@@ -227,9 +272,9 @@ func (ah *AuthHandler) SetAdminGroupID(groupID string) {
 	defer ah.mu.Unlock()
 	ah.adminGroupID = groupID
 	if groupID != "" {
-		log.Printf("Azure AD admin login configured (group: %s)", groupID)
+		slog.Info("Azure AD admin login configured", "group", groupID)
 	} else {
-		log.Printf("Azure AD admin login disabled (group ID cleared)")
+		slog.Info("Azure AD admin login disabled (group ID cleared)")
 	}
 }
 
@@ -259,24 +304,23 @@ func generateToken() string {
 
 // createSession creates a new session and returns the token
 func (ah *AuthHandler) createSession() string {
-	ah.mu.Lock()
-	defer ah.mu.Unlock()
-
+	sessions, _ := ah.sessionStores()
 	token := generateToken()
-	ah.sessions[token] = &Session{
+	if err := sessions.Put(&Session{
 		Token:     token,
 		ExpiresAt: time.Now().Add(SessionExpiry),
+	}); err != nil {
+		slog.Error("Failed to store session", "error", err)
 	}
+	metrics.countSession("admin")
 
 	return token
 }
 
 // validateSession checks if a session token is valid
 func (ah *AuthHandler) validateSession(token string) bool {
-	ah.mu.RLock()
-	defer ah.mu.RUnlock()
-
-	session, exists := ah.sessions[token]
+	sessions, _ := ah.sessionStores()
+	session, exists := sessions.Get(token)
 	if !exists {
 		return false
 	}
@@ -292,50 +336,20 @@ func (ah *AuthHandler) validateSession(token string) bool {
 
 // deleteSession removes a session
 func (ah *AuthHandler) deleteSession(token string) {
-	ah.mu.Lock()
-	defer ah.mu.Unlock()
-	delete(ah.sessions, token)
+	sessions, _ := ah.sessionStores()
+	if err := sessions.Delete(token); err != nil {
+		slog.Error("Failed to delete session", "error", err)
+	}
 }
 
-// getTemplate retrieves a cached template by filename, loading it lazily if needed
+// getTemplate returns the template of a login page (see authPages).
 func (ah *AuthHandler) getTemplate(filename string) (*template.Template, error) {
-	// Fast path: check cache first
 	ah.templatesMu.RLock()
+	defer ah.templatesMu.RUnlock()
 	tmpl, ok := ah.templates[filename]
-	ah.templatesMu.RUnlock()
-	if ok {
-		return tmpl, nil
-	}
-
-	// Slow path: load template
-	ah.templatesMu.Lock()
-	defer ah.templatesMu.Unlock()
-
-	// Double-check after acquiring write lock
-	if tmpl, ok := ah.templates[filename]; ok {
-		return tmpl, nil
-	}
-
-	// Map filename to full path
-	templatePaths := map[string]string{
-		"login.html":         "web/login.html",
-		"student-login.html": "web/student-login.html",
-	}
-
-	tmplPath, ok := templatePaths[filename]
 	if !ok {
 		return nil, fmt.Errorf("template %s not found", filename)
 	}
-
-	var err error
-	// Parse base template and page template together
-	tmpl, err = template.ParseFiles("web/base.html", tmplPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load template %s: %w", tmplPath, err)
-	}
-
-	// Execute the base template which will include the page-specific blocks
-	ah.templates[filename] = tmpl
 	return tmpl, nil
 }
 
@@ -352,7 +366,7 @@ func (ah *AuthHandler) ServeLogin(w http.ResponseWriter, r *http.Request) {
 	// Use cached template (lazy loaded)
 	tmpl, err := ah.getTemplate("login.html")
 	if err != nil {
-		log.Printf("Failed to load login template: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to load login template", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -375,7 +389,7 @@ func (ah *AuthHandler) ServeLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		log.Printf("Failed to execute login template: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to execute login template", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -404,14 +418,16 @@ func (ah *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	// Get password hash from form (client-side SHA-256 hashed)
 	passwordHash := getFormValue(r, "password_hash")
 	if passwordHash == "" {
-		log.Printf("Failed login attempt: empty password hash")
+		slog.WarnContext(r.Context(), "Failed login attempt: empty password hash")
+		metrics.countLoginFailure("admin")
 		http.Redirect(w, r, "/login?error=Invalid+password", http.StatusSeeOther)
 		return
 	}
 
 	// Compare received SHA-256 hash with stored bcrypt(SHA-256(password)) hash
 	if !comparePassword(ah.passwordHash, passwordHash) {
-		log.Printf("Failed login attempt")
+		slog.WarnContext(r.Context(), "Failed login attempt")
+		metrics.countLoginFailure("admin")
 		http.Redirect(w, r, "/login?error=Invalid+password", http.StatusSeeOther)
 		return
 	}
@@ -433,7 +449,7 @@ func (ah *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		MaxAge:   int(SessionExpiry.Seconds()),
 	})
 
-	log.Printf("Successful login")
+	slog.InfoContext(r.Context(), "Successful login")
 	http.Redirect(w, r, "/labs", http.StatusSeeOther)
 }
 
@@ -469,31 +485,48 @@ func (ah *AuthHandler) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 
 // createStudentSession creates a new student session and returns the token
 func (ah *AuthHandler) createStudentSession(email string) string {
-	ah.mu.Lock()
-	defer ah.mu.Unlock()
+	return ah.createLabStudentSession(email, "")
+}
 
+// createLabStudentSession creates a student session scoped to labID (every
+// lab when empty) and returns the token.
+func (ah *AuthHandler) createLabStudentSession(email, labID string) string {
+	_, studentSessions := ah.sessionStores()
 	token := generateToken()
-	ah.studentSessions[token] = &Session{
+	if err := studentSessions.Put(&Session{
 		Token:     token,
 		Email:     email,
+		LabID:     labID,
 		ExpiresAt: time.Now().Add(SessionExpiry),
+	}); err != nil {
+		slog.Error("Failed to store student session", "error", err)
 	}
+	metrics.countSession("student")
 
 	return token
 }
 
 // getStudentSessionEmail returns the email associated with a student session token
 func (ah *AuthHandler) getStudentSessionEmail(token string) string {
-	ah.mu.RLock()
-	defer ah.mu.RUnlock()
-
-	session, exists := ah.studentSessions[token]
+	_, studentSessions := ah.sessionStores()
+	session, exists := studentSessions.Get(token)
 	if !exists {
 		return ""
 	}
 	return session.Email
 }
 
+// getStudentSessionLab returns the lab a student session is scoped to, "" for
+// every lab.
+func (ah *AuthHandler) getStudentSessionLab(token string) string {
+	_, studentSessions := ah.sessionStores()
+	session, exists := studentSessions.Get(token)
+	if !exists {
+		return ""
+	}
+	return session.LabID
+}
+
 // studentEmailFromContext retrieves the student email stored in the request context
 func studentEmailFromContext(r *http.Request) string {
 	email, _ := r.Context().Value(studentEmailContextKey).(string)
@@ -502,10 +535,8 @@ func studentEmailFromContext(r *http.Request) string {
 
 // validateStudentSession checks if a student session token is valid
 func (ah *AuthHandler) validateStudentSession(token string) bool {
-	ah.mu.RLock()
-	defer ah.mu.RUnlock()
-
-	session, exists := ah.studentSessions[token]
+	_, studentSessions := ah.sessionStores()
+	session, exists := studentSessions.Get(token)
 	if !exists {
 		return false
 	}
@@ -521,9 +552,10 @@ func (ah *AuthHandler) validateStudentSession(token string) bool {
 
 // deleteStudentSession removes a student session
 func (ah *AuthHandler) deleteStudentSession(token string) {
-	ah.mu.Lock()
-	defer ah.mu.Unlock()
-	delete(ah.studentSessions, token)
+	_, studentSessions := ah.sessionStores()
+	if err := studentSessions.Delete(token); err != nil {
+		slog.Error("Failed to delete student session", "error", err)
+	}
 }
 
 // ServeStudentLogin serves the student login page
@@ -539,7 +571,7 @@ func (ah *AuthHandler) ServeStudentLogin(w http.ResponseWriter, r *http.Request)
 	// Use cached template (lazy loaded)
 	tmpl, err := ah.getTemplate("student-login.html")
 	if err != nil {
-		log.Printf("Failed to load student login template: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to load student login template", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -562,7 +594,7 @@ func (ah *AuthHandler) ServeStudentLogin(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		log.Printf("Failed to execute student login template: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to execute student login template", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -576,10 +608,11 @@ func (ah *AuthHandler) HandleStudentLogin(w http.ResponseWriter, r *http.Request
 
 	ah.mu.RLock()
 	storedHash := ah.studentPasswordHash
+	labAccessCodes := ah.labAccessCodes
 	classicDisabled := ah.classicLoginDisabled && ah.azureADEnabled
 	ah.mu.RUnlock()
 
-	if storedHash == "" {
+	if storedHash == "" && labAccessCodes == nil {
 		http.Redirect(w, r, "/student/login?error=Student+login+disabled", http.StatusSeeOther)
 		return
 	}
@@ -596,14 +629,23 @@ func (ah *AuthHandler) HandleStudentLogin(w http.ResponseWriter, r *http.Request
 	// Get password hash from form (client-side SHA-256 hashed)
 	passwordHash := getFormValue(r, "password_hash")
 	if passwordHash == "" {
-		log.Printf("Failed student login attempt: empty password hash")
+		slog.WarnContext(r.Context(), "Failed student login attempt: empty password hash")
+		metrics.countLoginFailure("student")
 		http.Redirect(w, r, "/student/login?error=Invalid+password", http.StatusSeeOther)
 		return
 	}
 
-	// Compare received SHA-256 hash with stored bcrypt(SHA-256(password)) hash
-	if !comparePassword(storedHash, passwordHash) {
-		log.Printf("Failed student login attempt")
+	// Compare received SHA-256 hash with stored bcrypt(SHA-256(password)) hash.
+	// The global password opens every lab; failing that, a lab's access code
+	// opens that lab only.
+	labID := ""
+	ok := storedHash != "" && comparePassword(storedHash, passwordHash)
+	if !ok && labAccessCodes != nil {
+		labID, ok = labAccessCodes(passwordHash)
+	}
+	if !ok {
+		slog.WarnContext(r.Context(), "Failed student login attempt")
+		metrics.countLoginFailure("student")
 		http.Redirect(w, r, "/student/login?error=Invalid+password", http.StatusSeeOther)
 		return
 	}
@@ -616,7 +658,7 @@ func (ah *AuthHandler) HandleStudentLogin(w http.ResponseWriter, r *http.Request
 	}
 
 	// Create session
-	token := ah.createStudentSession(email)
+	token := ah.createLabStudentSession(email, labID)
 
 	// Determine if HTTPS is being used
 	isSecure := r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
@@ -632,7 +674,7 @@ func (ah *AuthHandler) HandleStudentLogin(w http.ResponseWriter, r *http.Request
 		MaxAge:   int(SessionExpiry.Seconds()),
 	})
 
-	log.Printf("Successful student login")
+	slog.InfoContext(r.Context(), "Successful student login", "job_id", labID)
 	http.Redirect(w, r, "/student/dashboard", http.StatusSeeOther)
 }
 
@@ -657,7 +699,7 @@ func (ah *AuthHandler) HandleStudentLogout(w http.ResponseWriter, r *http.Reques
 // RequireStudentAuth is middleware that requires student authentication
 func (ah *AuthHandler) RequireStudentAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if ah.studentPasswordHash == "" && !ah.azureADEnabled {
+		if ah.studentPasswordHash == "" && ah.labAccessCodes == nil && !ah.azureADEnabled {
 			http.Error(w, "Student login is disabled", http.StatusForbidden)
 			return
 		}
@@ -668,6 +710,7 @@ func (ah *AuthHandler) RequireStudentAuth(next http.HandlerFunc) http.HandlerFun
 		}
 		email := ah.getStudentSessionEmail(cookie.Value)
 		ctx := context.WithValue(r.Context(), studentEmailContextKey, email)
+		ctx = context.WithValue(ctx, studentLabContextKey, ah.getStudentSessionLab(cookie.Value))
 		next(w, r.WithContext(ctx))
 	}
 }
@@ -706,7 +749,7 @@ func (ah *AuthHandler) HandleAzureADCallback(w http.ResponseWriter, r *http.Requ
 
 	errParam := r.URL.Query().Get("error")
 	if errParam != "" {
-		log.Printf("Azure AD OAuth error: %s", errParam)
+		slog.WarnContext(r.Context(), "Azure AD OAuth error", "error", errParam)
 		http.Redirect(w, r, "/student/login?error=Azure+AD+authentication+failed", http.StatusSeeOther)
 		return
 	}
@@ -723,7 +766,7 @@ func (ah *AuthHandler) HandleAzureADCallback(w http.ResponseWriter, r *http.Requ
 	ah.mu.Unlock()
 
 	if !valid || time.Now().After(expiry) {
-		log.Printf("Azure AD callback: invalid or expired state")
+		slog.WarnContext(r.Context(), "Azure AD callback: invalid or expired state")
 		http.Redirect(w, r, "/student/login?error=Invalid+authentication+state", http.StatusSeeOther)
 		return
 	}
@@ -742,20 +785,20 @@ func (ah *AuthHandler) HandleAzureADCallback(w http.ResponseWriter, r *http.Requ
 
 	token, err := ah.azureADConfig.Exchange(r.Context(), code, oauth2.SetAuthURLParam("redirect_uri", redirectURI))
 	if err != nil {
-		log.Printf("Azure AD token exchange failed: %v", err)
+		slog.ErrorContext(r.Context(), "Azure AD token exchange failed", "error", err)
 		http.Redirect(w, r, "/student/login?error=Authentication+failed", http.StatusSeeOther)
 		return
 	}
 
 	email, err := extractEmailFromIDToken(token)
 	if err != nil {
-		log.Printf("Azure AD: failed to extract email: %v", err)
+		slog.ErrorContext(r.Context(), "Azure AD: failed to extract email", "error", err)
 		http.Redirect(w, r, "/student/login?error=Could+not+retrieve+email+from+Azure+AD", http.StatusSeeOther)
 		return
 	}
 
 	if !strings.Contains(email, "@") {
-		log.Printf("Azure AD: invalid email format: %s", email)
+		slog.WarnContext(r.Context(), "Azure AD: invalid email format", "email", email)
 		http.Redirect(w, r, "/student/login?error=Invalid+email+from+Azure+AD", http.StatusSeeOther)
 		return
 	}
@@ -776,7 +819,7 @@ func (ah *AuthHandler) HandleAzureADCallback(w http.ResponseWriter, r *http.Requ
 		MaxAge:   int(SessionExpiry.Seconds()),
 	})
 
-	log.Printf("Successful Azure AD student login")
+	slog.InfoContext(r.Context(), "Successful Azure AD student login")
 	http.Redirect(w, r, "/student/dashboard", http.StatusSeeOther)
 }
 
@@ -817,7 +860,7 @@ func (ah *AuthHandler) HandleAdminAzureADCallback(w http.ResponseWriter, r *http
 	}
 
 	if errParam := r.URL.Query().Get("error"); errParam != "" {
-		log.Printf("Azure AD admin OAuth error: %s", errParam)
+		slog.WarnContext(r.Context(), "Azure AD admin OAuth error", "error", errParam)
 		http.Redirect(w, r, "/login?error=Azure+AD+authentication+failed", http.StatusSeeOther)
 		return
 	}
@@ -833,7 +876,7 @@ func (ah *AuthHandler) HandleAdminAzureADCallback(w http.ResponseWriter, r *http
 	ah.mu.Unlock()
 
 	if !valid || time.Now().After(expiry) {
-		log.Printf("Azure AD admin callback: invalid or expired state")
+		slog.WarnContext(r.Context(), "Azure AD admin callback: invalid or expired state")
 		http.Redirect(w, r, "/login?error=Invalid+authentication+state", http.StatusSeeOther)
 		return
 	}
@@ -852,14 +895,14 @@ func (ah *AuthHandler) HandleAdminAzureADCallback(w http.ResponseWriter, r *http
 
 	token, err := ah.azureADConfig.Exchange(r.Context(), code, oauth2.SetAuthURLParam("redirect_uri", redirectURI))
 	if err != nil {
-		log.Printf("Azure AD admin token exchange failed: %v", err)
+		slog.ErrorContext(r.Context(), "Azure AD admin token exchange failed", "error", err)
 		http.Redirect(w, r, "/login?error=Authentication+failed", http.StatusSeeOther)
 		return
 	}
 
 	email, err := extractEmailFromIDToken(token)
 	if err != nil {
-		log.Printf("Azure AD admin: failed to extract email: %v", err)
+		slog.ErrorContext(r.Context(), "Azure AD admin: failed to extract email", "error", err)
 		http.Redirect(w, r, "/login?error=Could+not+retrieve+email+from+Azure+AD", http.StatusSeeOther)
 		return
 	}
@@ -870,12 +913,12 @@ func (ah *AuthHandler) HandleAdminAzureADCallback(w http.ResponseWriter, r *http
 
 	member, err := checkDirectGroupMembership(token.AccessToken, groupID)
 	if err != nil {
-		log.Printf("Azure AD admin: group membership check failed for %s: %v", email, err)
+		slog.ErrorContext(r.Context(), "Azure AD admin: group membership check failed", "email", email, "error", err)
 		http.Redirect(w, r, "/login?error=Could+not+verify+group+membership", http.StatusSeeOther)
 		return
 	}
 	if !member {
-		log.Printf("Azure AD admin: user %s is not a member of group %s — access denied", email, groupID)
+		slog.WarnContext(r.Context(), "Azure AD admin: access denied, the user is not a member of the admin group", "email", email, "group", groupID)
 		http.Redirect(w, r, "/login?error=Not+authorized%3A+you+are+not+a+member+of+the+required+admin+group", http.StatusSeeOther)
 		return
 	}
@@ -892,7 +935,7 @@ func (ah *AuthHandler) HandleAdminAzureADCallback(w http.ResponseWriter, r *http
 		MaxAge:   int(SessionExpiry.Seconds()),
 	})
 
-	log.Printf("Successful Azure AD admin login: %s", email)
+	slog.InfoContext(r.Context(), "Successful Azure AD admin login", "email", email)
 	http.Redirect(w, r, "/labs", http.StatusSeeOther)
 }
 
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"sort"
@@ -171,14 +171,15 @@ func (h *Handler) azureWriteLocationOptionsLive(w http.ResponseWriter) error {
 		return fmt.Errorf("failed to list Azure locations: %w", err)
 	}
 	sort.Strings(names)
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var b strings.Builder
 	for i, region := range names {
 		selected := ""
 		if i == 0 {
 			selected = " selected"
 		}
-		fmt.Fprintf(w, `<option value="%s"%s>%s</option>`, escapeHTML(region), selected, escapeHTML(region))
+		fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, escapeHTML(region), selected, escapeHTML(region))
 	}
+	writeHTMLFragment(w, http.StatusOK, b.String())
 	return nil
 }
 
@@ -196,19 +197,20 @@ func (h *Handler) azureWriteVMSizesOptionsLive(w http.ResponseWriter, location s
 		return fmt.Errorf("failed to list Azure VM sizes: %w", err)
 	}
 	sizes = filterAzureVMSizesByCPURAM(sizes, minVcpus, maxVcpus, minRam, maxRam)
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if len(sizes) == 0 {
-		fmt.Fprint(w, `<option value="" disabled selected>No VM sizes match — adjust filters or use 0 for no limit</option>`)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>No VM sizes match — adjust filters or use 0 for no limit</option>`)
 		return nil
 	}
+	var b strings.Builder
 	for i, s := range sizes {
 		label := fmt.Sprintf("%s (%d vCPU, %d GB RAM)", s.Name, s.VCPUs, s.RAMGB)
 		if i == 0 {
-			fmt.Fprintf(w, `<option value="%s" selected>%s</option>`, escapeHTML(s.Name), escapeHTML(label))
+			fmt.Fprintf(&b, `<option value="%s" selected>%s</option>`, escapeHTML(s.Name), escapeHTML(label))
 		} else {
-			fmt.Fprintf(w, `<option value="%s">%s</option>`, escapeHTML(s.Name), escapeHTML(label))
+			fmt.Fprintf(&b, `<option value="%s">%s</option>`, escapeHTML(s.Name), escapeHTML(label))
 		}
 	}
+	writeHTMLFragment(w, http.StatusOK, b.String())
 	return nil
 }
 
@@ -244,7 +246,7 @@ func (h *Handler) GetAzureLocations(w http.ResponseWriter, r *http.Request) {
 
 	if h.azureOptionsManager != nil && h.azureOptionsManager.HasCache() {
 		regions, defaultRegion := h.azureOptionsManager.GetRegionsForForm()
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		var b strings.Builder
 		for _, region := range regions {
 			selected := ""
 			if region == defaultRegion || (defaultRegion == "" && len(regions) > 0 && region == regions[0]) {
@@ -255,15 +257,15 @@ func (h *Handler) GetAzureLocations(w http.ResponseWriter, r *http.Request) {
 			if disp != "" && disp != region {
 				label = fmt.Sprintf("%s (%s)", disp, region)
 			}
-			fmt.Fprintf(w, `<option value="%s"%s>%s</option>`, escapeHTML(region), selected, escapeHTML(label))
+			fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, escapeHTML(region), selected, escapeHTML(label))
 		}
+		writeHTMLFragment(w, http.StatusOK, b.String())
 		return
 	}
 
 	if err := h.azureWriteLocationOptionsLive(w); err != nil {
-		log.Printf("GetAzureLocations: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<option value="" disabled selected>Failed to load locations</option>`)
+		slog.ErrorContext(r.Context(), "GetAzureLocations failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>Failed to load locations</option>`)
 	}
 }
 
@@ -286,33 +288,32 @@ func (h *Handler) GetAzureVMSizes(w http.ResponseWriter, r *http.Request) {
 
 	if h.azureOptionsManager != nil && h.azureOptionsManager.HasCache() {
 		if err := h.azureOptionsManager.EnsureVMSizesCached(location); err != nil {
-			log.Printf("GetAzureVMSizes: %v", err)
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			fmt.Fprint(w, `<option value="" disabled selected>Failed to load VM sizes</option>`)
+			slog.ErrorContext(r.Context(), "GetAzureVMSizes failed", "error", err)
+			writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>Failed to load VM sizes</option>`)
 			return
 		}
 		sizes, defaultSize := h.azureOptionsManager.GetVMSizesForForm(location)
 		sizes = filterAzureVMSizesByCPURAM(sizes, minVcpus, maxVcpus, minRam, maxRam)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		if len(sizes) == 0 {
-			fmt.Fprint(w, `<option value="" disabled selected>No VM sizes match — adjust filters or use 0 for no limit</option>`)
+			writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>No VM sizes match — adjust filters or use 0 for no limit</option>`)
 			return
 		}
+		var b strings.Builder
 		for i, s := range sizes {
 			label := fmt.Sprintf("%s (%d vCPU, %d GB RAM)", s.Name, s.VCPUs, s.RAMGB)
 			selected := ""
 			if s.Name == defaultSize || (defaultSize == "" && i == 0) {
 				selected = " selected"
 			}
-			fmt.Fprintf(w, `<option value="%s"%s>%s</option>`, escapeHTML(s.Name), selected, escapeHTML(label))
+			fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, escapeHTML(s.Name), selected, escapeHTML(label))
 		}
+		writeHTMLFragment(w, http.StatusOK, b.String())
 		return
 	}
 
 	if err := h.azureWriteVMSizesOptionsLive(w, location, minVcpus, maxVcpus, minRam, maxRam); err != nil {
-		log.Printf("GetAzureVMSizes: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<option value="" disabled selected>Failed to load VM sizes</option>`)
+		slog.ErrorContext(r.Context(), "GetAzureVMSizes failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>Failed to load VM sizes</option>`)
 	}
 }
 
@@ -333,9 +334,8 @@ func (h *Handler) GetAzureOptionsRegionVMSizeHTML(w http.ResponseWriter, r *http
 	}
 
 	if err := h.azureOptionsManager.EnsureVMSizesCached(region); err != nil {
-		log.Printf("GetAzureOptionsRegionVMSizeHTML: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<p class="section-description">Failed to load VM sizes, please try again.</p>`)
+		slog.ErrorContext(r.Context(), "GetAzureOptionsRegionVMSizeHTML failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<p class="section-description">Failed to load VM sizes, please try again.</p>`)
 		return
 	}
 
@@ -345,9 +345,8 @@ func (h *Handler) GetAzureOptionsRegionVMSizeHTML(w http.ResponseWriter, r *http
 	enabledSet := toSet(vmCfg.Enabled)
 	showAll := len(enabledSet) == 0
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if len(sizes) == 0 {
-		fmt.Fprint(w, `<p class="section-description">No VM sizes returned for this region.</p>`)
+		writeHTMLFragment(w, http.StatusOK, `<p class="section-description">No VM sizes returned for this region.</p>`)
 		return
 	}
 
@@ -382,5 +381,5 @@ func (h *Handler) GetAzureOptionsRegionVMSizeHTML(w http.ResponseWriter, r *http
 		fmt.Fprintf(&buf, `</tr>`)
 	}
 	fmt.Fprintf(&buf, `</tbody></table>`)
-	_, _ = w.Write(buf.Bytes())
+	writeHTMLFragment(w, http.StatusOK, buf.String())
 }
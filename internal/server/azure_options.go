@@ -3,7 +3,7 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
@@ -64,7 +64,7 @@ func NewAzureOptionsManager(dataDir string, credsMgr *CredentialsManager) *Azure
 		credsMgr: credsMgr,
 	}
 	if err := m.LoadConfig(); err != nil {
-		log.Printf("[AZURE-OPTIONS] Warning: failed to load config: %v", err)
+		slog.Warn("Failed to load config", "component", "azure-options", "error", err)
 	}
 	return m
 }
@@ -184,7 +184,7 @@ func (m *AzureOptionsManager) RefreshFromAPI() error {
 	m.cachedVMSizes = make(map[string][]azureVMSize)
 	m.mu.Unlock()
 
-	log.Printf("[AZURE-OPTIONS] Cache refreshed: %d regions loaded", len(locations))
+	slog.Info("Cache refreshed", "component", "azure-options", "regions", len(locations))
 	return nil
 }
 
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBroadcastLength caps the broadcast message: it is a one-line banner.
+const maxBroadcastLength = 500
+
+// Broadcast is a message shown to admins and students on top of their pages,
+// e.g. "OVH is having issues, hang tight". ID changes each time the message is
+// set, so a banner a user dismissed shows again for the next message.
+type Broadcast struct {
+	ID        string    `json:"id,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	SetAt     time.Time `json:"set_at,omitzero"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// active reports whether the broadcast is set and not expired at now.
+func (b Broadcast) active(now time.Time) bool {
+	return b.Message != "" && (b.ExpiresAt.IsZero() || now.Before(b.ExpiresAt))
+}
+
+// BroadcastBoard holds the broadcast and persists it in dataDir, so that the
+// banner survives a restart during the incident it is about.
+type BroadcastBoard struct {
+	broadcast Broadcast
+	dataDir   string
+	mu        sync.RWMutex
+}
+
+// NewBroadcastBoard creates the board and loads its persisted broadcast. With
+// an empty dataDir the broadcast lives in memory only.
+func NewBroadcastBoard(dataDir string) *BroadcastBoard {
+	b := &BroadcastBoard{dataDir: dataDir}
+	if err := b.load(); err != nil {
+		slog.Warn("Failed to load broadcast", "component", "broadcast", "error", err)
+	}
+	return b
+}
+
+func (b *BroadcastBoard) path() string {
+	return filepath.Join(b.dataDir, "broadcast.json")
+}
+
+func (b *BroadcastBoard) load() error {
+	if b.dataDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(b.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read broadcast: %w", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := json.Unmarshal(data, &b.broadcast); err != nil {
+		return fmt.Errorf("failed to parse broadcast: %w", err)
+	}
+	return nil
+}
+
+// Current returns the broadcast to show, or a zero Broadcast when there is
+// none or it has expired.
+func (b *BroadcastBoard) Current() Broadcast {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.broadcast.active(time.Now()) {
+		return Broadcast{}
+	}
+	return b.broadcast
+}
+
+// Set replaces the broadcast and persists it. A zero expiresAt keeps it until
+// it is cleared; an empty message clears it.
+func (b *BroadcastBoard) Set(message string, expiresAt time.Time) (Broadcast, error) {
+	next := Broadcast{}
+	if message != "" {
+		now := time.Now()
+		next = Broadcast{ID: strconv.FormatInt(now.UnixNano(), 36), Message: message, SetAt: now, ExpiresAt: expiresAt}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dataDir != "" {
+		data, err := json.MarshalIndent(next, "", "  ")
+		if err != nil {
+			return b.broadcast, fmt.Errorf("failed to marshal broadcast: %w", err)
+		}
+		if err := os.MkdirAll(b.dataDir, 0755); err != nil {
+			return b.broadcast, fmt.Errorf("failed to create data dir: %w", err)
+		}
+		if err := os.WriteFile(b.path(), data, 0644); err != nil {
+			return b.broadcast, fmt.Errorf("failed to write broadcast: %w", err)
+		}
+	}
+	b.broadcast = next
+	return next, nil
+}
+
+// Clear removes the broadcast.
+func (b *BroadcastBoard) Clear() error {
+	_, err := b.Set("", time.Time{})
+	return err
+}
+
+// SetBroadcastBoard replaces the handler's in-memory broadcast with a
+// persisted one.
+func (h *Handler) SetBroadcastBoard(b *BroadcastBoard) {
+	h.broadcast = b
+}
+
+// parseBroadcastForm reads the message and the expiry of a broadcast.
+// expires_in is a duration such as "2h" or "30m"; empty means no expiry.
+func parseBroadcastForm(r *http.Request) (string, time.Time, error) {
+	message := strings.TrimSpace(getFormValue(r, "message"))
+	if len(message) > maxBroadcastLength {
+		return "", time.Time{}, fmt.Errorf("message is too long (%d characters, at most %d)", len(message), maxBroadcastLength)
+	}
+	if strings.ContainsAny(message, "\r\n") {
+		return "", time.Time{}, fmt.Errorf("message must fit on one line")
+	}
+	expiresIn := strings.TrimSpace(getFormValue(r, "expires_in"))
+	if message == "" || expiresIn == "" {
+		return message, time.Time{}, nil
+	}
+	d, err := time.ParseDuration(expiresIn)
+	if err != nil || d <= 0 {
+		return "", time.Time{}, fmt.Errorf("expires_in must be a positive duration such as 30m or 2h, got %q", expiresIn)
+	}
+	return message, time.Now().Add(d), nil
+}
+
+// Broadcast reports (GET), sets (POST) or clears (DELETE) the broadcast.
+// POST form fields: message (empty clears it), expires_in (optional, e.g. "2h").
+// Route: /api/broadcast (admin only)
+func (h *Handler) Broadcast(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := h.parseForm(w, r, 1<<20); err != nil {
+			return
+		}
+		message, expiresAt, err := parseBroadcastForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := h.broadcast.Set(message, expiresAt); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save the broadcast", "error", err)
+			http.Error(w, "Failed to save broadcast", http.StatusInternalServerError)
+			return
+		}
+		if message == "" {
+			slog.InfoContext(r.Context(), "Broadcast cleared")
+		} else {
+			slog.InfoContext(r.Context(), "Broadcast set", "message", message)
+		}
+	case http.MethodDelete:
+		if err := h.broadcast.Clear(); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to clear the broadcast", "error", err)
+			http.Error(w, "Failed to clear broadcast", http.StatusInternalServerError)
+			return
+		}
+		slog.InfoContext(r.Context(), "Broadcast cleared")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.broadcast.Current())
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcastBoard_SetAndPersist(t *testing.T) {
+	dir := t.TempDir()
+	b := NewBroadcastBoard(dir)
+	assert.Empty(t, b.Current().Message)
+
+	set, err := b.Set("OVH is having issues, hang tight", time.Time{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, set.ID)
+	assert.Equal(t, set, b.Current())
+
+	reloaded := NewBroadcastBoard(dir)
+	assert.Equal(t, "OVH is having issues, hang tight", reloaded.Current().Message, "survives a restart")
+	assert.Equal(t, set.ID, reloaded.Current().ID)
+
+	time.Sleep(time.Millisecond)
+	next, err := b.Set("Back to normal", time.Time{})
+	require.NoError(t, err)
+	assert.NotEqual(t, set.ID, next.ID, "a new message shows again after a dismiss")
+}
+
+func TestBroadcastBoard_Expires(t *testing.T) {
+	b := NewBroadcastBoard("")
+	_, err := b.Set("Labs restart at 14:00", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "Labs restart at 14:00", b.Current().Message)
+
+	b.broadcast.ExpiresAt = time.Now().Add(-time.Second)
+	assert.Equal(t, Broadcast{}, b.Current(), "an expired broadcast is not shown")
+}
+
+func TestBroadcastBoard_Clear(t *testing.T) {
+	dir := t.TempDir()
+	b := NewBroadcastBoard(dir)
+	_, err := b.Set("OVH incident", time.Time{})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Clear())
+	assert.Equal(t, Broadcast{}, b.Current())
+	assert.Equal(t, Broadcast{}, NewBroadcastBoard(dir).Current(), "the clear is persisted")
+}
+
+func TestBroadcastHandler(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	decode := func(rec *httptest.ResponseRecorder) Broadcast {
+		t.Helper()
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var b Broadcast
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &b))
+		return b
+	}
+
+	rec := httptest.NewRecorder()
+	h.Broadcast(rec, postForm(t, "/api/broadcast", url.Values{"message": {" OVH is having issues "}, "expires_in": {"2h"}}))
+	b := decode(rec)
+	assert.Equal(t, "OVH is having issues", b.Message)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), b.ExpiresAt, time.Minute)
+
+	rec = httptest.NewRecorder()
+	h.Broadcast(rec, httptest.NewRequest(http.MethodGet, "/api/broadcast", nil))
+	assert.Equal(t, b.ID, decode(rec).ID)
+
+	rec = httptest.NewRecorder()
+	h.Broadcast(rec, postForm(t, "/api/broadcast", url.Values{"message": {""}}))
+	assert.Equal(t, Broadcast{}, decode(rec), "an empty message clears it")
+
+	h.broadcast.Set("again", time.Time{})
+	rec = httptest.NewRecorder()
+	h.Broadcast(rec, httptest.NewRequest(http.MethodDelete, "/api/broadcast", nil))
+	assert.Equal(t, Broadcast{}, decode(rec))
+	assert.JSONEq(t, `{}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.Broadcast(rec, httptest.NewRequest(http.MethodPut, "/api/broadcast", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestBroadcastHandler_InvalidForm(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	for name, form := range map[string]url.Values{
+		"bad expiry":      {"message": {"hi"}, "expires_in": {"tomorrow"}},
+		"negative expiry": {"message": {"hi"}, "expires_in": {"-1h"}},
+		"two lines":       {"message": {"hi\nthere"}},
+		"too long":        {"message": {strings.Repeat("a", maxBroadcastLength+1)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.Broadcast(rec, postForm(t, "/api/broadcast", form))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Empty(t, h.broadcast.Current().Message)
+		})
+	}
+}
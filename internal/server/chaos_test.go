@@ -202,8 +202,8 @@ func TestChaos_CredentialsManager_ConcurrentSetAndGet(t *testing.T) {
 
 func TestChaos_AuthHandler_ConcurrentSessions(t *testing.T) {
 	ah := &AuthHandler{
-		sessions:        make(map[string]*Session),
-		studentSessions: make(map[string]*Session),
+		sessions:        NewMemorySessionStore(),
+		studentSessions: NewMemorySessionStore(),
 	}
 
 	var wg sync.WaitGroup
@@ -684,7 +684,7 @@ func TestChaos_JobManager_PersistenceWithCorruptedFile(t *testing.T) {
 
 func TestChaos_JobManager_RapidSaveAndLoad(t *testing.T) {
 	tempDir := t.TempDir()
-	jm := NewJobManager(tempDir)
+	jm := newTestJobManager(t, tempDir)
 
 	var wg sync.WaitGroup
 
@@ -772,15 +772,15 @@ func TestChaos_Recovery_AfterJobRemoval(t *testing.T) {
 
 func TestChaos_Recovery_SessionAfterExpiry(t *testing.T) {
 	ah := &AuthHandler{
-		sessions: make(map[string]*Session),
+		sessions: NewMemorySessionStore(),
 	}
 
 	// Create expired session manually
 	token := "expired-token"
-	ah.sessions[token] = &Session{
+	_ = ah.sessions.Put(&Session{
 		Token:     token,
 		ExpiresAt: time.Now().Add(-1 * time.Hour),
-	}
+	})
 
 	// Validate should fail
 	if ah.validateSession(token) {
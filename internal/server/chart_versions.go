@@ -0,0 +1,246 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"easylab/k8s"
+
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// helmIndexTTL is how long a chart repository's index is kept, and
+// helmIndexTimeout how long fetching it may take.
+const (
+	helmIndexTTL     = time.Hour
+	helmIndexTimeout = 15 * time.Second
+)
+
+// maxHelmIndexSize bounds the index read from a repository. The biggest
+// upstream ones (prometheus-community) are a few tens of MB.
+const maxHelmIndexSize = 64 << 20
+
+// helmIndex is the versions of each chart of a repository.
+type helmIndex map[string][]string
+
+type helmIndexEntry struct {
+	index   helmIndex
+	fetched time.Time
+}
+
+// helmIndexCache keeps each repository's index for helmIndexTTL, so creating
+// labs does not download the indexes every time.
+type helmIndexCache struct {
+	mu      sync.Mutex
+	entries map[string]helmIndexEntry
+}
+
+func (c *helmIndexCache) get(repo string, now time.Time) (helmIndex, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[repo]
+	if !ok || now.Sub(entry.fetched) > helmIndexTTL {
+		return nil, false
+	}
+	return entry.index, true
+}
+
+func (c *helmIndexCache) put(repo string, index helmIndex, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]helmIndexEntry)
+	}
+	c.entries[repo] = helmIndexEntry{index: index, fetched: now}
+}
+
+// fetchHelmIndex downloads and reads the index.yaml of a chart repository.
+func fetchHelmIndex(repo string) (helmIndex, error) {
+	client := &http.Client{Timeout: helmIndexTimeout}
+	resp, err := client.Get(strings.TrimRight(repo, "/") + "/index.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the index of %s: %w", repo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the index of %s: %s", repo, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHelmIndexSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the index of %s: %w", repo, err)
+	}
+	var raw struct {
+		Entries map[string][]struct {
+			Version string `yaml:"version"`
+		} `yaml:"entries"`
+	}
+	if err := yaml.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid index for %s: %w", repo, err)
+	}
+	index := make(helmIndex, len(raw.Entries))
+	for chart, versions := range raw.Entries {
+		for _, v := range versions {
+			index[chart] = append(index[chart], v.Version)
+		}
+	}
+	return index, nil
+}
+
+// helmIndex returns the index of repo, from the cache when it is fresh.
+func (h *Handler) helmIndex(repo string) (helmIndex, error) {
+	now := time.Now()
+	if index, ok := h.helmIndexCache.get(repo, now); ok {
+		return index, nil
+	}
+	index, err := fetchHelmIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+	h.helmIndexCache.put(repo, index, now)
+	return index, nil
+}
+
+// validateHelmChartVersions checks the chart versions a lab pins: charts the
+// labs install, each with a version.
+func validateHelmChartVersions(versions map[string]string) error {
+	for _, chart := range slices.Sorted(maps.Keys(versions)) {
+		if _, ok := k8s.PinnedCharts[chart]; !ok {
+			return fmt.Errorf("unknown chart %q: the labs install %s", chart, strings.Join(pinnedChartNames(), ", "))
+		}
+		if strings.TrimSpace(versions[chart]) == "" {
+			return fmt.Errorf("no version for the chart %s, use chart=version", chart)
+		}
+	}
+	return nil
+}
+
+func pinnedChartNames() []string {
+	return slices.Sorted(maps.Keys(k8s.PinnedCharts))
+}
+
+// checkHelmChartVersions checks that the chart versions the lab pins exist in
+// the repositories it pulls them from. A repository whose index cannot be read
+// is skipped: Helm has the last word during pulumi up.
+func (h *Handler) checkHelmChartVersions(cfg *LabConfig) error {
+	for _, chart := range slices.Sorted(maps.Keys(cfg.HelmChartVersions)) {
+		version := cfg.HelmChartVersions[chart]
+		repo := k8s.PinnedCharts[chart].Repo
+		if override := cfg.HelmRepoOverrides[chart]; override != "" {
+			repo = override
+		}
+		index, err := h.helmIndex(repo)
+		if err != nil {
+			slog.Warn("Skipping the version check of chart", "chart", chart, "error", err)
+			continue
+		}
+		if !slices.Contains(index[chart], version) {
+			return fmt.Errorf("chart %s has no version %s in %s", chart, version, repo)
+		}
+	}
+	return nil
+}
+
+// labCharts lists the charts the lab installs.
+func labCharts(cfg *LabConfig) []string {
+	var charts []string
+	if cfg.InstallCertManager == nil || *cfg.InstallCertManager {
+		if cfg.Domain != "" {
+			charts = append(charts, "cert-manager")
+		}
+	}
+	if cfg.UseExternalDNS {
+		charts = append(charts, "external-dns")
+	}
+	if cfg.InstallNginxIngress == nil || *cfg.InstallNginxIngress {
+		charts = append(charts, "ingress-nginx")
+	}
+	if cfg.InstallMonitoring {
+		charts = append(charts, "kube-prometheus-stack")
+	}
+	if cfg.NodePoolGPU {
+		charts = append(charts, "nvidia-device-plugin")
+	}
+	return charts
+}
+
+// helmChartVersionsSummary lists the version of each chart the lab installs,
+// for the dry-run and deployment output, e.g. "Helm charts: ingress-nginx
+// 4.11.3, cert-manager v1.15.0 (pinned by the lab)".
+func helmChartVersionsSummary(cfg *LabConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	var entries []string
+	for _, chart := range labCharts(cfg) {
+		entry := chart + " " + k8s.PinnedCharts[chart].Version
+		if v := cfg.HelmChartVersions[chart]; v != "" {
+			entry = chart + " " + v + " (pinned by the lab)"
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	return "Helm charts: " + strings.Join(entries, ", ")
+}
+
+// latestStableVersion returns the highest version in versions that is not a
+// pre-release, or "" if there is none. Chart versions may go with or without
+// a leading "v".
+func latestStableVersion(versions []string) string {
+	latest, latestSemver := "", ""
+	for _, v := range versions {
+		sv := "v" + strings.TrimPrefix(v, "v")
+		if !semver.IsValid(sv) || semver.Prerelease(sv) != "" {
+			continue
+		}
+		if latestSemver == "" || semver.Compare(sv, latestSemver) > 0 {
+			latest, latestSemver = v, sv
+		}
+	}
+	return latest
+}
+
+// WarnOutdatedCharts logs the pinned charts that have a newer stable version
+// upstream, so the pins are not left to age. It is meant to run once at
+// startup, in the background: the indexes can take a while to download.
+func (h *Handler) WarnOutdatedCharts() {
+	for _, chart := range pinnedChartNames() {
+		pinned := k8s.PinnedCharts[chart]
+		index, err := h.helmIndex(pinned.Repo)
+		if err != nil {
+			slog.Warn("Could not check a chart for updates", "chart", chart, "error", err)
+			continue
+		}
+		latest := latestStableVersion(index[chart])
+		if latest == "" {
+			continue
+		}
+		if semver.Compare("v"+strings.TrimPrefix(latest, "v"), "v"+strings.TrimPrefix(pinned.Version, "v")) > 0 {
+			slog.Info("Helm chart pinned behind the latest stable version", "chart", chart, "pinned", pinned.Version, "latest", latest)
+		}
+	}
+}
+
+// reportChartVersions records in the job's output the chart versions its
+// deployment installs.
+func (pe *PulumiExecutor) reportChartVersions(jobID string) {
+	job, exists := pe.jobManager.GetJob(jobID)
+	if !exists {
+		return
+	}
+	job.mu.RLock()
+	summary := helmChartVersionsSummary(job.Config)
+	job.mu.RUnlock()
+	if summary != "" {
+		pe.jobManager.AppendOutput(jobID, summary)
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"easylab/k8s"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chartRepo serves a Helm repository index listing the given ingress-nginx
+// versions, and counts the index downloads.
+func chartRepo(t *testing.T, versions ...string) (*httptest.Server, *int) {
+	t.Helper()
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		fetches++
+		body := "apiVersion: v1\nentries:\n  ingress-nginx:\n"
+		for _, v := range versions {
+			body += "  - name: ingress-nginx\n    version: " + v + "\n"
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &fetches
+}
+
+func TestValidateHelmChartVersions(t *testing.T) {
+	assert.NoError(t, validateHelmChartVersions(nil))
+	assert.NoError(t, validateHelmChartVersions(map[string]string{"ingress-nginx": "4.12.0", "cert-manager": "v1.17.0"}))
+	assert.Error(t, validateHelmChartVersions(map[string]string{"my-chart": "1.0.0"}), "unknown chart")
+	assert.Error(t, validateHelmChartVersions(map[string]string{"ingress-nginx": ""}), "no version")
+}
+
+func TestCheckHelmChartVersions(t *testing.T) {
+	srv, fetches := chartRepo(t, "4.12.0", "4.11.3")
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	cfg := func(version string) *LabConfig {
+		return &LabConfig{
+			HelmRepoOverrides: map[string]string{"ingress-nginx": srv.URL},
+			HelmChartVersions: map[string]string{"ingress-nginx": version},
+		}
+	}
+
+	assert.NoError(t, h.checkHelmChartVersions(cfg("4.12.0")))
+	err := h.checkHelmChartVersions(cfg("4.99.0"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no version 4.99.0")
+	assert.Equal(t, 1, *fetches, "the index is cached")
+
+	unreachable := &LabConfig{
+		HelmRepoOverrides: map[string]string{"ingress-nginx": "http://127.0.0.1:1"},
+		HelmChartVersions: map[string]string{"ingress-nginx": "4.99.0"},
+	}
+	assert.NoError(t, h.checkHelmChartVersions(unreachable), "an unreadable index skips the check")
+}
+
+func TestLatestStableVersion(t *testing.T) {
+	assert.Equal(t, "4.12.0", latestStableVersion([]string{"4.11.3", "4.12.0", "4.13.0-beta.1", "not-a-version"}))
+	assert.Equal(t, "v1.17.0", latestStableVersion([]string{"v1.16.2", "v1.17.0", "v1.18.0-alpha.0"}))
+	assert.Empty(t, latestStableVersion(nil))
+}
+
+func TestHelmChartVersionsSummary(t *testing.T) {
+	disabled := false
+	assert.Empty(t, helmChartVersionsSummary(&LabConfig{InstallNginxIngress: &disabled}))
+
+	got := helmChartVersionsSummary(&LabConfig{
+		Domain:            "lab.example.com",
+		InstallMonitoring: true,
+		HelmChartVersions: map[string]string{"ingress-nginx": "4.12.0"},
+	})
+	assert.Equal(t, "Helm charts: cert-manager "+k8s.PinnedCharts["cert-manager"].Version+
+		", ingress-nginx 4.12.0 (pinned by the lab), kube-prometheus-stack "+k8s.PinnedCharts["kube-prometheus-stack"].Version, got)
+}
+
+func TestGetConfigCommands_HelmChartVersions(t *testing.T) {
+	pe := &PulumiExecutor{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		assert.NotEqual(t, "helm:chartVersions", c.key, "no versions, no command")
+	}
+
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", HelmChartVersions: map[string]string{"ingress-nginx": "4.12.0"}}) {
+		got[c.key] = c.value
+	}
+	assert.JSONEq(t, `{"ingress-nginx": "4.12.0"}`, got["helm:chartVersions"])
+}
@@ -2,43 +2,14 @@ package server
 
 import (
 	"context"
-	"log"
-	"os"
-	"strconv"
+	"log/slog"
 	"time"
 )
 
-// cleanupInterval returns the workspace cleanup interval, configurable via CLEANUP_INTERVAL_MINUTES env var.
-// Defaults to 5 minutes.
-func cleanupInterval() time.Duration {
-	if v := os.Getenv("CLEANUP_INTERVAL_MINUTES"); v != "" {
-		if mins, err := strconv.Atoi(v); err == nil && mins > 0 {
-			return time.Duration(mins) * time.Minute
-		}
-	}
-	return 5 * time.Minute
-}
-
-// deletionRetryMaxRetries returns the maximum number of deletion retry attempts,
-// configurable via CLEANUP_DELETE_MAX_RETRIES env var. Defaults to 3.
-func deletionRetryMaxRetries() int {
-	if v := os.Getenv("CLEANUP_DELETE_MAX_RETRIES"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			return n
-		}
-	}
-	return 3
-}
-
-// deletionRetryInterval returns the minimum duration between deletion retry attempts,
-// configurable via CLEANUP_DELETE_RETRY_INTERVAL_HOURS env var. Defaults to 2 hours.
-func deletionRetryInterval() time.Duration {
-	if v := os.Getenv("CLEANUP_DELETE_RETRY_INTERVAL_HOURS"); v != "" {
-		if h, err := strconv.Atoi(v); err == nil && h > 0 {
-			return time.Duration(h) * time.Hour
-		}
-	}
-	return 2 * time.Hour
+// SetCleanupConfig sets how often expired workspaces are deleted and how
+// failed deletions are retried. Call it before StartWorkspaceCleanup.
+func (h *Handler) SetCleanupConfig(cfg CleanupConfig) {
+	h.cleanup = cfg
 }
 
 // clusterReachabilityTimeout bounds how long we probe the cluster API before skipping cleanup for a job.
@@ -48,8 +19,8 @@ const clusterReachabilityTimeout = 5 * time.Second
 // workspaces that have exceeded their configured lifetime and destroys labs past
 // their scheduled deletion date.
 func (h *Handler) StartWorkspaceCleanup(ctx context.Context) {
-	interval := cleanupInterval()
-	log.Printf("[cleanup] cleanup batch interval: %v (set CLEANUP_INTERVAL_MINUTES to override)", interval)
+	interval := time.Duration(max(h.cleanup.IntervalMinutes, 1)) * time.Minute
+	slog.InfoContext(ctx, "Cleanup batch interval (set cleanup.interval_minutes to override)", "component", "cleanup", "interval", interval)
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
@@ -63,10 +34,10 @@ func (h *Handler) StartWorkspaceCleanup(ctx context.Context) {
 	}
 }
 
-// cleanupExpiredWorkspaces iterates all jobs and deletes workspaces older than
+// cleanupExpiredWorkspaces iterates all labs and deletes workspaces older than
 // their configured WorkspaceLifetimeHours.
 func (h *Handler) cleanupExpiredWorkspaces() {
-	jobs := h.jobManager.GetAllJobs()
+	jobs := h.jobManager.GetLabs()
 	for _, job := range jobs {
 		if job.Status != JobStatusCompleted {
 			continue
@@ -77,7 +48,7 @@ func (h *Handler) cleanupExpiredWorkspaces() {
 
 		backend, err := h.newWorkspaceBackend(extractStringFromConfigValue(job.Kubeconfig), job.workspaceNamespace())
 		if err != nil {
-			log.Printf("[cleanup] skipping job %s: failed to build backend: %v", job.ID, err)
+			slog.Error("Skipping job: failed to build backend", "component", "cleanup", "job_id", job.ID, "error", err)
 			continue
 		}
 
@@ -85,23 +56,23 @@ func (h *Handler) cleanupExpiredWorkspaces() {
 		reachable := backend.Reachable(reachCtx)
 		cancel()
 		if !reachable {
-			log.Printf("[cleanup] skipping job %s: cluster API unreachable", job.ID)
+			slog.Warn("Skipping job: cluster API unreachable", "component", "cleanup", "job_id", job.ID)
 			continue
 		}
 
 		lifetime := time.Duration(job.Config.WorkspaceLifetimeHours) * time.Hour
 		workspaces, err := backend.ListWorkspaces(context.Background(), job.ID)
 		if err != nil {
-			log.Printf("[cleanup] failed to list workspaces for job %s: %v", job.ID, err)
+			slog.Error("Failed to list workspaces for job", "component", "cleanup", "job_id", job.ID, "error", err)
 			continue
 		}
 		// Record current workspace count before any deletions.
 		if err := h.jobManager.RecordWorkspaceSnapshot(job.ID, len(workspaces)); err != nil {
-			log.Printf("[cleanup] failed to record workspace snapshot for job %s: %v", job.ID, err)
+			slog.Error("Failed to record workspace snapshot for job", "component", "cleanup", "job_id", job.ID, "error", err)
 		}
 
-		maxRetries := deletionRetryMaxRetries()
-		retryInterval := deletionRetryInterval()
+		maxRetries := max(h.cleanup.DeleteMaxRetries, 1)
+		retryInterval := time.Duration(max(h.cleanup.DeleteRetryIntervalHours, 1)) * time.Hour
 
 		deleted := 0
 		for _, ws := range workspaces {
@@ -115,43 +86,44 @@ func (h *Handler) cleanupExpiredWorkspaces() {
 			job.mu.RUnlock()
 
 			if retry != nil && retry.GiveUp {
-				log.Printf("[cleanup] skipping workspace %s (%s) in job %s: max retries (%d) exhausted", ws.Name, wsID, job.ID, maxRetries)
+				slog.Warn("Skipping workspace: max retries exhausted", "component", "cleanup", "workspace", ws.Name, "workspace_id", wsID, "job_id", job.ID, "max_retries", maxRetries)
 				continue
 			}
 			if retry != nil && time.Since(retry.LastAttempt) < retryInterval {
 				remaining := (retryInterval - time.Since(retry.LastAttempt)).Round(time.Minute)
-				log.Printf("[cleanup] skipping workspace %s (%s) in job %s: next retry in %v (attempt %d/%d)", ws.Name, wsID, job.ID, remaining, retry.Attempts, maxRetries)
+				slog.Info("Skipping workspace until its next retry", "component", "cleanup", "workspace", ws.Name, "workspace_id", wsID, "job_id", job.ID, "next_retry_in", remaining, "attempt", retry.Attempts, "max_retries", maxRetries)
 				continue
 			}
 
-			log.Printf("[cleanup] deleting workspace %s (%s) in job %s: exceeded %dh lifetime", ws.Name, wsID, job.ID, job.Config.WorkspaceLifetimeHours)
+			slog.Info("Deleting workspace: it exceeded its lifetime", "component", "cleanup", "workspace", ws.Name, "workspace_id", wsID, "job_id", job.ID, "lifetime_hours", job.Config.WorkspaceLifetimeHours)
 			delErr := backend.DeleteWorkspace(context.Background(), job.ID, wsID)
 			if delErr != nil {
-				log.Printf("[cleanup] failed to delete workspace %s: %v", wsID, delErr)
+				slog.Error("Failed to delete workspace", "component", "cleanup", "workspace_id", wsID, "error", delErr)
 				if ferr := h.jobManager.RecordDeletionFailure(job.ID, wsID, ws.Name, maxRetries); ferr != nil {
-					log.Printf("[cleanup] failed to record deletion failure for workspace %s: %v", wsID, ferr)
+					slog.Error("Failed to record deletion failure", "component", "cleanup", "workspace_id", wsID, "error", ferr)
 				}
 			} else {
 				deleted++
 				if cerr := h.jobManager.ClearDeletionRetry(job.ID, wsID); cerr != nil {
-					log.Printf("[cleanup] failed to clear deletion retry for workspace %s: %v", wsID, cerr)
+					slog.Error("Failed to clear deletion retry for workspace", "component", "cleanup", "workspace_id", wsID, "error", cerr)
 				}
 			}
 		}
 		if deleted > 0 {
 			if err := h.jobManager.RecordCleanupEvent(job.ID, deleted); err != nil {
-				log.Printf("[cleanup] failed to record cleanup event for job %s: %v", job.ID, err)
+				slog.Error("Failed to record cleanup event for job", "component", "cleanup", "job_id", job.ID, "error", err)
 			} else if err := h.jobManager.SaveJob(job.ID); err != nil {
-				log.Printf("[cleanup] failed to persist cleanup event for job %s: %v", job.ID, err)
+				slog.Error("Failed to persist cleanup event for job", "component", "cleanup", "job_id", job.ID, "error", err)
 			}
 		}
 	}
 }
 
-// cleanupExpiredLabs iterates all completed jobs and destroys those that have passed
-// their configured LabDeletionDate.
+// cleanupExpiredLabs iterates all completed labs and destroys those that have passed
+// their configured LabDeletionDate, except the protected ones. Template jobs
+// are never destroyed.
 func (h *Handler) cleanupExpiredLabs() {
-	jobs := h.jobManager.GetAllJobs()
+	jobs := h.jobManager.GetLabs()
 	for _, job := range jobs {
 		if job.Status != JobStatusCompleted {
 			continue
@@ -163,15 +135,19 @@ func (h *Handler) cleanupExpiredLabs() {
 			continue
 		}
 		jobID := job.ID
-		log.Printf("[cleanup] scheduling automatic lab deletion for job %s (deletion date: %s)", jobID, job.Config.LabDeletionDate.Format("2006-01-02"))
+		if err := h.jobManager.checkNotProtected(jobID); err != nil {
+			slog.Warn("Skipping automatic lab deletion", "component", "cleanup", "error", err)
+			continue
+		}
+		slog.Info("Scheduling automatic lab deletion", "component", "cleanup", "job_id", jobID, "deletion_date", job.Config.LabDeletionDate.Format("2006-01-02"))
 		// Mark as running immediately to prevent duplicate destroy on the next tick.
 		if err := h.jobManager.UpdateJobStatus(jobID, JobStatusRunning); err != nil {
-			log.Printf("[cleanup] failed to mark job %s as running before deletion: %v", jobID, err)
+			slog.Error("Failed to mark job as running before deletion", "component", "cleanup", "job_id", jobID, "error", err)
 			continue
 		}
 		go func(id string) {
 			if err := h.pulumiExec.Destroy(id); err != nil {
-				log.Printf("[cleanup] automatic lab deletion failed for job %s: %v", id, err)
+				slog.Error("Automatic lab deletion failed for job", "component", "cleanup", "job_id", id, "error", err)
 			}
 		}(jobID)
 	}
@@ -91,7 +91,7 @@ func TestCleanupExpiredWorkspaces_ReachableNoWorkspaces(t *testing.T) {
 }
 
 func TestCleanupExpiredWorkspaces_DeletesExpiredWorkspace(t *testing.T) {
-	jm := NewJobManager(t.TempDir())
+	jm := newTestJobManager(t, t.TempDir())
 	id := completedLabWithKubeconfig(jm, 1) // 1h lifetime
 
 	fb := &fakeBackend{
@@ -167,7 +167,6 @@ func TestCleanupExpiredWorkspaces_SkipsWorkspaceWithGiveUp(t *testing.T) {
 }
 
 func TestCleanupExpiredWorkspaces_SkipsWorkspaceBeforeRetryInterval(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_RETRY_INTERVAL_HOURS", "2")
 	jm := NewJobManager("")
 	id := completedLabWithKubeconfig(jm, 1)
 
@@ -217,72 +216,6 @@ type assertErr string
 
 func (e assertErr) Error() string { return string(e) }
 
-// --- cleanupInterval tests ---
-
-func TestCleanupInterval_Default(t *testing.T) {
-	t.Setenv("CLEANUP_INTERVAL_MINUTES", "")
-	assert.Equal(t, 5*time.Minute, cleanupInterval())
-}
-
-func TestCleanupInterval_EnvVar(t *testing.T) {
-	t.Setenv("CLEANUP_INTERVAL_MINUTES", "15")
-	assert.Equal(t, 15*time.Minute, cleanupInterval())
-}
-
-func TestCleanupInterval_InvalidEnvVar(t *testing.T) {
-	t.Setenv("CLEANUP_INTERVAL_MINUTES", "not-a-number")
-	assert.Equal(t, 5*time.Minute, cleanupInterval())
-}
-
-func TestCleanupInterval_ZeroEnvVar(t *testing.T) {
-	t.Setenv("CLEANUP_INTERVAL_MINUTES", "0")
-	assert.Equal(t, 5*time.Minute, cleanupInterval())
-}
-
-// --- deletionRetryMaxRetries tests ---
-
-func TestDeletionRetryMaxRetries_Default(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_MAX_RETRIES", "")
-	assert.Equal(t, 3, deletionRetryMaxRetries())
-}
-
-func TestDeletionRetryMaxRetries_EnvVar(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_MAX_RETRIES", "5")
-	assert.Equal(t, 5, deletionRetryMaxRetries())
-}
-
-func TestDeletionRetryMaxRetries_Invalid(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_MAX_RETRIES", "not-a-number")
-	assert.Equal(t, 3, deletionRetryMaxRetries())
-}
-
-func TestDeletionRetryMaxRetries_Zero(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_MAX_RETRIES", "0")
-	assert.Equal(t, 3, deletionRetryMaxRetries())
-}
-
-// --- deletionRetryInterval tests ---
-
-func TestDeletionRetryInterval_Default(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_RETRY_INTERVAL_HOURS", "")
-	assert.Equal(t, 2*time.Hour, deletionRetryInterval())
-}
-
-func TestDeletionRetryInterval_EnvVar(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_RETRY_INTERVAL_HOURS", "4")
-	assert.Equal(t, 4*time.Hour, deletionRetryInterval())
-}
-
-func TestDeletionRetryInterval_Invalid(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_RETRY_INTERVAL_HOURS", "bad")
-	assert.Equal(t, 2*time.Hour, deletionRetryInterval())
-}
-
-func TestDeletionRetryInterval_Zero(t *testing.T) {
-	t.Setenv("CLEANUP_DELETE_RETRY_INTERVAL_HOURS", "0")
-	assert.Equal(t, 2*time.Hour, deletionRetryInterval())
-}
-
 // --- cleanupExpiredLabs tests ---
 
 func TestCleanupExpiredLabs_NoJobs(t *testing.T) {
@@ -356,3 +289,21 @@ func TestCleanupExpiredLabs_MarksRunningForPastDate(t *testing.T) {
 	job.mu.RUnlock()
 	assert.Equal(t, JobStatusRunning, status, "job past deletion date should be marked Running immediately")
 }
+
+func TestCleanupExpiredLabs_SkipsProtectedLab(t *testing.T) {
+	t.Parallel()
+	past := time.Now().Add(-1 * time.Hour)
+	jm := NewJobManager("")
+	id := jm.CreateJob(&LabConfig{StackName: "test", LabDeletionDate: &past})
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	require.NoError(t, jm.SetProtected(id, true, "127.0.0.1", ""))
+
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	h.cleanupExpiredLabs()
+
+	job, _ := jm.GetJob(id)
+	job.mu.RLock()
+	status := job.Status
+	job.mu.RUnlock()
+	assert.Equal(t, JobStatusCompleted, status, "protected job past its deletion date should not be destroyed")
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// ClusterInfo is what the admin sees of an OVH lab's cluster at a glance, read
+// back from the stack outputs after each deployment or scale.
+type ClusterInfo struct {
+	// URL is the cluster's API server.
+	URL string `json:"url,omitempty"`
+	// NodePoolStatus is OVH's status of the node pool, e.g. READY.
+	NodePoolStatus string `json:"node_pool_status,omitempty"`
+	CurrentNodes   int    `json:"current_nodes"`
+	AvailableNodes int    `json:"available_nodes"`
+	UpToDateNodes  int    `json:"up_to_date_nodes"`
+}
+
+// clusterInfoFromOutputs reads the cluster card from the outputs of a lab's
+// stack. It returns nil for stacks without an OVH cluster, and for labs
+// deployed before the cluster URL was exported.
+func (pe *PulumiExecutor) clusterInfoFromOutputs(outputs auto.OutputMap) *ClusterInfo {
+	urlVal, ok := outputs["kubeClusterUrl"]
+	if !ok {
+		return nil
+	}
+	info := &ClusterInfo{URL: pe.outputValueToString(urlVal.Value)}
+	// A lab spread over availability zones has one node pool per zone: the
+	// card adds their nodes up, and lists each status that differs.
+	var statuses []string
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("nodePool%d", i)
+		status, ok := outputs[prefix+"Status"]
+		if !ok {
+			break
+		}
+		if s := pe.outputValueToString(status.Value); s != "" && !slices.Contains(statuses, s) {
+			statuses = append(statuses, s)
+		}
+		info.CurrentNodes += outputInt(outputs, prefix+"CurrentNodes")
+		info.AvailableNodes += outputInt(outputs, prefix+"AvailableNodes")
+		info.UpToDateNodes += outputInt(outputs, prefix+"UpToDateNodes")
+	}
+	info.NodePoolStatus = strings.Join(statuses, ", ")
+	return info
+}
+
+// outputInt reads an integer stack output, which the Automation API decodes
+// from JSON as a float64. A missing output reads as 0.
+func outputInt(outputs auto.OutputMap, key string) int {
+	out, ok := outputs[key]
+	if !ok {
+		return 0
+	}
+	switch v := out.Value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// reportClusterInfo records the cluster card of a job from its stack outputs.
+func (pe *PulumiExecutor) reportClusterInfo(jobID string, outputs auto.OutputMap) {
+	info := pe.clusterInfoFromOutputs(outputs)
+	if info == nil {
+		return
+	}
+	pe.jobManager.SetClusterInfo(jobID, info)
+	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Cluster API server: %s (%d nodes available)", info.URL, info.AvailableNodes))
+}
+
+// clusterCardHTML renders the Cluster card of the job page.
+func clusterCardHTML(info *ClusterInfo, kubeVersion string) string {
+	var b strings.Builder
+	b.WriteString(`<div class="cluster-card"><h3>Cluster</h3>`)
+	row := func(label, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString(fmt.Sprintf(`<div class="credential-item"><label>%s:</label><div class="value">%s</div></div>`, label, value))
+	}
+	row("API server", "<code>"+template.HTMLEscapeString(info.URL)+"</code>")
+	row("Kubernetes", template.HTMLEscapeString(kubeVersion))
+	nodes := fmt.Sprintf("%d available, %d up to date, %d in total", info.AvailableNodes, info.UpToDateNodes, info.CurrentNodes)
+	if info.NodePoolStatus != "" {
+		nodes += " (" + template.HTMLEscapeString(info.NodePoolStatus) + ")"
+	}
+	row("Nodes", nodes)
+	b.WriteString(`</div>`)
+	return b.String()
+}
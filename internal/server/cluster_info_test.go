@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterInfoFromOutputs(t *testing.T) {
+	pe := &PulumiExecutor{}
+	info := pe.clusterInfoFromOutputs(auto.OutputMap{
+		"kubeClusterUrl":          {Value: "https://abc123.c1.gra9.k8s.ovh.net"},
+		"nodePool1Status":         {Value: "READY"},
+		"nodePool1CurrentNodes":   {Value: float64(3)},
+		"nodePool1AvailableNodes": {Value: float64(2)},
+		"nodePool1UpToDateNodes":  {Value: float64(3)},
+	})
+	require.NotNil(t, info)
+	assert.Equal(t, ClusterInfo{URL: "https://abc123.c1.gra9.k8s.ovh.net", NodePoolStatus: "READY", CurrentNodes: 3, AvailableNodes: 2, UpToDateNodes: 3}, *info)
+
+	assert.Nil(t, pe.clusterInfoFromOutputs(auto.OutputMap{"kubeconfig": {Value: "apiVersion: v1"}}), "no cluster URL, no card")
+}
+
+func TestClusterInfoFromOutputs_OnePoolPerZone(t *testing.T) {
+	pe := &PulumiExecutor{}
+	info := pe.clusterInfoFromOutputs(auto.OutputMap{
+		"kubeClusterUrl":          {Value: "https://abc123.c1.eu-west-par.k8s.ovh.net"},
+		"nodePool1Status":         {Value: "READY"},
+		"nodePool1CurrentNodes":   {Value: float64(2)},
+		"nodePool1AvailableNodes": {Value: float64(2)},
+		"nodePool1UpToDateNodes":  {Value: float64(2)},
+		"nodePool2Status":         {Value: "READY"},
+		"nodePool2CurrentNodes":   {Value: float64(2)},
+		"nodePool2AvailableNodes": {Value: float64(2)},
+		"nodePool2UpToDateNodes":  {Value: float64(2)},
+		"nodePool3Status":         {Value: "RESIZING"},
+		"nodePool3CurrentNodes":   {Value: float64(2)},
+		"nodePool3AvailableNodes": {Value: float64(1)},
+		"nodePool3UpToDateNodes":  {Value: float64(2)},
+	})
+	require.NotNil(t, info)
+	assert.Equal(t, ClusterInfo{URL: "https://abc123.c1.eu-west-par.k8s.ovh.net", NodePoolStatus: "READY, RESIZING", CurrentNodes: 6, AvailableNodes: 5, UpToDateNodes: 6}, *info)
+}
+
+func TestClusterCardHTML(t *testing.T) {
+	html := clusterCardHTML(&ClusterInfo{URL: "https://abc123.c1.gra9.k8s.ovh.net", NodePoolStatus: "READY", CurrentNodes: 3, AvailableNodes: 3, UpToDateNodes: 3}, "1.31.1")
+	assert.Contains(t, html, "<h3>Cluster</h3>")
+	assert.Contains(t, html, "https://abc123.c1.gra9.k8s.ovh.net")
+	assert.Contains(t, html, "1.31.1")
+	assert.Contains(t, html, "3 available, 3 up to date, 3 in total (READY)")
+
+	assert.NotContains(t, clusterCardHTML(&ClusterInfo{URL: "https://x"}, ""), "Kubernetes:")
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ipvsSchedulers are the kube-proxy IPVS schedulers OVH accepts.
+var ipvsSchedulers = []string{"rr", "lc", "dh", "sh", "sed", "nq"}
+
+// hasClusterOptions reports whether the lab sets any advanced cluster option.
+func hasClusterOptions(cfg *LabConfig) bool {
+	return cfg.K8sKubeProxyMode != "" || cfg.K8sIPVSScheduler != "" || cfg.K8sPrivateNetworkRouting || cfg.K8sPlan != ""
+}
+
+// validateClusterOptions checks the advanced cluster options, and the
+// combinations OVH refuses or that would cost the lab its cluster. A lab that
+// sets none is left as it always was.
+func validateClusterOptions(cfg *LabConfig) error {
+	if !hasClusterOptions(cfg) || cfg.UseExistingCluster {
+		return nil
+	}
+	if cfg.Provider == "azure" {
+		return fmt.Errorf("advanced cluster options are only supported on OVHcloud")
+	}
+	switch cfg.K8sKubeProxyMode {
+	case "", "iptables", "ipvs":
+	default:
+		return fmt.Errorf("invalid kube-proxy mode %q (want iptables or ipvs)", cfg.K8sKubeProxyMode)
+	}
+	if cfg.K8sIPVSScheduler != "" {
+		if !slices.Contains(ipvsSchedulers, cfg.K8sIPVSScheduler) {
+			return fmt.Errorf("invalid IPVS scheduler %q (want one of %v)", cfg.K8sIPVSScheduler, ipvsSchedulers)
+		}
+		if cfg.K8sKubeProxyMode != "ipvs" {
+			return fmt.Errorf("an IPVS scheduler needs the ipvs kube-proxy mode")
+		}
+	}
+	switch cfg.K8sPlan {
+	case "", "free", "standard":
+	default:
+		return fmt.Errorf("invalid cluster plan %q (want free or standard)", cfg.K8sPlan)
+	}
+	// With DHCP off and no gateway IP, the nodes would have no route out.
+	if cfg.K8sPrivateNetworkRouting && cfg.NetworkDHCP != nil && !*cfg.NetworkDHCP && cfg.NetworkGatewayIP == "" {
+		return fmt.Errorf("routing node traffic through the private network needs DHCP or a gateway IP on the subnet")
+	}
+	// OVH recreates a cluster whose proxy mode, plan or private network
+	// configuration changes, so an adopted cluster must keep its own.
+	if cfg.ImportClusterID != "" {
+		return fmt.Errorf("advanced cluster options cannot be set on an imported cluster: changing them would recreate it")
+	}
+	return nil
+}
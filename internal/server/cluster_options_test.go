@@ -0,0 +1,41 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateClusterOptions(t *testing.T) {
+	dhcpOff := false
+	tests := []struct {
+		name    string
+		cfg     LabConfig
+		wantErr string
+	}{
+		{"no options", LabConfig{}, ""},
+		{"no options on an imported cluster", LabConfig{ImportClusterID: "kube-1"}, ""},
+		{"ipvs with a scheduler", LabConfig{K8sKubeProxyMode: "ipvs", K8sIPVSScheduler: "lc"}, ""},
+		{"iptables on the standard plan", LabConfig{K8sKubeProxyMode: "iptables", K8sPlan: "standard"}, ""},
+		{"private routing with DHCP", LabConfig{K8sPrivateNetworkRouting: true}, ""},
+		{"private routing with a gateway IP", LabConfig{K8sPrivateNetworkRouting: true, NetworkDHCP: &dhcpOff, NetworkGatewayIP: "10.0.0.1"}, ""},
+		{"ignored on an existing cluster", LabConfig{UseExistingCluster: true, K8sKubeProxyMode: "nftables"}, ""},
+		{"unknown mode", LabConfig{K8sKubeProxyMode: "nftables"}, `invalid kube-proxy mode "nftables"`},
+		{"unknown scheduler", LabConfig{K8sKubeProxyMode: "ipvs", K8sIPVSScheduler: "wrr"}, `invalid IPVS scheduler "wrr"`},
+		{"scheduler without ipvs", LabConfig{K8sIPVSScheduler: "rr"}, "needs the ipvs kube-proxy mode"},
+		{"unknown plan", LabConfig{K8sPlan: "premium"}, `invalid cluster plan "premium"`},
+		{"private routing without a route out", LabConfig{K8sPrivateNetworkRouting: true, NetworkDHCP: &dhcpOff}, "needs DHCP or a gateway IP"},
+		{"imported cluster", LabConfig{ImportClusterID: "kube-1", K8sPlan: "standard"}, "cannot be set on an imported cluster"},
+		{"azure", LabConfig{Provider: "azure", K8sKubeProxyMode: "ipvs"}, "only supported on OVHcloud"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateClusterOptions(&tt.cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
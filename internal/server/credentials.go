@@ -2,11 +2,28 @@ package server
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 )
 
+// DefaultOVHEndpoint is the OVH API endpoint used when the credentials form
+// leaves it empty, unless the server is started with another one.
+const DefaultOVHEndpoint = "ovh-eu"
+
+// ovhEndpoints are the API endpoints the OVH provider knows.
+var ovhEndpoints = []string{"ovh-eu", "ovh-us", "ovh-ca", "kimsufi-eu", "kimsufi-ca", "soyoustart-eu", "soyoustart-ca"}
+
+// validateOVHEndpoint rejects an endpoint the OVH provider would not know.
+func validateOVHEndpoint(endpoint string) error {
+	if !slices.Contains(ovhEndpoints, endpoint) {
+		return fmt.Errorf("unknown OVH endpoint %q (expected one of %s)", endpoint, strings.Join(ovhEndpoints, ", "))
+	}
+	return nil
+}
+
 // OVHCredentials holds OVH API credentials
 type OVHCredentials struct {
 	ApplicationKey    string `json:"application_key"`
@@ -16,6 +33,30 @@ type OVHCredentials struct {
 	Endpoint          string `json:"endpoint"`
 }
 
+// setOVHCredentials copies the current OVH credentials into a lab's config,
+// from where ovhEnvVars hands them to the Pulumi program.
+func (c *LabConfig) setOVHCredentials(creds *OVHCredentials) {
+	c.OvhApplicationKey = creds.ApplicationKey
+	c.OvhApplicationSecret = creds.ApplicationSecret
+	c.OvhConsumerKey = creds.ConsumerKey
+	c.OvhServiceName = creds.ServiceName
+	c.OvhEndpoint = creds.Endpoint
+}
+
+// setProviderCredentials copies the stored credentials of the lab's provider
+// into its config.
+func (c *LabConfig) setProviderCredentials(creds ProviderCredentials) {
+	switch creds := creds.(type) {
+	case *OVHCredentials:
+		c.setOVHCredentials(creds)
+	case *AzureCredentials:
+		c.AzureClientID = creds.ClientID
+		c.AzureClientSecret = creds.ClientSecret
+		c.AzureTenantID = creds.TenantID
+		c.AzureSubscriptionID = creds.SubscriptionID
+	}
+}
+
 // AzureCredentials holds Azure Service Principal credentials
 type AzureCredentials struct {
 	ClientID       string `json:"client_id"`
@@ -82,7 +123,10 @@ func loadAzureCredentialsFromEnv() *AzureCredentials {
 // CredentialsManager manages credentials for multiple providers in memory
 type CredentialsManager struct {
 	credentials map[string]ProviderCredentials // key is provider name
-	mu          sync.RWMutex
+	// defaultOVHEndpoint fills the endpoint of OVH credentials saved without
+	// one; empty means DefaultOVHEndpoint.
+	defaultOVHEndpoint string
+	mu                 sync.RWMutex
 }
 
 // NewCredentialsManager creates a new credentials manager and loads credentials from environment variables
@@ -93,23 +137,51 @@ func NewCredentialsManager() *CredentialsManager {
 
 	if creds := loadOVHCredentialsFromEnv(); creds != nil {
 		if err := cm.SetCredentials(creds); err != nil {
-			log.Printf("Warning: Failed to load OVH credentials from environment variables: %v", err)
+			slog.Warn("Failed to load OVH credentials from environment variables", "error", err)
 		} else {
-			log.Printf("[STARTUP] OVH credentials loaded from environment variables")
+			slog.Info("OVH credentials loaded from environment variables", "component", "startup")
 		}
 	}
 
 	if creds := loadAzureCredentialsFromEnv(); creds != nil {
 		if err := cm.SetCredentials(creds); err != nil {
-			log.Printf("Warning: Failed to load Azure credentials from environment variables: %v", err)
+			slog.Warn("Failed to load Azure credentials from environment variables", "error", err)
 		} else {
-			log.Printf("[STARTUP] Azure credentials loaded from environment variables")
+			slog.Info("Azure credentials loaded from environment variables", "component", "startup")
 		}
 	}
 
 	return cm
 }
 
+// SetDefaultOVHEndpoint sets the endpoint used when OVH credentials are saved
+// without one.
+func (cm *CredentialsManager) SetDefaultOVHEndpoint(endpoint string) error {
+	if err := validateOVHEndpoint(endpoint); err != nil {
+		return err
+	}
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.defaultOVHEndpoint = endpoint
+	return nil
+}
+
+// DefaultOVHEndpoint returns the endpoint used when OVH credentials are saved
+// without one.
+func (cm *CredentialsManager) DefaultOVHEndpoint() string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.ovhEndpointFallback()
+}
+
+// ovhEndpointFallback is DefaultOVHEndpoint for callers holding cm.mu.
+func (cm *CredentialsManager) ovhEndpointFallback() string {
+	if cm.defaultOVHEndpoint == "" {
+		return DefaultOVHEndpoint
+	}
+	return cm.defaultOVHEndpoint
+}
+
 // SetCredentials stores provider credentials in memory
 func (cm *CredentialsManager) SetCredentials(creds ProviderCredentials) error {
 	switch c := creds.(type) {
@@ -134,6 +206,68 @@ func (cm *CredentialsManager) SetCredentials(creds ProviderCredentials) error {
 	return nil
 }
 
+// UpdateOVHCredentials merges update into the stored OVH credentials: an empty
+// field in update keeps the stored value, so rotating one key does not mean
+// re-entering the other four. With replaceAll, update must be complete and
+// replaces the stored credentials outright. An endpoint left empty either way
+// falls back to the server's default endpoint. The merged result is validated
+// like SetCredentials. It returns the names (form field names, e.g.
+// "consumer_key") of the fields whose value changed.
+func (cm *CredentialsManager) UpdateOVHCredentials(update *OVHCredentials, replaceAll bool) ([]string, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	merged := *update
+	var existing OVHCredentials
+	if c, ok := cm.credentials["ovh"].(*OVHCredentials); ok {
+		existing = *c
+	}
+	if !replaceAll {
+		keepIfEmpty(&merged.ApplicationKey, existing.ApplicationKey)
+		keepIfEmpty(&merged.ApplicationSecret, existing.ApplicationSecret)
+		keepIfEmpty(&merged.ConsumerKey, existing.ConsumerKey)
+		keepIfEmpty(&merged.ServiceName, existing.ServiceName)
+		keepIfEmpty(&merged.Endpoint, existing.Endpoint)
+	}
+	keepIfEmpty(&merged.Endpoint, cm.ovhEndpointFallback())
+	if merged.ApplicationKey == "" || merged.ApplicationSecret == "" ||
+		merged.ConsumerKey == "" || merged.ServiceName == "" || merged.Endpoint == "" {
+		if replaceAll {
+			return nil, fmt.Errorf("all OVH credentials are required to replace them")
+		}
+		return nil, fmt.Errorf("all OVH credentials are required (missing fields have no stored value to keep)")
+	}
+	if err := validateOVHEndpoint(merged.Endpoint); err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, f := range []struct {
+		name     string
+		old, new string
+	}{
+		{"application_key", existing.ApplicationKey, merged.ApplicationKey},
+		{"application_secret", existing.ApplicationSecret, merged.ApplicationSecret},
+		{"consumer_key", existing.ConsumerKey, merged.ConsumerKey},
+		{"service_name", existing.ServiceName, merged.ServiceName},
+		{"endpoint", existing.Endpoint, merged.Endpoint},
+	} {
+		if f.old != f.new {
+			changed = append(changed, f.name)
+		}
+	}
+
+	cm.credentials["ovh"] = &merged
+	return changed, nil
+}
+
+// keepIfEmpty sets *field to stored when it is empty.
+func keepIfEmpty(field *string, stored string) {
+	if *field == "" {
+		*field = stored
+	}
+}
+
 // GetCredentials retrieves credentials for a specific provider
 func (cm *CredentialsManager) GetCredentials(providerName string) (ProviderCredentials, error) {
 	cm.mu.RLock()
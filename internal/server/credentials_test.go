@@ -1,7 +1,11 @@
 package server
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -398,3 +402,198 @@ func TestCredentialsManager_GetAzureCredentials_Configured(t *testing.T) {
 		t.Errorf("GetAzureCredentials() ClientID = %q, want client", got.ClientID)
 	}
 }
+
+func TestCredentialsManager_UpdateOVHCredentials_MergesSingleField(t *testing.T) {
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	if err := cm.SetCredentials(&OVHCredentials{
+		ApplicationKey:    "key",
+		ApplicationSecret: "secret",
+		ConsumerKey:       "old-consumer",
+		ServiceName:       "service",
+		Endpoint:          "ovh-eu",
+	}); err != nil {
+		t.Fatalf("SetCredentials() error = %v", err)
+	}
+
+	changed, err := cm.UpdateOVHCredentials(&OVHCredentials{ConsumerKey: "new-consumer"}, false)
+	if err != nil {
+		t.Fatalf("UpdateOVHCredentials() error = %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "consumer_key" {
+		t.Errorf("changed = %v, want [consumer_key]", changed)
+	}
+
+	got, err := cm.GetOVHCredentials()
+	if err != nil {
+		t.Fatalf("GetOVHCredentials() error = %v", err)
+	}
+	want := OVHCredentials{
+		ApplicationKey:    "key",
+		ApplicationSecret: "secret",
+		ConsumerKey:       "new-consumer",
+		ServiceName:       "service",
+		Endpoint:          "ovh-eu",
+	}
+	if *got != want {
+		t.Errorf("credentials = %+v, want %+v", *got, want)
+	}
+}
+
+func TestCredentialsManager_UpdateOVHCredentials_UnchangedValue(t *testing.T) {
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	full := &OVHCredentials{ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p", Endpoint: "ovh-eu"}
+	if _, err := cm.UpdateOVHCredentials(full, false); err != nil {
+		t.Fatalf("UpdateOVHCredentials() error = %v", err)
+	}
+
+	changed, err := cm.UpdateOVHCredentials(&OVHCredentials{Endpoint: "ovh-eu"}, false)
+	if err != nil {
+		t.Fatalf("UpdateOVHCredentials() error = %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("changed = %v, want none", changed)
+	}
+}
+
+func TestCredentialsManager_UpdateOVHCredentials_NothingStored(t *testing.T) {
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	if _, err := cm.UpdateOVHCredentials(&OVHCredentials{ConsumerKey: "c"}, false); err == nil {
+		t.Error("UpdateOVHCredentials() with no stored value to merge should fail")
+	}
+	if cm.HasCredentials("ovh") {
+		t.Error("a failed update must not store anything")
+	}
+}
+
+func TestCredentialsManager_UpdateOVHCredentials_ReplaceAll(t *testing.T) {
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	full := &OVHCredentials{ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p", Endpoint: "ovh-eu"}
+	if _, err := cm.UpdateOVHCredentials(full, false); err != nil {
+		t.Fatalf("UpdateOVHCredentials() error = %v", err)
+	}
+
+	if _, err := cm.UpdateOVHCredentials(&OVHCredentials{ConsumerKey: "c2"}, true); err == nil {
+		t.Error("replace all with missing fields should fail")
+	}
+	got, _ := cm.GetOVHCredentials()
+	if got.ConsumerKey != "c" {
+		t.Errorf("ConsumerKey = %s, want c (failed replace must keep the old credentials)", got.ConsumerKey)
+	}
+
+	changed, err := cm.UpdateOVHCredentials(&OVHCredentials{
+		ApplicationKey: "k2", ApplicationSecret: "s2", ConsumerKey: "c2", ServiceName: "p", Endpoint: "ovh-ca",
+	}, true)
+	if err != nil {
+		t.Fatalf("UpdateOVHCredentials() error = %v", err)
+	}
+	want := []string{"application_key", "application_secret", "consumer_key", "endpoint"}
+	if strings.Join(changed, ",") != strings.Join(want, ",") {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+}
+
+func TestSetCredentials_OVHPartialUpdateReportsChangedFields(t *testing.T) {
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	_ = cm.SetCredentials(&OVHCredentials{ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p", Endpoint: "ovh-eu"})
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, cm, nil, nil, nil)
+
+	form := url.Values{"provider": {"ovh"}, "ovh_consumer_key": {"c2"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/credentials", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.SetCredentials(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "saved successfully") || !strings.Contains(body, "Changed: consumer_key") {
+		t.Errorf("response = %q, want a success listing consumer_key as changed", body)
+	}
+	if got, _ := cm.GetOVHCredentials(); got.ApplicationKey != "k" || got.ConsumerKey != "c2" {
+		t.Errorf("credentials = %+v, want application key kept and consumer key rotated", got)
+	}
+}
+
+func TestCredentialsManager_UpdateOVHCredentials_EmptyEndpointUsesDefault(t *testing.T) {
+	creds := func() *OVHCredentials {
+		return &OVHCredentials{ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p"}
+	}
+
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	if _, err := cm.UpdateOVHCredentials(creds(), false); err != nil {
+		t.Fatalf("UpdateOVHCredentials() error = %v", err)
+	}
+	if got, _ := cm.GetOVHCredentials(); got.Endpoint != DefaultOVHEndpoint {
+		t.Errorf("Endpoint = %q, want %q", got.Endpoint, DefaultOVHEndpoint)
+	}
+
+	if err := cm.SetDefaultOVHEndpoint("ovh-ca"); err != nil {
+		t.Fatalf("SetDefaultOVHEndpoint() error = %v", err)
+	}
+	if _, err := cm.UpdateOVHCredentials(creds(), true); err != nil {
+		t.Fatalf("UpdateOVHCredentials() error = %v", err)
+	}
+	if got, _ := cm.GetOVHCredentials(); got.Endpoint != "ovh-ca" {
+		t.Errorf("Endpoint = %q, want the configured default ovh-ca", got.Endpoint)
+	}
+}
+
+func TestCredentialsManager_UpdateOVHCredentials_StoredEndpointWinsOverDefault(t *testing.T) {
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	_ = cm.SetCredentials(&OVHCredentials{ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p", Endpoint: "ovh-us"})
+
+	if _, err := cm.UpdateOVHCredentials(&OVHCredentials{ConsumerKey: "c2"}, false); err != nil {
+		t.Fatalf("UpdateOVHCredentials() error = %v", err)
+	}
+	if got, _ := cm.GetOVHCredentials(); got.Endpoint != "ovh-us" {
+		t.Errorf("Endpoint = %q, want the stored ovh-us kept", got.Endpoint)
+	}
+}
+
+func TestCredentialsManager_UpdateOVHCredentials_UnknownEndpoint(t *testing.T) {
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	_, err := cm.UpdateOVHCredentials(&OVHCredentials{
+		ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p", Endpoint: "ovh-mars",
+	}, false)
+	if err == nil {
+		t.Fatal("UpdateOVHCredentials() with an unknown endpoint should fail")
+	}
+	if cm.HasCredentials("ovh") {
+		t.Error("a rejected endpoint must not be stored")
+	}
+}
+
+func TestCredentialsManager_SetDefaultOVHEndpoint_RejectsUnknown(t *testing.T) {
+	cm := NewCredentialsManager()
+	if err := cm.SetDefaultOVHEndpoint("eu"); err == nil {
+		t.Error("SetDefaultOVHEndpoint(\"eu\") should fail")
+	}
+	if got := cm.DefaultOVHEndpoint(); got != DefaultOVHEndpoint {
+		t.Errorf("DefaultOVHEndpoint() = %q, want %q after a rejected value", got, DefaultOVHEndpoint)
+	}
+}
+
+func TestSetCredentials_OVHEmptyEndpointFallsBackToDefault(t *testing.T) {
+	cm := &CredentialsManager{credentials: make(map[string]ProviderCredentials)}
+	if err := cm.SetDefaultOVHEndpoint("ovh-us"); err != nil {
+		t.Fatalf("SetDefaultOVHEndpoint() error = %v", err)
+	}
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, cm, nil, nil, nil)
+
+	form := url.Values{
+		"provider":               {"ovh"},
+		"ovh_application_key":    {"k"},
+		"ovh_application_secret": {"s"},
+		"ovh_consumer_key":       {"c"},
+		"ovh_service_name":       {"p"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/credentials", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.SetCredentials(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "saved successfully") {
+		t.Fatalf("response = %q, want a success", rec.Body.String())
+	}
+	if got, _ := cm.GetOVHCredentials(); got.Endpoint != "ovh-us" {
+		t.Errorf("Endpoint = %q, want the configured default ovh-us", got.Endpoint)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ovhAuthErrors are the (lowercased) messages of the OVH API refusing the
+// credentials: a wrong, expired or revoked consumer key, a deleted application,
+// or a key without the rights the deployment needs.
+var ovhAuthErrors = []string{
+	"invalid application key",
+	"this application key is invalid",
+	"invalid signature",
+	"this credential does not exist",
+	"this credential is not valid",
+	"this credential is expired",
+	"invalidcredential",
+	"client::unauthorized",
+	"this call has not been granted",
+	"(status code 401)",
+}
+
+// isOVHAuthError reports whether a failed pulumi up was refused by the OVH API
+// because of its credentials. Running it again as is would fail the same way.
+func isOVHAuthError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range ovhAuthErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint identifies a set of OVH credentials without keeping them, so
+// that a job can tell whether the credentials it was refused with changed.
+func (c *OVHCredentials) fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{c.ApplicationKey, c.ApplicationSecret, c.ConsumerKey, c.Endpoint}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ovhCredentials returns the OVH credentials the lab deploys with.
+func (c *LabConfig) ovhCredentials() *OVHCredentials {
+	return &OVHCredentials{
+		ApplicationKey:    c.OvhApplicationKey,
+		ApplicationSecret: c.OvhApplicationSecret,
+		ConsumerKey:       c.OvhConsumerKey,
+		ServiceName:       c.OvhServiceName,
+		Endpoint:          c.OvhEndpoint,
+	}
+}
+
+// failOnRejectedCredentials fails an OVH lab whose deployment failed with
+// upErr because OVH refused its credentials, and reports whether it did. The
+// job remembers which credentials were refused, for RetryJob to wait for new
+// ones; its stack is kept, so the retry picks up where the deployment stopped.
+func (jm *JobManager) failOnRejectedCredentials(jobID string, upErr error) bool {
+	if !isOVHAuthError(upErr) {
+		return false
+	}
+	job, exists := jm.GetJob(jobID)
+	if !exists {
+		return false
+	}
+	job.mu.Lock()
+	if job.Config == nil || job.Config.UseExistingCluster || job.Config.Provider == "azure" {
+		job.mu.Unlock()
+		return false
+	}
+	line := fmt.Sprintf("pulumi up failed: OVH rejected the credentials, update them and retry: %v", upErr)
+	job.RejectedCredentials = job.Config.ovhCredentials().fingerprint()
+	job.Error = line
+	job.setStatus(JobStatusFailed, line)
+	job.mu.Unlock()
+
+	jm.AppendOutput(jobID, "🔑 OVH rejected the credentials. Update them in Provider credentials, then retry the lab: it keeps its stack and resumes where it stopped.")
+	return true
+}
+
+// failOnRejectedCredentials fails and saves the job when its deployment failed
+// with upErr because OVH refused the credentials, and reports whether it did.
+// Nothing else is tried with those credentials: no refresh, no rollback.
+func (pe *PulumiExecutor) failOnRejectedCredentials(jobID string, upErr error) bool {
+	if !pe.jobManager.failOnRejectedCredentials(jobID, upErr) {
+		return false
+	}
+	slog.Warn("Deployment failed on rejected OVH credentials", "job_id", jobID, "error", upErr)
+	if err := pe.jobManager.SaveJob(jobID); err != nil {
+		slog.Warn("Failed to persist failed job", "job_id", jobID, "error", err)
+	}
+	return true
+}
+
+// errCredentialsUnchanged is why a lab that failed on its OVH credentials is
+// not retried with the same ones.
+var errCredentialsUnchanged = errors.New("OVH rejected this lab's credentials and they have not changed since. Update the OVH credentials, then retry")
+
+// checkRetryCredentials refuses to retry a job that failed on its OVH
+// credentials with the same credentials.
+func checkRetryCredentials(job *Job, current *OVHCredentials) error {
+	job.mu.RLock()
+	rejected := job.RejectedCredentials
+	job.mu.RUnlock()
+	if rejected != "" && current.fingerprint() == rejected {
+		return errCredentialsUnchanged
+	}
+	return nil
+}
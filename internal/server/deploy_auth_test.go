@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsOVHAuthError(t *testing.T) {
+	t.Parallel()
+
+	for _, msg := range []string{
+		`OVHcloud API error (status code 403): Client::Forbidden::InvalidCredential: "This credential does not exist"`,
+		`OVHcloud API error (status code 401): Client::Unauthorized: "Invalid signature"`,
+		`OVHcloud API error (status code 403): Client::Forbidden: "Invalid application key"`,
+		`OVHcloud API error (status code 403): Client::Forbidden: "This call has not been granted"`,
+		`calling Get(/cloud/project): OVHcloud API error (status code 401): "This credential is expired"`,
+	} {
+		assert.True(t, isOVHAuthError(errors.New(msg)), msg)
+	}
+
+	for _, err := range []error{
+		nil,
+		errors.New("OVHcloud API error (status code 400): Invalid flavor name b3-999"),
+		errors.New("quota exceeded"),
+		fmt.Errorf("pulumi up failed: %w", context.Canceled),
+	} {
+		assert.False(t, isOVHAuthError(err), "%v", err)
+	}
+}
+
+// ovhTestCredentials returns a complete set of OVH credentials with consumerKey.
+func ovhTestCredentials(consumerKey string) *OVHCredentials {
+	return &OVHCredentials{ApplicationKey: "ak", ApplicationSecret: "as", ConsumerKey: consumerKey, ServiceName: "project", Endpoint: "ovh-eu"}
+}
+
+// rejectedLab returns a lab that deployed with the consumer key "old" and
+// failed because OVH refused it.
+func rejectedLab(t *testing.T, jm *JobManager) string {
+	t.Helper()
+	cfg := &LabConfig{StackName: "test", Provider: "ovh"}
+	cfg.setOVHCredentials(ovhTestCredentials("old"))
+	jobID := jm.CreateJob(cfg)
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	require.True(t, jm.failOnRejectedCredentials(jobID, errors.New(`OVHcloud API error (status code 403): Client::Forbidden::InvalidCredential: "This credential does not exist"`)))
+	return jobID
+}
+
+func TestFailOnRejectedCredentials(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := rejectedLab(t, jm)
+
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, JobStatusFailed, job.Status)
+	assert.Contains(t, job.Error, "OVH rejected the credentials, update them and retry")
+	assert.Equal(t, ovhTestCredentials("old").fingerprint(), job.RejectedCredentials)
+	assert.NotContains(t, job.RejectedCredentials, "old", "the credentials are not kept")
+	assert.Contains(t, job.Output[len(job.Output)-1], "Update them in Provider credentials, then retry")
+
+	require.NoError(t, jm.ResetJobForRetry(jobID))
+	assert.Empty(t, job.RejectedCredentials, "a retry starts afresh")
+}
+
+func TestFailOnRejectedCredentials_OnlyOVH(t *testing.T) {
+	jm := NewJobManager("")
+	authErr := errors.New(`Client::Unauthorized: "Invalid signature"`)
+
+	azure := jm.CreateJob(&LabConfig{StackName: "aks", Provider: "azure"})
+	assert.False(t, jm.failOnRejectedCredentials(azure, authErr))
+	byok := jm.CreateJob(&LabConfig{StackName: "byok", UseExistingCluster: true})
+	assert.False(t, jm.failOnRejectedCredentials(byok, authErr))
+	ovh := jm.CreateJob(&LabConfig{StackName: "ovh"})
+	assert.False(t, jm.failOnRejectedCredentials(ovh, errors.New("quota exceeded")))
+
+	job, _ := jm.GetJob(azure)
+	assert.Empty(t, job.RejectedCredentials)
+}
+
+func TestCheckRetryCredentials(t *testing.T) {
+	jm := NewJobManager("")
+	job, _ := jm.GetJob(rejectedLab(t, jm))
+
+	assert.ErrorIs(t, checkRetryCredentials(job, ovhTestCredentials("old")), errCredentialsUnchanged)
+	assert.NoError(t, checkRetryCredentials(job, ovhTestCredentials("new")))
+
+	other, _ := jm.GetJob(jm.CreateJob(&LabConfig{StackName: "other"}))
+	assert.NoError(t, checkRetryCredentials(other, ovhTestCredentials("old")), "a lab that did not fail on its credentials retries as before")
+}
+
+func TestHandler_RetryJob_WaitsForNewCredentials(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := rejectedLab(t, jm)
+	cm := NewCredentialsManager()
+	require.NoError(t, cm.SetCredentials(ovhTestCredentials("old")))
+	h := NewHandler(jm, &PulumiExecutor{}, cm, nil, nil, nil)
+	retried := make(chan string, 1)
+	h.executeRetry = func(jobID string) error {
+		retried <- jobID
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.RetryJob(w, httptest.NewRequest("POST", "/api/jobs/"+jobID+"/retry", nil))
+	assert.Contains(t, w.Body.String(), "Credentials Unchanged")
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, JobStatusFailed, job.Status, "the lab is not retried with the refused credentials")
+
+	require.NoError(t, cm.SetCredentials(ovhTestCredentials("new")))
+	w = httptest.NewRecorder()
+	h.RetryJob(w, httptest.NewRequest("POST", "/api/jobs/"+jobID+"/retry", nil))
+	assert.Contains(t, w.Body.String(), "Job Retried")
+	assert.Equal(t, jobID, <-retried)
+
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	assert.Equal(t, "new", job.Config.OvhConsumerKey, "the retry deploys with the new credentials")
+	assert.Empty(t, job.RejectedCredentials)
+	assert.Contains(t, job.Output, "Retrying with the updated OVH credentials.")
+}
+
+func TestHandler_GetJobStatus_ShowsRejectedCredentials(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := rejectedLab(t, jm)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.GetJobStatus(w, httptest.NewRequest("GET", "/api/jobs/"+jobID, nil))
+	assert.Contains(t, w.Body.String(), `<a href="/credentials?provider=ovh">Update the OVH credentials</a>, then retry`)
+	assert.Contains(t, w.Body.String(), "Retry Job")
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvDeployRetries is how many times a deployment that failed on a transient
+// OVH capacity or quota error is run again, and EnvDeployRetryDelaySeconds how
+// long it waits before each run.
+const (
+	EnvDeployRetries           = "DEPLOY_RETRIES"
+	EnvDeployRetryDelaySeconds = "DEPLOY_RETRY_DELAY_SECONDS"
+)
+
+// maxDeployRetries caps EnvDeployRetries so that a region out of capacity for
+// good does not keep a lab deploying for hours.
+const maxDeployRetries = 5
+
+// retryableDeployErrors are the (lowercased) messages of OVH failures that
+// clear up on their own: a region short of capacity for the flavor, or a quota
+// that frees up as another lab is deleted.
+var retryableDeployErrors = []string{
+	"not enough resources",
+	"insufficient resources",
+	"insufficient capacity",
+	"no more capacity",
+	"out of capacity",
+	"quota exceeded",
+	"quota has been exceeded",
+	"service unavailable",
+	"try again later",
+}
+
+// isRetryableDeployError reports whether a failed pulumi up is worth running
+// again as is.
+func isRetryableDeployError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range retryableDeployErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// deployRetryPolicy decides whether a failed deployment is run again.
+type deployRetryPolicy struct {
+	// Retries is how many runs follow the first one at most; 0 turns retries off.
+	Retries int
+	Delay   time.Duration
+}
+
+// deployRetryPolicyFromEnv reads the policy from EnvDeployRetries (default 1,
+// at most maxDeployRetries) and EnvDeployRetryDelaySeconds (default 60).
+func deployRetryPolicyFromEnv() deployRetryPolicy {
+	p := deployRetryPolicy{Retries: 1, Delay: time.Minute}
+	if v := os.Getenv(EnvDeployRetries); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			p.Retries = min(n, maxDeployRetries)
+		}
+	}
+	if v := os.Getenv(EnvDeployRetryDelaySeconds); v != "" {
+		if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+			p.Delay = time.Duration(s) * time.Second
+		}
+	}
+	return p
+}
+
+// SetDeployConfig sets how deployments that failed on a transient OVH error
+// are retried, and whether failed ones are rolled back, replacing the policy
+// read from the environment.
+func (pe *PulumiExecutor) SetDeployConfig(cfg DeployConfig) {
+	pe.deployRetry = deployRetryPolicy{
+		Retries: min(max(cfg.Retries, 0), maxDeployRetries),
+		Delay:   time.Duration(max(cfg.RetryDelaySeconds, 0)) * time.Second,
+	}
+	pe.rollbackOnFailure = cfg.RollbackOnFailure
+}
+
+// shouldRetry reports whether the run number attempt (1 for the first), which
+// failed with err, is followed by another.
+func (p deployRetryPolicy) shouldRetry(err error, attempt int) bool {
+	return attempt <= min(p.Retries, maxDeployRetries) && isRetryableDeployError(err)
+}
+
+// deployRetryError is what a run that is to be retried returns to Execute.
+type deployRetryError struct {
+	err error
+}
+
+func (e *deployRetryError) Error() string { return e.err.Error() }
+func (e *deployRetryError) Unwrap() error { return e.err }
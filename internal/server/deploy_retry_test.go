@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryableDeployError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil},
+		{name: "region out of capacity", err: errors.New(`error: 1 error occurred: calling Post /cloud/project/abc/kube/xyz/nodepool: OVHcloud API error (status code 412): Client::PreconditionFailed: "Not enough resources available for flavor b3-16 in GRA11"`), want: true},
+		{name: "quota", err: errors.New("OVHcloud API error (status code 403): Quota exceeded for instances in region GRA11"), want: true},
+		{name: "wrapped", err: fmt.Errorf("pulumi up failed: %w", errors.New("503 Service Unavailable")), want: true},
+		{name: "invalid flavor", err: errors.New(`OVHcloud API error (status code 400): Client::BadRequest: "Invalid flavor name b3-999"`)},
+		{name: "program error", err: errors.New("failed to create node pools: missing nodepool:flavor")},
+		{name: "cancelled", err: fmt.Errorf("quota exceeded: %w", context.Canceled)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, isRetryableDeployError(tt.err))
+		})
+	}
+}
+
+func TestDeployRetryPolicy_ShouldRetry(t *testing.T) {
+	t.Parallel()
+
+	capacity := errors.New("not enough resources in region")
+	p := deployRetryPolicy{Retries: 2, Delay: time.Second}
+	assert.True(t, p.shouldRetry(capacity, 1))
+	assert.True(t, p.shouldRetry(capacity, 2))
+	assert.False(t, p.shouldRetry(capacity, 3), "the attempts are capped")
+	assert.False(t, p.shouldRetry(errors.New("invalid flavor"), 1), "only transient errors are retried")
+
+	assert.False(t, deployRetryPolicy{}.shouldRetry(capacity, 1), "retries can be turned off")
+	assert.False(t, deployRetryPolicy{Retries: 50}.shouldRetry(capacity, maxDeployRetries+1), "a policy never goes past maxDeployRetries")
+}
+
+func TestDeployRetryPolicyFromEnv(t *testing.T) {
+	t.Setenv(EnvDeployRetries, "")
+	t.Setenv(EnvDeployRetryDelaySeconds, "")
+	assert.Equal(t, deployRetryPolicy{Retries: 1, Delay: time.Minute}, deployRetryPolicyFromEnv())
+
+	t.Setenv(EnvDeployRetries, "3")
+	t.Setenv(EnvDeployRetryDelaySeconds, "120")
+	assert.Equal(t, deployRetryPolicy{Retries: 3, Delay: 2 * time.Minute}, deployRetryPolicyFromEnv())
+
+	t.Setenv(EnvDeployRetries, "99")
+	t.Setenv(EnvDeployRetryDelaySeconds, "nope")
+	assert.Equal(t, deployRetryPolicy{Retries: maxDeployRetries, Delay: time.Minute}, deployRetryPolicyFromEnv())
+
+	t.Setenv(EnvDeployRetries, "0")
+	assert.Equal(t, 0, deployRetryPolicyFromEnv().Retries)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+)
+
+// EnvDeployRollbackOnFailure, when true, destroys what a failed deployment
+// created instead of leaving it billing until an admin destroys the lab.
+const EnvDeployRollbackOnFailure = "DEPLOY_ROLLBACK_ON_FAILURE"
+
+// rollbackOnFailureFromEnv reads EnvDeployRollbackOnFailure (default false:
+// the resources stay, for a retry or a look at what went wrong).
+func rollbackOnFailureFromEnv() bool {
+	rollback, _ := strconv.ParseBool(os.Getenv(EnvDeployRollbackOnFailure))
+	return rollback
+}
+
+// rollbackSkipReason decides whether a deployment that failed with upErr is
+// rolled back. It returns the resources to destroy, or why there is nothing
+// to roll back. resources lists what the stack holds; it is not called when
+// the error alone decides.
+func rollbackSkipReason(upErr error, resources func() ([]DestroyedResource, error)) ([]DestroyedResource, string) {
+	if errors.Is(upErr, context.Canceled) {
+		return nil, "the deployment was interrupted, its resources are kept for a retry"
+	}
+	left, err := resources()
+	if err != nil {
+		return nil, fmt.Sprintf("could not list the resources the deployment created: %v", err)
+	}
+	if len(left) == 0 {
+		return nil, "the deployment failed before creating any resource"
+	}
+	return left, ""
+}
+
+// rollBackFailedDeploy destroys the resources of a deployment that failed with
+// upErr, when the executor is set to, streaming the destroy into the job. It
+// reports whether it rolled back (or tried to): the job is then failed and
+// saved, and the caller has nothing left to do.
+func (pe *PulumiExecutor) rollBackFailedDeploy(prep *JobPreparation, jobID string, upErr error) bool {
+	if !pe.rollbackOnFailure {
+		return false
+	}
+	// The deployment's context may have run out of time: the rollback gets its
+	// own, still interrupted by a shutdown.
+	ctx, cancel := context.WithTimeout(pe.runContext(jobID), pulumiExecutionTimeout)
+	defer cancel()
+
+	left, skip := rollbackSkipReason(upErr, func() ([]DestroyedResource, error) {
+		deployment, err := prep.Stack.Export(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return remainingResources(deployment)
+	})
+	if skip != "" {
+		pe.jobManager.AppendOutput(jobID, "Not rolling back: "+skip+".")
+		return false
+	}
+
+	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("pulumi up failed: %v", upErr))
+	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Rolling back: destroying what the deployment created (%s)...", summarizeResources(left)))
+	slog.Info("Rolling back a failed deployment", "job_id", jobID, "resources", len(left))
+
+	engineEvents := make(chan events.EngineEvent)
+	deletedCh := make(chan []DestroyedResource, 1)
+	go func() { deletedCh <- collectDeletedResources(engineEvents) }()
+	_, err := prep.Stack.Destroy(ctx, optdestroy.ProgressStreams(prep.Writer), optdestroy.EventStreams(engineEvents))
+	prep.Writer.Flush()
+	report := &DestroyReport{}
+	select {
+	case report.Deleted = <-deletedCh:
+	case <-time.After(destroyEventsGrace):
+	}
+	if err != nil {
+		if deployment, exportErr := prep.Stack.Export(ctx); exportErr == nil {
+			report.Remaining, _ = remainingResources(deployment)
+		}
+	}
+	pe.jobManager.finishRollback(jobID, upErr, report, err)
+	if saveErr := pe.jobManager.SaveJob(jobID); saveErr != nil {
+		slog.Warn("Failed to persist rolled back job", "job_id", jobID, "error", saveErr)
+	}
+	return true
+}
+
+// finishRollback records the outcome of the rollback of a deployment that
+// failed with upErr. The lab is failed either way; it is only marked rolled
+// back when the destroy went through, and keeps its stack for a retry.
+func (jm *JobManager) finishRollback(jobID string, upErr error, report *DestroyReport, rollbackErr error) {
+	job, exists := jm.GetJob(jobID)
+	if !exists {
+		return
+	}
+	var line string
+	if rollbackErr != nil {
+		line = fmt.Sprintf("pulumi up failed: %v; the rollback failed too, destroy the lab to delete what is left: %v", upErr, rollbackErr)
+	} else {
+		line = fmt.Sprintf("pulumi up failed: %v; rolled back", upErr)
+	}
+	jm.AppendOutput(jobID, report.Summary())
+	jm.AppendOutput(jobID, line)
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.DestroyReport = report
+	job.RolledBack = rollbackErr == nil
+	job.Error = line
+	job.setStatus(JobStatusFailed, line)
+}
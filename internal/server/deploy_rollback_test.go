@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackSkipReason(t *testing.T) {
+	t.Parallel()
+
+	cluster := []DestroyedResource{{Type: "ovh:CloudProject/kube:Kube", Name: "kubeCluster"}}
+	listing := func(resources []DestroyedResource, err error) func() ([]DestroyedResource, error) {
+		return func() ([]DestroyedResource, error) { return resources, err }
+	}
+	upErr := errors.New("OVHcloud API error (status code 400): Invalid flavor name b3-999")
+
+	left, skip := rollbackSkipReason(upErr, listing(cluster, nil))
+	assert.Empty(t, skip)
+	assert.Equal(t, cluster, left)
+
+	_, skip = rollbackSkipReason(upErr, listing(nil, nil))
+	assert.Equal(t, "the deployment failed before creating any resource", skip)
+
+	_, skip = rollbackSkipReason(upErr, listing(nil, errors.New("no stack")))
+	assert.Equal(t, "could not list the resources the deployment created: no stack", skip, "without knowing what exists, nothing is destroyed")
+
+	_, skip = rollbackSkipReason(fmt.Errorf("pulumi up failed: %w", context.Canceled), func() ([]DestroyedResource, error) {
+		t.Error("an interrupted deployment is not listed")
+		return cluster, nil
+	})
+	assert.Equal(t, "the deployment was interrupted, its resources are kept for a retry", skip)
+}
+
+func TestRollBackFailedDeploy_OffByDefault(t *testing.T) {
+	t.Setenv(EnvDeployRollbackOnFailure, "")
+	pe := NewPulumiExecutor(NewJobManager(""), t.TempDir())
+	assert.False(t, pe.rollbackOnFailure)
+	// The stack is never touched when rollbacks are off.
+	assert.False(t, pe.rollBackFailedDeploy(nil, "job-x", errors.New("boom")))
+
+	t.Setenv(EnvDeployRollbackOnFailure, "true")
+	assert.True(t, NewPulumiExecutor(NewJobManager(""), t.TempDir()).rollbackOnFailure)
+
+	pe.SetDeployConfig(DeployConfig{RollbackOnFailure: true})
+	assert.True(t, pe.rollbackOnFailure)
+}
+
+func TestFinishRollback(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "test"})
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	upErr := errors.New("quota exceeded")
+
+	report := &DestroyReport{Deleted: []DestroyedResource{{Type: "ovh:CloudProject/kube:Kube", Name: "kubeCluster"}}}
+	jm.finishRollback(jobID, upErr, report, nil)
+
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, JobStatusFailed, job.Status)
+	assert.True(t, job.RolledBack)
+	assert.Equal(t, "pulumi up failed: quota exceeded; rolled back", job.Error)
+	assert.Equal(t, report, job.DestroyReport)
+	assert.Equal(t, job.Error, job.History[len(job.History)-1].Reason)
+	assert.Contains(t, job.Output, "Deleted: cluster")
+
+	require.NoError(t, jm.ResetJobForRetry(jobID))
+	assert.False(t, job.RolledBack, "a retry starts afresh")
+}
+
+func TestFinishRollback_DestroyFailed(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "test"})
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+
+	report := &DestroyReport{Remaining: []DestroyedResource{{Type: "ovh:CloudProject/networkPrivate:NetworkPrivate", Name: "privateNetwork-v2"}}}
+	jm.finishRollback(jobID, errors.New("quota exceeded"), report, errors.New("network still in use"))
+
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, JobStatusFailed, job.Status)
+	assert.False(t, job.RolledBack)
+	assert.Equal(t, "pulumi up failed: quota exceeded; the rollback failed too, destroy the lab to delete what is left: network still in use", job.Error)
+	assert.Contains(t, job.Output, "Deleted: nothing. Still in the stack: network")
+}
+
+func TestHandler_GetJobStatus_ShowsRollback(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "test"})
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	jm.finishRollback(jobID, errors.New("quota exceeded"), &DestroyReport{Deleted: []DestroyedResource{{Type: "ovh:CloudProject/kube:Kube", Name: "kubeCluster"}}}, nil)
+
+	w := httptest.NewRecorder()
+	h.GetJobStatus(w, httptest.NewRequest("GET", "/api/jobs/"+jobID, nil))
+	assert.Contains(t, w.Body.String(), `<div class="status-badge status-failed">failed (rolled back)</div>`)
+	assert.Contains(t, w.Body.String(), `<div class="success-message">Deleted: cluster</div>`)
+}
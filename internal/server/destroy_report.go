@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// destroyEventsGrace bounds the wait for the destroy's engine events once
+// pulumi destroy has returned.
+const destroyEventsGrace = 5 * time.Second
+
+// DestroyedResource is one resource of a lab's stack, as listed in a destroy
+// report.
+type DestroyedResource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// DestroyReport records what a pulumi destroy deleted, in the order it deleted
+// it (dependents first), and what was still in the stack when it failed.
+type DestroyReport struct {
+	Deleted   []DestroyedResource `json:"deleted,omitempty"`
+	Remaining []DestroyedResource `json:"remaining,omitempty"`
+}
+
+// Summary renders the report for the job status, e.g. "Deleted: node pool,
+// cluster, subnet, network, gateway".
+func (r *DestroyReport) Summary() string {
+	if r == nil {
+		return ""
+	}
+	deleted := summarizeResources(r.Deleted)
+	if deleted == "" {
+		deleted = "nothing"
+	}
+	summary := "Deleted: " + deleted
+	if remaining := summarizeResources(r.Remaining); remaining != "" {
+		summary += ". Still in the stack: " + remaining
+	}
+	return summary
+}
+
+// collectDeletedResources reads a destroy's engine events until the channel is
+// closed and returns the resources it deleted. The stack itself and the
+// providers are bookkeeping, not cloud resources, so they are left out.
+func collectDeletedResources(ch <-chan events.EngineEvent) []DestroyedResource {
+	var deleted []DestroyedResource
+	for event := range ch {
+		if event.ResOutputsEvent == nil {
+			continue
+		}
+		meta := event.ResOutputsEvent.Metadata
+		if meta.Op != apitype.OpDelete || isBookkeepingResource(meta.Type) {
+			continue
+		}
+		deleted = append(deleted, DestroyedResource{Type: meta.Type, Name: nameFromURN(meta.URN)})
+	}
+	return deleted
+}
+
+// remainingResources lists the resources left in an exported stack.
+func remainingResources(deployment apitype.UntypedDeployment) ([]DestroyedResource, error) {
+	var state struct {
+		Resources []struct {
+			URN  string `json:"urn"`
+			Type string `json:"type"`
+		} `json:"resources"`
+	}
+	if len(deployment.Deployment) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(deployment.Deployment, &state); err != nil {
+		return nil, fmt.Errorf("failed to read the stack state: %w", err)
+	}
+	var remaining []DestroyedResource
+	for _, res := range state.Resources {
+		if !isBookkeepingResource(res.Type) {
+			remaining = append(remaining, DestroyedResource{Type: res.Type, Name: nameFromURN(res.URN)})
+		}
+	}
+	return remaining, nil
+}
+
+func isBookkeepingResource(typ string) bool {
+	return typ == "pulumi:pulumi:Stack" || strings.HasPrefix(typ, "pulumi:providers:")
+}
+
+// nameFromURN returns the resource name, the last "::" segment of its URN.
+func nameFromURN(urn string) string {
+	if i := strings.LastIndex(urn, "::"); i >= 0 {
+		return urn[i+2:]
+	}
+	return urn
+}
+
+// resourceKinds names the resource types a lab creates the way an admin thinks
+// of them. Other types fall back to their type name.
+var resourceKinds = map[string]string{
+	"ovh:CloudProject/kube:Kube":                                     "cluster",
+	"ovh:CloudProject/kubeNodePool:KubeNodePool":                     "node pool",
+	"ovh:CloudProject/gateway:Gateway":                               "gateway",
+	"ovh:CloudProject/networkPrivateSubnet:NetworkPrivateSubnet":     "subnet",
+	"ovh:CloudProject/networkPrivateSubnetV2:NetworkPrivateSubnetV2": "subnet",
+	"ovh:CloudProject/networkPrivate:NetworkPrivate":                 "network",
+	"azure-native:containerservice:ManagedCluster":                   "cluster",
+	"azure-native:resources:ResourceGroup":                           "resource group",
+	"kubernetes:helm.sh/v3:Release":                                  "Helm release",
+	"kubernetes:core/v1:Namespace":                                   "namespace",
+}
+
+// resourceKind returns the display name of a resource type: the mapped name,
+// or the type's last segment ("ovh:Domain/zoneRecord:ZoneRecord" -> "ZoneRecord").
+func resourceKind(typ string) string {
+	if kind, ok := resourceKinds[typ]; ok {
+		return kind
+	}
+	if i := strings.LastIndex(typ, ":"); i >= 0 {
+		return typ[i+1:]
+	}
+	return typ
+}
+
+// summarizeResources groups resources by kind, in the order each kind first
+// appears, with a count for kinds seen more than once: "node pool (2), cluster".
+func summarizeResources(resources []DestroyedResource) string {
+	var kinds []string
+	counts := map[string]int{}
+	for _, res := range resources {
+		kind := resourceKind(res.Type)
+		if counts[kind] == 0 {
+			kinds = append(kinds, kind)
+		}
+		counts[kind]++
+	}
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = kind
+		if counts[kind] > 1 {
+			parts[i] = fmt.Sprintf("%s (%d)", kind, counts[kind])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testURNPrefix = "urn:pulumi:dev::easylab::"
+
+func resourceEvent(op apitype.OpType, typ, name string) events.EngineEvent {
+	return events.EngineEvent{EngineEvent: apitype.EngineEvent{
+		ResOutputsEvent: &apitype.ResOutputsEvent{Metadata: apitype.StepEventMetadata{
+			Op: op, Type: typ, URN: testURNPrefix + typ + "::" + name,
+		}},
+	}}
+}
+
+// fakeDestroyEvents replays the engine events of an OVH lab's destroy.
+func fakeDestroyEvents() <-chan events.EngineEvent {
+	ch := make(chan events.EngineEvent)
+	go func() {
+		defer close(ch)
+		ch <- events.EngineEvent{EngineEvent: apitype.EngineEvent{PreludeEvent: &apitype.PreludeEvent{}}}
+		ch <- resourceEvent(apitype.OpDelete, "kubernetes:helm.sh/v3:Release", "ingress-nginx")
+		ch <- resourceEvent(apitype.OpDelete, "kubernetes:helm.sh/v3:Release", "cert-manager")
+		ch <- resourceEvent(apitype.OpDelete, "pulumi:providers:kubernetes", "k8sProvider")
+		ch <- resourceEvent(apitype.OpDelete, "ovh:CloudProject/kubeNodePool:KubeNodePool", "nodePool1")
+		ch <- resourceEvent(apitype.OpSame, "ovh:CloudProject/kube:Kube", "kubeCluster")
+		ch <- resourceEvent(apitype.OpDelete, "ovh:CloudProject/kube:Kube", "kubeCluster")
+		ch <- resourceEvent(apitype.OpDelete, "ovh:CloudProject/gateway:Gateway", "gateway")
+		ch <- resourceEvent(apitype.OpDelete, "ovh:CloudProject/networkPrivateSubnet:NetworkPrivateSubnet", "subnet")
+		ch <- resourceEvent(apitype.OpDelete, "ovh:CloudProject/networkPrivate:NetworkPrivate", "privateNetwork-v2")
+		ch <- resourceEvent(apitype.OpDelete, "pulumi:pulumi:Stack", "easylab-dev")
+	}()
+	return ch
+}
+
+func TestCollectDeletedResources(t *testing.T) {
+	deleted := collectDeletedResources(fakeDestroyEvents())
+
+	require.Len(t, deleted, 7, "only deletions of cloud resources are kept")
+	assert.Equal(t, DestroyedResource{Type: "ovh:CloudProject/kubeNodePool:KubeNodePool", Name: "nodePool1"}, deleted[2])
+
+	report := &DestroyReport{Deleted: deleted}
+	assert.Equal(t, "Deleted: Helm release (2), node pool, cluster, gateway, subnet, network", report.Summary())
+}
+
+func TestDestroyReport_PartialFailure(t *testing.T) {
+	deployment, err := json.Marshal(map[string]any{
+		"resources": []map[string]string{
+			{"urn": testURNPrefix + "pulumi:pulumi:Stack::easylab-dev", "type": "pulumi:pulumi:Stack"},
+			{"urn": testURNPrefix + "pulumi:providers:ovh::default", "type": "pulumi:providers:ovh"},
+			{"urn": testURNPrefix + "ovh:CloudProject/networkPrivateSubnet:NetworkPrivateSubnet::subnet", "type": "ovh:CloudProject/networkPrivateSubnet:NetworkPrivateSubnet"},
+			{"urn": testURNPrefix + "ovh:CloudProject/networkPrivate:NetworkPrivate::privateNetwork-v2", "type": "ovh:CloudProject/networkPrivate:NetworkPrivate"},
+		},
+	})
+	require.NoError(t, err)
+
+	remaining, err := remainingResources(apitype.UntypedDeployment{Version: 3, Deployment: deployment})
+	require.NoError(t, err)
+	assert.Equal(t, []DestroyedResource{
+		{Type: "ovh:CloudProject/networkPrivateSubnet:NetworkPrivateSubnet", Name: "subnet"},
+		{Type: "ovh:CloudProject/networkPrivate:NetworkPrivate", Name: "privateNetwork-v2"},
+	}, remaining)
+
+	report := &DestroyReport{
+		Deleted:   []DestroyedResource{{Type: "ovh:CloudProject/kube:Kube", Name: "kubeCluster"}, {Type: "ovh:CloudProject/gateway:Gateway", Name: "gateway"}},
+		Remaining: remaining,
+	}
+	assert.Equal(t, "Deleted: cluster, gateway. Still in the stack: subnet, network", report.Summary())
+
+	assert.Equal(t, "Deleted: nothing. Still in the stack: subnet, network", (&DestroyReport{Remaining: remaining}).Summary())
+
+	_, err = remainingResources(apitype.UntypedDeployment{Deployment: json.RawMessage(`{"resources": 1}`)})
+	assert.Error(t, err)
+}
+
+func TestResourceKind_FallsBackToTypeName(t *testing.T) {
+	assert.Equal(t, "ZoneRecord", resourceKind("ovh:Domain/zoneRecord:ZoneRecord"))
+	assert.Equal(t, "cluster", resourceKind("azure-native:containerservice:ManagedCluster"))
+}
+
+func TestHandler_GetJobStatus_ShowsDestroyReport(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "test"})
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	status := func() string {
+		w := httptest.NewRecorder()
+		h.GetJobStatus(w, httptest.NewRequest("GET", "/api/jobs/"+jobID, nil))
+		return w.Body.String()
+	}
+
+	report := &DestroyReport{Deleted: []DestroyedResource{{Type: "ovh:CloudProject/kube:Kube", Name: "kubeCluster"}}}
+	require.NoError(t, jm.SetDestroyReport(jobID, report))
+	require.NoError(t, jm.UpdateJobStatus(jobID, JobStatusDestroyed))
+	assert.Contains(t, status(), `<div class="success-message">Deleted: cluster</div>`)
+
+	report.Remaining = []DestroyedResource{{Type: "ovh:CloudProject/gateway:Gateway", Name: "gateway"}}
+	require.NoError(t, jm.UpdateJobStatus(jobID, JobStatusFailed))
+	assert.Contains(t, status(), `<div class="warning-message">Deleted: cluster. Still in the stack: gateway</div>`)
+}
@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -112,7 +112,7 @@ func (h *Handler) DetectDevcontainer(w http.ResponseWriter, r *http.Request) {
 
 	yamlDoc, err := marshalWorkspaceTemplatesYAML([]WorkspaceTemplate{tmpl})
 	if err != nil {
-		log.Printf("Failed to render devcontainer template YAML: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to render devcontainer template YAML", "error", err)
 		writeJSONError(w, http.StatusInternalServerError, "failed to render the workspace template")
 		return
 	}
@@ -125,7 +125,7 @@ func (h *Handler) DetectDevcontainer(w http.ResponseWriter, r *http.Request) {
 		Warnings:      warnings,
 		TemplatesYAML: yamlDoc,
 	}); err != nil {
-		log.Printf("Failed to encode devcontainer import response: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to encode devcontainer import response", "error", err)
 	}
 }
 
@@ -148,7 +148,7 @@ func devcontainerClientError(err error) string {
 		return err.Error()
 	}
 
-	log.Printf("Devcontainer import failed: %v", err)
+	slog.Error("Devcontainer import failed", "error", err)
 	return "could not read the devcontainer configuration"
 }
 
@@ -218,7 +218,7 @@ func cacheRepoWarnings(t WorkspaceTemplate) []devcontainer.Warning {
 // containing one.
 func (h *Handler) detectDevcontainerFromUpload(r *http.Request) (*devcontainer.Config, string, error) {
 	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		log.Printf("Failed to parse devcontainer upload form: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse devcontainer upload form", "error", err)
 		return nil, "", errDevcontainerRead
 	}
 
@@ -230,7 +230,7 @@ func (h *Handler) detectDevcontainerFromUpload(r *http.Request) (*devcontainer.C
 
 	tmpDir, err := os.MkdirTemp("", "detect-devcontainer-*")
 	if err != nil {
-		log.Printf("Failed to create temp dir for devcontainer upload: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to create temp dir for devcontainer upload", "error", err)
 		return nil, "", errDevcontainerRead
 	}
 	defer os.RemoveAll(tmpDir)
@@ -242,12 +242,12 @@ func (h *Handler) detectDevcontainerFromUpload(r *http.Request) (*devcontainer.C
 
 	out, err := os.Create(tmpPath)
 	if err != nil {
-		log.Printf("Failed to create temp file for devcontainer upload: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to create temp file for devcontainer upload", "error", err)
 		return nil, "", errDevcontainerRead
 	}
 	if _, err := io.Copy(out, file); err != nil {
 		out.Close()
-		log.Printf("Failed to write temp file for devcontainer upload: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to write temp file for devcontainer upload", "error", err)
 		return nil, "", errDevcontainerRead
 	}
 	out.Close()
@@ -277,7 +277,7 @@ func (h *Handler) detectDevcontainerFromGit(r *http.Request) (*devcontainer.Conf
 
 	tmpDir, err := os.MkdirTemp("", "detect-devcontainer-git-*")
 	if err != nil {
-		log.Printf("Failed to create temp dir for devcontainer clone: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to create temp dir for devcontainer clone", "error", err)
 		return nil, "", errDevcontainerClone
 	}
 	defer os.RemoveAll(tmpDir)
@@ -296,7 +296,7 @@ func (h *Handler) detectDevcontainerFromGit(r *http.Request) (*devcontainer.Conf
 	}
 
 	if _, err := git.PlainClone(tmpDir, false, opts); err != nil {
-		log.Printf("Failed to clone %s for devcontainer detection: %v", redactURL(repoURL), err)
+		slog.ErrorContext(r.Context(), "Failed to clone the repo for devcontainer detection", "repo", redactURL(repoURL), "error", err)
 		return nil, "", errDevcontainerClone
 	}
 
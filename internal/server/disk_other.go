@@ -0,0 +1,10 @@
+//go:build !unix
+
+package server
+
+import "errors"
+
+// diskFreeBytes is not implemented outside Unix; /health/ready then only warns.
+func diskFreeBytes(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errInterruptedByShutdown is the error of a job whose Pulumi operation was
+// interrupted because the server shut down.
+var errInterruptedByShutdown = errors.New("interrupted by a server shutdown")
+
+// drainProgressInterval is how often Drain logs the operations it waits for.
+const drainProgressInterval = 30 * time.Second
+
+// drainInterruptWait bounds how long Drain waits for the interrupted
+// operations to stop. Pulumi gets 10 seconds to save its state and release the
+// stack lock after the interrupt before it is killed; the rest is for the job
+// to record the failure.
+const drainInterruptWait = 30 * time.Second
+
+// pulumiRun is a Pulumi operation in flight.
+type pulumiRun struct {
+	jobID   string
+	op      string
+	started time.Time
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	// ownsStatus is set when the operation moves the job to its final status
+	// itself, so an interrupted one must leave the job failed. A kubeconfig
+	// rotation or a node pool scale leaves that to its caller.
+	ownsStatus  bool
+	interrupted bool
+}
+
+// runRegistry tracks the Pulumi operations in flight, so that a shutdown can
+// wait for them or interrupt them (see Drain).
+type runRegistry struct {
+	mu   sync.Mutex
+	runs map[string]*pulumiRun
+	// interrupting is set once Drain is done waiting: operations starting
+	// afterwards are interrupted straight away.
+	interrupting bool
+}
+
+// beginRun registers a Pulumi operation of the job, returning the context the
+// operation must run under and the function to call once it returned. An
+// operation started while another one of the job runs shares its context.
+func (pe *PulumiExecutor) beginRun(jobID, op string, ownsStatus bool) (context.Context, func()) {
+	r := &pe.runs
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if run, ok := r.runs[jobID]; ok {
+		return run.ctx, func() {}
+	}
+	if r.runs == nil {
+		r.runs = make(map[string]*pulumiRun)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &pulumiRun{jobID: jobID, op: op, started: time.Now(), ctx: ctx, cancel: cancel, done: make(chan struct{}), ownsStatus: ownsStatus}
+	if r.interrupting {
+		run.interrupted = true
+		cancel()
+	}
+	r.runs[jobID] = run
+	return ctx, func() { pe.endRun(run) }
+}
+
+// endRun unregisters run, marking its job interrupted when Drain interrupted it.
+func (pe *PulumiExecutor) endRun(run *pulumiRun) {
+	pe.runs.mu.Lock()
+	delete(pe.runs.runs, run.jobID)
+	interrupted := run.interrupted
+	pe.runs.mu.Unlock()
+
+	run.cancel()
+	if interrupted && run.ownsStatus {
+		pe.markInterrupted(run.jobID)
+	}
+	close(run.done)
+}
+
+// runContext returns the context of the job's running operation, or
+// context.Background when the job has none (e.g. in tests calling the
+// preparation steps directly).
+func (pe *PulumiExecutor) runContext(jobID string) context.Context {
+	pe.runs.mu.Lock()
+	defer pe.runs.mu.Unlock()
+	if run, ok := pe.runs.runs[jobID]; ok {
+		return run.ctx
+	}
+	return context.Background()
+}
+
+// running returns the operations in flight, oldest first.
+func (r *runRegistry) running() []*pulumiRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := make([]*pulumiRun, 0, len(r.runs))
+	for _, run := range r.runs {
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].started.Before(runs[j].started) })
+	return runs
+}
+
+// interruptAll cancels every operation in flight and the ones starting later.
+func (r *runRegistry) interruptAll() []*pulumiRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interrupting = true
+	runs := make([]*pulumiRun, 0, len(r.runs))
+	for _, run := range r.runs {
+		run.interrupted = true
+		run.cancel()
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// waitIdle waits until no operation is in flight, returning false when
+// deadline fires first. progress, when not nil, is called with the operations
+// left every drainProgressInterval.
+func (r *runRegistry) waitIdle(deadline <-chan time.Time, progress func([]*pulumiRun)) bool {
+	ticker := time.NewTicker(drainProgressInterval)
+	defer ticker.Stop()
+	for {
+		runs := r.running()
+		if len(runs) == 0 {
+			return true
+		}
+		select {
+		case <-runs[0].done:
+		case <-ticker.C:
+			if progress != nil {
+				progress(runs)
+			}
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// markInterrupted fails a job whose operation was interrupted by a shutdown and
+// saves it, unless the operation finished before it saw the interrupt. The
+// Pulumi state is saved on interrupt, so retrying the lab picks up where it
+// stopped.
+func (pe *PulumiExecutor) markInterrupted(jobID string) {
+	job, ok := pe.jobManager.GetJob(jobID)
+	if !ok {
+		return
+	}
+	job.mu.RLock()
+	status := job.Status
+	job.mu.RUnlock()
+	switch status {
+	case JobStatusCompleted, JobStatusDestroyed, JobStatusDryRunCompleted:
+		return
+	}
+	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("⚠️ Interrupted by a server shutdown at %s. Retry to pick up where it stopped.", time.Now().Format(time.RFC3339)))
+	pe.jobManager.SetError(jobID, errInterruptedByShutdown)
+	if err := pe.jobManager.SaveJob(jobID); err != nil {
+		slog.Warn("Failed to persist interrupted job", "job_id", jobID, "error", err)
+	}
+}
+
+// Drain is called on shutdown, once no new operation can be requested. It
+// waits up to timeout for the Pulumi operations in flight to finish, logging
+// the ones left as it goes, then interrupts the others and any starting later:
+// Pulumi is sent an interrupt, so it saves its state and releases the stack
+// lock, and their jobs are marked failed and saved. A timeout of 0 interrupts
+// them straight away.
+func (pe *PulumiExecutor) Drain(timeout time.Duration) {
+	if runs := pe.runs.running(); len(runs) > 0 {
+		slog.Info("Waiting for running Pulumi jobs before shutting down", "count", len(runs), "timeout", timeout)
+		started := time.Now()
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		if pe.runs.waitIdle(deadline.C, func(runs []*pulumiRun) {
+			for _, run := range runs {
+				slog.Info("Still waiting for a Pulumi job", "job_id", run.jobID, "operation", run.op, "running_for", time.Since(run.started).Round(time.Second), "time_left", (timeout - time.Since(started)).Round(time.Second))
+			}
+		}) {
+			slog.Info("Running Pulumi jobs finished", "waited", time.Since(started).Round(time.Second))
+		}
+	}
+
+	interrupted := pe.runs.interruptAll()
+	if len(interrupted) == 0 {
+		return
+	}
+	for _, run := range interrupted {
+		slog.Warn("Interrupting a Pulumi job to shut down", "job_id", run.jobID, "operation", run.op, "running_for", time.Since(run.started).Round(time.Second))
+	}
+	if pe.runs.waitIdle(time.After(drainInterruptWait), nil) {
+		return
+	}
+	// Whatever state these left behind, the job must not stay running forever.
+	for _, run := range pe.runs.running() {
+		slog.Error("Pulumi job did not stop after the interrupt, its stack may stay locked", "job_id", run.jobID, "operation", run.op)
+		if run.ownsStatus {
+			pe.markInterrupted(run.jobID)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drainTestExecutor returns an executor whose job manager saves jobs in a
+// temporary directory, and a running job.
+func drainTestExecutor(t *testing.T) (*PulumiExecutor, string) {
+	t.Helper()
+	jm := NewJobManager(t.TempDir())
+	t.Cleanup(jm.Close)
+	jobID := jm.CreateJob(&LabConfig{StackName: "drain"})
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	return &PulumiExecutor{jobManager: jm}, jobID
+}
+
+// startRun runs an operation of jobID in the background, as the executor
+// does, until it is cancelled or finish is closed; on finish it completes the
+// job. The returned channel is closed once the operation returned.
+func startRun(pe *PulumiExecutor, jobID string, ownsStatus bool, finish <-chan struct{}) <-chan struct{} {
+	ctx, end := pe.beginRun(jobID, "up", ownsStatus)
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		defer end()
+		select {
+		case <-ctx.Done():
+			pe.jobManager.SetError(jobID, ctx.Err())
+		case <-finish:
+			pe.jobManager.UpdateJobStatus(jobID, JobStatusCompleted)
+		}
+	}()
+	return returned
+}
+
+func TestDrain_NothingRunning(t *testing.T) {
+	pe, _ := drainTestExecutor(t)
+	start := time.Now()
+	pe.Drain(time.Minute)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestDrain_WaitsForRunningJobs(t *testing.T) {
+	pe, jobID := drainTestExecutor(t)
+	finish := make(chan struct{})
+	returned := startRun(pe, jobID, true, finish)
+	time.AfterFunc(50*time.Millisecond, func() { close(finish) })
+
+	pe.Drain(time.Minute)
+	<-returned
+
+	job, _ := pe.jobManager.GetJob(jobID)
+	assert.Equal(t, JobStatusCompleted, job.Status)
+	assert.Empty(t, job.Error)
+}
+
+func TestDrain_InterruptsJobsPastTheTimeout(t *testing.T) {
+	pe, jobID := drainTestExecutor(t)
+	returned := startRun(pe, jobID, true, nil)
+
+	pe.Drain(20 * time.Millisecond)
+	select {
+	case <-returned:
+	default:
+		t.Fatal("Drain returned before the interrupted job stopped")
+	}
+
+	job, _ := pe.jobManager.GetJob(jobID)
+	assert.Equal(t, JobStatusFailed, job.Status)
+	assert.Equal(t, errInterruptedByShutdown.Error(), job.Error)
+	assert.Contains(t, job.Output[len(job.Output)-1], "Interrupted by a server shutdown")
+	assert.FileExists(t, filepath.Join(pe.jobManager.dataDir, "jobs", jobID+".json"), "the interrupted job is saved")
+	assert.Empty(t, pe.runs.running())
+}
+
+func TestDrain_ZeroTimeoutInterruptsAtOnce(t *testing.T) {
+	pe, jobID := drainTestExecutor(t)
+	startRun(pe, jobID, true, nil)
+
+	start := time.Now()
+	pe.Drain(0)
+	assert.Less(t, time.Since(start), time.Second)
+	job, _ := pe.jobManager.GetJob(jobID)
+	assert.Equal(t, errInterruptedByShutdown.Error(), job.Error)
+}
+
+func TestDrain_LeavesTheStatusToCallersThatOwnIt(t *testing.T) {
+	pe, jobID := drainTestExecutor(t)
+	ctx, end := pe.beginRun(jobID, "scale", false)
+	go func() {
+		<-ctx.Done()
+		end()
+	}()
+
+	pe.Drain(0)
+	job, _ := pe.jobManager.GetJob(jobID)
+	assert.Equal(t, JobStatusRunning, job.Status, "the scale's caller records its outcome")
+	assert.Empty(t, job.Error)
+}
+
+func TestDrain_InterruptsOperationsStartingAfterwards(t *testing.T) {
+	pe, jobID := drainTestExecutor(t)
+	pe.Drain(0)
+
+	ctx, end := pe.beginRun(jobID, "destroy", true)
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	end()
+	job, _ := pe.jobManager.GetJob(jobID)
+	assert.Equal(t, errInterruptedByShutdown.Error(), job.Error)
+}
+
+func TestBeginRun_SharesTheContextOfTheJob(t *testing.T) {
+	pe, jobID := drainTestExecutor(t)
+	assert.Equal(t, context.Background(), pe.runContext(jobID))
+
+	ctx, end := pe.beginRun(jobID, "up", true)
+	assert.Equal(t, ctx, pe.runContext(jobID))
+	nested, endNested := pe.beginRun(jobID, "preview", true)
+	assert.Equal(t, ctx, nested)
+	endNested()
+	assert.Len(t, pe.runs.running(), 1, "only the outer operation unregisters the job")
+
+	end()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Empty(t, pe.runs.running())
+}
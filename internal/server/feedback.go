@@ -3,7 +3,7 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -107,14 +107,13 @@ func (h *Handler) ServeFeedback(w http.ResponseWriter, r *http.Request) {
 
 // writeToast writes a toast notification HTML fragment to the response
 func writeToast(w http.ResponseWriter, success bool, message string) {
-	w.Header().Set("Content-Type", "text/html")
 	class := "toast toast-success"
 	icon := "✅"
 	if !success {
 		class = "toast toast-error"
 		icon = "❌"
 	}
-	fmt.Fprintf(w, `<div class="%s"><span class="toast-icon">%s</span><span>%s</span></div>`, class, icon, message)
+	writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="%s"><span class="toast-icon">%s</span><span>%s</span></div>`, class, icon, message))
 }
 
 // isHTMXRequest returns true when the request was made by HTMX (has HX-Request header)
@@ -201,7 +200,7 @@ func (h *Handler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.feedbackStore.Add(f); err != nil {
-		log.Printf("Failed to save feedback for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "Failed to save feedback for lab", "job_id", labID, "error", err)
 		if htmx {
 			writeToast(w, false, "Failed to save feedback. Please try again.")
 		} else {
@@ -210,7 +209,7 @@ func (h *Handler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Feedback submitted for lab %s (rating=%d)", labID, rating)
+	slog.InfoContext(r.Context(), "Feedback submitted", "job_id", labID, "rating", rating)
 	if htmx {
 		writeToast(w, true, "Thank you! Your feedback has been saved.")
 	} else {
@@ -283,7 +282,7 @@ func (h *Handler) ServeAdminLabFeedback(w http.ResponseWriter, r *http.Request)
 
 		entries, err := h.feedbackStore.GetByLab(labID)
 		if err != nil {
-			log.Printf("Failed to load feedback for lab %s: %v", labID, err)
+			slog.ErrorContext(r.Context(), "Failed to load feedback for lab", "job_id", labID, "error", err)
 			http.Error(w, "Failed to load feedback", http.StatusInternalServerError)
 			return
 		}
@@ -152,7 +152,7 @@ func TestWriteToast_Success(t *testing.T) {
 	assert.Contains(t, body, "toast-success")
 	assert.Contains(t, body, "All good!")
 	assert.Contains(t, body, "✅")
-	assert.Equal(t, "text/html", w.Header().Get("Content-Type"))
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
 }
 
 func TestWriteToast_Error(t *testing.T) {
@@ -392,8 +392,7 @@ func TestServeAdminLabFeedback_NoLabID(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	h.ServeAdminLabFeedback(w, req)
-	// Will return 500 since templates aren't loaded in tests — but the function body runs
-	// (HasLabID=false path is exercised)
+	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestServeAdminLabFeedback_WithLabID_NoFeedback(t *testing.T) {
@@ -470,7 +469,7 @@ func TestServeFeedback(t *testing.T) {
 	req := httptest.NewRequest("GET", "/student/feedback?success=1", nil)
 	w := httptest.NewRecorder()
 	h.ServeFeedback(w, req)
-	// Template will fail (no web/ dir) but the handler body runs
+	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestServeFeedback_WithError(t *testing.T) {
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// duplicateLabActions lets the admin check the existing job or resubmit the
+// wizard with force set.
+const duplicateLabActions = `<a href="/jobs" class="btn btn-secondary">View Labs</a> ` +
+	`<button type="button" class="btn btn-primary" hx-post="/api/labs" hx-include="#lab-form" hx-encoding="multipart/form-data" ` +
+	`hx-vals='{"force": "true"}' hx-target="#form-response" hx-swap="innerHTML" hx-indicator="#loading">Create Anyway</button>`
+
+// labFingerprint is the part of a LabConfig that decides what a lab deploys and
+// where. Credentials, generated secrets, workspace templates and dates are left
+// out: two labs that differ only in those still land on the same stack and the
+// same cloud resources.
+type labFingerprint struct {
+	StackName          string `json:"stack_name"`
+	Provider           string `json:"provider"`
+	UseExistingCluster bool   `json:"use_existing_cluster"`
+
+	OvhServiceName            string `json:"ovh_service_name"`
+	AzureSubscriptionID       string `json:"azure_subscription_id"`
+	AzureLocation             string `json:"azure_location"`
+	NetworkRegion             string `json:"network_region"`
+	NetworkPrivateNetworkName string `json:"network_private_network_name"`
+	NetworkGatewayName        string `json:"network_gateway_name"`
+	K8sClusterName            string `json:"k8s_cluster_name"`
+	NodePoolName              string `json:"nodepool_name"`
+	NodePoolFlavor            string `json:"nodepool_flavor"`
+	NodePoolDesiredNodeCount  int    `json:"nodepool_desired_node_count"`
+
+	ImportPrivateNetworkID string `json:"import_private_network_id"`
+	ImportSubnetID         string `json:"import_subnet_id"`
+	ImportClusterID        string `json:"import_cluster_id"`
+
+	Domain             string `json:"domain"`
+	WildcardDomain     string `json:"wildcard_domain"`
+	WorkspaceNamespace string `json:"workspace_namespace"`
+}
+
+// fingerprint returns a stable hash of the infrastructure-relevant fields of
+// the config. Two active jobs with the same fingerprint are almost certainly
+// the same lab deployed twice.
+func (c *LabConfig) fingerprint() string {
+	provider := c.Provider
+	if provider == "" && !c.UseExistingCluster {
+		provider = "ovh"
+	}
+	// Marshalling a struct keeps the field order fixed, so the hash is stable.
+	data, _ := json.Marshal(labFingerprint{
+		StackName:          c.StackName,
+		Provider:           provider,
+		UseExistingCluster: c.UseExistingCluster,
+
+		OvhServiceName:            c.OvhServiceName,
+		AzureSubscriptionID:       c.AzureSubscriptionID,
+		AzureLocation:             c.AzureLocation,
+		NetworkRegion:             c.NetworkRegion,
+		NetworkPrivateNetworkName: c.NetworkPrivateNetworkName,
+		NetworkGatewayName:        c.NetworkGatewayName,
+		K8sClusterName:            c.K8sClusterName,
+		NodePoolName:              c.NodePoolName,
+		NodePoolFlavor:            c.NodePoolFlavor,
+		NodePoolDesiredNodeCount:  c.NodePoolDesiredNodeCount,
+
+		ImportPrivateNetworkID: c.ImportPrivateNetworkID,
+		ImportSubnetID:         c.ImportSubnetID,
+		ImportClusterID:        c.ImportClusterID,
+
+		Domain:             c.Domain,
+		WildcardDomain:     c.WildcardDomain,
+		WorkspaceNamespace: c.WorkspaceNamespace,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FindActiveDuplicate returns the newest pending, running or completed job whose
+// config has the same fingerprint as config. Failed, destroyed and dry-run jobs
+// hold no live lab, so they never count as duplicates, and neither do templates.
+func (jm *JobManager) FindActiveDuplicate(config *LabConfig) (*Job, bool) {
+	if config == nil {
+		return nil, false
+	}
+	want := config.fingerprint()
+	for _, job := range jm.GetLabs() {
+		job.mu.RLock()
+		active := job.Status == JobStatusPending || job.Status == JobStatusRunning || job.Status == JobStatusCompleted
+		match := active && job.Config != nil && job.Config.fingerprint() == want
+		job.mu.RUnlock()
+
+		if match {
+			return job, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fingerprintTestConfig() *LabConfig {
+	return &LabConfig{
+		StackName:                "workshop",
+		Provider:                 "ovh",
+		OvhServiceName:           "project-1",
+		NetworkRegion:            "GRA9",
+		K8sClusterName:           "workshop-cluster",
+		NodePoolName:             "pool",
+		NodePoolFlavor:           "b3-8",
+		NodePoolDesiredNodeCount: 3,
+		Domain:                   "lab.example.com",
+	}
+}
+
+func TestLabConfigFingerprint_IdenticalConfigsCollide(t *testing.T) {
+	a, b := fingerprintTestConfig(), fingerprintTestConfig()
+	assert.Equal(t, a.fingerprint(), b.fingerprint())
+
+	// Credentials, generated secrets, templates and dates are not infrastructure.
+	deletion := time.Now()
+	b.OvhApplicationKey = "other-key"
+	b.OvhApplicationSecret = "other-secret"
+	b.GrafanaAdminPassword = "generated"
+	b.WorkspaceTemplates = []WorkspaceTemplate{{Name: "python"}}
+	b.LabDeletionDate = &deletion
+	assert.Equal(t, a.fingerprint(), b.fingerprint())
+
+	// An empty provider is OVH, as in the rest of the server.
+	b.Provider = ""
+	assert.Equal(t, a.fingerprint(), b.fingerprint())
+}
+
+func TestLabConfigFingerprint_DifferingConfigsDoNot(t *testing.T) {
+	base := fingerprintTestConfig().fingerprint()
+	tests := map[string]func(*LabConfig){
+		"stack name":  func(c *LabConfig) { c.StackName = "workshop-2" },
+		"region":      func(c *LabConfig) { c.NetworkRegion = "SBG5" },
+		"cluster":     func(c *LabConfig) { c.K8sClusterName = "other" },
+		"node count":  func(c *LabConfig) { c.NodePoolDesiredNodeCount = 4 },
+		"domain":      func(c *LabConfig) { c.Domain = "other.example.com" },
+		"provider":    func(c *LabConfig) { c.Provider = "azure" },
+		"byok":        func(c *LabConfig) { c.UseExistingCluster = true },
+		"import":      func(c *LabConfig) { c.ImportClusterID = "kube-1" },
+		"ovh project": func(c *LabConfig) { c.OvhServiceName = "project-2" },
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := fingerprintTestConfig()
+			change(c)
+			assert.NotEqual(t, base, c.fingerprint())
+		})
+	}
+}
+
+func TestJobManager_FindActiveDuplicate(t *testing.T) {
+	jm := NewJobManager("")
+	_, ok := jm.FindActiveDuplicate(nil)
+	assert.False(t, ok)
+
+	jobID := jm.CreateJob(fingerprintTestConfig())
+	for _, status := range []JobStatus{JobStatusPending, JobStatusRunning, JobStatusCompleted} {
+		assert.NoError(t, jm.UpdateJobStatus(jobID, status))
+		job, ok := jm.FindActiveDuplicate(fingerprintTestConfig())
+		if assert.True(t, ok, "a %s job is active", status) {
+			assert.Equal(t, jobID, job.ID)
+		}
+	}
+	for _, status := range []JobStatus{JobStatusFailed, JobStatusDestroyed, JobStatusDryRunCompleted} {
+		assert.NoError(t, jm.UpdateJobStatus(jobID, status))
+		_, ok := jm.FindActiveDuplicate(fingerprintTestConfig())
+		assert.False(t, ok, "a %s job holds no live lab", status)
+	}
+
+	other := fingerprintTestConfig()
+	other.StackName = "another"
+	_, ok = jm.FindActiveDuplicate(other)
+	assert.False(t, ok)
+}
+
+func TestHandler_CreateLab_BlocksDuplicate(t *testing.T) {
+	jm := newTestJobManager(t, t.TempDir())
+	pe := NewPulumiExecutor(jm, t.TempDir())
+	h := NewHandler(jm, pe, NewCredentialsManager(), nil, nil, nil)
+
+	form := url.Values{}
+	form.Set("use_existing_cluster", "true")
+	form.Set("stack_name", "workshop")
+	newRequest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/labs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.CreateLab(w, req)
+		return w
+	}
+
+	existingID := jm.CreateJob(&LabConfig{StackName: "workshop", UseExistingCluster: true})
+	assert.NoError(t, jm.UpdateJobStatus(existingID, JobStatusCompleted))
+
+	body := newRequest().Body.String()
+	assert.Contains(t, body, "Duplicate Lab")
+	assert.Contains(t, body, existingID)
+	assert.Contains(t, body, `"force": "true"`)
+	assert.Len(t, jm.GetAllJobs(), 1, "no job is created for a duplicate")
+
+	// With force the request goes through to the next check, the BYOK kubeconfig.
+	form.Set("force", "true")
+	body = newRequest().Body.String()
+	assert.NotContains(t, body, "Duplicate Lab")
+	assert.Contains(t, body, "Kubeconfig")
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"easylab/ovh"
+)
+
+// flavorCatalogTTL is how long a region's flavor catalog is served from memory.
+// Prices and flavors change a few times a year at most.
+const flavorCatalogTTL = time.Hour
+
+// flavorCatalogItem is a node pool flavor as GET /api/ovh/flavors describes it
+// in JSON. Disk and price are zero when the OVH catalog could not be read.
+type flavorCatalogItem struct {
+	Name        string  `json:"name"`
+	VCPUs       int     `json:"vcpus"`
+	RAM         int     `json:"ram"`
+	GPUs        int     `json:"gpus"`
+	Disk        int     `json:"disk"`
+	HourlyPrice float64 `json:"hourly_price,omitempty"`
+	Currency    string  `json:"currency,omitempty"`
+}
+
+type flavorCatalogEntry struct {
+	flavors []flavorCatalogItem
+	fetched time.Time
+}
+
+// flavorCatalogCache keeps each region's catalog for flavorCatalogTTL, so
+// browsing flavors does not hit the OVH API on every request.
+type flavorCatalogCache struct {
+	mu      sync.Mutex
+	entries map[string]flavorCatalogEntry
+}
+
+func (c *flavorCatalogCache) get(region string, now time.Time) ([]flavorCatalogItem, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[region]
+	if !ok || now.Sub(entry.fetched) > flavorCatalogTTL {
+		return nil, false
+	}
+	return entry.flavors, true
+}
+
+func (c *flavorCatalogCache) put(region string, flavors []flavorCatalogItem, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]flavorCatalogEntry)
+	}
+	c.entries[region] = flavorCatalogEntry{flavors: flavors, fetched: now}
+}
+
+// flavorCatalog returns the node pool flavors of region with their disk and
+// hourly price. Without the prices the flavors are still returned, but not
+// cached, so the next request tries the OVH catalog again.
+func (h *Handler) flavorCatalog(region string) ([]flavorCatalogItem, error) {
+	now := time.Now()
+	if flavors, ok := h.flavorCatalogCache.get(region, now); ok {
+		return flavors, nil
+	}
+	flavors, err := h.regionFlavors(region)
+	if err != nil {
+		return nil, err
+	}
+	pricing, err := h.flavorPricing(region)
+	if err != nil {
+		slog.Warn("Flavor prices unavailable", "region", region, "error", err)
+	}
+	items := make([]flavorCatalogItem, 0, len(flavors))
+	for _, f := range flavors {
+		p := pricing[f.Name]
+		items = append(items, flavorCatalogItem{
+			Name: f.Name, VCPUs: f.VCPUs, RAM: f.RAM, GPUs: f.GPUs,
+			Disk: p.Disk, HourlyPrice: p.HourlyPrice, Currency: p.Currency,
+		})
+	}
+	if err == nil {
+		h.flavorCatalogCache.put(region, items, now)
+	}
+	return items, nil
+}
+
+// flavorPricing reads the disk and hourly price of region's flavors from the
+// OVH API, in the currency of the credentials' endpoint.
+func (h *Handler) flavorPricing(region string) (map[string]ovh.FlavorPricing, error) {
+	creds, err := h.credentialsManager.GetOVHCredentials()
+	if err != nil {
+		return nil, err
+	}
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = ovhCheckTimeout
+	return ovh.FetchFlavorPricing(client, serviceName, region, ovh.SubsidiaryForEndpoint(creds.Endpoint))
+}
+
+// acceptsJSON reports whether the client asked for JSON rather than the HTML
+// fragments the wizard loads.
+func acceptsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// writeFlavorCatalog answers GET /api/ovh/flavors with Accept: application/json:
+// every flavor of the region, ignoring the admin's filters, with its size and
+// price.
+func (h *Handler) writeFlavorCatalog(w http.ResponseWriter, region string) {
+	flavors, err := h.flavorCatalog(region)
+	if err != nil {
+		slog.Error("GetOVHFlavors failed", "error", err)
+		writeJSONError(w, http.StatusBadGateway, "Failed to load flavors")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"region":  region,
+		"flavors": flavors,
+	})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlavorCatalogCache_ExpiresAfterTTL(t *testing.T) {
+	var c flavorCatalogCache
+	now := time.Now()
+	_, ok := c.get("GRA11", now)
+	assert.False(t, ok)
+
+	c.put("GRA11", []flavorCatalogItem{{Name: "b2-7"}}, now)
+	flavors, ok := c.get("GRA11", now.Add(59*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, "b2-7", flavors[0].Name)
+
+	_, ok = c.get("GRA11", now.Add(61*time.Minute))
+	assert.False(t, ok, "the catalog is refreshed after an hour")
+}
+
+func TestGetOVHFlavors_JSONCatalog(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	h.flavorCatalogCache.put("GRA11", []flavorCatalogItem{{Name: "b2-7", VCPUs: 2, RAM: 7, Disk: 50, HourlyPrice: 0.0681, Currency: "EUR"}}, time.Now())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ovh/flavors?region=GRA11", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.GetOVHFlavors(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"region":"GRA11","flavors":[{"name":"b2-7","vcpus":2,"ram":7,"gpus":0,"disk":50,"hourly_price":0.0681,"currency":"EUR"}]}`, w.Body.String())
+
+	// Nothing cached and no credentials: the flavors cannot be listed.
+	req = httptest.NewRequest(http.MethodGet, "/api/ovh/flavors?region=BHS5", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	h.GetOVHFlavors(w, req)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
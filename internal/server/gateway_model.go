@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"easylab/ovh"
+)
+
+// gatewayAvailability fetches, from the OVH capabilities API, the gateway
+// models each region offers.
+func (h *Handler) gatewayAvailability() (ovh.GatewayAvailability, error) {
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = ovhCheckTimeout
+	return ovh.FetchGatewayAvailability(client, serviceName)
+}
+
+// checkGatewayModel rejects, for a dry run, a gateway model the lab's region
+// does not offer, which OVH otherwise only reports once pulumi up creates the
+// gateway. Like the VLAN check it is best effort: when the availability cannot
+// be fetched the model is let through.
+func (h *Handler) checkGatewayModel(cfg *LabConfig) error {
+	if cfg.UseExistingCluster || cfg.Provider == "azure" {
+		return nil
+	}
+	avail, err := h.gatewayAvailability()
+	if err != nil {
+		slog.Warn("Gateway model check skipped", "error", err)
+		return nil
+	}
+	return ovh.ValidateGatewayModel(cfg.NetworkGatewayModel, cfg.NetworkRegion, avail.Models(cfg.NetworkRegion))
+}
+
+// GetOVHGatewayModels returns HTML <option> elements for the gateway models
+// OVH offers in a region, with the cheapest selected.
+// Query param: region (required).
+// When the availability cannot be fetched it lists every model, so the wizard
+// still works without API access.
+func (h *Handler) GetOVHGatewayModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	region := r.URL.Query().Get("region")
+	if region == "" {
+		http.Error(w, "Region is required", http.StatusBadRequest)
+		return
+	}
+
+	models := ovh.GatewayModels
+	if avail, err := h.gatewayAvailability(); err != nil {
+		slog.ErrorContext(r.Context(), "GetOVHGatewayModels failed", "error", err)
+	} else if models = avail.Models(region); len(models) == 0 {
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>No gateway available in this region</option>`)
+		return
+	}
+	writeHTMLFragment(w, http.StatusOK, gatewayModelOptions(models))
+}
+
+// gatewayModelLabels names the known gateway sizes in the wizard.
+var gatewayModelLabels = map[string]string{"s": "Small", "m": "Medium", "l": "Large"}
+
+// gatewayModelOptions renders the models as <option> elements, the cheapest
+// selected.
+func gatewayModelOptions(models []string) string {
+	var b strings.Builder
+	cheapest := ovh.CheapestGatewayModel(models)
+	for _, m := range models {
+		label := m
+		if name, ok := gatewayModelLabels[m]; ok {
+			label = fmt.Sprintf("%s (%s)", m, name)
+		}
+		selected := ""
+		if m == cheapest {
+			selected = " selected"
+		}
+		fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, escapeHTML(m), selected, escapeHTML(label))
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatewayModelOptions_SelectsTheCheapest(t *testing.T) {
+	got := gatewayModelOptions([]string{"m", "l", "xl"})
+	assert.Equal(t, `<option value="m" selected>m (Medium)</option><option value="l">l (Large)</option><option value="xl">xl</option>`, got)
+}
+
+func TestGetOVHGatewayModels(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.GetOVHGatewayModels(w, httptest.NewRequest(http.MethodGet, "/api/ovh/gateway-models", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "the region is required")
+
+	w = httptest.NewRecorder()
+	h.GetOVHGatewayModels(w, httptest.NewRequest(http.MethodPost, "/api/ovh/gateway-models?region=GRA11", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	// Without credentials the availability is unknown: every model is offered.
+	w = httptest.NewRecorder()
+	h.GetOVHGatewayModels(w, httptest.NewRequest(http.MethodGet, "/api/ovh/gateway-models?region=GRA11", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, gatewayModelOptions([]string{"s", "m", "l"}), w.Body.String())
+}
+
+func TestCheckGatewayModel_SkipsWhenItCannotCheck(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	assert.NoError(t, h.checkGatewayModel(&LabConfig{Provider: "azure", NetworkGatewayModel: "xxl"}))
+	assert.NoError(t, h.checkGatewayModel(&LabConfig{UseExistingCluster: true, NetworkGatewayModel: "xxl"}))
+	// No OVH credentials: the availability cannot be fetched.
+	assert.NoError(t, h.checkGatewayModel(&LabConfig{NetworkRegion: "GRA11", NetworkGatewayModel: "xxl"}))
+}
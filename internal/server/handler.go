@@ -10,16 +10,18 @@ import (
 	"easylab/internal/tfparse"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -28,9 +30,13 @@ import (
 
 	"easylab/coder"
 	dnsregistry "easylab/internal/providers/dns"
+	"easylab/ovh"
+	"easylab/utils"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // Handler handles HTTP requests
@@ -40,15 +46,44 @@ type Handler struct {
 	// newWorkspaceBackend builds the workspace backend for a lab from its kubeconfig
 	// and namespace. Overridable in tests to inject a fake backend.
 	newWorkspaceBackend func(kubeconfig, namespace string) (workspace.Backend, error)
+	// resolveGitCommit returns the commit a template's branch points to, for
+	// pinned labs (see template_pins.go). Overridable in tests.
+	resolveGitCommit func(ctx context.Context, repo, branch string, auth transport.AuthMethod) (string, error)
+	// executeRetry runs a retried job in the background (see RetryJob).
+	// Overridable in tests.
+	executeRetry        func(jobID string) error
 	templates           map[string]*template.Template
-	templatesMu         sync.RWMutex
+	static              http.Handler
+	templatesMu         sync.RWMutex // guards templates and static
 	credentialsManager  *CredentialsManager
 	ovhOptionsManager   *OVHOptionsManager
 	azureOptionsManager *AzureOptionsManager
 	feedbackStore       *FeedbackStore
 	// pendingSecrets holds credentials captured in the creation wizard until the
 	// lab's cluster exists to receive them (see pending_secrets.go).
-	pendingSecrets              *pendingSecretStore
+	pendingSecrets *pendingSecretStore
+	// resendLimiter rate-limits ResendWorkspaceInfo per student and lab.
+	resendLimiter *resendLimiter
+	// maintenance pauses new deployments (see maintenance.go).
+	maintenance *MaintenanceMode
+	// broadcast is the banner shown to admins and students (see broadcast.go).
+	broadcast *BroadcastBoard
+	// notifications posts to the admins' chat channels (see notifications.go).
+	notifications *Notifications
+	// labHealth holds whether each lab's cluster answers (see lab_health.go).
+	labHealth labHealthCache
+	// flavorCatalogCache holds the flavors' sizes and prices (see flavor_catalog.go).
+	flavorCatalogCache flavorCatalogCache
+	// helmIndexCache holds the chart repositories' indexes (see chart_versions.go).
+	helmIndexCache helmIndexCache
+	// flavorPrices are the admin's flavor prices for cost estimates (see lab_cost.go).
+	flavorPrices FlavorPriceTable
+	// shareLinks signs the links students enroll with (see share_links.go).
+	shareLinks *shareLinkSigner
+	// cleanup is how often expired workspaces are deleted (see cleanup.go).
+	cleanup CleanupConfig
+	// metricsToken is the bearer token of /metrics; empty turns it off.
+	metricsToken                string
 	azureADConfigurer           func(clientID, clientSecret, tenantID string)
 	classicLoginConfigurer      func(disabled bool)
 	adminGroupIDConfigurer      func(groupID string)
@@ -110,24 +145,63 @@ func atoiForm(s string) int {
 	return n
 }
 
+// formInts parses the integer fields of a lab form. A field that is not a
+// whole number reads as 0 and is remembered, so that every offending field is
+// reported at once rather than deploying a lab with a zero in its place.
+type formInts struct {
+	r       *http.Request
+	invalid []string
+}
+
+// get returns the field's value, 0 when it is empty or not a whole number.
+func (f *formInts) get(field string) int {
+	v := strings.TrimSpace(f.r.FormValue(field))
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		f.invalid = append(f.invalid, fmt.Sprintf("%s=%q", field, v))
+		return 0
+	}
+	return n
+}
+
+// err lists the fields that are not whole numbers, nil when there are none.
+func (f *formInts) err() error {
+	if len(f.invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("not a whole number: %s", strings.Join(f.invalid, ", "))
+}
+
 // NewHandler creates a new HTTP handler
 func NewHandler(jobManager *JobManager, pulumiExec *PulumiExecutor, credentialsManager *CredentialsManager, ovhOptionsManager *OVHOptionsManager, azureOptionsManager *AzureOptionsManager, feedbackStore *FeedbackStore) *Handler {
 	h := &Handler{
 		jobManager:          jobManager,
 		pulumiExec:          pulumiExec,
 		newWorkspaceBackend: workspace.Default,
-		templates:           make(map[string]*template.Template),
+		resolveGitCommit:    lsRemoteCommit,
+		executeRetry:        pulumiExec.ExecuteRetry,
+		templates:           mustEmbedded(embeddedHandlerTemplates()),
+		static:              mustEmbedded(embeddedStatic()),
 		credentialsManager:  credentialsManager,
 		ovhOptionsManager:   ovhOptionsManager,
 		azureOptionsManager: azureOptionsManager,
 		feedbackStore:       feedbackStore,
 		pendingSecrets:      newPendingSecretStore(),
-	}
-	// Credentials captured in the wizard are written once the lab's cluster is up.
-	// The executor owns that moment; the handler owns the cluster connection — so
-	// the executor calls back here rather than growing a backend of its own.
+		resendLimiter:       newResendLimiter(),
+		maintenance:         NewMaintenanceMode(""),
+		broadcast:           NewBroadcastBoard(""),
+		shareLinks:          newShareLinkSigner(""),
+		cleanup:             DefaultServerConfig().Cleanup,
+	}
+	// Baseline manifests and the credentials captured in the wizard are written
+	// once the lab's cluster is up. The executor owns that moment; the handler owns
+	// the cluster connection — so the executor calls back here rather than growing
+	// a backend of its own.
 	if pulumiExec != nil {
-		pulumiExec.afterProvision = h.applyPendingSecrets
+		pulumiExec.afterProvision = h.prepareProvisionedCluster
 	}
 	return h
 }
@@ -179,15 +253,15 @@ func (h *Handler) parseForm(w http.ResponseWriter, r *http.Request, maxSize int6
 	}
 
 	contentType := r.Header.Get("Content-Type")
-	log.Printf("Content-Type: %s", contentType)
+	slog.DebugContext(r.Context(), "Parsing form", "content_type", contentType)
 
 	// Handle multipart/form-data
 	if strings.HasPrefix(contentType, "multipart/form-data") {
 		if err := r.ParseMultipartForm(maxSize); err != nil {
-			log.Printf("Failed to parse multipart form: %v", err)
+			slog.WarnContext(r.Context(), "Failed to parse multipart form", "error", err)
 			return fmt.Errorf("failed to parse multipart form: %w", err)
 		}
-		log.Printf("Form parsed successfully (multipart)")
+		slog.DebugContext(r.Context(), "Form parsed successfully (multipart)")
 		return nil
 	}
 
@@ -195,10 +269,10 @@ func (h *Handler) parseForm(w http.ResponseWriter, r *http.Request, maxSize int6
 	// ParseForm works for both urlencoded forms and can handle missing Content-Type
 	// by detecting the form data in the request body
 	if err := r.ParseForm(); err != nil {
-		log.Printf("Failed to parse form: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse form", "error", err)
 		return fmt.Errorf("failed to parse form: %w", err)
 	}
-	log.Printf("Form parsed successfully (urlencoded or auto-detected)")
+	slog.DebugContext(r.Context(), "Form parsed successfully (urlencoded or auto-detected)")
 	return nil
 }
 
@@ -206,7 +280,7 @@ func (h *Handler) parseForm(w http.ResponseWriter, r *http.Request, maxSize int6
 func (h *Handler) getOVHCredentials(w http.ResponseWriter) (*OVHCredentials, error) {
 	creds, err := h.credentialsManager.GetCredentials("ovh")
 	if err != nil {
-		log.Printf("OVH credentials not configured: %v", err)
+		slog.Warn("OVH credentials not configured", "error", err)
 		h.renderHTMLError(w, "OVH Credentials Not Configured", "Please configure your OVH credentials first.", `<a href="/credentials?provider=ovh" class="btn btn-primary">Configure OVH Credentials</a>`)
 		return nil, err
 	}
@@ -221,7 +295,7 @@ func (h *Handler) getProviderCredentials(w http.ResponseWriter, providerName str
 
 	creds, err := h.credentialsManager.GetCredentials(providerName)
 	if err != nil {
-		log.Printf("%s credentials not configured: %v", providerName, err)
+		slog.Warn("Credentials not configured", "provider", providerName, "error", err)
 		h.renderHTMLError(w, fmt.Sprintf("%s Credentials Not Configured", strings.ToUpper(providerName)),
 			fmt.Sprintf("Please configure your %s credentials first.", strings.ToUpper(providerName)),
 			fmt.Sprintf(`<a href="/credentials?provider=%s" class="btn btn-primary">Configure Credentials</a>`, providerName))
@@ -232,7 +306,7 @@ func (h *Handler) getProviderCredentials(w http.ResponseWriter, providerName str
 
 // parseWorkspaceTemplatesFromForm extracts workspace template entries from the form.
 // Expects template_N_name, template_N_image, template_N_git_repo, template_N_cpu,
-// template_N_memory, template_N_disk_size, and repeated template_N_env_name /
+// template_N_memory, template_N_disk_size, template_N_gpu, and repeated template_N_env_name /
 // template_N_env_value pairs.
 func parseWorkspaceTemplatesFromForm(r *http.Request) []WorkspaceTemplate {
 	var templates []WorkspaceTemplate
@@ -251,6 +325,7 @@ func parseWorkspaceTemplatesFromForm(r *http.Request) []WorkspaceTemplate {
 			CPU:           getFormValue(r, fmt.Sprintf("template_%d_cpu", i)),
 			Memory:        getFormValue(r, fmt.Sprintf("template_%d_memory", i)),
 			DiskSize:      getFormValue(r, fmt.Sprintf("template_%d_disk_size", i)),
+			GPU:           atoiForm(getFormValue(r, fmt.Sprintf("template_%d_gpu", i))),
 			StartupScript: getFormValue(r, fmt.Sprintf("template_%d_startup_script", i)),
 			DotfilesRepo:  getFormValue(r, fmt.Sprintf("template_%d_dotfiles_repo", i)),
 			Extensions:    splitList(getFormValue(r, fmt.Sprintf("template_%d_extensions", i))),
@@ -389,6 +464,20 @@ func toWorkspaceSidecars(in []WorkspaceSidecar) []workspace.Sidecar {
 	return out
 }
 
+// workspaceScheduling returns the nodeSelector and tolerations that put a lab's
+// workspaces on its labelled and tainted node pool. Only an OVH node pool
+// created by the lab carries them.
+func workspaceScheduling(cfg *LabConfig) (map[string]string, []workspace.Toleration) {
+	if cfg == nil || cfg.UseExistingCluster || cfg.Provider == "azure" {
+		return nil, nil
+	}
+	var tolerations []workspace.Toleration
+	for _, t := range cfg.NodePoolTaints {
+		tolerations = append(tolerations, workspace.Toleration{Key: t.Key, Value: t.Value, Effect: t.Effect})
+	}
+	return cfg.NodePoolLabels, tolerations
+}
+
 // toWorkspaceMounts maps server mount structs to backend types.
 func toWorkspaceMounts(in []WorkspaceMount) []workspace.Mount {
 	if len(in) == 0 {
@@ -416,8 +505,11 @@ func toWorkspaceDevcontainer(in *DevcontainerConfig) *workspace.DevcontainerSpec
 	}
 }
 
-// createLabConfigFromForm creates a LabConfig from form data and provider credentials.
-func (h *Handler) createLabConfigFromForm(r *http.Request, providerCreds ProviderCredentials) *LabConfig {
+// createLabConfigFromForm creates a LabConfig from form data and provider
+// credentials. It fails when an integer field is not a whole number.
+func (h *Handler) createLabConfigFromForm(r *http.Request, providerCreds ProviderCredentials) (*LabConfig, error) {
+	ints := &formInts{r: r}
+
 	// Get stack name with default
 	stackName := r.FormValue("stack_name")
 	if stackName == "" {
@@ -430,10 +522,22 @@ func (h *Handler) createLabConfigFromForm(r *http.Request, providerCreds Provide
 
 	config := &LabConfig{
 		StackName:          stackName,
+		Event:              strings.TrimSpace(r.FormValue("event")),
+		WebhookURLs:        splitWebhookURLs(r.FormValue("webhook_urls")),
 		UseExistingCluster: useExistingCluster,
 
-		WorkspaceNamespace: r.FormValue("workspace_namespace"),
-		WorkspaceTemplates: templates,
+		WorkspaceNamespace:    r.FormValue("workspace_namespace"),
+		StorageClass:          strings.TrimSpace(r.FormValue("storage_class")),
+		WorkspaceTemplates:    templates,
+		WorkspaceProxyEnabled: r.FormValue("workspace_proxy_enabled") == "true",
+		PostDeployManifests:   parsePostDeployManifests(r.FormValue("post_deploy_manifests")),
+		HelmRepoOverrides:     parseAnnotations(r.FormValue("helm_repo_overrides")),
+		HelmChartVersions:     parseAnnotations(r.FormValue("helm_chart_versions")),
+		InstallMonitoring:     r.FormValue("install_monitoring") == "true",
+		WorkspaceQuota:        parseWorkspaceQuota(r),
+		MaxWorkspaces:         ints.get("max_workspaces"),
+		StudentAccessCode:     r.FormValue("student_access_code"),
+		PinTemplateVersions:   r.FormValue("pin_template_versions") == "true",
 
 		Domain:         r.FormValue("domain"),
 		AcmeEmail:      r.FormValue("acme_email"),
@@ -449,6 +553,11 @@ func (h *Handler) createLabConfigFromForm(r *http.Request, providerCreds Provide
 	if !installNginx {
 		config.NginxIngressNamespace = r.FormValue("nginx_ingress_namespace")
 		config.NginxIngressServiceName = r.FormValue("nginx_ingress_service_name")
+	} else {
+		config.LoadBalancerAnnotations = parseAnnotations(r.FormValue("loadbalancer_annotations"))
+		config.LoadBalancerIP = strings.TrimSpace(r.FormValue("loadbalancer_ip"))
+		config.IngressIPTimeoutMinutes, _ = strconv.Atoi(r.FormValue("ingress_ip_timeout_minutes"))
+		config.NodePortFallback = r.FormValue("node_port_fallback") == "true"
 	}
 	installCertM := r.FormValue("install_cert_manager") == "true"
 	config.InstallCertManager = &installCertM
@@ -465,11 +574,12 @@ func (h *Handler) createLabConfigFromForm(r *http.Request, providerCreds Provide
 		}
 	}
 
-	workspaceLifetime, _ := strconv.Atoi(r.FormValue("workspace_lifetime_hours"))
+	workspaceLifetime := ints.get("workspace_lifetime_hours")
 	if r.FormValue("workspace_lifetime_unit") == "days" {
 		workspaceLifetime *= 24
 	}
 	config.WorkspaceLifetimeHours = workspaceLifetime
+	config.Verbosity = parseVerbosity(r.FormValue("verbosity"))
 
 	if labDeletionDateStr := r.FormValue("lab_deletion_date"); labDeletionDateStr != "" {
 		if d, err := time.Parse("2006-01-02", labDeletionDateStr); err == nil {
@@ -486,9 +596,9 @@ func (h *Handler) createLabConfigFromForm(r *http.Request, providerCreds Provide
 
 	if !useExistingCluster {
 		// Parse integer fields only for new infrastructure
-		desiredNodeCount, _ := strconv.Atoi(r.FormValue("nodepool_desired_node_count"))
-		minNodeCount, _ := strconv.Atoi(r.FormValue("nodepool_min_node_count"))
-		maxNodeCount, _ := strconv.Atoi(r.FormValue("nodepool_max_node_count"))
+		desiredNodeCount := ints.get("nodepool_desired_node_count")
+		minNodeCount := ints.get("nodepool_min_node_count")
+		maxNodeCount := ints.get("nodepool_max_node_count")
 
 		// Get provider from form (default to "ovh" for backward compatibility)
 		provider := r.FormValue("provider")
@@ -505,43 +615,60 @@ func (h *Handler) createLabConfigFromForm(r *http.Request, providerCreds Provide
 		config.NetworkStartIP = r.FormValue("network_start_ip")
 		config.NetworkEndIP = r.FormValue("network_end_ip")
 		config.NetworkID = r.FormValue("network_id")
+		// "" keeps OVH's default, so DHCP is only set when the admin picks a side.
+		if dhcp, err := strconv.ParseBool(r.FormValue("network_dhcp")); err == nil {
+			config.NetworkDHCP = &dhcp
+		}
+		config.NetworkGatewayIP = strings.TrimSpace(r.FormValue("network_gateway_ip"))
+		config.ImportPrivateNetworkID = strings.TrimSpace(r.FormValue("import_private_network_id"))
+		config.ImportSubnetID = strings.TrimSpace(r.FormValue("import_subnet_id"))
+		config.ImportClusterID = strings.TrimSpace(r.FormValue("import_cluster_id"))
 		config.K8sClusterName = r.FormValue("k8s_cluster_name")
+		config.K8sVersion = strings.TrimSpace(r.FormValue("k8s_version"))
+		config.K8sKubeProxyMode = r.FormValue("k8s_kube_proxy_mode")
+		config.K8sIPVSScheduler = r.FormValue("k8s_ipvs_scheduler")
+		config.K8sPrivateNetworkRouting = r.FormValue("k8s_private_network_routing") == "true"
+		config.K8sPlan = r.FormValue("k8s_plan")
 		config.NodePoolName = r.FormValue("nodepool_name")
 		config.NodePoolFlavor = r.FormValue("nodepool_flavor")
 		config.NodePoolDesiredNodeCount = desiredNodeCount
 		config.NodePoolMinNodeCount = minNodeCount
 		config.NodePoolMaxNodeCount = maxNodeCount
+		config.NodePoolAutoscale = r.FormValue("nodepool_autoscale") == "true"
+		config.NodePoolMonthlyBilled = r.FormValue("nodepool_monthly_billed") == "true"
+		config.NodePoolAntiAffinity = r.FormValue("nodepool_anti_affinity") == "true"
+		labels, err := parseNodeLabels(r.FormValue("nodepool_labels"))
+		if err != nil {
+			return nil, err
+		}
+		config.NodePoolLabels = labels
+		config.NodePoolTaints = parseNodeTaints(r.FormValue("nodepool_taints"))
+		config.NodePoolZones = parseAvailabilityZones(r.FormValue("nodepool_zones"))
+		config.NodePoolReadyTimeoutMinutes = ints.get("nodepool_ready_timeout")
 
 		// Copy provider-specific credentials into config
-		switch c := providerCreds.(type) {
-		case *OVHCredentials:
-			config.OvhApplicationKey = c.ApplicationKey
-			config.OvhApplicationSecret = c.ApplicationSecret
-			config.OvhConsumerKey = c.ConsumerKey
-			config.OvhServiceName = c.ServiceName
-			config.OvhEndpoint = c.Endpoint
-		case *AzureCredentials:
-			config.AzureClientID = c.ClientID
-			config.AzureClientSecret = c.ClientSecret
-			config.AzureTenantID = c.TenantID
-			config.AzureSubscriptionID = c.SubscriptionID
+		config.setProviderCredentials(providerCreds)
+		if _, ok := providerCreds.(*AzureCredentials); ok {
 			config.AzureLocation = r.FormValue("azure_location")
 		}
 	}
 
 	// Validate ACME email if provided
 	if config.AcmeEmail != "" && !validateEmail(config.AcmeEmail) {
-		log.Printf("Warning: Invalid email format in AcmeEmail: %s", config.AcmeEmail)
+		slog.WarnContext(r.Context(), "Invalid email format in AcmeEmail", "acme_email", config.AcmeEmail)
 	}
 
 	// Validate Git repository URLs for workspace templates
 	for i, t := range config.WorkspaceTemplates {
 		if t.GitRepo != "" && !validateURL(t.GitRepo) {
-			log.Printf("Warning: Invalid Git repository URL for template %d: %s", i, t.GitRepo)
+			slog.WarnContext(r.Context(), "Invalid Git repository URL", "template_index", i, "git_repo", t.GitRepo)
 		}
 	}
 
-	return config
+	if err := ints.err(); err != nil {
+		return nil, err
+	}
+	return config, nil
 }
 
 // validateDNSConfig rejects a DNS-provider selection that cannot produce a valid
@@ -565,6 +692,257 @@ func validateDNSConfig(cfg *LabConfig) error {
 	return nil
 }
 
+// validateImportConfig rejects import IDs the Pulumi program cannot adopt. OVH
+// addresses a subnet through its private network, so a subnet can only be
+// imported together with that network.
+func validateImportConfig(cfg *LabConfig) error {
+	if cfg.UseExistingCluster || cfg.Provider == "azure" {
+		return nil
+	}
+	if cfg.ImportSubnetID != "" && cfg.ImportPrivateNetworkID == "" {
+		return fmt.Errorf("importing subnet %q requires the ID of its private network", cfg.ImportSubnetID)
+	}
+	return nil
+}
+
+// validateNetworkConfig checks the VLAN ID, CIDR, DHCP range and gateway IP of
+// a lab that creates its own OVH network. The error is an *ovh.SubnetError naming the
+// field to fix.
+func validateNetworkConfig(cfg *LabConfig) error {
+	if cfg.UseExistingCluster || cfg.Provider == "azure" {
+		return nil
+	}
+	if _, err := ovh.ParseVlanID(cfg.NetworkID); err != nil {
+		return err
+	}
+	if err := ovh.ValidateSubnet(cfg.NetworkMask, cfg.NetworkStartIP, cfg.NetworkEndIP); err != nil {
+		return err
+	}
+	if cfg.NetworkGatewayIP != "" && cfg.ImportSubnetID != "" {
+		return &ovh.SubnetError{Field: "network_gateway_ip", Label: "Gateway IP", Msg: "an imported subnet keeps its own gateway IP, leave it empty"}
+	}
+	return ovh.ValidateGatewayIP(cfg.NetworkMask, cfg.NetworkStartIP, cfg.NetworkEndIP, cfg.NetworkGatewayIP)
+}
+
+// ovhAntiAffinityMaxNodes is the largest node pool OVH accepts with anti-affinity:
+// each node must land on a different hypervisor.
+const ovhAntiAffinityMaxNodes = 5
+
+// maxNodeReadyTimeoutMinutes caps how long a deployment waits for its nodes.
+const maxNodeReadyTimeoutMinutes = 120
+
+// splitNodeEntries splits a form field holding node labels or taints, one per
+// line or separated by commas.
+func splitNodeEntries(s string) []string {
+	var out []string
+	for _, e := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// parseNodeLabels parses "key=value" node labels. An entry without "=" is
+// rejected rather than read as a label with an empty value; the keys and values
+// are left for validateNodePoolOptions to check.
+func parseNodeLabels(s string) (map[string]string, error) {
+	entries := splitNodeEntries(s)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(entries))
+	for _, e := range entries {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid node label %q (want key=value)", e)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
+
+// parseNodeTaints parses "key=value:Effect" (or "key:Effect") node taints, the
+// kubectl taint syntax.
+func parseNodeTaints(s string) []utils.NodeTaint {
+	var taints []utils.NodeTaint
+	for _, e := range splitNodeEntries(s) {
+		kv, effect, _ := strings.Cut(e, ":")
+		k, v, _ := strings.Cut(kv, "=")
+		taints = append(taints, utils.NodeTaint{Key: strings.TrimSpace(k), Value: strings.TrimSpace(v), Effect: strings.TrimSpace(effect)})
+	}
+	return taints
+}
+
+// parseAnnotations parses "key=value" annotations, one per line. Values may hold
+// commas, so unlike node labels only newlines separate entries.
+func parseAnnotations(s string) map[string]string {
+	var annotations map[string]string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		k, v, _ := strings.Cut(line, "=")
+		annotations[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return annotations
+}
+
+// maxIngressIPTimeoutMinutes bounds the wait for the ingress LoadBalancer IP.
+const maxIngressIPTimeoutMinutes = 60
+
+// validateLoadBalancerOptions checks the annotation keys and reserved IP of the
+// ingress LoadBalancer service. Kubernetes would reject a bad key only once the
+// Helm release is installed, well into pulumi up.
+func validateLoadBalancerOptions(cfg *LabConfig) error {
+	if len(cfg.LoadBalancerAnnotations) == 0 && cfg.LoadBalancerIP == "" && cfg.IngressIPTimeoutMinutes == 0 && !cfg.NodePortFallback {
+		return nil
+	}
+	if cfg.InstallNginxIngress != nil && !*cfg.InstallNginxIngress {
+		return fmt.Errorf("load balancer options need the lab to install ingress-nginx")
+	}
+	if cfg.IngressIPTimeoutMinutes < 0 || cfg.IngressIPTimeoutMinutes > maxIngressIPTimeoutMinutes {
+		return fmt.Errorf("ingress IP timeout must be between 1 and %d minutes", maxIngressIPTimeoutMinutes)
+	}
+	// A DNS record cannot carry a port, so only nip.io labs can fall back.
+	if cfg.NodePortFallback && cfg.Domain != "" {
+		return fmt.Errorf("the NodePort fallback is only available for labs without a domain")
+	}
+	if cfg.LoadBalancerIP != "" && net.ParseIP(cfg.LoadBalancerIP) == nil {
+		return fmt.Errorf("invalid load balancer IP %q", cfg.LoadBalancerIP)
+	}
+	for k := range cfg.LoadBalancerAnnotations {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid load balancer annotation key %q: %s", k, strings.Join(errs, "; "))
+		}
+		if k == coder.SkipAwaitAnnotation {
+			return fmt.Errorf("load balancer annotation %q is managed by EasyLab", k)
+		}
+	}
+	return nil
+}
+
+// validateStorageClass checks the name of the workspaces' storage class. Whether
+// the cluster has it is only known at deployment.
+func validateStorageClass(name string) error {
+	if name == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid storage class %q: %s", name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validateNodePoolOptions rejects node pool option combinations OVH refuses, so
+// they fail at creation time rather than in the middle of pulumi up.
+func validateNodePoolOptions(cfg *LabConfig) error {
+	if cfg.UseExistingCluster {
+		return nil
+	}
+	if cfg.Provider == "azure" {
+		if len(cfg.NodePoolLabels) > 0 || len(cfg.NodePoolTaints) > 0 {
+			return fmt.Errorf("node labels and taints are only supported on OVHcloud")
+		}
+		return nil
+	}
+	for k, v := range cfg.NodePoolLabels {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid node label key %q: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("invalid value for node label %q: %s", k, strings.Join(errs, "; "))
+		}
+	}
+	for _, t := range cfg.NodePoolTaints {
+		if errs := validation.IsQualifiedName(t.Key); len(errs) > 0 {
+			return fmt.Errorf("invalid node taint key %q: %s", t.Key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(t.Value); len(errs) > 0 {
+			return fmt.Errorf("invalid value for node taint %q: %s", t.Key, strings.Join(errs, "; "))
+		}
+		switch t.Effect {
+		case "NoSchedule", "PreferNoSchedule", "NoExecute":
+		default:
+			return fmt.Errorf("invalid effect %q for node taint %q (want NoSchedule, PreferNoSchedule or NoExecute)", t.Effect, t.Key)
+		}
+	}
+	if cfg.NodePoolAntiAffinity {
+		if cfg.NodePoolMaxNodeCount > ovhAntiAffinityMaxNodes {
+			return fmt.Errorf("anti-affinity node pools are limited to %d nodes by OVH (max nodes is %d)", ovhAntiAffinityMaxNodes, cfg.NodePoolMaxNodeCount)
+		}
+		if cfg.NodePoolDesiredNodeCount > ovhAntiAffinityMaxNodes {
+			return fmt.Errorf("anti-affinity node pools are limited to %d nodes by OVH (desired nodes is %d)", ovhAntiAffinityMaxNodes, cfg.NodePoolDesiredNodeCount)
+		}
+	}
+	if cfg.NodePoolAutoscale && cfg.NodePoolMinNodeCount >= cfg.NodePoolMaxNodeCount {
+		return fmt.Errorf("autoscaling needs max nodes (%d) greater than min nodes (%d)", cfg.NodePoolMaxNodeCount, cfg.NodePoolMinNodeCount)
+	}
+	if cfg.NodePoolReadyTimeoutMinutes < 0 || cfg.NodePoolReadyTimeoutMinutes > maxNodeReadyTimeoutMinutes {
+		return fmt.Errorf("node ready timeout must be between 1 and %d minutes (0 for the default)", maxNodeReadyTimeoutMinutes)
+	}
+	return nil
+}
+
+// kubeVersionPattern matches the "major.minor" versions OVH accepts, e.g. "1.31".
+var kubeVersionPattern = regexp.MustCompile(`^\d+\.\d+$`)
+
+// validateK8sVersion checks the requested Kubernetes version against the ones
+// OVH supports in the lab's region. supported may be nil when the list could
+// not be fetched; only the format is checked then, and OVH has the last word.
+func validateK8sVersion(cfg *LabConfig, supported []string) error {
+	if cfg.K8sVersion == "" || cfg.UseExistingCluster {
+		return nil
+	}
+	if cfg.Provider == "azure" {
+		return fmt.Errorf("choosing the Kubernetes version is only supported on OVHcloud")
+	}
+	if !kubeVersionPattern.MatchString(cfg.K8sVersion) {
+		return fmt.Errorf("invalid Kubernetes version %q (want major.minor, e.g. 1.31)", cfg.K8sVersion)
+	}
+	if len(supported) > 0 && !slices.Contains(supported, cfg.K8sVersion) {
+		return fmt.Errorf("Kubernetes %s is not available in region %s (available: %s)",
+			cfg.K8sVersion, cfg.NetworkRegion, strings.Join(supported, ", "))
+	}
+	return nil
+}
+
+// validateOVHFlavor checks that the node pool flavor is offered in the lab's
+// region. GPU flavors exist only in some regions, so the error lists the ones
+// that do. flavors may be nil when the list could not be fetched.
+func validateOVHFlavor(cfg *LabConfig, flavors []ovhFlavor) error {
+	if len(flavors) == 0 {
+		return nil
+	}
+	if _, ok := findOVHFlavor(flavors, cfg.NodePoolFlavor); ok {
+		return nil
+	}
+	var gpuFlavors []string
+	for _, f := range flavors {
+		if f.GPUs > 0 {
+			gpuFlavors = append(gpuFlavors, f.Name)
+		}
+	}
+	if len(gpuFlavors) == 0 {
+		return fmt.Errorf("flavor %q is not available in region %s (no GPU flavors are offered there)", cfg.NodePoolFlavor, cfg.NetworkRegion)
+	}
+	return fmt.Errorf("flavor %q is not available in region %s (GPU flavors there: %s)",
+		cfg.NodePoolFlavor, cfg.NetworkRegion, strings.Join(gpuFlavors, ", "))
+}
+
+func findOVHFlavor(flavors []ovhFlavor, name string) (ovhFlavor, bool) {
+	for _, f := range flavors {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return ovhFlavor{}, false
+}
+
 // labConfigWarnings reports configurations that deploy successfully but do not
 // work, so the admin hears about them at creation time rather than from a student.
 // These are warnings, not errors: managing DNS by hand outside EasyLab is a
@@ -603,45 +981,50 @@ func (h *Handler) executeLabJob(config *LabConfig, isDryRun bool) (string, strin
 // executeLabJobWithID starts execution for an existing job, returning the job ID and HTML response
 func (h *Handler) executeLabJobWithID(config *LabConfig, isDryRun bool, jobID string) (string, string) {
 	if isDryRun {
-		log.Printf("Dry run job started: %s", jobID)
+		slog.Info("Dry run job started", "job_id", jobID)
 	} else {
-		log.Printf("Job started: %s", jobID)
+		slog.Info("Job started", "job_id", jobID)
 	}
 
 	// Start execution in a goroutine
 	go func() {
 		if isDryRun {
-			log.Printf("Starting Pulumi preview for job: %s", jobID)
-			if err := h.pulumiExec.Preview(jobID); err != nil {
-				log.Printf("Pulumi preview failed for job %s: %v", jobID, err)
+			slog.Info("Starting Pulumi preview for job", "job_id", jobID)
+			if err := h.pulumiExec.Preview(jobID); errors.Is(err, errPreviewCancelled) {
+				slog.Info("Pulumi preview cancelled for job", "job_id", jobID)
+				return
+			} else if err != nil {
+				slog.Error("Pulumi preview failed for job", "job_id", jobID, "error", err)
 				return
 			}
-			log.Printf("Pulumi preview completed for job: %s", jobID)
+			slog.Info("Pulumi preview completed for job", "job_id", jobID)
 		} else {
-			log.Printf("Starting Pulumi execution for job: %s", jobID)
+			slog.Info("Starting Pulumi execution for job", "job_id", jobID)
 			if err := h.pulumiExec.Execute(jobID); err != nil {
-				log.Printf("Pulumi execution failed for job %s: %v", jobID, err)
+				slog.Error("Pulumi execution failed for job", "job_id", jobID, "error", err)
 				return
 			}
-			log.Printf("Pulumi execution completed for job: %s", jobID)
+			slog.Info("Pulumi execution completed for job", "job_id", jobID)
 		}
 	}()
 
-	// Prepare response
+	return jobID, jobCreatedHTML(jobID, isDryRun)
+}
+
+// jobCreatedHTML is the fragment that follows a new job's status.
+func jobCreatedHTML(jobID string, isDryRun bool) string {
 	title := fmt.Sprintf("Job Created: %s", jobID)
 	if isDryRun {
 		title = fmt.Sprintf("Dry Run Started: %s", jobID)
 	}
 
-	html := fmt.Sprintf(`
+	return fmt.Sprintf(`
 		<div class="job-created">
 			<h3>%s</h3>
 			<div id="job-status" hx-get="/api/jobs/%s/status" hx-trigger="load, every 10s" hx-swap="innerHTML">
 				<p>Loading status...</p>
 			</div>
 		</div>`, title, jobID)
-
-	return jobID, html
 }
 
 // serveTemplate serves a template with optional data and no-cache headers
@@ -649,7 +1032,7 @@ func (h *Handler) serveTemplate(w http.ResponseWriter, templateName string, data
 	// Use cached template
 	tmpl, err := h.getTemplate(templateName)
 	if err != nil {
-		log.Printf("Failed to load template %s: %v", templateName, err)
+		slog.Error("Failed to load template", "template_name", templateName, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return err
 	}
@@ -662,7 +1045,7 @@ func (h *Handler) serveTemplate(w http.ResponseWriter, templateName string, data
 
 	// Execute the base template which includes all page-specific blocks
 	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		log.Printf("Failed to execute template %s: %v", templateName, err)
+		slog.Error("Failed to execute template", "template_name", templateName, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return err
 	}
@@ -670,70 +1053,69 @@ func (h *Handler) serveTemplate(w http.ResponseWriter, templateName string, data
 	return nil
 }
 
+// writeHTMLFragment writes an HTML fragment for HTMX to swap in. The
+// Content-Type, with its charset, is set before the status and body are
+// written: headers set after the first write are silently dropped.
+func writeHTMLFragment(w http.ResponseWriter, status int, html string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, html)
+}
+
 // renderHTMLError renders a standardized HTML error message
 func (h *Handler) renderHTMLError(w http.ResponseWriter, title, message string, optionalLink ...string) {
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprintf(w, `<div class="error-message">`)
-	fmt.Fprintf(w, `<h3>%s</h3>`, template.HTMLEscapeString(title))
-	fmt.Fprintf(w, `<p>%s</p>`, template.HTMLEscapeString(message))
+	h.renderHTMLErrorStatus(w, http.StatusOK, title, message, optionalLink...)
+}
+
+// renderHTMLErrorStatus is renderHTMLError with an HTTP status other than 200.
+func (h *Handler) renderHTMLErrorStatus(w http.ResponseWriter, status int, title, message string, optionalLink ...string) {
+	link := ""
 	if len(optionalLink) > 0 {
-		fmt.Fprint(w, optionalLink[0])
+		link = optionalLink[0]
 	}
-	fmt.Fprintf(w, `</div>`)
+	writeHTMLFragment(w, status, fmt.Sprintf(`<div class="error-message"><h3>%s</h3><p>%s</p>%s</div>`,
+		template.HTMLEscapeString(title), template.HTMLEscapeString(message), link))
+}
+
+// requestError is a request refused by the logic the HTMX handlers and the
+// JSON API share. The HTMX handlers render Title and Message, followed by the
+// optional Actions (HTML); the JSON API answers with Status.
+type requestError struct {
+	Status  int
+	Title   string
+	Message string
+	Actions string
+	// Plain errors are answered with Status by the HTMX handlers too, rather
+	// than rendered for the page to show.
+	Plain bool
+}
+
+func (e *requestError) Error() string {
+	return e.Title + ": " + e.Message
+}
+
+// badRequest is a requestError for an invalid request.
+func badRequest(title string, err error) *requestError {
+	return &requestError{Status: http.StatusBadRequest, Title: title, Message: err.Error()}
 }
 
-// getTemplate retrieves a cached template by filename, loading it lazily if needed
+// renderRequestError renders a requestError as an HTMX error fragment.
+func (h *Handler) renderRequestError(w http.ResponseWriter, err *requestError) {
+	if err.Plain {
+		http.Error(w, err.Message, err.Status)
+		return
+	}
+	h.renderHTMLError(w, err.Title, err.Message, err.Actions)
+}
+
+// getTemplate returns the template of a page (see handlerPages).
 func (h *Handler) getTemplate(filename string) (*template.Template, error) {
-	// Fast path: check cache first
 	h.templatesMu.RLock()
+	defer h.templatesMu.RUnlock()
 	tmpl, ok := h.templates[filename]
-	h.templatesMu.RUnlock()
-	if ok {
-		return tmpl, nil
-	}
-
-	// Slow path: load template
-	h.templatesMu.Lock()
-	defer h.templatesMu.Unlock()
-
-	// Double-check after acquiring write lock
-	if tmpl, ok := h.templates[filename]; ok {
-		return tmpl, nil
-	}
-
-	// Map filename to full path
-	templatePaths := map[string]string{
-		"index.html":              "web/index.html",
-		"admin.html":              "web/admin.html",
-		"student-dashboard.html":  "web/student-dashboard.html",
-		"student-workspaces.html": "web/student-workspaces.html",
-		"student-feedback.html":   "web/student-feedback.html",
-		"admin-feedback.html":     "web/admin-feedback.html",
-		"credentials.html":        "web/credentials.html",
-		"ovh-credentials.html":    "web/ovh-credentials.html", // Keep for backward compatibility
-		"ovh-options.html":        "web/ovh-options.html",
-		"azure-options.html":      "web/azure-options.html",
-		"azure-provider.html":     "web/azure-provider.html",
-		"azure-ad.html":           "web/azure-ad.html",
-		"labs-list.html":          "web/labs-list.html",
-		"lab-workspaces.html":     "web/lab-workspaces.html",
-		"admin-stats.html":        "web/admin-stats.html",
-	}
-
-	tmplPath, ok := templatePaths[filename]
 	if !ok {
 		return nil, fmt.Errorf("template %s not found", filename)
 	}
-
-	var err error
-	// Parse base template and page template together
-	tmpl, err = template.ParseFiles("web/base.html", tmplPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load template %s: %w", tmplPath, err)
-	}
-
-	// Execute the base template which will include the page-specific blocks
-	h.templates[filename] = tmpl
 	return tmpl, nil
 }
 
@@ -775,7 +1157,7 @@ func (h *Handler) ServeAdminUI(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) processLabRequest(w http.ResponseWriter, r *http.Request, isDryRun bool) {
 	// Parse form data - handle both multipart and urlencoded (50MB for template files)
 	if err := h.parseForm(w, r, 50<<20); err != nil {
-		log.Printf("Failed to parse form: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse form", "error", err)
 		h.renderHTMLError(w, "Form Parse Error", "Failed to parse form data, please try again.")
 		return
 	}
@@ -786,7 +1168,7 @@ func (h *Handler) processLabRequest(w http.ResponseWriter, r *http.Request, isDr
 	// fail here, before any job or directory exists.
 	templates, err := workspaceTemplatesFromRequest(r)
 	if err != nil {
-		log.Printf("Invalid workspace templates YAML: %v", err)
+		slog.WarnContext(r.Context(), "Invalid workspace templates YAML", "error", err)
 		h.renderHTMLError(w, "Invalid Workspace Templates YAML", err.Error())
 		return
 	}
@@ -794,7 +1176,7 @@ func (h *Handler) processLabRequest(w http.ResponseWriter, r *http.Request, isDr
 	// straight to Create does not, so leave a trail here. The message names the key
 	// and template, never the URL, which is what carries the token.
 	for _, warn := range gitCredentialWarnings(templates) {
-		log.Printf("Workspace template warning (%s): %s", warn.Key, warn.Message)
+		slog.WarnContext(r.Context(), "Workspace template warning", "key", warn.Key, "warning", warn.Message)
 	}
 
 	// Credentials the admin entered in the wizard. Parsed here so a mistake fails
@@ -803,7 +1185,7 @@ func (h *Handler) processLabRequest(w http.ResponseWriter, r *http.Request, isDr
 	// keeps none.
 	wizardSecrets, err := parseWizardSecrets(r)
 	if err != nil {
-		log.Printf("Invalid wizard credentials: %v", err)
+		slog.WarnContext(r.Context(), "Invalid wizard credentials", "error", err)
 		h.renderHTMLError(w, "Invalid Credentials", err.Error())
 		return
 	}
@@ -834,62 +1216,281 @@ func (h *Handler) processLabRequest(w http.ResponseWriter, r *http.Request, isDr
 	// Create the lab config from the form. Workspace templates are pure
 	// configuration (image + optional git repo + resources), so there are no
 	// files to upload.
-	initialConfig := h.createLabConfigFromForm(r, providerCreds)
+	initialConfig, err := h.createLabConfigFromForm(r, providerCreds)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Invalid lab form", "error", err)
+		h.renderHTMLErrorStatus(w, http.StatusBadRequest, "Invalid Form Field", err.Error())
+		return
+	}
 	initialConfig.WorkspaceTemplates = templates
 
+	// Handle kubeconfig for BYOK mode
+	if useExistingCluster {
+		kubeconfigContent, err := h.readKubeconfigFromForm(r)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to read kubeconfig", "error", err)
+			h.renderHTMLError(w, "Kubeconfig Error", "Failed to read kubeconfig, please check the file and try again.")
+			return
+		}
+		initialConfig.ExternalKubeconfig = kubeconfigContent
+	}
+
+	started, reqErr := h.startLab(labRequest{
+		Config:  initialConfig,
+		DryRun:  isDryRun,
+		Force:   r.FormValue("force") == "true",
+		Secrets: wizardSecrets,
+	})
+	if reqErr != nil {
+		h.renderRequestError(w, reqErr)
+		return
+	}
+
+	// Return job status div for HTMX to display with proper polling, preceded by
+	// any warning about a configuration that deploys but will not work.
+	warnings := renderConfigWarnings(started.Warnings)
+	if isDryRun {
+		warnings += h.vlanConflictWarning(initialConfig)
+	}
+	writeHTMLFragment(w, http.StatusOK, warnings+jobCreatedHTML(started.JobID, isDryRun))
+}
+
+// labRequest is a lab to create, as the creation wizard or the JSON API
+// submits it.
+type labRequest struct {
+	Config *LabConfig
+	DryRun bool
+	// Force creates the lab even when an active job has the same configuration.
+	Force bool
+	// Secrets are the credentials entered in the wizard, applied once the
+	// cluster is up.
+	Secrets []pendingSecret
+}
+
+// startedLab is the job startLab created for a lab.
+type startedLab struct {
+	JobID string
+	// Warnings are about a configuration that deploys but will not work.
+	Warnings []string
+}
+
+// startLab checks a lab request, creates its job and starts the Pulumi run (or
+// preview, for a dry run). It is shared by the creation wizard and the JSON
+// API, which differ only in how they read the config and render the outcome.
+func (h *Handler) startLab(req labRequest) (startedLab, *requestError) {
+	cfg := req.Config
+
 	// A DNS-provider selection with no (or a mismatched) zone can only fail deep
 	// inside pulumi up, after minutes of provisioning. Reject it here, before any
 	// job or job directory exists.
-	if err := validateDNSConfig(initialConfig); err != nil {
-		log.Printf("Invalid DNS configuration: %v", err)
-		h.renderHTMLError(w, "DNS Configuration Error", err.Error())
-		return
+	if err := validateDNSConfig(cfg); err != nil {
+		slog.Warn("Invalid DNS configuration", "error", err)
+		return startedLab{}, badRequest("DNS Configuration Error", err)
+	}
+	if err := validateImportConfig(cfg); err != nil {
+		slog.Warn("Invalid import configuration", "error", err)
+		return startedLab{}, badRequest("Import Configuration Error", err)
+	}
+	if err := validateNetworkConfig(cfg); err != nil {
+		slog.Warn("Invalid network configuration", "error", err)
+		title := "Network Configuration Error"
+		var subnetErr *ovh.SubnetError
+		if errors.As(err, &subnetErr) {
+			title = "Invalid " + subnetErr.Label
+		}
+		return startedLab{}, badRequest(title, err)
+	}
+
+	if err := validateNodePoolOptions(cfg); err != nil {
+		slog.Warn("Invalid node pool options", "error", err)
+		return startedLab{}, badRequest("Node Pool Configuration Error", err)
+	}
+	if err := validateLabNodeCounts(cfg); err != nil {
+		slog.Warn("Node counts outside the server limits", "error", err)
+		return startedLab{}, badRequest("Node Pool Configuration Error", err)
+	}
+	if err := validateClusterOptions(cfg); err != nil {
+		slog.Warn("Invalid cluster options", "error", err)
+		return startedLab{}, badRequest("Cluster Configuration Error", err)
+	}
+	if err := validateVerbosity(cfg.Verbosity); err != nil {
+		slog.Warn("Invalid verbosity", "error", err)
+		return startedLab{}, badRequest("Output Verbosity Error", err)
+	}
+	if err := validateWebhookURLs(cfg.WebhookURLs); err != nil {
+		slog.Warn("Invalid webhook URLs", "error", err)
+		return startedLab{}, badRequest("Webhook Error", err)
+	}
+	if err := validatePostDeployManifests(cfg.PostDeployManifests); err != nil {
+		slog.Warn("Invalid post-deploy manifests", "error", err)
+		return startedLab{}, badRequest("Post-Deploy Manifests Error", err)
+	}
+	if err := validateHelmRepoOverrides(cfg.HelmRepoOverrides); err != nil {
+		slog.Warn("Invalid Helm repositories", "error", err)
+		return startedLab{}, badRequest("Helm Repository Error", err)
+	}
+	if err := validateHelmChartVersions(cfg.HelmChartVersions); err != nil {
+		slog.Warn("Invalid Helm chart versions", "error", err)
+		return startedLab{}, badRequest("Helm Chart Version Error", err)
+	}
+	if err := h.checkHelmChartVersions(cfg); err != nil {
+		slog.Warn("Unknown Helm chart version", "error", err)
+		return startedLab{}, badRequest("Helm Chart Version Error", err)
+	}
+	if err := validateLoadBalancerOptions(cfg); err != nil {
+		slog.Warn("Invalid load balancer options", "error", err)
+		return startedLab{}, badRequest("Ingress Configuration Error", err)
+	}
+
+	// The region, version and flavor are checked against what OVH offers. When
+	// a list cannot be fetched its check is skipped and OVH has the last word
+	// during pulumi up.
+	createsOVHCluster := !cfg.UseExistingCluster && cfg.Provider != "azure"
+	var regionNote string
+	if createsOVHCluster {
+		if regions, err := h.labRegions(); err != nil {
+			slog.Warn("Could not list regions, skipping the region check", "error", err)
+		} else if len(regions) > 0 {
+			region, autoSelected, err := resolveRegion(cfg.NetworkRegion, regions)
+			if err != nil {
+				slog.Warn("Invalid region", "error", err)
+				return startedLab{}, badRequest("Invalid Region", err)
+			}
+			if autoSelected {
+				regionNote = fmt.Sprintf("Region %s selected: it is the only region enabled on the project", region)
+			}
+			cfg.NetworkRegion = region
+		}
+	}
+	var supportedVersions []string
+	if cfg.K8sVersion != "" && createsOVHCluster {
+		var err error
+		if supportedVersions, err = h.supportedKubeVersions(cfg.NetworkRegion); err != nil {
+			slog.Warn("Could not list Kubernetes versions for region, checking the format only", "network_region", cfg.NetworkRegion, "error", err)
+		}
+	}
+	if err := validateK8sVersion(cfg, supportedVersions); err != nil {
+		slog.Warn("Invalid Kubernetes version", "error", err)
+		return startedLab{}, badRequest("Kubernetes Version Error", err)
+	}
+	if cfg.NodePoolFlavor != "" && createsOVHCluster {
+		flavors, err := h.regionFlavors(cfg.NetworkRegion)
+		if err != nil {
+			slog.Warn("Could not list flavors for region, skipping the flavor check", "network_region", cfg.NetworkRegion, "error", err)
+		}
+		if err := validateOVHFlavor(cfg, flavors); err != nil {
+			slog.Warn("Invalid node pool flavor", "error", err)
+			return startedLab{}, badRequest("Node Pool Configuration Error", err)
+		}
+		// A GPU flavor needs the NVIDIA device plugin before pods can use its GPUs.
+		if f, ok := findOVHFlavor(flavors, cfg.NodePoolFlavor); ok {
+			cfg.NodePoolGPU = f.GPUs > 0
+			sizeWorkspaceQuota(cfg.WorkspaceQuota, f, cfg.NodePoolDesiredNodeCount)
+		}
+	}
+	if err := resolveNodePoolZones(cfg, h.regionAvailabilityZones); err != nil {
+		slog.Warn("Invalid availability zones", "error", err)
+		return startedLab{}, badRequest("Node Pool Configuration Error", err)
+	}
+	var quotaReport *QuotaReport
+	if req.DryRun {
+		if err := h.checkGatewayModel(cfg); err != nil {
+			slog.Warn("Invalid gateway model", "error", err)
+			return startedLab{}, badRequest("Invalid Gateway model", err)
+		}
+		quotaReport = h.checkQuota(cfg)
+	}
+	if err := validateWorkspaceQuota(cfg.WorkspaceQuota); err != nil {
+		slog.Warn("Invalid workspace quota", "error", err)
+		return startedLab{}, badRequest("Workspace Quota Error", err)
+	}
+	if err := validateEvent(cfg.Event); err != nil {
+		slog.Warn("Invalid event", "error", err)
+		return startedLab{}, badRequest("Event Error", err)
+	}
+	if err := validateMaxWorkspaces(cfg.MaxWorkspaces); err != nil {
+		slog.Warn("Invalid workspace capacity", "error", err)
+		return startedLab{}, badRequest("Workspace Capacity Error", err)
+	}
+	if err := validateStudentAccessCode(cfg.StudentAccessCode); err != nil {
+		slog.Warn("Invalid student access code")
+		return startedLab{}, badRequest("Student Access Code Error", err)
+	}
+	if err := validateStorageClass(cfg.StorageClass); err != nil {
+		slog.Warn("Invalid storage class", "error", err)
+		return startedLab{}, badRequest("Storage Class Error", err)
+	}
+
+	// The same lab submitted twice (a double click, a second tab) would fight
+	// over one Pulumi stack. Stop and name the live job; the admin can still
+	// go ahead with force. A dry run deploys nothing, so it is never blocked.
+	if !req.DryRun && !req.Force {
+		if existing, ok := h.jobManager.FindActiveDuplicate(cfg); ok {
+			slog.Info("Lab request matches an active job, asking for confirmation", "existing_job_id", existing.ID)
+			return startedLab{}, &requestError{
+				Status:  http.StatusConflict,
+				Title:   "Duplicate Lab",
+				Message: fmt.Sprintf("Job %s is already deploying or running a lab with this configuration.", existing.ID),
+				Actions: duplicateLabActions,
+			}
+		}
+	}
+
+	// Generated once per lab, so a retry or an update keeps the same password.
+	if cfg.InstallMonitoring {
+		password, err := GenerateWorkspaceToken()
+		if err != nil {
+			slog.Error("Failed to generate the Grafana password", "error", err)
+			return startedLab{}, &requestError{Status: http.StatusInternalServerError, Title: "Monitoring Error", Message: "Failed to generate the Grafana password, please try again."}
+		}
+		cfg.GrafanaAdminPassword = password
+	}
+	if reqErr := h.setStudentAccessCode(cfg); reqErr != nil {
+		return startedLab{}, reqErr
+	}
+
+	if cfg.UseExistingCluster && cfg.ExternalKubeconfig == "" {
+		return startedLab{}, &requestError{Status: http.StatusBadRequest, Title: "Kubeconfig Required", Message: "Please provide a kubeconfig file or paste its content"}
+	}
+
+	// Templates already pinned (a retry, a re-creation, a lab from a template
+	// job) keep their commit; see template_pins.go.
+	var pinNotes []string
+	if cfg.PinTemplateVersions {
+		pinNotes = h.pinTemplateVersions(cfg.WorkspaceTemplates, wizardGitAuth(req.Secrets), false)
 	}
 
 	// Create job and job directory
-	jobID := h.jobManager.CreateJob(initialConfig)
+	jobID := h.jobManager.CreateJob(cfg)
+	if regionNote != "" {
+		h.jobManager.AppendOutput(jobID, regionNote)
+	}
+	for _, note := range pinNotes {
+		h.jobManager.AppendOutput(jobID, note)
+	}
+	if quotaReport != nil {
+		h.jobManager.SetQuotaReport(jobID, quotaReport)
+	}
 	// A dry run provisions no cluster, so its credentials would never be applied
 	// and would only sit in memory. Keep them only for a real run.
-	if !isDryRun {
-		h.pendingSecrets.Put(jobID, wizardSecrets)
+	if !req.DryRun {
+		h.pendingSecrets.Put(jobID, req.Secrets)
 	}
 	jobDir := filepath.Join(h.pulumiExec.GetWorkDir(), jobID)
 	if err := os.MkdirAll(jobDir, 0755); err != nil {
-		log.Printf("Failed to create job directory: %v", err)
-		h.renderHTMLError(w, "Job Creation Error", "Failed to initialize job, please try again.")
-		return
+		slog.Error("Failed to create job directory", "error", err)
+		return startedLab{}, &requestError{Status: http.StatusInternalServerError, Title: "Job Creation Error", Message: "Failed to initialize job, please try again."}
 	}
-
-	// Handle kubeconfig for BYOK mode
-	if useExistingCluster {
-		kubeconfigContent, err := h.readKubeconfigFromForm(r)
-		if err != nil {
-			log.Printf("Failed to read kubeconfig: %v", err)
-			h.renderHTMLError(w, "Kubeconfig Error", "Failed to read kubeconfig, please check the file and try again.")
-			return
-		}
-		if kubeconfigContent == "" {
-			h.renderHTMLError(w, "Kubeconfig Required", "Please provide a kubeconfig file or paste its content")
-			return
-		}
+	if cfg.UseExistingCluster {
 		kubeconfigPath := filepath.Join(jobDir, "external-kubeconfig.yaml")
-		if err := os.WriteFile(kubeconfigPath, []byte(kubeconfigContent), 0600); err != nil {
-			log.Printf("Failed to write kubeconfig: %v", err)
-			h.renderHTMLError(w, "Kubeconfig Error", "Failed to save kubeconfig, please try again.")
-			return
+		if err := os.WriteFile(kubeconfigPath, []byte(cfg.ExternalKubeconfig), 0600); err != nil {
+			slog.Error("Failed to write kubeconfig", "error", err)
+			return startedLab{}, &requestError{Status: http.StatusInternalServerError, Title: "Kubeconfig Error", Message: "Failed to save kubeconfig, please try again."}
 		}
-		h.updateJobConfig(jobID, func(config *LabConfig) {
-			config.ExternalKubeconfig = kubeconfigContent
-		})
 	}
 
-	_, html := h.executeLabJobWithID(initialConfig, isDryRun, jobID)
-
-	// Return job status div for HTMX to display with proper polling, preceded by
-	// any warning about a configuration that deploys but will not work.
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprint(w, renderConfigWarnings(labConfigWarnings(initialConfig)))
-	fmt.Fprint(w, html)
+	h.executeLabJobWithID(cfg, req.DryRun, jobID)
+	return startedLab{JobID: jobID, Warnings: labConfigWarnings(cfg)}, nil
 }
 
 // renderConfigWarnings renders lab configuration warnings as an HTML fragment,
@@ -940,43 +1541,52 @@ func (h *Handler) updateJobConfig(jobID string, updater func(*LabConfig)) {
 
 // CreateLab handles lab creation requests
 func (h *Handler) CreateLab(w http.ResponseWriter, r *http.Request) {
-	log.Printf("CreateLab called: method=%s, path=%s", r.Method, r.URL.Path)
+	slog.DebugContext(r.Context(), "CreateLab called", "method", r.Method, "path", r.URL.Path)
 
 	if r.Method != http.MethodPost {
-		log.Printf("Method not allowed: %s", r.Method)
+		slog.WarnContext(r.Context(), "Method not allowed", "method", r.Method)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if h.rejectDuringMaintenance(w) {
+		return
+	}
 
 	h.processLabRequest(w, r, false)
 }
 
 // DryRunLab handles dry run requests
 func (h *Handler) DryRunLab(w http.ResponseWriter, r *http.Request) {
-	log.Printf("DryRunLab called: method=%s, path=%s", r.Method, r.URL.Path)
+	slog.DebugContext(r.Context(), "DryRunLab called", "method", r.Method, "path", r.URL.Path)
 
 	if r.Method != http.MethodPost {
-		log.Printf("Method not allowed: %s", r.Method)
+		slog.WarnContext(r.Context(), "Method not allowed", "method", r.Method)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if h.rejectDuringMaintenance(w) {
+		return
+	}
 
 	h.processLabRequest(w, r, true)
 }
 
 // LaunchLab handles launching a real deployment after a successful dry run
 func (h *Handler) LaunchLab(w http.ResponseWriter, r *http.Request) {
-	log.Printf("LaunchLab called: method=%s, path=%s", r.Method, r.URL.Path)
+	slog.DebugContext(r.Context(), "LaunchLab called", "method", r.Method, "path", r.URL.Path)
 
 	if r.Method != http.MethodPost {
-		log.Printf("Method not allowed: %s", r.Method)
+		slog.WarnContext(r.Context(), "Method not allowed", "method", r.Method)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if h.rejectDuringMaintenance(w) {
+		return
+	}
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		log.Printf("Failed to parse form: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse form", "error", err)
 		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
 		return
 	}
@@ -987,27 +1597,70 @@ func (h *Handler) LaunchLab(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the job
+	quotaReport, reqErr := h.launchLab(jobID, r.FormValue("override_quota") == "true", r.RemoteAddr)
+	switch {
+	case quotaReport != nil:
+		// The status div comes back so the admin can tick "Launch anyway".
+		writeHTMLFragment(w, http.StatusOK, quotaReportHTML(quotaReport)+fmt.Sprintf(`
+			<div id="job-status" hx-get="/api/jobs/%s/status" hx-trigger="load, every 10s" hx-swap="innerHTML">
+				<p>Loading status...</p>
+			</div>`, jobID))
+		return
+	case reqErr != nil:
+		h.renderRequestError(w, reqErr)
+		return
+	}
+
+	// Return job status div for HTMX to display with proper polling
+	writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
+		<div class="job-created">
+			<h3>Deployment Launched: %s</h3>
+			<div id="job-status" hx-get="/api/jobs/%s/status" hx-trigger="load, every 10s" hx-swap="innerHTML">
+				<p>Loading status...</p>
+			</div>
+		</div>`, jobID, jobID))
+}
+
+// launchLab starts the real deployment of a lab whose dry run completed. A lab
+// the dry run found short of quota is only launched with overrideQuota: without
+// it, the failed quota report is returned along with the error.
+func (h *Handler) launchLab(jobID string, overrideQuota bool, remoteAddr string) (*QuotaReport, *requestError) {
 	job, exists := h.jobManager.GetJob(jobID)
 	if !exists {
-		http.Error(w, "Job not found", http.StatusNotFound)
-		return
+		return nil, &requestError{Status: http.StatusNotFound, Title: "Job Not Found", Message: "Job not found", Plain: true}
 	}
 
 	// Check if job is in dry-run-completed status
 	job.mu.RLock()
 	status := job.Status
+	quotaReport := job.QuotaReport
+	isTemplate := job.IsTemplate
 	job.mu.RUnlock()
 
+	if isTemplate {
+		return nil, &requestError{Status: http.StatusConflict, Title: "Template Job", Message: templateJobError(jobID).Error()}
+	}
+
 	if status != JobStatusDryRunCompleted {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
-			<div class="error-message">
-				<h3>Invalid Job Status</h3>
-				<p>This job is not in dry-run-completed status. Current status: %s</p>
-				<p>Only jobs that have completed a successful dry run can be launched.</p>
-			</div>`, status)
-		return
+		return nil, &requestError{
+			Status:  http.StatusConflict,
+			Title:   "Invalid Job Status",
+			Message: fmt.Sprintf("This job is not in dry-run-completed status (current status: %s). Only jobs that have completed a successful dry run can be launched.", status),
+		}
+	}
+
+	// A lab the dry run found short of quota would fail half way through
+	// pulumi up; the admin may still launch it, e.g. once the quota is raised.
+	if !quotaReport.Fits() {
+		if !overrideQuota {
+			return quotaReport, &requestError{
+				Status:  http.StatusConflict,
+				Title:   "Quota Check Failed",
+				Message: "The dry run found the project short of quota for this lab. Launch it with override_quota to go ahead anyway.",
+			}
+		}
+		slog.Info("Lab launched over its quota check", "component", "audit", "job_id", jobID, "remote_addr", remoteAddr)
+		h.jobManager.AppendOutput(jobID, "Launched despite the failed quota check")
 	}
 
 	// Reset job status to pending and start execution
@@ -1016,23 +1669,14 @@ func (h *Handler) LaunchLab(w http.ResponseWriter, r *http.Request) {
 
 	// Start Pulumi execution in a goroutine
 	go func() {
-		log.Printf("Starting Pulumi execution for job: %s", jobID)
+		slog.Info("Starting Pulumi execution for job", "job_id", jobID)
 		if err := h.pulumiExec.Execute(jobID); err != nil {
-			log.Printf("Pulumi execution failed for job %s: %v", jobID, err)
+			slog.Error("Pulumi execution failed for job", "job_id", jobID, "error", err)
 			return
 		}
-		log.Printf("Pulumi execution completed for job: %s", jobID)
+		slog.Info("Pulumi execution completed for job", "job_id", jobID)
 	}()
-
-	// Return job status div for HTMX to display with proper polling
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprintf(w, `
-		<div class="job-created">
-			<h3>Deployment Launched: %s</h3>
-			<div id="job-status" hx-get="/api/jobs/%s/status" hx-trigger="load, every 10s" hx-swap="innerHTML">
-				<p>Loading status...</p>
-			</div>
-		</div>`, jobID, jobID)
+	return nil, nil
 }
 
 // GetJobStatus returns the current status of a job
@@ -1040,16 +1684,16 @@ func (h *Handler) GetJobStatus(w http.ResponseWriter, r *http.Request) {
 	// Extract job ID from path like /api/jobs/{id}/status or /api/jobs/{id}
 	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(pathParts) < 3 || pathParts[0] != "api" || pathParts[1] != "jobs" {
-		log.Printf("Invalid path for job status: %s", r.URL.Path)
+		slog.WarnContext(r.Context(), "Invalid path for job status", "path", r.URL.Path)
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
 	jobID := pathParts[2]
-	log.Printf("GetJobStatus called for job: %s", jobID)
+	slog.DebugContext(r.Context(), "GetJobStatus called for job", "job_id", jobID)
 
 	job, exists := h.jobManager.GetJob(jobID)
 	if !exists {
-		log.Printf("Job not found: %s", jobID)
+		slog.WarnContext(r.Context(), "Job not found", "job_id", jobID)
 		http.Error(w, "Job not found", http.StatusNotFound)
 		return
 	}
@@ -1059,40 +1703,120 @@ func (h *Handler) GetJobStatus(w http.ResponseWriter, r *http.Request) {
 	output := job.Output
 	errorMsg := job.Error
 	kubeconfig := job.Kubeconfig
+	kubeconfigVersion := job.kubeconfigVersion()
+	grafanaURL := job.GrafanaURL
+	cluster := job.Cluster
+	kubeVersion := job.KubeVersion
+	destroyReport := job.DestroyReport
+	rolledBack := job.RolledBack
+	credentialsRejected := job.RejectedCredentials != ""
+	quotaReport := job.QuotaReport
+	var grafanaPassword string
+	var nodePool nodePoolCounts
+	scalable := false
+	if job.Config != nil {
+		grafanaPassword = job.Config.GrafanaAdminPassword
+		nodePool = labNodePoolCounts(job.Config)
+		scalable = !job.Config.UseExistingCluster && job.Config.Provider != "azure"
+	}
 	job.mu.RUnlock()
 
-	w.Header().Set("Content-Type", "text/html")
-
 	var statusHTML strings.Builder
 	statusHTML.WriteString(`<div class="job-status">`)
-	statusHTML.WriteString(fmt.Sprintf(`<div class="status-badge status-%s">%s</div>`, status, status))
+	badge := string(status)
+	if rolledBack && status == JobStatusFailed {
+		badge += " (rolled back)"
+	}
+	statusHTML.WriteString(fmt.Sprintf(`<div class="status-badge status-%s">%s</div>`, status, badge))
 
 	// Show launch button if dry run completed successfully
 	if status == JobStatusDryRunCompleted {
-		statusHTML.WriteString(`<form hx-post="/api/labs/launch" hx-target="#job-status" hx-swap="outerHTML" style="display: inline-block; margin-left: 1rem;">`)
+		statusHTML.WriteString(`<form hx-post="/api/labs/launch" hx-target="#job-status" hx-swap="outerHTML" class="job-action-form">`)
 		statusHTML.WriteString(fmt.Sprintf(`<input type="hidden" name="job_id" value="%s">`, jobID))
+		if !quotaReport.Fits() {
+			statusHTML.WriteString(`<label><input type="checkbox" name="override_quota" value="true"> Launch anyway</label> `)
+		}
 		statusHTML.WriteString(`<button type="submit" class="btn btn-success">`)
 		statusHTML.WriteString(`<span class="btn-icon">🚀</span> Launch Real Deployment`)
 		statusHTML.WriteString(`</button>`)
 		statusHTML.WriteString(`</form>`)
+		statusHTML.WriteString(quotaReportHTML(quotaReport))
 	}
 
-	// Show retry button if job failed
+	// A dry run stuck on an unresponsive API can be stopped here.
+	if status == JobStatusRunning && h.pulumiExec.PreviewRunning(jobID) {
+		statusHTML.WriteString(fmt.Sprintf(`<form hx-post="/api/jobs/%s/cancel" hx-target="#job-status" hx-swap="innerHTML" hx-confirm="Cancel this dry run?" class="job-action-form">`, jobID))
+		statusHTML.WriteString(`<input type="hidden" name="remove_dir" value="true">`)
+		statusHTML.WriteString(`<button type="submit" class="btn btn-secondary">Cancel Dry Run</button>`)
+		statusHTML.WriteString(`</form>`)
+	}
+
+	// Show retry button if job failed, after new credentials when OVH refused
+	// the lab's.
+	if status == JobStatusFailed && credentialsRejected {
+		statusHTML.WriteString(`<div class="warning-message">🔑 OVH rejected the credentials. <a href="/credentials?provider=ovh">Update the OVH credentials</a>, then retry: the lab resumes where it stopped.</div>`)
+	}
 	if status == JobStatusFailed {
-		statusHTML.WriteString(`<form style="display: inline-block; margin-left: 1rem;">`)
+		statusHTML.WriteString(`<form class="job-action-form">`)
 		statusHTML.WriteString(`<button type="button" class="btn btn-primary" onclick="retryJob('` + jobID + `')">`)
 		statusHTML.WriteString(`<span class="btn-icon">🔄</span> Retry Job`)
 		statusHTML.WriteString(`</button>`)
 		statusHTML.WriteString(`</form>`)
 	}
 
+	// A completed OVH lab's node pool can be resized mid-workshop.
+	if status == JobStatusCompleted && scalable {
+		statusHTML.WriteString(fmt.Sprintf(`<form hx-post="/api/jobs/%s/nodepool/scale" hx-target="#job-status" hx-swap="innerHTML" hx-confirm="Scale the node pool?" class="job-action-form">`, jobID))
+		statusHTML.WriteString(fmt.Sprintf(`<label>Nodes <input type="number" name="nodepool_desired_node_count" min="1" value="%d" class="input-narrow"></label>`, nodePool.Desired))
+		statusHTML.WriteString(fmt.Sprintf(`<label>Min <input type="number" name="nodepool_min_node_count" min="0" value="%d" class="input-narrow"></label>`, nodePool.Min))
+		statusHTML.WriteString(fmt.Sprintf(`<label>Max <input type="number" name="nodepool_max_node_count" min="1" value="%d" class="input-narrow"></label>`, nodePool.Max))
+		statusHTML.WriteString(`<button type="submit" class="btn btn-secondary">Scale Node Pool</button>`)
+		statusHTML.WriteString(`</form>`)
+	}
+
 	// Show download button if kubeconfig is available (for both completed and failed jobs)
 	if kubeconfig != "" && (status == JobStatusCompleted || status == JobStatusFailed) {
-		statusHTML.WriteString(fmt.Sprintf(`<a href="/api/jobs/%s/kubeconfig" class="btn btn-download" download="kubeconfig-%s.yaml">`, jobID, jobID))
-		statusHTML.WriteString(`<span class="btn-icon">⬇</span> Download Kubeconfig`)
+		statusHTML.WriteString(fmt.Sprintf(`<a href="/api/jobs/%s/kubeconfig" class="btn btn-download" download="%s">`, jobID, kubeconfigFilename(jobID, kubeconfigVersion)))
+		statusHTML.WriteString(fmt.Sprintf(`<span class="btn-icon">⬇</span> Download Kubeconfig (v%d)`, kubeconfigVersion))
 		statusHTML.WriteString(`</a>`)
 	}
 
+	// A share link lets students enroll without the student password.
+	if status == JobStatusCompleted {
+		statusHTML.WriteString(fmt.Sprintf(`<form hx-post="/api/jobs/%s/share-link" hx-target="#share-link" hx-swap="innerHTML" class="job-action-form">`, jobID))
+		statusHTML.WriteString(`<label>Valid for <input type="number" name="ttl_hours" min="1" max="720" value="24" class="input-narrow"> hours</label>`)
+		statusHTML.WriteString(`<button type="submit" class="btn btn-secondary">Create Share Link</button>`)
+		statusHTML.WriteString(`</form>`)
+		statusHTML.WriteString(`<div id="share-link"></div>`)
+	}
+
+	// Rotating revokes every kubeconfig downloaded so far, e.g. after a workshop.
+	if kubeconfig != "" && status == JobStatusCompleted && scalable {
+		statusHTML.WriteString(fmt.Sprintf(`<form hx-post="/api/jobs/%s/kubeconfig/rotate" hx-target="#job-status" hx-swap="innerHTML" hx-confirm="Rotate the kubeconfig? Every copy downloaded so far will stop working." class="job-action-form">`, jobID))
+		statusHTML.WriteString(`<button type="submit" class="btn btn-secondary">Rotate Kubeconfig</button>`)
+		statusHTML.WriteString(`</form>`)
+	}
+
+	if cluster != nil && status == JobStatusCompleted {
+		statusHTML.WriteString(clusterCardHTML(cluster, kubeVersion))
+	}
+
+	// The Grafana password is only shown here: the job output and the jobs API
+	// never carry it.
+	if grafanaURL != "" && status == JobStatusCompleted {
+		statusHTML.WriteString(fmt.Sprintf(`<div class="credential-item"><label>Grafana:</label><div class="value"><a href="%s" target="_blank" rel="noopener">%s</a> — user <code>admin</code>, password <code>%s</code></div></div>`,
+			template.HTMLEscapeString(grafanaURL), template.HTMLEscapeString(grafanaURL), template.HTMLEscapeString(grafanaPassword)))
+	}
+
+	// What the last destroy deleted, and what a failed one left behind.
+	if destroyReport != nil && (status == JobStatusDestroyed || status == JobStatusFailed) {
+		class := "success-message"
+		if len(destroyReport.Remaining) > 0 {
+			class = "warning-message"
+		}
+		statusHTML.WriteString(fmt.Sprintf(`<div class="%s">%s</div>`, class, template.HTMLEscapeString(destroyReport.Summary())))
+	}
+
 	if errorMsg != "" {
 		statusHTML.WriteString(fmt.Sprintf(`<div class="error-message">%s</div>`, template.HTMLEscapeString(errorMsg)))
 	}
@@ -1105,6 +1829,9 @@ func (h *Handler) GetJobStatus(w http.ResponseWriter, r *http.Request) {
 	}
 	statusHTML.WriteString(`</pre>`)
 	statusHTML.WriteString(`</div>`)
+	if len(output) > 0 {
+		statusHTML.WriteString(fmt.Sprintf(`<a href="/api/jobs/%s/logs/download?compress=1" class="btn btn-secondary">Download logs (.log.gz)</a>`, jobID))
+	}
 
 	// Continue polling if job is still running
 	if status == JobStatusPending || status == JobStatusRunning {
@@ -1113,7 +1840,7 @@ func (h *Handler) GetJobStatus(w http.ResponseWriter, r *http.Request) {
 
 	statusHTML.WriteString(`</div>`)
 
-	fmt.Fprint(w, statusHTML.String())
+	writeHTMLFragment(w, http.StatusOK, statusHTML.String())
 }
 
 // GetJobStatusJSON returns job status as JSON (for API clients)
@@ -1132,41 +1859,99 @@ func (h *Handler) GetJobStatusJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	costEstimate := h.labCostEstimate(job, time.Now())
+
 	job.mu.RLock()
 	defer job.mu.RUnlock()
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(job)
+	json.NewEncoder(w).Encode(redactedJob{Job: job, Config: job.Config.redacted(), CostEstimate: costEstimate})
 }
 
-// ServeStatic serves static files
-func (h *Handler) ServeStatic(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/static/")
-	filePath := filepath.Join("web", "static", path)
+// redactedJob is a job as the jobs API returns it: the outer Config shadows the
+// job's own, so the secrets it carries can be left out without copying the job.
+type redactedJob struct {
+	*Job
+	Config *LabConfig `json:"config,omitempty"`
+	// CostEstimate is the lab's running cost so far, when it can be estimated.
+	CostEstimate *LabCostEstimate `json:"cost_estimate,omitempty"`
+}
 
-	// Security: prevent directory traversal
-	if strings.Contains(path, "..") {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+// defaultHistoryPageSize is the page size of GetJobHistory when none is given.
+const defaultHistoryPageSize = 20
+
+// jobHistoryPage is one page of a job's status history.
+type jobHistoryPage struct {
+	Total   int            `json:"total"`
+	Offset  int            `json:"offset"`
+	Limit   int            `json:"limit"`
+	History []StatusChange `json:"history"`
+}
+
+// GetJobHistory returns a page of the job's status history, oldest first.
+//
+//	GET /api/labs/{id}/history?offset=0&limit=20
+func (h *Handler) GetJobHistory(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "history" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
-
-	file, err := os.Open(filePath)
+	offset, limit, err := historyPageParams(r)
 	if err != nil {
-		http.NotFound(w, r)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	// Set content type based on file extension
-	if strings.HasSuffix(path, ".css") {
-		w.Header().Set("Content-Type", "text/css")
-	} else if strings.HasSuffix(path, ".js") {
-		w.Header().Set("Content-Type", "application/javascript")
-	} else if strings.HasSuffix(path, ".png") {
-		w.Header().Set("Content-Type", "image/png")
+	job, exists := h.jobManager.GetJob(pathParts[2])
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	job.mu.RLock()
+	page := jobHistoryPage{Total: len(job.History), Offset: offset, Limit: limit, History: []StatusChange{}}
+	if offset < len(job.History) {
+		end := min(offset+limit, len(job.History))
+		page.History = append(page.History, job.History[offset:end]...)
+	}
+	job.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// historyPageParams reads the offset and limit query parameters. The limit
+// defaults to defaultHistoryPageSize and is capped at maxStatusHistory, the
+// most a job keeps.
+func historyPageParams(r *http.Request) (offset, limit int, err error) {
+	limit = defaultHistoryPageSize
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			return 0, 0, fmt.Errorf("invalid limit %q", v)
+		}
 	}
+	return offset, min(limit, maxStatusHistory), nil
+}
 
-	io.Copy(w, file)
+// ServeStatic serves the files under /static/. They are read from the web UI's
+// file system (see SetWebFS), which nothing outside static/ can be reached
+// through, so there is no path to sanitize here.
+func (h *Handler) ServeStatic(w http.ResponseWriter, r *http.Request) {
+	// No directory listings.
+	if strings.HasSuffix(r.URL.Path, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	h.templatesMu.RLock()
+	static := h.static
+	h.templatesMu.RUnlock()
+	static.ServeHTTP(w, r)
 }
 
 // DownloadKubeconfig serves the kubeconfig file for download
@@ -1174,22 +1959,22 @@ func (h *Handler) DownloadKubeconfig(w http.ResponseWriter, r *http.Request) {
 	// Extract job ID from path like /api/jobs/{id}/kubeconfig or /api/labs/{id}/kubeconfig
 	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(pathParts) < 4 || pathParts[0] != "api" || pathParts[3] != "kubeconfig" {
-		log.Printf("Invalid path for kubeconfig download: %s", r.URL.Path)
+		slog.WarnContext(r.Context(), "Invalid path for kubeconfig download", "path", r.URL.Path)
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
 	// Accept both "jobs" and "labs" as the second path segment
 	if pathParts[1] != "jobs" && pathParts[1] != "labs" {
-		log.Printf("Invalid path for kubeconfig download: %s", r.URL.Path)
+		slog.WarnContext(r.Context(), "Invalid path for kubeconfig download", "path", r.URL.Path)
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
 	jobID := pathParts[2]
-	log.Printf("DownloadKubeconfig called for job: %s", jobID)
+	slog.DebugContext(r.Context(), "DownloadKubeconfig called for job", "job_id", jobID)
 
 	job, exists := h.jobManager.GetJob(jobID)
 	if !exists {
-		log.Printf("Job not found: %s", jobID)
+		slog.WarnContext(r.Context(), "Job not found", "job_id", jobID)
 		http.Error(w, "Job not found", http.StatusNotFound)
 		return
 	}
@@ -1197,6 +1982,7 @@ func (h *Handler) DownloadKubeconfig(w http.ResponseWriter, r *http.Request) {
 	job.mu.RLock()
 	kubeconfig := job.Kubeconfig
 	status := job.Status
+	filename := kubeconfigFilename(jobID, job.kubeconfigVersion())
 	job.mu.RUnlock()
 
 	// Allow download for completed or failed jobs if kubeconfig is available
@@ -1212,7 +1998,7 @@ func (h *Handler) DownloadKubeconfig(w http.ResponseWriter, r *http.Request) {
 
 	// Set headers for file download
 	w.Header().Set("Content-Type", "application/x-yaml")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=kubeconfig-%s.yaml", jobID))
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
 	w.Header().Set("Content-Length", strconv.Itoa(len(kubeconfig)))
 
 	w.Write([]byte(kubeconfig))
@@ -1225,12 +2011,12 @@ func (h *Handler) DownloadKubeconfig(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetCoderCredentials(w http.ResponseWriter, r *http.Request) {
 	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(pathParts) < 4 || pathParts[0] != "api" || pathParts[3] != "coder-credentials" {
-		log.Printf("Invalid path for lab credentials: %s", r.URL.Path)
+		slog.WarnContext(r.Context(), "Invalid path for lab credentials", "path", r.URL.Path)
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
 	if pathParts[1] != "jobs" && pathParts[1] != "labs" {
-		log.Printf("Invalid path for lab credentials: %s", r.URL.Path)
+		slog.WarnContext(r.Context(), "Invalid path for lab credentials", "path", r.URL.Path)
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
@@ -1238,7 +2024,7 @@ func (h *Handler) GetCoderCredentials(w http.ResponseWriter, r *http.Request) {
 
 	job, exists := h.jobManager.GetJob(jobID)
 	if !exists {
-		log.Printf("Job not found: %s", jobID)
+		slog.WarnContext(r.Context(), "Job not found", "job_id", jobID)
 		http.Error(w, "Job not found", http.StatusNotFound)
 		return
 	}
@@ -1265,7 +2051,7 @@ func (h *Handler) GetCoderCredentials(w http.ResponseWriter, r *http.Request) {
 	if kubeconfig != "" {
 		backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 		if err != nil {
-			log.Printf("Failed to build workspace backend for lab %s: %v", jobID, err)
+			slog.ErrorContext(r.Context(), "Failed to build workspace backend for lab", "job_id", jobID, "error", err)
 		} else if d, scheme := backend.Routing(r.Context(), domain); d != "" {
 			baseURL = scheme + "://" + d
 		}
@@ -1289,6 +2075,7 @@ func (h *Handler) ServeStudentDashboard(w http.ResponseWriter, r *http.Request)
 		"Email":           email,
 		"Initial":         initial,
 		"FeedbackSuccess": r.URL.Query().Get("feedback") == "1",
+		"Broadcast":       h.broadcast.Current(),
 	})
 }
 
@@ -1322,7 +2109,7 @@ func (h *Handler) ListLabTemplates(w http.ResponseWriter, r *http.Request) {
 	}
 
 	job, exists := h.jobManager.GetJob(labID)
-	if !exists {
+	if !exists || !studentCanUseLab(r.Context(), labID) {
 		http.Error(w, "Lab not found", http.StatusNotFound)
 		return
 	}
@@ -1391,7 +2178,7 @@ func (h *Handler) UploadTemplateToLab(w http.ResponseWriter, r *http.Request) {
 	// The drawer posts multipart FormData; ErrNotMultipart just means an older
 	// urlencoded caller, which ParseForm (called below via getFormValue) handles.
 	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
-		log.Printf("Failed to parse form for lab %s: %v", jobID, err)
+		slog.WarnContext(r.Context(), "Failed to parse form for lab", "job_id", jobID, "error", err)
 		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
 		return
 	}
@@ -1434,7 +2221,7 @@ func (h *Handler) UploadTemplateToLab(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := h.jobManager.SaveJob(jobID); err != nil {
-		log.Printf("Failed to persist templates for lab %s: %v", jobID, err)
+		slog.ErrorContext(r.Context(), "Failed to persist templates for lab", "job_id", jobID, "error", err)
 	}
 
 	names := make([]string, len(templates))
@@ -1472,6 +2259,7 @@ func templatesFromUploadRequest(r *http.Request) ([]WorkspaceTemplate, error) {
 		CPU:           strings.TrimSpace(r.FormValue("template_cpu")),
 		Memory:        strings.TrimSpace(r.FormValue("template_memory")),
 		DiskSize:      strings.TrimSpace(r.FormValue("template_disk_size")),
+		GPU:           atoiForm(strings.TrimSpace(r.FormValue("template_gpu"))),
 		StartupScript: r.FormValue("template_startup_script"),
 		DotfilesRepo:  strings.TrimSpace(r.FormValue("template_dotfiles_repo")),
 		Extensions:    splitList(r.FormValue("template_extensions")),
@@ -1482,23 +2270,41 @@ func templatesFromUploadRequest(r *http.Request) ([]WorkspaceTemplate, error) {
 	return []WorkspaceTemplate{tmpl}, nil
 }
 
-// ListLabs returns a list of completed jobs (labs) available for workspace requests
+// studentLab is what a student sees of a lab: enough to pick it from a list.
+// The cluster, its kubeconfig and the lab's credentials stay with the admin.
+type studentLab struct {
+	ID     string `json:"id"`
+	Config struct {
+		StackName string `json:"stack_name"`
+	} `json:"config"`
+	// Status is "ready" when the lab's cluster answers, and "starting" while it
+	// does not yet (see lab_health.go).
+	Status string `json:"status"`
+}
+
+// ListLabs returns the completed labs (newest first) available for workspace
+// requests, each with whether its cluster is ready to take one. A student who
+// logged in with a lab's access code only sees that lab.
 func (h *Handler) ListLabs(w http.ResponseWriter, r *http.Request) {
-	h.jobManager.mu.RLock()
-	defer h.jobManager.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.studentLabs(r.Context()))
+}
 
-	var completedLabs []*Job
-	for _, job := range h.jobManager.jobs {
-		job.mu.RLock()
-		status := job.Status
-		job.mu.RUnlock()
-		if status == JobStatusCompleted {
-			completedLabs = append(completedLabs, job)
+// studentLabs returns the labs of ListLabs.
+func (h *Handler) studentLabs(ctx context.Context) []studentLab {
+	var labs []catalogLab
+	for _, l := range h.catalogLabs() {
+		if studentCanUseLab(ctx, l.lab.ID) {
+			labs = append(labs, l)
 		}
 	}
+	h.labStatuses(ctx, labs, false)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(completedLabs)
+	completedLabs := make([]studentLab, 0, len(labs))
+	for _, l := range labs {
+		completedLabs = append(completedLabs, l.lab)
+	}
+	return completedLabs
 }
 
 // workspaceDeletionTime returns the moment a workspace will be automatically
@@ -1533,10 +2339,6 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get form values using helper
-	labID := getFormValue(r, "lab_id")
-	templateIDStr := getFormValue(r, "template_id")
-
 	// Email comes from the authenticated session
 	email := studentEmailFromContext(r)
 	if email == "" {
@@ -1544,17 +2346,171 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ws, reqErr := h.createStudentWorkspace(r.Context(), email, studentWorkspaceRequest{
+		LabID:    getFormValue(r, "lab_id"),
+		Template: getFormValue(r, "template_id"),
+		// Optional: a student who needs a second workspace (e.g. the first one broke)
+		// picks its name; otherwise one is derived from the lab, student and template.
+		WorkspaceName: getFormValue(r, "workspace_name"),
+	})
+	if reqErr != nil {
+		if reqErr.Plain {
+			http.Error(w, reqErr.Message, reqErr.Status)
+			return
+		}
+		writeHTMLFragment(w, http.StatusOK, studentErrorHTML(reqErr))
+		return
+	}
+
+	// A workspace got back carries no password: keep the one saved when it was
+	// created.
+	if !ws.Reused {
+		setWorkspaceInfoCookie(w, r, ws)
+	}
+
+	workspaceInfoJSONForClient, _ := json.Marshal(ws)
+	workspaceInfoJSONEscaped := template.HTMLEscapeString(string(workspaceInfoJSONForClient))
+
+	title := "✅ Workspace Created Successfully!"
+	if ws.Reused {
+		title = "✅ You Already Have This Workspace"
+	} else if ws.Ready {
+		title = "✅ Workspace Ready!"
+	}
+
+	var response strings.Builder
+	response.WriteString(`<div class="success-message">`)
+	response.WriteString(fmt.Sprintf(`<h3>%s</h3>`, title))
+	pollURL := fmt.Sprintf("/api/student/workspace/status?lab_id=%s&workspace_name=%s",
+		url.QueryEscape(ws.LabID), url.QueryEscape(ws.WorkspaceName))
+	response.WriteString(fmt.Sprintf(`<div class="workspace-ready-status workspace-ready-status--starting" data-poll-url="%s"><span class="workspace-status-spinner"></span><span>Workspace is starting, this may take a moment...</span></div>`,
+		template.HTMLEscapeString(pollURL)))
+	response.WriteString(`<details class="credentials-box">`)
+	response.WriteString(`<summary>Your Workspace Credentials</summary>`)
+	if ws.WorkspaceURL != "" {
+		response.WriteString(fmt.Sprintf(`<div class="credential-item"><label>Workspace URL:</label><div class="value"><a href="%s" target="_blank">%s</a></div></div>`, ws.WorkspaceURL, ws.WorkspaceURL))
+	}
+	response.WriteString(fmt.Sprintf(`<div class="credential-item"><label>Email:</label><div class="value">%s</div></div>`, template.HTMLEscapeString(email)))
+	if ws.Reused {
+		response.WriteString(fmt.Sprintf(`<p class="hint-text">%s</p>`, reusedWorkspaceHint))
+	} else {
+		response.WriteString(fmt.Sprintf(`<div class="credential-item"><label>Connection token:</label><div class="value">%s</div></div>`, template.HTMLEscapeString(ws.Password)))
+		response.WriteString(`<p><strong>Important:</strong> Please save these credentials. You will need the token to open your workspace.</p>`)
+		response.WriteString(`<p><small>Your workspace information can be encrypted and saved locally. Click "Encrypt & Save" below to store it securely.</small></p>`)
+		response.WriteString(fmt.Sprintf(`<div data-workspace-info='%s' style="display:none;"></div>`, workspaceInfoJSONEscaped))
+		response.WriteString(`<button onclick="encryptAndSaveWorkspaceInfo(this)" class="btn credentials-save-btn">Encrypt & Save Workspace Info</button>`)
+	}
+	response.WriteString(`</details>`)
+	response.WriteString(`<a href="/student/workspaces" class="btn workspace-view-all-link">View my workspaces →</a>`)
+	response.WriteString(`</div>`)
+
+	writeHTMLFragment(w, http.StatusOK, response.String())
+}
+
+// reusedWorkspaceHint tells a student who got back a workspace they already had
+// why its password is not shown, and where to reset it.
+const reusedWorkspaceHint = `This workspace was created before, so its password is not shown again. If you have lost it, reset it from <strong>Lost your workspace details?</strong> on the <a href="/student/workspaces">My workspaces</a> page.`
+
+// setWorkspaceInfoCookie saves the workspace's details in a cookie the "My
+// workspaces" page reads, client-side.
+func setWorkspaceInfoCookie(w http.ResponseWriter, r *http.Request, ws studentWorkspace) {
+	workspaceInfo := map[string]interface{}{
+		"email":              ws.Email,
+		"workspace_url":      ws.WorkspaceURL,
+		"password":           ws.Password, // code-server login password; encrypted client-side
+		"encrypted_password": "",
+		"workspace_name":     ws.WorkspaceName,
+		"lab_id":             ws.LabID,
+		"lab_name":           ws.LabName,
+		"template":           ws.Template,
+		"created_at":         ws.CreatedAt,
+		"deletion_at":        ws.DeletionAt,
+	}
+	workspaceInfoJSON, err := json.Marshal(workspaceInfo)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to marshal workspace info", "error", err)
+		return
+	}
+	isSecure := strings.HasPrefix(ws.WorkspaceURL, "https://") || r.TLS != nil
+	cookieName := fmt.Sprintf("workspace_info_%s_%s", ws.LabID, ws.WorkspaceName)
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    url.QueryEscape(string(workspaceInfoJSON)),
+		Path:     "/",
+		MaxAge:   86400,
+		HttpOnly: false,
+		Secure:   isSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	slog.DebugContext(r.Context(), "Set student cookie", "cookie", cookieName, "email", ws.Email)
+}
+
+// htmlTextEscaper escapes text for an HTML element's content, where quotes
+// need no escaping.
+var htmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// studentErrorHTML is the fragment the student portal shows for a refused
+// workspace request.
+func studentErrorHTML(err *requestError) string {
+	return fmt.Sprintf(`<div class="error-message">%s</div>`, htmlTextEscaper.Replace(err.Message))
+}
+
+// errClusterUnreachable is what a student is told when the lab's cluster cannot
+// be reached; the cause goes to the log.
+var errClusterUnreachable = &requestError{Status: http.StatusBadGateway, Title: "Cluster Unreachable", Message: "Unable to reach the lab cluster. Please contact the lab administrator."}
+
+// studentWorkspaceRequest is a student's request for a workspace in a lab.
+type studentWorkspaceRequest struct {
+	LabID string `json:"lab_id"`
+	// Template is the name of one of the lab's templates; empty picks the first.
+	Template string `json:"template,omitempty"`
+	// WorkspaceName is optional; empty derives one from the lab, student and
+	// template.
+	WorkspaceName string `json:"workspace_name,omitempty"`
+}
+
+// studentWorkspace is a workspace created for a student, with what they need
+// to open it.
+type studentWorkspace struct {
+	Email        string `json:"email"`
+	WorkspaceURL string `json:"workspace_url"`
+	// Password is only set when this request created the workspace: it is
+	// shown once, never handed out again.
+	Password      string `json:"password,omitempty"`
+	WorkspaceName string `json:"workspace_name"`
+	LabID         string `json:"lab_id"`
+	LabName       string `json:"lab_name"`
+	Template      string `json:"template"`
+	CreatedAt     string `json:"created_at"`
+	// DeletionAt is when the workspace is deleted automatically, "" when never.
+	DeletionAt string `json:"deletion_at"`
+	Ready      bool   `json:"ready"`
+	// Reused is set when the student got back a workspace they already had.
+	Reused bool `json:"reused,omitempty"`
+}
+
+// createStudentWorkspace creates (or finds again) the workspace of the student
+// with email in a lab, and records it on the lab's roster. It is shared by the
+// student portal and the JSON API.
+func (h *Handler) createStudentWorkspace(ctx context.Context, email string, req studentWorkspaceRequest) (studentWorkspace, *requestError) {
+	labID := req.LabID
+	templateIDStr := req.Template
+	workspaceNameReq := strings.ToLower(strings.TrimSpace(req.WorkspaceName))
+
 	// Validate lab ID
 	if labID == "" {
-		http.Error(w, "Lab ID is required", http.StatusBadRequest)
-		return
+		return studentWorkspace{}, &requestError{Status: http.StatusBadRequest, Title: "Invalid Request", Message: "Lab ID is required", Plain: true}
+	}
+
+	// A student logged in with a lab's access code only gets workspaces there.
+	if !studentCanUseLab(ctx, labID) {
+		return studentWorkspace{}, &requestError{Status: http.StatusForbidden, Title: "Lab Not Available", Message: "Your access code does not open this lab. Ask your instructor for its code.", Plain: true}
 	}
 
 	// Get the job
 	job, exists := h.jobManager.GetJob(labID)
 	if !exists {
-		http.Error(w, "Lab not found", http.StatusNotFound)
-		return
+		return studentWorkspace{}, &requestError{Status: http.StatusNotFound, Title: "Lab Not Found", Message: "Lab not found", Plain: true}
 	}
 
 	job.mu.RLock()
@@ -1565,6 +2521,9 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 	dnsProvider := ""
 	lifetimeHours := 0
 	labName := ""
+	proxyEnabled := false
+	storageClass := ""
+	maxWorkspaces := 0
 	var labDeletionDate *time.Time
 	var templates []WorkspaceTemplate
 	if job.Config != nil {
@@ -1573,18 +2532,20 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 		lifetimeHours = job.Config.WorkspaceLifetimeHours
 		labDeletionDate = job.Config.LabDeletionDate
 		labName = job.Config.StackName
+		proxyEnabled = job.Config.WorkspaceProxyEnabled
 		templates = job.Config.GetWorkspaceTemplates()
+		storageClass = job.Config.StorageClass
+		maxWorkspaces = job.Config.MaxWorkspaces
 	}
+	nodeSelector, tolerations := workspaceScheduling(job.Config)
 	job.mu.RUnlock()
 
 	if status != JobStatusCompleted {
-		http.Error(w, "Lab is not ready yet", http.StatusBadRequest)
-		return
+		return studentWorkspace{}, &requestError{Status: http.StatusBadRequest, Title: "Lab Not Ready", Message: "Lab is not ready yet", Plain: true}
 	}
 
 	if kubeconfig == "" {
-		http.Error(w, "Lab cluster configuration not available", http.StatusInternalServerError)
-		return
+		return studentWorkspace{}, &requestError{Status: http.StatusInternalServerError, Title: "Lab Not Ready", Message: "Lab cluster configuration not available", Plain: true}
 	}
 
 	// Generate the code-server password shown to the student. It stays within
@@ -1592,18 +2553,15 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 	// carry through the shell-quoted container bootstrap.
 	password, err := GenerateWorkspaceToken()
 	if err != nil {
-		log.Printf("Failed to generate workspace token: %v", err)
-		http.Error(w, "Failed to generate workspace token", http.StatusInternalServerError)
-		return
+		slog.ErrorContext(ctx, "Failed to generate workspace token", "error", err)
+		return studentWorkspace{}, &requestError{Status: http.StatusInternalServerError, Title: "Workspace Error", Message: "Failed to generate workspace token", Plain: true}
 	}
 
 	// Get sanitized username from email (before @) for use in resource names.
 	username := usernameFromEmail(email)
 
 	if len(templates) == 0 {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `<div class="error-message">No templates available in this lab</div>`)
-		return
+		return studentWorkspace{}, &requestError{Status: http.StatusConflict, Title: "No Templates", Message: "No templates available in this lab"}
 	}
 
 	// Resolve the selected template by name (template_id is the template name);
@@ -1619,18 +2577,42 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		if !found {
-			w.Header().Set("Content-Type", "text/html")
-			fmt.Fprintf(w, `<div class="error-message">Selected template is not available in this lab</div>`)
-			return
+			return studentWorkspace{}, &requestError{Status: http.StatusBadRequest, Title: "Unknown Template", Message: "Selected template is not available in this lab"}
+		}
+	}
+
+	if workspaceNameReq != "" {
+		if err := workspace.ValidateName(workspaceNameReq); err != nil {
+			return studentWorkspace{}, &requestError{Status: http.StatusBadRequest, Title: "Invalid Workspace Name", Message: "Invalid workspace name: " + err.Error()}
 		}
 	}
 
 	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 	if err != nil {
-		log.Printf("Failed to build workspace backend for lab %s: %v", labID, err)
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `<div class="error-message">Unable to reach the lab cluster. Please contact the lab administrator.</div>`)
-		return
+		slog.ErrorContext(ctx, "Failed to build workspace backend for lab", "job_id", labID, "error", err)
+		return studentWorkspace{}, errClusterUnreachable
+	}
+
+	// Without a chosen name, a student gets back the workspace they already have
+	// for the template, whatever it was named. A new name asks for another one,
+	// e.g. after the first one broke.
+	existing, err := backend.ListWorkspaces(ctx, labID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list the workspaces of lab", "job_id", labID, "error", err)
+		return studentWorkspace{}, errClusterUnreachable
+	}
+	own, hasOwn := ownWorkspace(existing, username, selected.Name)
+	reuse := hasOwn && workspaceNameReq == ""
+
+	// A lab with max_workspaces turns students away once every seat is taken.
+	fillsLab := false
+	if maxWorkspaces > 0 && !reuse {
+		var allowed bool
+		allowed, fillsLab = workspaceSeat(existing, maxWorkspaces, username, selected.Name, workspaceNameReq)
+		if !allowed {
+			metrics.countWorkspaceRequest("lab_full")
+			return studentWorkspace{}, &requestError{Status: http.StatusConflict, Title: "Lab Full", Message: "Every workspace of this lab is taken. Please contact the lab administrator."}
+		}
 	}
 
 	// A git-backed workspace needs a persistent volume to clone into; default one.
@@ -1643,14 +2625,18 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 		LabID:            labID,
 		Owner:            username,
 		Template:         selected.Name,
+		Name:             workspaceNameReq,
 		IDE:              selected.IDE,
 		Image:            selected.Image,
 		GitRepo:          selected.GitRepo,
 		GitBranch:        selected.GitBranch,
+		GitCommit:        selected.GitCommit,
 		GitFolder:        selected.GitFolder,
 		CPU:              selected.CPU,
 		Memory:           selected.Memory,
+		GPU:              selected.GPU,
 		DiskSize:         diskSize,
+		StorageClass:     storageClass,
 		Env:              selected.Env,
 		StartupScript:    selected.StartupScript,
 		DotfilesRepo:     selected.DotfilesRepo,
@@ -1660,6 +2646,8 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 		ImagePullSecrets: selected.ImagePullSecrets,
 		GitAuthSecret:    selected.GitAuthSecret,
 		Devcontainer:     toWorkspaceDevcontainer(selected.Devcontainer),
+		NodeSelector:     nodeSelector,
+		Tolerations:      tolerations,
 		Domain:           domain,
 		ClusterIssuer:    "letsencrypt-prod",
 		Token:            password,
@@ -1675,103 +2663,76 @@ func (h *Handler) RequestWorkspace(w http.ResponseWriter, r *http.Request) {
 		spec.WildcardTLSSecret = coder.WildcardTLSSecretName
 	}
 
-	ws, err := backend.EnsureWorkspace(r.Context(), spec)
+	ws := own
+	if !reuse {
+		ws, err = backend.EnsureWorkspace(ctx, spec)
+	}
+	if errors.Is(err, workspace.ErrNameTaken) {
+		metrics.countWorkspaceRequest("name_taken")
+		// The student picked the name, so the conflict is theirs to resolve.
+		return studentWorkspace{}, &requestError{Status: http.StatusConflict, Title: "Name Taken", Message: fmt.Sprintf(`The workspace name "%s" is already taken. Please choose another name.`, workspaceNameReq)}
+	}
 	if err != nil {
 		// The cause is for the admin, not the student: it can name the lab's
 		// credential Secrets, its namespace and its cluster, and there is nothing in
 		// it a student could act on anyway. It goes to the log; they get the same
 		// "ask your administrator" they get when the cluster is unreachable.
-		log.Printf("Failed to ensure workspace for %s in lab %s: %v", email, labID, err)
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprint(w, `<div class="error-message">Could not create your workspace. Please contact the lab administrator.</div>`)
-		return
+		slog.ErrorContext(ctx, "Failed to ensure workspace", "email", email, "job_id", labID, "error", err)
+		metrics.countWorkspaceRequest("failed")
+		return studentWorkspace{}, &requestError{Status: http.StatusBadGateway, Title: "Workspace Error", Message: "Could not create your workspace. Please contact the lab administrator."}
 	}
 
+	metrics.countWorkspaceRequest("created")
+	if fillsLab {
+		h.notifications.labFull(labID, labName, maxWorkspaces)
+	}
 	workspaceURL := ws.URL
 	workspaceName := ws.Name
 
+	createdAt := time.Now()
+	if err := h.jobManager.RecordRosterEntry(labID, RosterEntry{
+		Email: email, Username: username, WorkspaceName: workspaceName, Template: selected.Name, CreatedAt: createdAt,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to record the student on the roster", "email", email, "job_id", labID, "error", err)
+	} else if err := h.jobManager.SaveJob(labID); err != nil {
+		slog.ErrorContext(ctx, "Failed to persist the roster of lab", "job_id", labID, "error", err)
+	}
+	// In proxy mode the student opens the workspace through this server, since the
+	// workspace host is what the venue's network blocks.
+	if proxyEnabled {
+		workspaceURL = workspaceProxyURL(labID, workspaceName)
+	}
+
 	// Compute a single creation timestamp and the workspace's scheduled auto-deletion
 	// time (nil when neither a per-workspace lifetime nor a lab deletion date applies),
 	// so the student portal can show the student when the workspace will disappear.
-	createdAt := time.Now()
 	deletionAtStr := ""
 	if deletionAt := workspaceDeletionTime(createdAt, lifetimeHours, labDeletionDate); deletionAt != nil {
 		deletionAtStr = deletionAt.Format(time.RFC3339)
 	}
 
-	// Create workspace info structure for the client-side encrypted cookie.
-	workspaceInfo := map[string]interface{}{
-		"email":              email,
-		"workspace_url":      workspaceURL,
-		"password":           password, // code-server login password; encrypted client-side
-		"encrypted_password": "",
-		"workspace_name":     workspaceName,
-		"lab_id":             labID,
-		"lab_name":           labName,
-		"template":           selected.Name,
-		"created_at":         createdAt.Format(time.RFC3339),
-		"deletion_at":        deletionAtStr,
-	}
-	if workspaceInfoJSON, jsonErr := json.Marshal(workspaceInfo); jsonErr == nil {
-		isSecure := strings.HasPrefix(workspaceURL, "https://") || r.TLS != nil
-		cookieName := fmt.Sprintf("workspace_info_%s_%s", labID, workspaceName)
-		http.SetCookie(w, &http.Cookie{
-			Name:     cookieName,
-			Value:    url.QueryEscape(string(workspaceInfoJSON)),
-			Path:     "/",
-			MaxAge:   86400,
-			HttpOnly: false,
-			Secure:   isSecure,
-			SameSite: http.SameSiteLaxMode,
-		})
-		log.Printf("Set %s cookie for email: %s", cookieName, email)
-	} else {
-		log.Printf("Failed to marshal workspace info: %v", jsonErr)
-	}
-
-	workspaceInfoForClient := map[string]interface{}{
-		"email":          email,
-		"workspace_url":  workspaceURL,
-		"password":       password,
-		"workspace_name": workspaceName,
-		"lab_id":         labID,
-		"lab_name":       labName,
-		"template":       selected.Name,
-		"created_at":     createdAt.Format(time.RFC3339),
-		"deletion_at":    deletionAtStr,
-	}
-	workspaceInfoJSONForClient, _ := json.Marshal(workspaceInfoForClient)
-	workspaceInfoJSONEscaped := template.HTMLEscapeString(string(workspaceInfoJSONForClient))
-
-	title := "✅ Workspace Created Successfully!"
-	if ws.Ready {
-		title = "✅ Workspace Ready!"
-	}
-
-	w.Header().Set("Content-Type", "text/html")
-	var response strings.Builder
-	response.WriteString(`<div class="success-message">`)
-	response.WriteString(fmt.Sprintf(`<h3>%s</h3>`, title))
-	pollURL := fmt.Sprintf("/api/student/workspace/status?lab_id=%s&workspace_name=%s",
-		url.QueryEscape(labID), url.QueryEscape(workspaceName))
-	response.WriteString(fmt.Sprintf(`<div class="workspace-ready-status workspace-ready-status--starting" data-poll-url="%s"><span class="workspace-status-spinner"></span><span>Workspace is starting, this may take a moment...</span></div>`,
-		template.HTMLEscapeString(pollURL)))
-	response.WriteString(`<details class="credentials-box">`)
-	response.WriteString(`<summary>Your Workspace Credentials</summary>`)
-	if workspaceURL != "" {
-		response.WriteString(fmt.Sprintf(`<div class="credential-item"><label>Workspace URL:</label><div class="value"><a href="%s" target="_blank">%s</a></div></div>`, workspaceURL, workspaceURL))
-	}
-	response.WriteString(fmt.Sprintf(`<div class="credential-item"><label>Email:</label><div class="value">%s</div></div>`, template.HTMLEscapeString(email)))
-	response.WriteString(fmt.Sprintf(`<div class="credential-item"><label>Connection token:</label><div class="value">%s</div></div>`, template.HTMLEscapeString(password)))
-	response.WriteString(`<p><strong>Important:</strong> Please save these credentials. You will need the token to open your workspace.</p>`)
-	response.WriteString(`<p><small>Your workspace information can be encrypted and saved locally. Click "Encrypt & Save" below to store it securely.</small></p>`)
-	response.WriteString(fmt.Sprintf(`<div data-workspace-info='%s' style="display:none;"></div>`, workspaceInfoJSONEscaped))
-	response.WriteString(`<button onclick="encryptAndSaveWorkspaceInfo(this)" class="btn credentials-save-btn">Encrypt & Save Workspace Info</button>`)
-	response.WriteString(`</details>`)
-	response.WriteString(`<a href="/student/workspaces" class="btn workspace-view-all-link">View my workspaces →</a>`)
-	response.WriteString(`</div>`)
-
-	fmt.Fprint(w, response.String())
+	// A workspace that already existed keeps the password it was created with,
+	// and that one is never handed out again: whoever holds the session could
+	// read it back. Only a workspace this request created, with the password
+	// generated above, gets its password shown.
+	created := ws.Token == password
+	studentPassword := ""
+	if created {
+		studentPassword = password
+	}
+	return studentWorkspace{
+		Email:         email,
+		WorkspaceURL:  workspaceURL,
+		Password:      studentPassword,
+		WorkspaceName: workspaceName,
+		LabID:         labID,
+		LabName:       labName,
+		Template:      selected.Name,
+		CreatedAt:     createdAt.Format(time.RFC3339),
+		DeletionAt:    deletionAtStr,
+		Ready:         ws.Ready,
+		Reused:        !created,
+	}, nil
 }
 
 // WorkspaceStatus returns the current readiness status of a student workspace as an HTML partial.
@@ -1792,11 +2753,31 @@ func (h *Handler) WorkspaceStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	st := h.studentWorkspaceStatus(r.Context(), owner, labID, workspaceName)
+	writeHTMLFragment(w, http.StatusOK, buildWorkspaceStatusHTML(labID, st.WorkspaceName, st.Status, st.OpenURL))
+}
+
+// studentWorkspaceState is the readiness of a student's workspace.
+type studentWorkspaceState struct {
+	LabID         string `json:"lab_id"`
+	WorkspaceName string `json:"workspace_name"`
+	// Status is "running" once the workspace can be opened, "dns_propagating"
+	// while its hostname does not resolve yet, the pod phase before that,
+	// "checking" while it cannot be looked up and "unknown" for an unknown lab.
+	Status string `json:"status"`
+	// OpenURL opens the workspace with its token, once it is running.
+	OpenURL string `json:"open_url,omitempty"`
+}
+
+// studentWorkspaceStatus looks up the readiness of the workspace of owner. A
+// workspace of another student is reported as "checking", like one that does
+// not exist, so its existence is not disclosed.
+func (h *Handler) studentWorkspaceStatus(ctx context.Context, owner, labID, workspaceName string) studentWorkspaceState {
+	st := studentWorkspaceState{LabID: labID, WorkspaceName: workspaceName, Status: "checking"}
 	job, exists := h.jobManager.GetJob(labID)
 	if !exists {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprint(w, buildWorkspaceStatusHTML(labID, workspaceName, "unknown", ""))
-		return
+		st.Status = "unknown"
+		return st
 	}
 
 	job.mu.RLock()
@@ -1806,47 +2787,40 @@ func (h *Handler) WorkspaceStatus(w http.ResponseWriter, r *http.Request) {
 	job.mu.RUnlock()
 
 	if status != JobStatusCompleted || kubeconfig == "" {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprint(w, buildWorkspaceStatusHTML(labID, workspaceName, "checking", ""))
-		return
+		return st
 	}
 
 	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 	if err != nil {
-		log.Printf("WorkspaceStatus: failed to build backend for lab %s: %v", labID, err)
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprint(w, buildWorkspaceStatusHTML(labID, workspaceName, "checking", ""))
-		return
+		slog.ErrorContext(ctx, "WorkspaceStatus: failed to build backend for lab", "job_id", labID, "error", err)
+		return st
 	}
 
-	ws, err := backend.GetWorkspace(r.Context(), workspaceName)
+	ws, err := backend.GetWorkspace(ctx, workspaceName)
 	// Authorization: a student may only see their own workspace.
 	if err != nil || ws.Owner != owner {
-		log.Printf("[debug] WorkspaceStatus: lookup/authz failed workspace=%s owner=%s error=%v", workspaceName, owner, err)
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprint(w, buildWorkspaceStatusHTML(labID, workspaceName, "checking", ""))
-		return
+		slog.DebugContext(ctx, "WorkspaceStatus: lookup/authz failed", "workspace", workspaceName, "owner", owner, "error", err)
+		return st
 	}
 
-	readiness := ws.Phase
-	wsURL := ""
+	st.WorkspaceName = ws.Name
+	st.Status = ws.Phase
 	if ws.Ready {
-		if workspaceDNSReady(r.Context(), ws) {
-			readiness = "running"
+		if workspaceDNSReady(ctx, ws) {
+			st.Status = "running"
 			// Route through the OpenWorkspace redirect endpoint so the connection token
 			// is appended at click time.
-			wsURL = fmt.Sprintf("/api/student/workspace/open?lab_id=%s&workspace_name=%s",
+			st.OpenURL = fmt.Sprintf("/api/student/workspace/open?lab_id=%s&workspace_name=%s",
 				url.QueryEscape(labID), url.QueryEscape(ws.Name))
 		} else {
 			// The pod is up but the workspace hostname does not resolve yet — the DNS
 			// record created during provisioning is still propagating. Keep polling
 			// instead of handing the student a URL that would NXDOMAIN (which the
 			// browser then negatively caches, making the workspace look broken).
-			readiness = "dns_propagating"
+			st.Status = "dns_propagating"
 		}
 	}
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprint(w, buildWorkspaceStatusHTML(labID, ws.Name, readiness, wsURL))
+	return st
 }
 
 // dnsPropagationGrace bounds how long the student UI holds the "ready" signal back
@@ -1973,7 +2947,7 @@ func (h *Handler) OpenWorkspace(w http.ResponseWriter, r *http.Request) {
 
 	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 	if err != nil {
-		log.Printf("OpenWorkspace: failed to build backend for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "OpenWorkspace: failed to build backend for lab", "job_id", labID, "error", err)
 		http.Error(w, "lab is not ready", http.StatusServiceUnavailable)
 		return
 	}
@@ -1981,7 +2955,7 @@ func (h *Handler) OpenWorkspace(w http.ResponseWriter, r *http.Request) {
 	ws, err := backend.GetWorkspace(r.Context(), workspaceName)
 	// Authorization: a student may only open their own workspace.
 	if err != nil || ws.Owner != owner || ws.OpenURL == "" {
-		log.Printf("OpenWorkspace: lookup/authz failed workspace=%s owner=%s in lab %s: %v", workspaceName, owner, labID, err)
+		slog.ErrorContext(r.Context(), "OpenWorkspace: lookup/authz failed in lab", "workspace", workspaceName, "owner", owner, "job_id", labID, "error", err)
 		http.Error(w, "workspace not available", http.StatusServiceUnavailable)
 		return
 	}
@@ -1995,7 +2969,7 @@ func (h *Handler) OpenWorkspace(w http.ResponseWriter, r *http.Request) {
 		LoginURL string
 		Token    string
 	}{LoginURL: loginURL, Token: ws.Token}); err != nil {
-		log.Printf("OpenWorkspace: failed to render auto-login page for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "OpenWorkspace: failed to render auto-login page for lab", "job_id", labID, "error", err)
 	}
 }
 
@@ -2120,8 +3094,9 @@ func (h *Handler) ServeCredentials(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := map[string]interface{}{
-		"CurrentCreds": currentCreds,
-		"Provider":     provider,
+		"CurrentCreds":       currentCreds,
+		"Provider":           provider,
+		"DefaultOVHEndpoint": h.credentialsManager.DefaultOVHEndpoint(),
 	}
 
 	h.serveTemplate(w, "credentials.html", data)
@@ -2136,9 +3111,7 @@ func (h *Handler) ServeOVHCredentials(w http.ResponseWriter, r *http.Request) {
 // SetCredentials handles setting provider credentials
 func (h *Handler) SetCredentials(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.Header().Set("Content-Type", "text/html")
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		fmt.Fprintf(w, `
+		writeHTMLFragment(w, http.StatusMethodNotAllowed, `
 			<div class="error-message">
 				<h3>Method Not Allowed</h3>
 				<p>Only POST requests are accepted.</p>
@@ -2149,7 +3122,7 @@ func (h *Handler) SetCredentials(w http.ResponseWriter, r *http.Request) {
 	// Parse form data - handle both multipart and urlencoded
 	if err := h.parseForm(w, r, 10<<20); err != nil {
 		// Return HTML error for HTMX compatibility
-		log.Printf("SetCredentials - Failed to parse form: %v", err)
+		slog.WarnContext(r.Context(), "SetCredentials - Failed to parse form", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		h.renderHTMLError(w, "Failed to Parse Form", err.Error())
 		return
@@ -2168,50 +3141,52 @@ func (h *Handler) SetCredentials(w http.ResponseWriter, r *http.Request) {
 	case "azure":
 		h.setAzureCredentialsFromForm(w, r)
 	default:
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
 			<div class="error-message">
 				<h3>Unsupported Provider</h3>
 				<p>Provider "%s" is not yet supported.</p>
-			</div>`, template.HTMLEscapeString(provider))
+			</div>`, template.HTMLEscapeString(provider)))
 	}
 }
 
-// setOVHCredentialsFromForm handles OVH-specific credential setting
+// setOVHCredentialsFromForm handles OVH-specific credential setting. Empty
+// fields keep their stored value unless ovh_replace_all is set, so an admin can
+// rotate a single key.
 func (h *Handler) setOVHCredentialsFromForm(w http.ResponseWriter, r *http.Request) {
-	// Get form values using helper
-	applicationKey := getFormValue(r, "ovh_application_key")
-	applicationSecret := getFormValue(r, "ovh_application_secret")
-	consumerKey := getFormValue(r, "ovh_consumer_key")
-	serviceName := getFormValue(r, "ovh_service_name")
-	endpoint := getFormValue(r, "ovh_endpoint")
-
 	creds := &OVHCredentials{
-		ApplicationKey:    applicationKey,
-		ApplicationSecret: applicationSecret,
-		ConsumerKey:       consumerKey,
-		ServiceName:       serviceName,
-		Endpoint:          endpoint,
+		ApplicationKey:    getFormValue(r, "ovh_application_key"),
+		ApplicationSecret: getFormValue(r, "ovh_application_secret"),
+		ConsumerKey:       getFormValue(r, "ovh_consumer_key"),
+		ServiceName:       getFormValue(r, "ovh_service_name"),
+		Endpoint:          getFormValue(r, "ovh_endpoint"),
 	}
+	replaceAll := getFormValue(r, "ovh_replace_all") == "true"
 
-	if err := h.credentialsManager.SetCredentials(creds); err != nil {
-		log.Printf("Failed to set OVH credentials: %v", err)
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
+	changed, err := h.credentialsManager.UpdateOVHCredentials(creds, replaceAll)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to set OVH credentials", "error", err)
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
 			<div class="error-message">
 				<h3>Failed to Save Credentials</h3>
 				<p>%s</p>
 				<p><small>Please ensure all fields are filled correctly.</small></p>
-			</div>`, template.HTMLEscapeString(err.Error()))
+			</div>`, template.HTMLEscapeString(err.Error())))
 		return
 	}
 
-	log.Printf("OVH credentials saved successfully")
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprintf(w, `
+	slog.InfoContext(r.Context(), "OVH credentials saved", "changed", strings.Join(changed, ", "))
+	if len(changed) == 0 {
+		writeHTMLFragment(w, http.StatusOK, `
 		<div class="success-message">
-			<p>✅ OVH credentials saved successfully</p>
+			<p>✅ OVH credentials saved successfully — nothing changed</p>
 		</div>`)
+		return
+	}
+	writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
+		<div class="success-message">
+			<p>✅ OVH credentials saved successfully</p>
+			<p><small>Changed: %s</small></p>
+		</div>`, template.HTMLEscapeString(strings.Join(changed, ", "))))
 }
 
 // SetOVHCredentials handles setting OVH credentials (backward compatibility)
@@ -2229,20 +3204,18 @@ func (h *Handler) setAzureCredentialsFromForm(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := h.credentialsManager.SetCredentials(creds); err != nil {
-		log.Printf("Failed to set Azure credentials: %v", err)
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
+		slog.ErrorContext(r.Context(), "Failed to set Azure credentials", "error", err)
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
 			<div class="error-message">
 				<h3>Failed to Save Credentials</h3>
 				<p>%s</p>
 				<p><small>Please ensure all fields are filled correctly.</small></p>
-			</div>`, template.HTMLEscapeString(err.Error()))
+			</div>`, template.HTMLEscapeString(err.Error())))
 		return
 	}
 
-	log.Printf("Azure credentials saved successfully")
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprintf(w, `
+	slog.InfoContext(r.Context(), "Azure credentials saved successfully")
+	writeHTMLFragment(w, http.StatusOK, `
 		<div class="success-message">
 			<p>✅ Azure credentials saved successfully</p>
 		</div>`)
@@ -2261,57 +3234,69 @@ func (h *Handler) GetCredentials(w http.ResponseWriter, r *http.Request) {
 		provider = "ovh" // Default to OVH for backward compatibility
 	}
 
+	status, code := h.credentialsStatus(provider, r.URL.Query().Get("test") == "true")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
+
+// credentialsStatus describes the stored credentials of a provider, without
+// their secrets, along with the HTTP status to answer with. With test, the OVH
+// keys are also tried against the OVH API.
+func (h *Handler) credentialsStatus(provider string, test bool) (map[string]interface{}, int) {
 	creds, err := h.credentialsManager.GetCredentials(provider)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]interface{}{
+		return map[string]interface{}{
 			"configured": false,
 			"provider":   provider,
-		})
-		return
+		}, http.StatusNotFound
 	}
 
 	// Handle provider-specific response (return non-secret info only)
-	w.Header().Set("Content-Type", "application/json")
 	switch provider {
 	case "ovh":
 		ovhCreds, ok := creds.(*OVHCredentials)
 		if !ok {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid credentials type"})
-			return
+			return map[string]interface{}{"error": "Invalid credentials type"}, http.StatusInternalServerError
 		}
-		json.NewEncoder(w).Encode(map[string]interface{}{
+		status := map[string]interface{}{
 			"configured":          true,
 			"provider":            provider,
 			"service_name":        ovhCreds.ServiceName,
 			"endpoint":            ovhCreds.Endpoint,
 			"has_application_key": ovhCreds.ApplicationKey != "",
 			"has_consumer_key":    ovhCreds.ConsumerKey != "",
-		})
+		}
+		// test also calls the OVH API: listing where gateways are sold proves
+		// the keys work and shows which regions can host a lab.
+		if test {
+			if avail, err := h.gatewayAvailability(); err != nil {
+				status["connection"] = "failed: " + err.Error()
+			} else {
+				status["connection"] = "OK"
+				status["gateway_regions"] = len(avail)
+			}
+		}
+		return status, http.StatusOK
 	case "azure":
 		azCreds, ok := creds.(*AzureCredentials)
 		if !ok {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid credentials type"})
-			return
+			return map[string]interface{}{"error": "Invalid credentials type"}, http.StatusInternalServerError
 		}
-		json.NewEncoder(w).Encode(map[string]interface{}{
+		return map[string]interface{}{
 			"configured":        true,
 			"provider":          provider,
 			"subscription_id":   azCreds.SubscriptionID,
 			"tenant_id":         azCreds.TenantID,
 			"has_client_id":     azCreds.ClientID != "",
 			"has_client_secret": azCreds.ClientSecret != "",
-		})
+		}, http.StatusOK
 	default:
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]interface{}{
+		return map[string]interface{}{
 			"configured": false,
 			"provider":   provider,
 			"error":      "Provider not supported",
-		})
+		}, http.StatusNotFound
 	}
 }
 
@@ -2388,9 +3373,8 @@ func (h *Handler) SaveOVHOptions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := r.ParseForm(); err != nil {
-		log.Printf("SaveOVHOptions: failed to parse form: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<div class="error-message"><p>Failed to parse form</p></div>`)
+		slog.WarnContext(r.Context(), "SaveOVHOptions: failed to parse form", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<div class="error-message"><p>Failed to parse form</p></div>`)
 		return
 	}
 
@@ -2422,16 +3406,14 @@ func (h *Handler) SaveOVHOptions(w http.ResponseWriter, r *http.Request) {
 
 	h.ovhOptionsManager.SetConfig(cfg)
 	if err := h.ovhOptionsManager.SaveConfig(); err != nil {
-		log.Printf("SaveOVHOptions: failed to save config: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprintf(w, `<div class="error-message"><p>Failed to save: %s</p></div>`, escapeHTML(err.Error()))
+		slog.ErrorContext(r.Context(), "SaveOVHOptions: failed to save config", "error", err)
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="error-message"><p>Failed to save: %s</p></div>`, escapeHTML(err.Error())))
 		return
 	}
 
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("HX-Redirect", "/admin/ovh-options")
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<div class="success-message"><p>OVH options saved successfully</p></div>`)
+		writeHTMLFragment(w, http.StatusOK, `<div class="success-message"><p>OVH options saved successfully</p></div>`)
 	} else {
 		http.Redirect(w, r, "/admin/ovh-options", http.StatusSeeOther)
 	}
@@ -2445,15 +3427,13 @@ func (h *Handler) RefreshOVHOptions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.ovhOptionsManager.RefreshFromAPI(); err != nil {
-		log.Printf("RefreshOVHOptions: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprintf(w, `<div class="error-message"><p>Failed to refresh: %s</p></div>`, escapeHTML(err.Error()))
+		slog.ErrorContext(r.Context(), "RefreshOVHOptions failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="error-message"><p>Failed to refresh: %s</p></div>`, escapeHTML(err.Error())))
 		return
 	}
 
 	w.Header().Set("HX-Redirect", "/admin/ovh-options")
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprint(w, `<div class="success-message"><p>Cache refreshed successfully. Reloading...</p></div>`)
+	writeHTMLFragment(w, http.StatusOK, `<div class="success-message"><p>Cache refreshed successfully. Reloading...</p></div>`)
 }
 
 // ServeAzureOptions serves the Azure options admin page.
@@ -2548,8 +3528,7 @@ func (h *Handler) SaveAzureADConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := r.ParseForm(); err != nil {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<div class="error-message"><p>Failed to parse form</p></div>`)
+		writeHTMLFragment(w, http.StatusOK, `<div class="error-message"><p>Failed to parse form</p></div>`)
 		return
 	}
 
@@ -2587,9 +3566,8 @@ func (h *Handler) SaveAzureADConfig(w http.ResponseWriter, r *http.Request) {
 
 	if h.azureOptionsManager != nil {
 		if err := h.azureOptionsManager.SetAzureADConfig(cfg); err != nil {
-			log.Printf("SaveAzureADConfig: failed to persist: %v", err)
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			fmt.Fprintf(w, `<div class="error-message"><p>Failed to save Azure AD config: %s</p></div>`, escapeHTML(err.Error()))
+			slog.ErrorContext(r.Context(), "SaveAzureADConfig: failed to persist", "error", err)
+			writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="error-message"><p>Failed to save Azure AD config: %s</p></div>`, escapeHTML(err.Error())))
 			return
 		}
 	}
@@ -2609,8 +3587,7 @@ func (h *Handler) SaveAzureADConfig(w http.ResponseWriter, r *http.Request) {
 
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("HX-Redirect", "/admin/azure-ad")
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<div class="success-message"><p>Azure AD configuration saved</p></div>`)
+		writeHTMLFragment(w, http.StatusOK, `<div class="success-message"><p>Azure AD configuration saved</p></div>`)
 	} else {
 		http.Redirect(w, r, "/admin/azure-ad", http.StatusSeeOther)
 	}
@@ -2628,9 +3605,8 @@ func (h *Handler) SaveAzureOptions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := r.ParseForm(); err != nil {
-		log.Printf("SaveAzureOptions: failed to parse form: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<div class="error-message"><p>Failed to parse form</p></div>`)
+		slog.WarnContext(r.Context(), "SaveAzureOptions: failed to parse form", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<div class="error-message"><p>Failed to parse form</p></div>`)
 		return
 	}
 
@@ -2657,16 +3633,14 @@ func (h *Handler) SaveAzureOptions(w http.ResponseWriter, r *http.Request) {
 
 	h.azureOptionsManager.SetConfig(cfg)
 	if err := h.azureOptionsManager.SaveConfig(); err != nil {
-		log.Printf("SaveAzureOptions: failed to save config: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprintf(w, `<div class="error-message"><p>Failed to save: %s</p></div>`, escapeHTML(err.Error()))
+		slog.ErrorContext(r.Context(), "SaveAzureOptions: failed to save config", "error", err)
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="error-message"><p>Failed to save: %s</p></div>`, escapeHTML(err.Error())))
 		return
 	}
 
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("HX-Redirect", "/admin/azure-options")
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<div class="success-message"><p>Azure options saved successfully</p></div>`)
+		writeHTMLFragment(w, http.StatusOK, `<div class="success-message"><p>Azure options saved successfully</p></div>`)
 	} else {
 		http.Redirect(w, r, "/admin/azure-options", http.StatusSeeOther)
 	}
@@ -2684,15 +3658,13 @@ func (h *Handler) RefreshAzureOptions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.azureOptionsManager.RefreshFromAPI(); err != nil {
-		log.Printf("RefreshAzureOptions: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprintf(w, `<div class="error-message"><p>Failed to refresh: %s</p></div>`, escapeHTML(err.Error()))
+		slog.ErrorContext(r.Context(), "RefreshAzureOptions failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="error-message"><p>Failed to refresh: %s</p></div>`, escapeHTML(err.Error())))
 		return
 	}
 
 	w.Header().Set("HX-Redirect", "/admin/azure-options")
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprint(w, `<div class="success-message"><p>Cache refreshed successfully. Reloading...</p></div>`)
+	writeHTMLFragment(w, http.StatusOK, `<div class="success-message"><p>Cache refreshed successfully. Reloading...</p></div>`)
 }
 
 // GetOVHCredentials handles getting OVH credentials status (backward compatibility)
@@ -2754,8 +3726,10 @@ func (h *Handler) ListProviders(w http.ResponseWriter, r *http.Request) {
 
 // ServeLabsList serves the labs list page
 func (h *Handler) ServeLabsList(w http.ResponseWriter, r *http.Request) {
-	// Get all jobs (labs)
-	allJobs := h.jobManager.GetAllJobs()
+	// Get all jobs (labs), or those of the event picked in the filter
+	selectedEvent := r.URL.Query().Get("event")
+	events := labEvents(h.jobManager.GetLabs(), selectedEvent)
+	allJobs := filterJobsByEvent(h.jobManager.GetLabs(), selectedEvent)
 
 	// Helper function to shorten lab ID
 	shortenLabID := func(id string) string {
@@ -2773,6 +3747,7 @@ func (h *Handler) ServeLabsList(w http.ResponseWriter, r *http.Request) {
 		CreatedAt                 string
 		UpdatedAt                 string
 		StackName                 string
+		Event                     string
 		IsDryRun                  bool
 		HasError                  bool
 		ErrorMsg                  string
@@ -2782,6 +3757,7 @@ func (h *Handler) ServeLabsList(w http.ResponseWriter, r *http.Request) {
 		LabDeletionDate           string
 		HasDeletionDate           bool
 		WorkspaceTemplateNamesCSV string
+		Protected                 bool
 	}
 
 	labsDisplay := make([]LabDisplay, 0, len(allJobs))
@@ -2792,6 +3768,7 @@ func (h *Handler) ServeLabsList(w http.ResponseWriter, r *http.Request) {
 		if job.Config != nil {
 			stackName = job.Config.StackName
 		}
+		event := jobEvent(job)
 		isDryRun := job.Status == JobStatusDryRunCompleted
 		hasError := job.Error != ""
 		errorMsg := job.Error
@@ -2815,6 +3792,7 @@ func (h *Handler) ServeLabsList(w http.ResponseWriter, r *http.Request) {
 				templateNames = append(templateNames, t.Name)
 			}
 		}
+		protected := job.Protected
 		job.mu.RUnlock()
 
 		labsDisplay = append(labsDisplay, LabDisplay{
@@ -2824,6 +3802,7 @@ func (h *Handler) ServeLabsList(w http.ResponseWriter, r *http.Request) {
 			CreatedAt:                 createdAt,
 			UpdatedAt:                 updatedAt,
 			StackName:                 stackName,
+			Event:                     event,
 			IsDryRun:                  isDryRun,
 			HasError:                  hasError,
 			ErrorMsg:                  errorMsg,
@@ -2833,12 +3812,40 @@ func (h *Handler) ServeLabsList(w http.ResponseWriter, r *http.Request) {
 			LabDeletionDate:           labDeletionDate,
 			HasDeletionDate:           hasLabDeletionDate,
 			WorkspaceTemplateNamesCSV: strings.Join(templateNames, ", "),
+			Protected:                 protected,
 		})
 	}
 
+	// Template jobs are listed apart, as configs to create labs from.
+	type TemplateDisplay struct {
+		ID        string
+		IDShort   string
+		StackName string
+		Event     string
+		Provider  string
+		UpdatedAt string
+	}
+	var templatesDisplay []TemplateDisplay
+	for _, job := range h.jobManager.GetTemplates() {
+		job.mu.RLock()
+		t := TemplateDisplay{ID: job.ID, IDShort: shortenLabID(job.ID), Event: jobEvent(job), UpdatedAt: job.UpdatedAt.Format("2006-01-02 15:04:05")}
+		if job.Config != nil {
+			t.StackName = job.Config.StackName
+			t.Provider = job.Config.Provider
+			if job.Config.UseExistingCluster {
+				t.Provider = "existing cluster"
+			}
+		}
+		job.mu.RUnlock()
+		templatesDisplay = append(templatesDisplay, t)
+	}
+
 	data := map[string]interface{}{
-		"Labs":  labsDisplay,
-		"Count": len(labsDisplay),
+		"Labs":          labsDisplay,
+		"Count":         len(labsDisplay),
+		"Events":        events,
+		"SelectedEvent": selectedEvent,
+		"Templates":     templatesDisplay,
 	}
 
 	h.serveTemplate(w, "labs-list.html", data)
@@ -2939,14 +3946,14 @@ func (h *Handler) ServeLabWorkspaces(w http.ResponseWriter, r *http.Request) {
 
 	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 	if err != nil {
-		log.Printf("ServeLabWorkspaces: failed to build backend for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "ServeLabWorkspaces: failed to build backend for lab", "job_id", labID, "error", err)
 		http.Error(w, "Failed to reach lab cluster", http.StatusInternalServerError)
 		return
 	}
 
 	workspaces, err := backend.ListWorkspaces(r.Context(), labID)
 	if err != nil {
-		log.Printf("Failed to list workspaces: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to list workspaces", "error", err)
 		http.Error(w, "Failed to list workspaces", http.StatusInternalServerError)
 		return
 	}
@@ -3031,14 +4038,14 @@ func (h *Handler) ListLabWorkspaces(w http.ResponseWriter, r *http.Request) {
 
 	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 	if err != nil {
-		log.Printf("ListLabWorkspaces: failed to build backend for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "ListLabWorkspaces: failed to build backend for lab", "job_id", labID, "error", err)
 		http.Error(w, "Failed to reach lab cluster", http.StatusInternalServerError)
 		return
 	}
 
 	workspaces, err := backend.ListWorkspaces(r.Context(), labID)
 	if err != nil {
-		log.Printf("Failed to list workspaces: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to list workspaces", "error", err)
 		http.Error(w, "Failed to list workspaces", http.StatusInternalServerError)
 		return
 	}
@@ -3066,7 +4073,7 @@ func (h *Handler) DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		log.Printf("Failed to parse form: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse form", "error", err)
 		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Failed to parse form: %v", err))
 		return
 	}
@@ -3101,7 +4108,7 @@ func (h *Handler) DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
 
 		backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 		if err != nil {
-			log.Printf("DeleteWorkspace: failed to build backend for lab %s: %v", labID, err)
+			slog.ErrorContext(r.Context(), "DeleteWorkspace: failed to build backend for lab", "job_id", labID, "error", err)
 			writeJSONError(w, http.StatusInternalServerError, "Failed to reach lab cluster")
 			return
 		}
@@ -3170,13 +4177,13 @@ func (h *Handler) DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
 
 	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 	if err != nil {
-		log.Printf("DeleteWorkspace: failed to build backend for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "DeleteWorkspace: failed to build backend for lab", "job_id", labID, "error", err)
 		writeJSONError(w, http.StatusInternalServerError, "Failed to reach lab cluster")
 		return
 	}
 
 	if err := backend.DeleteWorkspace(r.Context(), labID, workspaceIDStr); err != nil {
-		log.Printf("Failed to delete workspace: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to delete workspace", "error", err)
 		writeJSONError(w, http.StatusInternalServerError, "Failed to delete workspace")
 		return
 	}
@@ -3197,7 +4204,7 @@ func (h *Handler) DestroyStack(w http.ResponseWriter, r *http.Request) {
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		log.Printf("Failed to parse form: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse form", "error", err)
 		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
 		return
 	}
@@ -3208,11 +4215,21 @@ func (h *Handler) DestroyStack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the job
+	if reqErr := h.destroyLab(jobID); reqErr != nil {
+		h.renderRequestError(w, reqErr)
+		return
+	}
+
+	// Redirect to admin page to view destroy progress (like CreateLab)
+	http.Redirect(w, r, fmt.Sprintf("/admin?job=%s", jobID), http.StatusSeeOther)
+}
+
+// destroyLab starts destroying the stack of a lab. The job is kept, marked as
+// destroyed once Pulumi is done.
+func (h *Handler) destroyLab(jobID string) *requestError {
 	job, exists := h.jobManager.GetJob(jobID)
 	if !exists {
-		http.Error(w, "Job not found", http.StatusNotFound)
-		return
+		return &requestError{Status: http.StatusNotFound, Title: "Job Not Found", Message: "Job not found", Plain: true}
 	}
 
 	// Check if job has a stack name
@@ -3224,43 +4241,42 @@ func (h *Handler) DestroyStack(w http.ResponseWriter, r *http.Request) {
 	job.mu.RUnlock()
 
 	if stackName == "" {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
-			<div class="error-message">
-				<h3>No Stack Associated</h3>
-				<p>This job does not have an associated stack to destroy.</p>
-			</div>`)
-		return
+		return &requestError{Status: http.StatusBadRequest, Title: "No Stack Associated", Message: "This job does not have an associated stack to destroy."}
+	}
+
+	if err := h.jobManager.checkNotProtected(jobID); err != nil {
+		return &requestError{Status: http.StatusConflict, Title: "Lab Protected", Message: err.Error(), Plain: true}
+	}
+	if err := h.jobManager.checkNotTemplate(jobID); err != nil {
+		return &requestError{Status: http.StatusConflict, Title: "Template Job", Message: err.Error(), Plain: true}
 	}
 
 	// Start destruction in a goroutine
 	go func() {
-		log.Printf("Starting stack destruction for job: %s, stack: %s", jobID, stackName)
+		slog.Info("Starting stack destruction", "job_id", jobID, "stack", stackName)
 		if err := h.pulumiExec.Destroy(jobID); err != nil {
-			log.Printf("Stack destruction failed for job %s: %v", jobID, err)
+			slog.Error("Stack destruction failed for job", "job_id", jobID, "error", err)
 			h.jobManager.SetError(jobID, fmt.Errorf("destroy failed: %w", err))
 			// Persist failed job to disk
 			if saveErr := h.jobManager.SaveJob(jobID); saveErr != nil {
-				log.Printf("Warning: failed to persist failed job %s: %v", jobID, saveErr)
+				slog.Warn("Failed to persist failed job", "job_id", jobID, "error", saveErr)
 				// Don't fail the job if persistence fails
 			}
 			return
 		}
-		log.Printf("Stack destruction completed for job: %s", jobID)
+		slog.Info("Stack destruction completed for job", "job_id", jobID)
 
 		// Mark job as destroyed instead of removing it
 		if err := h.jobManager.UpdateJobStatus(jobID, JobStatusDestroyed); err != nil {
-			log.Printf("Warning: failed to mark job %s as destroyed: %v", jobID, err)
+			slog.Warn("Failed to mark job as destroyed", "job_id", jobID, "error", err)
 		} else {
 			// Persist the destroyed job
 			if err := h.jobManager.SaveJob(jobID); err != nil {
-				log.Printf("Warning: failed to persist destroyed job %s: %v", jobID, err)
+				slog.Warn("Failed to persist destroyed job", "job_id", jobID, "error", err)
 			}
 		}
 	}()
-
-	// Redirect to admin page to view destroy progress (like CreateLab)
-	http.Redirect(w, r, fmt.Sprintf("/admin?job=%s", jobID), http.StatusSeeOther)
+	return nil
 }
 
 // parseRecreateDeletionDate reads the deletion schedule the admin entered in the
@@ -3300,10 +4316,13 @@ func (h *Handler) RecreateLab(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if h.rejectDuringMaintenance(w) {
+		return
+	}
 
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		log.Printf("Failed to parse form: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse form", "error", err)
 		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
 		return
 	}
@@ -3328,19 +4347,17 @@ func (h *Handler) RecreateLab(w http.ResponseWriter, r *http.Request) {
 	job.mu.RUnlock()
 
 	if status != JobStatusDestroyed {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
 			<div class="error-message">
 				<h3>Invalid Job Status</h3>
 				<p>This job is not destroyed. Current status: %s</p>
 				<p>Only destroyed jobs can be recreated.</p>
-			</div>`, status)
+			</div>`, status))
 		return
 	}
 
 	if config == nil {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
+		writeHTMLFragment(w, http.StatusOK, `
 			<div class="error-message">
 				<h3>No Configuration Available</h3>
 				<p>This job does not have configuration data available for recreation.</p>
@@ -3355,7 +4372,7 @@ func (h *Handler) RecreateLab(w http.ResponseWriter, r *http.Request) {
 	if config.LabDeletionDate != nil {
 		newDeletion, err := parseRecreateDeletionDate(r)
 		if err != nil {
-			log.Printf("Invalid recreate deletion date for job %s: %v", jobID, err)
+			slog.WarnContext(r.Context(), "Invalid recreate deletion date for job", "job_id", jobID, "error", err)
 			h.renderHTMLError(w, "Invalid Deletion Date", err.Error())
 			return
 		}
@@ -3368,12 +4385,13 @@ func (h *Handler) RecreateLab(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			return
 		}
+		// A lab OVH refused the credentials of only runs again with new ones.
+		if err := checkRetryCredentials(job, ovhCreds); err != nil {
+			h.renderHTMLError(w, "Credentials Unchanged", err.Error(), `<a href="/credentials?provider=ovh" class="btn btn-primary">Update OVH Credentials</a>`)
+			return
+		}
 		// Update config with current credentials (in case they changed)
-		config.OvhApplicationKey = ovhCreds.ApplicationKey
-		config.OvhApplicationSecret = ovhCreds.ApplicationSecret
-		config.OvhConsumerKey = ovhCreds.ConsumerKey
-		config.OvhServiceName = ovhCreds.ServiceName
-		config.OvhEndpoint = ovhCreds.Endpoint
+		config.setOVHCredentials(ovhCreds)
 	}
 
 	// Tokens the admin re-entered in the recreate prompt. The old cluster's
@@ -3382,7 +4400,7 @@ func (h *Handler) RecreateLab(w http.ResponseWriter, r *http.Request) {
 	// lab behind.
 	recreateSecrets, err := parseWizardSecrets(r)
 	if err != nil {
-		log.Printf("Invalid recreate credentials: %v", err)
+		slog.WarnContext(r.Context(), "Invalid recreate credentials", "error", err)
 		h.renderHTMLError(w, "Invalid Credentials", err.Error())
 		return
 	}
@@ -3390,25 +4408,25 @@ func (h *Handler) RecreateLab(w http.ResponseWriter, r *http.Request) {
 	// Create new job with the same configuration
 	newJobID := h.jobManager.CreateJob(config)
 	h.pendingSecrets.Put(newJobID, recreateSecrets)
-	log.Printf("Recreating lab from destroyed job %s as new job: %s", jobID, newJobID)
+	slog.InfoContext(r.Context(), "Recreating lab from a destroyed job", "source_job_id", jobID, "job_id", newJobID)
 
 	// Prepare the new job directory. Workspace templates are pure configuration,
 	// so there are no template files to copy over.
 	newJobDir := filepath.Join(h.pulumiExec.GetWorkDir(), newJobID)
 	if err := os.MkdirAll(newJobDir, 0755); err != nil {
-		log.Printf("Failed to create new job directory for %s: %v", newJobID, err)
+		slog.ErrorContext(r.Context(), "Failed to create new job directory", "job_id", newJobID, "error", err)
 		http.Error(w, "Failed to prepare job directory", http.StatusInternalServerError)
 		return
 	}
 
 	// Start Pulumi execution in a goroutine
 	go func() {
-		log.Printf("Starting Pulumi execution for recreated job: %s", newJobID)
+		slog.InfoContext(r.Context(), "Starting Pulumi execution for recreated job", "job_id", newJobID)
 		if err := h.pulumiExec.Execute(newJobID); err != nil {
-			log.Printf("Pulumi execution failed for recreated job %s: %v", newJobID, err)
+			slog.ErrorContext(r.Context(), "Pulumi execution failed for recreated job", "job_id", newJobID, "error", err)
 			return
 		}
-		log.Printf("Pulumi execution completed for recreated job: %s", newJobID)
+		slog.InfoContext(r.Context(), "Pulumi execution completed for recreated job", "job_id", newJobID)
 	}()
 
 	// Redirect to admin page to view recreation progress (like CreateLab)
@@ -3421,21 +4439,24 @@ func (h *Handler) RetryJob(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if h.rejectDuringMaintenance(w) {
+		return
+	}
 
 	// Extract job ID from path like /api/jobs/{id}/retry or /api/labs/{id}/retry
 	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(pathParts) < 4 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "retry" {
-		log.Printf("Invalid path for job retry: %s", r.URL.Path)
+		slog.WarnContext(r.Context(), "Invalid path for job retry", "path", r.URL.Path)
 		http.Error(w, "Invalid path", http.StatusBadRequest)
 		return
 	}
 	jobID := pathParts[2]
-	log.Printf("RetryJob called for job: %s", jobID)
+	slog.DebugContext(r.Context(), "RetryJob called for job", "job_id", jobID)
 
 	// Get the job
 	job, exists := h.jobManager.GetJob(jobID)
 	if !exists {
-		log.Printf("Job not found: %s", jobID)
+		slog.WarnContext(r.Context(), "Job not found", "job_id", jobID)
 		http.Error(w, "Job not found", http.StatusNotFound)
 		return
 	}
@@ -3444,22 +4465,26 @@ func (h *Handler) RetryJob(w http.ResponseWriter, r *http.Request) {
 	job.mu.RLock()
 	status := job.Status
 	config := job.Config
+	isTemplate := job.IsTemplate
+	credentialsRejected := job.RejectedCredentials != ""
 	job.mu.RUnlock()
 
+	if isTemplate {
+		http.Error(w, templateJobError(jobID).Error(), http.StatusConflict)
+		return
+	}
 	if status != JobStatusFailed {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
 			<div class="error-message">
 				<h3>Invalid Job Status</h3>
 				<p>This job is not in failed status. Current status: %s</p>
 				<p>Only failed jobs can be retried.</p>
-			</div>`, status)
+			</div>`, status))
 		return
 	}
 
 	if config == nil {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
+		writeHTMLFragment(w, http.StatusOK, `
 			<div class="error-message">
 				<h3>No Configuration Available</h3>
 				<p>This job does not have configuration data available for retry.</p>
@@ -3473,23 +4498,23 @@ func (h *Handler) RetryJob(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			return
 		}
+		// A lab OVH refused the credentials of only runs again with new ones.
+		if err := checkRetryCredentials(job, ovhCreds); err != nil {
+			h.renderHTMLError(w, "Credentials Unchanged", err.Error(), `<a href="/credentials?provider=ovh" class="btn btn-primary">Update OVH Credentials</a>`)
+			return
+		}
 		// Update config with current credentials (in case they changed)
-		config.OvhApplicationKey = ovhCreds.ApplicationKey
-		config.OvhApplicationSecret = ovhCreds.ApplicationSecret
-		config.OvhConsumerKey = ovhCreds.ConsumerKey
-		config.OvhServiceName = ovhCreds.ServiceName
-		config.OvhEndpoint = ovhCreds.Endpoint
+		config.setOVHCredentials(ovhCreds)
 	}
 
 	// Reset job for retry
 	if err := h.jobManager.ResetJobForRetry(jobID); err != nil {
-		log.Printf("Failed to reset job for retry: %v", err)
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
+		slog.ErrorContext(r.Context(), "Failed to reset job for retry", "error", err)
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
 			<div class="error-message">
 				<h3>Failed to Reset Job</h3>
 				<p>%s</p>
-			</div>`, template.HTMLEscapeString(err.Error()))
+			</div>`, template.HTMLEscapeString(err.Error())))
 		return
 	}
 
@@ -3500,26 +4525,28 @@ func (h *Handler) RetryJob(w http.ResponseWriter, r *http.Request) {
 
 	// Add retry message to output
 	h.jobManager.AppendOutput(jobID, fmt.Sprintf("Retrying job at %s", time.Now().Format(time.RFC3339)))
+	if credentialsRejected {
+		h.jobManager.AppendOutput(jobID, "Retrying with the updated OVH credentials.")
+	}
 
 	// Start Pulumi execution in a goroutine using retry-optimized path
 	go func() {
-		log.Printf("Starting Pulumi execution for retried job: %s", jobID)
-		if err := h.pulumiExec.ExecuteRetry(jobID); err != nil {
-			log.Printf("Pulumi execution failed for retried job %s: %v", jobID, err)
+		slog.InfoContext(r.Context(), "Starting Pulumi execution for retried job", "job_id", jobID)
+		if err := h.executeRetry(jobID); err != nil {
+			slog.ErrorContext(r.Context(), "Pulumi execution failed for retried job", "job_id", jobID, "error", err)
 			return
 		}
-		log.Printf("Pulumi execution completed for retried job: %s", jobID)
+		slog.InfoContext(r.Context(), "Pulumi execution completed for retried job", "job_id", jobID)
 	}()
 
 	// Return job status div for HTMX to display with proper polling
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprintf(w, `
+	writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
 		<div class="job-created">
 			<h3>Job Retried: %s</h3>
 			<div id="job-status" hx-get="/api/jobs/%s/status" hx-trigger="load, every 10s" hx-swap="innerHTML">
 				<p>Loading status...</p>
 			</div>
-		</div>`, jobID, jobID)
+		</div>`, jobID, jobID))
 }
 
 // buildWorkspaceName creates a valid Coder workspace name from lab name, labID,
@@ -3621,15 +4648,22 @@ func (h *Handler) DeleteLab(w http.ResponseWriter, r *http.Request) {
 
 	job.mu.RLock()
 	status := job.Status
+	isTemplate := job.IsTemplate
 	job.mu.RUnlock()
 
-	if status != JobStatusDestroyed && status != JobStatusFailed {
+	// A template holds no cluster whatever its status, so it can always go.
+	if !isTemplate && status != JobStatusDestroyed && status != JobStatusFailed {
 		http.Error(w, "Lab can only be removed when destroyed or failed", http.StatusBadRequest)
 		return
 	}
 
+	if err := h.jobManager.checkNotProtected(labID); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
 	if err := h.jobManager.RemoveJob(labID); err != nil {
-		log.Printf("Failed to remove lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "Failed to remove lab", "job_id", labID, "error", err)
 		http.Error(w, fmt.Sprintf("Failed to remove lab: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -3663,7 +4697,7 @@ func (h *Handler) DetectTemplateVariables(w http.ResponseWriter, r *http.Request
 	}
 
 	if err != nil {
-		log.Printf("Failed to detect template variables: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to detect template variables", "error", err)
 		writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("failed to detect variables: %v", err))
 		return
 	}
@@ -3761,7 +4795,7 @@ func (h *Handler) detectVariablesFromGit(r *http.Request) ([]tfparse.TFVariable,
 		}
 		vars, parseErr := tfparse.ParseVariablesFromFile(filepath.Join(targetDir, entry.Name()))
 		if parseErr != nil {
-			log.Printf("Warning: failed to parse %s: %v", entry.Name(), parseErr)
+			slog.WarnContext(r.Context(), "Failed to parse a template file", "file", entry.Name(), "error", parseErr)
 			continue
 		}
 		for _, v := range vars {
@@ -3958,6 +4992,6 @@ func (h *Handler) GetProjectStats(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("failed to encode stats response: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to encode stats response", "error", err)
 	}
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"easylab/internal/providers/workspace"
+	"easylab/utils"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -245,6 +247,26 @@ func TestHandler_GetJobStatus_Found(t *testing.T) {
 	if !strings.Contains(w.Body.String(), "pending") {
 		t.Error("GetJobStatus() response should contain 'pending' status")
 	}
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+func TestWriteHTMLFragment(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeHTMLFragment(w, http.StatusServiceUnavailable, `<p>Café fermé</p>`)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `<p>Café fermé</p>`, w.Body.String())
+}
+
+func TestHandler_RenderHTMLError_Charset(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	w := httptest.NewRecorder()
+	h.renderHTMLError(w, "Échec", "<script>", `<a href="/">Back</a>`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `<div class="error-message"><h3>Échec</h3><p>&lt;script&gt;</p><a href="/">Back</a></div>`, w.Body.String())
 }
 
 func TestHandler_GetJobStatusJSON_InvalidPath(t *testing.T) {
@@ -295,6 +317,75 @@ func TestHandler_GetJobStatusJSON_Found(t *testing.T) {
 	}
 }
 
+func TestHandler_GetJobHistory_Paginates(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "test"})
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	jm.SetError(jobID, errors.New("boom"))
+	jm.ResetJobForRetry(jobID)
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	get := func(query string) (*httptest.ResponseRecorder, jobHistoryPage) {
+		w := httptest.NewRecorder()
+		h.GetJobHistory(w, httptest.NewRequest("GET", "/api/labs/"+jobID+"/history"+query, nil))
+		var page jobHistoryPage
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		}
+		return w, page
+	}
+
+	w, page := get("?offset=1&limit=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, 5, page.Total)
+	if assert.Len(t, page.History, 2) {
+		assert.Equal(t, JobStatusRunning, page.History[0].Status)
+		assert.Equal(t, JobStatusFailed, page.History[1].Status)
+		assert.Equal(t, "boom", page.History[1].Reason)
+	}
+
+	_, page = get("")
+	assert.Equal(t, defaultHistoryPageSize, page.Limit)
+	assert.Len(t, page.History, 5)
+
+	_, page = get("?offset=10")
+	assert.NotNil(t, page.History, "a page past the end is empty, not null")
+	assert.Empty(t, page.History)
+
+	w, _ = get("?limit=0")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = get("?offset=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h.GetJobHistory(w, httptest.NewRequest("GET", "/api/labs/missing/history", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_GrafanaPassword_OnlyOnLabPage(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "test", InstallMonitoring: true, GrafanaAdminPassword: "s3cret"})
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+	require.NoError(t, jm.SetGrafanaURL(jobID, "https://grafana.lab.example.com"))
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.GetJobStatusJSON(w, httptest.NewRequest(http.MethodGet, "/api/jobs/"+jobID, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "s3cret")
+	assert.Contains(t, w.Body.String(), `"grafana_admin_password":"REDACTED"`)
+	assert.Contains(t, w.Body.String(), `"grafana_url":"https://grafana.lab.example.com"`)
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, "s3cret", job.Config.GrafanaAdminPassword, "redacting must not touch the stored config")
+
+	w = httptest.NewRecorder()
+	h.GetJobStatus(w, httptest.NewRequest(http.MethodGet, "/api/jobs/"+jobID+"/status", nil))
+	assert.Contains(t, w.Body.String(), "https://grafana.lab.example.com")
+	assert.Contains(t, w.Body.String(), "s3cret")
+}
+
 func TestHandler_DownloadKubeconfig_InvalidPath(t *testing.T) {
 	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
 
@@ -464,6 +555,28 @@ func TestHandler_ListLabs(t *testing.T) {
 	}
 }
 
+func TestHandler_ListLabs_HidesTheClusterFromStudents(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "workshop", OvhApplicationSecret: "secret"})
+	jm.SetKubeconfig(jobID, "apiVersion: v1")
+	jm.SetClusterInfo(jobID, &ClusterInfo{URL: "https://abc123.c1.gra9.k8s.ovh.net"})
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.ListLabs(w, httptest.NewRequest("GET", "/api/student/labs", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, `"id":"`+jobID+`"`) || !strings.Contains(body, `"stack_name":"workshop"`) {
+		t.Errorf("ListLabs() = %s, want the lab's id and name", body)
+	}
+	for _, hidden := range []string{"k8s.ovh.net", "apiVersion", "secret"} {
+		if strings.Contains(body, hidden) {
+			t.Errorf("ListLabs() = %s, leaks %q", body, hidden)
+		}
+	}
+}
+
 func TestHandler_RequestWorkspace_WrongMethod(t *testing.T) {
 	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
 
@@ -638,8 +751,29 @@ func TestHandler_ServeStatic_DirectoryTraversal(t *testing.T) {
 
 	h.ServeStatic(w, req)
 
-	if w.Code != http.StatusForbidden {
-		t.Errorf("ServeStatic() status = %d, want %d for directory traversal", w.Code, http.StatusForbidden)
+	// Only static/ can be reached: the path resolves inside it, to nothing.
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ServeStatic() status = %d, want %d for directory traversal", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandler_ServeStatic_Embedded(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	req := httptest.NewRequest("GET", "/static/admin.js", nil)
+	w := httptest.NewRecorder()
+	h.ServeStatic(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeStatic() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, "javascript") {
+		t.Errorf("Content-Type = %q, want JavaScript", ct)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeStatic(w, httptest.NewRequest("GET", "/static/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ServeStatic() status = %d, want %d: no directory listing", w.Code, http.StatusNotFound)
 	}
 }
 
@@ -884,7 +1018,7 @@ func TestEncryptWorkspacePassword(t *testing.T) {
 }
 
 func TestHandler_LaunchLab_DryRunCompleted(t *testing.T) {
-	jm := NewJobManager(t.TempDir())
+	jm := newTestJobManager(t, t.TempDir())
 	pe := NewPulumiExecutor(jm, t.TempDir())
 	id := jm.CreateJob(&LabConfig{StackName: "test"})
 	jm.UpdateJobStatus(id, JobStatusDryRunCompleted)
@@ -1042,7 +1176,9 @@ func TestHandler_ServeLabsList(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	h.ServeLabsList(w, req)
-	// Template loading will fail in tests (no web/ dir), but the data-building code is exercised.
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeLabsList() status = %d, want %d", w.Code, http.StatusOK)
+	}
 }
 
 func TestHandler_ServeUI_Root(t *testing.T) {
@@ -1051,7 +1187,9 @@ func TestHandler_ServeUI_Root(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	h.ServeUI(w, req)
-	// Template will fail to load (no web/ dir), but the root-path branch is covered.
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeUI() status = %d, want %d", w.Code, http.StatusOK)
+	}
 }
 
 func TestHandler_SetAzureADConfigurer(t *testing.T) {
@@ -1156,7 +1294,8 @@ func TestCreateLabConfigFromForm_BasicOVH(t *testing.T) {
 		ServiceName:       "service",
 		Endpoint:          "ovh-eu",
 	}
-	cfg := h.createLabConfigFromForm(req, creds)
+	cfg, err := h.createLabConfigFromForm(req, creds)
+	require.NoError(t, err)
 	if cfg.StackName != "my-stack" {
 		t.Errorf("StackName = %q, want my-stack", cfg.StackName)
 	}
@@ -1173,7 +1312,8 @@ func TestCreateLabConfigFromForm_BYOK(t *testing.T) {
 		"use_existing_cluster": {"true"},
 		"template_0_name":      {"tmpl"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	if !cfg.UseExistingCluster {
 		t.Error("UseExistingCluster should be true for BYOK")
 	}
@@ -1194,7 +1334,8 @@ func TestCreateLabConfigFromForm_AzureProvider(t *testing.T) {
 		TenantID:       "tenant",
 		SubscriptionID: "sub",
 	}
-	cfg := h.createLabConfigFromForm(req, creds)
+	cfg, err := h.createLabConfigFromForm(req, creds)
+	require.NoError(t, err)
 	if cfg.AzureClientID != "client" {
 		t.Errorf("AzureClientID = %q, want client", cfg.AzureClientID)
 	}
@@ -1209,7 +1350,8 @@ func TestCreateLabConfigFromForm_DefaultStackName(t *testing.T) {
 	req.Form = map[string][]string{
 		"template_0_name": {"tmpl"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	if cfg.StackName != "dev" {
 		t.Errorf("default StackName = %q, want dev", cfg.StackName)
 	}
@@ -1224,7 +1366,8 @@ func TestCreateLabConfigFromForm_WorkspaceLifetimeDays(t *testing.T) {
 		"workspace_lifetime_hours": {"3"},
 		"workspace_lifetime_unit":  {"days"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	if cfg.WorkspaceLifetimeHours != 72 {
 		t.Errorf("WorkspaceLifetimeHours = %d, want 72 (3 days)", cfg.WorkspaceLifetimeHours)
 	}
@@ -1239,7 +1382,8 @@ func TestCreateLabConfigFromForm_LabDeletionDate_Valid(t *testing.T) {
 		"template_0_name":   {"tmpl"},
 		"lab_deletion_date": {"2030-12-31"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	require.NotNil(t, cfg.LabDeletionDate, "LabDeletionDate should be set for a valid date")
 	assert.Equal(t, 2030, cfg.LabDeletionDate.Year())
 	assert.Equal(t, 12, int(cfg.LabDeletionDate.Month()))
@@ -1259,7 +1403,8 @@ func TestCreateLabConfigFromForm_LabDeletionDate_WithTime(t *testing.T) {
 		"lab_deletion_date": {"2030-06-15"},
 		"lab_deletion_time": {"14:30"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	require.NotNil(t, cfg.LabDeletionDate)
 	assert.Equal(t, 2030, cfg.LabDeletionDate.Year())
 	assert.Equal(t, 6, int(cfg.LabDeletionDate.Month()))
@@ -1278,7 +1423,8 @@ func TestCreateLabConfigFromForm_LabDeletionDate_InvalidTime(t *testing.T) {
 		"lab_deletion_date": {"2030-06-15"},
 		"lab_deletion_time": {"not-a-time"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	require.NotNil(t, cfg.LabDeletionDate, "LabDeletionDate should still be set when only time is invalid")
 	// Falls back to end-of-day default
 	assert.Equal(t, 23, cfg.LabDeletionDate.Hour())
@@ -1294,7 +1440,8 @@ func TestCreateLabConfigFromForm_LabDeletionDate_TimeWithoutDate(t *testing.T) {
 		"template_0_name":   {"tmpl"},
 		"lab_deletion_time": {"09:00"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	assert.Nil(t, cfg.LabDeletionDate, "LabDeletionDate should be nil when date is absent even if time is set")
 }
 
@@ -1306,7 +1453,8 @@ func TestCreateLabConfigFromForm_LabDeletionDate_Empty(t *testing.T) {
 		"stack_name":      {"s"},
 		"template_0_name": {"tmpl"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	assert.Nil(t, cfg.LabDeletionDate, "LabDeletionDate should be nil when field is absent")
 }
 
@@ -1319,7 +1467,8 @@ func TestCreateLabConfigFromForm_LabDeletionDate_Invalid(t *testing.T) {
 		"template_0_name":   {"tmpl"},
 		"lab_deletion_date": {"not-a-date"},
 	}
-	cfg := h.createLabConfigFromForm(req, nil)
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
 	assert.Nil(t, cfg.LabDeletionDate, "LabDeletionDate should be nil when date is invalid")
 }
 
@@ -1511,7 +1660,9 @@ func TestHandler_ServeAdminStats(t *testing.T) {
 	req := httptest.NewRequest("GET", "/admin/stats", nil)
 	w := httptest.NewRecorder()
 	h.ServeAdminStats(w, req)
-	// Template will fail (no web/ dir in tests), but the project-list building is exercised.
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeAdminStats() status = %d, want %d", w.Code, http.StatusOK)
+	}
 }
 
 func TestHandler_UpdateJobConfig(t *testing.T) {
@@ -1651,6 +1802,338 @@ func TestValidateDNSConfig(t *testing.T) {
 	}
 }
 
+func TestValidateImportConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  *LabConfig
+		wantErr bool
+	}{
+		{name: "nothing imported", config: &LabConfig{Provider: "ovh"}},
+		{name: "network and subnet", config: &LabConfig{Provider: "ovh", ImportPrivateNetworkID: "pn-1_0", ImportSubnetID: "sub-1"}},
+		{name: "cluster only", config: &LabConfig{Provider: "ovh", ImportClusterID: "kube-1"}},
+		{name: "subnet without its network", config: &LabConfig{Provider: "ovh", ImportSubnetID: "sub-1"}, wantErr: true},
+		{name: "ignored for existing clusters", config: &LabConfig{UseExistingCluster: true, ImportSubnetID: "sub-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateImportConfig(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateNetworkConfig(t *testing.T) {
+	t.Parallel()
+
+	network := func(mask, start, end string) *LabConfig {
+		return &LabConfig{Provider: "ovh", NetworkID: "10", NetworkMask: mask, NetworkStartIP: start, NetworkEndIP: end}
+	}
+	withGateway := func(cfg *LabConfig, gatewayIP string) *LabConfig {
+		cfg.NetworkGatewayIP = gatewayIP
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		config  *LabConfig
+		wantErr bool
+	}{
+		{name: "valid range", config: network("10.0.0.0/24", "10.0.0.100", "10.0.0.254")},
+		{name: "VLAN ID out of range", config: &LabConfig{Provider: "ovh", NetworkID: "4096", NetworkMask: "10.0.0.0/24", NetworkStartIP: "10.0.0.100", NetworkEndIP: "10.0.0.254"}, wantErr: true},
+		{name: "missing VLAN ID", config: &LabConfig{Provider: "ovh", NetworkMask: "10.0.0.0/24", NetworkStartIP: "10.0.0.100", NetworkEndIP: "10.0.0.254"}, wantErr: true},
+		{name: "start outside the network", config: network("10.0.0.0/24", "10.1.0.100", "10.0.0.254"), wantErr: true},
+		{name: "missing range", config: network("10.0.0.0/24", "", ""), wantErr: true},
+		{name: "custom gateway IP", config: withGateway(network("10.0.0.0/24", "10.0.0.100", "10.0.0.254"), "10.0.0.1")},
+		{name: "gateway IP inside the DHCP range", config: withGateway(network("10.0.0.0/24", "10.0.0.100", "10.0.0.254"), "10.0.0.150"), wantErr: true},
+		{name: "gateway IP with an imported subnet", config: &LabConfig{Provider: "ovh", NetworkID: "10", NetworkMask: "10.0.0.0/24", NetworkStartIP: "10.0.0.100", NetworkEndIP: "10.0.0.254", NetworkGatewayIP: "10.0.0.1", ImportPrivateNetworkID: "pn-1_10", ImportSubnetID: "sub-1"}, wantErr: true},
+		{name: "ignored for existing clusters", config: &LabConfig{UseExistingCluster: true}},
+		{name: "ignored for Azure", config: &LabConfig{Provider: "azure"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateNetworkConfig(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateLab_InvalidNetworkRangeNamesTheField(t *testing.T) {
+	jm := NewJobManager(t.TempDir())
+	cm := NewCredentialsManager()
+	require.NoError(t, cm.SetCredentials(&OVHCredentials{
+		ApplicationKey: "key", ApplicationSecret: "secret", ConsumerKey: "consumer", ServiceName: "service", Endpoint: "ovh-eu",
+	}))
+	h := NewHandler(jm, &PulumiExecutor{}, cm, nil, nil, nil)
+
+	form := url.Values{
+		"stack_name":       {"net-lab"},
+		"network_id":       {"10"},
+		"network_mask":     {"10.0.0.0/24"},
+		"network_start_ip": {"10.0.0.200"},
+		"network_end_ip":   {"10.0.0.100"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/labs/dry-run", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	h.DryRunLab(rec, req)
+
+	assert.Contains(t, rec.Body.String(), "Invalid End IP")
+	assert.Empty(t, jm.GetAllJobs(), "no job should be created for an invalid network")
+}
+
+func TestCreateLab_NonNumericNodeCountIsABadRequest(t *testing.T) {
+	jm := newTestJobManager(t, t.TempDir())
+	cm := NewCredentialsManager()
+	require.NoError(t, cm.SetCredentials(&OVHCredentials{
+		ApplicationKey: "key", ApplicationSecret: "secret", ConsumerKey: "consumer", ServiceName: "service", Endpoint: "ovh-eu",
+	}))
+	h := NewHandler(jm, &PulumiExecutor{}, cm, nil, nil, nil)
+
+	for name, handle := range map[string]http.HandlerFunc{"create": h.CreateLab, "dry run": h.DryRunLab} {
+		form := url.Values{
+			"stack_name":                  {"node-lab"},
+			"template_0_name":             {"default"},
+			"nodepool_desired_node_count": {"three"},
+			"nodepool_max_node_count":     {"5"},
+			"nodepool_ready_timeout":      {"1.5"},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/labs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+
+		handle(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), "Invalid Form Field", name)
+		assert.Contains(t, rec.Body.String(), "nodepool_desired_node_count=&#34;three&#34;", name)
+		assert.Contains(t, rec.Body.String(), "nodepool_ready_timeout=&#34;1.5&#34;", name)
+		assert.NotContains(t, rec.Body.String(), "nodepool_max_node_count", name)
+	}
+	assert.Empty(t, jm.GetAllJobs(), "no job should be created for an unreadable node count")
+}
+
+func TestCreateLabConfigFromForm_EmptyIntegersAreZero(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Form = map[string][]string{
+		"stack_name":                  {"s"},
+		"nodepool_desired_node_count": {" 3 "},
+		"nodepool_min_node_count":     {""},
+	}
+	cfg, err := h.createLabConfigFromForm(req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, cfg.NodePoolDesiredNodeCount)
+	assert.Zero(t, cfg.NodePoolMinNodeCount)
+}
+
+func TestValidateNodePoolOptions(t *testing.T) {
+	t.Parallel()
+
+	pool := func(minNodes, desired, maxNodes int) LabConfig {
+		return LabConfig{Provider: "ovh", NodePoolMinNodeCount: minNodes, NodePoolDesiredNodeCount: desired, NodePoolMaxNodeCount: maxNodes}
+	}
+	with := func(c LabConfig, f func(*LabConfig)) *LabConfig {
+		f(&c)
+		return &c
+	}
+
+	tests := []struct {
+		name    string
+		config  *LabConfig
+		wantErr bool
+		wantMsg string
+	}{
+		{name: "no options", config: with(pool(1, 1, 10), func(*LabConfig) {})},
+		{name: "autoscale with room to scale", config: with(pool(1, 1, 3), func(c *LabConfig) { c.NodePoolAutoscale = true })},
+		{name: "autoscale with min equal to max", config: with(pool(2, 2, 2), func(c *LabConfig) { c.NodePoolAutoscale = true }), wantErr: true},
+		{name: "anti-affinity within the limit", config: with(pool(1, 3, 5), func(c *LabConfig) { c.NodePoolAntiAffinity = true })},
+		{name: "anti-affinity above the limit", config: with(pool(1, 3, 6), func(c *LabConfig) { c.NodePoolAntiAffinity = true }), wantErr: true, wantMsg: "max nodes is 6"},
+		{name: "anti-affinity with desired above the limit", config: with(pool(1, 6, 5), func(c *LabConfig) { c.NodePoolAntiAffinity = true }), wantErr: true, wantMsg: "desired nodes is 6"},
+		{name: "monthly billing alone", config: with(pool(1, 1, 10), func(c *LabConfig) { c.NodePoolMonthlyBilled = true })},
+		{name: "ignored for azure", config: with(pool(1, 3, 10), func(c *LabConfig) { c.Provider = "azure"; c.NodePoolAntiAffinity = true })},
+		{name: "labels and taints", config: with(pool(1, 1, 3), func(c *LabConfig) {
+			c.NodePoolLabels = map[string]string{"easylab.io/pool": "workspaces"}
+			c.NodePoolTaints = []utils.NodeTaint{{Key: "dedicated", Value: "workspaces", Effect: "NoSchedule"}}
+		})},
+		{name: "invalid label key", config: with(pool(1, 1, 3), func(c *LabConfig) { c.NodePoolLabels = map[string]string{"bad key": "x"} }), wantErr: true},
+		{name: "taint without effect", config: with(pool(1, 1, 3), func(c *LabConfig) { c.NodePoolTaints = []utils.NodeTaint{{Key: "dedicated"}} }), wantErr: true},
+		{name: "labels on azure", config: with(pool(1, 1, 3), func(c *LabConfig) { c.Provider = "azure"; c.NodePoolLabels = map[string]string{"pool": "ws"} }), wantErr: true},
+		{name: "ready timeout", config: with(pool(1, 1, 3), func(c *LabConfig) { c.NodePoolReadyTimeoutMinutes = 45 })},
+		{name: "ready timeout above the cap", config: with(pool(1, 1, 3), func(c *LabConfig) { c.NodePoolReadyTimeoutMinutes = 121 }), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateNodePoolOptions(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				if tt.wantMsg != "" {
+					assert.Contains(t, err.Error(), tt.wantMsg)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateK8sVersion(t *testing.T) {
+	t.Parallel()
+
+	supported := []string{"1.31", "1.30"}
+	tests := []struct {
+		name      string
+		config    LabConfig
+		supported []string
+		wantErr   bool
+	}{
+		{name: "unset", config: LabConfig{Provider: "ovh"}, supported: supported},
+		{name: "supported", config: LabConfig{Provider: "ovh", K8sVersion: "1.30"}, supported: supported},
+		{name: "not offered in the region", config: LabConfig{Provider: "ovh", K8sVersion: "1.29"}, supported: supported, wantErr: true},
+		{name: "list unavailable", config: LabConfig{Provider: "ovh", K8sVersion: "1.29"}},
+		{name: "patch version", config: LabConfig{Provider: "ovh", K8sVersion: "1.31.2"}, wantErr: true},
+		{name: "azure", config: LabConfig{Provider: "azure", K8sVersion: "1.31"}, wantErr: true},
+		{name: "existing cluster", config: LabConfig{UseExistingCluster: true, K8sVersion: "1.29"}, supported: supported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateK8sVersion(&tt.config, tt.supported)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateOVHFlavor(t *testing.T) {
+	t.Parallel()
+
+	flavors := []ovhFlavor{{Name: "b3-8"}, {Name: "t2-45", GPUs: 1}, {Name: "t2-90", GPUs: 2}}
+	cfg := &LabConfig{NetworkRegion: "GRA9", NodePoolFlavor: "b3-8"}
+	require.NoError(t, validateOVHFlavor(cfg, flavors))
+
+	cfg.NodePoolFlavor = "l4-90"
+	err := validateOVHFlavor(cfg, flavors)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "GRA9")
+	assert.Contains(t, err.Error(), "t2-45, t2-90")
+
+	err = validateOVHFlavor(cfg, []ovhFlavor{{Name: "b3-8"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no GPU flavors")
+
+	assert.NoError(t, validateOVHFlavor(cfg, nil), "an unavailable flavor list must not block creation")
+}
+
+func TestParseAnnotations(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, parseAnnotations(" \n "))
+	assert.Equal(t, map[string]string{
+		"loadbalancer.ovhcloud.com/flavor":                       "medium",
+		"service.beta.kubernetes.io/load-balancer-source-ranges": "10.0.0.0/8,192.168.0.0/16",
+	}, parseAnnotations("loadbalancer.ovhcloud.com/flavor = medium\r\nservice.beta.kubernetes.io/load-balancer-source-ranges=10.0.0.0/8,192.168.0.0/16\n"))
+}
+
+func TestValidateLoadBalancerOptions(t *testing.T) {
+	t.Parallel()
+
+	noInstall := false
+	tests := []struct {
+		name    string
+		config  LabConfig
+		wantErr bool
+	}{
+		{name: "none", config: LabConfig{}},
+		{name: "valid", config: LabConfig{LoadBalancerAnnotations: map[string]string{"loadbalancer.ovhcloud.com/flavor": "medium"}}},
+		{name: "invalid key", config: LabConfig{LoadBalancerAnnotations: map[string]string{"bad key/flavor": "medium"}}, wantErr: true},
+		{name: "empty key", config: LabConfig{LoadBalancerAnnotations: map[string]string{"": "medium"}}, wantErr: true},
+		{name: "skipAwait", config: LabConfig{LoadBalancerAnnotations: map[string]string{"pulumi.kubernetes.io/skipAwait": "false"}}, wantErr: true},
+		{name: "existing controller", config: LabConfig{InstallNginxIngress: &noInstall, LoadBalancerAnnotations: map[string]string{"a": "b"}}, wantErr: true},
+		{name: "reserved IP", config: LabConfig{LoadBalancerIP: "51.178.10.20"}},
+		{name: "invalid IP", config: LabConfig{LoadBalancerIP: "51.178.10"}, wantErr: true},
+		{name: "IP with existing controller", config: LabConfig{InstallNginxIngress: &noInstall, LoadBalancerIP: "51.178.10.20"}, wantErr: true},
+		{name: "IP timeout", config: LabConfig{IngressIPTimeoutMinutes: 5}},
+		{name: "IP timeout too long", config: LabConfig{IngressIPTimeoutMinutes: 90}, wantErr: true},
+		{name: "NodePort fallback", config: LabConfig{NodePortFallback: true}},
+		{name: "NodePort fallback with a domain", config: LabConfig{NodePortFallback: true, Domain: "lab.example.com"}, wantErr: true},
+		{name: "NodePort fallback with existing controller", config: LabConfig{InstallNginxIngress: &noInstall, NodePortFallback: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateLoadBalancerOptions(&tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseNodeLabelsAndTaints(t *testing.T) {
+	t.Parallel()
+
+	labels, err := parseNodeLabels("  ")
+	require.NoError(t, err)
+	assert.Nil(t, labels)
+	labels, err = parseNodeLabels("pool=workspaces, tier=lab\n")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"pool": "workspaces", "tier": "lab"}, labels)
+	labels, err = parseNodeLabels("pool=workspaces, gpu")
+	assert.EqualError(t, err, `invalid node label "gpu" (want key=value)`, "not a label with an empty value")
+	assert.Nil(t, labels)
+	labels, err = parseNodeLabels("spare=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"spare": ""}, labels, "an empty value is explicit")
+
+	assert.Nil(t, parseNodeTaints(""))
+	assert.Equal(t, []utils.NodeTaint{
+		{Key: "dedicated", Value: "workspaces", Effect: "NoSchedule"},
+		{Key: "gpu", Effect: "NoExecute"},
+	}, parseNodeTaints("dedicated=workspaces:NoSchedule\ngpu:NoExecute"))
+}
+
+func TestWorkspaceScheduling(t *testing.T) {
+	t.Parallel()
+
+	cfg := &LabConfig{
+		Provider:       "ovh",
+		NodePoolLabels: map[string]string{"pool": "workspaces"},
+		NodePoolTaints: []utils.NodeTaint{{Key: "dedicated", Value: "workspaces", Effect: "NoSchedule"}},
+	}
+	selector, tolerations := workspaceScheduling(cfg)
+	assert.Equal(t, map[string]string{"pool": "workspaces"}, selector)
+	assert.Equal(t, []workspace.Toleration{{Key: "dedicated", Value: "workspaces", Effect: "NoSchedule"}}, tolerations)
+
+	cfg.UseExistingCluster = true
+	selector, tolerations = workspaceScheduling(cfg)
+	assert.Nil(t, selector, "an existing cluster has no node pool created by the lab")
+	assert.Nil(t, tolerations)
+}
+
 func TestLabConfigWarnings(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -1961,3 +2444,31 @@ func TestWorkspaceDeletionTime(t *testing.T) {
 func timePtr(t time.Time) *time.Time {
 	return &t
 }
+
+func TestValidateStorageClass(t *testing.T) {
+	assert.NoError(t, validateStorageClass(""))
+	assert.NoError(t, validateStorageClass("csi-cinder-high-speed"))
+	assert.Error(t, validateStorageClass("High Speed"))
+	assert.Contains(t, (&LabConfig{StackName: "lab", StorageClass: "fast_ssd"}).Validate(), validateStorageClass("fast_ssd"))
+}
+
+func TestRequestWorkspace_StorageClass(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{reachable: true}
+	useFakeBackend(h, fb)
+
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.mu.Lock()
+	job.Config.WorkspaceTemplates = []WorkspaceTemplate{{Name: "default", DiskSize: "10Gi"}}
+	job.Config.StorageClass = "csi-cinder-high-speed"
+	job.mu.Unlock()
+
+	req := postForm(t, "/api/workspace/request", url.Values{"lab_id": {labID}})
+	req = req.WithContext(context.WithValue(req.Context(), studentEmailContextKey, "student@example.com"))
+	h.RequestWorkspace(httptest.NewRecorder(), req)
+
+	require.Len(t, fb.Ensured, 1)
+	assert.Equal(t, "csi-cinder-high-speed", fb.Ensured[0].StorageClass)
+}
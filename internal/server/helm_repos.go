@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// validateHelmRepoOverrides checks the chart repository overrides when the lab
+// is created: a chart name and an http(s) repository each. Helm would only
+// reject them once pulumi up installs the chart.
+func validateHelmRepoOverrides(overrides map[string]string) error {
+	charts := make([]string, 0, len(overrides))
+	for chart := range overrides {
+		charts = append(charts, chart)
+	}
+	sort.Strings(charts)
+	for _, chart := range charts {
+		repo := overrides[chart]
+		if chart == "" {
+			return fmt.Errorf("no chart name for the Helm repository %q, use chart=URL", repo)
+		}
+		u, err := url.Parse(repo)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid Helm repository %q for chart %s: use an http(s) URL", repo, chart)
+		}
+	}
+	return nil
+}
+
+// helmRepoOverridesSummary lists the overridden chart repositories for the
+// dry-run output, or returns "" when the lab pulls every chart from upstream.
+func helmRepoOverridesSummary(config *LabConfig) string {
+	if config == nil || len(config.HelmRepoOverrides) == 0 {
+		return ""
+	}
+	entries := make([]string, 0, len(config.HelmRepoOverrides))
+	for chart, repo := range config.HelmRepoOverrides {
+		entries = append(entries, chart+" from "+repo)
+	}
+	sort.Strings(entries)
+	return "Helm repositories: " + strings.Join(entries, ", ")
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHelmRepoOverrides(t *testing.T) {
+	assert.NoError(t, validateHelmRepoOverrides(nil))
+	assert.NoError(t, validateHelmRepoOverrides(map[string]string{
+		"ingress-nginx": "https://charts.example.internal/ingress-nginx",
+		"cert-manager":  "http://10.0.0.5:8080/jetstack",
+	}))
+
+	for name, overrides := range map[string]map[string]string{
+		"no chart name":  {"": "https://charts.example.internal"},
+		"no URL":         {"cert-manager": ""},
+		"not a URL":      {"cert-manager": "charts.example.internal"},
+		"unknown scheme": {"cert-manager": "ftp://charts.example.internal"},
+	} {
+		assert.Error(t, validateHelmRepoOverrides(overrides), name)
+	}
+}
+
+func TestHelmRepoOverridesSummary(t *testing.T) {
+	assert.Empty(t, helmRepoOverridesSummary(&LabConfig{}))
+	assert.Equal(t, "Helm repositories: cert-manager from https://m/jetstack, ingress-nginx from https://m/nginx",
+		helmRepoOverridesSummary(&LabConfig{HelmRepoOverrides: map[string]string{"ingress-nginx": "https://m/nginx", "cert-manager": "https://m/jetstack"}}))
+}
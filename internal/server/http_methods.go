@@ -0,0 +1,139 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// apiAllowedMethods is the Allow header of an OPTIONS request to the API. The
+// routes do not declare their methods, so it lists every method one accepts.
+const apiAllowedMethods = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"
+
+// apiAllowedHeaders are the request headers the API reads, which a preflight
+// lets a cross-origin page send. Any other header is refused by the browser.
+var apiAllowedHeaders = []string{"Accept", "Content-Type", "HX-Request", RequestIDHeader}
+
+// apiExposedHeaders are the response headers a cross-origin page may read, on
+// top of the few a browser always shows.
+const apiExposedHeaders = RequestIDHeader + ", Retry-After"
+
+// CORSPolicy is the list of origins allowed to call the API cross-origin.
+type CORSPolicy struct {
+	origins   []string
+	wildcards []corsWildcard
+}
+
+// corsWildcard is an "https://*.example.com:8443" origin: its scheme
+// ("https://") and what its subdomains end with (".example.com:8443").
+type corsWildcard struct {
+	scheme string
+	suffix string
+}
+
+// NewCORSPolicy parses the origins of the cors.allowed_origins setting. An
+// origin is a scheme and host, without a path; "https://*.example.com" allows
+// every subdomain of example.com, but not example.com itself. The session
+// cookie goes with the requests, so there is no "*".
+func NewCORSPolicy(origins []string) (*CORSPolicy, error) {
+	p := &CORSPolicy{}
+	for _, origin := range origins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		// A wildcard is checked as the origin of the domain it stands for.
+		plain := origin
+		scheme, host, wildcard := strings.Cut(origin, "://*.")
+		if wildcard {
+			plain = scheme + "://" + host
+		}
+		u, err := url.Parse(plain)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" ||
+			strings.Contains(u.Host, "*") || (wildcard && !strings.Contains(u.Hostname(), ".")) {
+			return nil, fmt.Errorf("invalid origin %q, want scheme://host[:port] or scheme://*.domain[:port]", origin)
+		}
+		if wildcard {
+			p.wildcards = append(p.wildcards, corsWildcard{scheme: u.Scheme + "://", suffix: "." + u.Host})
+			continue
+		}
+		p.origins = append(p.origins, origin)
+	}
+	return p, nil
+}
+
+// allows reports whether the policy lets origin call the API.
+func (p *CORSPolicy) allows(origin string) bool {
+	if p == nil || origin == "" {
+		return false
+	}
+	if slices.Contains(p.origins, origin) {
+		return true
+	}
+	for _, w := range p.wildcards {
+		host, ok := strings.CutPrefix(origin, w.scheme)
+		if !ok {
+			continue
+		}
+		sub, ok := strings.CutSuffix(host, w.suffix)
+		if ok && sub != "" && !strings.ContainsAny(sub, ":/@") {
+			return true
+		}
+	}
+	return false
+}
+
+// headResponseWriter drops the body of a HEAD response and keeps its headers
+// and status.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (w headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// HeadAndOptions wraps the server's routes, which only branch on GET and POST:
+// HEAD is answered like GET without a body, and OPTIONS on the API with its
+// Allow header and, for a preflight from an allowed origin, the CORS headers.
+// A workspace proxy request is not the server's own route: its HEAD goes to the
+// workspace as is. It goes outside the auth checks, as a browser sends no
+// cookie with a preflight.
+func HeadAndOptions(next http.Handler, cors *CORSPolicy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api := strings.HasPrefix(r.URL.Path, "/api/")
+		origin := r.Header.Get("Origin")
+		if api && cors.allows(origin) {
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Expose-Headers", apiExposedHeaders)
+		}
+
+		switch {
+		case r.Method == http.MethodOptions && api:
+			w.Header().Set("Allow", apiAllowedMethods)
+			if r.Header.Get("Access-Control-Request-Method") != "" && cors.allows(origin) {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				w.Header().Set("Access-Control-Allow-Methods", apiAllowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(apiAllowedHeaders, ", "))
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodHead && !IsWorkspaceProxyPath(r.URL.Path):
+			get := r.Clone(r.Context())
+			get.Method = http.MethodGet
+			next.ServeHTTP(headResponseWriter{w}, get)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
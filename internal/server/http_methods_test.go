@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCORSPolicy(t *testing.T) {
+	p, err := NewCORSPolicy([]string{" https://portal.example.com/", "http://localhost:3000 ", ""})
+	require.NoError(t, err)
+	assert.True(t, p.allows("https://portal.example.com"))
+	assert.True(t, p.allows("http://localhost:3000"))
+	assert.False(t, p.allows("https://evil.example.com"))
+	assert.False(t, p.allows(""))
+
+	for _, origin := range []string{"*", "portal.example.com", "ftp://portal.example.com", "https://portal.example.com/app", "https://*", "https://*.com", "https://app.*.example.com"} {
+		_, err := NewCORSPolicy([]string{origin})
+		assert.Error(t, err, origin)
+	}
+
+	wildcard, err := NewCORSPolicy([]string{"https://*.example.com", "http://*.dev.local:3000"})
+	require.NoError(t, err)
+	assert.True(t, wildcard.allows("https://portal.example.com"))
+	assert.True(t, wildcard.allows("https://a.b.example.com"))
+	assert.True(t, wildcard.allows("http://web.dev.local:3000"))
+	assert.False(t, wildcard.allows("https://example.com"), "a wildcard does not cover the domain itself")
+	assert.False(t, wildcard.allows("http://portal.example.com"), "nor another scheme")
+	assert.False(t, wildcard.allows("https://portal.example.com:8443"), "nor another port")
+	assert.False(t, wildcard.allows("http://web.dev.local"))
+	assert.False(t, wildcard.allows("https://evilexample.com"))
+	assert.False(t, wildcard.allows("https://example.com.evil.net"))
+
+	var none *CORSPolicy
+	assert.False(t, none.allows("https://portal.example.com"), "a nil policy allows no origin")
+}
+
+// getOnly answers GET like the handlers do, and refuses any other method.
+func getOnly(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"labs": []}`))
+}
+
+func TestHeadAndOptions_Head(t *testing.T) {
+	h := HeadAndOptions(http.HandlerFunc(getOnly), nil)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/api/labs/job-1/status", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestHeadAndOptions_HeadToAWorkspaceIsProxiedAsIs(t *testing.T) {
+	var method string
+	h := HeadAndOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.Header().Set("Content-Length", "42")
+	}), nil)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/labs/job-1/coder/alice/static/app.js", nil))
+	assert.Equal(t, http.MethodHead, method, "the workspace answers the HEAD itself")
+	assert.Equal(t, "42", w.Header().Get("Content-Length"))
+
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/labs/job-1", nil))
+	assert.Equal(t, http.MethodGet, method, "the lab page is the server's own route")
+}
+
+func TestHeadAndOptions_Preflight(t *testing.T) {
+	cors, err := NewCORSPolicy([]string{"https://portal.example.com"})
+	require.NoError(t, err)
+	h := HeadAndOptions(http.HandlerFunc(getOnly), cors)
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/api/labs", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type, x-request-id")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := preflight("https://portal.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, apiAllowedMethods, w.Header().Get("Allow"))
+	assert.Equal(t, "https://portal.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, apiAllowedMethods, w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Accept, Content-Type, HX-Request, X-Request-ID", w.Header().Get("Access-Control-Allow-Headers"), "only the headers the API reads")
+	assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+	assert.Empty(t, w.Body.String())
+
+	w = preflight("https://evil.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, apiAllowedMethods, w.Header().Get("Allow"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), "an origin outside the policy gets no CORS headers")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+
+	// An allowed origin can read the API's answers.
+	req := httptest.NewRequest(http.MethodGet, "/api/labs", nil)
+	req.Header.Set("Origin", "https://portal.example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://portal.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Request-ID, Retry-After", w.Header().Get("Access-Control-Expose-Headers"))
+
+	// Pages outside /api/ are never shared with another origin.
+	req = httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Origin", "https://portal.example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestHeadAndOptions_OptionsOutsideTheAPI(t *testing.T) {
+	h := HeadAndOptions(http.HandlerFunc(getOnly), nil)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/admin", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code, "pages answer OPTIONS themselves")
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPAllowList lets requests through only from the networks it lists. A nil or
+// empty list lets every request through.
+type IPAllowList struct {
+	allowed        []netip.Prefix
+	trustedProxies []netip.Prefix
+}
+
+// NewIPAllowList parses the CIDRs of allowed and trustedProxies. A bare
+// address stands for itself alone.
+func NewIPAllowList(allowed, trustedProxies []string) (*IPAllowList, error) {
+	allowedPrefixes, err := parsePrefixes(allowed)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	return &IPAllowList{allowed: allowedPrefixes, trustedProxies: proxies}, nil
+}
+
+// NewIPAllowLists builds the admin and student allow-lists of cfg, which
+// ServerConfig.Validate checked.
+func NewIPAllowLists(cfg AccessConfig) (admin, student *IPAllowList, err error) {
+	if admin, err = NewIPAllowList(cfg.AdminAllowedCIDRs, cfg.TrustedProxyCIDRs); err != nil {
+		return nil, nil, fmt.Errorf("access.admin_allowed_cidrs: %w", err)
+	}
+	if student, err = NewIPAllowList(cfg.StudentAllowedCIDRs, cfg.TrustedProxyCIDRs); err != nil {
+		return nil, nil, fmt.Errorf("access.student_allowed_cidrs: %w", err)
+	}
+	return admin, student, nil
+}
+
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range list {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", field)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", field)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled reports whether the list restricts anything.
+func (l *IPAllowList) Enabled() bool {
+	return l != nil && len(l.allowed) > 0
+}
+
+// clientAddr is the address a request comes from. Behind trusted proxies it is
+// the last X-Forwarded-For entry a trusted proxy did not add: the entries
+// before it are whatever the client sent, and cannot be believed.
+func (l *IPAllowList) clientAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+	if !containsAddr(l.trustedProxies, addr) {
+		return addr, true
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = hop.Unmap()
+		if !containsAddr(l.trustedProxies, addr) {
+			break
+		}
+	}
+	return addr, true
+}
+
+// Allows reports whether the list lets r through.
+func (l *IPAllowList) Allows(r *http.Request) bool {
+	if !l.Enabled() {
+		return true
+	}
+	addr, ok := l.clientAddr(r)
+	return ok && containsAddr(l.allowed, addr)
+}
+
+// Require wraps a handler so that requests from outside the list get a 403.
+// It goes outside the auth check, so a refused client never reaches a login.
+func (l *IPAllowList) Require(next http.HandlerFunc) http.HandlerFunc {
+	if !l.Enabled() {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allows(r) {
+			slog.Warn("Refused request: not in the IP allow-list", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr, "x_forwarded_for", r.Header.Get("X-Forwarded-For"))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
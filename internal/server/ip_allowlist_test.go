@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// allowListRequest sends a request from remoteAddr through l to a handler that
+// answers 200, and returns the status.
+func allowListRequest(l *IPAllowList, remoteAddr, forwardedFor string) int {
+	r := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	r.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	l.Require(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})(w, r)
+	return w.Code
+}
+
+func TestIPAllowList_Require(t *testing.T) {
+	l, err := NewIPAllowList([]string{"10.0.0.0/8", "192.0.2.7", "2001:db8::/32"}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, allowListRequest(l, "10.1.2.3:51000", ""))
+	assert.Equal(t, http.StatusOK, allowListRequest(l, "192.0.2.7:51000", ""))
+	assert.Equal(t, http.StatusOK, allowListRequest(l, "[2001:db8::1]:51000", ""))
+	assert.Equal(t, http.StatusForbidden, allowListRequest(l, "192.0.2.8:51000", ""))
+	assert.Equal(t, http.StatusForbidden, allowListRequest(l, "203.0.113.9:51000", "10.1.2.3"), "X-Forwarded-For is ignored without trusted proxies")
+}
+
+func TestIPAllowList_TrustedProxies(t *testing.T) {
+	l, err := NewIPAllowList([]string{"198.51.100.0/24"}, []string{"172.16.0.0/12"})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, allowListRequest(l, "172.16.0.2:443", "198.51.100.20"))
+	assert.Equal(t, http.StatusOK, allowListRequest(l, "172.16.0.2:443", "203.0.113.9, 198.51.100.20, 172.16.0.3"), "entries added by trusted proxies are skipped")
+	assert.Equal(t, http.StatusForbidden, allowListRequest(l, "172.16.0.2:443", "198.51.100.20, 203.0.113.9"), "an entry forged by the client does not count")
+	assert.Equal(t, http.StatusForbidden, allowListRequest(l, "172.16.0.2:443", "not-an-ip"))
+	assert.Equal(t, http.StatusForbidden, allowListRequest(l, "172.16.0.2:443", ""), "the proxy itself is not in the list")
+}
+
+func TestIPAllowList_EmptyAllowsEveryone(t *testing.T) {
+	l, err := NewIPAllowList(nil, []string{"172.16.0.0/12"})
+	require.NoError(t, err)
+	assert.False(t, l.Enabled())
+	assert.Equal(t, http.StatusOK, allowListRequest(l, "203.0.113.9:51000", ""))
+
+	var none *IPAllowList
+	assert.Equal(t, http.StatusOK, allowListRequest(none, "203.0.113.9:51000", ""))
+}
+
+func TestNewIPAllowList_Invalid(t *testing.T) {
+	_, err := NewIPAllowList([]string{"10.0.0.0/33"}, nil)
+	assert.ErrorContains(t, err, `invalid CIDR "10.0.0.0/33"`)
+	_, err = NewIPAllowList([]string{"10.0.0.0/8"}, []string{"proxy.local"})
+	assert.ErrorContains(t, err, `trusted proxies: invalid address "proxy.local"`)
+}
+
+func TestNewIPAllowLists(t *testing.T) {
+	admin, student, err := NewIPAllowLists(AccessConfig{AdminAllowedCIDRs: []string{"10.0.0.0/8"}})
+	require.NoError(t, err)
+	assert.True(t, admin.Enabled())
+	assert.False(t, student.Enabled())
+
+	_, _, err = NewIPAllowLists(AccessConfig{StudentAllowedCIDRs: []string{"nope"}})
+	assert.ErrorContains(t, err, "access.student_allowed_cidrs")
+}
@@ -3,14 +3,15 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"easylab/utils"
 )
 
 // JobStatus represents the current status of a Pulumi job
@@ -23,6 +24,8 @@ const (
 	JobStatusFailed          JobStatus = "failed"
 	JobStatusDryRunCompleted JobStatus = "dry-run-completed"
 	JobStatusDestroyed       JobStatus = "destroyed"
+	// JobStatusCancelled is a dry run an admin cancelled (see CancelDryRun).
+	JobStatusCancelled JobStatus = "cancelled"
 )
 
 // CleanupEvent records a single automatic workspace cleanup run.
@@ -46,6 +49,19 @@ type WorkspaceSnapshot struct {
 	Count int       `json:"count"`
 }
 
+// StatusChange records one status transition of a job, with why it happened
+// when that is known (the error of a failed run, a retry).
+type StatusChange struct {
+	Status JobStatus `json:"status"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// maxStatusHistory bounds Job.History. A lab goes through a handful of
+// transitions per run, so this keeps every one but the oldest of a lab that has
+// been retried over and over.
+const maxStatusHistory = 100
+
 // Job represents a Pulumi execution job
 type Job struct {
 	ID         string     `json:"id"`
@@ -56,12 +72,55 @@ type Job struct {
 	Error      string     `json:"error,omitempty"`
 	Config     *LabConfig `json:"config,omitempty"`
 	Kubeconfig string     `json:"kubeconfig,omitempty"`
+	// KubeconfigVersion counts the kubeconfig rotations; 0 stands for the
+	// kubeconfig the cluster was created with (see kubeconfigVersion).
+	KubeconfigVersion int `json:"kubeconfig_version,omitempty"`
+	// KubeVersion is the cluster's effective Kubernetes version, read back from
+	// the stack outputs once it is up.
+	KubeVersion string `json:"kube_version,omitempty"`
+	// GrafanaURL is where the lab's Grafana is served, when it installs the
+	// monitoring stack.
+	GrafanaURL string `json:"grafana_url,omitempty"`
+	// Cluster is the cluster's API server and nodes, for OVH labs.
+	Cluster *ClusterInfo `json:"cluster,omitempty"`
+	// Protected labs cannot be destroyed or removed, by hand or on their
+	// deletion date, until the protection is removed. ProtectionLog is the
+	// audit trail of every change to it.
+	Protected     bool               `json:"protected,omitempty"`
+	ProtectionLog []ProtectionChange `json:"protection_log,omitempty"`
+	// IsTemplate jobs hold a config that seeds new labs and are never executed
+	// (see template_jobs.go).
+	IsTemplate bool `json:"is_template,omitempty"`
+	// DestroyReport is what the last destroy deleted and, if it failed, what
+	// remained in the stack.
+	DestroyReport *DestroyReport `json:"destroy_report,omitempty"`
+	// RolledBack is set on a failed deployment whose resources were destroyed
+	// right away (see rollBackFailedDeploy).
+	RolledBack bool `json:"rolled_back,omitempty"`
+	// RejectedCredentials is the fingerprint of the OVH credentials a failed
+	// deployment was refused with; a retry waits for other ones (see
+	// deploy_auth.go).
+	RejectedCredentials string `json:"rejected_credentials,omitempty"`
+	// QuotaReport is how the lab fits in the OVH project's quota, checked at
+	// dry-run time. Launch refuses a lab that does not fit unless overridden.
+	QuotaReport *QuotaReport `json:"quota_report,omitempty"`
+	// Roster lists the students a workspace was provisioned for, oldest first.
+	Roster []RosterEntry `json:"roster,omitempty"`
+	// WebhookDeliveries logs the webhook payloads sent for the job, oldest
+	// first, capped at maxWebhookDeliveries entries (see webhooks.go).
+	WebhookDeliveries []WebhookDelivery `json:"webhook_deliveries,omitempty"`
+	// History is the job's status timeline, oldest first, capped at
+	// maxStatusHistory entries.
+	History []StatusChange `json:"history,omitempty"`
 	// CleanupEvents/WorkspaceSnapshots/DeletionRetries feed the stats dashboard
 	// and the background workspace cleanup loop.
 	CleanupEvents      []CleanupEvent                     `json:"cleanup_events,omitempty"`
 	WorkspaceSnapshots []WorkspaceSnapshot                `json:"workspace_snapshots,omitempty"`
 	DeletionRetries    map[string]*WorkspaceDeletionRetry `json:"deletion_retries,omitempty"`
 	mu                 sync.RWMutex                       `json:"-"`
+	// events is the manager's event bus, which status and output changes are
+	// published to (see job_events.go).
+	events *jobEventBus `json:"-"`
 }
 
 // WorkspaceTemplate defines a selectable workspace flavor for a lab: the IDE
@@ -78,6 +137,9 @@ type WorkspaceTemplate struct {
 	Memory    string            `json:"memory,omitempty"`
 	DiskSize  string            `json:"disk_size,omitempty"`
 	Env       map[string]string `json:"env,omitempty"`
+	// GPU is the number of NVIDIA GPUs the workspace requests. The lab's node
+	// pool needs a GPU flavor for the workspace to be scheduled.
+	GPU int `json:"gpu,omitempty"`
 
 	// IDE selects the workspace IDE base. Only "code-server" is supported, so this
 	// is optional and normally left empty; the retired "openvscode" value is still
@@ -114,6 +176,10 @@ type WorkspaceTemplate struct {
 	// It is read by the clone step only, never by the IDE container: the student
 	// has a shell there, and the workshop author's token must not be in it.
 	GitAuthSecret string `json:"git_auth_secret,omitempty"`
+	// GitCommit is the commit GitRepo is pinned to, set when the lab is created
+	// with pin_template_versions. Workspaces check it out instead of the branch
+	// head.
+	GitCommit string `json:"git_commit,omitempty"`
 	// Devcontainer builds the workspace image from the workshop repo's
 	// devcontainer.json instead of using Image. Requires GitRepo; conflicts with
 	// Image.
@@ -179,10 +245,28 @@ type WorkspaceMount struct {
 	Path string `json:"path"` // mount path in the workspace container
 }
 
+// WorkspaceQuota caps the resources of the workspace namespace. CPU and Memory
+// are the namespace totals; the defaults are the requests and limits given to
+// containers that declare none. Values are Kubernetes quantities.
+type WorkspaceQuota struct {
+	CPU                  string `json:"cpu,omitempty"`
+	Memory               string `json:"memory,omitempty"`
+	DefaultCPURequest    string `json:"default_cpu_request,omitempty"`
+	DefaultMemoryRequest string `json:"default_memory_request,omitempty"`
+	DefaultCPULimit      string `json:"default_cpu_limit,omitempty"`
+	DefaultMemoryLimit   string `json:"default_memory_limit,omitempty"`
+}
+
 // LabConfig holds all configuration values for a lab
 type LabConfig struct {
 	// Pulumi Stack Name
 	StackName string `json:"stack_name"`
+	// Event tags the lab with the workshop or cohort it runs for, so the labs
+	// list can show one event's labs. Empty leaves the lab untagged.
+	Event string `json:"event,omitempty"`
+	// WebhookURLs replace the server's webhook URLs for this lab's status
+	// changes. Empty uses the server's.
+	WebhookURLs []string `json:"webhook_urls,omitempty"`
 
 	// Cloud Provider
 	Provider string `json:"provider"` // "ovh", "aws", "azure", etc.
@@ -206,9 +290,27 @@ type LabConfig struct {
 	NetworkStartIP            string `json:"network_start_ip"`
 	NetworkEndIP              string `json:"network_end_ip"`
 	NetworkID                 string `json:"network_id,omitempty"`
+	// Optional subnet settings; nil and empty keep OVH's defaults.
+	NetworkDHCP      *bool  `json:"network_dhcp,omitempty"`
+	NetworkGatewayIP string `json:"network_gateway_ip,omitempty"`
+
+	// OVH IDs of existing resources the lab adopts (Pulumi import) instead of
+	// creating them. Empty means create as usual.
+	ImportPrivateNetworkID string `json:"import_private_network_id,omitempty"`
+	ImportSubnetID         string `json:"import_subnet_id,omitempty"`
+	ImportClusterID        string `json:"import_cluster_id,omitempty"`
 
 	// Kubernetes Configuration
 	K8sClusterName string `json:"k8s_cluster_name"`
+	// K8sVersion is the "major.minor" Kubernetes version to create the cluster
+	// with. Empty lets OVH pick its default.
+	K8sVersion string `json:"k8s_version,omitempty"`
+	// Advanced cluster options (see validateClusterOptions). Empty keeps OVH's
+	// defaults: iptables, routing over the public network, free plan.
+	K8sKubeProxyMode         string `json:"k8s_kube_proxy_mode,omitempty"`
+	K8sIPVSScheduler         string `json:"k8s_ipvs_scheduler,omitempty"`
+	K8sPrivateNetworkRouting bool   `json:"k8s_private_network_routing,omitempty"`
+	K8sPlan                  string `json:"k8s_plan,omitempty"`
 
 	// Node Pool Configuration
 	NodePoolName             string `json:"nodepool_name"`
@@ -216,6 +318,26 @@ type LabConfig struct {
 	NodePoolDesiredNodeCount int    `json:"nodepool_desired_node_count"`
 	NodePoolMinNodeCount     int    `json:"nodepool_min_node_count"`
 	NodePoolMaxNodeCount     int    `json:"nodepool_max_node_count"`
+	// Optional OVH node pool options (see validateNodePoolOptions)
+	NodePoolAutoscale     bool `json:"nodepool_autoscale,omitempty"`
+	NodePoolMonthlyBilled bool `json:"nodepool_monthly_billed,omitempty"`
+	NodePoolAntiAffinity  bool `json:"nodepool_anti_affinity,omitempty"`
+	// NodePoolGPU records that the flavor has GPUs, looked up from the OVH
+	// capabilities at creation, so the NVIDIA device plugin gets installed.
+	NodePoolGPU bool `json:"nodepool_gpu,omitempty"`
+	// Node labels and taints set on the node pool; workspaces and the ingress
+	// add-ons get the matching nodeSelector and tolerations.
+	NodePoolLabels map[string]string `json:"nodepool_labels,omitempty"`
+	NodePoolTaints []utils.NodeTaint `json:"nodepool_taints,omitempty"`
+	// NodePoolZones spreads the cluster over availability zones of a
+	// multi-zone region: one node pool with the node counts above per zone.
+	NodePoolZones []string `json:"nodepool_zones,omitempty"`
+	// NodePoolReadyTimeoutMinutes is how long the nodes get to become Ready
+	// before the deployment fails. 0 keeps the default (20 minutes).
+	NodePoolReadyTimeoutMinutes int `json:"nodepool_ready_timeout_minutes,omitempty"`
+
+	// Verbosity of the Pulumi output streamed into the job's logs.
+	Verbosity Verbosity `json:"verbosity,omitempty"`
 
 	// Workspace Configuration
 	// WorkspaceNamespace is the Kubernetes namespace student workspaces are created in.
@@ -223,6 +345,39 @@ type LabConfig struct {
 	WorkspaceTemplates     []WorkspaceTemplate `json:"workspace_templates,omitempty"`
 	WorkspaceLifetimeHours int                 `json:"workspace_lifetime_hours,omitempty"`
 	LabDeletionDate        *time.Time          `json:"lab_deletion_date,omitempty"`
+	// MaxWorkspaces is how many student workspaces the lab holds at most; 0 is
+	// no limit.
+	MaxWorkspaces int `json:"max_workspaces,omitempty"`
+	// StudentAccessCode, when set, logs students into this lab only, next to
+	// the global student password. It is kept hashed (see lab_access_codes.go).
+	StudentAccessCode string `json:"student_access_code,omitempty"`
+	// StudentAccessCodeIndex finds the lab of an access code without bcrypt
+	// (see lab_access_codes.go).
+	StudentAccessCodeIndex string `json:"student_access_code_index,omitempty"`
+	// PinTemplateVersions resolves each template's Git branch to a commit when
+	// the lab is created, so that later workspaces, retries and re-creations
+	// clone the same code until the admin re-pins (see template_pins.go).
+	PinTemplateVersions bool `json:"pin_template_versions,omitempty"`
+	// WorkspaceProxyEnabled serves workspaces through the lab server at
+	// /labs/{id}/coder/{workspace}/ for venues that block the workspace hosts.
+	// Off by default.
+	WorkspaceProxyEnabled bool `json:"workspace_proxy_enabled,omitempty"`
+	// StorageClass is the StorageClass of the workspaces' persistent volumes,
+	// checked against the cluster at deployment. Empty uses the cluster default.
+	StorageClass string `json:"storage_class,omitempty"`
+	// PostDeployManifests are manifest URLs or server-side file paths applied, in
+	// order, to the cluster once it is provisioned (baseline namespaces, quotas,
+	// RBAC). A manifest that fails is reported and skipped.
+	PostDeployManifests []string `json:"post_deploy_manifests,omitempty"`
+	// WorkspaceQuota, when set, puts a ResourceQuota and a LimitRange on the
+	// workspace namespace (see parseWorkspaceQuota).
+	WorkspaceQuota *WorkspaceQuota `json:"workspace_quota,omitempty"`
+	// HelmRepoOverrides maps a chart name to the repository to pull it from
+	// instead of its upstream one, e.g. an internal mirror for air-gapped sites.
+	HelmRepoOverrides map[string]string `json:"helm_repo_overrides,omitempty"`
+	// HelmChartVersions maps a chart name to the version to install instead of
+	// the one EasyLab pins (see k8s.PinnedCharts).
+	HelmChartVersions map[string]string `json:"helm_chart_versions,omitempty"`
 
 	// OVH Endpoint
 	OvhEndpoint string `json:"ovh_endpoint"`
@@ -251,6 +406,21 @@ type LabConfig struct {
 	InstallCertManager      *bool  `json:"install_cert_manager,omitempty"`
 	CertManagerNamespace    string `json:"cert_manager_namespace,omitempty"`
 
+	// Annotations set on the LoadBalancer service of the ingress-nginx controller
+	// the lab installs, e.g. to size the OVH load balancer or enable the PROXY
+	// protocol. Ignored when an existing controller is used.
+	LoadBalancerAnnotations map[string]string `json:"loadbalancer_annotations,omitempty"`
+	// LoadBalancerIP pins that service to a reserved (floating) IP, so the ingress
+	// IP and the DNS records pointing at it survive a re-deployment.
+	LoadBalancerIP string `json:"loadbalancer_ip,omitempty"`
+	// IngressIPTimeoutMinutes is how long the deployment waits for that service's
+	// LoadBalancer IP; 0 is the default of 10 minutes.
+	IngressIPTimeoutMinutes int `json:"ingress_ip_timeout_minutes,omitempty"`
+	// NodePortFallback serves a domainless lab on a node's external IP and the
+	// controller's NodePort when no LoadBalancer IP comes in time, instead of
+	// failing the deployment.
+	NodePortFallback bool `json:"node_port_fallback,omitempty"`
+
 	// DNS provider for automated A-record creation and DNS-01 cert issuance
 	DNSProvider    string            `json:"dns_provider,omitempty"`
 	DNSZone        string            `json:"dns_zone,omitempty"`
@@ -260,6 +430,29 @@ type LabConfig struct {
 	// workspace ingresses and maintains one record per workspace. For zones where a
 	// wildcard record is not available. Requires DNSProvider.
 	UseExternalDNS bool `json:"use_external_dns,omitempty"`
+
+	// InstallMonitoring installs kube-prometheus-stack and serves Grafana next to
+	// the workspaces. GrafanaAdminPassword is generated with the lab and kept with
+	// it so the admin can read it on the lab page; the jobs API redacts it.
+	InstallMonitoring    bool   `json:"install_monitoring,omitempty"`
+	GrafanaAdminPassword string `json:"grafana_admin_password,omitempty"`
+}
+
+// redacted returns a copy of the config without the secrets EasyLab generated
+// for the lab, nor the hash of its student access code, for the jobs API.
+func (c *LabConfig) redacted() *LabConfig {
+	if c == nil || (c.GrafanaAdminPassword == "" && c.StudentAccessCode == "") {
+		return c
+	}
+	out := *c
+	if out.GrafanaAdminPassword != "" {
+		out.GrafanaAdminPassword = "REDACTED"
+	}
+	if out.StudentAccessCode != "" {
+		out.StudentAccessCode = "REDACTED"
+		out.StudentAccessCodeIndex = ""
+	}
+	return &out
 }
 
 // GetWorkspaceTemplates returns the lab's workspace templates. When none are
@@ -277,6 +470,20 @@ type JobManager struct {
 	jobs    map[string]*Job
 	dataDir string
 	mu      sync.RWMutex
+	events  *jobEventBus
+	// saveMu serializes the writes of job files: a job may be saved by its
+	// deployment and by the saver subscriber at once.
+	saveMu sync.Mutex
+	// stopSaver stops the saver subscriber (see Close).
+	stopSaver func()
+	// sequentialIDs and lastJobNumber name new jobs "lab-0001" and on; see
+	// job_ids.go.
+	sequentialIDs bool
+	lastJobNumber uint64
+	// accessCodeKey is the key of the access code index, loaded once; see
+	// lab_access_codes.go.
+	accessCodeKeyOnce sync.Once
+	accessCodeKey     []byte
 }
 
 // NewJobManager creates a new job manager with optional data directory for persistence
@@ -284,6 +491,10 @@ func NewJobManager(dataDir string) *JobManager {
 	jm := &JobManager{
 		jobs:    make(map[string]*Job),
 		dataDir: dataDir,
+		events:  &jobEventBus{},
+	}
+	if dataDir != "" {
+		jm.stopSaver = jm.Subscribe("saver", jm.saveOnTerminalStatus)
 	}
 
 	// Job loading is now done asynchronously after server starts
@@ -292,6 +503,14 @@ func NewJobManager(dataDir string) *JobManager {
 	return jm
 }
 
+// Close stops saving jobs on their terminal status, once the jobs that already
+// reached one are saved. Jobs can still be saved with SaveJob.
+func (jm *JobManager) Close() {
+	if jm.stopSaver != nil {
+		jm.stopSaver()
+	}
+}
+
 // CreateJob creates a new job and returns its ID
 func (jm *JobManager) CreateJob(config *LabConfig) string {
 	if jm == nil {
@@ -301,7 +520,7 @@ func (jm *JobManager) CreateJob(config *LabConfig) string {
 	defer jm.mu.Unlock()
 
 	now := time.Now()
-	jobID := "job-" + uuid.New().String()
+	jobID := jm.nextJobID()
 	job := &Job{
 		ID:              jobID,
 		Status:          JobStatusPending,
@@ -310,9 +529,12 @@ func (jm *JobManager) CreateJob(config *LabConfig) string {
 		Output:          []string{},
 		Config:          config,
 		DeletionRetries: make(map[string]*WorkspaceDeletionRetry),
+		History:         []StatusChange{{Status: JobStatusPending, At: now}},
+		events:          jm.events,
 	}
 
 	jm.jobs[jobID] = job
+	jm.events.publish(JobEvent{Type: JobEventCreated, JobID: jobID, At: now, Status: JobStatusPending})
 	return jobID
 }
 
@@ -344,11 +566,58 @@ func (jm *JobManager) UpdateJobStatus(id string, status JobStatus) error {
 	job.mu.Lock()
 	defer job.mu.Unlock()
 
-	job.Status = status
-	job.UpdatedAt = time.Now()
+	job.setStatus(status, "")
 	return nil
 }
 
+// setStatus moves the job to status, records the change in its history,
+// dropping the oldest entries past maxStatusHistory, and publishes it. The
+// caller holds j.mu.
+func (j *Job) setStatus(status JobStatus, reason string) {
+	now := time.Now()
+	if j.Status == JobStatusRunning && status != JobStatusRunning {
+		if started, ok := j.runningSince(); ok {
+			metrics.observeJob(status, now.Sub(started))
+		}
+	}
+	j.Status = status
+	j.UpdatedAt = now
+	j.History = append(j.History, StatusChange{Status: status, At: now, Reason: reason})
+	if over := len(j.History) - maxStatusHistory; over > 0 {
+		j.History = append(j.History[:0:0], j.History[over:]...)
+	}
+	j.events.publish(JobEvent{Type: JobEventStatus, JobID: j.ID, At: now, Status: status, Reason: reason})
+}
+
+// runningSince returns when the job last started to run. The caller holds j.mu.
+func (j *Job) runningSince() (time.Time, bool) {
+	for i := len(j.History) - 1; i >= 0; i-- {
+		if j.History[i].Status == JobStatusRunning {
+			return j.History[i].At, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// completedBefore reports whether the job had already completed before at
+// since it was last destroyed. A node pool scale or kubeconfig rotation runs a
+// deployed lab again, and its completion is not a new deployment. The caller
+// holds j.mu.
+func (j *Job) completedBefore(at time.Time) bool {
+	for i := len(j.History) - 1; i >= 0; i-- {
+		if !j.History[i].At.Before(at) {
+			continue
+		}
+		switch j.History[i].Status {
+		case JobStatusCompleted:
+			return true
+		case JobStatusDestroyed:
+			return false
+		}
+	}
+	return false
+}
+
 // AppendOutput appends output to a job
 func (jm *JobManager) AppendOutput(id string, line string) error {
 	jm.mu.RLock()
@@ -364,6 +633,7 @@ func (jm *JobManager) AppendOutput(id string, line string) error {
 
 	job.Output = append(job.Output, line)
 	job.UpdatedAt = time.Now()
+	job.events.publish(JobEvent{Type: JobEventOutput, JobID: id, At: job.UpdatedAt, Line: line})
 	return nil
 }
 
@@ -381,8 +651,7 @@ func (jm *JobManager) SetError(id string, err error) error {
 	defer job.mu.Unlock()
 
 	job.Error = err.Error()
-	job.Status = JobStatusFailed
-	job.UpdatedAt = time.Now()
+	job.setStatus(JobStatusFailed, job.Error)
 	return nil
 }
 
@@ -404,6 +673,96 @@ func (jm *JobManager) SetKubeconfig(id string, kubeconfig string) error {
 	return nil
 }
 
+// SetKubeVersion records the cluster's effective Kubernetes version for a job
+func (jm *JobManager) SetKubeVersion(id string, version string) error {
+	jm.mu.RLock()
+	job, exists := jm.jobs[id]
+	jm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.KubeVersion = version
+	job.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetGrafanaURL records where a job's Grafana is served
+func (jm *JobManager) SetGrafanaURL(id string, grafanaURL string) error {
+	jm.mu.RLock()
+	job, exists := jm.jobs[id]
+	jm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.GrafanaURL = grafanaURL
+	job.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetClusterInfo records what the job page shows of the lab's cluster.
+func (jm *JobManager) SetClusterInfo(id string, info *ClusterInfo) error {
+	jm.mu.RLock()
+	job, exists := jm.jobs[id]
+	jm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.Cluster = info
+	job.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetDestroyReport records the outcome of a destroy on a job.
+func (jm *JobManager) SetDestroyReport(id string, report *DestroyReport) error {
+	jm.mu.RLock()
+	job, exists := jm.jobs[id]
+	jm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.DestroyReport = report
+	job.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetQuotaReport records the quota check of a dry run on a job.
+func (jm *JobManager) SetQuotaReport(id string, report *QuotaReport) error {
+	jm.mu.RLock()
+	job, exists := jm.jobs[id]
+	jm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.QuotaReport = report
+	job.UpdatedAt = time.Now()
+	return nil
+}
+
 // defaultWorkspaceNamespace is the namespace student workspaces live in when a
 // lab does not specify one. It matches kube.DefaultNamespace.
 const defaultWorkspaceNamespace = "workshops"
@@ -418,6 +777,53 @@ func (j *Job) workspaceNamespace() string {
 	return defaultWorkspaceNamespace
 }
 
+// RecordRosterEntry adds a provisioned workspace to a job's roster. A
+// workspace already on it (EnsureWorkspace returning an existing one) keeps
+// its first entry.
+func (jm *JobManager) RecordRosterEntry(id string, entry RosterEntry) error {
+	jm.mu.RLock()
+	job, exists := jm.jobs[id]
+	jm.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	for _, e := range job.Roster {
+		if e.WorkspaceName == entry.WorkspaceName {
+			return nil
+		}
+	}
+	job.Roster = append(job.Roster, entry)
+	job.UpdatedAt = time.Now()
+	return nil
+}
+
+// RecordDetailsResend notes on a job's roster that the student with email had
+// their workspace details shown again at at. It returns how many of the
+// student's entries it marked.
+func (jm *JobManager) RecordDetailsResend(id, email string, at time.Time) (int, error) {
+	jm.mu.RLock()
+	job, exists := jm.jobs[id]
+	jm.mu.RUnlock()
+	if !exists {
+		return 0, fmt.Errorf("job %s not found", id)
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	marked := 0
+	for i := range job.Roster {
+		if strings.EqualFold(job.Roster[i].Email, email) {
+			job.Roster[i].DetailsResentAt = &at
+			marked++
+		}
+	}
+	if marked > 0 {
+		job.UpdatedAt = time.Now()
+	}
+	return marked, nil
+}
+
 // RecordCleanupEvent appends an auto-cleanup event to a job.
 func (jm *JobManager) RecordCleanupEvent(id string, count int) error {
 	jm.mu.RLock()
@@ -466,10 +872,12 @@ func (jm *JobManager) ResetJobForRetry(id string) error {
 	}
 
 	// Reset job state
-	job.Status = JobStatusPending
+	job.setStatus(JobStatusPending, "retry")
 	job.Error = ""
 	job.Output = []string{} // Clear previous output
-	job.UpdatedAt = time.Now()
+	job.DestroyReport = nil
+	job.RolledBack = false
+	job.RejectedCredentials = ""
 
 	return nil
 }
@@ -479,6 +887,8 @@ func (jm *JobManager) SaveJob(id string) error {
 	if jm.dataDir == "" {
 		return nil // Persistence disabled
 	}
+	jm.saveMu.Lock()
+	defer jm.saveMu.Unlock()
 
 	jm.mu.RLock()
 	job, exists := jm.jobs[id]
@@ -490,10 +900,11 @@ func (jm *JobManager) SaveJob(id string) error {
 
 	job.mu.RLock()
 	status := job.Status
+	isTemplate := job.IsTemplate
 	job.mu.RUnlock()
 
-	// Persist completed, destroyed, and failed jobs
-	if status != JobStatusCompleted && status != JobStatusDestroyed && status != JobStatusFailed {
+	// Persist completed, destroyed, and failed jobs, and templates
+	if !isTemplate && status != JobStatusCompleted && status != JobStatusDestroyed && status != JobStatusFailed {
 		return nil
 	}
 
@@ -555,18 +966,19 @@ func (jm *JobManager) LoadJobs() error {
 		jobFile := filepath.Join(jobsDir, entry.Name())
 		data, err := os.ReadFile(jobFile)
 		if err != nil {
-			log.Printf("Warning: failed to read job file %s: %v", jobFile, err)
+			slog.Warn("Failed to read job file", "job_file", jobFile, "error", err)
 			continue
 		}
 
 		var job Job
 		if err := json.Unmarshal(data, &job); err != nil {
-			log.Printf("Warning: failed to unmarshal job file %s: %v", jobFile, err)
+			slog.Warn("Failed to unmarshal job file", "job_file", jobFile, "error", err)
 			continue
 		}
 
-		// Load completed, destroyed, and failed jobs
-		if job.Status != JobStatusCompleted && job.Status != JobStatusDestroyed && job.Status != JobStatusFailed {
+		// Load completed, destroyed, and failed jobs, and templates, which are
+		// never executed whatever their status
+		if !job.IsTemplate && job.Status != JobStatusCompleted && job.Status != JobStatusDestroyed && job.Status != JobStatusFailed {
 			continue
 		}
 
@@ -575,15 +987,18 @@ func (jm *JobManager) LoadJobs() error {
 			job.DeletionRetries = make(map[string]*WorkspaceDeletionRetry)
 		}
 
+		job.events = jm.events
+
 		// Add to jobs map
 		jm.mu.Lock()
 		jm.jobs[job.ID] = &job
+		jm.noteJobID(job.ID)
 		jm.mu.Unlock()
 		loadedCount++
 	}
 
 	if loadedCount > 0 {
-		log.Printf("Loaded %d persisted job(s) from %s", loadedCount, jobsDir)
+		slog.Info("Loaded persisted jobs", "count", loadedCount, "dir", jobsDir)
 	}
 
 	return nil
@@ -658,6 +1073,10 @@ func (jm *JobManager) ClearDeletionRetry(jobID, wsID string) error {
 
 // RemoveJob removes a job from the manager and optionally deletes its persisted file
 func (jm *JobManager) RemoveJob(id string) error {
+	// saveMu is taken first, as in SaveJob, so that a save in flight does not
+	// write the file back once it is removed.
+	jm.saveMu.Lock()
+	defer jm.saveMu.Unlock()
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
 
@@ -674,7 +1093,7 @@ func (jm *JobManager) RemoveJob(id string) error {
 		jobsDir := filepath.Join(jm.dataDir, "jobs")
 		jobFile := filepath.Join(jobsDir, fmt.Sprintf("%s.json", id))
 		if err := os.Remove(jobFile); err != nil && !os.IsNotExist(err) {
-			log.Printf("Warning: failed to remove job file %s: %v", jobFile, err)
+			slog.Warn("Failed to remove job file", "job_file", jobFile, "error", err)
 			// Don't fail the operation if file removal fails
 		}
 	}
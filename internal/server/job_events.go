@@ -0,0 +1,129 @@
+package server
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// JobEventType says what changed in a job.
+type JobEventType string
+
+const (
+	// JobEventCreated is published once, when the job is created.
+	JobEventCreated JobEventType = "created"
+	// JobEventStatus is published on every status change, with the new status.
+	JobEventStatus JobEventType = "status"
+	// JobEventOutput is published for every line appended to the job's output.
+	JobEventOutput JobEventType = "output"
+)
+
+// JobEvent is a change to a job, as published to the JobManager's subscribers.
+// Subscribers that need more than the event carries read the job itself.
+type JobEvent struct {
+	Type   JobEventType
+	JobID  string
+	At     time.Time
+	Status JobStatus // the new status (JobEventCreated, JobEventStatus)
+	Reason string    // why the status changed, when known (JobEventStatus)
+	Line   string    // the appended line (JobEventOutput)
+}
+
+// Terminal reports whether the event moves the job to a status it stays in
+// until an admin acts on it again.
+func (e JobEvent) Terminal() bool {
+	if e.Type != JobEventStatus {
+		return false
+	}
+	switch e.Status {
+	case JobStatusCompleted, JobStatusFailed, JobStatusDestroyed, JobStatusDryRunCompleted, JobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// jobEventBuffer is how many events a subscriber may fall behind before the
+// next ones are dropped. A pulumi up streams a few hundred output lines.
+const jobEventBuffer = 1024
+
+// jobSubscriber receives the events in order, on its own goroutine.
+type jobSubscriber struct {
+	name   string
+	events chan JobEvent
+}
+
+// jobEventBus fans job events out to the subscribers. Publishing never blocks:
+// jobs publish while holding their lock, so a slow subscriber drops events
+// rather than stalling a deployment.
+type jobEventBus struct {
+	mu          sync.RWMutex
+	subscribers []*jobSubscriber
+}
+
+func (b *jobEventBus) subscribe(name string, handle func(JobEvent)) (unsubscribe func()) {
+	sub := &jobSubscriber{name: name, events: make(chan JobEvent, jobEventBuffer)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range sub.events {
+			handle(e)
+		}
+	}()
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, sub)
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			for i, s := range b.subscribers {
+				if s == sub {
+					b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+					break
+				}
+			}
+			close(sub.events)
+		})
+		<-done
+	}
+}
+
+func (b *jobEventBus) publish(e JobEvent) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subscribers {
+		select {
+		case sub.events <- e:
+		default:
+			slog.Warn("Job events: subscriber is behind, event dropped", "subscriber", sub.name, "event", e.Type, "job_id", e.JobID)
+		}
+	}
+}
+
+// Subscribe calls handle for every job event, in order, on a goroutine of its
+// own. name identifies the subscriber in logs. The returned function stops the
+// subscription once the events already published are handled; handle must not
+// call it.
+func (jm *JobManager) Subscribe(name string, handle func(JobEvent)) (unsubscribe func()) {
+	return jm.events.subscribe(name, handle)
+}
+
+// saveOnTerminalStatus persists a job once it reaches a terminal status, so
+// that every path to one is saved, not only those that call SaveJob.
+func (jm *JobManager) saveOnTerminalStatus(e JobEvent) {
+	if !e.Terminal() {
+		return
+	}
+	if _, exists := jm.GetJob(e.JobID); !exists {
+		return // removed since
+	}
+	if err := jm.SaveJob(e.JobID); err != nil {
+		slog.Warn("Failed to persist job", "job_id", e.JobID, "error", err)
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder collects the events a subscriber receives.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []JobEvent
+}
+
+func (r *eventRecorder) record(e JobEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) terminal() []JobEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []JobEvent
+	for _, e := range r.events {
+		if e.Terminal() {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestJobEvents_TerminalStatusReachesEverySubscriber(t *testing.T) {
+	jm := NewJobManager("")
+	recorders := []*eventRecorder{{}, {}, {}}
+	for i, rec := range recorders {
+		defer jm.Subscribe([]string{"metrics", "notifier", "streamer"}[i], rec.record)()
+	}
+
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab"})
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	jm.AppendOutput(jobID, "Running pulumi up...")
+	jm.SetError(jobID, errors.New("pulumi up failed"))
+
+	for _, rec := range recorders {
+		require.Eventually(t, func() bool { return len(rec.terminal()) == 1 }, time.Second, time.Millisecond)
+		e := rec.terminal()[0]
+		assert.Equal(t, jobID, e.JobID)
+		assert.Equal(t, JobStatusFailed, e.Status)
+		assert.Equal(t, "pulumi up failed", e.Reason)
+
+		rec.mu.Lock()
+		types := []JobEventType{}
+		for _, e := range rec.events {
+			types = append(types, e.Type)
+		}
+		rec.mu.Unlock()
+		assert.Equal(t, []JobEventType{JobEventCreated, JobEventStatus, JobEventOutput, JobEventStatus}, types, "events arrive in order")
+	}
+}
+
+func TestJobEvents_PublishingDoesNotWaitForASlowSubscriber(t *testing.T) {
+	jm := NewJobManager("")
+	release := make(chan struct{})
+	// Unsubscribing waits for the subscriber, so it is released first.
+	defer jm.Subscribe("stuck", func(JobEvent) { <-release })()
+	defer close(release)
+
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab"})
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*jobEventBuffer; i++ {
+			jm.AppendOutput(jobID, "line")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishing blocked on a subscriber that does not keep up")
+	}
+}
+
+func TestJobEvents_Unsubscribe(t *testing.T) {
+	jm := NewJobManager("")
+	rec := &eventRecorder{}
+	unsubscribe := jm.Subscribe("streamer", rec.record)
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab"})
+	require.Eventually(t, func() bool { rec.mu.Lock(); defer rec.mu.Unlock(); return len(rec.events) == 1 }, time.Second, time.Millisecond)
+
+	unsubscribe()
+	unsubscribe() // stopping twice is harmless
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+	time.Sleep(20 * time.Millisecond)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Len(t, rec.events, 1)
+}
+
+// newTestJobManager is NewJobManager(dataDir) for a test: its saver is stopped
+// when the test ends, before the test's temporary directories are removed.
+func newTestJobManager(t *testing.T, dataDir string) *JobManager {
+	jm := NewJobManager(dataDir)
+	t.Cleanup(jm.Close)
+	return jm
+}
+
+func TestJobEvents_SaverPersistsTerminalStatuses(t *testing.T) {
+	dataDir := t.TempDir()
+	jm := newTestJobManager(t, dataDir)
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab"})
+	jobFile := filepath.Join(dataDir, "jobs", jobID+".json")
+
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	time.Sleep(20 * time.Millisecond)
+	assert.NoFileExists(t, jobFile, "a running job is not persisted")
+
+	// A failure no code path saves explicitly is persisted all the same.
+	jm.SetError(jobID, errors.New("failed to create job directory"))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(jobFile)
+		return err == nil
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// exportedJob is a job as ExportJobs writes it. Like redactedJob, the outer
+// fields shadow the job's own: the kubeconfig is left out, and the config loses
+// the provider credentials too, as the export is meant to leave EasyLab for a
+// log store.
+type exportedJob struct {
+	*Job
+	Config     *LabConfig `json:"config,omitempty"`
+	Kubeconfig string     `json:"kubeconfig,omitempty"`
+}
+
+// exportConfig returns a copy of the config without any secret it carries.
+func exportConfig(c *LabConfig) *LabConfig {
+	if c == nil {
+		return nil
+	}
+	out := *c.redacted()
+	for _, secret := range []*string{&out.OvhApplicationKey, &out.OvhApplicationSecret, &out.OvhConsumerKey, &out.AzureClientSecret} {
+		if *secret != "" {
+			*secret = "REDACTED"
+		}
+	}
+	if len(out.WebhookURLs) > 0 {
+		out.WebhookURLs = make([]string, len(c.WebhookURLs))
+		for i, u := range c.WebhookURLs {
+			out.WebhookURLs[i] = webhookTarget(u)
+		}
+	}
+	return &out
+}
+
+// exportParams reads the since (RFC 3339) and fields (comma-separated JSON
+// keys) query parameters of ExportJobs.
+func exportParams(r *http.Request) (since time.Time, fields []string, err error) {
+	if v := r.URL.Query().Get("since"); v != "" {
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			return time.Time{}, nil, fmt.Errorf("invalid since %q, want an RFC 3339 time", v)
+		}
+	}
+	for _, f := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return since, fields, nil
+}
+
+// exportLine encodes one job for the export, keeping only fields when set.
+func exportLine(job *Job, fields []string) ([]byte, error) {
+	job.mu.RLock()
+	line, err := json.Marshal(exportedJob{Job: job, Config: exportConfig(job.Config)})
+	job.mu.RUnlock()
+	if err != nil || len(fields) == 0 {
+		return line, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(line, &all); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		if v, ok := all[f]; ok {
+			selected[f] = v
+		}
+	}
+	return json.Marshal(selected)
+}
+
+// ExportJobs streams every job as newline-delimited JSON, oldest first, for
+// log stores such as Elasticsearch or Loki. since keeps the jobs updated at or
+// after a time, for incremental exports, event the labs of one event, and
+// fields the listed top-level keys. Secrets are redacted and kubeconfigs left
+// out.
+//
+//	GET /api/v1/jobs/export?since=2026-03-01T00:00:00Z&event=devoxx-2026&fields=id,status,updated_at
+func (h *Handler) ExportJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	since, fields, err := exportParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jobs := filterJobsByEvent(h.jobManager.GetAllJobs(), r.URL.Query().Get("event"))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	rc := http.NewResponseController(w)
+	// GetAllJobs is newest first; an export reads best oldest first.
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		job.mu.RLock()
+		updated := job.UpdatedAt
+		job.mu.RUnlock()
+		if updated.Before(since) {
+			continue
+		}
+		line, err := exportLine(job, fields)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "ExportJobs: failed to encode job", "job_id", job.ID, "error", err)
+			continue
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return
+		}
+		rc.Flush()
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportLines runs ExportJobs and decodes each line of its answer.
+func exportLines(t *testing.T, h *Handler, query string) []map[string]interface{} {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ExportJobs(rec, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/export"+query, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(rec.Body.String()))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line), "every line is a JSON object: %s", scanner.Text())
+		lines = append(lines, line)
+	}
+	require.True(t, strings.HasSuffix(rec.Body.String(), "\n") || rec.Body.Len() == 0)
+	return lines
+}
+
+func TestExportJobs_NDJSONWithoutSecrets(t *testing.T) {
+	jm := NewJobManager("")
+	id := jm.CreateJob(&LabConfig{
+		StackName: "workshop", OvhApplicationSecret: "ovh-s3cret", OvhConsumerKey: "ovh-ck",
+		InstallMonitoring: true, GrafanaAdminPassword: "graf4na",
+	})
+	job, _ := jm.GetJob(id)
+	job.mu.Lock()
+	job.Kubeconfig = "apiVersion: v1\nclusters: []"
+	job.mu.Unlock()
+	jm.CreateJob(&LabConfig{StackName: "other"})
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.ExportJobs(rec, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/export", nil))
+	for _, secret := range []string{"ovh-s3cret", "ovh-ck", "graf4na", "apiVersion"} {
+		assert.NotContains(t, rec.Body.String(), secret)
+	}
+
+	lines := exportLines(t, h, "")
+	require.Len(t, lines, 2)
+	assert.Equal(t, id, lines[0]["id"], "oldest first")
+	config := lines[0]["config"].(map[string]interface{})
+	assert.Equal(t, "workshop", config["stack_name"])
+	assert.Equal(t, "REDACTED", config["ovh_application_secret"])
+}
+
+func TestExportJobs_Since(t *testing.T) {
+	jm := NewJobManager("")
+	old := jm.CreateJob(&LabConfig{StackName: "old"})
+	recent := jm.CreateJob(&LabConfig{StackName: "recent"})
+	cutoff := time.Now().Add(-time.Hour)
+	job, _ := jm.GetJob(old)
+	job.mu.Lock()
+	job.CreatedAt, job.UpdatedAt = cutoff.Add(-24*time.Hour), cutoff.Add(-time.Minute)
+	job.mu.Unlock()
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	lines := exportLines(t, h, "?since="+cutoff.UTC().Format(time.RFC3339))
+	require.Len(t, lines, 1)
+	assert.Equal(t, recent, lines[0]["id"])
+
+	rec := httptest.NewRecorder()
+	h.ExportJobs(rec, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/export?since=yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestExportJobs_Fields(t *testing.T) {
+	jm := NewJobManager("")
+	id := jm.CreateJob(&LabConfig{StackName: "workshop"})
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	lines := exportLines(t, h, "?fields=id,%20status,unknown")
+	require.Len(t, lines, 1)
+	assert.Equal(t, map[string]interface{}{"id": id, "status": "pending"}, lines[0])
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// How new jobs are named, the server.job_ids setting.
+const (
+	// JobIDsUUID names jobs "job-" and a random UUID, the default.
+	JobIDsUUID = "uuid"
+	// JobIDsSequential names jobs "lab-0001", "lab-0002" and so on.
+	JobIDsSequential = "sequential"
+)
+
+// sequentialJobIDPrefix starts the sequential job IDs.
+const sequentialJobIDPrefix = "lab-"
+
+// jobCounterFile, in the data directory, keeps the number of the last
+// sequential job ID, so that the numbering goes on after a restart.
+const jobCounterFile = "job-counter"
+
+// UseSequentialIDs names the jobs created from now on "lab-0001", "lab-0002"
+// and so on instead of "job-<uuid>", carrying on from the last number saved in
+// the data directory.
+func (jm *JobManager) UseSequentialIDs() error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	jm.sequentialIDs = true
+	if jm.dataDir == "" {
+		return nil
+	}
+	path := filepath.Join(jm.dataDir, jobCounterFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read job counter: %w", err)
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid job counter in %s: %q", path, strings.TrimSpace(string(data)))
+	}
+	jm.lastJobNumber = max(jm.lastJobNumber, n)
+	return nil
+}
+
+// nextJobID returns the ID of a new job. jm.mu must be held.
+func (jm *JobManager) nextJobID() string {
+	if !jm.sequentialIDs {
+		return "job-" + uuid.New().String()
+	}
+	// Skip the numbers in use, should the counter file have been lost.
+	var id string
+	for {
+		jm.lastJobNumber++
+		id = fmt.Sprintf("%s%04d", sequentialJobIDPrefix, jm.lastJobNumber)
+		if !jm.jobIDTaken(id) {
+			break
+		}
+	}
+	if err := jm.saveJobCounter(); err != nil {
+		slog.Warn("Failed to save job counter, job IDs may be reused after a restart", "job_id", id, "error", err)
+	}
+	return id
+}
+
+// jobIDTaken reports whether a job, loaded or only saved, already has id.
+// jm.mu must be held.
+func (jm *JobManager) jobIDTaken(id string) bool {
+	if _, exists := jm.jobs[id]; exists {
+		return true
+	}
+	if jm.dataDir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(jm.dataDir, "jobs", id+".json"))
+	return err == nil
+}
+
+// noteJobID moves the counter past the number of a loaded sequential job ID.
+// jm.mu must be held.
+func (jm *JobManager) noteJobID(id string) {
+	digits, ok := strings.CutPrefix(id, sequentialJobIDPrefix)
+	if !ok {
+		return
+	}
+	if n, err := strconv.ParseUint(digits, 10, 64); err == nil {
+		jm.lastJobNumber = max(jm.lastJobNumber, n)
+	}
+}
+
+// saveJobCounter writes the number of the last sequential job ID to the data
+// directory. jm.mu must be held.
+func (jm *JobManager) saveJobCounter() error {
+	if jm.dataDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(jm.dataDir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(jm.dataDir, jobCounterFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(jm.lastJobNumber, 10)+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobManager_UUIDJobIDsByDefault(t *testing.T) {
+	jm := NewJobManager("")
+	id := jm.CreateJob(&LabConfig{StackName: "a"})
+	assert.True(t, strings.HasPrefix(id, "job-"), id)
+	assert.Len(t, id, len("job-")+36)
+}
+
+func TestJobManager_SequentialJobIDs(t *testing.T) {
+	jm := NewJobManager("")
+	require.NoError(t, jm.UseSequentialIDs())
+
+	assert.Equal(t, "lab-0001", jm.CreateJob(&LabConfig{StackName: "a"}))
+	assert.Equal(t, "lab-0002", jm.CreateJob(&LabConfig{StackName: "b"}))
+
+	jm.lastJobNumber = 9999
+	assert.Equal(t, "lab-10000", jm.CreateJob(&LabConfig{StackName: "c"}), "the numbers outgrow the padding")
+}
+
+func TestJobManager_SequentialJobIDs_PersistAcrossRestart(t *testing.T) {
+	dataDir := t.TempDir()
+	jm := newTestJobManager(t, dataDir)
+	require.NoError(t, jm.UseSequentialIDs())
+	jm.CreateJob(&LabConfig{StackName: "a"})
+	assert.Equal(t, "lab-0002", jm.CreateJob(&LabConfig{StackName: "b"}))
+
+	counter, err := os.ReadFile(filepath.Join(dataDir, jobCounterFile))
+	require.NoError(t, err)
+	assert.Equal(t, "2\n", string(counter))
+
+	// The jobs were never saved: the counter alone carries the numbering on.
+	restarted := newTestJobManager(t, dataDir)
+	require.NoError(t, restarted.UseSequentialIDs())
+	require.NoError(t, restarted.LoadJobs())
+	assert.Equal(t, "lab-0003", restarted.CreateJob(&LabConfig{StackName: "c"}))
+}
+
+func TestJobManager_SequentialJobIDs_SkipSavedJobs(t *testing.T) {
+	dataDir := t.TempDir()
+	jm := newTestJobManager(t, dataDir)
+	require.NoError(t, jm.UseSequentialIDs())
+	for _, name := range []string{"a", "b", "c"} {
+		id := jm.CreateJob(&LabConfig{StackName: name})
+		require.NoError(t, jm.UpdateJobStatus(id, JobStatusCompleted))
+		require.NoError(t, jm.SaveJob(id))
+	}
+	require.NoError(t, os.Remove(filepath.Join(dataDir, jobCounterFile)))
+
+	// The jobs load in the background: one created meanwhile skips their IDs.
+	restarted := newTestJobManager(t, dataDir)
+	require.NoError(t, restarted.UseSequentialIDs())
+	assert.Equal(t, "lab-0004", restarted.CreateJob(&LabConfig{StackName: "d"}), "a lost counter does not reuse an ID")
+
+	reloaded := newTestJobManager(t, dataDir)
+	require.NoError(t, os.Remove(filepath.Join(dataDir, jobCounterFile)))
+	require.NoError(t, reloaded.UseSequentialIDs())
+	require.NoError(t, reloaded.LoadJobs())
+	assert.Equal(t, uint64(3), reloaded.lastJobNumber, "loading the jobs moves the counter past them")
+}
+
+func TestJobManager_SequentialJobIDs_InvalidCounter(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, jobCounterFile), []byte("lots\n"), 0644))
+	jm := newTestJobManager(t, dataDir)
+	assert.ErrorContains(t, jm.UseSequentialIDs(), `invalid job counter`)
+}
+
+func TestJobManager_SequentialJobIDs_Concurrent(t *testing.T) {
+	jm := newTestJobManager(t, t.TempDir())
+	require.NoError(t, jm.UseSequentialIDs())
+
+	const workers, perWorker = 8, 25
+	ids := make(chan string, workers*perWorker)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				ids <- jm.CreateJob(&LabConfig{StackName: "lab"})
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		assert.False(t, seen[id], "duplicate ID %s", id)
+		seen[id] = true
+	}
+	assert.Len(t, seen, workers*perWorker)
+	assert.Equal(t, uint64(workers*perWorker), jm.lastJobNumber)
+}
@@ -329,7 +329,7 @@ func TestJobManager_RemoveJob_NotFound(t *testing.T) {
 
 func TestJobManager_SaveJob(t *testing.T) {
 	tempDir := t.TempDir()
-	jm := NewJobManager(tempDir)
+	jm := newTestJobManager(t, tempDir)
 
 	config := &LabConfig{StackName: "test"}
 	jobID := jm.CreateJob(config)
@@ -376,7 +376,7 @@ func TestJobManager_LoadJobs(t *testing.T) {
 	tempDir := t.TempDir()
 
 	// Create and save a job
-	jm1 := NewJobManager(tempDir)
+	jm1 := newTestJobManager(t, tempDir)
 	config := &LabConfig{StackName: "test-stack"}
 	jobID := jm1.CreateJob(config)
 	jm1.UpdateJobStatus(jobID, JobStatusCompleted)
@@ -678,6 +678,93 @@ func TestJobManager_ResetJobForRetry_NotFound(t *testing.T) {
 	}
 }
 
+// --- Status history tests ---
+
+func TestJobManager_StatusHistory(t *testing.T) {
+	jm := NewJobManager("")
+	id := jm.CreateJob(&LabConfig{StackName: "test"})
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.SetError(id, errors.New("quota exceeded"))
+	jm.ResetJobForRetry(id)
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+
+	job, _ := jm.GetJob(id)
+	job.mu.RLock()
+	history := append([]StatusChange(nil), job.History...)
+	job.mu.RUnlock()
+
+	want := []StatusChange{
+		{Status: JobStatusPending},
+		{Status: JobStatusRunning},
+		{Status: JobStatusFailed, Reason: "quota exceeded"},
+		{Status: JobStatusPending, Reason: "retry"},
+		{Status: JobStatusRunning},
+		{Status: JobStatusCompleted},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("History has %d entries, want %d: %+v", len(history), len(want), history)
+	}
+	for i, got := range history {
+		if got.Status != want[i].Status || got.Reason != want[i].Reason {
+			t.Errorf("History[%d] = %s %q, want %s %q", i, got.Status, got.Reason, want[i].Status, want[i].Reason)
+		}
+		if got.At.IsZero() {
+			t.Errorf("History[%d] has no timestamp", i)
+		}
+		if i > 0 && got.At.Before(history[i-1].At) {
+			t.Errorf("History[%d] is older than the entry before it", i)
+		}
+	}
+}
+
+func TestJobManager_StatusHistory_Bounded(t *testing.T) {
+	jm := NewJobManager("")
+	id := jm.CreateJob(&LabConfig{StackName: "test"})
+	for i := 0; i < maxStatusHistory+10; i++ {
+		jm.UpdateJobStatus(id, JobStatusRunning)
+	}
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+
+	job, _ := jm.GetJob(id)
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	if len(job.History) != maxStatusHistory {
+		t.Fatalf("History has %d entries, want %d", len(job.History), maxStatusHistory)
+	}
+	if job.History[0].Status != JobStatusRunning {
+		t.Errorf("History[0] = %s, want the oldest entries dropped first", job.History[0].Status)
+	}
+	if last := job.History[len(job.History)-1]; last.Status != JobStatusCompleted {
+		t.Errorf("last History entry = %s, want %s", last.Status, JobStatusCompleted)
+	}
+}
+
+func TestJobManager_StatusHistory_Persisted(t *testing.T) {
+	tempDir := t.TempDir()
+	jm1 := newTestJobManager(t, tempDir)
+	id := jm1.CreateJob(&LabConfig{StackName: "test"})
+	jm1.UpdateJobStatus(id, JobStatusRunning)
+	jm1.UpdateJobStatus(id, JobStatusCompleted)
+	if err := jm1.SaveJob(id); err != nil {
+		t.Fatalf("SaveJob() error = %v", err)
+	}
+
+	jm2 := NewJobManager(tempDir)
+	if err := jm2.LoadJobs(); err != nil {
+		t.Fatalf("LoadJobs() error = %v", err)
+	}
+	job, _ := jm2.GetJob(id)
+	var statuses []JobStatus
+	for _, change := range job.History {
+		statuses = append(statuses, change.Status)
+	}
+	want := []JobStatus{JobStatusPending, JobStatusRunning, JobStatusCompleted}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("loaded History = %v, want %v", statuses, want)
+	}
+}
+
 // --- RecordDeletionFailure / ClearDeletionRetry tests ---
 
 func TestJobManager_RecordDeletionFailure_FirstAttempt(t *testing.T) {
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"easylab/ovh"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+)
+
+// kubeconfigFilename is the name a lab's kubeconfig is downloaded as. Rotated
+// kubeconfigs carry their version, so a stale copy is easy to tell apart.
+func kubeconfigFilename(jobID string, version int) string {
+	if version > 1 {
+		return fmt.Sprintf("kubeconfig-%s-v%d.yaml", jobID, version)
+	}
+	return fmt.Sprintf("kubeconfig-%s.yaml", jobID)
+}
+
+// kubeconfigVersion is the version of the job's current kubeconfig, starting at
+// 1 for the one the cluster was created with. The caller holds job.mu.
+func (j *Job) kubeconfigVersion() int {
+	if j.KubeconfigVersion < 1 {
+		return 1
+	}
+	return j.KubeconfigVersion
+}
+
+// RotateKubeconfig handles POST /api/jobs/{id}/kubeconfig/rotate: it resets the
+// kubeconfig of a completed lab's OVH cluster, which revokes every copy
+// downloaded so far, and stores the new one in the job.
+func (h *Handler) RotateKubeconfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.rejectDuringMaintenance(w) {
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 5 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "kubeconfig" || pathParts[4] != "rotate" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	jobID := pathParts[2]
+	job, exists := h.jobManager.GetJob(jobID)
+	if !exists {
+		h.renderHTMLError(w, "Job Not Found", fmt.Sprintf("Job %s does not exist.", jobID))
+		return
+	}
+
+	job.mu.RLock()
+	status := job.Status
+	cfg := job.Config
+	rotatable := cfg != nil && !cfg.UseExistingCluster && cfg.Provider != "azure"
+	job.mu.RUnlock()
+	if status != JobStatusCompleted || cfg == nil {
+		h.renderHTMLError(w, "Cannot Rotate", fmt.Sprintf("Only the kubeconfig of a completed lab can be rotated. Current status: %s", status))
+		return
+	}
+	if !rotatable {
+		h.renderHTMLError(w, "Cannot Rotate", "Only the kubeconfig of an OVHcloud cluster EasyLab created can be rotated.")
+		return
+	}
+	ovhCreds, err := h.getOVHCredentials(w)
+	if err != nil {
+		return
+	}
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		h.renderHTMLError(w, "OVH API Error", err.Error())
+		return
+	}
+	client.Timeout = ovhCheckTimeout
+
+	// The status is checked again under the lock: a scale, a retry or a destroy
+	// may have started since.
+	const reason = "Rotating the kubeconfig"
+	job.mu.Lock()
+	if job.Status != JobStatusCompleted {
+		status := job.Status
+		job.mu.Unlock()
+		h.renderHTMLError(w, "Cannot Rotate", fmt.Sprintf("Only the kubeconfig of a completed lab can be rotated. Current status: %s", status))
+		return
+	}
+	job.Config.setOVHCredentials(ovhCreds)
+	job.setStatus(JobStatusRunning, reason)
+	job.mu.Unlock()
+	h.jobManager.AppendOutput(jobID, reason+"...")
+	slog.InfoContext(r.Context(), reason, "job_id", jobID)
+
+	go func() {
+		version, err := h.pulumiExec.RotateKubeconfig(jobID, func(kubeID string) (string, error) {
+			return ovh.ResetKubeconfig(client, serviceName, kubeID)
+		})
+		h.jobManager.finishKubeconfigRotation(jobID, version, err)
+	}()
+
+	writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
+		<div class="job-created">
+			<h3>Rotating the kubeconfig</h3>
+			<div id="job-status" hx-get="/api/jobs/%s/status" hx-trigger="load, every 10s" hx-swap="innerHTML">
+				<p>Loading status...</p>
+			</div>
+		</div>`, jobID))
+}
+
+// finishKubeconfigRotation records the outcome of a rotation in the job. The lab
+// stays completed either way; version is 0 when the kubeconfig was not reset.
+func (jm *JobManager) finishKubeconfigRotation(jobID string, version int, rotateErr error) {
+	job, exists := jm.GetJob(jobID)
+	if !exists {
+		return
+	}
+	var line string
+	switch {
+	case version == 0:
+		line = fmt.Sprintf("Kubeconfig rotation failed, the current kubeconfig still works: %v", rotateErr)
+	case rotateErr != nil:
+		line = fmt.Sprintf("Kubeconfig rotated (v%d), but the Pulumi state was not updated: %v", version, rotateErr)
+	default:
+		line = fmt.Sprintf("Kubeconfig rotated (v%d), previously downloaded copies no longer work", version)
+	}
+	job.mu.Lock()
+	job.setStatus(JobStatusCompleted, line)
+	job.mu.Unlock()
+
+	jm.AppendOutput(jobID, line)
+	if err := jm.SaveJob(jobID); err != nil {
+		slog.Warn("Failed to persist job", "job_id", jobID, "error", err)
+	}
+}
+
+// kubeProviderURN is the URN of the Kubernetes provider the lab program builds
+// from the cluster's kubeconfig.
+func kubeProviderURN(stackName string) string {
+	return labResourceURN(stackName, "pulumi:providers:kubernetes", "k8sProvider")
+}
+
+// RotateKubeconfig resets the kubeconfig of a lab's cluster with reset, given
+// the cluster ID from the stack outputs, and stores the new kubeconfig in the
+// job under a new version. It then refreshes the cluster in the Pulumi state and
+// updates the Kubernetes provider, so later updates of the lab use the new
+// kubeconfig. It returns the new version, or 0 if the kubeconfig was not reset;
+// a failure after the reset comes with the version, the new kubeconfig being
+// stored already.
+func (pe *PulumiExecutor) RotateKubeconfig(jobID string, reset func(kubeID string) (string, error)) (int, error) {
+	runCtx, end := pe.beginRun(jobID, "rotate-kubeconfig", false)
+	defer end()
+	job, exists := pe.jobManager.GetJob(jobID)
+	if !exists {
+		return 0, fmt.Errorf("job %s not found", jobID)
+	}
+	job.mu.RLock()
+	config := *job.Config
+	job.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(runCtx, pulumiExecutionTimeout)
+	defer cancel()
+	stack, err := pe.selectLabStack(ctx, jobID, &config)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := pe.cleanupJobDirectory(jobID, true); err != nil {
+			slog.Warn("Failed to cleanup job directory", "job_id", jobID, "error", err)
+		}
+	}()
+
+	outputs, err := stack.Outputs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the stack outputs: %w", err)
+	}
+	kubeID := ""
+	if out, ok := outputs["kubeClusterId"]; ok {
+		kubeID = pe.outputValueToString(out.Value)
+	}
+	if kubeID == "" {
+		return 0, fmt.Errorf("the stack outputs have no kubeClusterId")
+	}
+	kubeconfig, err := reset(kubeID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := pe.jobManager.SetKubeconfig(jobID, kubeconfig); err != nil {
+		return 0, err
+	}
+	job.mu.Lock()
+	version := job.kubeconfigVersion() + 1
+	job.KubeconfigVersion = version
+	job.mu.Unlock()
+	if err := pe.jobManager.SaveJob(jobID); err != nil {
+		slog.Warn("Failed to persist job", "job_id", jobID, "error", err)
+	}
+	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Kubeconfig of cluster %s reset, stored as v%d", kubeID, version))
+
+	writer := &jobOutputWriter{jobID: jobID, jobManager: pe.jobManager}
+	defer writer.Flush()
+	pe.jobManager.AppendOutput(jobID, "Refreshing the cluster in the Pulumi state...")
+	clusterURN := labResourceURN(config.StackName, "ovh:CloudProject/kube:Kube", "kubeCluster")
+	if _, err := stack.Refresh(ctx, optrefresh.ProgressStreams(writer), optrefresh.Target([]string{clusterURN})); err != nil {
+		return version, fmt.Errorf("pulumi refresh failed: %w", err)
+	}
+	pe.jobManager.AppendOutput(jobID, "Updating the Kubernetes provider...")
+	if _, err := stack.Up(ctx, optup.ProgressStreams(writer), optup.Target([]string{kubeProviderURN(config.StackName)})); err != nil {
+		return version, fmt.Errorf("pulumi up failed: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubeconfigFilename(t *testing.T) {
+	assert.Equal(t, "kubeconfig-job-1.yaml", kubeconfigFilename("job-1", 1))
+	assert.Equal(t, "kubeconfig-job-1-v3.yaml", kubeconfigFilename("job-1", 3))
+}
+
+func TestKubeProviderURN(t *testing.T) {
+	assert.Equal(t, "urn:pulumi:lab-1::easylab::pulumi:providers:kubernetes::k8sProvider", kubeProviderURN("organization/easylab/lab-1"))
+}
+
+// rotateRequest posts to the job's kubeconfig rotation endpoint.
+func rotateRequest(h *Handler, jobID string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/jobs/"+jobID+"/kubeconfig/rotate", nil)
+	w := httptest.NewRecorder()
+	h.RotateKubeconfig(w, r)
+	return w
+}
+
+func TestRotateKubeconfig_Refusals(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}, NewCredentialsManager(), nil, nil, nil)
+
+	failed := jm.CreateJob(&LabConfig{StackName: "lab", Provider: "ovh"})
+	jm.UpdateJobStatus(failed, JobStatusFailed)
+	assert.Contains(t, rotateRequest(h, failed).Body.String(), "Only the kubeconfig of a completed lab can be rotated")
+
+	byok := jm.CreateJob(&LabConfig{StackName: "lab", UseExistingCluster: true})
+	jm.UpdateJobStatus(byok, JobStatusCompleted)
+	assert.Contains(t, rotateRequest(h, byok).Body.String(), "Only the kubeconfig of an OVHcloud cluster EasyLab created")
+
+	job, _ := jm.GetJob(byok)
+	assert.Equal(t, JobStatusCompleted, job.Status)
+}
+
+func TestRotateKubeconfig_RecordsAFailedRotationAndKeepsTheLab(t *testing.T) {
+	jm := NewJobManager("")
+	cm := NewCredentialsManager()
+	require.NoError(t, cm.SetCredentials(&OVHCredentials{ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p", Endpoint: "ovh-eu"}))
+	// No job directory: the lab's Pulumi state cannot be found.
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}, cm, nil, nil, nil)
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab", Provider: "ovh"})
+	require.NoError(t, jm.SetKubeconfig(jobID, "old"))
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+
+	assert.Contains(t, rotateRequest(h, jobID).Body.String(), "Rotating the kubeconfig")
+
+	job, _ := jm.GetJob(jobID)
+	require.Eventually(t, func() bool {
+		job.mu.RLock()
+		defer job.mu.RUnlock()
+		return job.Status == JobStatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	assert.Equal(t, "old", job.Kubeconfig)
+	assert.Equal(t, 1, job.kubeconfigVersion())
+	assert.Contains(t, job.History[len(job.History)-1].Reason, "Kubeconfig rotation failed")
+}
+
+func TestFinishKubeconfigRotation(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{})
+
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	jm.finishKubeconfigRotation(jobID, 2, nil)
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, JobStatusCompleted, job.Status)
+	assert.Equal(t, "Kubeconfig rotated (v2), previously downloaded copies no longer work", job.History[len(job.History)-1].Reason)
+
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	jm.finishKubeconfigRotation(jobID, 3, errors.New("pulumi refresh failed"))
+	assert.Equal(t, JobStatusCompleted, job.Status)
+	assert.Contains(t, job.History[len(job.History)-1].Reason, "Kubeconfig rotated (v3), but the Pulumi state was not updated")
+}
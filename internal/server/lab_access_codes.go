@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A lab's student access code (LabConfig.StudentAccessCode) is a password
+// that logs students into that lab only, so that workshops running at the same
+// time need not share the global student password. It is stored like that
+// password: bcrypt(SHA-256(code)), the login form sending the SHA-256.
+//
+// Next to it, LabConfig.StudentAccessCodeIndex is the HMAC of the SHA-256 with
+// a key of the server's, so that a login finds its lab without running bcrypt
+// against every lab: a wrong code costs no bcrypt at all.
+
+// accessCodeKeyFile, in the data directory, keeps the key of the access code
+// index, so that the labs' indexes still match after a restart.
+const accessCodeKeyFile = "access-code-key"
+
+// labAccessCodeLookup returns the lab whose access code has the SHA-256 hex
+// digest passwordHash.
+type labAccessCodeLookup func(passwordHash string) (labID string, ok bool)
+
+// SetLabAccessCodes lets students log in with a lab's access code, looked up
+// by lookup (usually JobManager.LabForAccessCode). The global student password
+// keeps opening every lab.
+func (ah *AuthHandler) SetLabAccessCodes(lookup func(passwordHash string) (string, bool)) {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	ah.labAccessCodes = lookup
+}
+
+// isHashedAccessCode reports whether code is already stored hashed: a lab
+// retried, re-created or created from a template keeps its hash.
+func isHashedAccessCode(code string) bool {
+	_, err := bcrypt.Cost([]byte(code))
+	return err == nil
+}
+
+// hashStudentAccessCode returns the stored form of an access code, which is
+// returned as is when already hashed.
+func hashStudentAccessCode(code string) (string, error) {
+	if isHashedAccessCode(code) {
+		return code, nil
+	}
+	sum := sha256.Sum256([]byte(code))
+	return hashPassword(hex.EncodeToString(sum[:]))
+}
+
+// validateStudentAccessCode checks a new, not yet hashed, access code.
+func validateStudentAccessCode(code string) error {
+	if code == "" || isHashedAccessCode(code) {
+		return nil
+	}
+	if len(code) < 8 {
+		return fmt.Errorf("the student access code must be at least 8 characters long")
+	}
+	return nil
+}
+
+// LabForAccessCode returns the newest lab, not destroyed, whose student
+// access code has the SHA-256 hex digest passwordHash. Only the lab its index
+// points to is checked with bcrypt, but for the labs hashed before there was
+// an index, which are indexed once a student logs in to them.
+func (jm *JobManager) LabForAccessCode(passwordHash string) (string, bool) {
+	index := jm.accessCodeIndex(passwordHash)
+	for _, job := range jm.GetLabs() {
+		job.mu.RLock()
+		stored, storedIndex := "", ""
+		if job.Config != nil && job.Status != JobStatusDestroyed {
+			stored = job.Config.StudentAccessCode
+			storedIndex = job.Config.StudentAccessCodeIndex
+		}
+		job.mu.RUnlock()
+		switch {
+		case stored == "":
+		case storedIndex != "":
+			if hmac.Equal([]byte(storedIndex), []byte(index)) && comparePassword(stored, passwordHash) {
+				return job.ID, true
+			}
+		case comparePassword(stored, passwordHash):
+			jm.indexAccessCode(job, stored, index)
+			return job.ID, true
+		}
+	}
+	return "", false
+}
+
+// accessCodeIndex returns the index of the access code with the SHA-256 hex
+// digest passwordHash (see LabConfig.StudentAccessCodeIndex).
+func (jm *JobManager) accessCodeIndex(passwordHash string) string {
+	jm.accessCodeKeyOnce.Do(func() {
+		jm.accessCodeKey = jm.loadAccessCodeKey()
+	})
+	m := hmac.New(sha256.New, jm.accessCodeKey)
+	m.Write([]byte(passwordHash))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// loadAccessCodeKey reads the key of the access code index from the data
+// directory, creating it the first time. Without a data directory, or when
+// the key cannot be kept, it is random: the labs indexed with the previous one
+// then need their code set again.
+func (jm *JobManager) loadAccessCodeKey() []byte {
+	if jm.dataDir != "" {
+		path := filepath.Join(jm.dataDir, accessCodeKeyFile)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if key, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil && len(key) == 32 {
+				return key
+			}
+			slog.Error("Invalid access code key, the student access codes set so far must be set again", "file", path)
+		} else if !os.IsNotExist(err) {
+			slog.Error("Failed to read the access code key, the student access codes set so far must be set again", "error", err)
+		}
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate the access code key: %v", err))
+	}
+	if jm.dataDir != "" {
+		if err := jm.saveAccessCodeKey(key); err != nil {
+			slog.Error("Failed to save the access code key, the student access codes will need to be set again after a restart", "error", err)
+		}
+	}
+	return key
+}
+
+// saveAccessCodeKey writes key to the data directory, readable by the server
+// only.
+func (jm *JobManager) saveAccessCodeKey(key []byte) error {
+	if err := os.MkdirAll(jm.dataDir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(jm.dataDir, accessCodeKeyFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// indexAccessCode records the index of job's access code, hashed as stored,
+// for a lab hashed before there was an index.
+func (jm *JobManager) indexAccessCode(job *Job, stored, index string) {
+	job.mu.Lock()
+	indexed := job.Config != nil && job.Config.StudentAccessCode == stored
+	if indexed {
+		job.Config.StudentAccessCodeIndex = index
+	}
+	job.mu.Unlock()
+	if !indexed {
+		return
+	}
+	if err := jm.SaveJob(job.ID); err != nil {
+		slog.Warn("Failed to persist the access code index of lab", "job_id", job.ID, "error", err)
+	}
+}
+
+// setStudentAccessCode hashes cfg's access code, refusing one that already
+// opens another lab: the student would land in either.
+func (h *Handler) setStudentAccessCode(cfg *LabConfig) *requestError {
+	code := cfg.StudentAccessCode
+	if code == "" || isHashedAccessCode(code) {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(code))
+	passwordHash := hex.EncodeToString(sum[:])
+	if labID, used := h.jobManager.LabForAccessCode(passwordHash); used {
+		return &requestError{Status: http.StatusConflict, Title: "Student Access Code Error", Message: fmt.Sprintf("Lab %s already uses this student access code, pick another one.", labID)}
+	}
+	hashed, err := hashStudentAccessCode(code)
+	if err != nil {
+		slog.Error("Failed to hash the student access code", "error", err)
+		return &requestError{Status: http.StatusInternalServerError, Title: "Student Access Code Error", Message: "Failed to store the student access code, please try again."}
+	}
+	cfg.StudentAccessCode = hashed
+	cfg.StudentAccessCodeIndex = h.jobManager.accessCodeIndex(passwordHash)
+	return nil
+}
+
+// studentLabScope returns the lab the student session of ctx is scoped to,
+// "" when it opens every lab.
+func studentLabScope(ctx context.Context) string {
+	labID, _ := ctx.Value(studentLabContextKey).(string)
+	return labID
+}
+
+// studentCanUseLab reports whether the student session of ctx may see and
+// request workspaces in labID.
+func studentCanUseLab(ctx context.Context, labID string) bool {
+	scope := studentLabScope(ctx)
+	return scope == "" || scope == labID
+}
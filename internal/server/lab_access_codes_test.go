@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sha256Hex is what the login form sends for a password.
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// labWithAccessCode creates a completed lab whose students log in with code.
+func labWithAccessCode(t *testing.T, jm *JobManager, name, code string) string {
+	t.Helper()
+	hashed, err := hashStudentAccessCode(code)
+	require.NoError(t, err)
+	jobID := jm.CreateJob(&LabConfig{StackName: name, StudentAccessCode: hashed, StudentAccessCodeIndex: jm.accessCodeIndex(sha256Hex(code))})
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+	return jobID
+}
+
+// studentLogin posts the student login form with password.
+func studentLogin(ah *AuthHandler, password string) *httptest.ResponseRecorder {
+	form := url.Values{"password_hash": {sha256Hex(password)}, "email": {"student@example.com"}}
+	req := httptest.NewRequest(http.MethodPost, "/student/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	ah.HandleStudentLogin(w, req)
+	return w
+}
+
+// sessionToken returns the student session cookie a login set, "" if none.
+func sessionToken(w *httptest.ResponseRecorder) string {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == StudentSessionCookieName {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestHashStudentAccessCode(t *testing.T) {
+	hashed, err := hashStudentAccessCode("workshop-a")
+	require.NoError(t, err)
+	assert.NotContains(t, hashed, "workshop-a")
+	assert.True(t, comparePassword(hashed, sha256Hex("workshop-a")))
+
+	again, err := hashStudentAccessCode(hashed)
+	require.NoError(t, err)
+	assert.Equal(t, hashed, again, "a hashed code is kept as is")
+}
+
+func TestValidateStudentAccessCode(t *testing.T) {
+	assert.NoError(t, validateStudentAccessCode(""))
+	assert.NoError(t, validateStudentAccessCode("workshop-a"))
+	assert.EqualError(t, validateStudentAccessCode("short"), "the student access code must be at least 8 characters long")
+
+	hashed, err := hashStudentAccessCode("workshop-a")
+	require.NoError(t, err)
+	assert.NoError(t, validateStudentAccessCode(hashed))
+}
+
+func TestLabForAccessCode(t *testing.T) {
+	jm := NewJobManager("")
+	labA := labWithAccessCode(t, jm, "workshop-a", "code-of-a")
+	labB := labWithAccessCode(t, jm, "workshop-b", "code-of-b")
+	jm.CreateJob(&LabConfig{StackName: "open"})
+
+	got, ok := jm.LabForAccessCode(sha256Hex("code-of-a"))
+	assert.True(t, ok)
+	assert.Equal(t, labA, got)
+	got, ok = jm.LabForAccessCode(sha256Hex("code-of-b"))
+	assert.True(t, ok)
+	assert.Equal(t, labB, got)
+	_, ok = jm.LabForAccessCode(sha256Hex("wrong-code"))
+	assert.False(t, ok)
+
+	jm.UpdateJobStatus(labA, JobStatusDestroyed)
+	_, ok = jm.LabForAccessCode(sha256Hex("code-of-a"))
+	assert.False(t, ok, "a destroyed lab's code no longer logs in")
+}
+
+func TestLabForAccessCode_OnlyChecksTheIndexedLab(t *testing.T) {
+	jm := NewJobManager("")
+	hashed, err := hashStudentAccessCode("code-of-a")
+	require.NoError(t, err)
+	jobID := jm.CreateJob(&LabConfig{StackName: "a", StudentAccessCode: hashed, StudentAccessCodeIndex: jm.accessCodeIndex(sha256Hex("another-code"))})
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+
+	_, ok := jm.LabForAccessCode(sha256Hex("code-of-a"))
+	assert.False(t, ok, "a lab whose index does not match is not compared with bcrypt")
+}
+
+func TestLabForAccessCode_IndexesLabsHashedWithoutIndex(t *testing.T) {
+	jm := newTestJobManager(t, t.TempDir())
+	hashed, err := hashStudentAccessCode("code-of-a")
+	require.NoError(t, err)
+	jobID := jm.CreateJob(&LabConfig{StackName: "a", StudentAccessCode: hashed})
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+
+	got, ok := jm.LabForAccessCode(sha256Hex("code-of-a"))
+	require.True(t, ok)
+	assert.Equal(t, jobID, got)
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, jm.accessCodeIndex(sha256Hex("code-of-a")), job.Config.StudentAccessCodeIndex)
+	_, ok = jm.LabForAccessCode(sha256Hex("code-of-a"))
+	assert.True(t, ok, "found through its index from now on")
+}
+
+func TestAccessCodeKey_SurvivesRestart(t *testing.T) {
+	dataDir := t.TempDir()
+	first := newTestJobManager(t, dataDir).accessCodeIndex(sha256Hex("code-of-a"))
+
+	info, err := os.Stat(filepath.Join(dataDir, accessCodeKeyFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.Equal(t, first, newTestJobManager(t, dataDir).accessCodeIndex(sha256Hex("code-of-a")))
+	assert.NotEqual(t, first, NewJobManager("").accessCodeIndex(sha256Hex("code-of-a")), "the index is keyed")
+}
+
+func TestSetStudentAccessCode(t *testing.T) {
+	jm := NewJobManager("")
+	existing := labWithAccessCode(t, jm, "workshop-a", "code-of-a")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	cfg := &LabConfig{StudentAccessCode: "code-of-b"}
+	require.Nil(t, h.setStudentAccessCode(cfg))
+	assert.True(t, comparePassword(cfg.StudentAccessCode, sha256Hex("code-of-b")), "the code is stored hashed")
+	assert.Equal(t, jm.accessCodeIndex(sha256Hex("code-of-b")), cfg.StudentAccessCodeIndex)
+
+	reqErr := h.setStudentAccessCode(&LabConfig{StudentAccessCode: "code-of-a"})
+	require.NotNil(t, reqErr)
+	assert.Equal(t, http.StatusConflict, reqErr.Status)
+	assert.Contains(t, reqErr.Message, existing)
+}
+
+func TestHandleStudentLogin_LabAccessCode(t *testing.T) {
+	jm := NewJobManager("")
+	labA := labWithAccessCode(t, jm, "workshop-a", "code-of-a")
+	ah := createTestAuthHandler()
+	ah.SetLabAccessCodes(jm.LabForAccessCode)
+
+	w := studentLogin(ah, "code-of-a")
+	assert.Equal(t, "/student/dashboard", w.Header().Get("Location"))
+	token := sessionToken(w)
+	require.NotEmpty(t, token)
+	assert.Equal(t, labA, ah.getStudentSessionLab(token))
+
+	w = studentLogin(ah, "test-student")
+	assert.Equal(t, "/student/dashboard", w.Header().Get("Location"))
+	assert.Empty(t, ah.getStudentSessionLab(sessionToken(w)), "the global password opens every lab")
+
+	w = studentLogin(ah, "code-of-b")
+	assert.Equal(t, "/student/login?error=Invalid+password", w.Header().Get("Location"))
+	assert.Empty(t, sessionToken(w))
+}
+
+func TestHandleStudentLogin_AccessCodesWithoutGlobalPassword(t *testing.T) {
+	jm := NewJobManager("")
+	labWithAccessCode(t, jm, "workshop-a", "code-of-a")
+	ah := createTestAuthHandler()
+	ah.studentPasswordHash = ""
+
+	w := studentLogin(ah, "code-of-a")
+	assert.Equal(t, "/student/login?error=Student+login+disabled", w.Header().Get("Location"))
+
+	ah.SetLabAccessCodes(jm.LabForAccessCode)
+	w = studentLogin(ah, "code-of-a")
+	assert.Equal(t, "/student/dashboard", w.Header().Get("Location"))
+	w = studentLogin(ah, "test-student")
+	assert.Equal(t, "/student/login?error=Invalid+password", w.Header().Get("Location"))
+}
+
+func TestRequireStudentAuth_ScopesTheSession(t *testing.T) {
+	ah := createTestAuthHandler()
+	token := ah.createLabStudentSession("student@example.com", "job-a")
+
+	var scope string
+	handler := ah.RequireStudentAuth(func(w http.ResponseWriter, r *http.Request) {
+		scope = studentLabScope(r.Context())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/student/dashboard", nil)
+	req.AddCookie(&http.Cookie{Name: StudentSessionCookieName, Value: token})
+	handler(httptest.NewRecorder(), req)
+	assert.Equal(t, "job-a", scope)
+}
+
+func TestStudentLabScoping(t *testing.T) {
+	jm := NewJobManager("")
+	labA := labWithAccessCode(t, jm, "workshop-a", "code-of-a")
+	labB := labWithAccessCode(t, jm, "workshop-b", "code-of-b")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	scoped := context.WithValue(context.Background(), studentLabContextKey, labA)
+
+	listLabs := func(ctx context.Context) []string {
+		w := httptest.NewRecorder()
+		h.ListLabs(w, httptest.NewRequest(http.MethodGet, "/api/student/labs", nil).WithContext(ctx))
+		var labs []studentLab
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &labs))
+		var ids []string
+		for _, l := range labs {
+			ids = append(ids, l.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{labA}, listLabs(scoped))
+	assert.ElementsMatch(t, []string{labA, labB}, listLabs(context.Background()))
+
+	w := httptest.NewRecorder()
+	h.ListLabTemplates(w, httptest.NewRequest(http.MethodGet, "/api/student/labs/templates?lab_id="+labB, nil).WithContext(scoped))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	h.ListLabTemplates(w, httptest.NewRequest(http.MethodGet, "/api/student/labs/templates?lab_id="+labA, nil).WithContext(scoped))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, reqErr := h.createStudentWorkspace(scoped, "student@example.com", studentWorkspaceRequest{LabID: labB})
+	require.NotNil(t, reqErr)
+	assert.Equal(t, http.StatusForbidden, reqErr.Status)
+
+	form := url.Values{"lab_id": {labB}}
+	req := httptest.NewRequest(http.MethodPost, "/api/student/workspace/request", strings.NewReader(form.Encode())).WithContext(
+		context.WithValue(scoped, studentEmailContextKey, "student@example.com"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	h.RequestWorkspace(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Your access code does not open this lab")
+}
+
+func TestLabConfig_RedactsTheAccessCode(t *testing.T) {
+	hashed, err := hashStudentAccessCode("code-of-a")
+	require.NoError(t, err)
+	cfg := &LabConfig{StackName: "a", StudentAccessCode: hashed, StudentAccessCodeIndex: "index"}
+	assert.Equal(t, "REDACTED", cfg.redacted().StudentAccessCode)
+	assert.Empty(t, cfg.redacted().StudentAccessCodeIndex)
+	assert.Empty(t, cfg.redacted().GrafanaAdminPassword)
+	assert.Equal(t, hashed, cfg.StudentAccessCode, "the lab keeps its hash")
+}
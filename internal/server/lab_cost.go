@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FlavorPriceTable is the hourly price of a node of each flavor, as the admin
+// configured it.
+type FlavorPriceTable struct {
+	Currency string
+	Hourly   map[string]float64
+}
+
+// ParseFlavorPrices parses the flavor=price pairs of CostsConfig.FlavorPrices.
+func ParseFlavorPrices(prices []string, currency string) (FlavorPriceTable, error) {
+	table := FlavorPriceTable{Currency: currency, Hourly: make(map[string]float64)}
+	if table.Currency == "" {
+		table.Currency = "EUR"
+	}
+	for _, pair := range prices {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		flavor, price, ok := strings.Cut(pair, "=")
+		flavor = strings.TrimSpace(flavor)
+		if !ok || flavor == "" {
+			return FlavorPriceTable{}, fmt.Errorf("invalid flavor price %q, want flavor=price", pair)
+		}
+		hourly, err := strconv.ParseFloat(strings.TrimSpace(price), 64)
+		if err != nil || hourly < 0 {
+			return FlavorPriceTable{}, fmt.Errorf("invalid price for flavor %s: %q", flavor, price)
+		}
+		table.Hourly[flavor] = hourly
+	}
+	return table, nil
+}
+
+// SetFlavorPrices sets the price table the lab cost estimates use first.
+func (h *Handler) SetFlavorPrices(table FlavorPriceTable) {
+	h.flavorPrices = table
+}
+
+// LabCostEstimate is a rough running cost of a lab: its nodes × the hourly
+// price of their flavor × the hours the lab has been up. It leaves out the
+// gateway, storage, traffic and taxes, and assumes the node count never
+// changed, so it is an estimate and never a bill.
+type LabCostEstimate struct {
+	Estimate     bool    `json:"estimate"` // always true, for clients that show the figure
+	Flavor       string  `json:"flavor"`
+	Nodes        int     `json:"nodes"`
+	HourlyPrice  float64 `json:"hourly_price"` // per node
+	Currency     string  `json:"currency"`
+	PriceSource  string  `json:"price_source"` // "table" (FLAVOR_PRICES) or "ovh" (the OVH catalog)
+	RunningHours float64 `json:"running_hours"`
+	Running      bool    `json:"running"` // the lab is up now, so the estimate keeps growing
+	Total        float64 `json:"total"`
+}
+
+// HourlyTotal is what the lab costs per hour while it is up.
+func (e *LabCostEstimate) HourlyTotal() float64 {
+	return float64(e.Nodes) * e.HourlyPrice
+}
+
+// estimateLabCost is nodes × hourly price × the running time in hours.
+func estimateLabCost(nodes int, hourlyPrice float64, running time.Duration) float64 {
+	return float64(nodes) * hourlyPrice * running.Hours()
+}
+
+// labRunningTime adds up the time a lab's cluster was up according to its
+// status history: from each time it completed to the destroy that follows, or
+// to now while it is still up. Retries and scales in between do not stop it.
+func labRunningTime(history []StatusChange, now time.Time) (total time.Duration, running bool) {
+	var since time.Time
+	for _, change := range history {
+		switch change.Status {
+		case JobStatusCompleted:
+			if since.IsZero() {
+				since = change.At
+			}
+		case JobStatusDestroyed:
+			if !since.IsZero() {
+				total += change.At.Sub(since)
+				since = time.Time{}
+			}
+		}
+	}
+	if !since.IsZero() {
+		total += now.Sub(since)
+		running = true
+	}
+	return total, running
+}
+
+// labCostEstimate estimates what a lab has cost so far. It returns nil for labs
+// without nodes of their own (existing clusters, Azure), labs that never
+// completed, and flavors without a known price. The caller must not hold
+// job.mu.
+func (h *Handler) labCostEstimate(job *Job, now time.Time) *LabCostEstimate {
+	job.mu.RLock()
+	cfg := job.Config
+	if cfg == nil || cfg.UseExistingCluster || cfg.Provider == "azure" {
+		job.mu.RUnlock()
+		return nil
+	}
+	flavor, region, nodes := cfg.NodePoolFlavor, cfg.NetworkRegion, cfg.NodePoolDesiredNodeCount*nodePoolCount(cfg)
+	running, up := labRunningTime(job.History, now)
+	job.mu.RUnlock()
+	if running == 0 {
+		return nil
+	}
+
+	price, currency, source, ok := h.flavorHourlyPrice(region, flavor)
+	if !ok {
+		return nil
+	}
+	return &LabCostEstimate{
+		Estimate:     true,
+		Flavor:       flavor,
+		Nodes:        nodes,
+		HourlyPrice:  price,
+		Currency:     currency,
+		PriceSource:  source,
+		RunningHours: running.Hours(),
+		Running:      up,
+		Total:        estimateLabCost(nodes, price, running),
+	}
+}
+
+// flavorHourlyPrice looks the flavor up in the configured price table, then in
+// the region's OVH catalog (cached for flavorCatalogTTL).
+func (h *Handler) flavorHourlyPrice(region, flavor string) (price float64, currency, source string, ok bool) {
+	if price, ok := h.flavorPrices.Hourly[flavor]; ok {
+		return price, h.flavorPrices.Currency, "table", true
+	}
+	if h.credentialsManager == nil || !h.credentialsManager.HasCredentials("ovh") {
+		return 0, "", "", false
+	}
+	catalog, err := h.flavorCatalog(region)
+	if err != nil {
+		slog.Warn("Cost estimate: no flavor prices for region", "region", region, "error", err)
+		return 0, "", "", false
+	}
+	for _, f := range catalog {
+		if f.Name == flavor && f.HourlyPrice > 0 {
+			return f.HourlyPrice, f.Currency, "ovh", true
+		}
+	}
+	return 0, "", "", false
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlavorPrices(t *testing.T) {
+	table, err := ParseFlavorPrices([]string{" b3-8=0.0977", "b3-16 = 0.1954 ", ""}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", table.Currency)
+	assert.Equal(t, map[string]float64{"b3-8": 0.0977, "b3-16": 0.1954}, table.Hourly)
+
+	_, err = ParseFlavorPrices([]string{"b3-8"}, "EUR")
+	assert.ErrorContains(t, err, "want flavor=price")
+	_, err = ParseFlavorPrices([]string{"b3-8=cheap"}, "EUR")
+	assert.ErrorContains(t, err, "invalid price for flavor b3-8")
+}
+
+func TestEstimateLabCost(t *testing.T) {
+	// 3 nodes at 0.10/h for 2h30.
+	assert.InDelta(t, 0.75, estimateLabCost(3, 0.10, 150*time.Minute), 1e-9)
+	assert.Zero(t, estimateLabCost(3, 0.10, 0))
+}
+
+func TestLabRunningTime(t *testing.T) {
+	start := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	history := []StatusChange{
+		{Status: JobStatusRunning, At: start},
+		{Status: JobStatusCompleted, At: start.Add(10 * time.Minute)},
+		{Status: JobStatusRunning, At: start.Add(time.Hour)}, // a node pool scale
+		{Status: JobStatusCompleted, At: start.Add(70 * time.Minute)},
+		{Status: JobStatusDestroyed, At: start.Add(3*time.Hour + 10*time.Minute)},
+	}
+	running, up := labRunningTime(history, start.Add(5*time.Hour))
+	assert.Equal(t, 3*time.Hour, running)
+	assert.False(t, up)
+
+	running, up = labRunningTime(history[:4], start.Add(2*time.Hour+10*time.Minute))
+	assert.Equal(t, 2*time.Hour, running)
+	assert.True(t, up)
+
+	running, _ = labRunningTime([]StatusChange{{Status: JobStatusDryRunCompleted, At: start}}, start.Add(time.Hour))
+	assert.Zero(t, running, "a dry run costs nothing")
+}
+
+// costJob is a completed lab of 3 b3-8 nodes, up for two hours at now.
+func costJob(t *testing.T, jm *JobManager, cfg *LabConfig, now time.Time) string {
+	t.Helper()
+	jobID := jm.CreateJob(cfg)
+	job, _ := jm.GetJob(jobID)
+	job.History = []StatusChange{{Status: JobStatusCompleted, At: now.Add(-2 * time.Hour)}}
+	job.Status = JobStatusCompleted
+	return jobID
+}
+
+func TestLabCostEstimate(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	h.SetFlavorPrices(FlavorPriceTable{Currency: "EUR", Hourly: map[string]float64{"b3-8": 0.1}})
+	now := time.Now()
+
+	lab := costJob(t, jm, &LabConfig{Provider: "ovh", NodePoolFlavor: "b3-8", NodePoolDesiredNodeCount: 3}, now)
+	job, _ := jm.GetJob(lab)
+	estimate := h.labCostEstimate(job, now)
+	require.NotNil(t, estimate)
+	assert.True(t, estimate.Estimate)
+	assert.Equal(t, "table", estimate.PriceSource)
+	assert.InDelta(t, 0.6, estimate.Total, 1e-9)
+	assert.InDelta(t, 0.3, estimate.HourlyTotal(), 1e-9)
+	assert.True(t, estimate.Running)
+
+	unpriced := costJob(t, jm, &LabConfig{Provider: "ovh", NodePoolFlavor: "t1-45", NodePoolDesiredNodeCount: 1}, now)
+	job, _ = jm.GetJob(unpriced)
+	assert.Nil(t, h.labCostEstimate(job, now), "no price, no estimate")
+
+	byok := costJob(t, jm, &LabConfig{UseExistingCluster: true, NodePoolFlavor: "b3-8", NodePoolDesiredNodeCount: 3}, now)
+	job, _ = jm.GetJob(byok)
+	assert.Nil(t, h.labCostEstimate(job, now), "an existing cluster is not the lab's")
+}
+
+func TestGetJobStatusJSON_CostEstimate(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	h.SetFlavorPrices(FlavorPriceTable{Currency: "EUR", Hourly: map[string]float64{"b3-8": 0.1}})
+	jobID := costJob(t, jm, &LabConfig{Provider: "ovh", NodePoolFlavor: "b3-8", NodePoolDesiredNodeCount: 3}, time.Now())
+
+	w := httptest.NewRecorder()
+	h.GetJobStatusJSON(w, httptest.NewRequest(http.MethodGet, "/api/jobs/"+jobID+"?format=json", nil))
+
+	var body struct {
+		CostEstimate *LabCostEstimate `json:"cost_estimate"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.NotNil(t, body.CostEstimate)
+	assert.True(t, body.CostEstimate.Estimate)
+	assert.InDelta(t, 0.6, body.CostEstimate.Total, 0.01)
+}
+
+func TestMetrics(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	h.SetFlavorPrices(FlavorPriceTable{Currency: "EUR", Hourly: map[string]float64{"b3-8": 0.1}})
+	jobID := costJob(t, jm, &LabConfig{StackName: `ws "1"`, Provider: "ovh", NodePoolFlavor: "b3-8", NodePoolDesiredNodeCount: 3}, time.Now())
+
+	scrape := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.Metrics(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, scrape("").Code, "off without a token")
+
+	h.SetMetricsToken("s3cret")
+	assert.Equal(t, http.StatusUnauthorized, scrape("wrong").Code)
+
+	w := scrape("s3cret")
+	require.Equal(t, http.StatusOK, w.Code)
+	labels := `{lab="` + jobID + `",name="ws \"1\"",flavor="b3-8",currency="EUR"}`
+	assert.Contains(t, w.Body.String(), "# TYPE easylab_lab_cost_estimate gauge")
+	assert.Contains(t, w.Body.String(), "easylab_lab_cost_estimate"+labels+" 0.6")
+	assert.Contains(t, w.Body.String(), "easylab_lab_hourly_cost_estimate"+labels+" 0.3")
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxEventLength caps the event a lab is tagged with: it is a label for the
+// labs list, such as "devoxx-2026" or "cohort B".
+const maxEventLength = 64
+
+// validateEvent checks the event or cohort tag of a lab.
+func validateEvent(event string) error {
+	if len(event) > maxEventLength {
+		return fmt.Errorf("event is too long (%d characters, at most %d)", len(event), maxEventLength)
+	}
+	if strings.IndexFunc(event, unicode.IsControl) >= 0 {
+		return fmt.Errorf("event must fit on one line")
+	}
+	return nil
+}
+
+// labEvent is one entry of the event filter of the labs list.
+type labEvent struct {
+	Name     string
+	Count    int
+	Selected bool
+}
+
+// jobEvent returns the event the job's lab is tagged with, "" when untagged.
+// The caller holds job.mu.
+func jobEvent(job *Job) string {
+	if job.Config == nil {
+		return ""
+	}
+	return job.Config.Event
+}
+
+// labEvents groups the jobs by event, sorted by name, with the number of labs
+// in each. Untagged labs are left out.
+func labEvents(jobs []*Job, selected string) []labEvent {
+	counts := make(map[string]int)
+	for _, job := range jobs {
+		job.mu.RLock()
+		event := jobEvent(job)
+		job.mu.RUnlock()
+		if event != "" {
+			counts[event]++
+		}
+	}
+	events := make([]labEvent, 0, len(counts))
+	for name, count := range counts {
+		events = append(events, labEvent{Name: name, Count: count, Selected: name == selected})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	return events
+}
+
+// filterJobsByEvent keeps the jobs tagged with event, in order. An empty event
+// keeps them all.
+func filterJobsByEvent(jobs []*Job, event string) []*Job {
+	if event == "" {
+		return jobs
+	}
+	var kept []*Job
+	for _, job := range jobs {
+		job.mu.RLock()
+		match := jobEvent(job) == event
+		job.mu.RUnlock()
+		if match {
+			kept = append(kept, job)
+		}
+	}
+	return kept
+}
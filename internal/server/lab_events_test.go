@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEvent(t *testing.T) {
+	assert.NoError(t, validateEvent(""))
+	assert.NoError(t, validateEvent("Devoxx 2026 — cohort B"))
+	assert.Error(t, validateEvent(strings.Repeat("x", maxEventLength+1)))
+	assert.Error(t, validateEvent("devoxx\n2026"))
+}
+
+func TestCreateLab_InvalidEvent(t *testing.T) {
+	jm := newTestJobManager(t, t.TempDir())
+	h := NewHandler(jm, NewPulumiExecutor(jm, t.TempDir()), NewCredentialsManager(), nil, nil, nil)
+
+	form := url.Values{"use_existing_cluster": {"true"}, "stack_name": {"lab"}, "external_kubeconfig": {"apiVersion: v1"}, "event": {strings.Repeat("x", 65)}}
+	req := httptest.NewRequest("POST", "/api/labs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.CreateLab(w, req)
+	assert.Contains(t, w.Body.String(), "event is too long")
+	assert.Empty(t, jm.GetAllJobs())
+}
+
+func TestJobManager_EventPersisted(t *testing.T) {
+	tempDir := t.TempDir()
+	jm1 := newTestJobManager(t, tempDir)
+	id := jm1.CreateJob(&LabConfig{StackName: "lab", Event: "devoxx-2026"})
+	jm1.UpdateJobStatus(id, JobStatusCompleted)
+	require.NoError(t, jm1.SaveJob(id))
+
+	jm2 := newTestJobManager(t, tempDir)
+	require.NoError(t, jm2.LoadJobs())
+	job, exists := jm2.GetJob(id)
+	require.True(t, exists)
+	assert.Equal(t, "devoxx-2026", job.Config.Event)
+}
+
+func TestLabEvents_FilterAndGroup(t *testing.T) {
+	jm := NewJobManager("")
+	devoxx1 := jm.CreateJob(&LabConfig{StackName: "lab-a", Event: "devoxx-2026"})
+	jm.CreateJob(&LabConfig{StackName: "lab-b", Event: "cohort-b"})
+	devoxx2 := jm.CreateJob(&LabConfig{StackName: "lab-c", Event: "devoxx-2026"})
+	jm.CreateJob(&LabConfig{StackName: "lab-d"})
+	jobs := jm.GetAllJobs()
+
+	assert.Equal(t, []labEvent{{Name: "cohort-b", Count: 1}, {Name: "devoxx-2026", Count: 2, Selected: true}}, labEvents(jobs, "devoxx-2026"))
+
+	var ids []string
+	for _, job := range filterJobsByEvent(jobs, "devoxx-2026") {
+		ids = append(ids, job.ID)
+	}
+	assert.ElementsMatch(t, []string{devoxx1, devoxx2}, ids)
+	assert.Len(t, filterJobsByEvent(jobs, ""), 4, "no event keeps every lab")
+	assert.Empty(t, filterJobsByEvent(jobs, "unknown"))
+
+	lines := exportLines(t, NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil), "?event=cohort-b&fields=id,config")
+	require.Len(t, lines, 1)
+	assert.Equal(t, "cohort-b", lines[0]["config"].(map[string]interface{})["event"])
+}
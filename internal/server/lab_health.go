@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// A lab is "completed" once Pulumi is done, which can come before its cluster
+// answers: the API server may still be starting, or its DNS propagating. The
+// student catalog only offers the labs whose cluster responds, and shows the
+// others as starting.
+const (
+	// labHealthTTL is how long a lab's health is trusted before the catalog
+	// checks it again.
+	labHealthTTL = time.Minute
+	// labHealthInterval is how often StartLabHealthChecks re-checks every lab,
+	// so that the catalog rarely has to wait on a cluster.
+	labHealthInterval = 30 * time.Second
+)
+
+// Student-facing states of a lab.
+const (
+	labStatusReady    = "ready"
+	labStatusStarting = "starting"
+)
+
+type labHealthEntry struct {
+	ready   bool
+	checked time.Time
+}
+
+// labHealthCache remembers whether each lab's cluster answered when last checked.
+type labHealthCache struct {
+	mu      sync.Mutex
+	entries map[string]labHealthEntry
+}
+
+func (c *labHealthCache) get(labID string, now time.Time) (ready, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[labID]
+	if !ok || now.Sub(entry.checked) > labHealthTTL {
+		return false, false
+	}
+	return entry.ready, true
+}
+
+func (c *labHealthCache) set(labID string, ready bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]labHealthEntry)
+	}
+	c.entries[labID] = labHealthEntry{ready: ready, checked: now}
+}
+
+// retain drops the labs not in keep, e.g. destroyed ones.
+func (c *labHealthCache) retain(keep map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.entries {
+		if !keep[id] {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// catalogLab is what the health check needs of a completed lab, copied out of
+// the job so that no lock is held while the cluster is probed.
+type catalogLab struct {
+	lab        studentLab
+	kubeconfig string
+	namespace  string
+}
+
+// catalogLabs returns the completed labs, newest first.
+func (h *Handler) catalogLabs() []catalogLab {
+	var labs []catalogLab
+	for _, job := range h.jobManager.GetLabs() {
+		job.mu.RLock()
+		if job.Status == JobStatusCompleted {
+			l := catalogLab{
+				lab:        studentLab{ID: job.ID},
+				kubeconfig: extractStringFromConfigValue(job.Kubeconfig),
+				namespace:  job.workspaceNamespace(),
+			}
+			if job.Config != nil {
+				l.lab.Config.StackName = job.Config.StackName
+			}
+			labs = append(labs, l)
+		}
+		job.mu.RUnlock()
+	}
+	return labs
+}
+
+// probeLab reports whether the lab's cluster answers. A lab without a
+// kubeconfig yet is not ready.
+func (h *Handler) probeLab(ctx context.Context, l catalogLab) bool {
+	if l.kubeconfig == "" {
+		return false
+	}
+	backend, err := h.newWorkspaceBackend(l.kubeconfig, l.namespace)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to build workspace backend for lab health check", "job_id", l.lab.ID, "error", err)
+		return false
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, clusterReachabilityTimeout)
+	defer cancel()
+	return backend.Reachable(probeCtx)
+}
+
+// labStatuses sets the Status of labs, from the cache when fresh and otherwise
+// by probing them, all at once. When force is set every lab is probed.
+func (h *Handler) labStatuses(ctx context.Context, labs []catalogLab, force bool) {
+	var wg sync.WaitGroup
+	for i := range labs {
+		l := &labs[i]
+		if ready, ok := h.labHealth.get(l.lab.ID, time.Now()); ok && !force {
+			l.lab.Status = labStatus(ready)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ready := h.probeLab(ctx, *l)
+			h.labHealth.set(l.lab.ID, ready, time.Now())
+			l.lab.Status = labStatus(ready)
+		}()
+	}
+	wg.Wait()
+}
+
+func labStatus(ready bool) string {
+	if ready {
+		return labStatusReady
+	}
+	return labStatusStarting
+}
+
+// StartLabHealthChecks re-checks the clusters of the completed labs every
+// labHealthInterval until ctx is done, logging the labs that change state.
+func (h *Handler) StartLabHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(labHealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.checkLabHealth(ctx)
+		}
+	}
+}
+
+// checkLabHealth probes every completed lab and forgets the others.
+func (h *Handler) checkLabHealth(ctx context.Context) {
+	labs := h.catalogLabs()
+	before := make(map[string]string, len(labs))
+	keep := make(map[string]bool, len(labs))
+	for _, l := range labs {
+		keep[l.lab.ID] = true
+		if ready, ok := h.labHealth.get(l.lab.ID, time.Now()); ok {
+			before[l.lab.ID] = labStatus(ready)
+		}
+	}
+	h.labHealth.retain(keep)
+	h.labStatuses(ctx, labs, true)
+	for _, l := range labs {
+		if was, ok := before[l.lab.ID]; ok && was != l.lab.Status {
+			slog.InfoContext(ctx, "Lab health changed", "job_id", l.lab.ID, "from", was, "to", l.lab.Status)
+		}
+	}
+}
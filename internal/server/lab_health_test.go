@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// labHealthFixture is a handler with two completed labs, "up" and "down",
+// whose clusters are fake backends picked by kubeconfig.
+type labHealthFixture struct {
+	h        *Handler
+	backends map[string]*fakeBackend
+	probes   atomic.Int32
+	upID     string
+	downID   string
+}
+
+func newLabHealthFixture(t *testing.T) *labHealthFixture {
+	t.Helper()
+	jm := NewJobManager("")
+	f := &labHealthFixture{
+		h:        NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil),
+		backends: map[string]*fakeBackend{"kc-up": {reachable: true}, "kc-down": {reachable: false}},
+	}
+	f.h.newWorkspaceBackend = func(kubeconfig, _ string) (workspace.Backend, error) {
+		f.probes.Add(1)
+		return f.backends[kubeconfig], nil
+	}
+	for name, kubeconfig := range map[string]string{"up": "kc-up", "down": "kc-down"} {
+		id := jm.CreateJob(&LabConfig{StackName: name})
+		jm.SetKubeconfig(id, kubeconfig)
+		jm.UpdateJobStatus(id, JobStatusCompleted)
+		if name == "up" {
+			f.upID = id
+		} else {
+			f.downID = id
+		}
+	}
+	return f
+}
+
+// statuses lists the labs as a student and returns their status by name.
+func (f *labHealthFixture) statuses(t *testing.T) map[string]string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	f.h.ListLabs(w, httptest.NewRequest("GET", "/api/student/labs", nil))
+	var labs []studentLab
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &labs), w.Body.String())
+	got := make(map[string]string)
+	for _, l := range labs {
+		got[l.Config.StackName] = l.Status
+	}
+	return got
+}
+
+func TestListLabs_MarksLabsWhoseClusterIsDownAsStarting(t *testing.T) {
+	f := newLabHealthFixture(t)
+
+	assert.Equal(t, map[string]string{"up": labStatusReady, "down": labStatusStarting}, f.statuses(t))
+	assert.EqualValues(t, 2, f.probes.Load())
+}
+
+func TestListLabs_CachesTheHealth(t *testing.T) {
+	f := newLabHealthFixture(t)
+	f.statuses(t)
+
+	// The cluster comes up, but the catalog trusts its last check for a while.
+	f.backends["kc-down"].reachable = true
+	assert.Equal(t, labStatusStarting, f.statuses(t)["down"])
+	assert.EqualValues(t, 2, f.probes.Load(), "the second listing probes nothing")
+
+	// The periodic check notices.
+	f.h.checkLabHealth(context.Background())
+	assert.EqualValues(t, 4, f.probes.Load())
+	assert.Equal(t, map[string]string{"up": labStatusReady, "down": labStatusReady}, f.statuses(t))
+}
+
+func TestCheckLabHealth_ForgetsLabsNoLongerCompleted(t *testing.T) {
+	f := newLabHealthFixture(t)
+	f.h.checkLabHealth(context.Background())
+	f.h.jobManager.UpdateJobStatus(f.downID, JobStatusDestroyed)
+
+	f.h.checkLabHealth(context.Background())
+	_, cached := f.h.labHealth.get(f.downID, time.Now())
+	assert.False(t, cached)
+	_, cached = f.h.labHealth.get(f.upID, time.Now())
+	assert.True(t, cached)
+}
+
+func TestListLabs_LabWithoutKubeconfigIsStarting(t *testing.T) {
+	jm := NewJobManager("")
+	id := jm.CreateJob(&LabConfig{StackName: "fresh"})
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{reachable: true})
+
+	w := httptest.NewRecorder()
+	h.ListLabs(w, httptest.NewRequest("GET", "/api/student/labs", nil))
+	assert.Contains(t, w.Body.String(), `"status":"starting"`)
+}
@@ -3,7 +3,7 @@ package server
 import (
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -59,7 +59,7 @@ func (h *Handler) labSecretManager(w http.ResponseWriter, r *http.Request) (sm w
 
 	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 	if err != nil {
-		log.Printf("Lab secrets: failed to build backend for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "Lab secrets: failed to build backend for lab", "job_id", labID, "error", err)
 		http.Error(w, "Failed to reach lab cluster", http.StatusInternalServerError)
 		return nil, "", false
 	}
@@ -95,7 +95,7 @@ func (h *Handler) ServeLabSecrets(w http.ResponseWriter, r *http.Request) {
 
 	secrets, err := sm.ListAuthSecrets(r.Context())
 	if err != nil {
-		log.Printf("Failed to list auth secrets for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "Failed to list auth secrets for lab", "job_id", labID, "error", err)
 		http.Error(w, "Failed to list credentials", http.StatusInternalServerError)
 		return
 	}
@@ -112,8 +112,7 @@ func (h *Handler) ServeLabSecrets(w http.ResponseWriter, r *http.Request) {
 	}
 	missing := pendingCredentialNames(secrets, templates)
 
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprint(w, renderLabSecrets(labID, secrets, missing))
+	writeHTMLFragment(w, http.StatusOK, renderLabSecrets(labID, secrets, missing))
 }
 
 // renderLabSecrets builds the panel's HTML fragment. Every value interpolated
@@ -195,10 +194,9 @@ func (h *Handler) ServeRecreateCredentials(w http.ResponseWriter, r *http.Reques
 	}
 	job.mu.RUnlock()
 
-	w.Header().Set("Content-Type", "text/html")
 	// Credentials first, then the reschedule-deletion section. Either may be empty;
 	// an entirely empty response tells the client to recreate without prompting.
-	fmt.Fprint(w, renderRecreateCredentials(referencedCredentials(templates))+renderRecreateDeletionDate(deletionDate))
+	writeHTMLFragment(w, http.StatusOK, renderRecreateCredentials(referencedCredentials(templates))+renderRecreateDeletionDate(deletionDate))
 }
 
 // renderRecreateCredentials builds the recreate prompt's rows. Names come from
@@ -279,7 +277,7 @@ func (h *Handler) SaveLabSecret(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := h.parseForm(w, r, 0); err != nil {
-		log.Printf("Failed to parse form while saving a secret for lab %s: %v", labID, err)
+		slog.WarnContext(r.Context(), "Failed to parse form while saving a secret for lab", "job_id", labID, "error", err)
 		writeToast(w, false, "Could not read the submitted form.")
 		return
 	}
@@ -324,12 +322,12 @@ func (h *Handler) SaveLabSecret(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// The error can name the secret and the cluster; the admin gets a generic
 		// message and the detail goes to the log.
-		log.Printf("Failed to save %s secret %q for lab %s: %v", kind, name, labID, err)
+		slog.ErrorContext(r.Context(), "Failed to save secret", "kind", kind, "name", name, "job_id", labID, "error", err)
 		writeToast(w, false, "Could not save the credential. Check the server logs.")
 		return
 	}
 
-	log.Printf("Saved %s credential %q for lab %s", kind, name, labID)
+	slog.InfoContext(r.Context(), "Saved credential", "kind", kind, "name", name, "job_id", labID)
 	h.ServeLabSecrets(w, r)
 }
 
@@ -346,7 +344,7 @@ func (h *Handler) DeleteLabSecret(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := h.parseForm(w, r, 0); err != nil {
-		log.Printf("Failed to parse form while deleting a secret for lab %s: %v", labID, err)
+		slog.WarnContext(r.Context(), "Failed to parse form while deleting a secret for lab", "job_id", labID, "error", err)
 		writeToast(w, false, "Could not read the submitted form.")
 		return
 	}
@@ -357,12 +355,12 @@ func (h *Handler) DeleteLabSecret(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := sm.DeleteAuthSecret(r.Context(), name); err != nil {
-		log.Printf("Failed to delete secret %q for lab %s: %v", name, labID, err)
+		slog.ErrorContext(r.Context(), "Failed to delete secret", "name", name, "job_id", labID, "error", err)
 		writeToast(w, false, "Could not delete the credential. Check the server logs.")
 		return
 	}
 
-	log.Printf("Deleted credential %q for lab %s", name, labID)
+	slog.InfoContext(r.Context(), "Deleted credential", "name", name, "job_id", labID)
 	h.ServeLabSecrets(w, r)
 }
 
@@ -461,3 +461,60 @@ func TestRequestWorkspace_BackendErrorIsNotShownToTheStudent(t *testing.T) {
 		assert.NotContains(t, body, leak, "internal detail %q leaked to the student", leak)
 	}
 }
+
+// A student-chosen name reaches the backend as-is (lowercased), an invalid one is
+// rejected before anything is created, and a name clash is reported as such rather
+// than as the generic "contact your administrator" failure. Without a name, the
+// student's workspace for the template comes back with its own password.
+func TestRequestWorkspace_ChosenName(t *testing.T) {
+	tests := []struct {
+		name        string
+		wsName      string
+		ensureErr   error
+		existing    []workspace.Workspace
+		wantEnsured bool
+		wantSpec    string
+		wantBody    string
+		notWantBody string
+	}{
+		{name: "default name", wsName: "", wantEnsured: true, wantSpec: ""},
+		{name: "chosen name is lowercased", wsName: "Retry-2", wantEnsured: true, wantSpec: "retry-2"},
+		{name: "invalid name", wsName: "bad_name", wantBody: "Invalid workspace name"},
+		{name: "name taken", wsName: "retry", ensureErr: fmt.Errorf("%w: retry", workspace.ErrNameTaken), wantEnsured: true, wantSpec: "retry", wantBody: `"retry" is already taken`},
+		{name: "another name for the same template", wsName: "second", existing: []workspace.Workspace{{Name: "first", Owner: "student", Template: "default"}}, wantEnsured: true, wantSpec: "second"},
+		{name: "no name gets the existing workspace back", wsName: "", existing: []workspace.Workspace{{Name: "first", Owner: "student", Template: "default", Token: "first-token"}}, wantBody: "not shown again", notWantBody: "first-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jm := NewJobManager("")
+			h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+			fb := &fakeBackend{reachable: true, ensureErr: tt.ensureErr, workspaces: tt.existing}
+			useFakeBackend(h, fb)
+
+			labID := completedLabWithKubeconfig(jm, 0)
+			job, _ := jm.GetJob(labID)
+			job.mu.Lock()
+			job.Config.WorkspaceTemplates = []WorkspaceTemplate{{Name: "default"}}
+			job.mu.Unlock()
+
+			req := postForm(t, "/api/workspace/request", url.Values{"lab_id": {labID}, "workspace_name": {tt.wsName}})
+			req = req.WithContext(context.WithValue(req.Context(), studentEmailContextKey, "student@example.com"))
+			rec := httptest.NewRecorder()
+			h.RequestWorkspace(rec, req)
+
+			if !tt.wantEnsured {
+				assert.Empty(t, fb.Ensured)
+			} else {
+				require.Len(t, fb.Ensured, 1)
+				assert.Equal(t, tt.wantSpec, fb.Ensured[0].Name)
+			}
+			if tt.wantBody != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantBody)
+			}
+			if tt.notWantBody != "" {
+				assert.NotContains(t, rec.Body.String(), tt.notWantBody)
+				assert.Empty(t, rec.Result().Cookies(), "the saved workspace info must not be overwritten")
+			}
+		})
+	}
+}
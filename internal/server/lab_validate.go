@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxLabConfigSize caps a submitted lab config. Configs are a few kilobytes;
+// inline devcontainer files in the templates make up most of the rest.
+const maxLabConfigSize = 1 << 20
+
+// ValidationReport is the outcome of validating a lab config: the errors that
+// would make the lab fail to be created, and the warnings about what would
+// deploy but not work.
+type ValidationReport struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// Validate runs the checks the server makes before creating a lab's job, and
+// returns every failure rather than stopping at the first. It needs neither OVH
+// nor credentials: checks against what OVH offers are left to the caller.
+func (c *LabConfig) Validate() []error {
+	var errs []error
+	if c.StackName == "" {
+		errs = append(errs, fmt.Errorf("stack_name is required"))
+	}
+	checks := []func() error{
+		func() error { return validateDNSConfig(c) },
+		func() error { return validateImportConfig(c) },
+		func() error { return validateNetworkConfig(c) },
+		func() error { return validateNodePoolOptions(c) },
+		func() error { return validateLabNodeCounts(c) },
+		func() error { return validatePostDeployManifests(c.PostDeployManifests) },
+		func() error { return validateHelmRepoOverrides(c.HelmRepoOverrides) },
+		func() error { return validateHelmChartVersions(c.HelmChartVersions) },
+		func() error { return validateLoadBalancerOptions(c) },
+		func() error { return validateK8sVersion(c, nil) },
+		func() error { return validateClusterOptions(c) },
+		func() error { return validateWorkspaceQuota(c.WorkspaceQuota) },
+		func() error { return validateStorageClass(c.StorageClass) },
+		func() error { return validateMaxWorkspaces(c.MaxWorkspaces) },
+		func() error { return validateEvent(c.Event) },
+		func() error { return validateWebhookURLs(c.WebhookURLs) },
+		func() error { return validateWorkspaceTemplates(c.WorkspaceTemplates) },
+		func() error { return validateVerbosity(c.Verbosity) },
+	}
+	for _, check := range checks {
+		if err := check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// ParseLabConfig reads a lab config in JSON or YAML, with the keys of the jobs
+// API ("stack_name", "network_region"...). Unknown keys are errors, so a typo
+// does not silently fall back to a default.
+func ParseLabConfig(data []byte) (*LabConfig, error) {
+	// JSON is YAML, so one decoder reads both. Going through JSON afterwards
+	// applies LabConfig's json tags.
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid config: %s", cleanYAMLError(err))
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("invalid config: the document is empty")
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var config LabConfig
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid config: %s", cleanDecodeError(err))
+	}
+	return &config, nil
+}
+
+// ValidateLabConfig parses and validates a lab config file without creating a
+// job. The validate subcommand and POST /api/labs/validate share it.
+func ValidateLabConfig(data []byte) ValidationReport {
+	report := ValidationReport{Errors: []string{}, Warnings: []string{}}
+	config, err := ParseLabConfig(data)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	for _, err := range config.Validate() {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	report.Warnings = append(report.Warnings, labConfigWarnings(config)...)
+	for _, warn := range gitCredentialWarnings(config.WorkspaceTemplates) {
+		report.Warnings = append(report.Warnings, warn.Message)
+	}
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+// ValidateLab handles POST /api/labs/validate: it validates the JSON or YAML lab
+// config in the request body and answers with a ValidationReport, 200 when the
+// config is valid and 422 when it is not. No job is created and OVH is never
+// called. With ?checks=ovh the credentials, region, flavor and Kubernetes
+// version are also checked, against the OVH options cache only.
+func (h *Handler) ValidateLab(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLabConfigSize))
+	if err != nil {
+		http.Error(w, "Config too large or unreadable", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	report := ValidateLabConfig(data)
+	if r.URL.Query().Get("checks") == "ovh" {
+		if config, err := ParseLabConfig(data); err == nil {
+			h.checkAgainstOVHCache(config, &report)
+		}
+	}
+
+	status := http.StatusOK
+	if !report.Valid {
+		status = http.StatusUnprocessableEntity
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.ErrorContext(r.Context(), "ValidateLab: failed to write the report", "error", err)
+	}
+}
+
+// checkAgainstOVHCache adds to the report the checks that depend on the OVH
+// project: credentials, then region, flavor and Kubernetes version against the
+// OVH options cache. A check whose data is not cached is skipped with a warning.
+func (h *Handler) checkAgainstOVHCache(config *LabConfig, report *ValidationReport) {
+	if config.UseExistingCluster || config.Provider == "azure" {
+		return
+	}
+	fail := func(err error) {
+		report.Errors = append(report.Errors, err.Error())
+		report.Valid = false
+	}
+	if !h.credentialsManager.HasCredentials("ovh") {
+		fail(fmt.Errorf("OVH credentials are not configured on the server"))
+	}
+	if h.ovhOptionsManager == nil || !h.ovhOptionsManager.HasCache() {
+		report.Warnings = append(report.Warnings, "The OVH options cache is empty: the region, flavor and Kubernetes version were not checked.")
+		return
+	}
+
+	regions, _ := h.ovhOptionsManager.GetRegionsForForm()
+	region, _, err := resolveRegion(config.NetworkRegion, regions)
+	if err != nil {
+		fail(err)
+		return
+	}
+	config.NetworkRegion = region
+	if config.NodePoolFlavor != "" {
+		if err := validateOVHFlavor(config, h.ovhOptionsManager.GetCachedFlavors(region)); err != nil {
+			fail(err)
+		}
+	}
+	if err := validateK8sVersion(config, h.ovhOptionsManager.GetCachedKubeVersions(region)); err != nil {
+		fail(err)
+	}
+}
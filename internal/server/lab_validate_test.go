@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validLabConfigYAML = `
+stack_name: ci-lab
+provider: ovh
+network_region: GRA11
+network_id: "10"
+network_mask: 10.0.0.0/24
+network_start_ip: 10.0.0.100
+network_end_ip: 10.0.0.200
+nodepool_flavor: b3-8
+k8s_version: "1.31"
+domain: lab.example.com
+workspace_templates:
+  - name: default
+`
+
+func TestValidateLabConfig_Valid(t *testing.T) {
+	report := ValidateLabConfig([]byte(validLabConfigYAML))
+	assert.True(t, report.Valid, "errors: %v", report.Errors)
+	assert.Empty(t, report.Errors)
+	assert.NotEmpty(t, report.Warnings, "a domain without a DNS provider is worth a warning")
+
+	// The same config in JSON.
+	report = ValidateLabConfig([]byte(`{"stack_name": "ci-lab", "provider": "ovh", "network_id": "10", "network_mask": "10.0.0.0/24",
+		"network_start_ip": "10.0.0.100", "network_end_ip": "10.0.0.200", "workspace_templates": [{"name": "default"}]}`))
+	assert.True(t, report.Valid, "errors: %v", report.Errors)
+}
+
+func TestValidateLabConfig_ReportsEveryError(t *testing.T) {
+	report := ValidateLabConfig([]byte(`
+provider: ovh
+network_id: "5000"
+network_mask: 10.0.0.0/24
+network_start_ip: 10.0.0.100
+network_end_ip: 10.0.0.200
+k8s_version: "1.31.2"
+dns_provider: ovh
+helm_repo_overrides:
+  cert-manager: ftp://mirror
+workspace_templates:
+  - name: default
+  - name: default
+`))
+	assert.False(t, report.Valid)
+	require.Len(t, report.Errors, 6, "errors: %v", report.Errors)
+	for i, want := range []string{"stack_name", "DNS Zone", "VLAN", "Helm repository", "Kubernetes version", "duplicate template"} {
+		assert.Contains(t, report.Errors[i], want)
+	}
+}
+
+func TestValidateLabConfig_UnreadableConfig(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":       "",
+		"unknown key": "stack_name: lab\nnetwork_regoin: GRA11\n",
+		"wrong type":  "stack_name: lab\nnodepool_desired_node_count: three\n",
+		"not YAML":    "stack_name: [lab\n",
+	} {
+		report := ValidateLabConfig([]byte(data))
+		assert.False(t, report.Valid, name)
+		assert.Len(t, report.Errors, 1, name)
+	}
+}
+
+func TestValidateLab(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	post := func(target, body string) (*httptest.ResponseRecorder, ValidationReport) {
+		w := httptest.NewRecorder()
+		h.ValidateLab(w, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		var report ValidationReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w, report
+	}
+
+	w, report := post("/api/labs/validate", validLabConfigYAML)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, report.Valid)
+
+	w, report = post("/api/labs/validate", "stack_name: \"\"\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.False(t, report.Valid)
+	assert.Empty(t, jm.GetAllJobs(), "validating must not create a job")
+
+	// Without credentials nor an OVH options cache, the OVH checks fail on the
+	// credentials and skip the rest.
+	w, report = post("/api/labs/validate?checks=ovh", validLabConfigYAML)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, strings.Join(report.Errors, "\n"), "credentials")
+	assert.Contains(t, strings.Join(report.Warnings, "\n"), "cache is empty")
+
+	w = httptest.NewRecorder()
+	h.ValidateLab(w, httptest.NewRequest(http.MethodGet, "/api/labs/validate", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestValidateLab_ChecksAgainstTheOVHCache(t *testing.T) {
+	cm := NewCredentialsManager()
+	require.NoError(t, cm.SetCredentials(&OVHCredentials{ApplicationKey: "key", ApplicationSecret: "secret", ConsumerKey: "consumer", ServiceName: "service", Endpoint: "ovh-eu"}))
+	opts := NewOVHOptionsManager("", cm)
+	opts.mu.Lock()
+	opts.cachedRegions = map[string]bool{"GRA11": true}
+	opts.cachedFlavors = map[string][]ovhFlavor{"GRA11": {{Name: "b3-16", VCPUs: 4, RAM: 16}}}
+	opts.cachedKubeVersions = map[string][]string{"GRA11": {"1.31"}}
+	opts.mu.Unlock()
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, cm, opts, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.ValidateLab(w, httptest.NewRequest(http.MethodPost, "/api/labs/validate?checks=ovh", strings.NewReader(validLabConfigYAML)))
+	var report ValidationReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0], `flavor "b3-8" is not available in region GRA11`)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+)
+
+// Serving states of the server, as /readyz and the easylab_server_state
+// metric report them.
+const (
+	// stateStarting: the persisted jobs are still loading.
+	stateStarting = "starting"
+	// stateServing: the server takes new work.
+	stateServing = "serving"
+	// stateDraining: a shutdown began; the server finishes what it runs and
+	// takes nothing new.
+	stateDraining = "draining"
+)
+
+// serverStates lists the states in the order they come.
+var serverStates = []string{stateStarting, stateServing, stateDraining}
+
+// Lifecycle tracks whether the server takes new work, for the Kubernetes
+// probes: /livez only says the process answers, /readyz whether load balancers
+// should send it requests. A shutdown flips /readyz first, so that traffic
+// moves away before the server stops listening and drains its Pulumi jobs.
+type Lifecycle struct {
+	state atomic.Value // string
+}
+
+// NewLifecycle returns the lifecycle of a server that is starting.
+func NewLifecycle() *Lifecycle {
+	l := &Lifecycle{}
+	l.set(stateStarting)
+	return l
+}
+
+func (l *Lifecycle) set(state string) {
+	l.state.Store(state)
+	metrics.setServerState(state)
+}
+
+// State returns the current serving state.
+func (l *Lifecycle) State() string {
+	return l.state.Load().(string)
+}
+
+// Serving marks the server ready for new work. It does nothing once a
+// shutdown began.
+func (l *Lifecycle) Serving() {
+	if l.state.CompareAndSwap(stateStarting, stateServing) {
+		metrics.setServerState(stateServing)
+		slog.Info("Server ready for new work")
+	}
+}
+
+// StartDraining marks the server as shutting down: /readyz fails from now on.
+func (l *Lifecycle) StartDraining() {
+	if l.state.Swap(stateDraining) != stateDraining {
+		metrics.setServerState(stateDraining)
+		slog.Info("Server draining, /readyz now fails")
+	}
+}
+
+// Livez serves /livez: 200 as long as the process answers, draining or not,
+// so that an orchestrator does not kill a server finishing its deployments.
+func (l *Lifecycle) Livez(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, l.State())
+}
+
+// Readyz serves /readyz: 200 while the server takes new work, 503 while it
+// starts or drains.
+func (l *Lifecycle) Readyz(w http.ResponseWriter, r *http.Request) {
+	state := l.State()
+	status := http.StatusOK
+	if state != stateServing {
+		status = http.StatusServiceUnavailable
+	}
+	writeProbe(w, status, state)
+}
+
+// writeProbe writes the body of a probe endpoint.
+func writeProbe(w http.ResponseWriter, status int, state string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"state": state})
+}
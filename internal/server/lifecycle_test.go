@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// probe calls a probe handler and returns its status and body.
+func probe(handler http.HandlerFunc) (int, string) {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+// scrapeServerMetrics returns the server metrics as /metrics writes them.
+func scrapeServerMetrics() string {
+	var b strings.Builder
+	metrics.write(&b, nil)
+	return b.String()
+}
+
+func TestLifecycle_Probes(t *testing.T) {
+	l := NewLifecycle()
+	code, body := probe(l.Readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code, "not ready while the jobs load")
+	assert.Equal(t, `{"state":"starting"}`, body)
+	code, _ = probe(l.Livez)
+	assert.Equal(t, http.StatusOK, code)
+
+	l.Serving()
+	code, body = probe(l.Readyz)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"state":"serving"}`, body)
+
+	l.StartDraining()
+	code, body = probe(l.Readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, `{"state":"draining"}`, body)
+	code, body = probe(l.Livez)
+	assert.Equal(t, http.StatusOK, code, "a draining server is still alive")
+	assert.Equal(t, `{"state":"draining"}`, body)
+
+	l.Serving()
+	assert.Equal(t, stateDraining, l.State(), "jobs loading late do not undo a shutdown")
+}
+
+func TestLifecycle_Metrics(t *testing.T) {
+	l := NewLifecycle()
+	out := scrapeServerMetrics()
+	assert.Contains(t, out, `easylab_server_state{state="starting"} 1`)
+	assert.Contains(t, out, "easylab_server_ready 0\n")
+
+	l.Serving()
+	out = scrapeServerMetrics()
+	assert.Contains(t, out, `easylab_server_state{state="starting"} 0`)
+	assert.Contains(t, out, `easylab_server_state{state="serving"} 1`)
+	assert.Contains(t, out, "easylab_server_ready 1\n")
+
+	l.StartDraining()
+	out = scrapeServerMetrics()
+	assert.Contains(t, out, `easylab_server_state{state="serving"} 0`)
+	assert.Contains(t, out, `easylab_server_state{state="draining"} 1`)
+	assert.Contains(t, out, "easylab_server_ready 0\n")
+}
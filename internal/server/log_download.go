@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// DownloadJobLogs serves the job's output as a text file.
+//
+//	GET /api/labs/{id}/logs/download[?compress=1]
+//
+// A client that accepts gzip gets the log compressed on the wire
+// (Content-Encoding: gzip) and saves it as a plain .log. With compress=1 the
+// response is a .log.gz file instead, for logs too large to keep uncompressed;
+// it is not marked Content-Encoding, or the browser would unpack it behind the
+// .gz name. Lines are written one by one through the compressor, so no second
+// copy of the log is built in memory.
+func (h *Handler) DownloadJobLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 5 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") ||
+		pathParts[3] != "logs" || pathParts[4] != "download" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	jobID := pathParts[2]
+
+	job, exists := h.jobManager.GetJob(jobID)
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	// Output is append-only, so a copy of the slice header is a stable snapshot
+	// of the lines written so far.
+	job.mu.RLock()
+	lines := job.Output[:len(job.Output):len(job.Output)]
+	job.mu.RUnlock()
+
+	var out io.Writer = w
+	switch {
+	case r.URL.Query().Get("compress") == "1":
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.log.gz", jobID))
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	case acceptsGzip(r):
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.log", jobID))
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.log", jobID))
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	buf := bufio.NewWriter(out)
+	for _, line := range lines {
+		if _, err := buf.WriteString(line); err != nil {
+			slog.WarnContext(r.Context(), "Log download for job aborted", "job_id", jobID, "error", err)
+			return
+		}
+		buf.WriteByte('\n')
+	}
+	if err := buf.Flush(); err != nil {
+		slog.WarnContext(r.Context(), "Log download for job aborted", "job_id", jobID, "error", err)
+	}
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		// "gzip;q=0" explicitly refuses it.
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogDownloadHandler(t *testing.T, lines int) (*Handler, string, string) {
+	t.Helper()
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "logs"})
+	var want strings.Builder
+	for i := range lines {
+		line := fmt.Sprintf("Updating (logs): resource %d created", i)
+		require.NoError(t, jm.AppendOutput(jobID, line))
+		want.WriteString(line + "\n")
+	}
+	return NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil), jobID, want.String()
+}
+
+func gunzip(t *testing.T, r io.Reader) string {
+	t.Helper()
+	gz, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	defer gz.Close()
+	b, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestDownloadJobLogs_Plain(t *testing.T) {
+	h, jobID, want := newLogDownloadHandler(t, 3)
+
+	w := httptest.NewRecorder()
+	h.DownloadJobLogs(w, httptest.NewRequest(http.MethodGet, "/api/labs/"+jobID+"/logs/download", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), jobID+".log")
+	assert.Equal(t, want, w.Body.String())
+}
+
+func TestDownloadJobLogs_AcceptEncodingGzip(t *testing.T) {
+	h, jobID, want := newLogDownloadHandler(t, 5000)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs/"+jobID+"/logs/download", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	w := httptest.NewRecorder()
+	h.DownloadJobLogs(w, req)
+
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.True(t, strings.HasSuffix(w.Header().Get("Content-Disposition"), jobID+".log"))
+	assert.Less(t, w.Body.Len(), len(want), "the body should be compressed")
+	assert.Equal(t, want, gunzip(t, w.Body))
+}
+
+func TestDownloadJobLogs_CompressAlwaysGzips(t *testing.T) {
+	h, jobID, want := newLogDownloadHandler(t, 10)
+
+	w := httptest.NewRecorder()
+	h.DownloadJobLogs(w, httptest.NewRequest(http.MethodGet, "/api/labs/"+jobID+"/logs/download?compress=1", nil))
+
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Encoding"), "a .gz file must not be unpacked by the browser")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), jobID+".log.gz")
+	assert.Equal(t, want, gunzip(t, w.Body))
+}
+
+func TestDownloadJobLogs_Errors(t *testing.T) {
+	h, jobID, _ := newLogDownloadHandler(t, 1)
+
+	w := httptest.NewRecorder()
+	h.DownloadJobLogs(w, httptest.NewRequest(http.MethodGet, "/api/labs/missing/logs/download", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	h.DownloadJobLogs(w, httptest.NewRequest(http.MethodPost, "/api/labs/"+jobID+"/logs/download", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = httptest.NewRecorder()
+	h.DownloadJobLogs(w, httptest.NewRequest(http.MethodGet, "/api/labs/"+jobID+"/logs", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                   false,
+		"gzip":               true,
+		"deflate, gzip":      true,
+		"gzip;q=0.5":         true,
+		"gzip; q=0":          false,
+		"br":                 false,
+		"x-gzip, identity":   false,
+		"identity;q=1, gzip": true,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		assert.Equal(t, want, acceptsGzip(r), "Accept-Encoding: %q", header)
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Limits of a log search: the matches returned by default and at most, and
+// the longest query accepted.
+const (
+	defaultLogSearchLimit = 100
+	maxLogSearchLimit     = 1000
+	maxLogSearchQuery     = 256
+)
+
+// logMatch is a line of the job's output matching a search. Index is the
+// line's position in the output (from 0), for the client to jump to it.
+type logMatch struct {
+	Index int    `json:"index"`
+	Line  string `json:"line"`
+}
+
+// logSearchResult is the response of SearchJobLogs. Total counts every match,
+// including those past the limit; Truncated tells the client some are left out.
+type logSearchResult struct {
+	Query     string     `json:"query"`
+	Regex     bool       `json:"regex"`
+	Matches   []logMatch `json:"matches"`
+	Total     int        `json:"total"`
+	Truncated bool       `json:"truncated"`
+}
+
+// logMatcher returns a function reporting whether a line matches query, as a
+// regular expression with regex, and ignoring case with ignoreCase.
+func logMatcher(query string, regex, ignoreCase bool) (func(string) bool, error) {
+	if regex {
+		if ignoreCase {
+			query = "(?i)" + query
+		}
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return re.MatchString, nil
+	}
+	if ignoreCase {
+		lower := strings.ToLower(query)
+		return func(line string) bool { return strings.Contains(strings.ToLower(line), lower) }, nil
+	}
+	return func(line string) bool { return strings.Contains(line, query) }, nil
+}
+
+// searchLines returns the first limit lines matching match, and how many
+// match in all.
+func searchLines(lines []string, match func(string) bool, limit int) (matches []logMatch, total int) {
+	matches = []logMatch{}
+	for i, line := range lines {
+		if !match(line) {
+			continue
+		}
+		total++
+		if len(matches) < limit {
+			matches = append(matches, logMatch{Index: i, Line: line})
+		}
+	}
+	return matches, total
+}
+
+// SearchJobLogs returns the lines of the job's output that contain q, so that a
+// client can jump to them in a long deploy log. With regex=1, q is a regular
+// expression (RE2 syntax); with ignore_case=1 case is ignored. limit (default
+// 100, at most 1000) caps the lines returned.
+//
+//	GET /api/labs/{id}/logs/search?q=error[&regex=1][&ignore_case=1][&limit=100]
+func (h *Handler) SearchJobLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 5 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") ||
+		pathParts[3] != "logs" || pathParts[4] != "search" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	jobID := pathParts[2]
+
+	query := r.URL.Query()
+	q := query.Get("q")
+	if q == "" {
+		writeJSONError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	if len(q) > maxLogSearchQuery {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("q is longer than %d characters", maxLogSearchQuery))
+		return
+	}
+	limit := defaultLogSearchLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+		limit = min(n, maxLogSearchLimit)
+	}
+	regex := query.Get("regex") == "1"
+	match, err := logMatcher(q, regex, query.Get("ignore_case") == "1")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, exists := h.jobManager.GetJob(jobID)
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	// Output is append-only, so a copy of the slice header is a stable snapshot
+	// of the lines written so far.
+	job.mu.RLock()
+	lines := job.Output[:len(job.Output):len(job.Output)]
+	job.mu.RUnlock()
+
+	matches, total := searchLines(lines, match, limit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logSearchResult{
+		Query:     q,
+		Regex:     regex,
+		Matches:   matches,
+		Total:     total,
+		Truncated: total > len(matches),
+	})
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLogSearchHandler returns a handler with a job whose output is lines.
+func newLogSearchHandler(t *testing.T, lines ...string) (*Handler, string) {
+	t.Helper()
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "logs"})
+	for _, line := range lines {
+		require.NoError(t, jm.AppendOutput(jobID, line))
+	}
+	return NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil), jobID
+}
+
+func searchLogs(t *testing.T, h *Handler, jobID string, params url.Values) (*httptest.ResponseRecorder, logSearchResult) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.SearchJobLogs(w, httptest.NewRequest(http.MethodGet, "/api/labs/"+jobID+"/logs/search?"+params.Encode(), nil))
+	var res logSearchResult
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	}
+	return w, res
+}
+
+var deployLog = []string{
+	"Updating (lab):",
+	"  + ovh:CloudProject:Kube cluster creating",
+	"  error: quota exceeded for flavor b3-8",
+	"  + kubernetes:helm.sh/v3:Release ingress-nginx created",
+	"Error: update failed",
+	"Resources: 3 created, 1 errored",
+}
+
+func TestSearchJobLogs_Plain(t *testing.T) {
+	h, jobID := newLogSearchHandler(t, deployLog...)
+
+	w, res := searchLogs(t, h, jobID, url.Values{"q": {"error"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []logMatch{
+		{Index: 2, Line: "  error: quota exceeded for flavor b3-8"},
+		{Index: 5, Line: "Resources: 3 created, 1 errored"},
+	}, res.Matches, "plain search is case-sensitive")
+	assert.Equal(t, 2, res.Total)
+	assert.False(t, res.Truncated)
+
+	_, res = searchLogs(t, h, jobID, url.Values{"q": {"error"}, "ignore_case": {"1"}})
+	assert.Equal(t, 3, res.Total)
+	assert.Equal(t, 4, res.Matches[1].Index)
+
+	_, res = searchLogs(t, h, jobID, url.Values{"q": {"(lab)"}})
+	assert.Equal(t, []logMatch{{Index: 0, Line: "Updating (lab):"}}, res.Matches, "plain queries are not patterns")
+
+	_, res = searchLogs(t, h, jobID, url.Values{"q": {"panic"}})
+	assert.Empty(t, res.Matches)
+	assert.NotNil(t, res.Matches, "no matches is an empty list, not null")
+}
+
+func TestSearchJobLogs_Regex(t *testing.T) {
+	h, jobID := newLogSearchHandler(t, deployLog...)
+
+	_, res := searchLogs(t, h, jobID, url.Values{"q": {`^\s+\+ \S+ .*created$`}, "regex": {"1"}})
+	require.Len(t, res.Matches, 1)
+	assert.Equal(t, 3, res.Matches[0].Index)
+	assert.True(t, res.Regex)
+
+	_, res = searchLogs(t, h, jobID, url.Values{"q": {`^error`}, "regex": {"1"}, "ignore_case": {"1"}})
+	assert.Equal(t, []logMatch{{Index: 4, Line: "Error: update failed"}}, res.Matches)
+
+	w, _ := searchLogs(t, h, jobID, url.Values{"q": {`quota (exceeded`}, "regex": {"1"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid regular expression")
+}
+
+func TestSearchJobLogs_CapsResults(t *testing.T) {
+	lines := make([]string, maxLogSearchLimit+50)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("resource %d created", i)
+	}
+	h, jobID := newLogSearchHandler(t, lines...)
+
+	_, res := searchLogs(t, h, jobID, url.Values{"q": {"created"}})
+	assert.Len(t, res.Matches, defaultLogSearchLimit)
+	assert.Equal(t, len(lines), res.Total, "the total counts every match")
+	assert.True(t, res.Truncated)
+
+	_, res = searchLogs(t, h, jobID, url.Values{"q": {"created"}, "limit": {"5000"}})
+	assert.Len(t, res.Matches, maxLogSearchLimit)
+	assert.Equal(t, maxLogSearchLimit-1, res.Matches[maxLogSearchLimit-1].Index)
+
+	_, res = searchLogs(t, h, jobID, url.Values{"q": {"created"}, "limit": {"3"}})
+	assert.Equal(t, []int{0, 1, 2}, []int{res.Matches[0].Index, res.Matches[1].Index, res.Matches[2].Index})
+}
+
+func TestSearchJobLogs_BadRequests(t *testing.T) {
+	h, jobID := newLogSearchHandler(t, deployLog...)
+
+	for name, params := range map[string]url.Values{
+		"no query":   {},
+		"long query": {"q": {string(make([]byte, maxLogSearchQuery+1))}},
+		"bad limit":  {"q": {"error"}, "limit": {"0"}},
+	} {
+		w, _ := searchLogs(t, h, jobID, params)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	w, _ := searchLogs(t, h, "job-missing", url.Values{"q": {"error"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	h.SearchJobLogs(w, httptest.NewRequest(http.MethodPost, "/api/labs/"+jobID+"/logs/search?q=error", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaintenanceState is the persisted maintenance flag. While Enabled, no new
+// deployment starts; status, listing and destroy keep working.
+type MaintenanceState struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message,omitempty"`
+	Since   time.Time `json:"since,omitzero"`
+}
+
+// MaintenanceMode holds the maintenance flag and persists it in dataDir, so a
+// restart during an incident does not silently reopen lab creation.
+type MaintenanceMode struct {
+	state   MaintenanceState
+	dataDir string
+	mu      sync.RWMutex
+}
+
+// NewMaintenanceMode creates the flag and loads its persisted state. With an
+// empty dataDir the flag lives in memory only.
+func NewMaintenanceMode(dataDir string) *MaintenanceMode {
+	m := &MaintenanceMode{dataDir: dataDir}
+	if err := m.load(); err != nil {
+		slog.Warn("Failed to load state", "component", "maintenance", "error", err)
+	}
+	return m
+}
+
+func (m *MaintenanceMode) path() string {
+	return filepath.Join(m.dataDir, "maintenance.json")
+}
+
+func (m *MaintenanceMode) load() error {
+	if m.dataDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read maintenance state: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := json.Unmarshal(data, &m.state); err != nil {
+		return fmt.Errorf("failed to parse maintenance state: %w", err)
+	}
+	return nil
+}
+
+// State returns the current maintenance state.
+func (m *MaintenanceMode) State() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Set turns maintenance on or off and persists the change. Turning it on again
+// while already on only updates the message and keeps the original start time.
+func (m *MaintenanceMode) Set(enabled bool, message string) (MaintenanceState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next := MaintenanceState{Enabled: enabled}
+	if enabled {
+		next.Message = message
+		next.Since = m.state.Since
+		if !m.state.Enabled {
+			next.Since = time.Now()
+		}
+	}
+
+	if m.dataDir != "" {
+		data, err := json.MarshalIndent(next, "", "  ")
+		if err != nil {
+			return m.state, fmt.Errorf("failed to marshal maintenance state: %w", err)
+		}
+		if err := os.MkdirAll(m.dataDir, 0755); err != nil {
+			return m.state, fmt.Errorf("failed to create data dir: %w", err)
+		}
+		if err := os.WriteFile(m.path(), data, 0644); err != nil {
+			return m.state, fmt.Errorf("failed to write maintenance state: %w", err)
+		}
+	}
+	m.state = next
+	return next, nil
+}
+
+// SetMaintenanceMode replaces the handler's in-memory maintenance flag with a
+// persisted one.
+func (h *Handler) SetMaintenanceMode(m *MaintenanceMode) {
+	h.maintenance = m
+}
+
+// rejectDuringMaintenance answers 503 and returns true when maintenance mode is
+// on. Handlers that start a deployment call it before doing anything else.
+func (h *Handler) rejectDuringMaintenance(w http.ResponseWriter) bool {
+	reqErr := h.maintenanceError()
+	if reqErr == nil {
+		return false
+	}
+	w.Header().Set("Retry-After", "600")
+	writeHTMLFragment(w, reqErr.Status, fmt.Sprintf(`<div class="error-message"><h3>%s</h3><p>%s</p></div>`, reqErr.Title, template.HTMLEscapeString(reqErr.Message)))
+	return true
+}
+
+// maintenanceError is the 503 a deployment is refused with during maintenance,
+// nil when maintenance mode is off.
+func (h *Handler) maintenanceError() *requestError {
+	state := h.maintenance.State()
+	if !state.Enabled {
+		return nil
+	}
+	message := "EasyLab is under maintenance: new deployments are paused. Existing labs can still be viewed and destroyed."
+	if state.Message != "" {
+		message += " " + state.Message
+	}
+	return &requestError{Status: http.StatusServiceUnavailable, Title: "Maintenance in progress", Message: message}
+}
+
+// Maintenance reports (GET) or changes (POST) maintenance mode.
+// POST form fields: enabled ("true"/"false"), message (optional, shown to admins).
+// Route: /api/maintenance (admin only)
+func (h *Handler) Maintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := h.parseForm(w, r, 1<<20); err != nil {
+			return
+		}
+		enabled := getFormValue(r, "enabled") == "true"
+		state, err := h.maintenance.Set(enabled, strings.TrimSpace(getFormValue(r, "message")))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save the maintenance mode", "error", err)
+			http.Error(w, "Failed to save maintenance mode", http.StatusInternalServerError)
+			return
+		}
+		if state.Enabled {
+			slog.InfoContext(r.Context(), "Maintenance mode enabled: new deployments are paused")
+		} else {
+			slog.InfoContext(r.Context(), "Maintenance mode disabled")
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.maintenance.State())
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance_BlocksNewDeployments(t *testing.T) {
+	jm := NewJobManager("")
+	dryRunID := jm.CreateJob(&LabConfig{StackName: "test"})
+	jm.UpdateJobStatus(dryRunID, JobStatusDryRunCompleted)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	_, err := h.maintenance.Set(true, "OVH incident, back at 14:00")
+	require.NoError(t, err)
+
+	deployments := map[string]func(http.ResponseWriter, *http.Request){
+		"/api/labs":                        h.CreateLab,
+		"/api/labs/dry-run":                h.DryRunLab,
+		"/api/labs/launch":                 h.LaunchLab,
+		"/api/labs/recreate":               h.RecreateLab,
+		"/api/labs/" + dryRunID + "/retry": h.RetryJob,
+	}
+	for path, handle := range deployments {
+		t.Run(path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handle(rec, postForm(t, path, url.Values{"job_id": {dryRunID}, "stack_name": {"new-lab"}}))
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), "OVH incident, back at 14:00")
+		})
+	}
+	assert.Len(t, jm.GetAllJobs(), 1, "no job is created during maintenance")
+}
+
+func TestMaintenance_StatusStillAvailable(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{StackName: "test"})
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	_, err := h.maintenance.Set(true, "")
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.GetJobStatus(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/"+jobID+"/status", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.GetJobStatusJSON(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/"+jobID+"?format=json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMaintenanceHandler_SetAndGet(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.Maintenance(rec, postForm(t, "/api/maintenance", url.Values{"enabled": {"true"}, "message": {" upgrade "}}))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var state MaintenanceState
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	assert.True(t, state.Enabled)
+	assert.Equal(t, "upgrade", state.Message)
+	assert.False(t, state.Since.IsZero())
+
+	rec = httptest.NewRecorder()
+	h.Maintenance(rec, postForm(t, "/api/maintenance", url.Values{"enabled": {"false"}}))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.Maintenance(rec, httptest.NewRequest(http.MethodGet, "/api/maintenance", nil))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	assert.False(t, state.Enabled)
+
+	rec = httptest.NewRecorder()
+	h.Maintenance(rec, httptest.NewRequest(http.MethodDelete, "/api/maintenance", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestMaintenanceMode_Persisted(t *testing.T) {
+	dir := t.TempDir()
+	first := NewMaintenanceMode(dir)
+	enabled, err := first.Set(true, "incident")
+	require.NoError(t, err)
+
+	again, err := first.Set(true, "still investigating")
+	require.NoError(t, err)
+	assert.True(t, enabled.Since.Equal(again.Since), "updating the message keeps the start time")
+
+	second := NewMaintenanceMode(dir)
+	got := second.State()
+	assert.True(t, got.Enabled, "maintenance survives a restart")
+	assert.Equal(t, "still investigating", got.Message)
+
+	_, err = second.Set(false, "ignored")
+	require.NoError(t, err)
+	assert.Equal(t, MaintenanceState{}, NewMaintenanceMode(dir).State())
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EnvMetricsToken is the bearer token Prometheus sends to scrape /metrics. The
+// endpoint is off while it is not set.
+const EnvMetricsToken = "METRICS_TOKEN"
+
+// SetMetricsToken sets the bearer token /metrics requires; empty turns it off.
+func (h *Handler) SetMetricsToken(token string) {
+	h.metricsToken = token
+}
+
+// metricLabelValue escapes a label value for the Prometheus text format.
+func metricLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// Metrics serves GET /metrics in the Prometheus text format: the estimated cost
+// of each lab so far and, for the labs up now, per hour (see LabCostEstimate),
+// then the server's own metrics (see serverMetrics).
+func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	if h.metricsToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.metricsToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	var total, hourly strings.Builder
+	for _, job := range h.jobManager.GetLabs() {
+		estimate := h.labCostEstimate(job, now)
+		if estimate == nil {
+			continue
+		}
+		job.mu.RLock()
+		name := job.Config.StackName
+		job.mu.RUnlock()
+		labels := fmt.Sprintf(`lab="%s",name="%s",flavor="%s",currency="%s"`,
+			metricLabelValue(job.ID), metricLabelValue(name), metricLabelValue(estimate.Flavor), metricLabelValue(estimate.Currency))
+		fmt.Fprintf(&total, "easylab_lab_cost_estimate{%s} %g\n", labels, estimate.Total)
+		if estimate.Running {
+			fmt.Fprintf(&hourly, "easylab_lab_hourly_cost_estimate{%s} %g\n", labels, estimate.HourlyTotal())
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintln(w, "# HELP easylab_lab_cost_estimate Estimated cost of the lab so far (nodes x flavor hourly price x hours up). An estimate, not a bill.")
+	fmt.Fprintln(w, "# TYPE easylab_lab_cost_estimate gauge")
+	fmt.Fprint(w, total.String())
+	fmt.Fprintln(w, "# HELP easylab_lab_hourly_cost_estimate Estimated hourly cost of the labs up now (nodes x flavor hourly price).")
+	fmt.Fprintln(w, "# TYPE easylab_lab_hourly_cost_estimate gauge")
+	fmt.Fprint(w, hourly.String())
+	metrics.write(w, h.jobManager.GetAllJobs())
+}
@@ -0,0 +1,35 @@
+package server
+
+import "fmt"
+
+// nodeCountLimits are the node counts a node pool of a lab must stay within,
+// set at startup with SetNodeCountLimits.
+var nodeCountLimits = DefaultServerConfig().NodePools
+
+// SetNodeCountLimits sets the node counts LabConfig.Validate and node pool
+// scaling hold every lab to. Call it at startup, before serving requests.
+func SetNodeCountLimits(cfg NodePoolsConfig) {
+	nodeCountLimits = cfg
+}
+
+// validateNodeCountLimits checks the node counts of each node pool of the lab
+// against the server's limits: the desired count must be within them, and the
+// min and max counts, which autoscaling can reach, must not exceed the maximum.
+func validateNodeCountLimits(counts nodePoolCounts, limits NodePoolsConfig) error {
+	if counts.Desired < limits.MinNodes || counts.Desired > limits.MaxNodes {
+		return fmt.Errorf("desired node count %d is outside the limits of this server (%d to %d nodes per node pool)", counts.Desired, limits.MinNodes, limits.MaxNodes)
+	}
+	if counts.Min > limits.MaxNodes || counts.Max > limits.MaxNodes {
+		return fmt.Errorf("node counts above %d are not allowed on this server (min nodes is %d, max nodes is %d)", limits.MaxNodes, counts.Min, counts.Max)
+	}
+	return nil
+}
+
+// validateLabNodeCounts applies validateNodeCountLimits to the node pool a lab
+// creates. An existing cluster has none.
+func validateLabNodeCounts(cfg *LabConfig) error {
+	if cfg.UseExistingCluster {
+		return nil
+	}
+	return validateNodeCountLimits(labNodePoolCounts(cfg), nodeCountLimits)
+}
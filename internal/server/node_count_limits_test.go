@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useNodeCountLimits sets the server's node count limits for the test.
+func useNodeCountLimits(t *testing.T, limits NodePoolsConfig) {
+	previous := nodeCountLimits
+	SetNodeCountLimits(limits)
+	t.Cleanup(func() { SetNodeCountLimits(previous) })
+}
+
+func TestValidateLabNodeCounts(t *testing.T) {
+	useNodeCountLimits(t, NodePoolsConfig{MinNodes: 1, MaxNodes: 10})
+	tests := []struct {
+		name    string
+		cfg     LabConfig
+		wantErr string
+	}{
+		{name: "within range", cfg: LabConfig{NodePoolDesiredNodeCount: 3, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 10}},
+		{name: "below min", cfg: LabConfig{NodePoolDesiredNodeCount: 0, NodePoolMinNodeCount: 0, NodePoolMaxNodeCount: 3}, wantErr: "desired node count 0 is outside the limits of this server (1 to 10 nodes"},
+		{name: "desired above max", cfg: LabConfig{NodePoolDesiredNodeCount: 200, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 200}, wantErr: "desired node count 200"},
+		{name: "max above max", cfg: LabConfig{NodePoolDesiredNodeCount: 3, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 20}, wantErr: "node counts above 10 are not allowed"},
+		{name: "existing cluster", cfg: LabConfig{UseExistingCluster: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLabNodeCounts(&tt.cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Contains(t, tt.cfg.Validate(), err, "Validate reports it")
+		})
+	}
+}
+
+func TestValidateNodePoolScale_NodeCountLimits(t *testing.T) {
+	useNodeCountLimits(t, NodePoolsConfig{MinNodes: 0, MaxNodes: 8})
+	cfg := &LabConfig{Provider: "ovh", NodePoolDesiredNodeCount: 3, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 5}
+
+	assert.NoError(t, validateNodePoolScale(cfg, nodePoolCounts{Desired: 8, Min: 1, Max: 8}))
+	err := validateNodePoolScale(cfg, nodePoolCounts{Desired: 9, Min: 1, Max: 9})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside the limits of this server")
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+)
+
+// ovhNodePoolMaxNodes is the largest node pool OVH Managed Kubernetes accepts.
+const ovhNodePoolMaxNodes = 100
+
+// nodePoolCounts are the node counts of a lab's node pool.
+type nodePoolCounts struct {
+	Desired int
+	Min     int
+	Max     int
+}
+
+func (c nodePoolCounts) String() string {
+	return fmt.Sprintf("%d nodes (min %d, max %d)", c.Desired, c.Min, c.Max)
+}
+
+func labNodePoolCounts(cfg *LabConfig) nodePoolCounts {
+	return nodePoolCounts{Desired: cfg.NodePoolDesiredNodeCount, Min: cfg.NodePoolMinNodeCount, Max: cfg.NodePoolMaxNodeCount}
+}
+
+func (c nodePoolCounts) applyTo(cfg *LabConfig) {
+	cfg.NodePoolDesiredNodeCount = c.Desired
+	cfg.NodePoolMinNodeCount = c.Min
+	cfg.NodePoolMaxNodeCount = c.Max
+}
+
+// parseNodePoolCounts reads the new counts from the form fields of the creation
+// wizard. A field left empty keeps the lab's current count.
+func parseNodePoolCounts(r *http.Request, current nodePoolCounts) (nodePoolCounts, error) {
+	counts := current
+	fields := []struct {
+		name string
+		dst  *int
+	}{
+		{"nodepool_desired_node_count", &counts.Desired},
+		{"nodepool_min_node_count", &counts.Min},
+		{"nodepool_max_node_count", &counts.Max},
+	}
+	for _, f := range fields {
+		v := strings.TrimSpace(r.FormValue(f.name))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return counts, fmt.Errorf("invalid %s %q", f.name, v)
+		}
+		*f.dst = n
+	}
+	return counts, nil
+}
+
+// validateNodePoolScale checks the new counts against what OVH accepts for the
+// lab's node pool, anti-affinity and autoscaling included, and against the
+// server's node count limits.
+func validateNodePoolScale(cfg *LabConfig, counts nodePoolCounts) error {
+	if cfg.UseExistingCluster || cfg.Provider == "azure" {
+		return fmt.Errorf("only the OVHcloud node pools EasyLab creates can be scaled")
+	}
+	if counts.Desired < 1 || counts.Min < 0 {
+		return fmt.Errorf("the node pool needs at least one node")
+	}
+	if counts.Min > counts.Desired || counts.Desired > counts.Max {
+		return fmt.Errorf("invalid node counts: want min (%d) ≤ desired (%d) ≤ max (%d)", counts.Min, counts.Desired, counts.Max)
+	}
+	if counts.Max > ovhNodePoolMaxNodes {
+		return fmt.Errorf("OVH node pools are limited to %d nodes (max nodes is %d)", ovhNodePoolMaxNodes, counts.Max)
+	}
+	if err := validateNodeCountLimits(counts, nodeCountLimits); err != nil {
+		return err
+	}
+	scaled := *cfg
+	counts.applyTo(&scaled)
+	return validateNodePoolOptions(&scaled)
+}
+
+// ScaleNodePool handles POST /api/jobs/{id}/nodepool/scale: it changes the node
+// counts of a completed lab's node pool, without redeploying the rest of the
+// lab. Form fields: nodepool_desired_node_count, nodepool_min_node_count and
+// nodepool_max_node_count, each optional.
+func (h *Handler) ScaleNodePool(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.rejectDuringMaintenance(w) {
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 5 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "nodepool" || pathParts[4] != "scale" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	jobID := pathParts[2]
+	job, exists := h.jobManager.GetJob(jobID)
+	if !exists {
+		h.renderHTMLError(w, "Job Not Found", fmt.Sprintf("Job %s does not exist.", jobID))
+		return
+	}
+
+	job.mu.RLock()
+	status := job.Status
+	hasConfig := job.Config != nil
+	var cfg LabConfig
+	if hasConfig {
+		cfg = *job.Config
+	}
+	job.mu.RUnlock()
+	if status != JobStatusCompleted || !hasConfig {
+		h.renderHTMLError(w, "Cannot Scale", fmt.Sprintf("Only completed labs can be scaled. Current status: %s", status))
+		return
+	}
+
+	previous := labNodePoolCounts(&cfg)
+	counts, err := parseNodePoolCounts(r, previous)
+	if err == nil {
+		err = validateNodePoolScale(&cfg, counts)
+	}
+	if err == nil && counts.Desired > previous.Desired {
+		// New nodes need the flavor, which OVH may have stopped offering in the region.
+		flavors, listErr := h.regionFlavors(cfg.NetworkRegion)
+		if listErr != nil {
+			slog.WarnContext(r.Context(), "Could not list flavors for region, skipping the flavor check", "network_region", cfg.NetworkRegion, "error", listErr)
+		}
+		err = validateOVHFlavor(&cfg, flavors)
+	}
+	if err != nil {
+		h.renderHTMLError(w, "Node Pool Configuration Error", err.Error())
+		return
+	}
+	if counts == previous {
+		h.renderHTMLError(w, "Nothing to Scale", fmt.Sprintf("The node pool already has %s.", counts))
+		return
+	}
+	ovhCreds, err := h.getOVHCredentials(w)
+	if err != nil {
+		return
+	}
+
+	// The status is checked again under the lock: a second scale, a retry or a
+	// destroy may have started since.
+	reason := fmt.Sprintf("Scaling the node pool from %s to %s", previous, counts)
+	job.mu.Lock()
+	if job.Status != JobStatusCompleted {
+		status := job.Status
+		job.mu.Unlock()
+		h.renderHTMLError(w, "Cannot Scale", fmt.Sprintf("Only completed labs can be scaled. Current status: %s", status))
+		return
+	}
+	counts.applyTo(job.Config)
+	job.Config.setOVHCredentials(ovhCreds)
+	job.setStatus(JobStatusRunning, reason)
+	job.mu.Unlock()
+	h.jobManager.AppendOutput(jobID, reason+"...")
+	slog.InfoContext(r.Context(), reason, "job_id", jobID)
+
+	go func() {
+		err := h.pulumiExec.ScaleNodePool(jobID)
+		h.jobManager.finishNodePoolScale(jobID, previous, counts, err)
+	}()
+
+	writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`
+		<div class="job-created">
+			<h3>Scaling the node pool to %s</h3>
+			<div id="job-status" hx-get="/api/jobs/%s/status" hx-trigger="load, every 10s" hx-swap="innerHTML">
+				<p>Loading status...</p>
+			</div>
+		</div>`, template.HTMLEscapeString(counts.String()), jobID))
+}
+
+// finishNodePoolScale records the outcome of a scale in the job. The lab stays
+// completed either way: when the scale fails, the rest of the lab is untouched
+// and the job's config goes back to the counts it had.
+func (jm *JobManager) finishNodePoolScale(jobID string, previous, counts nodePoolCounts, scaleErr error) {
+	job, exists := jm.GetJob(jobID)
+	if !exists {
+		return
+	}
+	var line string
+	job.mu.Lock()
+	if scaleErr != nil {
+		previous.applyTo(job.Config)
+		line = fmt.Sprintf("Node pool scaling failed, the lab keeps its config of %s: %v", previous, scaleErr)
+	} else {
+		line = fmt.Sprintf("Node pool scaled to %s", counts)
+	}
+	job.setStatus(JobStatusCompleted, line)
+	job.mu.Unlock()
+
+	jm.AppendOutput(jobID, line)
+	if err := jm.SaveJob(jobID); err != nil {
+		slog.Warn("Failed to persist job", "job_id", jobID, "error", err)
+	}
+}
+
+// nodePoolURNs are the URNs of the node pools ovh.InitNodePools declares in a
+// lab's stack, one per availability zone, the only resources a scale updates.
+func nodePoolURNs(stackName string, pools int) []string {
+	urns := make([]string, pools)
+	for i := range urns {
+		urns[i] = labResourceURN(stackName, "ovh:CloudProject/kubeNodePool:KubeNodePool", fmt.Sprintf("nodePool%d", i+1))
+	}
+	return urns
+}
+
+// ScaleNodePool runs pulumi up targeted at the node pool of a lab whose config
+// already holds the new node counts. Targeting leaves the cluster, network and
+// Helm releases as they are, even if their config drifted since the lab was
+// created. The caller records the outcome in the job.
+func (pe *PulumiExecutor) ScaleNodePool(jobID string) error {
+	defer metrics.trackPulumi("scale")()
+	runCtx, end := pe.beginRun(jobID, "scale", false)
+	defer end()
+	job, exists := pe.jobManager.GetJob(jobID)
+	if !exists {
+		return fmt.Errorf("job %s not found", jobID)
+	}
+	job.mu.RLock()
+	config := *job.Config
+	job.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(runCtx, pulumiExecutionTimeout)
+	defer cancel()
+	stack, err := pe.selectLabStack(ctx, jobID, &config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := pe.cleanupJobDirectory(jobID, true); err != nil {
+			slog.Warn("Failed to cleanup job directory", "job_id", jobID, "error", err)
+		}
+	}()
+
+	writer := &jobOutputWriter{jobID: jobID, jobManager: pe.jobManager}
+	defer writer.Flush()
+	pe.jobManager.AppendOutput(jobID, "Running pulumi up on the node pool only...")
+	upResult, err := stack.Up(ctx, optup.ProgressStreams(writer), optup.Target(nodePoolURNs(config.StackName, nodePoolCount(&config))))
+	if err != nil {
+		return fmt.Errorf("pulumi up failed: %w", err)
+	}
+	pe.reportClusterInfo(jobID, upResult.Outputs)
+	return nil
+}
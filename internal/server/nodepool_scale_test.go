@@ -0,0 +1,141 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateNodePoolScale(t *testing.T) {
+	ovhLab := &LabConfig{Provider: "ovh", NodePoolDesiredNodeCount: 3, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 5}
+	tests := []struct {
+		name    string
+		cfg     *LabConfig
+		counts  nodePoolCounts
+		wantErr string
+	}{
+		{name: "two more nodes", cfg: ovhLab, counts: nodePoolCounts{Desired: 5, Min: 1, Max: 5}},
+		{name: "desired above max", cfg: ovhLab, counts: nodePoolCounts{Desired: 6, Min: 1, Max: 5}, wantErr: "min (1) ≤ desired (6) ≤ max (5)"},
+		{name: "min above desired", cfg: ovhLab, counts: nodePoolCounts{Desired: 2, Min: 3, Max: 5}, wantErr: "min (3)"},
+		{name: "no nodes", cfg: ovhLab, counts: nodePoolCounts{Desired: 0, Min: 0, Max: 5}, wantErr: "at least one node"},
+		{name: "over the OVH limit", cfg: ovhLab, counts: nodePoolCounts{Desired: 3, Min: 1, Max: 101}, wantErr: "limited to 100 nodes"},
+		{
+			name:    "anti-affinity",
+			cfg:     &LabConfig{Provider: "ovh", NodePoolAntiAffinity: true},
+			counts:  nodePoolCounts{Desired: 6, Min: 1, Max: 6},
+			wantErr: "anti-affinity",
+		},
+		{
+			name:    "autoscaling needs room",
+			cfg:     &LabConfig{Provider: "ovh", NodePoolAutoscale: true},
+			counts:  nodePoolCounts{Desired: 3, Min: 3, Max: 3},
+			wantErr: "autoscaling",
+		},
+		{name: "existing cluster", cfg: &LabConfig{UseExistingCluster: true}, counts: nodePoolCounts{Desired: 3, Min: 1, Max: 5}, wantErr: "only the OVHcloud node pools"},
+		{name: "azure", cfg: &LabConfig{Provider: "azure"}, counts: nodePoolCounts{Desired: 3, Min: 1, Max: 5}, wantErr: "only the OVHcloud node pools"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNodePoolScale(tt.cfg, tt.counts)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestParseNodePoolCounts_KeepsTheFieldsLeftEmpty(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"nodepool_desired_node_count": {"5"}, "nodepool_max_node_count": {" 6 "}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	counts, err := parseNodePoolCounts(r, nodePoolCounts{Desired: 3, Min: 1, Max: 5})
+	require.NoError(t, err)
+	assert.Equal(t, nodePoolCounts{Desired: 5, Min: 1, Max: 6}, counts)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("nodepool_min_node_count=two"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = parseNodePoolCounts(r, nodePoolCounts{})
+	assert.ErrorContains(t, err, "nodepool_min_node_count")
+}
+
+func TestNodePoolURNs(t *testing.T) {
+	want := "urn:pulumi:lab-1::easylab::ovh:CloudProject/kubeNodePool:KubeNodePool::nodePool1"
+	assert.Equal(t, []string{want}, nodePoolURNs("lab-1", 1))
+	assert.Equal(t, []string{want}, nodePoolURNs("organization/easylab/lab-1", 1))
+	assert.Equal(t, []string{
+		want,
+		"urn:pulumi:lab-1::easylab::ovh:CloudProject/kubeNodePool:KubeNodePool::nodePool2",
+	}, nodePoolURNs("lab-1", 2))
+}
+
+// scaleRequest posts the node counts to the job's scale endpoint.
+func scaleRequest(h *Handler, jobID string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/jobs/"+jobID+"/nodepool/scale", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ScaleNodePool(w, r)
+	return w
+}
+
+func TestScaleNodePool_RefusesALabThatIsNotCompleted(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}, NewCredentialsManager(), nil, nil, nil)
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab", Provider: "ovh", NodePoolDesiredNodeCount: 3, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 5})
+	jm.UpdateJobStatus(jobID, JobStatusFailed)
+
+	w := scaleRequest(h, jobID, url.Values{"nodepool_desired_node_count": {"2"}})
+	assert.Contains(t, w.Body.String(), "Only completed labs can be scaled")
+
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, 3, job.Config.NodePoolDesiredNodeCount)
+}
+
+func TestScaleNodePool_RecordsAFailedScaleAndKeepsTheLab(t *testing.T) {
+	jm := NewJobManager("")
+	cm := NewCredentialsManager()
+	require.NoError(t, cm.SetCredentials(&OVHCredentials{ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p", Endpoint: "ovh-eu"}))
+	// No job directory: the lab's Pulumi state cannot be found.
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}, cm, nil, nil, nil)
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab", Provider: "ovh", NodePoolDesiredNodeCount: 3, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 5})
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+
+	w := scaleRequest(h, jobID, url.Values{"nodepool_desired_node_count": {"2"}})
+	assert.Contains(t, w.Body.String(), "Scaling the node pool to 2 nodes (min 1, max 5)")
+
+	job, _ := jm.GetJob(jobID)
+	require.Eventually(t, func() bool {
+		job.mu.RLock()
+		defer job.mu.RUnlock()
+		return job.Status == JobStatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	assert.Equal(t, 3, job.Config.NodePoolDesiredNodeCount, "a failed scale restores the counts")
+	n := len(job.History)
+	require.GreaterOrEqual(t, n, 2)
+	assert.Equal(t, JobStatusRunning, job.History[n-2].Status)
+	assert.Contains(t, job.History[n-2].Reason, "from 3 nodes (min 1, max 5) to 2 nodes (min 1, max 5)")
+	assert.Contains(t, job.History[n-1].Reason, "Node pool scaling failed")
+}
+
+func TestFinishNodePoolScale_Success(t *testing.T) {
+	jm := NewJobManager("")
+	jobID := jm.CreateJob(&LabConfig{NodePoolDesiredNodeCount: 5, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 5})
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+
+	jm.finishNodePoolScale(jobID, nodePoolCounts{Desired: 3, Min: 1, Max: 5}, nodePoolCounts{Desired: 5, Min: 1, Max: 5}, nil)
+
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, JobStatusCompleted, job.Status)
+	assert.Equal(t, 5, job.Config.NodePoolDesiredNodeCount)
+	assert.Equal(t, "Node pool scaled to 5 nodes (min 1, max 5)", job.History[len(job.History)-1].Reason)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"easylab/ovh"
+)
+
+// allZones is the nodepool_zones value that spreads the lab over every zone of
+// its region.
+const allZones = "all"
+
+// parseAvailabilityZones parses the comma- or line-separated zones of the
+// nodepool_zones form field. OVH names zones in lower case.
+func parseAvailabilityZones(s string) []string {
+	var zones []string
+	for _, e := range splitNodeEntries(s) {
+		zones = append(zones, strings.ToLower(e))
+	}
+	return zones
+}
+
+// nodePoolCount is how many node pools the lab's cluster has: one per
+// availability zone, or a single one.
+func nodePoolCount(cfg *LabConfig) int {
+	return max(1, len(cfg.NodePoolZones))
+}
+
+// regionAvailabilityZones fetches the availability zones of an OVH region.
+func (h *Handler) regionAvailabilityZones(region string) ([]string, error) {
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = ovhCheckTimeout
+	return ovh.FetchAvailabilityZones(client, serviceName, region)
+}
+
+// resolveNodePoolZones checks the zones the admin asked to spread the node pool
+// over against the region's, and expands "all". In a single-zone region the
+// zones are dropped and the lab gets its usual single node pool. Like the
+// gateway model check it is best effort: when the zones cannot be fetched,
+// explicit zones are let through and OVH reports a wrong one at pulumi up.
+func resolveNodePoolZones(cfg *LabConfig, regionZones func(region string) ([]string, error)) error {
+	if len(cfg.NodePoolZones) == 0 || cfg.UseExistingCluster || cfg.Provider == "azure" {
+		return nil
+	}
+	spreadAll := len(cfg.NodePoolZones) == 1 && cfg.NodePoolZones[0] == allZones
+	available, err := regionZones(cfg.NetworkRegion)
+	if err != nil {
+		if spreadAll {
+			return fmt.Errorf("cannot list the availability zones of region %s: %w", cfg.NetworkRegion, err)
+		}
+		slog.Warn("Availability zone check skipped", "error", err)
+		return nil
+	}
+	if len(available) <= 1 {
+		slog.Info("Region has a single availability zone: the lab gets a single node pool", "region", cfg.NetworkRegion)
+		cfg.NodePoolZones = nil
+		return nil
+	}
+	if spreadAll {
+		cfg.NodePoolZones = append([]string(nil), available...)
+		return nil
+	}
+	return ovh.ValidateAvailabilityZones(cfg.NodePoolZones, cfg.NetworkRegion, available)
+}
+
+// nodePoolTopologySummary describes, for the dry-run output, where the lab's
+// nodes go, or returns "" when the lab does not create an OVH node pool.
+func nodePoolTopologySummary(cfg *LabConfig) string {
+	if cfg == nil || cfg.UseExistingCluster || cfg.Provider == "azure" {
+		return ""
+	}
+	if len(cfg.NodePoolZones) == 0 {
+		return fmt.Sprintf("Node pool topology: a single node pool of %d nodes, placed by OVHcloud", cfg.NodePoolDesiredNodeCount)
+	}
+	return fmt.Sprintf("Node pool topology: %d node pools, one per availability zone (%s), %d nodes each (%d in total)",
+		len(cfg.NodePoolZones), strings.Join(cfg.NodePoolZones, ", "), cfg.NodePoolDesiredNodeCount, len(cfg.NodePoolZones)*cfg.NodePoolDesiredNodeCount)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var parisZones = []string{"eu-west-par-a", "eu-west-par-b", "eu-west-par-c"}
+
+// fixedZones answers every region lookup with zones, or fails with err.
+func fixedZones(zones []string, err error) func(string) ([]string, error) {
+	return func(string) ([]string, error) { return zones, err }
+}
+
+func TestParseAvailabilityZones(t *testing.T) {
+	assert.Equal(t, []string{"eu-west-par-a", "eu-west-par-b"}, parseAvailabilityZones(" EU-WEST-PAR-A,\neu-west-par-b "))
+	assert.Nil(t, parseAvailabilityZones(""))
+}
+
+func TestResolveNodePoolZones(t *testing.T) {
+	t.Run("all zones of a multi-zone region", func(t *testing.T) {
+		cfg := &LabConfig{Provider: "ovh", NetworkRegion: "EU-WEST-PAR", NodePoolZones: []string{allZones}}
+		require.NoError(t, resolveNodePoolZones(cfg, fixedZones(parisZones, nil)))
+		assert.Equal(t, parisZones, cfg.NodePoolZones)
+	})
+
+	t.Run("single-zone region falls back to one pool", func(t *testing.T) {
+		cfg := &LabConfig{Provider: "ovh", NetworkRegion: "GRA11", NodePoolZones: []string{allZones}}
+		require.NoError(t, resolveNodePoolZones(cfg, fixedZones(nil, nil)))
+		assert.Nil(t, cfg.NodePoolZones)
+		assert.Equal(t, 1, nodePoolCount(cfg))
+	})
+
+	t.Run("zone outside the region", func(t *testing.T) {
+		cfg := &LabConfig{Provider: "ovh", NetworkRegion: "EU-WEST-PAR", NodePoolZones: []string{"eu-west-par-a", "eu-south-mil-a"}}
+		assert.ErrorContains(t, resolveNodePoolZones(cfg, fixedZones(parisZones, nil)), `"eu-south-mil-a" is not in region EU-WEST-PAR`)
+	})
+
+	t.Run("explicit zones kept when the lookup fails", func(t *testing.T) {
+		cfg := &LabConfig{Provider: "ovh", NetworkRegion: "EU-WEST-PAR", NodePoolZones: []string{"eu-west-par-a", "eu-west-par-b"}}
+		require.NoError(t, resolveNodePoolZones(cfg, fixedZones(nil, errors.New("no credentials"))))
+		assert.Equal(t, 2, nodePoolCount(cfg))
+	})
+
+	t.Run("all cannot be resolved when the lookup fails", func(t *testing.T) {
+		cfg := &LabConfig{Provider: "ovh", NetworkRegion: "EU-WEST-PAR", NodePoolZones: []string{allZones}}
+		assert.ErrorContains(t, resolveNodePoolZones(cfg, fixedZones(nil, errors.New("no credentials"))), "no credentials")
+	})
+
+	t.Run("existing cluster ignores zones", func(t *testing.T) {
+		cfg := &LabConfig{UseExistingCluster: true, NodePoolZones: []string{"eu-west-par-a"}}
+		require.NoError(t, resolveNodePoolZones(cfg, func(string) ([]string, error) {
+			t.Fatal("no lookup expected")
+			return nil, nil
+		}))
+	})
+}
+
+func TestNodePoolTopologySummary(t *testing.T) {
+	assert.Equal(t, "Node pool topology: a single node pool of 2 nodes, placed by OVHcloud",
+		nodePoolTopologySummary(&LabConfig{Provider: "ovh", NodePoolDesiredNodeCount: 2}))
+	assert.Equal(t, "Node pool topology: 3 node pools, one per availability zone (eu-west-par-a, eu-west-par-b, eu-west-par-c), 2 nodes each (6 in total)",
+		nodePoolTopologySummary(&LabConfig{Provider: "ovh", NodePoolDesiredNodeCount: 2, NodePoolZones: parisZones}))
+	assert.Empty(t, nodePoolTopologySummary(&LabConfig{Provider: "azure"}))
+}
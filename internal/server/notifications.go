@@ -0,0 +1,283 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chat notifications, configured from the environment. Unlike the generic
+// webhooks (see webhooks.go), they post a message written for people to read.
+const (
+	// EnvSlackWebhookURL is a Slack incoming webhook URL.
+	EnvSlackWebhookURL = "SLACK_WEBHOOK_URL"
+	// EnvDiscordWebhookURL is a Discord channel webhook URL.
+	EnvDiscordWebhookURL = "DISCORD_WEBHOOK_URL"
+	// EnvNotifyLogOnly, when true, writes the messages to the server log instead
+	// of posting them, to try the notifications out.
+	EnvNotifyLogOnly = "NOTIFY_LOG_ONLY"
+)
+
+// notificationTimeout bounds the post of one message.
+const notificationTimeout = 10 * time.Second
+
+// NotificationKind is what a notification is about.
+type NotificationKind string
+
+const (
+	NotifyLabCompleted NotificationKind = "lab_completed"
+	NotifyLabFailed    NotificationKind = "lab_failed"
+	// NotifyLabFull is sent when a student takes the last workspace of a lab
+	// with a max_workspaces.
+	NotifyLabFull NotificationKind = "lab_full"
+)
+
+// Notification is one message, rendered by each notifier in its own markup.
+type Notification struct {
+	Kind    NotificationKind
+	LabID   string
+	LabName string
+	// Detail is the error of a failed lab, or the capacity of a full one.
+	Detail string
+	// Link is the lab's page, a path when no public URL is set.
+	Link string
+}
+
+// message renders the notification, with link formatting a link in the
+// notifier's markup.
+func (n Notification) message(link func(target, text string) string) string {
+	switch n.Kind {
+	case NotifyLabCompleted:
+		return fmt.Sprintf("✅ Lab %s is ready. %s", n.LabName, link(n.Link, "Open the lab"))
+	case NotifyLabFailed:
+		return fmt.Sprintf("❌ Lab %s failed: %s. %s", n.LabName, n.Detail, link(n.Link, "See the logs"))
+	case NotifyLabFull:
+		return fmt.Sprintf("🪑 Lab %s is full: %s workspaces are taken. %s", n.LabName, n.Detail, link(n.Link, "Manage the workspaces"))
+	}
+	return fmt.Sprintf("Lab %s: %s. %s", n.LabName, n.Kind, link(n.Link, "Open the lab"))
+}
+
+// Notifier posts notifications to one channel.
+type Notifier interface {
+	// Name identifies the notifier in logs.
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// slackNotifier posts to a Slack incoming webhook.
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (s *slackNotifier) Name() string { return "slack" }
+
+func (s *slackNotifier) Notify(ctx context.Context, n Notification) error {
+	text := n.message(func(target, text string) string { return "<" + target + "|" + text + ">" })
+	return postNotification(ctx, s.client, s.url, map[string]string{"text": text})
+}
+
+// discordNotifier posts to a Discord channel webhook.
+type discordNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (d *discordNotifier) Name() string { return "discord" }
+
+func (d *discordNotifier) Notify(ctx context.Context, n Notification) error {
+	content := n.message(func(target, text string) string { return "[" + text + "](" + target + ")" })
+	return postNotification(ctx, d.client, d.url, map[string]string{"content": content})
+}
+
+// logNotifier writes the messages to the server log.
+type logNotifier struct{}
+
+func (logNotifier) Name() string { return "log" }
+
+func (logNotifier) Notify(_ context.Context, n Notification) error {
+	slog.Info(n.message(func(target, text string) string { return text + ": " + target }), "component", "notify")
+	return nil
+}
+
+// postNotification POSTs body as JSON to a chat webhook URL.
+func postNotification(ctx context.Context, client *http.Client, target string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error quotes the URL, token included.
+		return fmt.Errorf("request failed: %s", strings.ReplaceAll(err.Error(), target, webhookTarget(target)))
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Notifications sends chat notifications on lab status changes and when a lab
+// is full. Messages are sent in the background: a failure is logged and never
+// changes a job.
+type Notifications struct {
+	notifiers []Notifier
+	publicURL string
+	// inflight tracks the messages being sent, so that stopping waits for them.
+	inflight sync.WaitGroup
+}
+
+// NewNotifications returns the notifications sent to notifiers, with links
+// under publicURL.
+func NewNotifications(publicURL string, notifiers ...Notifier) *Notifications {
+	return &Notifications{notifiers: notifiers, publicURL: strings.TrimSuffix(publicURL, "/")}
+}
+
+// NewNotificationsFromEnv reads the Slack and Discord webhook URLs. With
+// NOTIFY_LOG_ONLY=true, messages go to the log instead of the channels.
+func NewNotificationsFromEnv(publicURL string) (*Notifications, error) {
+	cfg := NotificationsConfig{
+		SlackWebhookURL:   os.Getenv(EnvSlackWebhookURL),
+		DiscordWebhookURL: os.Getenv(EnvDiscordWebhookURL),
+	}
+	if v := os.Getenv(EnvNotifyLogOnly); v != "" {
+		logOnly, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: want true or false, got %q", EnvNotifyLogOnly, v)
+		}
+		cfg.LogOnly = logOnly
+	}
+	return NewNotificationsFromConfig(publicURL, cfg)
+}
+
+// NewNotificationsFromConfig returns the notifications posted to the Slack and
+// Discord webhooks of cfg, or written to the log with cfg.LogOnly.
+func NewNotificationsFromConfig(publicURL string, cfg NotificationsConfig) (*Notifications, error) {
+	client := &http.Client{Timeout: notificationTimeout}
+	var notifiers []Notifier
+	for _, channel := range []struct {
+		env string
+		url string
+		new func(string) Notifier
+	}{
+		{EnvSlackWebhookURL, cfg.SlackWebhookURL, func(u string) Notifier { return &slackNotifier{url: u, client: client} }},
+		{EnvDiscordWebhookURL, cfg.DiscordWebhookURL, func(u string) Notifier { return &discordNotifier{url: u, client: client} }},
+	} {
+		u := strings.TrimSpace(channel.url)
+		if u == "" {
+			continue
+		}
+		if err := validateWebhookURLs([]string{u}); err != nil {
+			return nil, fmt.Errorf("%s: %w", channel.env, err)
+		}
+		notifiers = append(notifiers, channel.new(u))
+	}
+	if cfg.LogOnly {
+		notifiers = []Notifier{logNotifier{}}
+	}
+	return NewNotifications(publicURL, notifiers...), nil
+}
+
+// SetNotifications sets the chat notifications sent when a lab is full.
+func (h *Handler) SetNotifications(ns *Notifications) {
+	h.notifications = ns
+}
+
+// Enabled reports whether any notifier is set.
+func (ns *Notifications) Enabled() bool {
+	return ns != nil && len(ns.notifiers) > 0
+}
+
+// Names lists the notifiers, for the startup log.
+func (ns *Notifications) Names() []string {
+	var names []string
+	if ns != nil {
+		for _, n := range ns.notifiers {
+			names = append(names, n.Name())
+		}
+	}
+	return names
+}
+
+// Start subscribes the notifications to the job events of jm. The returned
+// function stops them once the messages under way are sent.
+func (ns *Notifications) Start(jm *JobManager) (stop func()) {
+	unsubscribe := jm.Subscribe("notifications", func(e JobEvent) { ns.onJobEvent(jm, e) })
+	return func() {
+		unsubscribe()
+		ns.inflight.Wait()
+	}
+}
+
+func (ns *Notifications) onJobEvent(jm *JobManager, e JobEvent) {
+	if e.Type != JobEventStatus || (e.Status != JobStatusCompleted && e.Status != JobStatusFailed) {
+		return
+	}
+	job, exists := jm.GetJob(e.JobID)
+	if !exists {
+		return
+	}
+	job.mu.RLock()
+	// A node pool scale or kubeconfig rotation completes the lab again: it was
+	// announced ready the first time.
+	redeployed := e.Status == JobStatusCompleted && job.completedBefore(e.At)
+	labName := e.JobID
+	if job.Config != nil && job.Config.StackName != "" {
+		labName = job.Config.StackName
+	}
+	job.mu.RUnlock()
+	if redeployed {
+		return
+	}
+
+	n := Notification{Kind: NotifyLabCompleted, LabID: e.JobID, LabName: labName, Link: ns.publicURL + "/admin?job=" + url.QueryEscape(e.JobID)}
+	if e.Status == JobStatusFailed {
+		n.Kind = NotifyLabFailed
+		n.Detail = e.Reason
+	}
+	ns.send(n)
+}
+
+// labFull notifies that the last workspace of a lab was taken.
+func (ns *Notifications) labFull(labID, labName string, maxWorkspaces int) {
+	if !ns.Enabled() {
+		return
+	}
+	ns.send(Notification{
+		Kind:    NotifyLabFull,
+		LabID:   labID,
+		LabName: labName,
+		Detail:  strconv.Itoa(maxWorkspaces),
+		Link:    ns.publicURL + "/labs/" + url.PathEscape(labID) + "/workspaces",
+	})
+}
+
+// send posts n with every notifier, in the background.
+func (ns *Notifications) send(n Notification) {
+	for _, notifier := range ns.notifiers {
+		ns.inflight.Add(1)
+		go func(notifier Notifier) {
+			defer ns.inflight.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+			defer cancel()
+			if err := notifier.Notify(ctx, n); err != nil {
+				slog.Error("Notifications: message failed", "notifier", notifier.Name(), "job_id", n.LabID, "error", err)
+			}
+		}(notifier)
+	}
+}
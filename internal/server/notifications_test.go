@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier keeps the notifications it is given.
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (r *recordingNotifier) Name() string { return "recording" }
+
+func (r *recordingNotifier) Notify(_ context.Context, n Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+// chatServer answers status to every post and keeps the JSON bodies.
+func chatServer(t *testing.T, status int) (*[]map[string]string, string) {
+	var bodies []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]string
+		json.Unmarshal(data, &body)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return &bodies, srv.URL
+}
+
+func TestNotifications_SlackAndDiscordMessages(t *testing.T) {
+	slackBodies, slackURL := chatServer(t, http.StatusOK)
+	discordBodies, discordURL := chatServer(t, http.StatusNoContent)
+	t.Setenv(EnvSlackWebhookURL, slackURL)
+	t.Setenv(EnvDiscordWebhookURL, discordURL)
+	ns, err := NewNotificationsFromEnv("https://easylab.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"slack", "discord"}, ns.Names())
+
+	jm := NewJobManager("")
+	stop := ns.Start(jm)
+	id := jm.CreateJob(&LabConfig{StackName: "devoxx"})
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	stop()
+
+	link := "https://easylab.example.com/admin?job=" + id
+	require.Len(t, *slackBodies, 1)
+	assert.Equal(t, "✅ Lab devoxx is ready. <"+link+"|Open the lab>", (*slackBodies)[0]["text"])
+	require.Len(t, *discordBodies, 1)
+	assert.Equal(t, "✅ Lab devoxx is ready. [Open the lab]("+link+")", (*discordBodies)[0]["content"])
+}
+
+func TestNotifications_FailedLab(t *testing.T) {
+	rec := &recordingNotifier{}
+	ns := NewNotifications("", rec)
+	jm := NewJobManager("")
+	stop := ns.Start(jm)
+	id := jm.CreateJob(&LabConfig{StackName: "devoxx"})
+	jm.SetError(id, assert.AnError)
+	jm.UpdateJobStatus(id, JobStatusDryRunCompleted)
+	stop()
+
+	require.Len(t, rec.sent, 1, "only completions and failures are notified")
+	assert.Equal(t, NotifyLabFailed, rec.sent[0].Kind)
+	assert.Equal(t, assert.AnError.Error(), rec.sent[0].Detail)
+	assert.Equal(t, "/admin?job="+id, rec.sent[0].Link)
+}
+
+func TestNotifications_ScaleIsNotAnnounced(t *testing.T) {
+	rec := &recordingNotifier{}
+	ns := NewNotifications("", rec)
+	jm := NewJobManager("")
+	stop := ns.Start(jm)
+	cm := NewCredentialsManager()
+	require.NoError(t, cm.SetCredentials(&OVHCredentials{ApplicationKey: "k", ApplicationSecret: "s", ConsumerKey: "c", ServiceName: "p", Endpoint: "ovh-eu"}))
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}, cm, nil, nil, nil)
+	id := jm.CreateJob(&LabConfig{StackName: "devoxx", Provider: "ovh", NodePoolDesiredNodeCount: 3, NodePoolMinNodeCount: 1, NodePoolMaxNodeCount: 5})
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+
+	scaleRequest(h, id, url.Values{"nodepool_desired_node_count": {"2"}})
+	job, _ := jm.GetJob(id)
+	require.Eventually(t, func() bool {
+		job.mu.RLock()
+		defer job.mu.RUnlock()
+		return job.Status == JobStatusCompleted && len(job.History) > 3
+	}, 5*time.Second, 10*time.Millisecond)
+	stop()
+
+	require.Len(t, rec.sent, 1, "the lab is announced ready once")
+	assert.Equal(t, NotifyLabCompleted, rec.sent[0].Kind)
+}
+
+func TestNotifications_FailureLeavesTheJobAlone(t *testing.T) {
+	bodies, url := chatServer(t, http.StatusInternalServerError)
+	ns := NewNotifications("", &slackNotifier{url: url, client: http.DefaultClient})
+	jm := NewJobManager("")
+	stop := ns.Start(jm)
+	id := jm.CreateJob(&LabConfig{StackName: "devoxx"})
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	stop()
+
+	assert.Len(t, *bodies, 1)
+	job, _ := jm.GetJob(id)
+	assert.Equal(t, JobStatusCompleted, job.Status)
+	assert.Empty(t, job.Error)
+	assert.Empty(t, job.Output)
+}
+
+func TestNewNotificationsFromEnv(t *testing.T) {
+	t.Setenv(EnvSlackWebhookURL, "")
+	t.Setenv(EnvDiscordWebhookURL, "")
+	ns, err := NewNotificationsFromEnv("")
+	require.NoError(t, err)
+	assert.False(t, ns.Enabled())
+
+	t.Setenv(EnvSlackWebhookURL, "https://hooks.slack.com/services/T0/B0/x")
+	t.Setenv(EnvNotifyLogOnly, "true")
+	ns, err = NewNotificationsFromEnv("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"log"}, ns.Names(), "the log replaces the channels")
+
+	t.Setenv(EnvNotifyLogOnly, "maybe")
+	_, err = NewNotificationsFromEnv("")
+	assert.Error(t, err)
+
+	t.Setenv(EnvNotifyLogOnly, "")
+	t.Setenv(EnvDiscordWebhookURL, "discord.com/api/webhooks/1/x")
+	_, err = NewNotificationsFromEnv("")
+	assert.ErrorContains(t, err, EnvDiscordWebhookURL)
+}
+
+func TestWorkspaceSeat(t *testing.T) {
+	t.Parallel()
+	existing := []workspace.Workspace{
+		{Name: "lab-alice-default", Owner: "alice", Template: "default"},
+		{Name: "bobs-box", Owner: "bob", Template: "default"},
+	}
+	tests := []struct {
+		name                  string
+		max                   int
+		owner, template, ws   string
+		wantAllowed, wantLast bool
+	}{
+		{"room left", 4, "carol", "default", "", true, false},
+		{"last seat", 3, "carol", "default", "", true, true},
+		{"full", 2, "carol", "default", "", false, false},
+		{"own workspace again", 2, "alice", "default", "", true, false},
+		{"own named workspace again", 2, "bob", "default", "bobs-box", true, false},
+		{"another template", 2, "alice", "docker", "", false, false},
+	}
+	for _, tt := range tests {
+		allowed, last := workspaceSeat(existing, tt.max, tt.owner, tt.template, tt.ws)
+		assert.Equal(t, tt.wantAllowed, allowed, tt.name)
+		assert.Equal(t, tt.wantLast, last, tt.name)
+	}
+}
+
+func TestOwnWorkspace(t *testing.T) {
+	t.Parallel()
+	existing := []workspace.Workspace{
+		{Name: "lab-alice-default", Owner: "alice", Template: "default"},
+		{Name: "bobs-box", Owner: "bob", Template: "default"},
+	}
+	own, ok := ownWorkspace(existing, "bob", "default")
+	assert.True(t, ok)
+	assert.Equal(t, "bobs-box", own.Name, "whatever it was named")
+	_, ok = ownWorkspace(existing, "alice", "docker")
+	assert.False(t, ok, "another template")
+	_, ok = ownWorkspace(existing, "carol", "default")
+	assert.False(t, ok)
+}
+
+func TestCreateStudentWorkspace_LabFull(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	rec := &recordingNotifier{}
+	ns := NewNotifications("", rec)
+	h.SetNotifications(ns)
+	fb := &fakeBackend{reachable: true, workspaces: []workspace.Workspace{{Name: "ws-alice", Owner: "alice", Template: "default"}}}
+	useFakeBackend(h, fb)
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.mu.Lock()
+	job.Config.WorkspaceTemplates = []WorkspaceTemplate{{Name: "default"}}
+	job.Config.MaxWorkspaces = 2
+	job.mu.Unlock()
+
+	_, reqErr := h.createStudentWorkspace(context.Background(), "bob@example.com", studentWorkspaceRequest{LabID: labID})
+	require.Nil(t, reqErr)
+	ns.inflight.Wait()
+	require.Len(t, rec.sent, 1, "bob took the last seat")
+	assert.Equal(t, NotifyLabFull, rec.sent[0].Kind)
+	assert.Equal(t, "2", rec.sent[0].Detail)
+	assert.Equal(t, "/labs/"+labID+"/workspaces", rec.sent[0].Link)
+
+	fb.workspaces = append(fb.workspaces, workspace.Workspace{Name: "ws-bob", Owner: "bob", Template: "default"})
+	_, reqErr = h.createStudentWorkspace(context.Background(), "carol@example.com", studentWorkspaceRequest{LabID: labID})
+	require.NotNil(t, reqErr)
+	assert.Equal(t, http.StatusConflict, reqErr.Status)
+	assert.Equal(t, "Lab Full", reqErr.Title)
+	assert.Len(t, fb.Ensured, 1, "no workspace is created for carol")
+
+	ws, reqErr := h.createStudentWorkspace(context.Background(), "alice@example.com", studentWorkspaceRequest{LabID: labID})
+	require.Nil(t, reqErr, "alice keeps her seat")
+	assert.Equal(t, "ws-alice", ws.WorkspaceName)
+	assert.Len(t, fb.Ensured, 1, "alice gets her workspace back")
+
+	_, reqErr = h.createStudentWorkspace(context.Background(), "alice@example.com", studentWorkspaceRequest{LabID: labID, WorkspaceName: "another-one"})
+	require.NotNil(t, reqErr, "a second workspace takes a seat")
+	assert.Equal(t, "Lab Full", reqErr.Title)
+}
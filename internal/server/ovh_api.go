@@ -3,11 +3,12 @@ package server
 import (
 	"fmt"
 	"html"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/ovh/go-ovh/ovh"
 )
@@ -16,6 +17,31 @@ type ovhFlavor struct {
 	Name  string `json:"name"`
 	VCPUs int    `json:"vcpus"`
 	RAM   int    `json:"ram"`
+	GPUs  int    `json:"gpus"`
+}
+
+// label describes the flavor in the wizard's flavor list.
+func (f ovhFlavor) label() string {
+	if f.GPUs > 0 {
+		return fmt.Sprintf("%s (%d vCPU, %d GB RAM, %d GPU)", f.Name, f.VCPUs, f.RAM, f.GPUs)
+	}
+	return fmt.Sprintf("%s (%d vCPU, %d GB RAM)", f.Name, f.VCPUs, f.RAM)
+}
+
+// sizeAttrs are the data attributes the wizard reads to pre-fill the workspace
+// quota from the node pool size.
+func (f ovhFlavor) sizeAttrs() string {
+	return fmt.Sprintf(` data-vcpus="%d" data-ram="%d"`, f.VCPUs, f.RAM)
+}
+
+// ovhAPIClient is the OVH API client of the credentials. go-ovh signs its
+// requests and keeps the clock delta with the API.
+func ovhAPIClient(creds *OVHCredentials) (*ovh.Client, error) {
+	client, err := ovh.NewClient(creds.Endpoint, creds.ApplicationKey, creds.ApplicationSecret, creds.ConsumerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OVH client: %w", err)
+	}
+	return client, nil
 }
 
 func (h *Handler) newOVHClient() (*ovh.Client, string, error) {
@@ -24,9 +50,9 @@ func (h *Handler) newOVHClient() (*ovh.Client, string, error) {
 		return nil, "", fmt.Errorf("OVH credentials not configured: %w", err)
 	}
 
-	client, err := ovh.NewClient(creds.Endpoint, creds.ApplicationKey, creds.ApplicationSecret, creds.ConsumerKey)
+	client, err := ovhAPIClient(creds)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create OVH client: %w", err)
+		return nil, "", err
 	}
 
 	return client, creds.ServiceName, nil
@@ -42,49 +68,91 @@ func (h *Handler) GetOVHRegions(w http.ResponseWriter, r *http.Request) {
 
 	if h.ovhOptionsManager != nil && h.ovhOptionsManager.HasCache() {
 		regions, defaultRegion := h.ovhOptionsManager.GetRegionsForForm()
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		var b strings.Builder
 		for _, region := range regions {
 			selected := ""
 			if region == defaultRegion || (defaultRegion == "" && region == regions[0]) {
 				selected = " selected"
 			}
-			fmt.Fprintf(w, `<option value="%s"%s>%s</option>`, escapeHTML(region), selected, escapeHTML(region))
+			fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, escapeHTML(region), selected, escapeHTML(region))
 		}
+		writeHTMLFragment(w, http.StatusOK, b.String())
 		return
 	}
 
 	client, serviceName, err := h.newOVHClient()
 	if err != nil {
-		log.Printf("GetOVHRegions: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<option value="" disabled selected>Failed to load regions</option>`)
+		slog.ErrorContext(r.Context(), "GetOVHRegions failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>Failed to load regions</option>`)
 		return
 	}
 
-	var regions []string
-	endpoint := fmt.Sprintf("/cloud/project/%s/capabilities/kube/regions", serviceName)
-	if err := client.Get(endpoint, &regions); err != nil {
-		log.Printf("GetOVHRegions: OVH API error: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprintf(w, `<option value="" disabled selected>Failed to load regions</option>`)
+	regions, err := fetchOVHRegions(client, serviceName)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "GetOVHRegions failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>Failed to load regions</option>`)
 		return
 	}
 
-	sort.Strings(regions)
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var b strings.Builder
 	for i, region := range regions {
 		if i == 0 {
-			fmt.Fprintf(w, `<option value="%s" selected>%s</option>`, escapeHTML(region), escapeHTML(region))
+			fmt.Fprintf(&b, `<option value="%s" selected>%s</option>`, escapeHTML(region), escapeHTML(region))
 		} else {
-			fmt.Fprintf(w, `<option value="%s">%s</option>`, escapeHTML(region), escapeHTML(region))
+			fmt.Fprintf(&b, `<option value="%s">%s</option>`, escapeHTML(region), escapeHTML(region))
+		}
+	}
+	writeHTMLFragment(w, http.StatusOK, b.String())
+}
+
+// fetchOVHRegions lists the regions where OVH offers Managed Kubernetes, sorted.
+func fetchOVHRegions(client *ovh.Client, serviceName string) ([]string, error) {
+	var regions []string
+	endpoint := fmt.Sprintf("/cloud/project/%s/capabilities/kube/regions", serviceName)
+	if err := client.Get(endpoint, &regions); err != nil {
+		return nil, fmt.Errorf("OVH API error: %w", err)
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// labRegions returns the regions a lab may be created in: those the OVH options
+// enable when the cache is loaded, else every region the OVH API offers.
+func (h *Handler) labRegions() ([]string, error) {
+	if h.ovhOptionsManager != nil && h.ovhOptionsManager.HasCache() {
+		regions, _ := h.ovhOptionsManager.GetRegionsForForm()
+		return regions, nil
+	}
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		return nil, err
+	}
+	return fetchOVHRegions(client, serviceName)
+}
+
+// resolveRegion matches the lab's region against the available regions,
+// ignoring case ("gra11" is GRA11), and returns the region as OVH names it. An
+// empty region resolves to the only available one, reported by autoSelected.
+func resolveRegion(region string, regions []string) (resolved string, autoSelected bool, err error) {
+	region = strings.TrimSpace(region)
+	if region == "" {
+		if len(regions) == 1 {
+			return regions[0], true, nil
+		}
+		return "", false, fmt.Errorf("a region is required, use one of: %s", strings.Join(regions, ", "))
+	}
+	for _, r := range regions {
+		if strings.EqualFold(r, region) {
+			return r, false, nil
 		}
 	}
+	return "", false, fmt.Errorf("region %q is not available, use one of: %s", region, strings.Join(regions, ", "))
 }
 
 // GetOVHFlavors returns HTML <option> elements for flavors available in a given region.
 // Query param: region (required).
 // Uses the OVHOptionsManager cache when available; falls back to a live OVH API call.
+// With Accept: application/json it returns the region's flavor catalog instead.
 func (h *Handler) GetOVHFlavors(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -96,6 +164,10 @@ func (h *Handler) GetOVHFlavors(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Region is required", http.StatusBadRequest)
 		return
 	}
+	if acceptsJSON(r) {
+		h.writeFlavorCatalog(w, region)
+		return
+	}
 	q := r.URL.Query()
 	minVcpus, _ := strconv.Atoi(q.Get("min_vcpus"))
 	maxVcpus, _ := strconv.Atoi(q.Get("max_vcpus"))
@@ -105,57 +177,170 @@ func (h *Handler) GetOVHFlavors(w http.ResponseWriter, r *http.Request) {
 	if h.ovhOptionsManager != nil && h.ovhOptionsManager.HasCache() {
 		flavors, defaultFlavor := h.ovhOptionsManager.GetFlavorsForForm(region)
 		flavors = filterFlavorsByCPURAM(flavors, minVcpus, maxVcpus, minRam, maxRam)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		if len(flavors) == 0 {
-			fmt.Fprint(w, `<option value="" disabled selected>No flavors match — adjust filters or use 0 for no limit</option>`)
+			writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>No flavors match — adjust filters or use 0 for no limit</option>`)
 			return
 		}
+		var b strings.Builder
 		for i, f := range flavors {
-			label := fmt.Sprintf("%s (%d vCPU, %d GB RAM)", f.Name, f.VCPUs, f.RAM)
+			label := f.label()
 			selected := ""
 			if f.Name == defaultFlavor || (defaultFlavor == "" && i == 0) {
 				selected = " selected"
 			}
-			fmt.Fprintf(w, `<option value="%s"%s>%s</option>`, escapeHTML(f.Name), selected, escapeHTML(label))
+			fmt.Fprintf(&b, `<option value="%s"%s%s>%s</option>`, escapeHTML(f.Name), selected, f.sizeAttrs(), escapeHTML(label))
 		}
+		writeHTMLFragment(w, http.StatusOK, b.String())
 		return
 	}
 
 	client, serviceName, err := h.newOVHClient()
 	if err != nil {
-		log.Printf("GetOVHFlavors: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<option value="" disabled selected>Failed to load flavors</option>`)
+		slog.ErrorContext(r.Context(), "GetOVHFlavors failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>Failed to load flavors</option>`)
 		return
 	}
 
-	var flavors []ovhFlavor
-	endpoint := fmt.Sprintf("/cloud/project/%s/capabilities/kube/flavors?region=%s", serviceName, url.QueryEscape(region))
-	if err := client.Get(endpoint, &flavors); err != nil {
-		log.Printf("GetOVHFlavors: OVH API error: %v", err)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, `<option value="" disabled selected>Failed to load flavors</option>`)
+	flavors, err := fetchOVHFlavors(client, serviceName, region)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "GetOVHFlavors failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>Failed to load flavors</option>`)
 		return
 	}
-
-	sort.Slice(flavors, func(i, j int) bool {
-		return flavors[i].Name < flavors[j].Name
-	})
 	flavors = filterFlavorsByCPURAM(flavors, minVcpus, maxVcpus, minRam, maxRam)
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if len(flavors) == 0 {
-		fmt.Fprint(w, `<option value="" disabled selected>No flavors match — adjust filters or use 0 for no limit</option>`)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" disabled selected>No flavors match — adjust filters or use 0 for no limit</option>`)
 		return
 	}
+	var b strings.Builder
 	for i, f := range flavors {
-		label := fmt.Sprintf("%s (%d vCPU, %d GB RAM)", f.Name, f.VCPUs, f.RAM)
+		label := f.label()
 		if i == 0 {
-			fmt.Fprintf(w, `<option value="%s" selected>%s</option>`, escapeHTML(f.Name), escapeHTML(label))
+			fmt.Fprintf(&b, `<option value="%s" selected%s>%s</option>`, escapeHTML(f.Name), f.sizeAttrs(), escapeHTML(label))
 		} else {
-			fmt.Fprintf(w, `<option value="%s">%s</option>`, escapeHTML(f.Name), escapeHTML(label))
+			fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, escapeHTML(f.Name), f.sizeAttrs(), escapeHTML(label))
+		}
+	}
+	writeHTMLFragment(w, http.StatusOK, b.String())
+}
+
+// fetchOVHFlavors lists the node pool flavors OVH offers in region, sorted by name.
+func fetchOVHFlavors(client *ovh.Client, serviceName, region string) ([]ovhFlavor, error) {
+	var flavors []ovhFlavor
+	endpoint := fmt.Sprintf("/cloud/project/%s/capabilities/kube/flavors?region=%s", serviceName, url.QueryEscape(region))
+	if err := client.Get(endpoint, &flavors); err != nil {
+		return nil, fmt.Errorf("OVH API error: %w", err)
+	}
+	sort.Slice(flavors, func(i, j int) bool { return flavors[i].Name < flavors[j].Name })
+	return flavors, nil
+}
+
+// ovhPrivateNetwork is the part of an OVH private network the VLAN check reads.
+type ovhPrivateNetwork struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	VlanID int    `json:"vlanId"`
+}
+
+// fetchOVHPrivateNetworks lists the project's private networks.
+func fetchOVHPrivateNetworks(client *ovh.Client, serviceName string) ([]ovhPrivateNetwork, error) {
+	var networks []ovhPrivateNetwork
+	if err := client.Get(fmt.Sprintf("/cloud/project/%s/network/private", serviceName), &networks); err != nil {
+		return nil, fmt.Errorf("OVH API error: %w", err)
+	}
+	return networks, nil
+}
+
+// regionFlavors returns every flavor OVH offers in region, ignoring the admin's
+// filters, from the OVHOptionsManager cache or else the OVH API.
+func (h *Handler) regionFlavors(region string) ([]ovhFlavor, error) {
+	if h.ovhOptionsManager != nil && h.ovhOptionsManager.HasCache() {
+		if flavors := h.ovhOptionsManager.GetCachedFlavors(region); len(flavors) > 0 {
+			return flavors, nil
+		}
+	}
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		return nil, err
+	}
+	return fetchOVHFlavors(client, serviceName, region)
+}
+
+// GetOVHKubeVersions returns HTML <option> elements for the Kubernetes versions
+// OVH offers in a region, newest first, after an empty "OVH default" option.
+// Query param: region (required).
+// Uses the OVHOptionsManager cache when available; falls back to a live OVH API call.
+func (h *Handler) GetOVHKubeVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	region := r.URL.Query().Get("region")
+	if region == "" {
+		http.Error(w, "Region is required", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.supportedKubeVersions(region)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "GetOVHKubeVersions failed", "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<option value="" selected>OVH default (failed to load versions)</option>`)
+		return
+	}
+	var b strings.Builder
+	b.WriteString(`<option value="" selected>OVH default</option>`)
+	for _, v := range versions {
+		fmt.Fprintf(&b, `<option value="%s">%s</option>`, escapeHTML(v), escapeHTML(v))
+	}
+	writeHTMLFragment(w, http.StatusOK, b.String())
+}
+
+// supportedKubeVersions returns the Kubernetes versions OVH offers in region,
+// newest first, from the OVHOptionsManager cache or else the OVH API.
+func (h *Handler) supportedKubeVersions(region string) ([]string, error) {
+	if h.ovhOptionsManager != nil && h.ovhOptionsManager.HasCache() {
+		if versions := h.ovhOptionsManager.GetCachedKubeVersions(region); len(versions) > 0 {
+			return versions, nil
 		}
 	}
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		return nil, err
+	}
+	return fetchOVHKubeVersions(client, serviceName, region)
+}
+
+// fetchOVHKubeVersions lists the Kubernetes versions available for new clusters
+// in region, newest first.
+func fetchOVHKubeVersions(client *ovh.Client, serviceName, region string) ([]string, error) {
+	var versions []string
+	endpoint := fmt.Sprintf("/cloud/project/%s/capabilities/kube/versions?region=%s", serviceName, url.QueryEscape(region))
+	if err := client.Get(endpoint, &versions); err != nil {
+		return nil, fmt.Errorf("OVH API error: %w", err)
+	}
+	sortKubeVersions(versions)
+	return versions, nil
+}
+
+// sortKubeVersions sorts "major.minor" versions newest first, comparing the
+// parts as numbers so that 1.10 sorts above 1.9.
+func sortKubeVersions(versions []string) {
+	parse := func(v string) [2]int {
+		var p [2]int
+		major, minor, _ := strings.Cut(v, ".")
+		p[0], _ = strconv.Atoi(major)
+		p[1], _ = strconv.Atoi(minor)
+		return p
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := parse(versions[i]), parse(versions[j])
+		if a[0] != b[0] {
+			return a[0] > b[0]
+		}
+		return a[1] > b[1]
+	})
 }
 
 func escapeHTML(s string) string {
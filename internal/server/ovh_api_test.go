@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 )
@@ -64,6 +65,48 @@ func TestHandler_GetOVHFlavors_NoCredentials(t *testing.T) {
 	}
 }
 
+func TestHandler_GetOVHKubeVersions_WithCachedVersions(t *testing.T) {
+	cm := NewCredentialsManager()
+	opts := NewOVHOptionsManager("", cm)
+	opts.mu.Lock()
+	opts.cachedRegions = map[string]bool{"GRA7": true}
+	opts.cachedKubeVersions = map[string][]string{"GRA7": {"1.31", "1.30"}}
+	opts.mu.Unlock()
+
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, cm, opts, nil, nil)
+	req := httptest.NewRequest("GET", "/api/ovh/kube-versions?region=GRA7", nil)
+	w := httptest.NewRecorder()
+	h.GetOVHKubeVersions(w, req)
+	body := w.Body.String()
+	if !strings.HasPrefix(body, `<option value="" selected>OVH default</option>`) {
+		t.Errorf("GetOVHKubeVersions should offer the OVH default first, got %q", body)
+	}
+	if !strings.Contains(body, `<option value="1.31">`) || !strings.Contains(body, `<option value="1.30">`) {
+		t.Errorf("GetOVHKubeVersions cached should return cached versions, got %q", body)
+	}
+}
+
+func TestHandler_GetOVHKubeVersions_NoCredentials(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	req := httptest.NewRequest("GET", "/api/ovh/kube-versions?region=GRA7", nil)
+	w := httptest.NewRecorder()
+	h.GetOVHKubeVersions(w, req)
+	if !strings.Contains(w.Body.String(), "failed to load versions") {
+		t.Error("GetOVHKubeVersions no-creds should still offer the OVH default")
+	}
+}
+
+func TestSortKubeVersions(t *testing.T) {
+	versions := []string{"1.9", "1.31", "1.10", "1.30"}
+	sortKubeVersions(versions)
+	want := []string{"1.31", "1.30", "1.10", "1.9"}
+	for i := range want {
+		if versions[i] != want[i] {
+			t.Fatalf("sortKubeVersions() = %v, want %v", versions, want)
+		}
+	}
+}
+
 func TestEscapeHTML(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -89,3 +132,67 @@ func TestEscapeHTML(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveRegion(t *testing.T) {
+	regions := []string{"BHS5", "GRA11", "GRA7"}
+	tests := []struct {
+		name         string
+		region       string
+		regions      []string
+		want         string
+		autoSelected bool
+		wantErr      bool
+	}{
+		{name: "exact", region: "GRA11", regions: regions, want: "GRA11"},
+		{name: "case-insensitive", region: " gra7 ", regions: regions, want: "GRA7"},
+		{name: "not offered", region: "GRA9", regions: regions, wantErr: true},
+		{name: "empty with several regions", region: "", regions: regions, wantErr: true},
+		{name: "empty with a single region", region: "", regions: []string{"SBG5"}, want: "SBG5", autoSelected: true},
+		{name: "set with a single region", region: "sbg5", regions: []string{"SBG5"}, want: "SBG5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, autoSelected, err := resolveRegion(tt.region, tt.regions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveRegion(%q) error = %v, wantErr %v", tt.region, err, tt.wantErr)
+			}
+			if got != tt.want || autoSelected != tt.autoSelected {
+				t.Errorf("resolveRegion(%q) = %q, %v, want %q, %v", tt.region, got, autoSelected, tt.want, tt.autoSelected)
+			}
+		})
+	}
+}
+
+func TestDryRunLab_RejectsARegionTheProjectDoesNotOffer(t *testing.T) {
+	jm := NewJobManager(t.TempDir())
+	cm := NewCredentialsManager()
+	if err := cm.SetCredentials(&OVHCredentials{ApplicationKey: "key", ApplicationSecret: "secret", ConsumerKey: "consumer", ServiceName: "service", Endpoint: "ovh-eu"}); err != nil {
+		t.Fatal(err)
+	}
+	opts := NewOVHOptionsManager("", cm)
+	opts.mu.Lock()
+	opts.cachedRegions = map[string]bool{"GRA11": true, "SBG5": true}
+	opts.mu.Unlock()
+	h := NewHandler(jm, &PulumiExecutor{}, cm, opts, nil, nil)
+
+	form := url.Values{
+		"stack_name":       {"region-lab"},
+		"network_region":   {"GRA7"},
+		"network_id":       {"10"},
+		"network_mask":     {"10.0.0.0/24"},
+		"network_start_ip": {"10.0.0.100"},
+		"network_end_ip":   {"10.0.0.200"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/labs/dry-run", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.DryRunLab(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Invalid Region") || !strings.Contains(body, "GRA11, SBG5") {
+		t.Errorf("DryRunLab with an unknown region = %q, want an error listing the regions", body)
+	}
+	if jobs := jm.GetAllJobs(); len(jobs) != 0 {
+		t.Errorf("DryRunLab with an unknown region created %d jobs", len(jobs))
+	}
+}
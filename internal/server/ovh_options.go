@@ -3,14 +3,11 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/url"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 	"sync"
-
-	"github.com/ovh/go-ovh/ovh"
 )
 
 // OVHItemConfig holds the enabled items and default for a category (regions or flavors per region).
@@ -31,19 +28,21 @@ type OVHOptionsConfig struct {
 
 // OVHOptionsManager caches OVH regions/flavors in memory and applies admin filtering preferences.
 type OVHOptionsManager struct {
-	cachedRegions map[string]bool
-	cachedFlavors map[string][]ovhFlavor // region -> flavors
-	config        OVHOptionsConfig
-	dataDir       string
-	credsMgr      *CredentialsManager
-	mu            sync.RWMutex
+	cachedRegions      map[string]bool
+	cachedFlavors      map[string][]ovhFlavor // region -> flavors
+	cachedKubeVersions map[string][]string    // region -> Kubernetes versions
+	config             OVHOptionsConfig
+	dataDir            string
+	credsMgr           *CredentialsManager
+	mu                 sync.RWMutex
 }
 
 // NewOVHOptionsManager creates a new manager and loads persisted config from disk.
 func NewOVHOptionsManager(dataDir string, credsMgr *CredentialsManager) *OVHOptionsManager {
 	m := &OVHOptionsManager{
-		cachedRegions: make(map[string]bool),
-		cachedFlavors: make(map[string][]ovhFlavor),
+		cachedRegions:      make(map[string]bool),
+		cachedFlavors:      make(map[string][]ovhFlavor),
+		cachedKubeVersions: make(map[string][]string),
 		config: OVHOptionsConfig{
 			Flavors: make(map[string]OVHItemConfig),
 		},
@@ -51,7 +50,7 @@ func NewOVHOptionsManager(dataDir string, credsMgr *CredentialsManager) *OVHOpti
 		credsMgr: credsMgr,
 	}
 	if err := m.LoadConfig(); err != nil {
-		log.Printf("[OVH-OPTIONS] Warning: failed to load config: %v", err)
+		slog.Warn("Failed to load config", "component", "ovh-options", "error", err)
 	}
 	return m
 }
@@ -137,16 +136,16 @@ func (m *OVHOptionsManager) GetConfig() OVHOptionsConfig {
 	return cfg
 }
 
-// RefreshFromAPI fetches regions and flavors from the OVH API and populates the cache.
+// RefreshFromAPI fetches regions, flavors and Kubernetes versions from the OVH API and populates the cache.
 func (m *OVHOptionsManager) RefreshFromAPI() error {
 	creds, err := m.credsMgr.GetOVHCredentials()
 	if err != nil {
 		return fmt.Errorf("OVH credentials not configured: %w", err)
 	}
 
-	client, err := ovh.NewClient(creds.Endpoint, creds.ApplicationKey, creds.ApplicationSecret, creds.ConsumerKey)
+	client, err := ovhAPIClient(creds)
 	if err != nil {
-		return fmt.Errorf("failed to create OVH client: %w", err)
+		return err
 	}
 
 	var regions []string
@@ -157,16 +156,24 @@ func (m *OVHOptionsManager) RefreshFromAPI() error {
 
 	flavorsMap := make(map[string][]ovhFlavor, len(regions))
 	for _, region := range regions {
-		var flavors []ovhFlavor
-		ep := fmt.Sprintf("/cloud/project/%s/capabilities/kube/flavors?region=%s", creds.ServiceName, url.QueryEscape(region))
-		if err := client.Get(ep, &flavors); err != nil {
-			log.Printf("[OVH-OPTIONS] Warning: failed to fetch flavors for region %s: %v", region, err)
+		flavors, err := fetchOVHFlavors(client, creds.ServiceName, region)
+		if err != nil {
+			slog.Warn("Failed to fetch flavors for region", "component", "ovh-options", "region", region, "error", err)
 			continue
 		}
-		sort.Slice(flavors, func(i, j int) bool { return flavors[i].Name < flavors[j].Name })
 		flavorsMap[region] = flavors
 	}
 
+	versionsMap := make(map[string][]string, len(regions))
+	for _, region := range regions {
+		versions, err := fetchOVHKubeVersions(client, creds.ServiceName, region)
+		if err != nil {
+			slog.Warn("Failed to fetch Kubernetes versions for region", "component", "ovh-options", "region", region, "error", err)
+			continue
+		}
+		versionsMap[region] = versions
+	}
+
 	regionsSet := make(map[string]bool, len(regions))
 	for _, r := range regions {
 		regionsSet[r] = true
@@ -175,9 +182,10 @@ func (m *OVHOptionsManager) RefreshFromAPI() error {
 	m.mu.Lock()
 	m.cachedRegions = regionsSet
 	m.cachedFlavors = flavorsMap
+	m.cachedKubeVersions = versionsMap
 	m.mu.Unlock()
 
-	log.Printf("[OVH-OPTIONS] Cache refreshed: %d regions loaded", len(regions))
+	slog.Info("Cache refreshed", "component", "ovh-options", "regions", len(regions))
 	return nil
 }
 
@@ -210,6 +218,14 @@ func (m *OVHOptionsManager) GetCachedFlavors(region string) []ovhFlavor {
 	return out
 }
 
+// GetCachedKubeVersions returns the cached Kubernetes versions for a given region,
+// newest first.
+func (m *OVHOptionsManager) GetCachedKubeVersions(region string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.cachedKubeVersions[region]...)
+}
+
 // GetRegionsForForm returns the filtered/sorted region list with the default first.
 // If the admin config has enabled regions, only those are shown; otherwise all cached regions.
 func (m *OVHOptionsManager) GetRegionsForForm() (regions []string, defaultRegion string) {
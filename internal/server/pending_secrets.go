@@ -3,7 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
@@ -99,7 +99,7 @@ func (h *Handler) applyPendingSecrets(jobID string) {
 
 	job, exists := h.jobManager.GetJob(jobID)
 	if !exists {
-		log.Printf("Cannot apply credentials for lab %s: lab not found", jobID)
+		slog.Error("Cannot apply credentials for lab: lab not found", "job_id", jobID)
 		return
 	}
 	job.mu.RLock()
@@ -108,18 +108,18 @@ func (h *Handler) applyPendingSecrets(jobID string) {
 	job.mu.RUnlock()
 
 	if kubeconfig == "" {
-		log.Printf("Cannot apply credentials for lab %s: no kubeconfig", jobID)
+		slog.Error("Cannot apply credentials for lab: no kubeconfig", "job_id", jobID)
 		return
 	}
 
 	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
 	if err != nil {
-		log.Printf("Cannot apply credentials for lab %s: %v", jobID, err)
+		slog.Error("Cannot apply credentials for lab", "job_id", jobID, "error", err)
 		return
 	}
 	sm, ok := backend.(workspace.SecretManager)
 	if !ok {
-		log.Printf("Cannot apply credentials for lab %s: backend cannot manage secrets", jobID)
+		slog.Error("Cannot apply credentials for lab: backend cannot manage secrets", "job_id", jobID)
 		return
 	}
 
@@ -136,11 +136,11 @@ func (h *Handler) applyPendingSecrets(jobID string) {
 		}
 		if err != nil {
 			// Names the credential, never the token: this goes to the lab's log.
-			log.Printf("Failed to apply %s credential %q to lab %s: %v", sec.Kind, sec.Name, jobID, err)
+			slog.Error("Failed to apply credential", "kind", sec.Kind, "name", sec.Name, "job_id", jobID, "error", err)
 			h.jobManager.AppendOutput(jobID, "WARNING: could not create credential "+sec.Name+" — add it from the lab's Workspaces page")
 			continue
 		}
-		log.Printf("Applied %s credential %q to lab %s", sec.Kind, sec.Name, jobID)
+		slog.Info("Applied credential", "kind", sec.Kind, "name", sec.Name, "job_id", jobID)
 		h.jobManager.AppendOutput(jobID, "Created credential "+sec.Name)
 	}
 }
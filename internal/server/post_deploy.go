@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"easylab/internal/providers/workspace"
+)
+
+// postDeployManifestTimeout bounds fetching and applying one post-deploy
+// manifest, so an unreachable URL cannot hold a lab in "running" forever.
+const postDeployManifestTimeout = 2 * time.Minute
+
+// maxPostDeployManifestSize caps a fetched manifest; baseline manifests are a few
+// kilobytes, anything near this is not one.
+const maxPostDeployManifestSize = 4 << 20
+
+// parsePostDeployManifests reads the wizard's manifest list: one URL or path per
+// line, blank lines ignored.
+func parsePostDeployManifests(s string) []string {
+	var sources []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			sources = append(sources, line)
+		}
+	}
+	return sources
+}
+
+// isManifestURL reports whether a post-deploy manifest source is fetched over
+// HTTP rather than read from the server's disk.
+func isManifestURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// validatePostDeployManifests checks each manifest source when the lab is
+// created. A path must exist now; a URL is only fetched after pulumi up, so only
+// its form is checked.
+func validatePostDeployManifests(sources []string) error {
+	for _, source := range sources {
+		if isManifestURL(source) {
+			if u, err := url.Parse(source); err != nil || u.Host == "" {
+				return fmt.Errorf("invalid manifest URL %q", source)
+			}
+			continue
+		}
+		if !filepath.IsAbs(source) {
+			return fmt.Errorf("manifest %q must be an http(s) URL or an absolute path on the server", source)
+		}
+		info, err := os.Stat(source)
+		if err != nil {
+			return fmt.Errorf("manifest %q cannot be read: %w", source, err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("manifest %q is a directory", source)
+		}
+	}
+	return nil
+}
+
+// loadManifest returns the content of a post-deploy manifest source.
+func loadManifest(ctx context.Context, source string) ([]byte, error) {
+	if !isManifestURL(source) {
+		return os.ReadFile(source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPostDeployManifestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPostDeployManifestSize {
+		return nil, fmt.Errorf("manifest is larger than %d bytes", maxPostDeployManifestSize)
+	}
+	return data, nil
+}
+
+// prepareProvisionedCluster is the executor's afterProvision hook. The baseline
+// manifests go first, since the lab may depend on what they create; then the
+// credentials captured in the wizard.
+func (h *Handler) prepareProvisionedCluster(jobID string) {
+	h.applyPostDeployManifests(jobID)
+	h.applyPendingSecrets(jobID)
+}
+
+// applyPostDeployManifests applies a lab's post-deploy manifests to its freshly
+// provisioned cluster, in order, reporting each object in the lab's output.
+//
+// Like applyPendingSecrets it fails soft: a manifest that cannot be fetched or
+// applied is reported as a warning and the next one is still applied, because
+// the cluster itself is up and the admin can apply the rest by hand.
+func (h *Handler) applyPostDeployManifests(jobID string) {
+	job, exists := h.jobManager.GetJob(jobID)
+	if !exists {
+		return
+	}
+	job.mu.RLock()
+	var sources []string
+	if job.Config != nil {
+		sources = job.Config.PostDeployManifests
+	}
+	kubeconfig := extractStringFromConfigValue(job.Kubeconfig)
+	namespace := job.workspaceNamespace()
+	job.mu.RUnlock()
+
+	if len(sources) == 0 {
+		return
+	}
+	if kubeconfig == "" {
+		h.jobManager.AppendOutput(jobID, "WARNING: no kubeconfig, post-deploy manifests were not applied")
+		return
+	}
+	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
+	if err != nil {
+		slog.Error("Cannot apply post-deploy manifests to lab", "job_id", jobID, "error", err)
+		h.jobManager.AppendOutput(jobID, fmt.Sprintf("WARNING: post-deploy manifests were not applied: %v", err))
+		return
+	}
+	applier, ok := backend.(workspace.ManifestApplier)
+	if !ok {
+		h.jobManager.AppendOutput(jobID, "WARNING: this workspace backend cannot apply manifests, post-deploy manifests were not applied")
+		return
+	}
+
+	h.jobManager.AppendOutput(jobID, fmt.Sprintf("Applying %d post-deploy manifest(s)...", len(sources)))
+	for _, source := range sources {
+		h.applyPostDeployManifest(jobID, applier, source)
+	}
+}
+
+func (h *Handler) applyPostDeployManifest(jobID string, applier workspace.ManifestApplier, source string) {
+	ctx, cancel := context.WithTimeout(context.Background(), postDeployManifestTimeout)
+	defer cancel()
+
+	manifest, err := loadManifest(ctx, source)
+	if err != nil {
+		slog.Error("Failed to load post-deploy manifest", "source", source, "job_id", jobID, "error", err)
+		h.jobManager.AppendOutput(jobID, fmt.Sprintf("WARNING: could not load manifest %s: %v", source, err))
+		return
+	}
+	applied, err := applier.ApplyManifest(ctx, manifest)
+	for _, obj := range applied {
+		h.jobManager.AppendOutput(jobID, fmt.Sprintf("  %s applied", obj))
+	}
+	if err != nil {
+		slog.Error("Failed to apply post-deploy manifest", "source", source, "job_id", jobID, "error", err)
+		h.jobManager.AppendOutput(jobID, fmt.Sprintf("WARNING: manifest %s was not fully applied: %v", source, err))
+		return
+	}
+	h.jobManager.AppendOutput(jobID, fmt.Sprintf("Applied manifest %s", source))
+}
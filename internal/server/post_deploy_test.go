@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeManifestBackend is a fakeSecretBackend that can also apply manifests. It
+// records each manifest it is given, and how many credentials had been written
+// by then, so tests can check what runs in which order.
+type fakeManifestBackend struct {
+	fakeSecretBackend
+
+	// applyErr fails any manifest containing the key.
+	applyErr map[string]error
+
+	Applied                []string
+	CredentialsAtApplyTime []int
+}
+
+func (f *fakeManifestBackend) ApplyManifest(_ context.Context, manifest []byte) ([]string, error) {
+	content := strings.TrimSpace(string(manifest))
+	f.Applied = append(f.Applied, content)
+	f.CredentialsAtApplyTime = append(f.CredentialsAtApplyTime, len(f.GitCalls)+len(f.RegistryCalls))
+	for key, err := range f.applyErr {
+		if strings.Contains(content, key) {
+			return nil, err
+		}
+	}
+	return []string{"ConfigMap/" + content}, nil
+}
+
+// newManifestTestLab returns a handler wired to a fakeManifestBackend and a
+// completed lab whose config lists the given post-deploy manifests.
+func newManifestTestLab(t *testing.T, sources []string) (*Handler, *fakeManifestBackend, string) {
+	t.Helper()
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeManifestBackend{}
+	h.newWorkspaceBackend = func(_, _ string) (workspace.Backend, error) { return fb, nil }
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.mu.Lock()
+	job.Config.PostDeployManifests = sources
+	job.mu.Unlock()
+	return h, fb, labID
+}
+
+func jobOutput(t *testing.T, jm *JobManager, jobID string) string {
+	t.Helper()
+	job, ok := jm.GetJob(jobID)
+	require.True(t, ok)
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	return strings.Join(job.Output, "\n")
+}
+
+func TestApplyPostDeployManifests_InOrderAndFailSoft(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+	defer srv.Close()
+	file := filepath.Join(t.TempDir(), "rbac.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("rbac"), 0600))
+
+	h, fb, labID := newManifestTestLab(t, []string{
+		srv.URL + "/namespaces",
+		srv.URL + "/missing.yaml",
+		srv.URL + "/broken-quota",
+		file,
+	})
+	fb.applyErr = map[string]error{"broken": fmt.Errorf("admission denied")}
+
+	h.applyPostDeployManifests(labID)
+
+	assert.Equal(t, []string{"namespaces", "broken-quota", "rbac"}, fb.Applied,
+		"an unreachable or failing manifest must not stop the ones after it")
+	out := jobOutput(t, h.jobManager, labID)
+	assert.Contains(t, out, "ConfigMap/namespaces applied")
+	assert.Contains(t, out, "WARNING: could not load manifest "+srv.URL+"/missing.yaml: HTTP 404")
+	assert.Contains(t, out, "admission denied")
+	assert.Contains(t, out, "Applied manifest "+file)
+}
+
+func TestPrepareProvisionedCluster_ManifestsBeforeCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "baseline")
+	}))
+	defer srv.Close()
+
+	h, fb, labID := newManifestTestLab(t, []string{srv.URL + "/baseline.yaml"})
+	h.pendingSecrets.Put(labID, []pendingSecret{{Kind: workspace.AuthSecretGit, Name: "gitcred", Username: "oauth2", Token: "t"}})
+
+	h.prepareProvisionedCluster(labID)
+
+	assert.Equal(t, []int{0}, fb.CredentialsAtApplyTime, "manifests are applied before credentials are written")
+	assert.Len(t, fb.GitCalls, 1)
+}
+
+func TestApplyPostDeployManifests_BackendCannotApply(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{})
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.Config.PostDeployManifests = []string{"https://example.com/baseline.yaml"}
+
+	h.applyPostDeployManifests(labID) // must not panic
+	assert.Contains(t, jobOutput(t, jm, labID), "cannot apply manifests")
+}
+
+func TestValidatePostDeployManifests(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "baseline.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("kind: Namespace"), 0600))
+
+	tests := []struct {
+		name    string
+		sources []string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "url and file", sources: []string{"https://example.com/a.yaml", file}},
+		{name: "url without host", sources: []string{"https:///a.yaml"}, wantErr: true},
+		{name: "relative path", sources: []string{"baseline.yaml"}, wantErr: true},
+		{name: "missing file", sources: []string{file + ".missing"}, wantErr: true},
+		{name: "directory", sources: []string{filepath.Dir(file)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validatePostDeployManifests(tt.sources)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParsePostDeployManifests(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, parsePostDeployManifests(" \n"))
+	assert.Equal(t, []string{"https://example.com/a.yaml", "/etc/easylab/b.yaml"},
+		parsePostDeployManifests(" https://example.com/a.yaml\r\n\n/etc/easylab/b.yaml\n"))
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errPreviewCancelled is returned by Preview when an admin cancelled it.
+var errPreviewCancelled = errors.New("dry run cancelled")
+
+// previewCancelWait bounds how long CancelDryRun waits for the preview to stop
+// before answering; the preview still stops afterwards, the answer just says so.
+const previewCancelWait = 10 * time.Second
+
+// runningPreview is a dry run that can be cancelled.
+type runningPreview struct {
+	cancel    context.CancelFunc
+	done      chan struct{}
+	removeDir bool
+}
+
+// previewRegistry tracks the running dry runs. Only previews are registered:
+// cancelling a real deployment half way would leave resources behind, whereas a
+// preview only plans and creates nothing.
+type previewRegistry struct {
+	mu       sync.Mutex
+	previews map[string]*runningPreview
+}
+
+func (r *previewRegistry) add(jobID string, cancel context.CancelFunc) *runningPreview {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.previews == nil {
+		r.previews = make(map[string]*runningPreview)
+	}
+	p := &runningPreview{cancel: cancel, done: make(chan struct{})}
+	r.previews[jobID] = p
+	return p
+}
+
+func (r *previewRegistry) remove(jobID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.previews, jobID)
+}
+
+// cancel stops the job's preview, returning a channel closed once it has
+// stopped, or false when the job has no running preview.
+func (r *previewRegistry) cancel(jobID string, removeDir bool) (<-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.previews[jobID]
+	if !ok {
+		return nil, false
+	}
+	p.removeDir = p.removeDir || removeDir
+	p.cancel()
+	return p.done, true
+}
+
+// runCancellablePreview runs preview under a context that CancelPreview can
+// cancel. A cancelled preview marks the job cancelled (not failed) and, when
+// asked, removes the job directory once the preview has let go of it.
+func (pe *PulumiExecutor) runCancellablePreview(ctx context.Context, jobID string, preview func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p := pe.previews.add(jobID, cancel)
+	defer close(p.done)
+	defer pe.previews.remove(jobID)
+
+	err := preview(ctx)
+	if ctx.Err() != context.Canceled {
+		return err
+	}
+
+	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Dry run cancelled at %s", time.Now().Format(time.RFC3339)))
+	pe.jobManager.UpdateJobStatus(jobID, JobStatusCancelled)
+	pe.previews.mu.Lock()
+	removeDir := p.removeDir
+	pe.previews.mu.Unlock()
+	if removeDir && pe.workDir != "" {
+		jobDir := filepath.Join(pe.workDir, jobID)
+		if err := os.RemoveAll(jobDir); err != nil {
+			slog.WarnContext(ctx, "Failed to remove the job directory of a cancelled dry run", "dir", jobDir, "error", err)
+		} else {
+			pe.jobManager.AppendOutput(jobID, "Job directory removed")
+		}
+	}
+	return errPreviewCancelled
+}
+
+// PreviewRunning reports whether the job has a dry run that can be cancelled.
+func (pe *PulumiExecutor) PreviewRunning(jobID string) bool {
+	pe.previews.mu.Lock()
+	defer pe.previews.mu.Unlock()
+	_, ok := pe.previews.previews[jobID]
+	return ok
+}
+
+// CancelPreview cancels the job's running dry run, returning a channel closed
+// once it has stopped, or false when the job has no running dry run.
+func (pe *PulumiExecutor) CancelPreview(jobID string, removeDir bool) (<-chan struct{}, bool) {
+	return pe.previews.cancel(jobID, removeDir)
+}
+
+// CancelDryRun cancels a running dry run, e.g. one stuck on an unresponsive
+// provider API. With remove_dir=true the job directory is deleted as well.
+//
+//	POST /api/labs/{id}/cancel
+func (h *Handler) CancelDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "cancel" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	jobID := pathParts[2]
+	if _, exists := h.jobManager.GetJob(jobID); !exists {
+		h.renderHTMLError(w, "Job Not Found", fmt.Sprintf("Job %s does not exist.", jobID))
+		return
+	}
+
+	done, ok := h.pulumiExec.CancelPreview(jobID, r.FormValue("remove_dir") == "true")
+	if !ok {
+		h.renderHTMLError(w, "Nothing to Cancel", fmt.Sprintf("Job %s has no running dry run. Only dry runs can be cancelled.", jobID))
+		return
+	}
+	slog.InfoContext(r.Context(), "Dry run cancelled by admin", "job_id", jobID)
+
+	select {
+	case <-done:
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="success-message"><p>Dry run %s cancelled.</p></div>`, escapeHTML(jobID)))
+	case <-time.After(previewCancelWait):
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="warning-message"><p>Dry run %s is stopping; it will show as cancelled once Pulumi exits.</p></div>`, escapeHTML(jobID)))
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingPreview stands in for a preview wedged on a provider API: it only
+// returns once its context is cancelled.
+func blockingPreview(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// startPreview runs preview for jobID in the background and waits until it can
+// be cancelled. The returned channel yields its result.
+func startPreview(t *testing.T, pe *PulumiExecutor, jobID string, preview func(context.Context) error) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() { result <- pe.runCancellablePreview(context.Background(), jobID, preview) }()
+	require.Eventually(t, func() bool { return pe.PreviewRunning(jobID) }, time.Second, time.Millisecond)
+	return result
+}
+
+func TestRunCancellablePreview_CancelStopsAHungPreview(t *testing.T) {
+	jm := NewJobManager("")
+	pe := &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}
+	jobID := jm.CreateJob(&LabConfig{StackName: "dry"})
+	jm.UpdateJobStatus(jobID, JobStatusRunning)
+	jobDir := filepath.Join(pe.workDir, jobID)
+	require.NoError(t, os.MkdirAll(jobDir, 0755))
+
+	result := startPreview(t, pe, jobID, blockingPreview)
+	done, ok := pe.CancelPreview(jobID, true)
+	require.True(t, ok)
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, errPreviewCancelled)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the preview did not stop after being cancelled")
+	}
+	<-done
+
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, JobStatusCancelled, job.Status)
+	assert.NoDirExists(t, jobDir)
+	assert.False(t, pe.PreviewRunning(jobID))
+}
+
+func TestRunCancellablePreview_KeepsDirectoryUnlessAsked(t *testing.T) {
+	jm := NewJobManager("")
+	pe := &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}
+	jobID := jm.CreateJob(&LabConfig{StackName: "dry"})
+	jobDir := filepath.Join(pe.workDir, jobID)
+	require.NoError(t, os.MkdirAll(jobDir, 0755))
+
+	result := startPreview(t, pe, jobID, blockingPreview)
+	_, ok := pe.CancelPreview(jobID, false)
+	require.True(t, ok)
+	assert.ErrorIs(t, <-result, errPreviewCancelled)
+	assert.DirExists(t, jobDir)
+}
+
+func TestRunCancellablePreview_FailureIsNotCancellation(t *testing.T) {
+	jm := NewJobManager("")
+	pe := &PulumiExecutor{jobManager: jm}
+	jobID := jm.CreateJob(&LabConfig{StackName: "dry"})
+
+	boom := errors.New("preview failed")
+	err := pe.runCancellablePreview(context.Background(), jobID, func(context.Context) error { return boom })
+	assert.ErrorIs(t, err, boom)
+	job, _ := jm.GetJob(jobID)
+	assert.NotEqual(t, JobStatusCancelled, job.Status)
+
+	_, ok := pe.CancelPreview(jobID, false)
+	assert.False(t, ok, "a finished preview cannot be cancelled")
+}
+
+func TestCancelDryRun(t *testing.T) {
+	jm := NewJobManager("")
+	pe := &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}
+	h := NewHandler(jm, pe, NewCredentialsManager(), nil, nil, nil)
+	jobID := jm.CreateJob(&LabConfig{StackName: "dry"})
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/labs/"+jobID+"/cancel", strings.NewReader(url.Values{"remove_dir": {"true"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.CancelDryRun(w, req)
+		return w
+	}
+
+	assert.Contains(t, post().Body.String(), "Nothing to Cancel")
+
+	result := startPreview(t, pe, jobID, blockingPreview)
+	assert.Contains(t, post().Body.String(), "cancelled")
+	assert.ErrorIs(t, <-result, errPreviewCancelled)
+	job, _ := jm.GetJob(jobID)
+	assert.Equal(t, JobStatusCancelled, job.Status)
+
+	w := httptest.NewRecorder()
+	h.CancelDryRun(w, httptest.NewRequest(http.MethodGet, "/api/labs/"+jobID+"/cancel", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protectRequest patches the protection of a job.
+func protectRequest(h *Handler, jobID string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPatch, "/api/jobs/"+jobID+"/protect", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ProtectLab(w, r)
+	return w
+}
+
+func TestProtectLab_TogglesAndAudits(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	jobID := jm.CreateJob(&LabConfig{StackName: "prod-like"})
+
+	w := protectRequest(h, jobID, url.Values{"protected": {"true"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"`+jobID+`","protected":true}`, w.Body.String())
+
+	// Protecting an already protected lab changes nothing.
+	protectRequest(h, jobID, url.Values{"protected": {"true"}})
+	protectRequest(h, jobID, url.Values{"protected": {"false"}, "reason": {"end of term"}})
+
+	job, _ := jm.GetJob(jobID)
+	assert.False(t, job.Protected)
+	require.Len(t, job.ProtectionLog, 2)
+	assert.True(t, job.ProtectionLog[0].Protected)
+	assert.False(t, job.ProtectionLog[1].Protected)
+	assert.Equal(t, "end of term", job.ProtectionLog[1].Reason)
+	assert.NotEmpty(t, job.ProtectionLog[1].From)
+	assert.Contains(t, job.Output, "Deletion protection turned off: end of term")
+}
+
+func TestProtectLab_Refusals(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab"})
+
+	assert.Equal(t, http.StatusBadRequest, protectRequest(h, jobID, url.Values{}).Code)
+	assert.Equal(t, http.StatusBadRequest, protectRequest(h, jobID, url.Values{"protected": {"maybe"}}).Code)
+	assert.Equal(t, http.StatusNotFound, protectRequest(h, "missing", url.Values{"protected": {"true"}}).Code)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/jobs/"+jobID+"/protect", nil)
+	w := httptest.NewRecorder()
+	h.ProtectLab(w, r)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestProtectedLab_CannotBeDestroyedOrRemoved(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab"})
+	jm.UpdateJobStatus(jobID, JobStatusFailed)
+	require.NoError(t, jm.SetProtected(jobID, true, "127.0.0.1", ""))
+
+	r := httptest.NewRequest(http.MethodPost, "/api/stacks/destroy", strings.NewReader("job_id="+jobID))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.DestroyStack(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "protected")
+
+	r = httptest.NewRequest(http.MethodPost, "/api/labs/"+jobID+"/delete", nil)
+	w = httptest.NewRecorder()
+	h.DeleteLab(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	job, exists := jm.GetJob(jobID)
+	require.True(t, exists)
+	assert.Equal(t, JobStatusFailed, job.Status)
+}
+
+func TestProtection_IsPersisted(t *testing.T) {
+	dir := t.TempDir()
+	jm := newTestJobManager(t, dir)
+	jobID := jm.CreateJob(&LabConfig{StackName: "lab"})
+	jm.UpdateJobStatus(jobID, JobStatusCompleted)
+	require.NoError(t, jm.SetProtected(jobID, true, "10.0.0.1", "exam week"))
+
+	reloaded := newTestJobManager(t, dir)
+	require.NoError(t, reloaded.LoadJobs())
+	job, exists := reloaded.GetJob(jobID)
+	require.True(t, exists)
+	assert.True(t, job.Protected)
+	require.Len(t, job.ProtectionLog, 1)
+	assert.Equal(t, "exam week", job.ProtectionLog[0].Reason)
+}
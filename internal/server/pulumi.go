@@ -3,11 +3,15 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,9 +21,8 @@ import (
 	internalPulumi "easylab/internal/pulumi"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
-	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
-	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
@@ -40,6 +43,14 @@ type PulumiExecutor struct {
 	// credentials reach the new cluster. Optional (nil in tests that do not need
 	// it); the executor owns the timing, the handler owns the cluster connection.
 	afterProvision func(jobID string)
+	// previews tracks the running dry runs so they can be cancelled.
+	previews previewRegistry
+	// runs tracks every Pulumi operation in flight, for a graceful shutdown.
+	runs runRegistry
+	// deployRetry re-runs a deployment that failed on a transient OVH error.
+	deployRetry deployRetryPolicy
+	// rollbackOnFailure destroys what a failed deployment created.
+	rollbackOnFailure bool
 }
 
 // jobOutputWriter is a custom io.Writer that forwards output to jobManager
@@ -47,6 +58,14 @@ type jobOutputWriter struct {
 	jobID      string
 	jobManager *JobManager
 	buffer     []byte
+	// verbosity filters the lines appended to the job (see Verbosity.keepLine).
+	verbosity Verbosity
+}
+
+func (w *jobOutputWriter) appendLine(line string) {
+	if line != "" && w.verbosity.keepLine(line) {
+		w.jobManager.AppendOutput(w.jobID, line)
+	}
 }
 
 func (w *jobOutputWriter) Write(p []byte) (n int, err error) {
@@ -66,10 +85,7 @@ func (w *jobOutputWriter) Write(p []byte) (n int, err error) {
 			break // No complete line yet
 		}
 
-		line := strings.TrimRight(string(w.buffer[:idx]), "\r\n")
-		if line != "" {
-			w.jobManager.AppendOutput(w.jobID, line)
-		}
+		w.appendLine(strings.TrimRight(string(w.buffer[:idx]), "\r\n"))
 
 		w.buffer = w.buffer[idx+1:]
 	}
@@ -79,10 +95,7 @@ func (w *jobOutputWriter) Write(p []byte) (n int, err error) {
 
 func (w *jobOutputWriter) Flush() {
 	if len(w.buffer) > 0 {
-		line := strings.TrimRight(string(w.buffer), "\r\n")
-		if line != "" {
-			w.jobManager.AppendOutput(w.jobID, line)
-		}
+		w.appendLine(strings.TrimRight(string(w.buffer), "\r\n"))
 		w.buffer = nil
 	}
 }
@@ -128,10 +141,12 @@ func getAppBaseDir() string {
 
 // NewPulumiExecutor creates a new Pulumi executor
 func NewPulumiExecutor(jobManager *JobManager, workDir string) *PulumiExecutor {
-	log.Printf("Work directory: %s", workDir)
+	slog.Info("Work directory", "work_dir", workDir)
 	return &PulumiExecutor{
-		jobManager: jobManager,
-		workDir:    workDir,
+		jobManager:        jobManager,
+		workDir:           workDir,
+		deployRetry:       deployRetryPolicyFromEnv(),
+		rollbackOnFailure: rollbackOnFailureFromEnv(),
 	}
 }
 
@@ -167,7 +182,7 @@ func (pe *PulumiExecutor) CheckAndInstallPlugins() {
 	pulumiHome := getEnvOrDefault("PULUMI_HOME", filepath.Join(getAppBaseDir(), ".pulumi"))
 	pluginsDir := filepath.Join(pulumiHome, "plugins")
 
-	log.Printf("[PLUGINS] Checking required Pulumi plugins in %s", pluginsDir)
+	slog.Info("Checking required Pulumi plugins", "component", "plugins", "plugins_dir", pluginsDir)
 
 	for _, p := range requiredPlugins {
 		dirName := fmt.Sprintf("resource-%s-v%s", p.Name, p.Version)
@@ -182,16 +197,16 @@ func (pe *PulumiExecutor) CheckAndInstallPlugins() {
 
 		if binaryMissing {
 			// Full reinstall: binary is absent so we need the CLI to download everything.
-			log.Printf("[PLUGINS] Plugin %s v%s binary missing — installing...", p.Name, p.Version)
+			slog.Info("Plugin binary missing, installing", "component", "plugins", "plugin", p.Name, "version", p.Version)
 			if _, statErr := os.Stat(pluginDir); statErr == nil {
 				if removeErr := os.RemoveAll(pluginDir); removeErr != nil {
-					log.Printf("[PLUGINS] Warning: failed to remove incomplete plugin dir %s: %v", pluginDir, removeErr)
+					slog.Warn("Failed to remove incomplete plugin dir", "component", "plugins", "plugin_dir", pluginDir, "error", removeErr)
 				}
 			}
 			if installErr := pe.installPlugin(p.Name, p.Version, p.Server, pulumiHome); installErr != nil {
-				log.Printf("[PLUGINS] Warning: could not install plugin %s v%s: %v", p.Name, p.Version, installErr)
+				slog.Warn("Could not install plugin", "component", "plugins", "plugin", p.Name, "version", p.Version, "error", installErr)
 			} else {
-				log.Printf("[PLUGINS] Plugin %s v%s installed successfully", p.Name, p.Version)
+				slog.Info("Plugin installed", "component", "plugins", "plugin", p.Name, "version", p.Version)
 			}
 			// Refresh YAML presence after reinstall.
 			_, yamlErr = os.Stat(yamlPath)
@@ -199,27 +214,27 @@ func (pe *PulumiExecutor) CheckAndInstallPlugins() {
 		} else if yamlMissing {
 			// Binary is fine but PulumiPlugin.yaml is missing (e.g. OVH provider).
 			// Just synthesize the YAML — no need to delete the working binary.
-			log.Printf("[PLUGINS] Plugin %s v%s PulumiPlugin.yaml missing — creating it...", p.Name, p.Version)
+			slog.Info("Plugin PulumiPlugin.yaml missing, creating it", "component", "plugins", "plugin", p.Name, "version", p.Version)
 		} else {
-			log.Printf("[PLUGINS] Plugin %s v%s OK", p.Name, p.Version)
+			slog.Debug("Plugin OK", "component", "plugins", "plugin", p.Name, "version", p.Version)
 		}
 
 		// Synthesize PulumiPlugin.yaml when absent (after install or for an existing binary).
 		if yamlMissing {
 			if mkErr := os.MkdirAll(pluginDir, 0755); mkErr != nil {
-				log.Printf("[PLUGINS] Warning: failed to create plugin dir %s: %v", pluginDir, mkErr)
+				slog.Warn("Failed to create plugin dir", "component", "plugins", "plugin_dir", pluginDir, "error", mkErr)
 				continue
 			}
 			content := fmt.Sprintf("name: %s\nversion: %s\nruntime: go\n", p.Name, p.Version)
 			if writeErr := os.WriteFile(yamlPath, []byte(content), 0644); writeErr != nil {
-				log.Printf("[PLUGINS] Warning: failed to write PulumiPlugin.yaml for %s v%s: %v", p.Name, p.Version, writeErr)
+				slog.Warn("Failed to write PulumiPlugin.yaml", "component", "plugins", "plugin", p.Name, "version", p.Version, "error", writeErr)
 			} else {
-				log.Printf("[PLUGINS] PulumiPlugin.yaml written for %s v%s", p.Name, p.Version)
+				slog.Info("PulumiPlugin.yaml written", "component", "plugins", "plugin", p.Name, "version", p.Version)
 			}
 		}
 	}
 
-	log.Printf("[PLUGINS] Plugin check complete")
+	slog.Info("Plugin check complete", "component", "plugins")
 }
 
 // installPlugin runs `pulumi plugin install resource <name> <version>` with the
@@ -239,7 +254,7 @@ func (pe *PulumiExecutor) installPlugin(name, version, server, pulumiHome string
 
 	output, err := cmd.CombinedOutput()
 	if len(output) > 0 {
-		log.Printf("[PLUGINS] Install output for %s v%s: %s", name, version, strings.TrimSpace(string(output)))
+		slog.Debug("Plugin install output", "component", "plugins", "plugin", name, "version", version, "output", strings.TrimSpace(string(output)))
 	}
 	if err != nil {
 		return fmt.Errorf("pulumi plugin install resource %s %s: %w\nOutput: %s", name, version, err, string(output))
@@ -313,11 +328,7 @@ func getPulumiEnvVars(config *LabConfig, workDir ...string) map[string]string {
 		// Add provider-specific environment variables
 		switch provider {
 		case "ovh":
-			envVars["OVH_APPLICATION_KEY"] = config.OvhApplicationKey
-			envVars["OVH_APPLICATION_SECRET"] = config.OvhApplicationSecret
-			envVars["OVH_CONSUMER_KEY"] = config.OvhConsumerKey
-			envVars["OVH_SERVICE_NAME"] = config.OvhServiceName
-			// OVH_ENDPOINT is set via Pulumi config, not env var
+			maps.Copy(envVars, ovhEnvVars(config))
 		case "azure":
 			envVars["AZURE_CLIENT_ID"] = config.AzureClientID
 			envVars["AZURE_CLIENT_SECRET"] = config.AzureClientSecret
@@ -329,6 +340,17 @@ func getPulumiEnvVars(config *LabConfig, workDir ...string) map[string]string {
 	return envVars
 }
 
+// ovhEnvVars maps a lab's OVH credentials to the environment variables the OVH
+// provider reads. OVH_ENDPOINT is set via Pulumi config, not env var.
+func ovhEnvVars(config *LabConfig) map[string]string {
+	return map[string]string{
+		"OVH_APPLICATION_KEY":    config.OvhApplicationKey,
+		"OVH_APPLICATION_SECRET": config.OvhApplicationSecret,
+		"OVH_CONSUMER_KEY":       config.OvhConsumerKey,
+		"OVH_SERVICE_NAME":       config.OvhServiceName,
+	}
+}
+
 // getOrCreateStackInline gets or creates a Pulumi stack using inline program (pre-compiled)
 // This avoids the Go compilation step on each job, providing significant performance improvements
 func (pe *PulumiExecutor) getOrCreateStackInline(ctx context.Context, stackName, workDir, jobID string, config *LabConfig) (auto.Stack, error) {
@@ -367,6 +389,36 @@ func (pe *PulumiExecutor) getOrCreateStackInline(ctx context.Context, stackName,
 	return stack, nil
 }
 
+// selectLabStack selects the stack of a deployed lab and updates its config,
+// for the operations that change one part of a lab in place. The stack must
+// already exist: selecting, not creating, it keeps a lab whose state was lost
+// from getting lone resources.
+func (pe *PulumiExecutor) selectLabStack(ctx context.Context, jobID string, config *LabConfig) (auto.Stack, error) {
+	jobDir := filepath.Join(pe.workDir, jobID)
+	if _, err := os.Stat(jobDir); err != nil {
+		return auto.Stack{}, fmt.Errorf("the lab's Pulumi state was not found: %w", err)
+	}
+	stack, err := auto.SelectStackInlineSource(ctx, config.StackName, "easylab", internalPulumi.CreateLabProgram(jobDir),
+		auto.WorkDir(jobDir),
+		auto.EnvVars(getPulumiEnvVars(config, jobDir)))
+	if err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to select stack %s: %w", config.StackName, err)
+	}
+	if err := pe.setStackConfig(ctx, stack, config); err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to update the stack config: %w", err)
+	}
+	return stack, nil
+}
+
+// labResourceURN is the URN of a resource the lab program declares at the root
+// of stackName, for operations targeted at it.
+func labResourceURN(stackName, resourceType, name string) string {
+	if i := strings.LastIndex(stackName, "/"); i >= 0 {
+		stackName = stackName[i+1:]
+	}
+	return fmt.Sprintf("urn:pulumi:%s::easylab::%s::%s", stackName, resourceType, name)
+}
+
 // outputValueToString converts a Pulumi output value to string
 func (pe *PulumiExecutor) outputValueToString(val interface{}) string {
 	if val == nil {
@@ -507,7 +559,7 @@ func (pe *PulumiExecutor) prepareJob(jobID string, allowMissingDir bool) (*JobPr
 	config := job.Config
 
 	// Create context with timeout to prevent indefinite hangs
-	ctx, cancel := context.WithTimeout(context.Background(), pulumiExecutionTimeout)
+	ctx, cancel := context.WithTimeout(pe.runContext(jobID), pulumiExecutionTimeout)
 
 	// Update status to running
 	pe.jobManager.UpdateJobStatus(jobID, JobStatusRunning)
@@ -572,6 +624,7 @@ func (pe *PulumiExecutor) prepareJob(jobID string, allowMissingDir bool) (*JobPr
 	outputWriter := &jobOutputWriter{
 		jobID:      jobID,
 		jobManager: pe.jobManager,
+		verbosity:  config.Verbosity,
 	}
 
 	return &JobPreparation{
@@ -627,7 +680,7 @@ func (pe *PulumiExecutor) prepareJobForRetry(jobID string) (*JobPreparation, err
 	}
 
 	// Create context with timeout to prevent indefinite hangs
-	ctx, cancel := context.WithTimeout(context.Background(), pulumiExecutionTimeout)
+	ctx, cancel := context.WithTimeout(pe.runContext(jobID), pulumiExecutionTimeout)
 
 	// Update status to running
 	pe.jobManager.UpdateJobStatus(jobID, JobStatusRunning)
@@ -673,6 +726,7 @@ func (pe *PulumiExecutor) prepareJobForRetry(jobID string) (*JobPreparation, err
 	outputWriter := &jobOutputWriter{
 		jobID:      jobID,
 		jobManager: pe.jobManager,
+		verbosity:  config.Verbosity,
 	}
 
 	return &JobPreparation{
@@ -704,7 +758,7 @@ func (pe *PulumiExecutor) prepareDestroyJob(jobID string) (*JobPreparation, erro
 	}
 
 	// Create context with timeout to prevent indefinite hangs
-	ctx, cancel := context.WithTimeout(context.Background(), pulumiExecutionTimeout)
+	ctx, cancel := context.WithTimeout(pe.runContext(jobID), pulumiExecutionTimeout)
 
 	// Update status to running
 	pe.jobManager.UpdateJobStatus(jobID, JobStatusRunning)
@@ -863,6 +917,12 @@ func (pe *PulumiExecutor) extractKubeconfigFromOutputs(jobID string, outputs aut
 	// Verify that Kubernetes cluster was created before extracting kubeconfig
 	if _, ok := outputs["kubeClusterId"]; ok {
 		pe.jobManager.AppendOutput(jobID, "Kubernetes cluster found, extracting kubeconfig...")
+		if versionVal, ok := outputs["kubeVersion"]; ok {
+			if version := pe.outputValueToString(versionVal); version != "" {
+				pe.jobManager.SetKubeVersion(jobID, version)
+				pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Kubernetes version: %s", version))
+			}
+		}
 		// Get kubeconfig from outputs
 		if kubeconfigVal, ok := outputs["kubeconfig"]; ok {
 			kubeconfig := pe.outputValueToString(kubeconfigVal)
@@ -888,6 +948,66 @@ func (pe *PulumiExecutor) extractKubeconfigFromOutputs(jobID string, outputs aut
 	}
 }
 
+// reportIngressAddress tells the admin where the lab's workspaces are served and,
+// when EasyLab does not manage the DNS zone, which records they must create for
+// the domain to resolve and its certificates to be issued.
+func (pe *PulumiExecutor) reportIngressAddress(jobID string, outputs auto.OutputMap) {
+	job, exists := pe.jobManager.GetJob(jobID)
+	if !exists {
+		return
+	}
+	job.mu.RLock()
+	var domain, dnsProvider string
+	if job.Config != nil {
+		domain, dnsProvider = job.Config.Domain, job.Config.DNSProvider
+	}
+	job.mu.RUnlock()
+
+	ipVal, ok := outputs["ingressIP"]
+	if !ok {
+		return
+	}
+	ip := pe.outputValueToString(ipVal.Value)
+	if ip == "" {
+		pe.jobManager.AppendOutput(jobID, "Warning: the ingress controller has no LoadBalancer IP yet")
+		return
+	}
+	if host, port, err := net.SplitHostPort(ip); err == nil {
+		// Only a domainless lab with the NodePort fallback gets a port here.
+		slog.Warn("Ingress got no LoadBalancer IP, serving on a NodePort", "job_id", jobID, "address", ip)
+		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Warning: the ingress controller got no LoadBalancer IP in time, falling back to NodePort %s on node %s", port, host))
+		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("No domain set: workspaces are served over HTTP at {workspace}.%s.nip.io:%s", host, port))
+		return
+	}
+	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Ingress IP: %s", ip))
+	switch {
+	case domain == "":
+		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("No domain set: workspaces are served over HTTP at {workspace}.%s.nip.io", ip))
+	case dnsProvider == "":
+		pe.jobManager.AppendOutput(jobID, "ACTION REQUIRED: create these DNS A records, then workspaces get their HTTPS certificates:")
+		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("  %s -> %s", domain, ip))
+		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("  *.%s -> %s", domain, ip))
+	default:
+		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("DNS records %s and *.%s point to %s (managed through %s)", domain, domain, ip, dnsProvider))
+	}
+}
+
+// reportGrafanaURL records where the lab's Grafana is served, for labs that
+// install the monitoring stack. The password stays out of the output; the lab
+// page shows it.
+func (pe *PulumiExecutor) reportGrafanaURL(jobID string, outputs auto.OutputMap) {
+	urlVal, ok := outputs["grafanaURL"]
+	if !ok {
+		return
+	}
+	grafanaURL := pe.outputValueToString(urlVal.Value)
+	if grafanaURL == "" {
+		return
+	}
+	pe.jobManager.SetGrafanaURL(jobID, grafanaURL)
+	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Grafana: %s (user admin, password on the lab page)", grafanaURL))
+}
+
 // checkLocalKubeconfigFile checks for kubeconfig in the job directory (external-kubeconfig.yaml or kubeconfig.yaml)
 func (pe *PulumiExecutor) checkLocalKubeconfigFile(jobID string) {
 	jobDir := filepath.Join(pe.workDir, jobID)
@@ -926,7 +1046,33 @@ func (pe *PulumiExecutor) checkLocalKubeconfigFile(jobID string) {
 }
 
 // Execute runs pulumi up for a given job
+//
+// A run that fails on a transient OVH capacity or quota error is run again
+// after a delay, up to the executor's deployRetry policy, in the same goroutine.
+// The job stays running in between.
 func (pe *PulumiExecutor) Execute(jobID string) error {
+	defer metrics.trackPulumi("up")()
+	ctx, end := pe.beginRun(jobID, "up", true)
+	defer end()
+	for attempt := 1; ; attempt++ {
+		err := pe.executeUp(jobID, attempt)
+		var retry *deployRetryError
+		if !errors.As(err, &retry) {
+			return err
+		}
+		slog.Warn("Deployment failed on a transient OVH error, retrying", "job_id", jobID, "attempt", attempt, "max_attempts", pe.deployRetry.Retries+1, "retry_in", pe.deployRetry.Delay, "error", retry.err)
+		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("⚠️ pulumi up failed on a transient OVH error (attempt %d/%d). Running it again in %s.",
+			attempt, pe.deployRetry.Retries+1, pe.deployRetry.Delay))
+		select {
+		case <-time.After(pe.deployRetry.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// executeUp is one run of Execute. attempt counts the runs, 1 for the first.
+func (pe *PulumiExecutor) executeUp(jobID string, attempt int) error {
 	// Prepare job with common setup
 	prep, err := pe.prepareJob(jobID, false) // false = always create directory
 	if err != nil {
@@ -940,9 +1086,19 @@ func (pe *PulumiExecutor) Execute(jobID string) error {
 	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Job started at %s", time.Now().Format(time.RFC3339)))
 
 	// Run pulumi up with streaming output
+	pe.reportChartVersions(jobID)
 	pe.jobManager.AppendOutput(jobID, "Running pulumi up...")
-	upResult, err := prep.Stack.Up(prep.Context, optup.ProgressStreams(prep.Writer))
+	upResult, err := prep.Stack.Up(prep.Context, upOptions(prep.Writer.verbosity, prep.Writer)...)
 	if err != nil {
+		if pe.deployRetry.shouldRetry(err, attempt) {
+			return &deployRetryError{err: err}
+		}
+		if pe.failOnRejectedCredentials(jobID, err) {
+			return err
+		}
+		if pe.rollBackFailedDeploy(prep, jobID, err) {
+			return err
+		}
 		pe.jobManager.SetError(jobID, fmt.Errorf("pulumi up failed: %w", err))
 
 		// Even if pulumi up failed, try to extract kubeconfig if cluster was created
@@ -965,7 +1121,7 @@ func (pe *PulumiExecutor) Execute(jobID string) error {
 
 		// Persist failed job to disk
 		if saveErr := pe.jobManager.SaveJob(jobID); saveErr != nil {
-			log.Printf("Warning: failed to persist failed job %s: %v", jobID, saveErr)
+			slog.Warn("Failed to persist failed job", "job_id", jobID, "error", saveErr)
 		}
 		return err
 	}
@@ -975,10 +1131,13 @@ func (pe *PulumiExecutor) Execute(jobID string) error {
 	// Extract outputs from the result
 	pe.jobManager.AppendOutput(jobID, "Extracting stack outputs...")
 	pe.extractKubeconfigFromOutputs(jobID, upResult.Outputs)
+	pe.reportIngressAddress(jobID, upResult.Outputs)
+	pe.reportGrafanaURL(jobID, upResult.Outputs)
+	pe.reportClusterInfo(jobID, upResult.Outputs)
 
-	// Write any credentials the admin supplied in the wizard now that the cluster
-	// exists, before the lab is reported ready — so a completed lab has the
-	// credentials its templates reference.
+	// Apply the admin's post-deploy manifests and wizard credentials now that the
+	// cluster exists, before the lab is reported ready — so a completed lab has
+	// the objects and credentials its templates rely on.
 	if pe.afterProvision != nil {
 		pe.afterProvision(jobID)
 	}
@@ -989,12 +1148,12 @@ func (pe *PulumiExecutor) Execute(jobID string) error {
 
 	// Clean up source files but preserve .pulumi directory for file backend
 	if err := pe.cleanupJobDirectory(jobID, true); err != nil {
-		log.Printf("Warning: failed to cleanup job directory for %s: %v", jobID, err)
+		slog.Warn("Failed to cleanup job directory", "job_id", jobID, "error", err)
 	}
 
 	// Persist completed job to disk
 	if err := pe.jobManager.SaveJob(jobID); err != nil {
-		log.Printf("Warning: failed to persist job %s: %v", jobID, err)
+		slog.Warn("Failed to persist job", "job_id", jobID, "error", err)
 	}
 
 	return nil
@@ -1002,6 +1161,9 @@ func (pe *PulumiExecutor) Execute(jobID string) error {
 
 // ExecuteRetry runs pulumi up for a retried job, reusing existing configuration and files
 func (pe *PulumiExecutor) ExecuteRetry(jobID string) error {
+	defer metrics.trackPulumi("up")()
+	_, end := pe.beginRun(jobID, "up", true)
+	defer end()
 	// Prepare job with retry-optimized setup
 	prep, err := pe.prepareJobForRetry(jobID)
 	if err != nil {
@@ -1015,9 +1177,16 @@ func (pe *PulumiExecutor) ExecuteRetry(jobID string) error {
 	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Retry started at %s", time.Now().Format(time.RFC3339)))
 
 	// Run pulumi up with streaming output
+	pe.reportChartVersions(jobID)
 	pe.jobManager.AppendOutput(jobID, "Running pulumi up...")
-	upResult, err := prep.Stack.Up(prep.Context, optup.ProgressStreams(prep.Writer))
+	upResult, err := prep.Stack.Up(prep.Context, upOptions(prep.Writer.verbosity, prep.Writer)...)
 	if err != nil {
+		if pe.failOnRejectedCredentials(jobID, err) {
+			return err
+		}
+		if pe.rollBackFailedDeploy(prep, jobID, err) {
+			return err
+		}
 		pe.jobManager.SetError(jobID, fmt.Errorf("pulumi up failed: %w", err))
 
 		// Even if pulumi up failed, try to extract kubeconfig if cluster was created
@@ -1040,7 +1209,7 @@ func (pe *PulumiExecutor) ExecuteRetry(jobID string) error {
 
 		// Persist failed job to disk
 		if saveErr := pe.jobManager.SaveJob(jobID); saveErr != nil {
-			log.Printf("Warning: failed to persist failed job %s: %v", jobID, saveErr)
+			slog.Warn("Failed to persist failed job", "job_id", jobID, "error", saveErr)
 			// Don't fail the job if persistence fails
 		}
 		return err
@@ -1051,10 +1220,13 @@ func (pe *PulumiExecutor) ExecuteRetry(jobID string) error {
 	// Extract outputs from the result
 	pe.jobManager.AppendOutput(jobID, "Extracting stack outputs...")
 	pe.extractKubeconfigFromOutputs(jobID, upResult.Outputs)
+	pe.reportIngressAddress(jobID, upResult.Outputs)
+	pe.reportGrafanaURL(jobID, upResult.Outputs)
+	pe.reportClusterInfo(jobID, upResult.Outputs)
 
-	// Write any credentials the admin supplied in the wizard now that the cluster
-	// exists, before the lab is reported ready — so a completed lab has the
-	// credentials its templates reference.
+	// Apply the admin's post-deploy manifests and wizard credentials now that the
+	// cluster exists, before the lab is reported ready — so a completed lab has
+	// the objects and credentials its templates rely on.
 	if pe.afterProvision != nil {
 		pe.afterProvision(jobID)
 	}
@@ -1065,19 +1237,59 @@ func (pe *PulumiExecutor) ExecuteRetry(jobID string) error {
 
 	// Clean up source files but preserve .pulumi directory for file backend
 	if err := pe.cleanupJobDirectory(jobID, true); err != nil {
-		log.Printf("Warning: failed to cleanup job directory for %s: %v", jobID, err)
+		slog.Warn("Failed to cleanup job directory", "job_id", jobID, "error", err)
 	}
 
 	// Persist completed job to disk
 	if err := pe.jobManager.SaveJob(jobID); err != nil {
-		log.Printf("Warning: failed to persist job %s: %v", jobID, err)
+		slog.Warn("Failed to persist job", "job_id", jobID, "error", err)
 	}
 
 	return nil
 }
 
+// nodePoolOptionsSummary describes the optional OVH node pool options for the
+// dry-run output, or returns "" when the lab does not create an OVH node pool.
+func nodePoolOptionsSummary(config *LabConfig) string {
+	if config == nil || config.UseExistingCluster || config.Provider == "azure" {
+		return ""
+	}
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+	return fmt.Sprintf("Node pool options: autoscale %s, monthly billing %s, anti-affinity %s",
+		onOff(config.NodePoolAutoscale), onOff(config.NodePoolMonthlyBilled), onOff(config.NodePoolAntiAffinity))
+}
+
+// subnetSummary describes the subnet the lab gets, with OVH's defaults spelled
+// out, for the dry-run output. It returns "" for labs that create no network.
+func subnetSummary(config *LabConfig) string {
+	if config == nil || config.UseExistingCluster || config.Provider == "azure" {
+		return ""
+	}
+	dhcp := "OVH default"
+	if config.NetworkDHCP != nil {
+		dhcp = "off"
+		if *config.NetworkDHCP {
+			dhcp = "on"
+		}
+	}
+	gateway := config.NetworkGatewayIP
+	if gateway == "" {
+		gateway = "first address of the network (OVH default)"
+	}
+	return fmt.Sprintf("Subnet: %s, DHCP range %s-%s, DHCP %s, gateway IP %s",
+		config.NetworkMask, config.NetworkStartIP, config.NetworkEndIP, dhcp, gateway)
+}
+
 // Preview runs pulumi preview for a given job (dry run)
 func (pe *PulumiExecutor) Preview(jobID string) error {
+	defer metrics.trackPulumi("preview")()
+	_, end := pe.beginRun(jobID, "preview", true)
+	defer end()
 	// Prepare job with common setup
 	prep, err := pe.prepareJob(jobID, false) // false = always create directory
 	if err != nil {
@@ -1089,15 +1301,31 @@ func (pe *PulumiExecutor) Preview(jobID string) error {
 
 	// Add preview-specific output
 	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Dry run started at %s", time.Now().Format(time.RFC3339)))
+	if job, ok := pe.jobManager.GetJob(jobID); ok {
+		job.mu.RLock()
+		summaries := []string{subnetSummary(job.Config), nodePoolOptionsSummary(job.Config), nodePoolTopologySummary(job.Config), workspaceQuotaSummary(job.Config), helmRepoOverridesSummary(job.Config), helmChartVersionsSummary(job.Config), quotaReportSummary(job.QuotaReport)}
+		job.mu.RUnlock()
+		for _, summary := range summaries {
+			if summary != "" {
+				pe.jobManager.AppendOutput(jobID, summary)
+			}
+		}
+	}
 
 	// Run pulumi preview with streaming output
 	pe.jobManager.AppendOutput(jobID, "Running pulumi preview (dry run)...")
-	_, err = prep.Stack.Preview(prep.Context, optpreview.ProgressStreams(prep.Writer))
+	err = pe.runCancellablePreview(prep.Context, jobID, func(ctx context.Context) error {
+		_, err := prep.Stack.Preview(ctx, previewOptions(prep.Writer.verbosity, prep.Writer)...)
+		return err
+	})
+	if errors.Is(err, errPreviewCancelled) {
+		return err
+	}
 	if err != nil {
 		pe.jobManager.SetError(jobID, fmt.Errorf("pulumi preview failed: %w", err))
 		// Persist failed job to disk
 		if saveErr := pe.jobManager.SaveJob(jobID); saveErr != nil {
-			log.Printf("Warning: failed to persist failed job %s: %v", jobID, saveErr)
+			slog.Warn("Failed to persist failed job", "job_id", jobID, "error", saveErr)
 			// Don't fail the job if persistence fails
 		}
 		return err
@@ -1106,6 +1334,15 @@ func (pe *PulumiExecutor) Preview(jobID string) error {
 	// Success - mark as dry-run-completed
 	pe.jobManager.UpdateJobStatus(jobID, JobStatusDryRunCompleted)
 	pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Dry run completed successfully at %s", time.Now().Format(time.RFC3339)))
+	if job, ok := pe.jobManager.GetJob(jobID); ok {
+		job.mu.RLock()
+		fits := job.QuotaReport.Fits()
+		job.mu.RUnlock()
+		if !fits {
+			pe.jobManager.AppendOutput(jobID, "⚠️ Dry run passed, but the lab does not fit in the project's quota: raise it, or launch anyway.")
+			return nil
+		}
+	}
 	pe.jobManager.AppendOutput(jobID, "✅ Dry run passed! You can now launch the real deployment.")
 
 	return nil
@@ -1113,6 +1350,9 @@ func (pe *PulumiExecutor) Preview(jobID string) error {
 
 // Destroy runs pulumi destroy and removes the stack for a given job
 func (pe *PulumiExecutor) Destroy(jobID string) error {
+	defer metrics.trackPulumi("destroy")()
+	_, end := pe.beginRun(jobID, "destroy", true)
+	defer end()
 	// Prepare job with destroy-specific setup
 	prep, err := pe.prepareDestroyJob(jobID)
 	if err != nil {
@@ -1135,19 +1375,37 @@ func (pe *PulumiExecutor) Destroy(jobID string) error {
 	job.mu.RUnlock()
 	jobDir := filepath.Join(pe.workDir, jobID)
 
-	// Run pulumi destroy with streaming output
+	// Run pulumi destroy with streaming output, recording what it deletes from
+	// the engine events.
 	pe.jobManager.AppendOutput(jobID, "Running pulumi destroy...")
-	destroyResult, err := prep.Stack.Destroy(prep.Context, optdestroy.ProgressStreams(prep.Writer))
+	engineEvents := make(chan events.EngineEvent)
+	deletedCh := make(chan []DestroyedResource, 1)
+	go func() { deletedCh <- collectDeletedResources(engineEvents) }()
+	_, err = prep.Stack.Destroy(prep.Context, optdestroy.ProgressStreams(prep.Writer), optdestroy.EventStreams(engineEvents))
+	report := &DestroyReport{}
+	// The engine closes the event channel once the destroy is over, but never
+	// opens it when the destroy fails to start.
+	select {
+	case report.Deleted = <-deletedCh:
+	case <-time.After(destroyEventsGrace):
+	}
 	if err != nil {
 		// Destroy failed - don't continue with stack removal or mark as destroyed
 		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("ERROR: pulumi destroy failed: %v", err))
+		if deployment, exportErr := prep.Stack.Export(prep.Context); exportErr != nil {
+			pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Warning: could not list the resources left in the stack: %v", exportErr))
+		} else if report.Remaining, exportErr = remainingResources(deployment); exportErr != nil {
+			pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Warning: could not list the resources left in the stack: %v", exportErr))
+		}
+		pe.jobManager.SetDestroyReport(jobID, report)
+		pe.jobManager.AppendOutput(jobID, report.Summary())
 		pe.jobManager.AppendOutput(jobID, "Destroy operation failed. Resources may still exist in the cloud.")
 		pe.jobManager.AppendOutput(jobID, "Stack state is preserved. You can retry the destroy operation.")
 		pe.jobManager.SetError(jobID, fmt.Errorf("pulumi destroy failed: %w", err))
 
 		// Persist failed job to disk so it can be retried
 		if saveErr := pe.jobManager.SaveJob(jobID); saveErr != nil {
-			log.Printf("Warning: failed to persist failed destroy job %s: %v", jobID, saveErr)
+			slog.Warn("Failed to persist failed destroy job", "job_id", jobID, "error", saveErr)
 		}
 
 		return fmt.Errorf("destroy failed: %w", err)
@@ -1155,9 +1413,8 @@ func (pe *PulumiExecutor) Destroy(jobID string) error {
 
 	// Destroy succeeded - verify stack is empty before proceeding
 	pe.jobManager.AppendOutput(jobID, "Destroy completed successfully. Verifying stack state...")
-	if destroyResult.Summary.ResourceChanges != nil {
-		pe.jobManager.AppendOutput(jobID, fmt.Sprintf("Destroy summary: %+v", destroyResult.Summary))
-	}
+	pe.jobManager.SetDestroyReport(jobID, report)
+	pe.jobManager.AppendOutput(jobID, report.Summary())
 
 	// Get environment variables including OVH credentials (scoped to job directory)
 	envVars := getPulumiEnvVars(job.Config, jobDir)
@@ -1190,13 +1447,13 @@ func (pe *PulumiExecutor) Destroy(jobID string) error {
 
 	// Persist destroyed job to disk
 	if err := pe.jobManager.SaveJob(jobID); err != nil {
-		log.Printf("Warning: failed to persist destroyed job %s: %v", jobID, err)
+		slog.Warn("Failed to persist destroyed job", "job_id", jobID, "error", err)
 	}
 
 	// Clean up the job directory completely since all resources have been destroyed
 	// Stack state is no longer needed after destroy
 	if err := pe.cleanupJobDirectory(jobID, false); err != nil {
-		log.Printf("Warning: failed to cleanup job directory for %s: %v", jobID, err)
+		slog.Warn("Failed to cleanup job directory", "job_id", jobID, "error", err)
 		// Don't fail the job if cleanup fails
 	}
 
@@ -1324,6 +1581,82 @@ func (pe *PulumiExecutor) getConfigCommands(config *LabConfig) []configCommand {
 		if config.NetworkID != "" {
 			commands = append(commands, configCommand{"network:networkId", config.NetworkID, false})
 		}
+		if config.NetworkDHCP != nil {
+			commands = append(commands, configCommand{"network:networkDhcp", strconv.FormatBool(*config.NetworkDHCP), false})
+		}
+		if config.NetworkGatewayIP != "" {
+			commands = append(commands, configCommand{"network:networkGatewayIp", config.NetworkGatewayIP, false})
+		}
+
+		// Existing resources to adopt rather than recreate (see ovh.ImportIDs).
+		if config.ImportPrivateNetworkID != "" {
+			commands = append(commands, configCommand{"network:importPrivateNetworkId", config.ImportPrivateNetworkID, false})
+		}
+		if config.ImportSubnetID != "" {
+			commands = append(commands, configCommand{"network:importSubnetId", config.ImportSubnetID, false})
+		}
+		if config.ImportClusterID != "" {
+			commands = append(commands, configCommand{"k8s:importClusterId", config.ImportClusterID, false})
+		}
+		if config.K8sVersion != "" {
+			commands = append(commands, configCommand{"k8s:version", config.K8sVersion, false})
+		}
+		if config.K8sKubeProxyMode != "" {
+			commands = append(commands, configCommand{"k8s:kubeProxyMode", config.K8sKubeProxyMode, false})
+		}
+		if config.K8sIPVSScheduler != "" {
+			commands = append(commands, configCommand{"k8s:ipvsScheduler", config.K8sIPVSScheduler, false})
+		}
+		if config.K8sPrivateNetworkRouting {
+			commands = append(commands, configCommand{"k8s:privateNetworkRoutingAsDefault", "true", false})
+		}
+		if config.K8sPlan != "" {
+			commands = append(commands, configCommand{"k8s:plan", config.K8sPlan, false})
+		}
+
+		if config.NodePoolAutoscale {
+			commands = append(commands, configCommand{"nodepool:autoscale", "true", false})
+		}
+		if config.NodePoolMonthlyBilled {
+			commands = append(commands, configCommand{"nodepool:monthlyBilled", "true", false})
+		}
+		if config.NodePoolAntiAffinity {
+			commands = append(commands, configCommand{"nodepool:antiAffinity", "true", false})
+		}
+		if config.NodePoolGPU {
+			commands = append(commands, configCommand{"nodepool:gpu", "true", false})
+		}
+		if len(config.NodePoolLabels) > 0 {
+			labels, _ := json.Marshal(config.NodePoolLabels)
+			commands = append(commands, configCommand{"nodepool:labels", string(labels), false})
+		}
+		if len(config.NodePoolTaints) > 0 {
+			taints, _ := json.Marshal(config.NodePoolTaints)
+			commands = append(commands, configCommand{"nodepool:taints", string(taints), false})
+		}
+		if len(config.NodePoolZones) > 0 {
+			zones, _ := json.Marshal(config.NodePoolZones)
+			commands = append(commands, configCommand{"nodepool:zones", string(zones), false})
+		}
+		if config.NodePoolReadyTimeoutMinutes > 0 {
+			commands = append(commands, configCommand{"nodepool:readyTimeout", strconv.Itoa(config.NodePoolReadyTimeoutMinutes), false})
+		}
+	}
+
+	if config.StorageClass != "" {
+		commands = append(commands, configCommand{"k8s:storageClass", config.StorageClass, false})
+	}
+	if config.WorkspaceQuota != nil {
+		quota, _ := json.Marshal(config.WorkspaceQuota)
+		commands = append(commands, configCommand{"coder:workspaceQuota", string(quota), false})
+	}
+	if len(config.HelmRepoOverrides) > 0 {
+		overrides, _ := json.Marshal(config.HelmRepoOverrides)
+		commands = append(commands, configCommand{"helm:repoOverrides", string(overrides), false})
+	}
+	if len(config.HelmChartVersions) > 0 {
+		versions, _ := json.Marshal(config.HelmChartVersions)
+		commands = append(commands, configCommand{"helm:chartVersions", string(versions), false})
 	}
 
 	// Ingress controller configuration. This applies with or without a domain:
@@ -1338,6 +1671,20 @@ func (pe *PulumiExecutor) getConfigCommands(config *LabConfig) []configCommand {
 		if config.NginxIngressServiceName != "" {
 			commands = append(commands, configCommand{"coder:nginxIngressServiceName", config.NginxIngressServiceName, false})
 		}
+	} else {
+		if len(config.LoadBalancerAnnotations) > 0 {
+			annotations, _ := json.Marshal(config.LoadBalancerAnnotations)
+			commands = append(commands, configCommand{"coder:loadBalancerAnnotations", string(annotations), false})
+		}
+		if config.LoadBalancerIP != "" {
+			commands = append(commands, configCommand{"coder:loadBalancerIP", config.LoadBalancerIP, false})
+		}
+		if config.IngressIPTimeoutMinutes > 0 {
+			commands = append(commands, configCommand{"coder:ingressIPTimeout", strconv.Itoa(config.IngressIPTimeoutMinutes), false})
+		}
+		if config.NodePortFallback {
+			commands = append(commands, configCommand{"coder:nodePortFallback", "true", false})
+		}
 	}
 
 	// HTTPS / TLS configuration — only meaningful when a domain is set.
@@ -1357,6 +1704,13 @@ func (pe *PulumiExecutor) getConfigCommands(config *LabConfig) []configCommand {
 		}
 	}
 
+	if config.InstallMonitoring {
+		commands = append(commands,
+			configCommand{"monitoring:enabled", "true", false},
+			configCommand{"monitoring:grafanaAdminPassword", config.GrafanaAdminPassword, true},
+		)
+	}
+
 	// DNS provider configuration (for A-record automation and DNS-01 cert issuance)
 	if config.DNSProvider != "" {
 		commands = append(commands,
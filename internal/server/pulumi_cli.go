@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinPulumiCLIVersion is the oldest Pulumi CLI the server accepts. It is the
+// floor the Automation API enforces itself; checking it at startup reports an
+// outdated CLI before the first lab instead of as an error deep inside that lab.
+const MinPulumiCLIVersion = "3.2.0"
+
+// pulumiInstallHint tells the admin how to fix a missing or outdated CLI.
+const pulumiInstallHint = "install the Pulumi CLI (https://www.pulumi.com/docs/install/, " +
+	"e.g. `curl -fsSL https://get.pulumi.com | sh`) and make sure `pulumi` is on the server's PATH"
+
+// PulumiCLIInfo describes the Pulumi CLI found at startup.
+type PulumiCLIInfo struct {
+	Path    string
+	Version string // without the leading "v", e.g. "3.243.0"
+}
+
+// DetectPulumiCLI locates the pulumi binary on PATH and checks that its version is
+// at least MinPulumiCLIVersion. Without this, a missing CLI only surfaces as a
+// confusing Automation API error when the first lab is deployed.
+func DetectPulumiCLI(ctx context.Context) (PulumiCLIInfo, error) {
+	path, err := exec.LookPath("pulumi")
+	if err != nil {
+		return PulumiCLIInfo{}, fmt.Errorf("pulumi CLI not found on PATH: %s", pulumiInstallHint)
+	}
+	info := PulumiCLIInfo{Path: path}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, "version")
+	cmd.Env = append(os.Environ(), "PULUMI_SKIP_UPDATE_CHECK=true")
+	out, err := cmd.Output()
+	if err != nil {
+		return info, fmt.Errorf("failed to run %s version: %w", path, err)
+	}
+
+	version, err := parsePulumiVersion(string(out))
+	if err != nil {
+		return info, err
+	}
+	info.Version = formatVersion(version)
+
+	minVersion, _ := parsePulumiVersion(MinPulumiCLIVersion)
+	if compareVersions(version, minVersion) < 0 {
+		return info, fmt.Errorf("pulumi CLI %s at %s is older than the required %s: %s",
+			info.Version, path, MinPulumiCLIVersion, pulumiInstallHint)
+	}
+	return info, nil
+}
+
+// parsePulumiVersion parses `pulumi version` output such as "v3.243.0",
+// "3.243.0" or "v3.100.0-alpha.1+dirty" into major, minor and patch. Pre-release
+// and build suffixes are ignored.
+func parsePulumiVersion(s string) ([3]int, error) {
+	var v [3]int
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(raw, "-+ \n"); i >= 0 {
+		raw = raw[:i]
+	}
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("unrecognized pulumi version %q", strings.TrimSpace(s))
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("unrecognized pulumi version %q", strings.TrimSpace(s))
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// compareVersions returns -1, 0 or 1 as a is older than, equal to or newer than b.
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func formatVersion(v [3]int) string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
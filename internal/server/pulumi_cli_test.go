@@ -0,0 +1,64 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePulumiVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    [3]int
+		wantErr bool
+	}{
+		{input: "v3.243.0\n", want: [3]int{3, 243, 0}},
+		{input: "3.2.0", want: [3]int{3, 2, 0}},
+		{input: "v3.100.0-alpha.1+dirty", want: [3]int{3, 100, 0}},
+		{input: "", wantErr: true},
+		{input: "v3.243", wantErr: true},
+		{input: "warning: something\n", wantErr: true},
+		{input: "v3.x.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			got, err := parsePulumiVersion(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b [3]int
+		want int
+	}{
+		{a: [3]int{3, 243, 0}, b: [3]int{3, 243, 0}, want: 0},
+		{a: [3]int{3, 2, 0}, b: [3]int{3, 10, 0}, want: -1},
+		{a: [3]int{3, 10, 0}, b: [3]int{3, 2, 0}, want: 1},
+		{a: [3]int{2, 99, 99}, b: [3]int{3, 0, 0}, want: -1},
+		{a: [3]int{3, 2, 1}, b: [3]int{3, 2, 0}, want: 1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, compareVersions(tt.a, tt.b), "compareVersions(%v, %v)", tt.a, tt.b)
+	}
+}
+
+func TestMinPulumiCLIVersionParses(t *testing.T) {
+	t.Parallel()
+	_, err := parsePulumiVersion(MinPulumiCLIVersion)
+	require.NoError(t, err)
+}
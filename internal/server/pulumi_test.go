@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"easylab/utils"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
@@ -353,6 +358,36 @@ func TestGetPulumiEnvVars_OVHProvider(t *testing.T) {
 	}
 }
 
+func TestOVHEnvVars_FromCredentials(t *testing.T) {
+	cfg := &LabConfig{Provider: "ovh"}
+	cfg.setOVHCredentials(&OVHCredentials{
+		ApplicationKey:    "app-key",
+		ApplicationSecret: "app-secret",
+		ConsumerKey:       "consumer-key",
+		ServiceName:       "service",
+		Endpoint:          "ovh-ca",
+	})
+	if cfg.OvhEndpoint != "ovh-ca" {
+		t.Errorf("OvhEndpoint = %q, want ovh-ca", cfg.OvhEndpoint)
+	}
+
+	want := map[string]string{
+		"OVH_APPLICATION_KEY":    "app-key",
+		"OVH_APPLICATION_SECRET": "app-secret",
+		"OVH_CONSUMER_KEY":       "consumer-key",
+		"OVH_SERVICE_NAME":       "service",
+	}
+	if got := ovhEnvVars(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("ovhEnvVars() = %v, want exactly %v", got, want)
+	}
+	vars := getPulumiEnvVars(cfg)
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("getPulumiEnvVars()[%s] = %q, want %q", k, vars[k], v)
+		}
+	}
+}
+
 func TestGetPulumiEnvVars_AzureProvider(t *testing.T) {
 	cfg := &LabConfig{
 		Provider:            "azure",
@@ -419,6 +454,394 @@ func TestGetConfigCommands_OVH(t *testing.T) {
 	}
 }
 
+func TestGetConfigCommands_OVHImport(t *testing.T) {
+	pe := &PulumiExecutor{}
+
+	// Without import IDs nothing is adopted.
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		if strings.Contains(c.key, "import") {
+			t.Errorf("getConfigCommands() without import IDs emitted %s", c.key)
+		}
+	}
+
+	cfg := &LabConfig{
+		Provider:               "ovh",
+		StackName:              "my-stack",
+		ImportPrivateNetworkID: "pn-123456_0",
+		ImportSubnetID:         "subnet-1",
+		ImportClusterID:        "kube-1",
+	}
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(cfg) {
+		got[c.key] = c.value
+	}
+	want := map[string]string{
+		"network:importPrivateNetworkId": "pn-123456_0",
+		"network:importSubnetId":         "subnet-1",
+		"k8s:importClusterId":            "kube-1",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("getConfigCommands() %s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	// BYOK never provisions OVH resources, so there is nothing to import.
+	cfg.UseExistingCluster = true
+	for _, c := range pe.getConfigCommands(cfg) {
+		if strings.Contains(c.key, "import") {
+			t.Errorf("getConfigCommands() in BYOK mode emitted %s", c.key)
+		}
+	}
+}
+
+func TestGetConfigCommands_OVHNodePoolOptions(t *testing.T) {
+	pe := &PulumiExecutor{}
+
+	// Options are only emitted when enabled.
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		switch c.key {
+		case "nodepool:autoscale", "nodepool:monthlyBilled", "nodepool:antiAffinity", "nodepool:gpu", "nodepool:readyTimeout":
+			t.Errorf("getConfigCommands() without options emitted %s", c.key)
+		}
+	}
+
+	cfg := &LabConfig{
+		Provider:                    "ovh",
+		StackName:                   "my-stack",
+		NodePoolAutoscale:           true,
+		NodePoolMonthlyBilled:       true,
+		NodePoolAntiAffinity:        true,
+		NodePoolGPU:                 true,
+		NodePoolReadyTimeoutMinutes: 45,
+	}
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(cfg) {
+		got[c.key] = c.value
+	}
+	for _, k := range []string{"nodepool:autoscale", "nodepool:monthlyBilled", "nodepool:antiAffinity", "nodepool:gpu"} {
+		if got[k] != "true" {
+			t.Errorf("getConfigCommands() %s = %q, want true", k, got[k])
+		}
+	}
+	if got["nodepool:readyTimeout"] != "45" {
+		t.Errorf("getConfigCommands() nodepool:readyTimeout = %q, want 45", got["nodepool:readyTimeout"])
+	}
+}
+
+func TestGetConfigCommands_OVHNodePoolScheduling(t *testing.T) {
+	pe := &PulumiExecutor{}
+	cfg := &LabConfig{
+		Provider:       "ovh",
+		StackName:      "my-stack",
+		NodePoolLabels: map[string]string{"pool": "workspaces"},
+		NodePoolTaints: []utils.NodeTaint{{Key: "dedicated", Value: "workspaces", Effect: "NoSchedule"}},
+	}
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(cfg) {
+		got[c.key] = c.value
+	}
+	if want := `{"pool":"workspaces"}`; got["nodepool:labels"] != want {
+		t.Errorf("getConfigCommands() nodepool:labels = %q, want %q", got["nodepool:labels"], want)
+	}
+	if want := `[{"key":"dedicated","value":"workspaces","effect":"NoSchedule"}]`; got["nodepool:taints"] != want {
+		t.Errorf("getConfigCommands() nodepool:taints = %q, want %q", got["nodepool:taints"], want)
+	}
+}
+
+func TestGetConfigCommands_OVHNodePoolZones(t *testing.T) {
+	pe := &PulumiExecutor{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		if c.key == "nodepool:zones" {
+			t.Errorf("getConfigCommands() without zones emitted nodepool:zones = %q", c.value)
+		}
+	}
+
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", NodePoolZones: []string{"eu-west-par-a", "eu-west-par-b"}}) {
+		got[c.key] = c.value
+	}
+	if want := `["eu-west-par-a","eu-west-par-b"]`; got["nodepool:zones"] != want {
+		t.Errorf("getConfigCommands() nodepool:zones = %q, want %q", got["nodepool:zones"], want)
+	}
+}
+
+func TestGetConfigCommands_K8sVersion(t *testing.T) {
+	pe := &PulumiExecutor{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		if c.key == "k8s:version" {
+			t.Errorf("getConfigCommands() without a version emitted k8s:version = %q", c.value)
+		}
+	}
+
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", K8sVersion: "1.31"}) {
+		got[c.key] = c.value
+	}
+	if got["k8s:version"] != "1.31" {
+		t.Errorf("getConfigCommands() k8s:version = %q, want 1.31", got["k8s:version"])
+	}
+}
+
+func TestGetConfigCommands_ClusterOptions(t *testing.T) {
+	pe := &PulumiExecutor{}
+	// A lab without options must keep the cluster OVH always gave it.
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		switch c.key {
+		case "k8s:kubeProxyMode", "k8s:ipvsScheduler", "k8s:privateNetworkRoutingAsDefault", "k8s:plan":
+			t.Errorf("getConfigCommands() without cluster options emitted %s = %q", c.key, c.value)
+		}
+	}
+
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack",
+		K8sKubeProxyMode: "ipvs", K8sIPVSScheduler: "lc", K8sPrivateNetworkRouting: true, K8sPlan: "standard"}) {
+		got[c.key] = c.value
+	}
+	want := map[string]string{"k8s:kubeProxyMode": "ipvs", "k8s:ipvsScheduler": "lc", "k8s:privateNetworkRoutingAsDefault": "true", "k8s:plan": "standard"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("getConfigCommands() %s = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+func TestGetConfigCommands_LoadBalancerAnnotations(t *testing.T) {
+	pe := &PulumiExecutor{}
+	annotations := map[string]string{"loadbalancer.ovhcloud.com/flavor": "medium"}
+
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", LoadBalancerAnnotations: annotations, LoadBalancerIP: "51.178.10.20"}) {
+		got[c.key] = c.value
+	}
+	if want := `{"loadbalancer.ovhcloud.com/flavor":"medium"}`; got["coder:loadBalancerAnnotations"] != want {
+		t.Errorf("getConfigCommands() coder:loadBalancerAnnotations = %q, want %q", got["coder:loadBalancerAnnotations"], want)
+	}
+	if got["coder:loadBalancerIP"] != "51.178.10.20" {
+		t.Errorf("getConfigCommands() coder:loadBalancerIP = %q, want 51.178.10.20", got["coder:loadBalancerIP"])
+	}
+
+	// An existing controller is not managed by the lab, so it keeps its annotations.
+	noInstall := false
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", InstallNginxIngress: &noInstall, LoadBalancerAnnotations: annotations}) {
+		if c.key == "coder:loadBalancerAnnotations" || c.key == "coder:loadBalancerIP" {
+			t.Errorf("getConfigCommands() with an existing controller emitted %s", c.key)
+		}
+	}
+}
+
+func TestGetConfigCommands_NodePortFallback(t *testing.T) {
+	pe := &PulumiExecutor{}
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", IngressIPTimeoutMinutes: 3, NodePortFallback: true}) {
+		got[c.key] = c.value
+	}
+	if got["coder:ingressIPTimeout"] != "3" {
+		t.Errorf("getConfigCommands() coder:ingressIPTimeout = %q, want 3", got["coder:ingressIPTimeout"])
+	}
+	if got["coder:nodePortFallback"] != "true" {
+		t.Errorf("getConfigCommands() coder:nodePortFallback = %q, want true", got["coder:nodePortFallback"])
+	}
+
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		if c.key == "coder:ingressIPTimeout" || c.key == "coder:nodePortFallback" {
+			t.Errorf("getConfigCommands() without the options emitted %s", c.key)
+		}
+	}
+}
+
+func TestReportIngressAddress_NodePortFallback(t *testing.T) {
+	jm := NewJobManager("")
+	pe := &PulumiExecutor{jobManager: jm}
+	jobID := jm.CreateJob(&LabConfig{StackName: "s", NodePortFallback: true})
+	pe.reportIngressAddress(jobID, auto.OutputMap{"ingressIP": auto.OutputValue{Value: "57.128.1.2:31080"}})
+
+	job, _ := jm.GetJob(jobID)
+	out := strings.Join(job.Output, "\n")
+	for _, want := range []string{"falling back to NodePort 31080 on node 57.128.1.2", "{workspace}.57.128.1.2.nip.io:31080"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("reportIngressAddress() output = %q, want it to contain %q", out, want)
+		}
+	}
+}
+
+func TestReportIngressAddress(t *testing.T) {
+	outputs := auto.OutputMap{"ingressIP": auto.OutputValue{Value: "51.178.10.20"}}
+	tests := []struct {
+		name   string
+		config *LabConfig
+		want   []string
+	}{
+		{
+			name:   "no domain",
+			config: &LabConfig{StackName: "s"},
+			want:   []string{"{workspace}.51.178.10.20.nip.io"},
+		},
+		{
+			name:   "domain without DNS provider",
+			config: &LabConfig{StackName: "s", Domain: "lab.example.com"},
+			want:   []string{"ACTION REQUIRED", "  lab.example.com -> 51.178.10.20", "  *.lab.example.com -> 51.178.10.20"},
+		},
+		{
+			name:   "domain with DNS provider",
+			config: &LabConfig{StackName: "s", Domain: "lab.example.com", DNSProvider: "ovh"},
+			want:   []string{"managed through ovh"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jm := NewJobManager("")
+			pe := &PulumiExecutor{jobManager: jm}
+			jobID := jm.CreateJob(tt.config)
+			pe.reportIngressAddress(jobID, outputs)
+
+			job, _ := jm.GetJob(jobID)
+			out := strings.Join(job.Output, "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("reportIngressAddress() output = %q, want it to contain %q", out, want)
+				}
+			}
+			if tt.config.DNSProvider != "" && strings.Contains(out, "ACTION REQUIRED") {
+				t.Errorf("reportIngressAddress() asked for manual DNS records with a DNS provider: %q", out)
+			}
+		})
+	}
+}
+
+func TestGetConfigCommands_Monitoring(t *testing.T) {
+	pe := &PulumiExecutor{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		if strings.HasPrefix(c.key, "monitoring:") {
+			t.Errorf("getConfigCommands() without monitoring emitted %s", c.key)
+		}
+	}
+
+	got := map[string]configCommand{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", InstallMonitoring: true, GrafanaAdminPassword: "s3cret"}) {
+		got[c.key] = c
+	}
+	if got["monitoring:enabled"].value != "true" {
+		t.Errorf("getConfigCommands() monitoring:enabled = %q, want true", got["monitoring:enabled"].value)
+	}
+	if c := got["monitoring:grafanaAdminPassword"]; c.value != "s3cret" || !c.secret {
+		t.Errorf("getConfigCommands() monitoring:grafanaAdminPassword = %+v, want a secret s3cret", c)
+	}
+}
+
+func TestGetConfigCommands_WorkspaceQuota(t *testing.T) {
+	pe := &PulumiExecutor{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		if c.key == "coder:workspaceQuota" {
+			t.Errorf("getConfigCommands() without a quota emitted %s", c.key)
+		}
+	}
+
+	quota := &WorkspaceQuota{CPU: "6400m", Memory: "26214Mi", DefaultCPURequest: "250m"}
+	for _, cfg := range []*LabConfig{
+		{Provider: "ovh", StackName: "my-stack", WorkspaceQuota: quota},
+		{UseExistingCluster: true, StackName: "my-stack", WorkspaceQuota: quota},
+	} {
+		var got string
+		for _, c := range pe.getConfigCommands(cfg) {
+			if c.key == "coder:workspaceQuota" {
+				got = c.value
+			}
+		}
+		want := `{"cpu":"6400m","memory":"26214Mi","default_cpu_request":"250m"}`
+		if got != want {
+			t.Errorf("getConfigCommands() coder:workspaceQuota = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestGetConfigCommands_HelmRepoOverrides(t *testing.T) {
+	pe := &PulumiExecutor{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		if c.key == "helm:repoOverrides" {
+			t.Errorf("getConfigCommands() without overrides emitted %s", c.key)
+		}
+	}
+
+	var got string
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", HelmRepoOverrides: map[string]string{"cert-manager": "https://mirror.example/jetstack"}}) {
+		if c.key == "helm:repoOverrides" {
+			got = c.value
+		}
+	}
+	if want := `{"cert-manager":"https://mirror.example/jetstack"}`; got != want {
+		t.Errorf("getConfigCommands() helm:repoOverrides = %q, want %q", got, want)
+	}
+}
+
+func TestReportGrafanaURL(t *testing.T) {
+	jm := NewJobManager("")
+	pe := &PulumiExecutor{jobManager: jm}
+	jobID := jm.CreateJob(&LabConfig{StackName: "s", InstallMonitoring: true, GrafanaAdminPassword: "s3cret"})
+
+	pe.reportGrafanaURL(jobID, auto.OutputMap{"grafanaURL": auto.OutputValue{Value: "https://grafana.lab.example.com"}})
+
+	job, _ := jm.GetJob(jobID)
+	if job.GrafanaURL != "https://grafana.lab.example.com" {
+		t.Errorf("reportGrafanaURL() GrafanaURL = %q", job.GrafanaURL)
+	}
+	out := strings.Join(job.Output, "\n")
+	if !strings.Contains(out, "https://grafana.lab.example.com") || strings.Contains(out, "s3cret") {
+		t.Errorf("reportGrafanaURL() output = %q, want the URL and not the password", out)
+	}
+}
+
+func TestNodePoolOptionsSummary(t *testing.T) {
+	got := nodePoolOptionsSummary(&LabConfig{Provider: "ovh", NodePoolAutoscale: true})
+	want := "Node pool options: autoscale on, monthly billing off, anti-affinity off"
+	if got != want {
+		t.Errorf("nodePoolOptionsSummary() = %q, want %q", got, want)
+	}
+	if got := nodePoolOptionsSummary(&LabConfig{UseExistingCluster: true}); got != "" {
+		t.Errorf("nodePoolOptionsSummary() for an existing cluster = %q, want empty", got)
+	}
+}
+
+func TestGetConfigCommands_SubnetOptions(t *testing.T) {
+	pe := &PulumiExecutor{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack"}) {
+		if c.key == "network:networkDhcp" || c.key == "network:networkGatewayIp" {
+			t.Errorf("getConfigCommands() without subnet options emitted %s", c.key)
+		}
+	}
+
+	dhcp := false
+	got := map[string]string{}
+	for _, c := range pe.getConfigCommands(&LabConfig{Provider: "ovh", StackName: "my-stack", NetworkDHCP: &dhcp, NetworkGatewayIP: "10.0.0.1"}) {
+		got[c.key] = c.value
+	}
+	if got["network:networkDhcp"] != "false" {
+		t.Errorf("getConfigCommands() network:networkDhcp = %q, want false", got["network:networkDhcp"])
+	}
+	if got["network:networkGatewayIp"] != "10.0.0.1" {
+		t.Errorf("getConfigCommands() network:networkGatewayIp = %q, want 10.0.0.1", got["network:networkGatewayIp"])
+	}
+}
+
+func TestSubnetSummary(t *testing.T) {
+	cfg := &LabConfig{Provider: "ovh", NetworkMask: "10.0.0.0/24", NetworkStartIP: "10.0.0.100", NetworkEndIP: "10.0.0.254"}
+	want := "Subnet: 10.0.0.0/24, DHCP range 10.0.0.100-10.0.0.254, DHCP OVH default, gateway IP first address of the network (OVH default)"
+	if got := subnetSummary(cfg); got != want {
+		t.Errorf("subnetSummary() = %q, want %q", got, want)
+	}
+
+	dhcp := true
+	cfg.NetworkDHCP, cfg.NetworkGatewayIP = &dhcp, "10.0.0.10"
+	want = "Subnet: 10.0.0.0/24, DHCP range 10.0.0.100-10.0.0.254, DHCP on, gateway IP 10.0.0.10"
+	if got := subnetSummary(cfg); got != want {
+		t.Errorf("subnetSummary() = %q, want %q", got, want)
+	}
+
+	if got := subnetSummary(&LabConfig{UseExistingCluster: true}); got != "" {
+		t.Errorf("subnetSummary() for an existing cluster = %q, want empty", got)
+	}
+}
+
 func TestGetConfigCommands_Azure(t *testing.T) {
 	pe := &PulumiExecutor{}
 	cfg := &LabConfig{
@@ -515,3 +938,19 @@ func TestCheckLocalKubeconfigFile_ValidKubeconfig(t *testing.T) {
 		t.Errorf("checkLocalKubeconfigFile() set kubeconfig = %q, want %q", kc, kubeconfig)
 	}
 }
+
+func TestGetConfigCommands_StorageClass(t *testing.T) {
+	pe := &PulumiExecutor{}
+	for _, cfg := range []*LabConfig{
+		{Provider: "ovh", StackName: "lab", StorageClass: "csi-cinder-classic"},
+		{UseExistingCluster: true, StorageClass: "csi-cinder-classic"},
+	} {
+		got := map[string]string{}
+		for _, c := range pe.getConfigCommands(cfg) {
+			got[c.key] = c.value
+		}
+		if got["k8s:storageClass"] != "csi-cinder-classic" {
+			t.Errorf("getConfigCommands() k8s:storageClass = %q, want csi-cinder-classic", got["k8s:storageClass"])
+		}
+	}
+}
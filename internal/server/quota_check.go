@@ -0,0 +1,104 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"strings"
+	"time"
+
+	"easylab/ovh"
+)
+
+// QuotaReport is the pre-flight quota check of a dry run: what the lab needs
+// from the OVH project, and each quota too small for it.
+type QuotaReport struct {
+	Region     string               `json:"region"`
+	CheckedAt  time.Time            `json:"checked_at"`
+	Needs      ovh.QuotaNeeds       `json:"needs"`
+	Shortfalls []ovh.QuotaShortfall `json:"shortfalls,omitempty"`
+}
+
+// Fits reports whether the lab fits in the quota. A lab that was not checked
+// fits.
+func (r *QuotaReport) Fits() bool {
+	return r == nil || len(r.Shortfalls) == 0
+}
+
+// labQuotaNeeds is what a lab takes from the quota: its nodes (every pool's),
+// sized by the flavor when it is known, the gateway, and the ingress
+// LoadBalancer unless the lab skips ingress-nginx.
+func labQuotaNeeds(cfg *LabConfig, flavor *ovhFlavor) ovh.QuotaNeeds {
+	nodes := cfg.NodePoolDesiredNodeCount * nodePoolCount(cfg)
+	needs := ovh.QuotaNeeds{Instances: nodes, Gateways: 1}
+	if flavor != nil {
+		needs.Cores = nodes * flavor.VCPUs
+		needs.RAMMB = nodes * flavor.RAM * 1024
+	}
+	if cfg.InstallNginxIngress == nil || *cfg.InstallNginxIngress {
+		needs.LoadBalancers = 1
+	}
+	return needs
+}
+
+// checkQuota compares, for a dry run, what the lab needs with the quota left in
+// its region, which OVH otherwise only reports once pulumi up fails half way.
+// Like the gateway model check it is best effort: it returns nil when the
+// quota cannot be fetched.
+func (h *Handler) checkQuota(cfg *LabConfig) *QuotaReport {
+	if cfg.UseExistingCluster || cfg.Provider == "azure" {
+		return nil
+	}
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		slog.Warn("Quota check skipped", "error", err)
+		return nil
+	}
+	client.Timeout = ovhCheckTimeout
+	quota, err := ovh.FetchRegionQuota(client, serviceName, cfg.NetworkRegion)
+	if err != nil {
+		slog.Warn("Quota check skipped", "error", err)
+		return nil
+	}
+
+	var flavor *ovhFlavor
+	if flavors, err := h.regionFlavors(cfg.NetworkRegion); err != nil {
+		slog.Warn("Quota check: could not list flavors for region, checking the instance count only", "network_region", cfg.NetworkRegion, "error", err)
+	} else if f, ok := findOVHFlavor(flavors, cfg.NodePoolFlavor); ok {
+		flavor = &f
+	}
+	needs := labQuotaNeeds(cfg, flavor)
+	return &QuotaReport{Region: cfg.NetworkRegion, CheckedAt: time.Now(), Needs: needs, Shortfalls: quota.Shortfalls(needs)}
+}
+
+// quotaReportSummary is the dry-run output line of the quota check, or "" when
+// the quota was not checked.
+func quotaReportSummary(report *QuotaReport) string {
+	if report == nil {
+		return ""
+	}
+	if report.Fits() {
+		return fmt.Sprintf("Quota check passed in %s: %d instances, %d vCPU cores, %d MB RAM, %d gateway, %d load balancer needed",
+			report.Region, report.Needs.Instances, report.Needs.Cores, report.Needs.RAMMB, report.Needs.Gateways, report.Needs.LoadBalancers)
+	}
+	short := make([]string, len(report.Shortfalls))
+	for i, s := range report.Shortfalls {
+		short[i] = s.String()
+	}
+	return fmt.Sprintf("❌ Quota check failed in %s: %s", report.Region, strings.Join(short, "; "))
+}
+
+// quotaReportHTML renders a failed quota check on the job page, above the
+// launch form.
+func quotaReportHTML(report *QuotaReport) string {
+	if report.Fits() {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<div class="error-message"><h3>The lab does not fit in the quota of %s</h3><ul>`, template.HTMLEscapeString(report.Region))
+	for _, s := range report.Shortfalls {
+		fmt.Fprintf(&b, `<li>%s</li>`, template.HTMLEscapeString(s.String()))
+	}
+	b.WriteString(`</ul><p>Raise the quota in the OVHcloud Control Panel, or launch anyway if it will be raised in time.</p></div>`)
+	return b.String()
+}
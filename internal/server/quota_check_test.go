@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"easylab/ovh"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabQuotaNeeds(t *testing.T) {
+	noIngress := false
+	cfg := &LabConfig{NodePoolDesiredNodeCount: 3, NodePoolZones: []string{"eu-west-par-a", "eu-west-par-b"}}
+
+	assert.Equal(t, ovh.QuotaNeeds{Instances: 6, Cores: 24, RAMMB: 6 * 16 * 1024, Gateways: 1, LoadBalancers: 1},
+		labQuotaNeeds(cfg, &ovhFlavor{Name: "b3-16", VCPUs: 4, RAM: 16}), "one pool per zone")
+
+	cfg.NodePoolZones = nil
+	cfg.InstallNginxIngress = &noIngress
+	assert.Equal(t, ovh.QuotaNeeds{Instances: 3, Gateways: 1}, labQuotaNeeds(cfg, nil),
+		"an unknown flavor leaves the cores and RAM out, and no ingress needs no load balancer")
+}
+
+func TestQuotaReportSummary(t *testing.T) {
+	assert.Empty(t, quotaReportSummary(nil), "an unchecked quota has no summary")
+
+	report := &QuotaReport{Region: "GRA11", Needs: ovh.QuotaNeeds{Instances: 3, Cores: 12, RAMMB: 24576, Gateways: 1, LoadBalancers: 1}}
+	assert.Equal(t, "Quota check passed in GRA11: 3 instances, 12 vCPU cores, 24576 MB RAM, 1 gateway, 1 load balancer needed", quotaReportSummary(report))
+
+	report.Shortfalls = []ovh.QuotaShortfall{{Quota: "vCPU cores", Needed: 12, Available: 4}, {Quota: "gateways", Needed: 1, Available: 0}}
+	assert.Equal(t, "❌ Quota check failed in GRA11: vCPU cores: needs 12, 4 available; gateways: needs 1, 0 available", quotaReportSummary(report))
+}
+
+func TestCheckQuota_SkipsWhenItCannotCheck(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	assert.Nil(t, h.checkQuota(&LabConfig{Provider: "azure"}))
+	assert.Nil(t, h.checkQuota(&LabConfig{UseExistingCluster: true}))
+	// No OVH credentials: the quota cannot be fetched.
+	assert.Nil(t, h.checkQuota(&LabConfig{NetworkRegion: "GRA11", NodePoolDesiredNodeCount: 3}))
+}
+
+func TestLaunchLab_QuotaShortfallNeedsOverride(t *testing.T) {
+	jm := newTestJobManager(t, t.TempDir())
+	pe := NewPulumiExecutor(jm, t.TempDir())
+	id := jm.CreateJob(&LabConfig{StackName: "test"})
+	jm.SetQuotaReport(id, &QuotaReport{Region: "GRA11", Shortfalls: []ovh.QuotaShortfall{{Quota: "gateways", Needed: 1, Available: 0}}})
+	jm.UpdateJobStatus(id, JobStatusDryRunCompleted)
+	h := NewHandler(jm, pe, NewCredentialsManager(), nil, nil, nil)
+
+	status := httptest.NewRecorder()
+	h.GetJobStatus(status, httptest.NewRequest("GET", "/api/jobs/"+id+"/status", nil))
+	assert.Contains(t, status.Body.String(), `name="override_quota"`, "the launch form offers the override")
+	assert.Contains(t, status.Body.String(), "gateways: needs 1, 0 available")
+
+	launch := func(form url.Values) *httptest.ResponseRecorder {
+		form.Set("job_id", id)
+		req := httptest.NewRequest("POST", "/api/labs/launch", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.LaunchLab(w, req)
+		return w
+	}
+
+	w := launch(url.Values{})
+	assert.Contains(t, w.Body.String(), "does not fit in the quota of GRA11")
+	assert.NotContains(t, w.Body.String(), "Deployment Launched")
+	job, _ := jm.GetJob(id)
+	job.mu.RLock()
+	assert.Equal(t, JobStatusDryRunCompleted, job.Status, "the lab is not launched")
+	job.mu.RUnlock()
+
+	w = launch(url.Values{"override_quota": {"true"}})
+	assert.Contains(t, w.Body.String(), "Deployment Launched")
+	job.mu.RLock()
+	assert.Contains(t, job.Output, "Launched despite the failed quota check")
+	job.mu.RUnlock()
+
+	// Wait for background goroutine to finish before t.TempDir cleanup runs.
+	waitForJobsTerminal(jm, 5*time.Second)
+}
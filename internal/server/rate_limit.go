@@ -0,0 +1,220 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route classes of the rate limiter, each with its own limit.
+const (
+	// rateClassStudent: the student API, but for the status polls. Most calls
+	// reach the lab's cluster.
+	rateClassStudent = "student"
+	// rateClassAdminJobs: the admin endpoints that start a Pulumi job.
+	rateClassAdminJobs = "admin_jobs"
+	// rateClassLogin: the login pages and share links, which need no session:
+	// each login runs bcrypt, and each enrollment opens a session.
+	rateClassLogin = "login"
+)
+
+// rateLimitPruneInterval is how often the buckets left full are dropped, so
+// that clients seen once do not stay in memory.
+const rateLimitPruneInterval = time.Minute
+
+// loginPaths are the routes of rateClassLogin, whatever the method.
+var loginPaths = []string{"/login", "/student/login", "/enroll"}
+
+// adminJobPaths are the admin endpoints that start a Pulumi job on POST, on
+// top of the lab routes ending with one of adminJobSuffixes.
+var (
+	adminJobPaths    = []string{"/api/labs", "/api/labs/dry-run", "/api/labs/launch", "/api/labs/recreate", "/api/stacks/destroy", "/api/v1/labs", "/api/v1/labs/dry-run"}
+	adminJobSuffixes = []string{"/retry", "/launch", "/destroy", "/nodepool/scale"}
+)
+
+// rateLimitClass returns the route class of r, "" for a request that is not
+// limited: pages, status polls (which the UI calls every few seconds by
+// design) and the admin endpoints that do not start a job.
+func rateLimitClass(r *http.Request) string {
+	path := r.URL.Path
+	for _, p := range loginPaths {
+		if path == p {
+			return rateClassLogin
+		}
+	}
+	switch {
+	case path == "/api/student/workspace/status",
+		r.Method == http.MethodGet && strings.HasPrefix(path, "/api/v1/student/workspaces/"):
+		return ""
+	case strings.HasPrefix(path, "/api/student/"), strings.HasPrefix(path, "/api/v1/student/"):
+		return rateClassStudent
+	case r.Method != http.MethodPost:
+		return ""
+	case strings.HasPrefix(path, "/api/labs/from-template/"):
+		return rateClassAdminJobs
+	}
+	for _, p := range adminJobPaths {
+		if path == p {
+			return rateClassAdminJobs
+		}
+	}
+	if strings.HasPrefix(path, "/api/labs/") || strings.HasPrefix(path, "/api/jobs/") || strings.HasPrefix(path, "/api/v1/labs/") {
+		for _, suffix := range adminJobSuffixes {
+			if strings.HasSuffix(path, suffix) {
+				return rateClassAdminJobs
+			}
+		}
+	}
+	return ""
+}
+
+// tokenBucket holds what a client may still send in a route class: one token
+// per request, refilled continuously up to the limit per minute.
+type tokenBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// RateLimiter limits how often each client calls the expensive endpoints, by
+// route class: a client may send up to the class's limit at once, then as
+// many per minute. A client is its session once the auth handler has
+// validated it (see SetAuthHandler), else its address (see SetTrustedProxies).
+type RateLimiter struct {
+	perMinute map[string]int // by class; 0 is unlimited
+	auth      *AuthHandler
+	addrs     *IPAllowList // lists no network, only the trusted proxies
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket // by class and client
+	lastPrune time.Time
+}
+
+// NewRateLimiter returns a limiter with the limits of cfg.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		perMinute: map[string]int{
+			rateClassStudent:   cfg.StudentPerMinute,
+			rateClassAdminJobs: cfg.AdminJobsPerMinute,
+			rateClassLogin:     cfg.LoginPerMinute,
+		},
+		addrs:   &IPAllowList{},
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// SetAuthHandler lets the limiter tell the clients with a valid session apart,
+// so that students sharing an address do not share a limit. Without it every
+// client is its address. Call it at startup, before serving requests.
+func (l *RateLimiter) SetAuthHandler(ah *AuthHandler) {
+	l.auth = ah
+}
+
+// SetTrustedProxies lets the limiter find the address of the clients behind
+// the reverse proxies of cidrs in X-Forwarded-For, as the IP allow-lists do:
+// without it every client of a proxy shares the proxy's address, and its
+// limit. Call it at startup, before serving requests.
+func (l *RateLimiter) SetTrustedProxies(cidrs []string) error {
+	addrs, err := NewIPAllowList(nil, cidrs)
+	if err != nil {
+		return err
+	}
+	l.addrs = addrs
+	return nil
+}
+
+// allow takes a token from the bucket of client in class at now. When there is
+// none left it returns false and how long until there is one.
+func (l *RateLimiter) allow(class, client string, now time.Time) (bool, time.Duration) {
+	limit := float64(l.perMinute[class])
+	if limit <= 0 {
+		return true, 0
+	}
+	perSecond := limit / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastPrune) >= rateLimitPruneInterval {
+		for k, b := range l.buckets {
+			// A minute refills any bucket.
+			if now.Sub(b.at) >= time.Minute {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	key := class + "\x00" + client
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: limit, at: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(limit, b.tokens+now.Sub(b.at).Seconds()*perSecond)
+	b.at = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// client identifies who sent r in class: their session for the portal of the
+// class, when it is valid, else their address. A cookie alone is not trusted:
+// a client sending a new one each time would get a new limit, and a bucket
+// kept in memory, each time.
+func (l *RateLimiter) client(r *http.Request, class string) string {
+	if l.auth != nil && class != rateClassLogin {
+		cookie, valid := SessionCookieName, l.auth.validateSession
+		if class == rateClassStudent {
+			cookie, valid = StudentSessionCookieName, l.auth.validateStudentSession
+		}
+		if c, err := r.Cookie(cookie); err == nil && c.Value != "" && valid(c.Value) {
+			return "session:" + c.Value
+		}
+	}
+	if addr, ok := l.addrs.clientAddr(r); ok {
+		return "ip:" + addr.String()
+	}
+	// An address that does not parse, such as a garbled X-Forwarded-For, is
+	// limited with the connection's.
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// RateLimit answers 429, with a Retry-After header, to the requests to the
+// expensive endpoints over the limits of limits (see rateLimitClass). A nil
+// limiter limits nothing.
+func RateLimit(next http.Handler, limits *RateLimiter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := rateLimitClass(r)
+		if limits == nil || class == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ok, wait := limits.allow(class, limits.client(r, class), time.Now())
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		metrics.countRateLimited(class)
+		seconds := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		msg := fmt.Sprintf("Too many requests, please wait %d seconds before trying again.", seconds)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v1/"):
+			writeAPIError(w, &requestError{Status: http.StatusTooManyRequests, Title: "Too Many Requests", Message: msg})
+		case isHTMXRequest(r):
+			writeHTMLFragment(w, http.StatusTooManyRequests, `<div class="error-message">`+msg+`</div>`)
+		default:
+			http.Error(w, msg, http.StatusTooManyRequests)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitClass(t *testing.T) {
+	for _, tc := range []struct {
+		method, path, class string
+	}{
+		{http.MethodPost, "/api/student/workspace/request", rateClassStudent},
+		{http.MethodGet, "/api/student/labs", rateClassStudent},
+		{http.MethodPost, "/api/v1/student/workspaces", rateClassStudent},
+		{http.MethodDelete, "/api/v1/student/workspaces/job-1/alice", rateClassStudent},
+		{http.MethodGet, "/api/student/workspace/status", ""},
+		{http.MethodGet, "/api/v1/student/workspaces/job-1/alice", ""},
+
+		{http.MethodPost, "/api/labs", rateClassAdminJobs},
+		{http.MethodPost, "/api/labs/dry-run", rateClassAdminJobs},
+		{http.MethodPost, "/api/labs/launch", rateClassAdminJobs},
+		{http.MethodPost, "/api/stacks/destroy", rateClassAdminJobs},
+		{http.MethodPost, "/api/labs/job-1/retry", rateClassAdminJobs},
+		{http.MethodPost, "/api/jobs/job-1/retry", rateClassAdminJobs},
+		{http.MethodPost, "/api/labs/from-template/job-1", rateClassAdminJobs},
+		{http.MethodPost, "/api/v1/labs", rateClassAdminJobs},
+		{http.MethodPost, "/api/v1/labs/job-1/launch", rateClassAdminJobs},
+		{http.MethodPost, "/api/v1/labs/job-1/destroy", rateClassAdminJobs},
+		{http.MethodPost, "/api/jobs/job-1/nodepool/scale", rateClassAdminJobs},
+		{http.MethodPost, "/api/labs/job-1/nodepool/scale", rateClassAdminJobs},
+
+		{http.MethodGet, "/api/labs/job-1/status", ""},
+		{http.MethodGet, "/api/v1/labs", ""},
+		{http.MethodPost, "/api/labs/job-1/secrets", ""},
+		{http.MethodPost, "/api/broadcast", ""},
+		{http.MethodGet, "/admin", ""},
+
+		{http.MethodPost, "/login", rateClassLogin},
+		{http.MethodGet, "/student/login", rateClassLogin},
+		{http.MethodPost, "/student/login", rateClassLogin},
+		{http.MethodGet, "/enroll", rateClassLogin},
+		{http.MethodGet, "/student/dashboard", ""},
+	} {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		assert.Equal(t, tc.class, rateLimitClass(r), "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{StudentPerMinute: 3})
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow(rateClassStudent, "alice", now)
+		require.True(t, ok, "request %d is within the burst", i+1)
+	}
+	ok, wait := l.allow(rateClassStudent, "alice", now)
+	assert.False(t, ok)
+	assert.Equal(t, 20*time.Second, wait, "3 a minute is one every 20 seconds")
+
+	ok, _ = l.allow(rateClassStudent, "bob", now)
+	assert.True(t, ok, "each client has its own bucket")
+
+	ok, _ = l.allow(rateClassStudent, "alice", now.Add(20*time.Second))
+	assert.True(t, ok, "a token came back")
+	ok, _ = l.allow(rateClassStudent, "alice", now.Add(20*time.Second))
+	assert.False(t, ok)
+
+	for i := 0; i < 10; i++ {
+		ok, _ = l.allow(rateClassAdminJobs, "alice", now)
+		assert.True(t, ok, "0 lifts the limit")
+	}
+}
+
+func TestRateLimiter_PrunesFullBuckets(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{StudentPerMinute: 3})
+	now := time.Now()
+	l.allow(rateClassStudent, "alice", now)
+	l.allow(rateClassStudent, "bob", now.Add(45*time.Second))
+	assert.Len(t, l.buckets, 2)
+
+	l.allow(rateClassStudent, "carol", now.Add(90*time.Second))
+	assert.Len(t, l.buckets, 2, "alice's bucket refilled and was dropped")
+}
+
+func TestRateLimit_Middleware(t *testing.T) {
+	served := 0
+	ah := createTestAuthHandler()
+	limiter := NewRateLimiter(RateLimitConfig{StudentPerMinute: 2, AdminJobsPerMinute: 1})
+	limiter.SetAuthHandler(ah)
+	h := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served++ }), limiter)
+	alice := ah.createStudentSession("alice@example.com")
+	bob := ah.createStudentSession("bob@example.com")
+
+	request := func(method, path, session string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if session != "" {
+			r.AddCookie(&http.Cookie{Name: StudentSessionCookieName, Value: session})
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	request(http.MethodPost, "/api/student/workspace/request", alice)
+	request(http.MethodPost, "/api/student/workspace/request", alice)
+	w := request(http.MethodPost, "/api/student/workspace/request", alice)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "please wait 30 seconds")
+	assert.Equal(t, 2, served)
+
+	w = request(http.MethodPost, "/api/student/workspace/request", bob)
+	assert.Equal(t, http.StatusOK, w.Code, "another session is not limited by alice's")
+
+	for i := 0; i < 5; i++ {
+		w = request(http.MethodGet, "/api/student/workspace/status", alice)
+		assert.Equal(t, http.StatusOK, w.Code, "status polls are exempt")
+	}
+
+	request(http.MethodPost, "/api/v1/labs", "")
+	w = request(http.MethodPost, "/api/v1/labs", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error": "Too many requests, please wait 60 seconds before trying again.", "title": "Too Many Requests"}`, w.Body.String())
+
+	assert.Contains(t, scrapeServerMetrics(), `easylab_rate_limited_requests_total{class="student"}`)
+}
+
+func TestRateLimit_UnknownSessionsShareTheAddressLimit(t *testing.T) {
+	ah := createTestAuthHandler()
+	limiter := NewRateLimiter(RateLimitConfig{StudentPerMinute: 2})
+	limiter.SetAuthHandler(ah)
+	h := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), limiter)
+
+	codes := make([]int, 0, 3)
+	for _, cookie := range []string{"forged-1", "forged-2", "forged-3"} {
+		r := httptest.NewRequest(http.MethodPost, "/api/student/workspace/request", nil)
+		r.AddCookie(&http.Cookie{Name: StudentSessionCookieName, Value: cookie})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes, "a new cookie is not a new limit")
+	assert.Len(t, limiter.buckets, 1, "nor a new bucket")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/student/workspace/request", nil)
+	r.AddCookie(&http.Cookie{Name: StudentSessionCookieName, Value: ah.createStudentSession("alice@example.com")})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, "a valid session has its own limit")
+}
+
+func TestRateLimit_LoginsByAddress(t *testing.T) {
+	ah := createTestAuthHandler()
+	limiter := NewRateLimiter(RateLimitConfig{LoginPerMinute: 2})
+	limiter.SetAuthHandler(ah)
+	h := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), limiter)
+	session := ah.createStudentSession("alice@example.com")
+
+	login := func(path, remoteAddr string) int {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.RemoteAddr = remoteAddr
+		r.AddCookie(&http.Cookie{Name: StudentSessionCookieName, Value: session})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, login("/student/login", "192.0.2.1:1234"))
+	assert.Equal(t, http.StatusOK, login("/login", "192.0.2.1:1235"))
+	assert.Equal(t, http.StatusTooManyRequests, login("/enroll", "192.0.2.1:1236"), "the session does not lift the address's limit")
+	assert.Equal(t, http.StatusOK, login("/student/login", "192.0.2.2:1234"))
+}
+
+func TestRateLimit_LoginsBehindAProxy(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{LoginPerMinute: 1})
+	require.NoError(t, limiter.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	h := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), limiter)
+
+	login := func(remoteAddr, forwardedFor string) int {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, login("10.0.0.2:443", "198.51.100.20"))
+	assert.Equal(t, http.StatusOK, login("10.0.0.2:443", "198.51.100.21"), "clients of the proxy have their own limit")
+	assert.Equal(t, http.StatusTooManyRequests, login("10.0.0.3:443", "198.51.100.20, 10.0.0.2"), "whichever proxy they come through")
+	assert.Equal(t, http.StatusTooManyRequests, login("10.0.0.2:443", "203.0.113.9, 198.51.100.21"), "an entry forged by the client does not count")
+	assert.Equal(t, http.StatusOK, login("192.0.2.1:1234", "198.51.100.20"), "X-Forwarded-For is ignored from other addresses")
+	assert.Equal(t, http.StatusTooManyRequests, login("192.0.2.1:1234", "198.51.100.22"))
+
+	assert.Error(t, limiter.SetTrustedProxies([]string{"proxy.local"}))
+}
+
+func TestRateLimit_NilLimiter(t *testing.T) {
+	h := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+	for i := 0; i < 50; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/labs", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Readiness check results. A failed check makes /health/ready answer 503; a
+// warning is reported but the server stays ready.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// readinessCacheTTL is how long /health/ready answers with the same results:
+// the endpoint needs no login, and each run of the checks writes to the disks.
+const readinessCacheTTL = 5 * time.Second
+
+// readinessCheck is one line of the /health/ready breakdown.
+type readinessCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// readinessReport is the body of /health/ready.
+type readinessReport struct {
+	Status string           `json:"status"` // "ready" or "not_ready"
+	Checks []readinessCheck `json:"checks"`
+}
+
+// ReadinessChecker answers /health/ready: whether the server can deploy labs
+// right now, unlike /health, which only says the process is up. The details
+// never name directories, providers or versions, as the endpoint needs no
+// login.
+type ReadinessChecker struct {
+	dataDir       string
+	workDir       string
+	minFreeBytes  uint64
+	pulumiVersion string
+	credentials   *CredentialsManager
+	jobs          *JobManager
+	// Stubbed in tests.
+	freeBytes    func(dir string) (uint64, error)
+	lookupPulumi func() error
+	now          func() time.Time
+
+	mu        sync.Mutex // serializes the checks
+	report    readinessReport
+	checkedAt time.Time
+}
+
+// NewReadinessChecker returns the checker of a server keeping its files in
+// dataDir and workDir, which wants minFreeBytes free on each. pulumiVersion is
+// the CLI found at startup, empty when none was.
+func NewReadinessChecker(dataDir, workDir string, minFreeBytes uint64, pulumiVersion string, credentials *CredentialsManager, jobs *JobManager) *ReadinessChecker {
+	return &ReadinessChecker{
+		dataDir:       dataDir,
+		workDir:       workDir,
+		minFreeBytes:  minFreeBytes,
+		pulumiVersion: pulumiVersion,
+		credentials:   credentials,
+		jobs:          jobs,
+		freeBytes:     diskFreeBytes,
+		lookupPulumi: func() error {
+			_, err := exec.LookPath("pulumi")
+			return err
+		},
+		now: time.Now,
+	}
+}
+
+// check runs every check, in a fixed order.
+func (c *ReadinessChecker) check() readinessReport {
+	checks := []readinessCheck{
+		c.checkWritable("data_dir", c.dataDir),
+		c.checkWritable("work_dir", c.workDir),
+		c.checkDisk("data_dir_disk", c.dataDir),
+		c.checkDisk("work_dir_disk", c.workDir),
+		c.checkPulumi(),
+		c.checkCredentials(),
+		c.checkStuckJobs(),
+	}
+	report := readinessReport{Status: "ready", Checks: checks}
+	for _, check := range checks {
+		if check.Status == checkFail {
+			report.Status = "not_ready"
+		}
+	}
+	return report
+}
+
+// cachedCheck returns the results of the last checks, running them again once
+// they are readinessCacheTTL old.
+func (c *ReadinessChecker) cachedCheck() readinessReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now := c.now(); c.checkedAt.IsZero() || now.Sub(c.checkedAt) >= readinessCacheTTL {
+		c.report = c.check()
+		c.checkedAt = now
+	}
+	return c.report
+}
+
+// checkWritable creates and removes a file in dir.
+func (c *ReadinessChecker) checkWritable(name, dir string) readinessCheck {
+	f, err := os.CreateTemp(dir, ".ready-*")
+	if err != nil {
+		return readinessCheck{Name: name, Status: checkFail, Detail: "not writable: " + errorReason(err)}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return readinessCheck{Name: name, Status: checkOK}
+}
+
+// checkDisk compares the space left on dir's file system with minFreeBytes.
+func (c *ReadinessChecker) checkDisk(name, dir string) readinessCheck {
+	free, err := c.freeBytes(dir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return readinessCheck{Name: name, Status: checkWarn, Detail: "free space cannot be checked on this platform"}
+	}
+	if err != nil {
+		return readinessCheck{Name: name, Status: checkFail, Detail: "free space unknown: " + errorReason(err)}
+	}
+	detail := fmt.Sprintf("%d MB free, %d MB required", free>>20, c.minFreeBytes>>20)
+	if free < c.minFreeBytes {
+		return readinessCheck{Name: name, Status: checkFail, Detail: detail}
+	}
+	return readinessCheck{Name: name, Status: checkOK, Detail: detail}
+}
+
+// checkPulumi fails when no usable CLI was found at startup (lenient mode) or
+// it has since disappeared from the PATH.
+func (c *ReadinessChecker) checkPulumi() readinessCheck {
+	if c.pulumiVersion == "" {
+		return readinessCheck{Name: "pulumi", Status: checkFail, Detail: "no usable Pulumi CLI was found at startup"}
+	}
+	if err := c.lookupPulumi(); err != nil {
+		return readinessCheck{Name: "pulumi", Status: checkFail, Detail: "the Pulumi CLI is no longer on the PATH"}
+	}
+	return readinessCheck{Name: "pulumi", Status: checkOK}
+}
+
+// checkCredentials warns when no cloud provider has credentials: the admin
+// can still enter them, so the server is ready.
+func (c *ReadinessChecker) checkCredentials() readinessCheck {
+	for _, provider := range []string{"ovh", "azure"} {
+		if c.credentials != nil && c.credentials.HasCredentials(provider) {
+			return readinessCheck{Name: "credentials", Status: checkOK}
+		}
+	}
+	return readinessCheck{Name: "credentials", Status: checkWarn, Detail: "no cloud provider credentials, labs cannot be deployed until an admin enters them"}
+}
+
+// checkStuckJobs warns about jobs running for longer than a Pulumi operation
+// may: their run ended without the job hearing of it.
+func (c *ReadinessChecker) checkStuckJobs() readinessCheck {
+	stuck := 0
+	now := c.now()
+	for _, job := range c.jobs.GetAllJobs() {
+		job.mu.RLock()
+		if job.Status == JobStatusRunning {
+			if since, ok := job.runningSince(); ok && now.Sub(since) > pulumiExecutionTimeout {
+				stuck++
+			}
+		}
+		job.mu.RUnlock()
+	}
+	if stuck > 0 {
+		return readinessCheck{Name: "stuck_jobs", Status: checkWarn, Detail: fmt.Sprintf("%d job(s) running for over %s", stuck, pulumiExecutionTimeout)}
+	}
+	return readinessCheck{Name: "stuck_jobs", Status: checkOK}
+}
+
+// errorReason is the cause of a file system error without the path it names.
+func errorReason(err error) string {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// ServeHTTP serves /health/ready: 200 when every hard check passes, 503
+// otherwise, with the checks in the body either way.
+func (c *ReadinessChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.cachedCheck()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != "ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readyChecker returns a checker whose every check passes but the
+// credentials one, which only warns.
+func readyChecker(t *testing.T) *ReadinessChecker {
+	t.Helper()
+	c := NewReadinessChecker(t.TempDir(), t.TempDir(), 1<<30, "3.243.0", nil, NewJobManager(""))
+	c.freeBytes = func(string) (uint64, error) { return 5 << 30, nil }
+	c.lookupPulumi = func() error { return nil }
+	return c
+}
+
+// serveReady returns the status and body of /health/ready.
+func serveReady(t *testing.T, c *ReadinessChecker) (int, readinessReport) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	var report readinessReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+// checkNamed returns the check called name.
+func checkNamed(t *testing.T, report readinessReport, name string) readinessCheck {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("no %s check in %+v", name, report.Checks)
+	return readinessCheck{}
+}
+
+func TestReadiness_Ready(t *testing.T) {
+	code, report := serveReady(t, readyChecker(t))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", report.Status)
+
+	var names []string
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+	}
+	assert.Equal(t, []string{"data_dir", "work_dir", "data_dir_disk", "work_dir_disk", "pulumi", "credentials", "stuck_jobs"}, names)
+	assert.Equal(t, readinessCheck{Name: "work_dir_disk", Status: checkOK, Detail: "5120 MB free, 1024 MB required"}, checkNamed(t, report, "work_dir_disk"))
+	assert.Equal(t, checkWarn, checkNamed(t, report, "credentials").Status, "missing credentials only warn")
+}
+
+func TestReadiness_UnwritableDirectory(t *testing.T) {
+	c := readyChecker(t)
+	c.dataDir = filepath.Join(t.TempDir(), "missing")
+
+	code, report := serveReady(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", report.Status)
+	dataDir := checkNamed(t, report, "data_dir")
+	assert.Equal(t, checkFail, dataDir.Status)
+	assert.NotContains(t, dataDir.Detail, c.dataDir, "the directory is not disclosed")
+	assert.Equal(t, checkOK, checkNamed(t, report, "work_dir").Status)
+
+	entries, err := os.ReadDir(c.workDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the probe file is removed")
+}
+
+func TestReadiness_DiskFull(t *testing.T) {
+	c := readyChecker(t)
+	c.freeBytes = func(dir string) (uint64, error) {
+		if dir == c.workDir {
+			return 100 << 20, nil
+		}
+		return 5 << 30, nil
+	}
+
+	code, report := serveReady(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, readinessCheck{Name: "work_dir_disk", Status: checkFail, Detail: "100 MB free, 1024 MB required"}, checkNamed(t, report, "work_dir_disk"))
+	assert.Equal(t, checkOK, checkNamed(t, report, "data_dir_disk").Status)
+
+	c = readyChecker(t)
+	c.freeBytes = func(string) (uint64, error) { return 0, errors.ErrUnsupported }
+	code, report = serveReady(t, c)
+	assert.Equal(t, http.StatusOK, code, "a platform without the check only warns")
+	assert.Equal(t, checkWarn, checkNamed(t, report, "work_dir_disk").Status)
+}
+
+func TestReadiness_Pulumi(t *testing.T) {
+	c := readyChecker(t)
+	c.pulumiVersion = ""
+	code, report := serveReady(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "no usable Pulumi CLI was found at startup", checkNamed(t, report, "pulumi").Detail)
+
+	c = readyChecker(t)
+	c.lookupPulumi = func() error { return errors.New("not found") }
+	code, report = serveReady(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, checkFail, checkNamed(t, report, "pulumi").Status)
+}
+
+func TestReadiness_HidesProvidersAndVersion(t *testing.T) {
+	c := readyChecker(t)
+	c.credentials = NewCredentialsManager()
+	require.NoError(t, c.credentials.SetCredentials(&OVHCredentials{ApplicationKey: "key", ApplicationSecret: "secret", ConsumerKey: "consumer", ServiceName: "service", Endpoint: "ovh-eu"}))
+
+	_, report := serveReady(t, c)
+	assert.Equal(t, readinessCheck{Name: "credentials", Status: checkOK}, checkNamed(t, report, "credentials"))
+	assert.Equal(t, readinessCheck{Name: "pulumi", Status: checkOK}, checkNamed(t, report, "pulumi"))
+}
+
+func TestReadiness_CachesResults(t *testing.T) {
+	c := readyChecker(t)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	runs := 0
+	c.lookupPulumi = func() error { runs++; return nil }
+
+	serveReady(t, c)
+	c.freeBytes = func(string) (uint64, error) { return 0, nil }
+	code, _ := serveReady(t, c)
+	assert.Equal(t, http.StatusOK, code, "the disks are not checked again yet")
+	assert.Equal(t, 1, runs)
+
+	now = now.Add(readinessCacheTTL)
+	code, _ = serveReady(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, 2, runs)
+}
+
+func TestReadiness_StuckJobs(t *testing.T) {
+	c := readyChecker(t)
+	running := c.jobs.CreateJob(&LabConfig{StackName: "running"})
+	c.jobs.UpdateJobStatus(running, JobStatusRunning)
+	stuck := c.jobs.CreateJob(&LabConfig{StackName: "stuck"})
+	c.jobs.UpdateJobStatus(stuck, JobStatusRunning)
+	job, _ := c.jobs.GetJob(stuck)
+	job.History[len(job.History)-1].At = time.Now().Add(-2 * pulumiExecutionTimeout)
+
+	code, report := serveReady(t, c)
+	assert.Equal(t, http.StatusOK, code, "stuck jobs only warn")
+	assert.Equal(t, readinessCheck{Name: "stuck_jobs", Status: checkWarn, Detail: "1 job(s) running for over 45m0s"}, checkNamed(t, report, "stuck_jobs"))
+}
+
+func TestDiskFreeBytes(t *testing.T) {
+	free, err := diskFreeBytes(t.TempDir())
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("no free space check on this platform")
+	}
+	require.NoError(t, err)
+	assert.Positive(t, free)
+
+	_, err = diskFreeBytes(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
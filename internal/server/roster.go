@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RosterEntry is one workspace provisioned in a lab, recorded when
+// RequestWorkspace succeeds. It has no password or token: the roster is for
+// instructors to see who is in, not to log in as them.
+type RosterEntry struct {
+	Email         string    `json:"email"`
+	Username      string    `json:"username"`
+	WorkspaceName string    `json:"workspace_name"`
+	Template      string    `json:"template,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	// DetailsResentAt is the last time the student had the workspace's details
+	// shown again (see ResendWorkspaceInfo).
+	DetailsResentAt *time.Time `json:"details_resent_at,omitempty"`
+}
+
+// GetJobRoster returns the lab's roster as JSON, oldest first.
+// Route: GET /api/jobs/{id}/roster
+func (h *Handler) GetJobRoster(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "roster" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	job, exists := h.jobManager.GetJob(pathParts[2])
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	job.mu.RLock()
+	roster := append([]RosterEntry{}, job.Roster...)
+	job.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"roster": roster})
+}
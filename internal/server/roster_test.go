@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestWorkspaceAs(t *testing.T, h *Handler, labID, email string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := postForm(t, "/api/workspace/request", url.Values{"lab_id": {labID}})
+	req = req.WithContext(context.WithValue(req.Context(), studentEmailContextKey, email))
+	rec := httptest.NewRecorder()
+	h.RequestWorkspace(rec, req)
+	return rec
+}
+
+func TestRequestWorkspace_AppendsToTheRoster(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{reachable: true})
+
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.mu.Lock()
+	job.Config.WorkspaceTemplates = []WorkspaceTemplate{{Name: "default"}}
+	job.mu.Unlock()
+
+	require.Equal(t, http.StatusOK, requestWorkspaceAs(t, h, labID, "jane.doe@example.com").Code)
+	// Asking again returns the same workspace, which stays a single entry.
+	requestWorkspaceAs(t, h, labID, "jane.doe@example.com")
+
+	rec := httptest.NewRecorder()
+	h.GetJobRoster(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/"+labID+"/roster", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got struct {
+		Roster []map[string]interface{} `json:"roster"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got.Roster, 1)
+	entry := got.Roster[0]
+	assert.Equal(t, "jane.doe@example.com", entry["email"])
+	assert.Equal(t, "jane-doe", entry["username"])
+	assert.Equal(t, "ws-jane-doe", entry["workspace_name"])
+	assert.Equal(t, "default", entry["template"])
+	assert.NotEmpty(t, entry["created_at"])
+	for key := range entry {
+		assert.NotContains(t, []string{"password", "token"}, key, "the roster must not carry credentials")
+	}
+}
+
+func TestJobManager_RosterPersisted(t *testing.T) {
+	tempDir := t.TempDir()
+	jm1 := newTestJobManager(t, tempDir)
+	id := jm1.CreateJob(&LabConfig{StackName: "test"})
+	jm1.UpdateJobStatus(id, JobStatusCompleted)
+	created := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	require.NoError(t, jm1.RecordRosterEntry(id, RosterEntry{Email: "jane@example.com", Username: "jane", WorkspaceName: "ws-jane", CreatedAt: created}))
+	require.NoError(t, jm1.SaveJob(id))
+
+	jm2 := newTestJobManager(t, tempDir)
+	require.NoError(t, jm2.LoadJobs())
+	job, exists := jm2.GetJob(id)
+	require.True(t, exists)
+	assert.Equal(t, []RosterEntry{{Email: "jane@example.com", Username: "jane", WorkspaceName: "ws-jane", CreatedAt: created}}, job.Roster)
+}
+
+func TestGetJobRoster_UnknownJob(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	rec := httptest.NewRecorder()
+	h.GetJobRoster(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/missing/roster", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
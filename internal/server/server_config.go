@@ -0,0 +1,675 @@
+package server
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"easylab/utils"
+
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+)
+
+// ServerConfig is every setting of the server. Each one is read, from the
+// lowest precedence up, from its default, the YAML file given with -config,
+// its flag and its environment variable: the environment wins, so that a
+// secret injected by the platform overrides a file baked into the image.
+//
+// The struct tags declare where a setting comes from: yaml is its key in the
+// file (under its section's key), flag its command-line flag, env its
+// environment variable and secret marks values masked by -print-config.
+type ServerConfig struct {
+	Server        ListenConfig        `yaml:"server"`
+	TLS           TLSSettings         `yaml:"tls"`
+	Logging       LoggingConfig       `yaml:"logging"`
+	Auth          AuthConfig          `yaml:"auth"`
+	Deploy        DeployConfig        `yaml:"deploy"`
+	Cleanup       CleanupConfig       `yaml:"cleanup"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Metrics       MetricsConfig       `yaml:"metrics"`
+	Shutdown      ShutdownConfig      `yaml:"shutdown"`
+	CORS          CORSConfig          `yaml:"cors"`
+	RateLimits    RateLimitConfig     `yaml:"rate_limits"`
+	Access        AccessConfig        `yaml:"access"`
+	Costs         CostsConfig         `yaml:"costs"`
+	NodePools     NodePoolsConfig     `yaml:"node_pools"`
+
+	// sources maps the path of each setting not left at its default to where
+	// it was read: "config.yaml:12", "-port" or "$DATA_DIR".
+	sources map[string]string
+}
+
+// ListenConfig is where the server listens and keeps its files.
+type ListenConfig struct {
+	Port        string `yaml:"port" flag:"port" usage:"Port to listen on"`
+	MetricsPort string `yaml:"metrics_port" flag:"metrics-port" usage:"Port to serve /metrics on instead of the main port"`
+	PublicURL   string `yaml:"public_url" flag:"public-url" env:"PUBLIC_URL" usage:"URL the server is reached at, for the links in webhook payloads"`
+	WorkDir     string `yaml:"work_dir" flag:"work-dir" env:"WORK_DIR" usage:"Directory for job workspaces"`
+	DataDir     string `yaml:"data_dir" flag:"data-dir" env:"DATA_DIR" usage:"Directory for persisting job data"`
+	OVHEndpoint string `yaml:"ovh_endpoint" flag:"ovh-endpoint" env:"OVH_DEFAULT_ENDPOINT" usage:"OVH API endpoint used when the credentials form leaves it empty"`
+	WebDir      string `yaml:"web_dir" flag:"web-dir" usage:"Serve the web UI from this directory instead of the copy built into the binary, for development"`
+	JobIDs      string `yaml:"job_ids" flag:"job-ids" env:"JOB_IDS" usage:"How new jobs are named: uuid (job-<uuid>) or sequential (lab-0001, lab-0002, ...)"`
+	// Pulumi plugins, stack state and Helm charts fill the work directory fast;
+	// below this, /health/ready reports the server not ready.
+	MinFreeDiskMB int `yaml:"min_free_disk_mb" flag:"min-free-disk-mb" env:"MIN_FREE_DISK_MB" usage:"Free space, in MB, /health/ready wants in the data and work directories"`
+	// Lets the UI come up on a machine without Pulumi (e.g. to browse labs);
+	// deployments will still fail until the CLI is installed.
+	LenientPulumiCheck bool `yaml:"lenient_pulumi_check" flag:"lenient-pulumi-check" usage:"Only warn, instead of exiting, when the Pulumi CLI is missing or too old"`
+}
+
+// TLSSettings is how the server serves HTTPS; see tls.go.
+type TLSSettings struct {
+	Cert             string `yaml:"cert" flag:"tls-cert" env:"TLS_CERT_FILE" usage:"PEM certificate to serve HTTPS with, reloaded on SIGHUP"`
+	Key              string `yaml:"key" flag:"tls-key" env:"TLS_KEY_FILE" usage:"PEM private key of -tls-cert"`
+	SelfSigned       bool   `yaml:"self_signed" flag:"tls-self-signed" usage:"Serve HTTPS with a self-signed certificate kept in the data directory"`
+	HTTPRedirectPort string `yaml:"http_redirect_port" flag:"http-redirect-port" usage:"Port answering plain HTTP with a redirect to HTTPS (needs TLS)"`
+}
+
+// Enabled reports whether the server serves HTTPS.
+func (t TLSSettings) Enabled() bool {
+	return t.Cert != "" || t.SelfSigned
+}
+
+// LoggingConfig is what the server logs and how; see logging.go.
+type LoggingConfig struct {
+	Level  string `yaml:"level" flag:"log-level" env:"LOG_LEVEL" usage:"Lowest level logged: debug, info, warn or error"`
+	Format string `yaml:"format" flag:"log-format" env:"LOG_FORMAT" usage:"Log format: text or json"`
+}
+
+// AuthConfig is how admins and students log in.
+type AuthConfig struct {
+	AdminPassword       string `yaml:"admin_password" env:"LAB_ADMIN_PASSWORD" secret:"true"`
+	StudentPassword     string `yaml:"student_password" env:"LAB_STUDENT_PASSWORD" secret:"true"`
+	SessionStore        string `yaml:"session_store" env:"SESSION_STORE"`
+	SessionRedisURL     string `yaml:"session_redis_url" env:"SESSION_REDIS_URL" secret:"true"`
+	ShareLinkSecret     string `yaml:"share_link_secret" env:"SHARE_LINK_SECRET" secret:"true"`
+	AzureADClientID     string `yaml:"azure_ad_client_id" env:"AZURE_AD_CLIENT_ID"`
+	AzureADClientSecret string `yaml:"azure_ad_client_secret" env:"AZURE_AD_CLIENT_SECRET" secret:"true"`
+	AzureADTenantID     string `yaml:"azure_ad_tenant_id" env:"AZURE_AD_TENANT_ID"`
+	AzureADAdminGroupID string `yaml:"azure_ad_admin_group_id" env:"AZURE_AD_ADMIN_GROUP_ID"`
+}
+
+// DeployConfig is how failed deployments are retried and rolled back; see
+// deploy_retry.go and deploy_rollback.go.
+type DeployConfig struct {
+	Retries           int  `yaml:"retries" env:"DEPLOY_RETRIES"`
+	RetryDelaySeconds int  `yaml:"retry_delay_seconds" env:"DEPLOY_RETRY_DELAY_SECONDS"`
+	RollbackOnFailure bool `yaml:"rollback_on_failure" env:"DEPLOY_ROLLBACK_ON_FAILURE"`
+}
+
+// CleanupConfig is how often expired workspaces are deleted; see cleanup.go.
+type CleanupConfig struct {
+	IntervalMinutes          int `yaml:"interval_minutes" env:"CLEANUP_INTERVAL_MINUTES"`
+	DeleteMaxRetries         int `yaml:"delete_max_retries" env:"CLEANUP_DELETE_MAX_RETRIES"`
+	DeleteRetryIntervalHours int `yaml:"delete_retry_interval_hours" env:"CLEANUP_DELETE_RETRY_INTERVAL_HOURS"`
+}
+
+// NotificationsConfig is who is told about lab status changes; see
+// webhooks.go and notifications.go. Webhook URLs often carry a token, so they
+// are secrets.
+type NotificationsConfig struct {
+	WebhookURLs       []string `yaml:"webhook_urls" flag:"webhook-url" env:"WEBHOOK_URLS" secret:"true" usage:"Comma-separated URLs notified when a lab completes, fails or is destroyed"`
+	WebhookSecret     string   `yaml:"webhook_secret" env:"WEBHOOK_SECRET" secret:"true"`
+	SlackWebhookURL   string   `yaml:"slack_webhook_url" env:"SLACK_WEBHOOK_URL" secret:"true"`
+	DiscordWebhookURL string   `yaml:"discord_webhook_url" env:"DISCORD_WEBHOOK_URL" secret:"true"`
+	LogOnly           bool     `yaml:"log_only" env:"NOTIFY_LOG_ONLY"`
+}
+
+// MetricsConfig protects /metrics; see metrics.go.
+type MetricsConfig struct {
+	Token string `yaml:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+// ShutdownConfig is how the server stops; see drain.go and lifecycle.go.
+type ShutdownConfig struct {
+	// ReadinessDelay is how long /readyz fails before the server stops taking
+	// requests, for load balancers to stop sending any.
+	ReadinessDelay string `yaml:"readiness_delay" flag:"readiness-delay" env:"READINESS_DELAY" usage:"How long a shutdown reports the server not ready before it stops taking requests, e.g. 10s"`
+	DrainTimeout   string `yaml:"drain_timeout" flag:"drain-timeout" env:"DRAIN_TIMEOUT" usage:"How long a shutdown waits for running Pulumi jobs before interrupting them, e.g. 5m (0 interrupts them at once)"`
+}
+
+// ReadinessDelayDuration returns ReadinessDelay, which Validate checked.
+func (s ShutdownConfig) ReadinessDelayDuration() time.Duration {
+	d, _ := time.ParseDuration(s.ReadinessDelay)
+	return d
+}
+
+// DrainTimeoutDuration returns DrainTimeout, which Validate checked.
+func (s ShutdownConfig) DrainTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(s.DrainTimeout)
+	return d
+}
+
+// CORSConfig is which other sites' pages may call the API; see
+// http_methods.go.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" flag:"cors-allowed-origins" env:"CORS_ALLOWED_ORIGINS" usage:"Comma-separated origins, e.g. https://portal.example.com or https://*.example.com, whose pages may call /api/ with the user's session"`
+}
+
+// RateLimitConfig is how many requests a client may send per minute to the
+// expensive endpoints; see rate_limit.go. 0 lifts the limit.
+type RateLimitConfig struct {
+	StudentPerMinute   int `yaml:"student_per_minute" env:"RATE_LIMIT_STUDENT_PER_MINUTE"`
+	AdminJobsPerMinute int `yaml:"admin_jobs_per_minute" env:"RATE_LIMIT_ADMIN_JOBS_PER_MINUTE"`
+	LoginPerMinute     int `yaml:"login_per_minute" env:"RATE_LIMIT_LOGIN_PER_MINUTE"`
+}
+
+// AccessConfig is which networks may reach the admin and student routes; see
+// ip_allowlist.go. An empty list lets everyone through.
+type AccessConfig struct {
+	AdminAllowedCIDRs   []string `yaml:"admin_allowed_cidrs" env:"ADMIN_ALLOWED_CIDRS"`
+	StudentAllowedCIDRs []string `yaml:"student_allowed_cidrs" env:"STUDENT_ALLOWED_CIDRS"`
+	// TrustedProxyCIDRs are the reverse proxies whose X-Forwarded-For is
+	// believed. Without them only the connection's address is looked at.
+	TrustedProxyCIDRs []string `yaml:"trusted_proxy_cidrs" env:"TRUSTED_PROXY_CIDRS"`
+}
+
+// CostsConfig is the admin's price table for the lab cost estimates; see
+// lab_cost.go. FlavorPrices are flavor=price pairs, the hourly price of a node
+// (e.g. b3-8=0.0977); flavors left out are priced from the OVH catalog.
+type CostsConfig struct {
+	FlavorPrices []string `yaml:"flavor_prices" env:"FLAVOR_PRICES"`
+	Currency     string   `yaml:"currency" env:"FLAVOR_PRICES_CURRENCY"`
+}
+
+// NodePoolsConfig bounds the node counts a lab may ask for, so that a typo in
+// a form does not create a cluster without nodes or a 90-node bill on a
+// shared OVH account; see node_count_limits.go.
+type NodePoolsConfig struct {
+	MinNodes int `yaml:"min_nodes" env:"NODEPOOL_MIN_NODES"`
+	MaxNodes int `yaml:"max_nodes" env:"NODEPOOL_MAX_NODES"`
+}
+
+// maskedSecret replaces the secrets printed by -print-config.
+const maskedSecret = "********"
+
+// DefaultServerConfig returns the settings used when nothing sets them.
+func DefaultServerConfig() *ServerConfig {
+	return &ServerConfig{
+		Server: ListenConfig{
+			Port:          "8081",
+			WorkDir:       utils.DEFAULT_WORK_DIR,
+			DataDir:       utils.DEFAULT_DATA_DIR,
+			OVHEndpoint:   DefaultOVHEndpoint,
+			JobIDs:        JobIDsUUID,
+			MinFreeDiskMB: 1024,
+		},
+		Logging: LoggingConfig{Level: "info", Format: "text"},
+		Auth:    AuthConfig{SessionStore: SessionStoreMemory},
+		Deploy:  DeployConfig{Retries: 1, RetryDelaySeconds: 60},
+		Cleanup: CleanupConfig{IntervalMinutes: 5, DeleteMaxRetries: 3, DeleteRetryIntervalHours: 2},
+		// Long enough for most deployments to finish; the container's stop
+		// grace period must be longer (see docker-compose.yml and the chart).
+		Shutdown: ShutdownConfig{ReadinessDelay: "5s", DrainTimeout: "5m"},
+		// A student dashboard polls the status endpoints, which are not
+		// limited; 30 requests a minute is far more than a student clicks.
+		// Logins are limited by address, which a whole classroom may share.
+		RateLimits: RateLimitConfig{StudentPerMinute: 30, AdminJobsPerMinute: 10, LoginPerMinute: 120},
+		Costs:      CostsConfig{Currency: "EUR"},
+		NodePools:  NodePoolsConfig{MinNodes: 0, MaxNodes: ovhNodePoolMaxNodes},
+	}
+}
+
+// configField is one setting of a ServerConfig.
+type configField struct {
+	path   string // section and key, e.g. "deploy.retries"
+	flag   string
+	env    string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+// fields lists the settings of c, in declaration order.
+func (c *ServerConfig) fields() []configField {
+	var out []configField
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := sf.Tag.Get("yaml")
+			if key == "" {
+				continue
+			}
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), prefix+key+".")
+				continue
+			}
+			out = append(out, configField{
+				path:   prefix + key,
+				flag:   sf.Tag.Get("flag"),
+				env:    sf.Tag.Get("env"),
+				usage:  sf.Tag.Get("usage"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return out
+}
+
+// set parses s into the setting. A list is comma- or newline-separated.
+func (f configField) set(s string) error {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(strings.TrimSpace(s))
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("want a whole number, got %q", s)
+		}
+		f.value.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("want true or false, got %q", s)
+		}
+		f.value.SetBool(b)
+	case reflect.Slice:
+		f.value.Set(reflect.ValueOf(splitWebhookURLs(s)))
+	}
+	return nil
+}
+
+// RegisterConfigFlags defines the flags of the settings that have one on fs,
+// with their defaults for -help. LoadServerConfig reads those set on the
+// command line.
+func RegisterConfigFlags(fs *flag.FlagSet) {
+	for _, f := range DefaultServerConfig().fields() {
+		if f.flag == "" {
+			continue
+		}
+		usage := f.usage
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		switch f.value.Kind() {
+		case reflect.Bool:
+			fs.Bool(f.flag, f.value.Bool(), usage)
+		case reflect.Int:
+			fs.Int(f.flag, int(f.value.Int()), usage)
+		case reflect.Slice:
+			fs.String(f.flag, strings.Join(f.value.Interface().([]string), ","), usage)
+		default:
+			fs.String(f.flag, f.value.String(), usage)
+		}
+	}
+}
+
+// LoadServerConfig returns the configuration read from the YAML file at path
+// (none when empty), the flags set on fs and the environment read with getenv,
+// over the defaults. It does not validate it; see Validate.
+func LoadServerConfig(path string, fs *flag.FlagSet, getenv func(string) string) (*ServerConfig, error) {
+	c := DefaultServerConfig()
+	c.sources = make(map[string]string)
+	fields := c.fields()
+
+	if path != "" {
+		if err := c.loadFile(path, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	if fs != nil {
+		byFlag := make(map[string]configField)
+		for _, f := range fields {
+			if f.flag != "" {
+				byFlag[f.flag] = f
+			}
+		}
+		fs.Visit(func(fl *flag.Flag) {
+			f, ok := byFlag[fl.Name]
+			if !ok {
+				return
+			}
+			if err := f.set(fl.Value.String()); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", fl.Name, err))
+				return
+			}
+			c.sources[f.path] = "-" + fl.Name
+		})
+	}
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		v := getenv(f.env)
+		if v == "" {
+			continue
+		}
+		if err := f.set(v); err != nil {
+			errs = append(errs, fmt.Errorf("$%s: %w", f.env, err))
+			continue
+		}
+		c.sources[f.path] = "$" + f.env
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return c, nil
+}
+
+// loadFile applies the settings of the YAML file at path. Errors name the
+// line: an unknown key is an error rather than a silently ignored typo.
+func (c *ServerConfig) loadFile(path string, fields []configField) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// "yaml: line 3: did not find expected key" -> "config.yaml:3: did not ..."
+		msg := strings.TrimPrefix(err.Error(), "yaml: ")
+		if rest, ok := strings.CutPrefix(msg, "line "); ok {
+			if line, detail, ok := strings.Cut(rest, ": "); ok {
+				return fmt.Errorf("%s:%s: %s", path, line, detail)
+			}
+		}
+		return fmt.Errorf("%s: %s", path, msg)
+	}
+	if len(doc.Content) == 0 {
+		return nil // empty file
+	}
+
+	byPath := make(map[string]configField, len(fields))
+	sections := make(map[string]bool)
+	for _, f := range fields {
+		byPath[f.path] = f
+		section, _, _ := strings.Cut(f.path, ".")
+		sections[section] = true
+	}
+
+	var errs []error
+	at := func(n *yaml.Node) string { return fmt.Sprintf("%s:%d", path, n.Line) }
+	var walk func(n *yaml.Node, prefix string)
+	walk = func(n *yaml.Node, prefix string) {
+		if n.Kind != yaml.MappingNode {
+			name := strings.TrimSuffix(prefix, ".")
+			if name == "" {
+				name = "top level"
+			}
+			errs = append(errs, fmt.Errorf("%s: %s: want a mapping of settings", at(n), name))
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			p := prefix + key.Value
+			if prefix == "" && sections[p] {
+				walk(value, p+".")
+				continue
+			}
+			f, ok := byPath[p]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown setting %s", at(key), p))
+				continue
+			}
+			if err := setFromNode(f, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", at(value), p, err))
+				continue
+			}
+			c.sources[p] = at(value)
+		}
+	}
+	walk(doc.Content[0], "")
+	return errors.Join(errs...)
+}
+
+// setFromNode sets f from a YAML value: a scalar, or a sequence of scalars for
+// a list. A null value leaves the setting alone.
+func setFromNode(f configField, n *yaml.Node) error {
+	switch {
+	case n.Kind == yaml.ScalarNode && n.Tag == "!!null":
+		return nil
+	case n.Kind == yaml.ScalarNode:
+		return f.set(n.Value)
+	case n.Kind == yaml.SequenceNode && f.value.Kind() == reflect.Slice:
+		items := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			if item.Kind != yaml.ScalarNode {
+				return errors.New("want a list of strings")
+			}
+			items = append(items, item.Value)
+		}
+		return f.set(strings.Join(items, ","))
+	case f.value.Kind() == reflect.Slice:
+		return errors.New("want a string or a list of strings")
+	default:
+		return errors.New("want a single value")
+	}
+}
+
+// source is where the setting at path was read, or "" for its default.
+func (c *ServerConfig) source(path string) string {
+	return c.sources[path]
+}
+
+// invalid is a validation error for the setting at path, prefixed with where
+// it was read.
+func (c *ServerConfig) invalid(path, format string, args ...any) error {
+	msg := path + ": " + fmt.Sprintf(format, args...)
+	if src := c.source(path); src != "" {
+		msg = src + ": " + msg
+	}
+	return errors.New(msg)
+}
+
+// Validate checks every setting and returns all the problems found, each
+// naming the file line, flag or environment variable it came from.
+func (c *ServerConfig) Validate() error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	check(c.validatePort("server.port", c.Server.Port, true))
+	check(c.validatePort("server.metrics_port", c.Server.MetricsPort, false))
+	check(c.validatePort("tls.http_redirect_port", c.TLS.HTTPRedirectPort, false))
+	if c.Server.WorkDir == "" {
+		check(c.invalid("server.work_dir", "is required"))
+	}
+	if c.Server.DataDir == "" {
+		check(c.invalid("server.data_dir", "is required"))
+	}
+	if c.Server.JobIDs != JobIDsUUID && c.Server.JobIDs != JobIDsSequential {
+		check(c.invalid("server.job_ids", "unknown scheme %q (want %s or %s)", c.Server.JobIDs, JobIDsUUID, JobIDsSequential))
+	}
+	if c.Server.MinFreeDiskMB < 0 {
+		check(c.invalid("server.min_free_disk_mb", "must not be negative"))
+	}
+	if c.Server.PublicURL != "" {
+		if u, err := url.Parse(c.Server.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			check(c.invalid("server.public_url", "want an http or https URL, got %q", c.Server.PublicURL))
+		}
+	}
+
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		path := "tls.cert"
+		if c.TLS.Cert != "" {
+			path = "tls.key"
+		}
+		check(c.invalid(path, "tls.cert and tls.key must be set together"))
+	}
+	if c.TLS.SelfSigned && c.TLS.Cert != "" {
+		check(c.invalid("tls.self_signed", "cannot be combined with tls.cert"))
+	}
+	if c.TLS.HTTPRedirectPort != "" && !c.TLS.Enabled() {
+		check(c.invalid("tls.http_redirect_port", "needs tls.cert or tls.self_signed"))
+	}
+
+	if _, err := ParseLogLevel(c.Logging.Level); err != nil {
+		check(c.invalid("logging.level", "%v", err))
+	}
+	if _, err := NewLogHandler(io.Discard, 0, c.Logging.Format); err != nil {
+		check(c.invalid("logging.format", "%v", err))
+	}
+
+	if c.Auth.AdminPassword == "" {
+		check(c.invalid("auth.admin_password", "is required (or set %s)", EnvAdminPassword))
+	}
+	switch strings.ToLower(c.Auth.SessionStore) {
+	case "", SessionStoreMemory, SessionStoreFile:
+	case SessionStoreRedis:
+		if c.Auth.SessionRedisURL == "" {
+			check(c.invalid("auth.session_redis_url", "is required when auth.session_store is %s", SessionStoreRedis))
+		} else if _, err := redis.ParseURL(c.Auth.SessionRedisURL); err != nil {
+			check(c.invalid("auth.session_redis_url", "%v", err))
+		}
+	default:
+		check(c.invalid("auth.session_store", "unknown store %q (want %s, %s or %s)", c.Auth.SessionStore, SessionStoreMemory, SessionStoreFile, SessionStoreRedis))
+	}
+
+	if c.Deploy.Retries < 0 || c.Deploy.Retries > maxDeployRetries {
+		check(c.invalid("deploy.retries", "must be between 0 and %d", maxDeployRetries))
+	}
+	if c.Deploy.RetryDelaySeconds < 0 {
+		check(c.invalid("deploy.retry_delay_seconds", "must not be negative"))
+	}
+	for path, v := range map[string]int{
+		"cleanup.interval_minutes":            c.Cleanup.IntervalMinutes,
+		"cleanup.delete_max_retries":          c.Cleanup.DeleteMaxRetries,
+		"cleanup.delete_retry_interval_hours": c.Cleanup.DeleteRetryIntervalHours,
+	} {
+		if v < 1 {
+			check(c.invalid(path, "must be at least 1"))
+		}
+	}
+
+	if err := validateWebhookURLs(c.Notifications.WebhookURLs); err != nil {
+		check(c.invalid("notifications.webhook_urls", "%v", err))
+	}
+	for path, u := range map[string]string{
+		"notifications.slack_webhook_url":   c.Notifications.SlackWebhookURL,
+		"notifications.discord_webhook_url": c.Notifications.DiscordWebhookURL,
+	} {
+		if u == "" {
+			continue
+		}
+		if err := validateWebhookURLs([]string{u}); err != nil {
+			check(c.invalid(path, "%v", err))
+		}
+	}
+
+	for path, value := range map[string]string{
+		"shutdown.readiness_delay": c.Shutdown.ReadinessDelay,
+		"shutdown.drain_timeout":   c.Shutdown.DrainTimeout,
+	} {
+		if d, err := time.ParseDuration(value); err != nil {
+			check(c.invalid(path, "want a duration such as 5m or 90s, got %q", value))
+		} else if d < 0 {
+			check(c.invalid(path, "must not be negative"))
+		}
+	}
+
+	for path, v := range map[string]int{
+		"rate_limits.student_per_minute":    c.RateLimits.StudentPerMinute,
+		"rate_limits.admin_jobs_per_minute": c.RateLimits.AdminJobsPerMinute,
+		"rate_limits.login_per_minute":      c.RateLimits.LoginPerMinute,
+	} {
+		if v < 0 {
+			check(c.invalid(path, "must not be negative (0 lifts the limit)"))
+		}
+	}
+
+	if _, err := NewCORSPolicy(c.CORS.AllowedOrigins); err != nil {
+		check(c.invalid("cors.allowed_origins", "%v", err))
+	}
+
+	for path, list := range map[string][]string{
+		"access.admin_allowed_cidrs":   c.Access.AdminAllowedCIDRs,
+		"access.student_allowed_cidrs": c.Access.StudentAllowedCIDRs,
+		"access.trusted_proxy_cidrs":   c.Access.TrustedProxyCIDRs,
+	} {
+		if _, err := parsePrefixes(list); err != nil {
+			check(c.invalid(path, "%v", err))
+		}
+	}
+
+	if _, err := ParseFlavorPrices(c.Costs.FlavorPrices, c.Costs.Currency); err != nil {
+		check(c.invalid("costs.flavor_prices", "%v", err))
+	}
+
+	if c.NodePools.MaxNodes < 1 || c.NodePools.MaxNodes > ovhNodePoolMaxNodes {
+		check(c.invalid("node_pools.max_nodes", "must be between 1 and %d, the OVH limit", ovhNodePoolMaxNodes))
+	}
+	if c.NodePools.MinNodes < 0 {
+		check(c.invalid("node_pools.min_nodes", "must not be negative"))
+	} else if c.NodePools.MinNodes > c.NodePools.MaxNodes {
+		check(c.invalid("node_pools.min_nodes", "must not be above node_pools.max_nodes (%d)", c.NodePools.MaxNodes))
+	}
+
+	// The maps above are iterated in random order; keep the report stable.
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// validatePort checks that the setting at path is a TCP port number.
+func (c *ServerConfig) validatePort(path, port string, required bool) error {
+	if port == "" {
+		if required {
+			return c.invalid(path, "is required")
+		}
+		return nil
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return c.invalid(path, "want a port number between 1 and 65535, got %q", port)
+	}
+	return nil
+}
+
+// WriteYAML writes the configuration as a config file, with secrets masked and
+// each setting not left at its default commented with where it was read.
+func (c *ServerConfig) WriteYAML(w io.Writer) error {
+	masked := *c
+	for _, f := range masked.fields() {
+		if !f.secret || f.value.IsZero() {
+			continue
+		}
+		if f.value.Kind() == reflect.Slice {
+			f.value.Set(reflect.ValueOf([]string{maskedSecret}))
+		} else {
+			f.value.SetString(maskedSecret)
+		}
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(&masked); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		section := doc.Content[i].Value
+		settings := doc.Content[i+1]
+		for j := 0; j+1 < len(settings.Content); j += 2 {
+			src := c.source(section + "." + settings.Content[j].Value)
+			if src == "" {
+				continue
+			}
+			// A list's comment goes after its key, not its last item.
+			if value := settings.Content[j+1]; value.Kind == yaml.ScalarNode {
+				value.LineComment = "from " + src
+			} else {
+				settings.Content[j].LineComment = "from " + src
+			}
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return enc.Close()
+}
@@ -0,0 +1,350 @@
+package server
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigFile writes a config file in a temporary directory.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// configFlags returns the config flags parsed from args.
+func configFlags(t *testing.T, args ...string) *flag.FlagSet {
+	t.Helper()
+	fs := flag.NewFlagSet("easylab", flag.ContinueOnError)
+	RegisterConfigFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return fs
+}
+
+// envMap is a getenv reading from m.
+func envMap(m map[string]string) func(string) string {
+	return func(key string) string { return m[key] }
+}
+
+func TestLoadServerConfig_Defaults(t *testing.T) {
+	c, err := LoadServerConfig("", configFlags(t), envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "8081", c.Server.Port)
+	assert.Equal(t, DefaultOVHEndpoint, c.Server.OVHEndpoint)
+	assert.Equal(t, DeployConfig{Retries: 1, RetryDelaySeconds: 60}, c.Deploy)
+	assert.Equal(t, CleanupConfig{IntervalMinutes: 5, DeleteMaxRetries: 3, DeleteRetryIntervalHours: 2}, c.Cleanup)
+}
+
+func TestLoadServerConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  port: "9000"
+  data_dir: /srv/data
+  work_dir: /srv/jobs
+deploy:
+  retries: 2
+  retry_delay_seconds: 30
+`)
+	fs := configFlags(t, "-port", "9100", "-data-dir", "/flag/data")
+	c, err := LoadServerConfig(path, fs, envMap(map[string]string{
+		"DATA_DIR":                   "/env/data",
+		"DEPLOY_RETRIES":             "4",
+		"DEPLOY_ROLLBACK_ON_FAILURE": "true",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "9100", c.Server.Port, "a flag wins over the file")
+	assert.Equal(t, "/env/data", c.Server.DataDir, "the environment wins over flags")
+	assert.Equal(t, "/srv/jobs", c.Server.WorkDir, "the file wins over defaults")
+	assert.Equal(t, 4, c.Deploy.Retries)
+	assert.Equal(t, 30, c.Deploy.RetryDelaySeconds)
+	assert.True(t, c.Deploy.RollbackOnFailure)
+
+	assert.Equal(t, "-port", c.source("server.port"))
+	assert.Equal(t, "$DATA_DIR", c.source("server.data_dir"))
+	assert.Equal(t, path+":5", c.source("server.work_dir"))
+	assert.Empty(t, c.source("server.ovh_endpoint"))
+}
+
+func TestLoadServerConfig_Lists(t *testing.T) {
+	path := writeConfigFile(t, `
+notifications:
+  webhook_urls:
+    - https://hooks.example.com/a
+    - https://hooks.example.com/b
+`)
+	c, err := LoadServerConfig(path, configFlags(t), envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://hooks.example.com/a", "https://hooks.example.com/b"}, c.Notifications.WebhookURLs)
+
+	c, err = LoadServerConfig(path, configFlags(t), envMap(map[string]string{"WEBHOOK_URLS": "https://x.example.com, https://y.example.com"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://x.example.com", "https://y.example.com"}, c.Notifications.WebhookURLs)
+}
+
+func TestLoadServerConfig_FileErrorsNameTheLine(t *testing.T) {
+	path := writeConfigFile(t, `server:
+  port: "9000"
+deploy:
+  retires: 2
+  retry_delay_seconds: soon
+colour: blue
+tls:
+  self_signed: maybe
+`)
+	_, err := LoadServerConfig(path, configFlags(t), envMap(nil))
+	require.Error(t, err)
+	lines := strings.Split(err.Error(), "\n")
+	assert.Equal(t, []string{
+		path + ":4: unknown setting deploy.retires",
+		path + `:5: deploy.retry_delay_seconds: want a whole number, got "soon"`,
+		path + ":6: unknown setting colour",
+		path + `:8: tls.self_signed: want true or false, got "maybe"`,
+	}, lines)
+}
+
+func TestLoadServerConfig_SyntaxError(t *testing.T) {
+	path := writeConfigFile(t, "server:\n  port: [\n")
+	_, err := LoadServerConfig(path, configFlags(t), envMap(nil))
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), path+":"), err.Error())
+
+	path = writeConfigFile(t, "server: 8081\n")
+	_, err = LoadServerConfig(path, configFlags(t), envMap(nil))
+	assert.EqualError(t, err, path+":1: server: want a mapping of settings")
+}
+
+func TestLoadServerConfig_EmptyFile(t *testing.T) {
+	c, err := LoadServerConfig(writeConfigFile(t, "# nothing set\n"), configFlags(t), envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "8081", c.Server.Port)
+}
+
+func TestLoadServerConfig_BadEnvAndFlags(t *testing.T) {
+	_, err := LoadServerConfig("", configFlags(t), envMap(map[string]string{"CLEANUP_INTERVAL_MINUTES": "often"}))
+	assert.EqualError(t, err, `$CLEANUP_INTERVAL_MINUTES: want a whole number, got "often"`)
+
+	_, err = LoadServerConfig("/nonexistent/config.yaml", configFlags(t), envMap(nil))
+	assert.ErrorContains(t, err, "failed to read config file")
+}
+
+// validConfig returns a configuration that passes Validate.
+func validConfig() *ServerConfig {
+	c := DefaultServerConfig()
+	c.Auth.AdminPassword = "admin"
+	return c
+}
+
+func TestServerConfig_Validate(t *testing.T) {
+	require.NoError(t, validConfig().Validate())
+
+	tests := []struct {
+		name   string
+		modify func(c *ServerConfig)
+		want   string
+	}{
+		{"no admin password", func(c *ServerConfig) { c.Auth.AdminPassword = "" }, "auth.admin_password: is required"},
+		{"bad port", func(c *ServerConfig) { c.Server.Port = "http" }, "server.port: want a port number"},
+		{"bad public URL", func(c *ServerConfig) { c.Server.PublicURL = "easylab.example.com" }, "server.public_url: want an http or https URL"},
+		{"cert without key", func(c *ServerConfig) { c.TLS.Cert = "tls.crt" }, "tls.key: tls.cert and tls.key must be set together"},
+		{"key without cert", func(c *ServerConfig) { c.TLS.Key = "tls.key" }, "tls.cert: tls.cert and tls.key must be set together"},
+		{"self-signed and cert", func(c *ServerConfig) { c.TLS.Cert, c.TLS.Key, c.TLS.SelfSigned = "tls.crt", "tls.key", true }, "tls.self_signed: cannot be combined with tls.cert"},
+		{"redirect without TLS", func(c *ServerConfig) { c.TLS.HTTPRedirectPort = "80" }, "tls.http_redirect_port: needs tls.cert or tls.self_signed"},
+		{"log level", func(c *ServerConfig) { c.Logging.Level = "verbose" }, "logging.level: invalid log level"},
+		{"log format", func(c *ServerConfig) { c.Logging.Format = "xml" }, "logging.format: invalid log format"},
+		{"session store", func(c *ServerConfig) { c.Auth.SessionStore = "etcd" }, `auth.session_store: unknown store "etcd"`},
+		{"redis without URL", func(c *ServerConfig) { c.Auth.SessionStore = SessionStoreRedis }, "auth.session_redis_url: is required"},
+		{"too many retries", func(c *ServerConfig) { c.Deploy.Retries = maxDeployRetries + 1 }, "deploy.retries: must be between 0 and 5"},
+		{"no cleanup interval", func(c *ServerConfig) { c.Cleanup.IntervalMinutes = 0 }, "cleanup.interval_minutes: must be at least 1"},
+		{"bad webhook", func(c *ServerConfig) { c.Notifications.WebhookURLs = []string{"ftp://x"} }, "notifications.webhook_urls: invalid webhook URL"},
+		{"bad slack webhook", func(c *ServerConfig) { c.Notifications.SlackWebhookURL = "hooks.slack.com" }, "notifications.slack_webhook_url: invalid webhook URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+			assert.ErrorContains(t, c.Validate(), tt.want)
+		})
+	}
+
+	c := validConfig()
+	c.TLS.SelfSigned, c.TLS.HTTPRedirectPort = true, "80"
+	assert.NoError(t, c.Validate(), "self-signed TLS with a redirect")
+}
+
+func TestServerConfig_ValidateNamesTheSource(t *testing.T) {
+	path := writeConfigFile(t, `
+auth:
+  admin_password: admin
+deploy:
+  retries: 9
+cleanup:
+  interval_minutes: 0
+`)
+	c, err := LoadServerConfig(path, configFlags(t, "-port", "0"), envMap(map[string]string{"LOG_LEVEL": "loud"}))
+	require.NoError(t, err)
+	err = c.Validate()
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		"$LOG_LEVEL: logging.level: invalid log level \"loud\": want debug, info, warn or error",
+		`-port: server.port: want a port number between 1 and 65535, got "0"`,
+		path + ":5: deploy.retries: must be between 0 and 5",
+		path + ":7: cleanup.interval_minutes: must be at least 1",
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestServerConfig_WriteYAMLMasksSecrets(t *testing.T) {
+	path := writeConfigFile(t, `
+auth:
+  admin_password: s3cret-admin
+notifications:
+  webhook_urls: https://hooks.example.com/T0?token=s3cret-hook
+`)
+	c, err := LoadServerConfig(path, configFlags(t, "-port", "9000"), envMap(map[string]string{"METRICS_TOKEN": "s3cret-metrics"}))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, c.WriteYAML(&buf))
+	out := buf.String()
+	assert.NotContains(t, out, "s3cret")
+	assert.Contains(t, out, "admin_password: '********' # from "+path+":3")
+	assert.Contains(t, out, "token: '********' # from $METRICS_TOKEN")
+	assert.Contains(t, out, `port: "9000" # from -port`)
+	assert.Contains(t, out, `student_password: ""`, "unset secrets are shown as unset")
+	assert.Equal(t, "s3cret-admin", c.Auth.AdminPassword, "masking works on a copy")
+	assert.Equal(t, []string{"https://hooks.example.com/T0?token=s3cret-hook"}, c.Notifications.WebhookURLs)
+
+	// The output is a config file that loads back.
+	reloaded, err := LoadServerConfig(writeConfigFile(t, out), configFlags(t), envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "9000", reloaded.Server.Port)
+}
+
+func TestRegisterConfigFlags(t *testing.T) {
+	fs := flag.NewFlagSet("easylab", flag.ContinueOnError)
+	RegisterConfigFlags(fs)
+	assert.Equal(t, "8081", fs.Lookup("port").DefValue)
+	assert.Equal(t, "false", fs.Lookup("tls-self-signed").DefValue)
+	assert.Contains(t, fs.Lookup("data-dir").Usage, "(env DATA_DIR)")
+	assert.Nil(t, fs.Lookup("admin-password"), "secrets have no flag")
+}
+
+func TestServerConfig_DrainTimeout(t *testing.T) {
+	c, err := LoadServerConfig("", configFlags(t, "-drain-timeout", "90s"), envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, c.Shutdown.DrainTimeoutDuration())
+	assert.Equal(t, 5*time.Minute, DefaultServerConfig().Shutdown.DrainTimeoutDuration())
+
+	c = validConfig()
+	c.Shutdown.DrainTimeout = "0"
+	assert.NoError(t, c.Validate(), "0 interrupts running jobs at once")
+	c.Shutdown.DrainTimeout = "soon"
+	assert.ErrorContains(t, c.Validate(), `shutdown.drain_timeout: want a duration such as 5m or 90s, got "soon"`)
+	c.Shutdown.DrainTimeout = "-1m"
+	assert.ErrorContains(t, c.Validate(), "shutdown.drain_timeout: must not be negative")
+}
+
+func TestServerConfig_ReadinessDelay(t *testing.T) {
+	c, err := LoadServerConfig("", configFlags(t, "-readiness-delay", "15s"), envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Second, c.Shutdown.ReadinessDelayDuration())
+	assert.Equal(t, 5*time.Second, DefaultServerConfig().Shutdown.ReadinessDelayDuration())
+
+	c = validConfig()
+	c.Shutdown.ReadinessDelay = "0s"
+	assert.NoError(t, c.Validate())
+	c.Shutdown.ReadinessDelay = "-5s"
+	assert.ErrorContains(t, c.Validate(), "shutdown.readiness_delay: must not be negative")
+}
+
+func TestServerConfig_CORSAllowedOrigins(t *testing.T) {
+	c, err := LoadServerConfig("", configFlags(t), envMap(map[string]string{"CORS_ALLOWED_ORIGINS": "https://portal.example.com, https://*.example.org"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://portal.example.com", "https://*.example.org"}, c.CORS.AllowedOrigins)
+	assert.Empty(t, DefaultServerConfig().CORS.AllowedOrigins, "same-origin only by default")
+
+	c = validConfig()
+	c.CORS.AllowedOrigins = []string{"*"}
+	assert.ErrorContains(t, c.Validate(), `cors.allowed_origins: invalid origin "*"`)
+}
+
+func TestServerConfig_JobIDs(t *testing.T) {
+	c, err := LoadServerConfig("", configFlags(t, "-job-ids", "sequential"), envMap(nil))
+	require.NoError(t, err)
+	assert.Equal(t, JobIDsSequential, c.Server.JobIDs)
+	assert.Equal(t, JobIDsUUID, DefaultServerConfig().Server.JobIDs)
+
+	c = validConfig()
+	c.Server.JobIDs = "pretty"
+	assert.ErrorContains(t, c.Validate(), `server.job_ids: unknown scheme "pretty" (want uuid or sequential)`)
+}
+
+func TestServerConfig_RateLimits(t *testing.T) {
+	c, err := LoadServerConfig("", configFlags(t), envMap(map[string]string{"RATE_LIMIT_STUDENT_PER_MINUTE": "0"}))
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.RateLimits.StudentPerMinute)
+	assert.Equal(t, 10, c.RateLimits.AdminJobsPerMinute)
+	assert.Equal(t, 120, c.RateLimits.LoginPerMinute)
+
+	c = validConfig()
+	c.RateLimits.AdminJobsPerMinute = -1
+	assert.ErrorContains(t, c.Validate(), "rate_limits.admin_jobs_per_minute: must not be negative")
+}
+
+func TestServerConfig_Access(t *testing.T) {
+	path := writeConfigFile(t, `
+access:
+  admin_allowed_cidrs:
+    - 203.0.113.0/24
+    - 198.51.100.7
+`)
+	c, err := LoadServerConfig(path, configFlags(t), envMap(map[string]string{"TRUSTED_PROXY_CIDRS": "10.0.0.0/8"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.0/24", "198.51.100.7"}, c.Access.AdminAllowedCIDRs)
+	assert.Empty(t, c.Access.StudentAllowedCIDRs)
+	assert.Equal(t, []string{"10.0.0.0/8"}, c.Access.TrustedProxyCIDRs)
+
+	c = validConfig()
+	c.sources = map[string]string{"access.student_allowed_cidrs": "config.yaml:4"}
+	c.Access.StudentAllowedCIDRs = []string{"10.0.0.0/33"}
+	assert.ErrorContains(t, c.Validate(), `config.yaml:4: access.student_allowed_cidrs: invalid CIDR "10.0.0.0/33"`)
+}
+
+func TestServerConfig_Costs(t *testing.T) {
+	c, err := LoadServerConfig("", configFlags(t), envMap(map[string]string{"FLAVOR_PRICES": "b3-8=0.0977,b3-16=0.1954"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b3-8=0.0977", "b3-16=0.1954"}, c.Costs.FlavorPrices)
+	assert.Equal(t, "EUR", c.Costs.Currency)
+
+	c = validConfig()
+	c.Costs.FlavorPrices = []string{"b3-8=cheap"}
+	assert.ErrorContains(t, c.Validate(), "costs.flavor_prices: invalid price for flavor b3-8")
+}
+
+func TestServerConfig_NodePools(t *testing.T) {
+	c, err := LoadServerConfig("", configFlags(t), envMap(map[string]string{"NODEPOOL_MIN_NODES": "1", "NODEPOOL_MAX_NODES": "10"}))
+	require.NoError(t, err)
+	assert.Equal(t, NodePoolsConfig{MinNodes: 1, MaxNodes: 10}, c.NodePools)
+	assert.Equal(t, NodePoolsConfig{MinNodes: 0, MaxNodes: ovhNodePoolMaxNodes}, DefaultServerConfig().NodePools)
+
+	c = validConfig()
+	c.NodePools.MaxNodes = 500
+	assert.ErrorContains(t, c.Validate(), "node_pools.max_nodes: must be between 1 and 100, the OVH limit")
+
+	c = validConfig()
+	c.NodePools = NodePoolsConfig{MinNodes: 20, MaxNodes: 10}
+	assert.ErrorContains(t, c.Validate(), "node_pools.min_nodes: must not be above node_pools.max_nodes (10)")
+
+	c = validConfig()
+	c.NodePools.MinNodes = -1
+	assert.ErrorContains(t, c.Validate(), "node_pools.min_nodes: must not be negative")
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpDurationBuckets are the upper bounds, in seconds, of the request duration
+// histogram: pages and API calls take milliseconds, a lab creation a few
+// seconds and a streamed log or proxied workspace much longer.
+var httpDurationBuckets = []float64{0.005, 0.025, 0.1, 0.25, 1, 2.5, 10, 60}
+
+// jobDurationBuckets are the upper bounds, in seconds, of the job duration
+// histogram: a dry run takes a minute or two, an OVH lab 10 to 30 minutes.
+var jobDurationBuckets = []float64{60, 300, 600, 900, 1200, 1800, 2700, 3600}
+
+// histogram is a Prometheus histogram without labels of its own.
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // per bucket, not cumulative
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range h.buckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// write writes the series of the histogram, with labels (`a="b",c="d"`) on
+// each.
+func (h *histogram) write(w io.Writer, name, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"%s\"} %d\n", name, labels, sep, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// httpRequestKey identifies a series of easylab_http_requests_total.
+type httpRequestKey struct {
+	route  string
+	method string
+	status int
+}
+
+// serverMetrics are the counters of the server's own activity that /metrics
+// exposes next to the lab costs. Like Prometheus' default registry, there is a
+// single one, so the job manager, the executor and the handlers all feed it.
+type serverMetrics struct {
+	mu                sync.Mutex
+	httpRequests      map[httpRequestKey]uint64
+	httpDurations     map[string]*histogram // by route
+	jobDurations      map[JobStatus]*histogram
+	pulumiRunning     map[string]int // by operation
+	workspaceRequests map[string]uint64
+	loginFailures     map[string]uint64 // by portal
+	sessionsCreated   map[string]uint64 // by portal
+	rateLimited       map[string]uint64 // by route class
+	serverState       string            // see Lifecycle
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		httpRequests:      make(map[httpRequestKey]uint64),
+		httpDurations:     make(map[string]*histogram),
+		jobDurations:      make(map[JobStatus]*histogram),
+		pulumiRunning:     make(map[string]int),
+		workspaceRequests: make(map[string]uint64),
+		loginFailures:     make(map[string]uint64),
+		sessionsCreated:   make(map[string]uint64),
+		rateLimited:       make(map[string]uint64),
+	}
+}
+
+var metrics = newServerMetrics()
+
+func (m *serverMetrics) observeHTTP(route, method string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.httpRequests[httpRequestKey{route, method, status}]++
+	h, ok := m.httpDurations[route]
+	if !ok {
+		h = newHistogram(httpDurationBuckets)
+		m.httpDurations[route] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// observeJob records how long a job ran before ending with status.
+func (m *serverMetrics) observeJob(status JobStatus, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.jobDurations[status]
+	if !ok {
+		h = newHistogram(jobDurationBuckets)
+		m.jobDurations[status] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// trackPulumi counts a Pulumi operation (up, preview, destroy...) as running
+// until the returned function is called.
+func (m *serverMetrics) trackPulumi(operation string) func() {
+	m.mu.Lock()
+	m.pulumiRunning[operation]++
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		m.pulumiRunning[operation]--
+		m.mu.Unlock()
+	}
+}
+
+// countWorkspaceRequest counts a student's workspace request by result:
+// "created", "name_taken", "lab_full" or "failed".
+func (m *serverMetrics) countWorkspaceRequest(result string) {
+	m.mu.Lock()
+	m.workspaceRequests[result]++
+	m.mu.Unlock()
+}
+
+// countLoginFailure counts a refused password login on the "admin" or
+// "student" portal.
+func (m *serverMetrics) countLoginFailure(portal string) {
+	m.mu.Lock()
+	m.loginFailures[portal]++
+	m.mu.Unlock()
+}
+
+// countSession counts a session opened on the "admin" or "student" portal.
+func (m *serverMetrics) countSession(portal string) {
+	m.mu.Lock()
+	m.sessionsCreated[portal]++
+	m.mu.Unlock()
+}
+
+// countRateLimited counts a request refused by the rate limiter, by route
+// class (see rateLimitClass).
+func (m *serverMetrics) countRateLimited(class string) {
+	m.mu.Lock()
+	m.rateLimited[class]++
+	m.mu.Unlock()
+}
+
+// setServerState records the serving state of the server (see Lifecycle).
+func (m *serverMetrics) setServerState(state string) {
+	m.mu.Lock()
+	m.serverState = state
+	m.mu.Unlock()
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeCounters writes a metric with one label and a series per value.
+func writeCounters[V int | uint64](w io.Writer, name, label string, series map[string]V) {
+	for _, k := range slices.Sorted(maps.Keys(series)) {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, label, metricLabelValue(k), series[k])
+	}
+}
+
+// write writes the server metrics in the Prometheus text format. jobs are
+// counted by status at the time of the scrape.
+func (m *serverMetrics) write(w io.Writer, jobs []*Job) {
+	byStatus := make(map[string]int)
+	for _, job := range jobs {
+		job.mu.RLock()
+		byStatus[string(job.Status)]++
+		job.mu.RUnlock()
+	}
+	writeHeader(w, "easylab_jobs", "gauge", "Jobs (labs, dry runs) known to the server, by status.")
+	writeCounters(w, "easylab_jobs", "status", byStatus)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeHeader(w, "easylab_server_state", "gauge", "1 for the state the server is in (starting, serving, draining), 0 for the others.")
+	for _, state := range serverStates {
+		fmt.Fprintf(w, "easylab_server_state{state=\"%s\"} %d\n", state, boolGauge(m.serverState == state))
+	}
+	writeHeader(w, "easylab_server_ready", "gauge", "1 while the server takes new work (/readyz answers 200), 0 otherwise.")
+	fmt.Fprintf(w, "easylab_server_ready %d\n", boolGauge(m.serverState == stateServing))
+
+	writeHeader(w, "easylab_http_requests_total", "counter", "HTTP requests served, by route, method and status.")
+	for _, k := range slices.SortedFunc(maps.Keys(m.httpRequests), func(a, b httpRequestKey) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method), cmp.Compare(a.status, b.status))
+	}) {
+		fmt.Fprintf(w, "easylab_http_requests_total{route=\"%s\",method=\"%s\",status=\"%d\"} %d\n",
+			metricLabelValue(k.route), metricLabelValue(k.method), k.status, m.httpRequests[k])
+	}
+	writeHeader(w, "easylab_http_request_duration_seconds", "histogram", "Time to serve an HTTP request, by route.")
+	for _, route := range slices.Sorted(maps.Keys(m.httpDurations)) {
+		m.httpDurations[route].write(w, "easylab_http_request_duration_seconds", fmt.Sprintf(`route="%s"`, metricLabelValue(route)))
+	}
+
+	writeHeader(w, "easylab_job_duration_seconds", "histogram", "Time from a job starting to run to its end, by final status.")
+	for _, status := range slices.Sorted(maps.Keys(m.jobDurations)) {
+		m.jobDurations[status].write(w, "easylab_job_duration_seconds", fmt.Sprintf(`status="%s"`, metricLabelValue(string(status))))
+	}
+	writeHeader(w, "easylab_pulumi_operations_running", "gauge", "Pulumi operations running now, by operation.")
+	writeCounters(w, "easylab_pulumi_operations_running", "operation", m.pulumiRunning)
+
+	writeHeader(w, "easylab_workspace_requests_total", "counter", "Workspace requests of students, by result.")
+	writeCounters(w, "easylab_workspace_requests_total", "result", m.workspaceRequests)
+	writeHeader(w, "easylab_login_failures_total", "counter", "Refused password logins, by portal.")
+	writeCounters(w, "easylab_login_failures_total", "portal", m.loginFailures)
+	writeHeader(w, "easylab_sessions_created_total", "counter", "Sessions opened, by portal.")
+	writeCounters(w, "easylab_sessions_created_total", "portal", m.sessionsCreated)
+	writeHeader(w, "easylab_rate_limited_requests_total", "counter", "Requests refused with 429 by the rate limiter, by route class.")
+	writeCounters(w, "easylab_rate_limited_requests_total", "class", m.rateLimited)
+}
+
+// boolGauge is the value of a gauge that is true or false.
+func boolGauge(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// statusRecorder keeps the status and size of a response for the request
+// metrics and the access log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the real writer, for the log
+// streams and the workspace proxy.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// InstrumentHTTP counts the requests to next and times them. The route label
+// is the pattern of the mux that served the request, e.g. "/api/labs/", so
+// lab IDs and workspace paths do not each make a series.
+func InstrumentHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		method := r.Method
+		if !slices.Contains(strings.Split(apiAllowedMethods, ", "), method) {
+			method = "OTHER"
+		}
+		metrics.observeHTTP(route, method, status, time.Since(start))
+	})
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramWrite(t *testing.T) {
+	h := newHistogram([]float64{0.1, 1})
+	h.observe(0.05)
+	h.observe(0.5)
+	h.observe(5)
+
+	var b strings.Builder
+	h.write(&b, "x_seconds", `route="/"`)
+	assert.Equal(t, `x_seconds_bucket{route="/",le="0.1"} 1
+x_seconds_bucket{route="/",le="1"} 2
+x_seconds_bucket{route="/",le="+Inf"} 3
+x_seconds_sum{route="/"} 5.55
+x_seconds_count{route="/"} 3
+`, b.String())
+
+	b.Reset()
+	newHistogram([]float64{1}).write(&b, "y", "")
+	assert.Equal(t, "y_bucket{le=\"1\"} 0\ny_bucket{le=\"+Inf\"} 0\ny_sum 0\ny_count 0\n", b.String())
+}
+
+func TestInstrumentHTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/test-metrics/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	handler := InstrumentHTTP(mux)
+
+	for _, path := range []string{"/test-metrics/a", "/test-metrics/b", "/test-metrics/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/test-metrics/a", nil))
+
+	var b strings.Builder
+	metrics.write(&b, nil)
+	out := b.String()
+	assert.Contains(t, out, `easylab_http_requests_total{route="/test-metrics/{id}",method="GET",status="200"} 2`, "one series per route, not per lab")
+	assert.Contains(t, out, `easylab_http_requests_total{route="/test-metrics/{id}",method="GET",status="404"} 1`)
+	assert.Contains(t, out, `easylab_http_requests_total{route="/test-metrics/{id}",method="OTHER",status="200"} 1`)
+	assert.Contains(t, out, `easylab_http_request_duration_seconds_count{route="/test-metrics/{id}"} 4`)
+}
+
+func TestServerMetrics_Counters(t *testing.T) {
+	m := newServerMetrics()
+	done := m.trackPulumi("up")
+	m.trackPulumi("destroy")
+	m.countWorkspaceRequest("created")
+	m.countWorkspaceRequest("created")
+	m.countLoginFailure("student")
+	m.countSession("admin")
+
+	var b strings.Builder
+	m.write(&b, nil)
+	assert.Contains(t, b.String(), `easylab_pulumi_operations_running{operation="up"} 1`)
+	assert.Contains(t, b.String(), `easylab_pulumi_operations_running{operation="destroy"} 1`)
+	assert.Contains(t, b.String(), `easylab_workspace_requests_total{result="created"} 2`)
+	assert.Contains(t, b.String(), `easylab_login_failures_total{portal="student"} 1`)
+	assert.Contains(t, b.String(), `easylab_sessions_created_total{portal="admin"} 1`)
+
+	done()
+	b.Reset()
+	m.write(&b, nil)
+	assert.Contains(t, b.String(), `easylab_pulumi_operations_running{operation="up"} 0`)
+}
+
+func TestSetStatus_ObservesJobDuration(t *testing.T) {
+	job := &Job{ID: "j", Status: JobStatusRunning, events: &jobEventBus{}}
+	job.History = []StatusChange{
+		{Status: JobStatusPending, At: time.Now().Add(-20 * time.Minute)},
+		{Status: JobStatusRunning, At: time.Now().Add(-10 * time.Minute)},
+	}
+
+	var before strings.Builder
+	metrics.write(&before, nil)
+	job.setStatus(JobStatusDestroyed, "")
+
+	var after strings.Builder
+	metrics.write(&after, nil)
+	assert.NotContains(t, before.String(), `easylab_job_duration_seconds_bucket{status="destroyed",le="900"} 1`)
+	assert.Contains(t, after.String(), `easylab_job_duration_seconds_bucket{status="destroyed",le="600"} 0`)
+	assert.Contains(t, after.String(), `easylab_job_duration_seconds_bucket{status="destroyed",le="900"} 1`)
+}
+
+func TestMetrics_ServerSeries(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	h.SetMetricsToken("s3cret")
+	jm.CreateJob(&LabConfig{StackName: "a"})
+	jm.CreateJob(&LabConfig{StackName: "b"})
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	h.Metrics(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "# TYPE easylab_jobs gauge")
+	assert.Contains(t, w.Body.String(), `easylab_jobs{status="pending"} 2`)
+	assert.Contains(t, w.Body.String(), "# TYPE easylab_http_requests_total counter")
+	assert.Contains(t, w.Body.String(), "# TYPE easylab_job_duration_seconds histogram")
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Session store backends, selected with EnvSessionStore.
+const (
+	SessionStoreMemory = "memory"
+	SessionStoreFile   = "file"
+	SessionStoreRedis  = "redis"
+
+	// EnvSessionStore selects the session store backend (default: memory).
+	EnvSessionStore = "SESSION_STORE"
+	// EnvSessionRedisURL is the Redis URL used by the redis backend,
+	// e.g. redis://:password@redis:6379/0.
+	EnvSessionRedisURL = "SESSION_REDIS_URL"
+)
+
+// SessionStore holds login sessions by token. The default in-memory store loses
+// every session on restart; the file store survives restarts of a single node and
+// the Redis store is shared by several replicas.
+//
+// Stores do not check expiry on Get: AuthHandler does, so every backend expires
+// sessions the same way. DeleteExpired is the periodic sweep.
+type SessionStore interface {
+	// Put stores a session under its token, replacing any previous one.
+	Put(s *Session) error
+	// Get returns the session for token, or false when there is none.
+	Get(token string) (*Session, bool)
+	// Delete removes a session. Deleting an unknown token is not an error.
+	Delete(token string) error
+	// DeleteExpired removes every session that expired before now.
+	DeleteExpired(now time.Time) error
+}
+
+// NewSessionStores builds the admin and student session stores of kind
+// (memory when empty). The file store keeps its files under dataDir, and the
+// redis store connects to redisURL.
+func NewSessionStores(kind, redisURL, dataDir string) (admin, student SessionStore, err error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	switch kind {
+	case "", SessionStoreMemory:
+		return NewMemorySessionStore(), NewMemorySessionStore(), nil
+	case SessionStoreFile:
+		if admin, err = NewFileSessionStore(filepath.Join(dataDir, "sessions.json")); err != nil {
+			return nil, nil, err
+		}
+		if student, err = NewFileSessionStore(filepath.Join(dataDir, "student-sessions.json")); err != nil {
+			return nil, nil, err
+		}
+		return admin, student, nil
+	case SessionStoreRedis:
+		if redisURL == "" {
+			return nil, nil, fmt.Errorf("%s is required when %s=%s", EnvSessionRedisURL, EnvSessionStore, SessionStoreRedis)
+		}
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", EnvSessionRedisURL, err)
+		}
+		client := redis.NewClient(opts)
+		return NewRedisSessionStore(client, "easylab:session:"), NewRedisSessionStore(client, "easylab:student-session:"), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown %s %q (want %s, %s or %s)", EnvSessionStore, kind, SessionStoreMemory, SessionStoreFile, SessionStoreRedis)
+	}
+}
+
+// MemorySessionStore keeps sessions in a map. It is the default.
+type MemorySessionStore struct {
+	sessions map[string]*Session
+	mu       sync.RWMutex
+}
+
+// NewMemorySessionStore creates an empty in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*Session)}
+}
+
+func (m *MemorySessionStore) Put(s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.Token] = s
+	return nil
+}
+
+func (m *MemorySessionStore) Get(token string) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[token]
+	return s, ok
+}
+
+func (m *MemorySessionStore) Delete(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, token)
+	return nil
+}
+
+func (m *MemorySessionStore) DeleteExpired(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for tok, s := range m.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(m.sessions, tok)
+		}
+	}
+	return nil
+}
+
+// FileSessionStore is an in-memory store that rewrites a JSON file on every
+// change, so sessions survive a restart of a single-node deployment.
+type FileSessionStore struct {
+	path string
+	mem  *MemorySessionStore
+	mu   sync.Mutex // serializes writes to path
+}
+
+// NewFileSessionStore loads the sessions saved at path (if any) and persists
+// further changes there. The file holds live session tokens, so it is 0600.
+func NewFileSessionStore(path string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	fs := &FileSessionStore{path: path, mem: NewMemorySessionStore()}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+	var sessions []*Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		// A corrupt file only costs everyone a fresh login; it must not stop the server.
+		slog.Warn("Ignoring unreadable session file", "path", path, "error", err)
+		return fs, nil
+	}
+	for _, s := range sessions {
+		_ = fs.mem.Put(s)
+	}
+	return fs, nil
+}
+
+func (f *FileSessionStore) Put(s *Session) error {
+	_ = f.mem.Put(s)
+	return f.save()
+}
+
+func (f *FileSessionStore) Get(token string) (*Session, bool) {
+	return f.mem.Get(token)
+}
+
+func (f *FileSessionStore) Delete(token string) error {
+	_ = f.mem.Delete(token)
+	return f.save()
+}
+
+func (f *FileSessionStore) DeleteExpired(now time.Time) error {
+	_ = f.mem.DeleteExpired(now)
+	return f.save()
+}
+
+// save writes the current sessions atomically (temp file + rename).
+func (f *FileSessionStore) save() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.mem.mu.RLock()
+	sessions := make([]*Session, 0, len(f.mem.sessions))
+	for _, s := range f.mem.sessions {
+		sessions = append(sessions, s)
+	}
+	f.mem.mu.RUnlock()
+
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sessions: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to rename session file: %w", err)
+	}
+	return nil
+}
+
+// RedisSessionStore keeps sessions in Redis so several replicas share them.
+// Each session is a JSON value whose TTL is its remaining lifetime, so Redis
+// expires sessions on its own.
+type RedisSessionStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// redisTimeout bounds every Redis call: a session lookup sits on every request.
+const redisTimeout = 2 * time.Second
+
+// NewRedisSessionStore stores sessions under keys prefix+token.
+func NewRedisSessionStore(client *redis.Client, prefix string) *RedisSessionStore {
+	return &RedisSessionStore{client: client, prefix: prefix}
+}
+
+func (r *RedisSessionStore) Put(s *Session) error {
+	ttl := time.Until(s.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client.Set(ctx, r.prefix+s.Token, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisSessionStore) Get(token string) (*Session, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	data, err := r.client.Get(ctx, r.prefix+token).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			slog.Error("Failed to read session from Redis", "error", err)
+		}
+		return nil, false
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		slog.Error("Failed to parse session from Redis", "error", err)
+		return nil, false
+	}
+	return &s, true
+}
+
+func (r *RedisSessionStore) Delete(token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client.Del(ctx, r.prefix+token).Err(); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired is a no-op: Redis expires the keys itself.
+func (r *RedisSessionStore) DeleteExpired(time.Time) error {
+	return nil
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionStoreFactories lists the stores the shared suite runs against. Redis is
+// left out: it needs a server, and its expiry is Redis's own TTL.
+func sessionStoreFactories() map[string]func(t *testing.T) SessionStore {
+	return map[string]func(t *testing.T) SessionStore{
+		SessionStoreMemory: func(t *testing.T) SessionStore { return NewMemorySessionStore() },
+		SessionStoreFile: func(t *testing.T) SessionStore {
+			s, err := NewFileSessionStore(filepath.Join(t.TempDir(), "sessions.json"))
+			require.NoError(t, err)
+			return s
+		},
+	}
+}
+
+func TestSessionStore_Suite(t *testing.T) {
+	for name, newStore := range sessionStoreFactories() {
+		t.Run(name, func(t *testing.T) {
+			t.Run("put and get", func(t *testing.T) {
+				s := newStore(t)
+				exp := time.Now().Add(time.Hour).Truncate(time.Second)
+				require.NoError(t, s.Put(&Session{Token: "tok", Email: "a@example.com", ExpiresAt: exp}))
+
+				got, ok := s.Get("tok")
+				require.True(t, ok)
+				assert.Equal(t, "a@example.com", got.Email)
+				assert.True(t, exp.Equal(got.ExpiresAt))
+			})
+
+			t.Run("unknown token", func(t *testing.T) {
+				_, ok := newStore(t).Get("missing")
+				assert.False(t, ok)
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				s := newStore(t)
+				require.NoError(t, s.Put(&Session{Token: "tok", ExpiresAt: time.Now().Add(time.Hour)}))
+				require.NoError(t, s.Delete("tok"))
+				_, ok := s.Get("tok")
+				assert.False(t, ok)
+				assert.NoError(t, s.Delete("tok"), "deleting an unknown token is not an error")
+			})
+
+			t.Run("delete expired", func(t *testing.T) {
+				s := newStore(t)
+				now := time.Now()
+				require.NoError(t, s.Put(&Session{Token: "old", ExpiresAt: now.Add(-time.Minute)}))
+				require.NoError(t, s.Put(&Session{Token: "live", ExpiresAt: now.Add(time.Hour)}))
+				require.NoError(t, s.DeleteExpired(now))
+
+				_, ok := s.Get("old")
+				assert.False(t, ok)
+				_, ok = s.Get("live")
+				assert.True(t, ok)
+			})
+
+			t.Run("auth handler", func(t *testing.T) {
+				ah := &AuthHandler{sessions: newStore(t), studentSessions: newStore(t)}
+				token := ah.createStudentSession("student@example.com")
+				assert.True(t, ah.validateStudentSession(token))
+				assert.Equal(t, "student@example.com", ah.getStudentSessionEmail(token))
+				ah.deleteStudentSession(token)
+				assert.False(t, ah.validateStudentSession(token))
+			})
+		})
+	}
+}
+
+func TestFileSessionStore_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	first, err := NewFileSessionStore(path)
+	require.NoError(t, err)
+	require.NoError(t, first.Put(&Session{Token: "tok", Email: "a@example.com", ExpiresAt: time.Now().Add(time.Hour)}))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "the file holds live tokens")
+
+	second, err := NewFileSessionStore(path)
+	require.NoError(t, err)
+	got, ok := second.Get("tok")
+	require.True(t, ok)
+	assert.Equal(t, "a@example.com", got.Email)
+}
+
+func TestFileSessionStore_CorruptFileStartsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0600))
+
+	s, err := NewFileSessionStore(path)
+	require.NoError(t, err)
+	_, ok := s.Get("anything")
+	assert.False(t, ok)
+}
+
+// slowSessionStore blocks every Put until release is closed, like a file or
+// Redis store stuck on its I/O.
+type slowSessionStore struct {
+	SessionStore
+	release chan struct{}
+}
+
+func (s *slowSessionStore) Put(session *Session) error {
+	<-s.release
+	return s.SessionStore.Put(session)
+}
+
+func TestAuthHandler_SlowStoreDoesNotBlockOtherRequests(t *testing.T) {
+	ah := createTestAuthHandler()
+	slow := &slowSessionStore{SessionStore: NewMemorySessionStore(), release: make(chan struct{})}
+	ah.SetSessionStores(NewMemorySessionStore(), slow)
+
+	created := make(chan string)
+	go func() { created <- ah.createLabStudentSession("alice@example.com", "") }()
+
+	adminToken := ah.createSession()
+	assert.True(t, ah.validateSession(adminToken), "the admin portal does not wait for the student store")
+	ah.SetClassicLoginDisabled(true)
+
+	close(slow.release)
+	assert.True(t, ah.validateStudentSession(<-created))
+}
+
+func TestNewSessionStores(t *testing.T) {
+	dir := t.TempDir()
+
+	admin, student, err := NewSessionStores("", "", dir)
+	require.NoError(t, err)
+	assert.IsType(t, &MemorySessionStore{}, admin)
+	assert.IsType(t, &MemorySessionStore{}, student)
+
+	admin, student, err = NewSessionStores("file", "", dir)
+	require.NoError(t, err)
+	assert.IsType(t, &FileSessionStore{}, admin)
+	assert.IsType(t, &FileSessionStore{}, student)
+
+	_, _, err = NewSessionStores("redis", "", dir)
+	assert.Error(t, err, "redis needs a URL")
+
+	admin, _, err = NewSessionStores("redis", "redis://localhost:6379/0", dir)
+	require.NoError(t, err)
+	assert.IsType(t, &RedisSessionStore{}, admin)
+
+	_, _, err = NewSessionStores("etcd", "", dir)
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvShareLinkSecret is the key share links are signed with. Without it a
+// random key is used, and the links stop working when the server restarts.
+const EnvShareLinkSecret = "SHARE_LINK_SECRET"
+
+// A share link lets students enroll in a lab without the student password:
+// /enroll?token=... where the token names the lab and an expiry, signed with
+// HMAC-SHA256. Anyone holding the link can provision workspaces in that lab
+// until it expires, so it is short-lived.
+const (
+	defaultShareLinkTTL = 24 * time.Hour
+	maxShareLinkTTL     = 30 * 24 * time.Hour
+)
+
+// errInvalidShareLink is returned for a token that is malformed, tampered with
+// or expired. Students are not told which: the link is useless either way.
+var errInvalidShareLink = errors.New("invalid or expired share link")
+
+// shareLinkClaims is the signed payload of a share link token.
+type shareLinkClaims struct {
+	LabID     string `json:"lab"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// shareLinkSigner signs and verifies share link tokens.
+type shareLinkSigner struct {
+	key []byte
+	now func() time.Time
+}
+
+// newShareLinkSigner signs with secret, or with a random key when it is empty.
+func newShareLinkSigner(secret string) *shareLinkSigner {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(fmt.Sprintf("failed to generate share link key: %v", err))
+		}
+	}
+	return &shareLinkSigner{key: key, now: time.Now}
+}
+
+// SetShareLinkSecret sets the key share links are signed with. Links signed
+// with the previous key stop working.
+func (h *Handler) SetShareLinkSecret(secret string) {
+	h.shareLinks = newShareLinkSigner(secret)
+}
+
+func (s *shareLinkSigner) mac(payload string) []byte {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
+
+// sign returns the token of a link to labID valid until expiresAt:
+// base64url(claims) "." base64url(HMAC of the first part).
+func (s *shareLinkSigner) sign(labID string, expiresAt time.Time) string {
+	claims, _ := json.Marshal(shareLinkClaims{LabID: labID, ExpiresAt: expiresAt.Unix()})
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// verify returns the lab ID of a token whose signature is valid and which has
+// not expired.
+func (s *shareLinkSigner) verify(token string) (string, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || payload == "" || strings.Contains(sig, ".") {
+		return "", errInvalidShareLink
+	}
+	gotMAC, err := base64.RawURLEncoding.Strict().DecodeString(sig)
+	if err != nil || !hmac.Equal(gotMAC, s.mac(payload)) {
+		return "", errInvalidShareLink
+	}
+	raw, err := base64.RawURLEncoding.Strict().DecodeString(payload)
+	if err != nil {
+		return "", errInvalidShareLink
+	}
+	var claims shareLinkClaims
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&claims); err != nil || claims.LabID == "" || claims.ExpiresAt == 0 {
+		return "", errInvalidShareLink
+	}
+	if !s.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return "", errInvalidShareLink
+	}
+	return claims.LabID, nil
+}
+
+// shareLinkTTL parses the ttl_hours of a share link request; empty is
+// defaultShareLinkTTL.
+func shareLinkTTL(hours string) (time.Duration, error) {
+	if hours == "" {
+		return defaultShareLinkTTL, nil
+	}
+	n, err := strconv.Atoi(hours)
+	if err != nil || n < 1 || time.Duration(n)*time.Hour > maxShareLinkTTL {
+		return 0, fmt.Errorf("ttl_hours must be a number of hours between 1 and %d", int(maxShareLinkTTL.Hours()))
+	}
+	return time.Duration(n) * time.Hour, nil
+}
+
+// requestBaseURL is the scheme and host the request was sent to, for links
+// handed out in responses.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// CreateShareLink signs a link students open to enroll in the lab without the
+// student password. The optional ttl_hours (default 24, at most 720) sets how
+// long it works. HTMX requests get a fragment to show the link, others JSON.
+//
+//	POST /api/labs/{id}/share-link
+func (h *Handler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "share-link" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	labID := pathParts[2]
+	job, exists := h.jobManager.GetJob(labID)
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Lab not found")
+		return
+	}
+	job.mu.RLock()
+	status := job.Status
+	job.mu.RUnlock()
+	if status == JobStatusDestroyed {
+		writeJSONError(w, http.StatusConflict, "The lab is destroyed")
+		return
+	}
+	ttl, err := shareLinkTTL(r.FormValue("ttl_hours"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	token := h.shareLinks.sign(labID, expiresAt)
+	link := requestBaseURL(r) + "/enroll?token=" + url.QueryEscape(token)
+	slog.InfoContext(r.Context(), "Share link created", "job_id", labID, "expires_at", expiresAt)
+
+	if isHTMXRequest(r) {
+		writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="credential-item"><label>Share link (until %s):</label><div class="value"><code>%s</code></div></div>`,
+			template.HTMLEscapeString(expiresAt.UTC().Format("2006-01-02 15:04 MST")), template.HTMLEscapeString(link)))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":        link,
+		"token":      token,
+		"expires_at": expiresAt.UTC(),
+	})
+}
+
+// Enroll is where a share link leads. Without an email it asks for one; with
+// it, it provisions the student's workspace like RequestWorkspace and shows
+// the credentials. With format=json the result is JSON, for scripts.
+//
+//	GET /enroll?token=...[&email=...][&template_id=...]
+func (h *Handler) Enroll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	asJSON := query.Get("format") == "json"
+	token := query.Get("token")
+	labID, err := h.shareLinks.verify(token)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Rejected share link")
+		const msg = "This enrollment link is invalid or has expired. Ask your instructor for a new one."
+		if asJSON {
+			writeJSONError(w, http.StatusForbidden, msg)
+			return
+		}
+		http.Error(w, msg, http.StatusForbidden)
+		return
+	}
+
+	data := map[string]interface{}{"Token": token}
+	if job, ok := h.jobManager.GetJob(labID); ok {
+		job.mu.RLock()
+		if job.Config != nil {
+			data["LabName"] = job.Config.StackName
+		}
+		job.mu.RUnlock()
+	}
+
+	email := strings.TrimSpace(query.Get("email"))
+	if email == "" {
+		if asJSON {
+			writeJSONError(w, http.StatusBadRequest, "email is required")
+			return
+		}
+		h.serveTemplate(w, "enroll.html", data)
+		return
+	}
+	if !validateEmail(email) {
+		if asJSON {
+			writeJSONError(w, http.StatusBadRequest, "Invalid email")
+			return
+		}
+		data["Error"] = "Please enter a valid email address."
+		h.serveTemplate(w, "enroll.html", data)
+		return
+	}
+
+	ws, reqErr := h.createStudentWorkspace(r.Context(), email, studentWorkspaceRequest{
+		LabID:    labID,
+		Template: query.Get("template_id"),
+	})
+	if reqErr != nil {
+		if asJSON {
+			writeJSONError(w, reqErr.Status, reqErr.Message)
+			return
+		}
+		data["Error"] = reqErr.Message
+		h.serveTemplate(w, "enroll.html", data)
+		return
+	}
+	slog.InfoContext(r.Context(), "Student enrolled through a share link", "job_id", labID, "email", email)
+	if !ws.Reused {
+		setWorkspaceInfoCookie(w, r, ws)
+	}
+
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ws)
+		return
+	}
+	data["Workspace"] = ws
+	h.serveTemplate(w, "enroll.html", data)
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareLinkSigner_ValidToken(t *testing.T) {
+	s := newShareLinkSigner("secret")
+	token := s.sign("job-1", time.Now().Add(time.Hour))
+
+	labID, err := s.verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", labID)
+}
+
+func TestShareLinkSigner_ExpiredToken(t *testing.T) {
+	s := newShareLinkSigner("secret")
+	expiresAt := time.Now().Add(time.Hour)
+	token := s.sign("job-1", expiresAt)
+
+	s.now = func() time.Time { return expiresAt }
+	_, err := s.verify(token)
+	assert.ErrorIs(t, err, errInvalidShareLink, "a link stops working at its expiry")
+
+	s.now = func() time.Time { return expiresAt.Add(-time.Second) }
+	_, err = s.verify(token)
+	assert.NoError(t, err)
+}
+
+func TestShareLinkSigner_TamperedToken(t *testing.T) {
+	s := newShareLinkSigner("secret")
+	token := s.sign("job-1", time.Now().Add(time.Hour))
+	payload, sig, _ := strings.Cut(token, ".")
+
+	// Another lab, or a later expiry, with the original signature.
+	forged := func(claims string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(claims)) + "." + sig
+	}
+	flipped := []byte(sig)
+	flipped[0] ^= 1
+
+	for name, tampered := range map[string]string{
+		"other lab":          forged(`{"lab":"job-2","exp":` + strings.Repeat("9", 10) + `}`),
+		"later expiry":       forged(`{"lab":"job-1","exp":4102444800}`),
+		"flipped signature":  payload + "." + string(flipped),
+		"no signature":       payload,
+		"empty signature":    payload + ".",
+		"extra part":         token + ".x",
+		"other key":          newShareLinkSigner("other").sign("job-1", time.Now().Add(time.Hour)),
+		"random key":         newShareLinkSigner("").sign("job-1", time.Now().Add(time.Hour)),
+		"empty":              "",
+		"padded signature":   payload + "." + sig + "=",
+		"signature as claim": sig + "." + payload,
+	} {
+		_, err := s.verify(tampered)
+		assert.ErrorIs(t, err, errInvalidShareLink, name)
+	}
+}
+
+func TestShareLinkSigner_RejectsSignedButMalformedClaims(t *testing.T) {
+	s := newShareLinkSigner("secret")
+	signed := func(claims string) string {
+		payload := base64.RawURLEncoding.EncodeToString([]byte(claims))
+		return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+	}
+	for _, claims := range []string{
+		`{"exp":4102444800}`,
+		`{"lab":"job-1"}`,
+		`{"lab":"job-1","exp":4102444800,"admin":true}`,
+		`not json`,
+	} {
+		_, err := s.verify(signed(claims))
+		assert.ErrorIs(t, err, errInvalidShareLink, claims)
+	}
+}
+
+func TestShareLinkTTL(t *testing.T) {
+	ttl, err := shareLinkTTL("")
+	require.NoError(t, err)
+	assert.Equal(t, defaultShareLinkTTL, ttl)
+	ttl, err = shareLinkTTL("48")
+	require.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, ttl)
+	for _, bad := range []string{"0", "-1", "721", "a day"} {
+		_, err := shareLinkTTL(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestCreateShareLink(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	labID := completedLab(t, jm)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/labs/"+labID+"/share-link?ttl_hours=2", nil)
+	r.Host = "easylab.example.com"
+	w := httptest.NewRecorder()
+	h.CreateShareLink(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		URL       string    `json:"url"`
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "http://easylab.example.com/enroll?token="+url.QueryEscape(resp.Token), resp.URL)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), resp.ExpiresAt, time.Minute)
+	got, err := h.shareLinks.verify(resp.Token)
+	require.NoError(t, err)
+	assert.Equal(t, labID, got)
+
+	w = httptest.NewRecorder()
+	h.CreateShareLink(w, httptest.NewRequest(http.MethodPost, "/api/labs/missing/share-link", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	h.CreateShareLink(w, httptest.NewRequest(http.MethodPost, "/api/labs/"+labID+"/share-link?ttl_hours=1000", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSetShareLinkSecret_InvalidatesOldLinks(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	token := h.shareLinks.sign("job-1", time.Now().Add(time.Hour))
+
+	h.SetShareLinkSecret("configured")
+	_, err := h.shareLinks.verify(token)
+	assert.ErrorIs(t, err, errInvalidShareLink)
+}
+
+// enroll drives Enroll with format=json.
+func enroll(h *Handler, params url.Values) *httptest.ResponseRecorder {
+	params.Set("format", "json")
+	w := httptest.NewRecorder()
+	h.Enroll(w, httptest.NewRequest(http.MethodGet, "/enroll?"+params.Encode(), nil))
+	return w
+}
+
+func TestEnroll_ProvisionsTheStudent(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{reachable: true}
+	useFakeBackend(h, fb)
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.mu.Lock()
+	job.Config.WorkspaceTemplates = []WorkspaceTemplate{{Name: "default"}}
+	job.mu.Unlock()
+
+	token := h.shareLinks.sign(labID, time.Now().Add(time.Hour))
+	w := enroll(h, url.Values{"token": {token}, "email": {"alice@example.com"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var ws studentWorkspace
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ws))
+	assert.Equal(t, "alice@example.com", ws.Email)
+	assert.Equal(t, labID, ws.LabID)
+	assert.NotEmpty(t, ws.Password)
+	assert.Len(t, fb.Ensured, 1)
+	assert.NotEmpty(t, w.Result().Cookies(), "the workspace is saved for the My workspaces page")
+
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	require.Len(t, job.Roster, 1)
+	assert.Equal(t, "alice@example.com", job.Roster[0].Email)
+}
+
+func TestEnroll_NeverHandsBackAnExistingPassword(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{reachable: true, workspaces: []workspace.Workspace{{Name: "alice-ws", Owner: "alice", Template: "default", Token: "alice-token"}}}
+	useFakeBackend(h, fb)
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.mu.Lock()
+	job.Config.WorkspaceTemplates = []WorkspaceTemplate{{Name: "default"}}
+	job.mu.Unlock()
+
+	// Anyone with the link can type alice's email: they must not get her password.
+	token := h.shareLinks.sign(labID, time.Now().Add(time.Hour))
+	w := enroll(h, url.Values{"token": {token}, "email": {"alice@example.com"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var ws studentWorkspace
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ws))
+	assert.Equal(t, "alice-ws", ws.WorkspaceName)
+	assert.True(t, ws.Reused)
+	assert.Empty(t, ws.Password)
+	assert.NotContains(t, w.Body.String(), "alice-token")
+	assert.Empty(t, fb.Ensured)
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestEnroll_RejectsExpiredAndTamperedLinks(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{reachable: true}
+	useFakeBackend(h, fb)
+	labID := completedLabWithKubeconfig(jm, 0)
+
+	expired := h.shareLinks.sign(labID, time.Now().Add(-time.Minute))
+	payload, _, _ := strings.Cut(h.shareLinks.sign(labID, time.Now().Add(time.Hour)), ".")
+	tampered := payload + "." + base64.RawURLEncoding.EncodeToString([]byte("forged"))
+
+	for name, token := range map[string]string{"expired": expired, "tampered": tampered, "missing": ""} {
+		w := enroll(h, url.Values{"token": {token}, "email": {"alice@example.com"}})
+		assert.Equal(t, http.StatusForbidden, w.Code, name)
+	}
+	assert.Empty(t, fb.Ensured, "no workspace is created")
+
+	w := httptest.NewRecorder()
+	h.Enroll(w, httptest.NewRequest(http.MethodGet, "/enroll?token="+url.QueryEscape(expired), nil))
+	assert.Equal(t, http.StatusForbidden, w.Code, "the page refuses it too")
+}
+
+func TestEnroll_NeedsAValidEmail(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{reachable: true}
+	useFakeBackend(h, fb)
+	labID := completedLabWithKubeconfig(jm, 0)
+	token := h.shareLinks.sign(labID, time.Now().Add(time.Hour))
+
+	for _, email := range []string{"", "not-an-email"} {
+		w := enroll(h, url.Values{"token": {token}, "email": {email}})
+		assert.Equal(t, http.StatusBadRequest, w.Code, email)
+	}
+	assert.Empty(t, fb.Ensured)
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// studentDashboard is the student dashboard as JSON, for a school portal
+// embedding the catalog: what the dashboard page shows, and the workspaces the
+// student has in the labs they can use.
+type studentDashboard struct {
+	Email string `json:"email"`
+	// Broadcast is the admin's current banner, if any.
+	Broadcast  *Broadcast                  `json:"broadcast,omitempty"`
+	Labs       []studentLab                `json:"labs"`
+	Workspaces []studentDashboardWorkspace `json:"workspaces"`
+}
+
+// studentDashboardWorkspace is a workspace provisioned for the student, as
+// recorded on its lab's roster and still on the cluster. Its password is never
+// shown again, and whether it is ready is for StatusURL to tell.
+type studentDashboardWorkspace struct {
+	LabID         string `json:"lab_id"`
+	LabName       string `json:"lab_name"`
+	WorkspaceName string `json:"workspace_name"`
+	Template      string `json:"template,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	// DeletionAt is when the workspace is deleted automatically, "" when never.
+	DeletionAt string `json:"deletion_at"`
+	// OpenURL opens the workspace with its token.
+	OpenURL string `json:"open_url"`
+	// StatusURL is the JSON API route with the workspace's readiness.
+	StatusURL string `json:"status_url"`
+}
+
+// GetStudentDashboard returns the data of the student dashboard as JSON.
+// Route: GET /api/student/dashboard and GET /api/v1/student/dashboard
+func (h *Handler) GetStudentDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	email := studentEmailFromContext(r)
+	owner := usernameFromEmail(email)
+	if owner == "" {
+		http.Error(w, "Session email not found, please log in again", http.StatusUnauthorized)
+		return
+	}
+
+	dashboard := studentDashboard{
+		Email:      email,
+		Labs:       h.studentLabs(r.Context()),
+		Workspaces: []studentDashboardWorkspace{},
+	}
+	if b := h.broadcast.Current(); b.Message != "" {
+		dashboard.Broadcast = &b
+	}
+	for _, lab := range dashboard.Labs {
+		dashboard.Workspaces = append(dashboard.Workspaces, h.studentRosterWorkspaces(r.Context(), lab.ID, owner)...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dashboard)
+}
+
+// studentRosterWorkspaces returns the workspaces of owner on the roster of the
+// lab labID, oldest first. The roster keeps every workspace ever provisioned,
+// so only those the cluster still lists are returned; none when it cannot be
+// listed.
+func (h *Handler) studentRosterWorkspaces(ctx context.Context, labID, owner string) []studentDashboardWorkspace {
+	job, exists := h.jobManager.GetJob(labID)
+	if !exists {
+		return nil
+	}
+	job.mu.RLock()
+	kubeconfig := extractStringFromConfigValue(job.Kubeconfig)
+	namespace := job.workspaceNamespace()
+	roster := append([]RosterEntry(nil), job.Roster...)
+	var labName string
+	var lifetimeHours int
+	var labDeletionDate *time.Time
+	if job.Config != nil {
+		labName = job.Config.StackName
+		lifetimeHours = job.Config.WorkspaceLifetimeHours
+		labDeletionDate = job.Config.LabDeletionDate
+	}
+	job.mu.RUnlock()
+
+	if !slices.ContainsFunc(roster, func(e RosterEntry) bool { return e.Username == owner }) {
+		return nil
+	}
+	if kubeconfig == "" {
+		return nil
+	}
+	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to build workspace backend for lab", "job_id", labID, "error", err)
+		return nil
+	}
+	workspaces, err := backend.ListWorkspaces(ctx, labID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list the workspaces of lab", "job_id", labID, "error", err)
+		return nil
+	}
+	live := make(map[string]bool, len(workspaces))
+	for _, ws := range workspaces {
+		if ws.Owner == owner {
+			live[ws.Name] = true
+		}
+	}
+
+	var out []studentDashboardWorkspace
+	for _, e := range roster {
+		if e.Username != owner || !live[e.WorkspaceName] {
+			continue
+		}
+		deletionAt := ""
+		if t := workspaceDeletionTime(e.CreatedAt, lifetimeHours, labDeletionDate); t != nil {
+			deletionAt = t.Format(time.RFC3339)
+		}
+		out = append(out, studentDashboardWorkspace{
+			LabID:         labID,
+			LabName:       labName,
+			WorkspaceName: e.WorkspaceName,
+			Template:      e.Template,
+			CreatedAt:     e.CreatedAt.Format(time.RFC3339),
+			DeletionAt:    deletionAt,
+			OpenURL: "/api/student/workspace/open?lab_id=" + url.QueryEscape(labID) +
+				"&workspace_name=" + url.QueryEscape(e.WorkspaceName),
+			StatusURL: "/api/v1/student/workspaces/" + url.PathEscape(labID) + "/" + url.PathEscape(e.WorkspaceName),
+		})
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dashboardRequest calls GetStudentDashboard as the student with email, in a
+// session scoped to labID ("" for every lab).
+func dashboardRequest(h *Handler, email, labID string) *httptest.ResponseRecorder {
+	ctx := context.WithValue(context.Background(), studentEmailContextKey, email)
+	ctx = context.WithValue(ctx, studentLabContextKey, labID)
+	w := httptest.NewRecorder()
+	h.GetStudentDashboard(w, httptest.NewRequest(http.MethodGet, "/api/student/dashboard", nil).WithContext(ctx))
+	return w
+}
+
+func TestGetStudentDashboard(t *testing.T) {
+	jm := NewJobManager("")
+	deletion := time.Date(2030, 6, 1, 18, 0, 0, 0, time.UTC)
+	labA := jm.CreateJob(&LabConfig{StackName: "workshop-a", LabDeletionDate: &deletion})
+	jm.UpdateJobStatus(labA, JobStatusCompleted)
+	job, _ := jm.GetJob(labA)
+	job.mu.Lock()
+	job.Kubeconfig = "fake-kubeconfig"
+	job.mu.Unlock()
+	labB := jm.CreateJob(&LabConfig{StackName: "workshop-b"})
+	jm.UpdateJobStatus(labB, JobStatusCompleted)
+	jm.CreateJob(&LabConfig{StackName: "deploying"})
+
+	created := time.Date(2030, 5, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, jm.RecordRosterEntry(labA, RosterEntry{Email: "alice@example.com", Username: "alice", WorkspaceName: "alice", Template: "go", CreatedAt: created}))
+	require.NoError(t, jm.RecordRosterEntry(labA, RosterEntry{Email: "bob@example.com", Username: "bob", WorkspaceName: "bob", CreatedAt: created}))
+
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{workspaces: []workspace.Workspace{
+		{ID: "alice", Name: "alice", Owner: "alice", Template: "go"},
+		{ID: "bob", Name: "bob", Owner: "bob"},
+	}})
+	_, err := h.broadcast.Set("Lunch at noon", time.Time{})
+	require.NoError(t, err)
+
+	w := dashboardRequest(h, "alice@example.com", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.ElementsMatch(t, []string{"email", "broadcast", "labs", "workspaces"}, slices.Collect(maps.Keys(got)))
+	assert.Equal(t, "alice@example.com", got["email"])
+	assert.Equal(t, "Lunch at noon", got["broadcast"].(map[string]any)["message"])
+
+	var dashboard studentDashboard
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dashboard))
+	require.Len(t, dashboard.Labs, 2, "only the completed labs")
+	assert.ElementsMatch(t, []string{labA, labB}, []string{dashboard.Labs[0].ID, dashboard.Labs[1].ID})
+	assert.Equal(t, []studentDashboardWorkspace{{
+		LabID:         labA,
+		LabName:       "workshop-a",
+		WorkspaceName: "alice",
+		Template:      "go",
+		CreatedAt:     "2030-05-01T09:00:00Z",
+		DeletionAt:    "2030-06-01T18:00:00Z",
+		OpenURL:       "/api/student/workspace/open?lab_id=" + labA + "&workspace_name=alice",
+		StatusURL:     "/api/v1/student/workspaces/" + labA + "/alice",
+	}}, dashboard.Workspaces, "only the student's own workspaces")
+	assert.NotContains(t, w.Body.String(), "password")
+	assert.NotContains(t, w.Body.String(), "bob")
+}
+
+func TestGetStudentDashboard_HidesDeletedWorkspaces(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	created := time.Date(2030, 5, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, jm.RecordRosterEntry(labID, RosterEntry{Username: "alice", WorkspaceName: "alice-old", CreatedAt: created}))
+	require.NoError(t, jm.RecordRosterEntry(labID, RosterEntry{Username: "alice", WorkspaceName: "alice", CreatedAt: created.Add(time.Hour)}))
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{workspaces: []workspace.Workspace{
+		{ID: "alice", Name: "alice", Owner: "alice"},
+		{ID: "alice-old", Name: "alice-old", Owner: "mallory"},
+	}}
+	useFakeBackend(h, fb)
+
+	var dashboard studentDashboard
+	require.NoError(t, json.Unmarshal(dashboardRequest(h, "alice@example.com", "").Body.Bytes(), &dashboard))
+	require.Len(t, dashboard.Workspaces, 1, "a deleted workspace stays on the roster, not on the dashboard")
+	assert.Equal(t, "alice", dashboard.Workspaces[0].WorkspaceName)
+
+	fb.listErr = errors.New("cluster unreachable")
+	require.NoError(t, json.Unmarshal(dashboardRequest(h, "alice@example.com", "").Body.Bytes(), &dashboard))
+	assert.Empty(t, dashboard.Workspaces, "nothing the cluster cannot confirm")
+}
+
+func TestGetStudentDashboard_ScopedSession(t *testing.T) {
+	jm := NewJobManager("")
+	labA := labWithAccessCode(t, jm, "workshop-a", "code-of-a")
+	labB := labWithAccessCode(t, jm, "workshop-b", "code-of-b")
+	require.NoError(t, jm.RecordRosterEntry(labB, RosterEntry{Username: "alice", WorkspaceName: "alice", CreatedAt: time.Now()}))
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	var dashboard map[string]any
+	require.NoError(t, json.Unmarshal(dashboardRequest(h, "alice@example.com", labA).Body.Bytes(), &dashboard))
+	labs := dashboard["labs"].([]any)
+	require.Len(t, labs, 1)
+	assert.Equal(t, labA, labs[0].(map[string]any)["id"])
+	assert.Empty(t, dashboard["workspaces"], "a workspace in a lab outside the session's scope is not listed")
+	assert.NotContains(t, dashboard, "broadcast", "no banner, no key")
+}
+
+func TestGetStudentDashboard_RequiresStudentSession(t *testing.T) {
+	ah := createTestAuthHandler()
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	handler := ah.RequireStudentAuth(h.GetStudentDashboard)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/student/dashboard", nil))
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/student/login", w.Header().Get("Location"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/student/dashboard", nil)
+	req.AddCookie(&http.Cookie{Name: StudentSessionCookieName, Value: ah.createStudentSession("alice@example.com")})
+	w = httptest.NewRecorder()
+	handler(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"email": "alice@example.com", "labs": [], "workspaces": []}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.GetStudentDashboard(w, httptest.NewRequest(http.MethodPost, "/api/student/dashboard", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
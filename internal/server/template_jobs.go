@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A template job holds a lab config that seeds new labs. It is persisted and
+// loaded at startup like any job, but never executed: it cannot be launched,
+// retried or destroyed, and it is left out of the labs list, the duplicate
+// check and the cleanup loops. CreateLabFromTemplate clones its config into a
+// new job, which runs like any lab.
+
+// templateJobError is why a template job cannot be run or destroyed.
+func templateJobError(jobID string) error {
+	return fmt.Errorf("job %s is a template: create a lab from it instead", jobID)
+}
+
+// checkNotTemplate returns an error when the job is a template.
+func (jm *JobManager) checkNotTemplate(jobID string) error {
+	job, exists := jm.GetJob(jobID)
+	if !exists {
+		return nil
+	}
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	if job.IsTemplate {
+		return templateJobError(jobID)
+	}
+	return nil
+}
+
+// GetLabs returns the jobs that are labs, newest first: every job but the
+// templates.
+func (jm *JobManager) GetLabs() []*Job {
+	return jm.filterJobs(false)
+}
+
+// GetTemplates returns the template jobs, newest first.
+func (jm *JobManager) GetTemplates() []*Job {
+	return jm.filterJobs(true)
+}
+
+func (jm *JobManager) filterJobs(templates bool) []*Job {
+	var kept []*Job
+	for _, job := range jm.GetAllJobs() {
+		job.mu.RLock()
+		isTemplate := job.IsTemplate
+		job.mu.RUnlock()
+		if isTemplate == templates {
+			kept = append(kept, job)
+		}
+	}
+	return kept
+}
+
+// SetTemplate marks a job as a template, or back as a plain job, and persists
+// it. Only a job that holds no cluster can become a template: a destroyed or
+// failed lab, or a completed dry run.
+func (jm *JobManager) SetTemplate(jobID string, isTemplate bool) error {
+	job, exists := jm.GetJob(jobID)
+	if !exists {
+		return fmt.Errorf("job %s not found", jobID)
+	}
+	job.mu.Lock()
+	if job.IsTemplate == isTemplate {
+		job.mu.Unlock()
+		return nil
+	}
+	if isTemplate {
+		if job.Config == nil {
+			job.mu.Unlock()
+			return fmt.Errorf("job %s has no configuration to make a template of", jobID)
+		}
+		if job.Status != JobStatusDestroyed && job.Status != JobStatusFailed && job.Status != JobStatusDryRunCompleted {
+			status := job.Status
+			job.mu.Unlock()
+			return fmt.Errorf("job %s is %s: only a destroyed or failed lab, or a completed dry run, can become a template", jobID, status)
+		}
+	}
+	job.IsTemplate = isTemplate
+	job.UpdatedAt = time.Now()
+	job.mu.Unlock()
+
+	if isTemplate {
+		jm.AppendOutput(jobID, "Marked as a template")
+	} else {
+		jm.AppendOutput(jobID, "No longer a template")
+	}
+	slog.Info("Template flag changed", "component", "audit", "job_id", jobID, "is_template", isTemplate)
+	return jm.SaveJob(jobID)
+}
+
+// cloneLabConfig returns a deep copy of a config, for a lab created from a
+// template: the template's own config must not change with the new lab's.
+func cloneLabConfig(c *LabConfig) (*LabConfig, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var clone LabConfig
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// MarkTemplate handles PATCH /api/labs/{id}/template: form field template
+// ("true" or "false") marks the job as a template or back as a plain job.
+func (h *Handler) MarkTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "template" {
+		writeJSONError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+	jobID := pathParts[2]
+	if _, exists := h.jobManager.GetJob(jobID); !exists {
+		writeJSONError(w, http.StatusNotFound, "Lab not found")
+		return
+	}
+	isTemplate, err := strconv.ParseBool(strings.TrimSpace(r.FormValue("template")))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, `template must be "true" or "false"`)
+		return
+	}
+	if err := h.jobManager.SetTemplate(jobID, isTemplate); err != nil {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": jobID, "is_template": isTemplate})
+}
+
+// CreateLabFromTemplate handles POST /api/labs/from-template/{id}: it clones
+// the template's config into a new job and starts it, then redirects to the
+// new lab like RecreateLab. Optional form fields: stack_name and event replace
+// the template's, dry_run=true previews the lab instead, and force=true skips
+// the duplicate check. The provider credentials are the current ones.
+func (h *Handler) CreateLabFromTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.rejectDuringMaintenance(w) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	templateID := strings.TrimPrefix(r.URL.Path, "/api/labs/from-template/")
+	job, exists := h.jobManager.GetJob(templateID)
+	if !exists || strings.Contains(templateID, "/") {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+	job.mu.RLock()
+	isTemplate := job.IsTemplate
+	config, err := cloneLabConfig(job.Config)
+	job.mu.RUnlock()
+	if !isTemplate {
+		h.renderHTMLError(w, "Not a Template", fmt.Sprintf("Job %s is not a template.", templateID))
+		return
+	}
+	if err != nil || config == nil {
+		slog.ErrorContext(r.Context(), "Failed to clone the config of a template", "template_id", templateID, "error", err)
+		h.renderHTMLError(w, "No Configuration Available", "This template has no configuration to create a lab from.")
+		return
+	}
+
+	if name := strings.TrimSpace(r.FormValue("stack_name")); name != "" {
+		config.StackName = name
+	}
+	if event := strings.TrimSpace(r.FormValue("event")); event != "" {
+		config.Event = event
+	}
+	// A deletion date the template was saved with has most likely passed; the
+	// new lab would be destroyed on the next cleanup tick.
+	if config.LabDeletionDate != nil && !config.LabDeletionDate.After(time.Now()) {
+		config.LabDeletionDate = nil
+	}
+	if !config.UseExistingCluster {
+		creds, err := h.getProviderCredentials(w, config.Provider)
+		if err != nil {
+			return
+		}
+		config.setProviderCredentials(creds)
+	}
+
+	dryRun := r.FormValue("dry_run") == "true"
+	started, reqErr := h.startLab(labRequest{Config: config, DryRun: dryRun, Force: r.FormValue("force") == "true"})
+	if reqErr != nil {
+		h.renderRequestError(w, reqErr)
+		return
+	}
+	h.jobManager.AppendOutput(started.JobID, fmt.Sprintf("Created from template %s", templateID))
+	slog.InfoContext(r.Context(), "Created lab from template", "job_id", started.JobID, "template_id", templateID)
+	http.Redirect(w, r, fmt.Sprintf("/admin?job=%s", started.JobID), http.StatusSeeOther)
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// templateJob creates a destroyed BYOK lab and marks it as a template.
+func templateJob(t *testing.T, jm *JobManager) string {
+	t.Helper()
+	past := time.Now().Add(-24 * time.Hour)
+	id := jm.CreateJob(&LabConfig{
+		StackName:          "workshop",
+		Event:              "devoxx-2026",
+		UseExistingCluster: true,
+		ExternalKubeconfig: "kc",
+		LabDeletionDate:    &past,
+		WorkspaceTemplates: []WorkspaceTemplate{{Name: "default"}},
+	})
+	jm.UpdateJobStatus(id, JobStatusDestroyed)
+	require.NoError(t, jm.SetTemplate(id, true))
+	return id
+}
+
+func fromTemplateRequest(h *Handler, templateID string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/labs/from-template/"+templateID, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.CreateLabFromTemplate(w, r)
+	return w
+}
+
+func TestSetTemplate_OnlyForJobsWithoutACluster(t *testing.T) {
+	jm := NewJobManager("")
+	for status, allowed := range map[JobStatus]bool{
+		JobStatusPending:         false,
+		JobStatusRunning:         false,
+		JobStatusCompleted:       false,
+		JobStatusFailed:          true,
+		JobStatusDestroyed:       true,
+		JobStatusDryRunCompleted: true,
+	} {
+		id := jm.CreateJob(&LabConfig{StackName: "lab"})
+		jm.UpdateJobStatus(id, status)
+		err := jm.SetTemplate(id, true)
+		if allowed {
+			assert.NoError(t, err, status)
+		} else {
+			assert.Error(t, err, status)
+		}
+	}
+
+	assert.Error(t, jm.SetTemplate("missing", true))
+}
+
+func TestMarkTemplate_TogglesTheFlag(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	id := jm.CreateJob(&LabConfig{StackName: "lab"})
+	jm.UpdateJobStatus(id, JobStatusDestroyed)
+
+	patch := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPatch, "/api/labs/"+id+"/template", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.MarkTemplate(w, r)
+		return w
+	}
+
+	w := patch(url.Values{"template": {"true"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"`+id+`","is_template":true}`, w.Body.String())
+	assert.Empty(t, jm.GetLabs())
+	require.Len(t, jm.GetTemplates(), 1)
+
+	assert.Equal(t, http.StatusBadRequest, patch(url.Values{"template": {"maybe"}}).Code)
+
+	w = patch(url.Values{"template": {"false"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, jm.GetLabs(), 1)
+	assert.Empty(t, jm.GetTemplates())
+
+	job, _ := jm.GetJob(id)
+	assert.Contains(t, job.Output, "Marked as a template")
+	assert.Contains(t, job.Output, "No longer a template")
+}
+
+func TestCreateLabFromTemplate_ClonesTheConfig(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm, workDir: t.TempDir()}, NewCredentialsManager(), nil, nil, nil)
+	templateID := templateJob(t, jm)
+
+	w := fromTemplateRequest(h, templateID, url.Values{"stack_name": {"workshop-2"}, "dry_run": {"true"}})
+	require.Equal(t, http.StatusSeeOther, w.Code, w.Body.String())
+	location := w.Header().Get("Location")
+	require.True(t, strings.HasPrefix(location, "/admin?job="), location)
+	newID := strings.TrimPrefix(location, "/admin?job=")
+
+	job, exists := jm.GetJob(newID)
+	require.True(t, exists)
+	job.mu.RLock()
+	config := job.Config
+	isTemplate := job.IsTemplate
+	job.mu.RUnlock()
+	assert.False(t, isTemplate)
+	assert.Equal(t, "workshop-2", config.StackName)
+	assert.Equal(t, "devoxx-2026", config.Event)
+	assert.Nil(t, config.LabDeletionDate, "the template's past deletion date is dropped")
+	require.Len(t, config.WorkspaceTemplates, 1)
+
+	template, _ := jm.GetJob(templateID)
+	template.mu.RLock()
+	defer template.mu.RUnlock()
+	assert.Equal(t, "workshop", template.Config.StackName, "the template's config is left unchanged")
+	assert.NotNil(t, template.Config.LabDeletionDate)
+	assert.NotSame(t, template.Config, config)
+}
+
+func TestCreateLabFromTemplate_Refusals(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	plainID := jm.CreateJob(&LabConfig{StackName: "lab"})
+
+	assert.Equal(t, http.StatusNotFound, fromTemplateRequest(h, "missing", nil).Code)
+
+	w := fromTemplateRequest(h, plainID, nil)
+	assert.Contains(t, w.Body.String(), "Not a Template")
+	assert.Len(t, jm.GetAllJobs(), 1, "no lab is created from a job that is not a template")
+}
+
+func TestTemplateJob_NotRunNorDestroyed(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	templateID := templateJob(t, jm)
+
+	_, reqErr := h.launchLab(templateID, false, "127.0.0.1")
+	require.NotNil(t, reqErr)
+	assert.Equal(t, http.StatusConflict, reqErr.Status)
+
+	reqErr = h.destroyLab(templateID)
+	require.NotNil(t, reqErr)
+	assert.Equal(t, http.StatusConflict, reqErr.Status)
+
+	job, _ := jm.GetJob(templateID)
+	_, found := jm.FindActiveDuplicate(job.Config)
+	assert.False(t, found)
+}
+
+func TestCleanupExpiredLabs_SkipsTemplateJob(t *testing.T) {
+	t.Parallel()
+	jm := NewJobManager("")
+	templateID := templateJob(t, jm)
+	// A template edited by hand may claim any status; it is never destroyed.
+	jm.UpdateJobStatus(templateID, JobStatusCompleted)
+	past := time.Now().Add(-1 * time.Hour)
+	labID := jm.CreateJob(&LabConfig{StackName: "test", LabDeletionDate: &past})
+	jm.UpdateJobStatus(labID, JobStatusCompleted)
+
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	h.cleanupExpiredLabs()
+
+	template, _ := jm.GetJob(templateID)
+	template.mu.RLock()
+	assert.Equal(t, JobStatusCompleted, template.Status, "template job past its deletion date should not be destroyed")
+	template.mu.RUnlock()
+
+	lab, _ := jm.GetJob(labID)
+	lab.mu.RLock()
+	assert.Equal(t, JobStatusRunning, lab.Status, "other labs past their deletion date are still destroyed")
+	lab.mu.RUnlock()
+}
+
+func TestTemplateJob_PersistedAndReloaded(t *testing.T) {
+	dataDir := t.TempDir()
+	jm := newTestJobManager(t, dataDir)
+	id := jm.CreateJob(&LabConfig{StackName: "lab"})
+	jm.UpdateJobStatus(id, JobStatusDryRunCompleted)
+	require.NoError(t, jm.SetTemplate(id, true))
+
+	reloaded := newTestJobManager(t, dataDir)
+	require.NoError(t, reloaded.LoadJobs())
+	templates := reloaded.GetTemplates()
+	require.Len(t, templates, 1)
+	assert.Equal(t, id, templates[0].ID)
+	assert.Empty(t, reloaded.GetLabs())
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// gitResolveTimeout bounds the lookup of one template's commit.
+const gitResolveTimeout = 30 * time.Second
+
+// lsRemoteCommit returns the commit branch points to on repo, or the one the
+// remote's HEAD points to when branch is empty. It lists the remote's refs,
+// like git ls-remote, so nothing is cloned.
+func lsRemoteCommit(ctx context.Context, repo, branch string, auth transport.AuthMethod) (string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{Name: "origin", URLs: []string{repo}})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		// The error may quote the URL, credentials included.
+		return "", fmt.Errorf("listing %s: %s", redactURL(repo), strings.ReplaceAll(err.Error(), repo, redactURL(repo)))
+	}
+
+	want := plumbing.HEAD
+	if branch != "" {
+		want = plumbing.NewBranchReferenceName(branch)
+	}
+	// HEAD is usually listed as a symbolic reference to the default branch, so
+	// it may take a second lookup.
+	for hops := 0; hops < 2; hops++ {
+		var target plumbing.ReferenceName
+		for _, ref := range refs {
+			if ref.Name() != want {
+				continue
+			}
+			if ref.Type() == plumbing.HashReference {
+				return ref.Hash().String(), nil
+			}
+			target = ref.Target()
+		}
+		if target == "" {
+			break
+		}
+		want = target
+	}
+	if branch == "" {
+		return "", fmt.Errorf("%s has no default branch", redactURL(repo))
+	}
+	return "", fmt.Errorf("branch %q not found in %s", branch, redactURL(repo))
+}
+
+// shortCommit abbreviates a commit for the job's output.
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// pinTemplateVersions sets the GitCommit of the Git templates from their
+// branch. Templates already pinned are kept unless repin is set, which is how
+// retries and re-creations reuse the first deploy's code. auth returns the
+// credentials for a template's private repo, nil for anonymous.
+//
+// A template that cannot be resolved is left as it was, so its workspaces
+// clone the branch head (or the earlier pin); the returned notes say so.
+func (h *Handler) pinTemplateVersions(templates []WorkspaceTemplate, auth func(WorkspaceTemplate) transport.AuthMethod, repin bool) []string {
+	var notes []string
+	for i := range templates {
+		t := &templates[i]
+		if strings.TrimSpace(t.GitRepo) == "" || (t.GitCommit != "" && !repin) {
+			continue
+		}
+		// envbuilder does its own clone from the branch, which the commit cannot
+		// be handed to.
+		if t.Devcontainer != nil {
+			notes = append(notes, fmt.Sprintf("Template %s builds a devcontainer, which always clones the head of its branch: not pinned", t.Name))
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), gitResolveTimeout)
+		commit, err := h.resolveGitCommit(ctx, strings.TrimSpace(t.GitRepo), strings.TrimSpace(t.GitBranch), auth(*t))
+		cancel()
+		if err != nil {
+			slog.Warn("Could not pin template", "template", t.Name, "error", err)
+			if t.GitCommit != "" {
+				notes = append(notes, fmt.Sprintf("Could not re-pin template %s, it stays on %s: %v", t.Name, shortCommit(t.GitCommit), err))
+			} else {
+				notes = append(notes, fmt.Sprintf("Could not pin template %s, its workspaces clone the head of the branch: %v", t.Name, err))
+			}
+			continue
+		}
+
+		ref := t.GitBranch
+		if ref == "" {
+			ref = "default branch"
+		}
+		switch {
+		case t.GitCommit == commit:
+			notes = append(notes, fmt.Sprintf("Template %s stays pinned to %s (%s)", t.Name, shortCommit(commit), ref))
+		case t.GitCommit != "":
+			notes = append(notes, fmt.Sprintf("Template %s re-pinned from %s to %s (%s)", t.Name, shortCommit(t.GitCommit), shortCommit(commit), ref))
+		default:
+			notes = append(notes, fmt.Sprintf("Template %s pinned to %s (%s)", t.Name, shortCommit(commit), ref))
+		}
+		t.GitCommit = commit
+	}
+	return notes
+}
+
+// wizardGitAuth returns the credentials of a template's git_auth_secret among
+// those entered in the creation wizard.
+func wizardGitAuth(secrets []pendingSecret) func(WorkspaceTemplate) transport.AuthMethod {
+	return func(t WorkspaceTemplate) transport.AuthMethod {
+		for _, s := range secrets {
+			if s.Kind == workspace.AuthSecretGit && s.Name == t.GitAuthSecret {
+				return gitCloneAuth(s.Username, s.Token)
+			}
+		}
+		return nil
+	}
+}
+
+// RepinTemplates handles POST /api/labs/{id}/pin: it resolves the Git
+// templates of a lab created with pin_template_versions to their branch's
+// current commit. Workspaces created from then on clone the new commit;
+// existing ones keep their files. Optional form fields git_username and
+// git_token are the credentials for private repos, which the server does not
+// keep once they are written to the cluster.
+func (h *Handler) RepinTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "pin" {
+		writeJSONError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+	jobID := pathParts[2]
+	job, exists := h.jobManager.GetJob(jobID)
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Lab not found")
+		return
+	}
+
+	job.mu.RLock()
+	pinned := job.Config != nil && job.Config.PinTemplateVersions
+	var templates []WorkspaceTemplate
+	if pinned {
+		templates = append(templates, job.Config.WorkspaceTemplates...)
+	}
+	job.mu.RUnlock()
+	if !pinned {
+		writeJSONError(w, http.StatusConflict, "The lab does not pin its template versions")
+		return
+	}
+
+	creds := gitCloneAuth(r.FormValue("git_username"), r.FormValue("git_token"))
+	notes := h.pinTemplateVersions(templates, func(WorkspaceTemplate) transport.AuthMethod { return creds }, true)
+	if err := h.jobManager.setTemplateCommits(jobID, templates); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to persist the template pins of lab", "job_id", jobID, "error", err)
+	}
+	for _, note := range notes {
+		h.jobManager.AppendOutput(jobID, note)
+	}
+
+	pins := make(map[string]string)
+	for _, t := range templates {
+		if t.GitCommit != "" {
+			pins[t.Name] = t.GitCommit
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": jobID, "pins": pins, "notes": notes})
+}
+
+// setTemplateCommits copies the GitCommit of templates onto the job's
+// templates of the same name, and persists the job.
+func (jm *JobManager) setTemplateCommits(jobID string, templates []WorkspaceTemplate) error {
+	job, exists := jm.GetJob(jobID)
+	if !exists {
+		return fmt.Errorf("job %s not found", jobID)
+	}
+	commits := make(map[string]string, len(templates))
+	for _, t := range templates {
+		commits[t.Name] = t.GitCommit
+	}
+	job.mu.Lock()
+	if job.Config != nil {
+		for i := range job.Config.WorkspaceTemplates {
+			if commit, ok := commits[job.Config.WorkspaceTemplates[i].Name]; ok {
+				job.Config.WorkspaceTemplates[i].GitCommit = commit
+			}
+		}
+	}
+	job.mu.Unlock()
+	return jm.SaveJob(jobID)
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitRemote answers resolveGitCommit with the current head of each
+// branch, and counts the lookups.
+type fakeGitRemote struct {
+	heads   map[string]string
+	lookups int
+}
+
+func (f *fakeGitRemote) resolve(_ context.Context, repo, branch string, _ transport.AuthMethod) (string, error) {
+	f.lookups++
+	if commit, ok := f.heads[repo+"#"+branch]; ok {
+		return commit, nil
+	}
+	return "", errors.New("branch not found")
+}
+
+func pinnedLabConfig() *LabConfig {
+	return &LabConfig{
+		StackName:           "workshop",
+		UseExistingCluster:  true,
+		ExternalKubeconfig:  "kc",
+		PinTemplateVersions: true,
+		WorkspaceTemplates: []WorkspaceTemplate{
+			{Name: "default", GitRepo: "https://gitlab.com/org/workshop.git", GitBranch: "main"},
+			{Name: "plain", Image: "ubuntu:24.04"},
+		},
+	}
+}
+
+func TestStartLab_SecondDeployReusesThePinnedCommit(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm, workDir: bgJobTempDir(t)}, NewCredentialsManager(), nil, nil, nil)
+	remote := &fakeGitRemote{heads: map[string]string{"https://gitlab.com/org/workshop.git#main": "1111111111111111111111111111111111111111"}}
+	h.resolveGitCommit = remote.resolve
+
+	first, reqErr := h.startLab(labRequest{Config: pinnedLabConfig(), DryRun: true})
+	require.Nil(t, reqErr)
+	job, _ := jm.GetJob(first.JobID)
+	job.mu.RLock()
+	config := *job.Config
+	output := job.Output
+	job.mu.RUnlock()
+	assert.Equal(t, 1, remote.lookups, "only the Git template is resolved")
+	assert.Equal(t, "1111111111111111111111111111111111111111", config.WorkspaceTemplates[0].GitCommit)
+	assert.Empty(t, config.WorkspaceTemplates[1].GitCommit)
+	assert.Contains(t, output, "Template default pinned to 111111111111 (main)")
+
+	// The branch moves on; a re-creation copies the config and keeps the pin.
+	remote.heads["https://gitlab.com/org/workshop.git#main"] = "2222222222222222222222222222222222222222"
+	config.WorkspaceTemplates = append([]WorkspaceTemplate(nil), config.WorkspaceTemplates...)
+	second, reqErr := h.startLab(labRequest{Config: &config, DryRun: true})
+	require.Nil(t, reqErr)
+	assert.Equal(t, 1, remote.lookups, "the second deploy does not look the branch up again")
+
+	job, _ = jm.GetJob(second.JobID)
+	job.mu.Lock()
+	assert.Equal(t, "1111111111111111111111111111111111111111", job.Config.WorkspaceTemplates[0].GitCommit)
+	// Let a student ask for a workspace on it.
+	job.Status = JobStatusCompleted
+	job.Kubeconfig = "fake-kubeconfig"
+	job.mu.Unlock()
+	fb := &fakeBackend{reachable: true}
+	useFakeBackend(h, fb)
+	_, reqErr = h.createStudentWorkspace(context.Background(), "alice@example.com", studentWorkspaceRequest{LabID: second.JobID, Template: "default"})
+	require.Nil(t, reqErr)
+	require.Len(t, fb.Ensured, 1)
+	assert.Equal(t, "1111111111111111111111111111111111111111", fb.Ensured[0].GitCommit)
+	assert.Equal(t, "main", fb.Ensured[0].GitBranch)
+}
+
+func TestStartLab_UnpinnedLabIsNotResolved(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm, workDir: bgJobTempDir(t)}, NewCredentialsManager(), nil, nil, nil)
+	remote := &fakeGitRemote{}
+	h.resolveGitCommit = remote.resolve
+
+	cfg := pinnedLabConfig()
+	cfg.PinTemplateVersions = false
+	started, reqErr := h.startLab(labRequest{Config: cfg, DryRun: true})
+	require.Nil(t, reqErr)
+	assert.Zero(t, remote.lookups)
+	job, _ := jm.GetJob(started.JobID)
+	assert.Empty(t, job.Config.WorkspaceTemplates[0].GitCommit)
+}
+
+func TestPinTemplateVersions_FailureLeavesTheTemplateUnpinned(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	h.resolveGitCommit = (&fakeGitRemote{}).resolve
+	templates := []WorkspaceTemplate{
+		{Name: "gone", GitRepo: "https://gitlab.com/org/workshop.git", GitBranch: "deleted"},
+		{Name: "devcontainer", GitRepo: "https://gitlab.com/org/workshop.git", Devcontainer: &DevcontainerConfig{}},
+	}
+
+	notes := h.pinTemplateVersions(templates, wizardGitAuth(nil), false)
+	assert.Empty(t, templates[0].GitCommit)
+	assert.Empty(t, templates[1].GitCommit)
+	require.Len(t, notes, 2)
+	assert.Contains(t, notes[0], "Could not pin template gone")
+	assert.Contains(t, notes[1], "not pinned")
+}
+
+func repinRequest(h *Handler, labID string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/labs/"+labID+"/pin", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.RepinTemplates(w, r)
+	return w
+}
+
+func TestRepinTemplates_MovesToTheBranchHead(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	remote := &fakeGitRemote{heads: map[string]string{"https://gitlab.com/org/workshop.git#main": "2222222222222222222222222222222222222222"}}
+	h.resolveGitCommit = remote.resolve
+	cfg := pinnedLabConfig()
+	cfg.WorkspaceTemplates[0].GitCommit = "1111111111111111111111111111111111111111"
+	id := jm.CreateJob(cfg)
+
+	w := repinRequest(h, id, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"default":"2222222222222222222222222222222222222222"`)
+	job, _ := jm.GetJob(id)
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	assert.Equal(t, "2222222222222222222222222222222222222222", job.Config.WorkspaceTemplates[0].GitCommit)
+	assert.Contains(t, job.Output, "Template default re-pinned from 111111111111 to 222222222222 (main)")
+}
+
+func TestRepinTemplates_Refusals(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{jobManager: jm}, NewCredentialsManager(), nil, nil, nil)
+	id := jm.CreateJob(&LabConfig{StackName: "lab"})
+
+	assert.Equal(t, http.StatusNotFound, repinRequest(h, "missing", nil).Code)
+	assert.Equal(t, http.StatusConflict, repinRequest(h, id, nil).Code, "the lab does not pin its templates")
+}
+
+func TestLsRemoteCommit_LocalRepo(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	commit, err := wt.Commit("initial", &git.CommitOptions{
+		AllowEmptyCommits: true,
+		Author:            &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+
+	got, err := lsRemoteCommit(context.Background(), dir, head.Name().Short(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, commit.String(), got)
+
+	got, err = lsRemoteCommit(context.Background(), dir, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, commit.String(), got, "no branch is the remote's HEAD")
+
+	_, err = lsRemoteCommit(context.Background(), dir, "missing", nil)
+	assert.ErrorContains(t, err, `branch "missing" not found`)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureSelfSignedCert_IsKeptAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, err := EnsureSelfSignedCert(dir)
+	require.NoError(t, err)
+	first, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Contains(t, cert.Leaf.DNSNames, "localhost")
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "the key is private")
+
+	_, _, err = EnsureSelfSignedCert(dir)
+	require.NoError(t, err)
+	second, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "browsers that trusted it keep trusting it")
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writePair := func() {
+		certPEM, keyPEM, err := generateSelfSignedCert(time.Now())
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(certFile, certPEM, 0644))
+		require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	}
+	writePair()
+	r, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	before, _ := r.GetCertificate(nil)
+
+	writePair()
+	require.NoError(t, r.Reload())
+	renewed, _ := r.GetCertificate(nil)
+	assert.NotEqual(t, before.Leaf.SerialNumber, renewed.Leaf.SerialNumber)
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0600))
+	assert.Error(t, r.Reload())
+	kept, _ := r.GetCertificate(nil)
+	assert.Same(t, renewed, kept, "a broken renewal keeps the current certificate")
+}
+
+func TestNewCertReloader_MissingFiles(t *testing.T) {
+	_, err := NewCertReloader("/nonexistent/tls.crt", "/nonexistent/tls.key")
+	assert.ErrorContains(t, err, "failed to load TLS certificate")
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		port, host, target, want string
+	}{
+		{"8443", "easylab.example.com:8080", "/admin?tab=labs", "https://easylab.example.com:8443/admin?tab=labs"},
+		{"443", "easylab.example.com", "/", "https://easylab.example.com/"},
+		{"443", "[::1]:80", "/student", "https://[::1]/student"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		r.Host = tt.host
+		w := httptest.NewRecorder()
+		RedirectToHTTPS(tt.port).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, tt.want, w.Header().Get("Location"))
+	}
+}
+
+func TestTLSServer_SetsSecureCookies(t *testing.T) {
+	ah := createTestAuthHandler()
+	srv := httptest.NewTLSServer(http.HandlerFunc(ah.HandleLogin))
+	defer srv.Close()
+	client := srv.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	sha := sha256.Sum256([]byte("test-admin"))
+	resp, err := client.PostForm(srv.URL+"/login", url.Values{"password_hash": {hex.EncodeToString(sha[:])}})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NotEmpty(t, resp.Cookies())
+	for _, c := range resp.Cookies() {
+		assert.True(t, c.Secure, c.Name)
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/debug"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+)
+
+// Verbosity is how much of Pulumi's output a lab's deployment streams into its
+// logs. The zero value is the normal output.
+type Verbosity string
+
+const (
+	// VerbosityQuiet keeps the steps that finished, the diagnostics and the
+	// summary, without the in-progress lines or the stack outputs.
+	VerbosityQuiet Verbosity = "quiet"
+	// VerbosityNormal is Pulumi's default output.
+	VerbosityNormal Verbosity = ""
+	// VerbosityVerbose adds the debug messages of the program and providers
+	// (pulumi --debug).
+	VerbosityVerbose Verbosity = "verbose"
+	// VerbosityDebug also streams Pulumi's own logs at -v=9, providers
+	// included. They may hold secrets the providers send to the cloud.
+	VerbosityDebug Verbosity = "debug"
+)
+
+// debugLogLevel is the -v level of VerbosityDebug, the one Pulumi's
+// troubleshooting guide asks for.
+const debugLogLevel uint = 9
+
+// validateVerbosity rejects a verbosity this code does not know.
+func validateVerbosity(v Verbosity) error {
+	switch v {
+	case VerbosityQuiet, VerbosityNormal, VerbosityVerbose, VerbosityDebug:
+		return nil
+	}
+	return fmt.Errorf("verbosity must be quiet, verbose or debug (empty for the normal output), got %q", v)
+}
+
+// parseVerbosity reads the verbosity form field, where "normal" stands for the
+// default.
+func parseVerbosity(s string) Verbosity {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "normal" {
+		return VerbosityNormal
+	}
+	return Verbosity(s)
+}
+
+// debugLogging is the Pulumi logging of the verbosity, or nil to keep Pulumi's
+// default.
+func (v Verbosity) debugLogging() *debug.LoggingOptions {
+	switch v {
+	case VerbosityVerbose:
+		return &debug.LoggingOptions{Debug: true}
+	case VerbosityDebug:
+		level := debugLogLevel
+		return &debug.LoggingOptions{Debug: true, LogLevel: &level, LogToStdErr: true, FlowToPlugins: true}
+	}
+	return nil
+}
+
+// upOptions are the options of a lab's pulumi up at the verbosity, streaming
+// into w, followed by extra.
+func upOptions(v Verbosity, w *jobOutputWriter, extra ...optup.Option) []optup.Option {
+	opts := []optup.Option{optup.ProgressStreams(w)}
+	switch v {
+	case VerbosityQuiet:
+		opts = append(opts, optup.SuppressProgress(), optup.SuppressOutputs())
+	case VerbosityDebug:
+		// -v logs go to stderr.
+		opts = append(opts, optup.DebugLogging(*v.debugLogging()), optup.ErrorProgressStreams(w))
+	case VerbosityVerbose:
+		opts = append(opts, optup.DebugLogging(*v.debugLogging()))
+	}
+	return append(opts, extra...)
+}
+
+// previewOptions are upOptions for pulumi preview.
+func previewOptions(v Verbosity, w *jobOutputWriter, extra ...optpreview.Option) []optpreview.Option {
+	opts := []optpreview.Option{optpreview.ProgressStreams(w)}
+	switch v {
+	case VerbosityQuiet:
+		opts = append(opts, optpreview.SuppressProgress(), optpreview.SuppressOutputs())
+	case VerbosityDebug:
+		opts = append(opts, optpreview.DebugLogging(*v.debugLogging()), optpreview.ErrorProgressStreams(w))
+	case VerbosityVerbose:
+		opts = append(opts, optpreview.DebugLogging(*v.debugLogging()))
+	}
+	return append(opts, extra...)
+}
+
+// inProgressLine matches the lines Pulumi prints while a step runs, e.g.
+// " +  ovh:CloudProject:Kube kubeCluster creating (42s) ". The line printed
+// once the step is done ("created (300s)") and the diagnostics stay.
+var inProgressLine = regexp.MustCompile(`\b\w+ing \(\d+s\)\s*$|^\s*@ \w+ing\.*\s*$`)
+
+// keepLine reports whether a line of Pulumi output goes to the job's logs at
+// the verbosity.
+func (v Verbosity) keepLine(line string) bool {
+	return v != VerbosityQuiet || !inProgressLine.MatchString(line)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appliedUpOptions applies the pulumi up options of the verbosity, as the
+// stack would.
+func appliedUpOptions(v Verbosity, w *jobOutputWriter) optup.Options {
+	var opts optup.Options
+	for _, o := range upOptions(v, w) {
+		o.ApplyOption(&opts)
+	}
+	return opts
+}
+
+func TestUpOptions(t *testing.T) {
+	w := &jobOutputWriter{}
+
+	normal := appliedUpOptions(VerbosityNormal, w)
+	assert.Len(t, normal.ProgressStreams, 1)
+	assert.Zero(t, normal.DebugLogOpts)
+	assert.False(t, normal.SuppressProgress)
+	assert.False(t, normal.SuppressOutputs)
+
+	quiet := appliedUpOptions(VerbosityQuiet, w)
+	assert.True(t, quiet.SuppressProgress)
+	assert.True(t, quiet.SuppressOutputs)
+	assert.Zero(t, quiet.DebugLogOpts)
+
+	verbose := appliedUpOptions(VerbosityVerbose, w)
+	assert.True(t, verbose.DebugLogOpts.Debug)
+	assert.Nil(t, verbose.DebugLogOpts.LogLevel)
+	assert.Empty(t, verbose.ErrorProgressStreams)
+
+	dbg := appliedUpOptions(VerbosityDebug, w)
+	require.NotNil(t, dbg.DebugLogOpts.LogLevel)
+	assert.Equal(t, debugLogLevel, *dbg.DebugLogOpts.LogLevel)
+	assert.True(t, dbg.DebugLogOpts.LogToStdErr)
+	assert.True(t, dbg.DebugLogOpts.FlowToPlugins)
+	assert.Len(t, dbg.ErrorProgressStreams, 1, "the -v logs go to stderr, which is streamed too")
+}
+
+func TestUpOptions_KeepsExtraOptions(t *testing.T) {
+	var opts optup.Options
+	for _, o := range upOptions(VerbosityQuiet, &jobOutputWriter{}, optup.Target([]string{"urn"})) {
+		o.ApplyOption(&opts)
+	}
+	assert.Equal(t, []string{"urn"}, opts.Target)
+	assert.True(t, opts.SuppressProgress)
+}
+
+func TestPreviewOptions(t *testing.T) {
+	apply := func(v Verbosity) optpreview.Options {
+		var opts optpreview.Options
+		for _, o := range previewOptions(v, &jobOutputWriter{}) {
+			o.ApplyOption(&opts)
+		}
+		return opts
+	}
+	assert.Zero(t, apply(VerbosityNormal).DebugLogOpts)
+	assert.True(t, apply(VerbosityQuiet).SuppressOutputs)
+	assert.True(t, apply(VerbosityVerbose).DebugLogOpts.Debug)
+	assert.NotNil(t, apply(VerbosityDebug).DebugLogOpts.LogLevel)
+	assert.Len(t, apply(VerbosityDebug).ErrorProgressStreams, 1)
+}
+
+func TestJobOutputWriter_QuietDropsInProgressLines(t *testing.T) {
+	jm := NewJobManager("")
+	id := jm.CreateJob(&LabConfig{StackName: "test"})
+	w := &jobOutputWriter{jobID: id, jobManager: jm, verbosity: VerbosityQuiet}
+
+	w.Write([]byte("Updating (lab):\n" +
+		" +  ovh:CloudProject:Kube kubeCluster creating (0s) \n" +
+		"@ updating....\n" +
+		" +  ovh:CloudProject:Kube kubeCluster creating (42s) \n" +
+		" +  ovh:CloudProject:Kube kubeCluster created (300s) \n" +
+		"    error: quota exceeded\n"))
+	w.Flush()
+
+	job, _ := jm.GetJob(id)
+	assert.Equal(t, []string{
+		"Updating (lab):",
+		" +  ovh:CloudProject:Kube kubeCluster created (300s) ",
+		"    error: quota exceeded",
+	}, job.Output[len(job.Output)-3:])
+	assert.NotContains(t, job.Output, "@ updating....")
+}
+
+func TestValidateVerbosity(t *testing.T) {
+	for _, v := range []Verbosity{VerbosityQuiet, VerbosityNormal, VerbosityVerbose, VerbosityDebug} {
+		assert.NoError(t, validateVerbosity(v))
+	}
+	assert.ErrorContains(t, validateVerbosity("loud"), `got "loud"`)
+	assert.Equal(t, VerbosityNormal, parseVerbosity("Normal"))
+	assert.Equal(t, VerbosityDebug, parseVerbosity(" debug "))
+}
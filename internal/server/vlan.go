@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"time"
+
+	"easylab/ovh"
+)
+
+// ovhCheckTimeout bounds the OVH API calls of the dry-run checks, so a slow API
+// delays a dry run by seconds rather than the client's default three minutes.
+const ovhCheckTimeout = 10 * time.Second
+
+// vlanConflictWarning checks, for a dry run, whether the lab's VLAN ID is
+// already used by a private network of the OVH project, which OVH only reports
+// once pulumi up tries to create the network. It returns a warning fragment
+// suggesting a free VLAN, or "" when the VLAN is free or cannot be checked: the
+// check is best effort and never blocks a dry run.
+func (h *Handler) vlanConflictWarning(cfg *LabConfig) string {
+	// An imported network keeps its own VLAN, which is expected to be taken.
+	if cfg.UseExistingCluster || cfg.Provider == "azure" || cfg.ImportPrivateNetworkID != "" {
+		return ""
+	}
+	vlanID, err := ovh.ParseVlanID(cfg.NetworkID)
+	if err != nil {
+		return ""
+	}
+	client, serviceName, err := h.newOVHClient()
+	if err != nil {
+		slog.Warn("VLAN check skipped", "error", err)
+		return ""
+	}
+	client.Timeout = ovhCheckTimeout
+	networks, err := fetchOVHPrivateNetworks(client, serviceName)
+	if err != nil {
+		slog.Warn("VLAN check skipped", "error", err)
+		return ""
+	}
+
+	taken, ok := vlanConflict(networks, vlanID)
+	if !ok {
+		return ""
+	}
+	msg := fmt.Sprintf("VLAN %d is already used by the private network %q (%s): creating the lab's network will fail.", vlanID, taken.Name, taken.ID)
+	if free, ok := freeVlanID(networks); ok {
+		msg += fmt.Sprintf(" VLAN %d is free.", free)
+	}
+	return fmt.Sprintf(`<div class="warning-message"><h3>VLAN Already in Use</h3><p>%s</p></div>`, template.HTMLEscapeString(msg))
+}
+
+// vlanConflict returns the network already using vlanID, if any.
+func vlanConflict(networks []ovhPrivateNetwork, vlanID int) (ovhPrivateNetwork, bool) {
+	for _, n := range networks {
+		if n.VlanID == vlanID {
+			return n, true
+		}
+	}
+	return ovhPrivateNetwork{}, false
+}
+
+// freeVlanID returns the lowest VLAN ID no network uses, or false when every
+// VLAN OVH accepts is taken.
+func freeVlanID(networks []ovhPrivateNetwork) (int, bool) {
+	used := make(map[int]bool, len(networks))
+	for _, n := range networks {
+		used[n.VlanID] = true
+	}
+	for id := ovh.MinVlanID; id <= ovh.MaxVlanID; id++ {
+		if !used[id] {
+			return id, true
+		}
+	}
+	return 0, false
+}
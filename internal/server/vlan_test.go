@@ -0,0 +1,47 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVlanConflict(t *testing.T) {
+	networks := []ovhPrivateNetwork{
+		{ID: "pn-1_0", Name: "Ext-Net", VlanID: 0},
+		{ID: "pn-1_1", Name: "lab-a", VlanID: 1},
+		{ID: "pn-1_5", Name: "lab-b", VlanID: 5},
+	}
+
+	taken, ok := vlanConflict(networks, 5)
+	assert.True(t, ok)
+	assert.Equal(t, "lab-b", taken.Name)
+
+	_, ok = vlanConflict(networks, 2)
+	assert.False(t, ok)
+}
+
+func TestFreeVlanID(t *testing.T) {
+	free, ok := freeVlanID([]ovhPrivateNetwork{{VlanID: 0}, {VlanID: 1}, {VlanID: 2}, {VlanID: 4}})
+	assert.True(t, ok)
+	assert.Equal(t, 3, free, "the lowest unused VLAN should be suggested")
+
+	free, ok = freeVlanID(nil)
+	assert.True(t, ok)
+	assert.Equal(t, 1, free)
+
+	all := make([]ovhPrivateNetwork, 0, 4000)
+	for id := 1; id <= 4000; id++ {
+		all = append(all, ovhPrivateNetwork{VlanID: id})
+	}
+	_, ok = freeVlanID(all)
+	assert.False(t, ok)
+}
+
+func TestVlanConflictWarning_SkippedWithoutAPIAccess(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, &CredentialsManager{credentials: map[string]ProviderCredentials{}}, nil, nil, nil)
+
+	assert.Empty(t, h.vlanConflictWarning(&LabConfig{Provider: "ovh", NetworkID: "5"}), "no credentials: the check is skipped")
+	assert.Empty(t, h.vlanConflictWarning(&LabConfig{Provider: "ovh", NetworkID: "5", ImportPrivateNetworkID: "pn-1_5"}))
+	assert.Empty(t, h.vlanConflictWarning(&LabConfig{Provider: "azure"}))
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sync"
+
+	"easylab/web"
+)
+
+// handlerPages are the page templates the Handler renders, and authPages
+// those of the AuthHandler.
+var (
+	handlerPages = []string{
+		"index.html",
+		"admin.html",
+		"student-dashboard.html",
+		"student-workspaces.html",
+		"student-feedback.html",
+		"admin-feedback.html",
+		"credentials.html",
+		"ovh-credentials.html", // Keep for backward compatibility
+		"ovh-options.html",
+		"azure-options.html",
+		"azure-provider.html",
+		"azure-ad.html",
+		"labs-list.html",
+		"lab-workspaces.html",
+		"admin-stats.html",
+		"enroll.html",
+	}
+	authPages = []string{
+		"login.html",
+		"student-login.html",
+	}
+)
+
+// The embedded pages never change, so every handler shares one parse.
+var (
+	embeddedHandlerTemplates = sync.OnceValues(func() (map[string]*template.Template, error) {
+		return parseWebTemplates(web.FS, handlerPages...)
+	})
+	embeddedAuthTemplates = sync.OnceValues(func() (map[string]*template.Template, error) {
+		return parseWebTemplates(web.FS, authPages...)
+	})
+	embeddedStatic = sync.OnceValues(func() (http.Handler, error) {
+		return staticFileServer(web.FS)
+	})
+)
+
+// parseWebTemplates parses each page of fsys together with base.html, which
+// lays it out. The result maps the page's file name to its template.
+func parseWebTemplates(fsys fs.FS, pages ...string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		tmpl, err := template.ParseFS(fsys, "base.html", page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load template %s: %w", page, err)
+		}
+		templates[page] = tmpl
+	}
+	return templates, nil
+}
+
+// staticFileServer serves the static/ directory of fsys under /static/.
+func staticFileServer(fsys fs.FS) (http.Handler, error) {
+	static, err := fs.Sub(fsys, "static")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open static files: %w", err)
+	}
+	return http.StripPrefix("/static/", http.FileServerFS(static)), nil
+}
+
+// mustEmbedded returns the result of parsing an embedded asset, which can only
+// fail if the binary was built with a broken page.
+func mustEmbedded[T any](v T, err error) T {
+	if err != nil {
+		panic(fmt.Sprintf("embedded web UI: %v", err))
+	}
+	return v
+}
+
+// SetWebFS serves the pages and static files from fsys instead of those built
+// into the binary, e.g. os.DirFS("web") while working on the UI. The pages are
+// parsed at once, so a broken one is reported here rather than on its first
+// request.
+func (h *Handler) SetWebFS(fsys fs.FS) error {
+	templates, err := parseWebTemplates(fsys, handlerPages...)
+	if err != nil {
+		return err
+	}
+	static, err := staticFileServer(fsys)
+	if err != nil {
+		return err
+	}
+	h.templatesMu.Lock()
+	defer h.templatesMu.Unlock()
+	h.templates = templates
+	h.static = static
+	return nil
+}
+
+// SetWebFS renders the login pages from fsys instead of the pages built into
+// the binary; see Handler.SetWebFS.
+func (ah *AuthHandler) SetWebFS(fsys fs.FS) error {
+	templates, err := parseWebTemplates(fsys, authPages...)
+	if err != nil {
+		return err
+	}
+	ah.templatesMu.Lock()
+	defer ah.templatesMu.Unlock()
+	ah.templates = templates
+	return nil
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webFS returns a web UI with every page of the Handler and the AuthHandler,
+// each rendering its name.
+func webFS() fstest.MapFS {
+	fsys := fstest.MapFS{
+		"base.html":       {Data: []byte(`{{define "base"}}<main>{{template "body" .}}</main>{{end}}`)},
+		"static/site.css": {Data: []byte("body { color: red; }")},
+	}
+	for _, page := range append(append([]string{}, handlerPages...), authPages...) {
+		fsys[page] = &fstest.MapFile{Data: []byte(`{{define "body"}}` + page + `{{end}}`)}
+	}
+	return fsys
+}
+
+func TestEmbeddedWebUI_ParsesEveryPage(t *testing.T) {
+	templates, err := embeddedHandlerTemplates()
+	require.NoError(t, err)
+	assert.Len(t, templates, len(handlerPages))
+	templates, err = embeddedAuthTemplates()
+	require.NoError(t, err)
+	assert.Len(t, templates, len(authPages))
+}
+
+func TestHandler_SetWebFS(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	require.NoError(t, h.SetWebFS(webFS()))
+
+	w := httptest.NewRecorder()
+	h.ServeUI(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<main>index.html</main>", w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeStatic(w, httptest.NewRequest(http.MethodGet, "/static/site.css", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body, _ := io.ReadAll(w.Body)
+	assert.Equal(t, "body { color: red; }", string(body))
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/css")
+}
+
+func TestHandler_SetWebFS_BrokenPageKeepsTheCurrentUI(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	broken := webFS()
+	broken["admin.html"] = &fstest.MapFile{Data: []byte(`{{define "body"}}{{.Missing`)}
+	assert.ErrorContains(t, h.SetWebFS(broken), "admin.html")
+
+	missing := webFS()
+	delete(missing, "enroll.html")
+	assert.ErrorContains(t, h.SetWebFS(missing), "enroll.html")
+
+	w := httptest.NewRecorder()
+	h.ServeStatic(w, httptest.NewRequest(http.MethodGet, "/static/admin.js", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "the embedded UI is still served")
+}
+
+func TestAuthHandler_SetWebFS(t *testing.T) {
+	ah := createTestAuthHandler()
+	require.NoError(t, ah.SetWebFS(webFS()))
+
+	w := httptest.NewRecorder()
+	ah.ServeStudentLogin(w, httptest.NewRequest(http.MethodGet, "/student/login", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "student-login.html")
+}
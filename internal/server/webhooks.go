@@ -0,0 +1,293 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvWebhookSecret is the shared secret webhook payloads are signed with. The
+// payloads go out unsigned while it is not set.
+const EnvWebhookSecret = "WEBHOOK_SECRET"
+
+// WebhookSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+// request body, keyed with the webhook secret.
+const WebhookSignatureHeader = "X-EasyLab-Signature"
+
+const (
+	// webhookAttempts is how many times a payload is sent before giving up.
+	webhookAttempts = 3
+	// webhookRetryDelay is the wait before the first retry; it doubles after.
+	webhookRetryDelay = 2 * time.Second
+	// webhookTimeout bounds one attempt.
+	webhookTimeout = 10 * time.Second
+	// maxWebhookDeliveries bounds Job.WebhookDeliveries, oldest dropped first.
+	maxWebhookDeliveries = 50
+)
+
+// WebhookPayload is the JSON body POSTed to the webhook URLs when a job
+// completes, fails or is destroyed.
+type WebhookPayload struct {
+	Event     string    `json:"event"` // "job.completed", "job.failed" or "job.destroyed"
+	JobID     string    `json:"job_id"`
+	StackName string    `json:"stack_name"`
+	Status    JobStatus `json:"status"`
+	// DurationSeconds is how long the job ran before reaching the status.
+	DurationSeconds float64   `json:"duration_seconds,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Link            string    `json:"link"`
+	At              time.Time `json:"at"`
+}
+
+// WebhookDelivery records one payload sent to one URL, in Job.WebhookDeliveries.
+type WebhookDelivery struct {
+	Event string    `json:"event"`
+	At    time.Time `json:"at"`
+	// Target is the URL without its path, which often holds a token.
+	Target     string `json:"target"`
+	Attempts   int    `json:"attempts"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Delivered  bool   `json:"delivered"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WebhookNotifier POSTs a signed WebhookPayload to the webhook URLs when a job
+// reaches the completed, failed or destroyed status. A lab's own webhook_urls
+// replace the server's URLs.
+type WebhookNotifier struct {
+	jobManager *JobManager
+	urls       []string
+	secret     string
+	publicURL  string
+	client     *http.Client
+	retryDelay time.Duration
+	// inflight tracks the deliveries being sent, so that stopping waits for them.
+	inflight sync.WaitGroup
+}
+
+// NewWebhookNotifier returns a notifier for the jobs of jm. publicURL is where
+// the server is reached from, for the payload's link; empty makes the link a
+// path.
+func NewWebhookNotifier(jm *JobManager, urls []string, secret, publicURL string) *WebhookNotifier {
+	return &WebhookNotifier{
+		jobManager: jm,
+		urls:       urls,
+		secret:     secret,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		client:     &http.Client{Timeout: webhookTimeout},
+		retryDelay: webhookRetryDelay,
+	}
+}
+
+// Start subscribes the notifier to the job events. The returned function stops
+// it once the deliveries under way are done.
+func (n *WebhookNotifier) Start() (stop func()) {
+	unsubscribe := n.jobManager.Subscribe("webhooks", n.handle)
+	return func() {
+		unsubscribe()
+		n.inflight.Wait()
+	}
+}
+
+// ParseWebhookURLs reads a comma- or newline-separated list of webhook URLs.
+func ParseWebhookURLs(s string) ([]string, error) {
+	urls := splitWebhookURLs(s)
+	return urls, validateWebhookURLs(urls)
+}
+
+// splitWebhookURLs splits a comma- or newline-separated list of URLs.
+func splitWebhookURLs(s string) []string {
+	var urls []string
+	for _, u := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// validateWebhookURLs checks that every URL is an absolute http or https URL.
+func validateWebhookURLs(urls []string) error {
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q: want an http or https URL", webhookTarget(raw))
+		}
+	}
+	return nil
+}
+
+// webhookTarget returns the URL without its path and query, for logs and the
+// delivery log: Slack-like webhook URLs carry their token in the path.
+func webhookTarget(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "[unparseable url]"
+	}
+	target := u.Scheme + "://" + u.Host
+	if u.Path != "" && u.Path != "/" || u.RawQuery != "" {
+		target += "/…"
+	}
+	return target
+}
+
+// signWebhook returns the WebhookSignatureHeader value for body.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEvent returns the payload event of a status, or false when the status
+// is not notified.
+func webhookEvent(status JobStatus) (string, bool) {
+	switch status {
+	case JobStatusCompleted, JobStatusFailed, JobStatusDestroyed:
+		return "job." + string(status), true
+	}
+	return "", false
+}
+
+func (n *WebhookNotifier) handle(e JobEvent) {
+	if e.Type != JobEventStatus {
+		return
+	}
+	event, ok := webhookEvent(e.Status)
+	if !ok {
+		return
+	}
+	job, exists := n.jobManager.GetJob(e.JobID)
+	if !exists {
+		return
+	}
+	job.mu.RLock()
+	redeployed := e.Status == JobStatusCompleted && job.completedBefore(e.At)
+	job.mu.RUnlock()
+	if redeployed {
+		return
+	}
+
+	payload := WebhookPayload{
+		Event:  event,
+		JobID:  e.JobID,
+		Status: e.Status,
+		Error:  e.Reason,
+		Link:   n.publicURL + "/admin?job=" + url.QueryEscape(e.JobID),
+		At:     e.At,
+	}
+	urls := n.urls
+	job.mu.RLock()
+	if job.Config != nil {
+		payload.StackName = job.Config.StackName
+		if len(job.Config.WebhookURLs) > 0 {
+			urls = job.Config.WebhookURLs
+		}
+	}
+	if started, ok := job.runningSince(); ok && started.Before(e.At) {
+		payload.DurationSeconds = e.At.Sub(started).Round(time.Second).Seconds()
+	}
+	job.mu.RUnlock()
+	if e.Status != JobStatusFailed {
+		payload.Error = ""
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Webhooks: failed to encode the payload", "event", event, "job_id", e.JobID, "error", err)
+		return
+	}
+	// Deliveries retry for a while; events of other jobs must not wait on them.
+	for _, u := range urls {
+		n.inflight.Add(1)
+		go func(u string) {
+			defer n.inflight.Done()
+			n.jobManager.recordWebhookDelivery(e.JobID, n.deliver(u, event, body))
+		}(u)
+	}
+}
+
+// deliver POSTs body to u, retrying on network errors, 429 and 5xx responses.
+func (n *WebhookNotifier) deliver(u, event string, body []byte) WebhookDelivery {
+	d := WebhookDelivery{Event: event, Target: webhookTarget(u)}
+	delay := n.retryDelay
+	for d.Attempts < webhookAttempts {
+		if d.Attempts > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		d.Attempts++
+		d.StatusCode, d.Error = 0, ""
+		retry, err := n.post(u, event, body, &d)
+		if err == nil {
+			d.Delivered = true
+			break
+		}
+		d.Error = err.Error()
+		if !retry {
+			break
+		}
+	}
+	d.At = time.Now()
+	return d
+}
+
+func (n *WebhookNotifier) post(u, event string, body []byte, d *WebhookDelivery) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EasyLab-Webhook")
+	req.Header.Set("X-EasyLab-Event", event)
+	if n.secret != "" {
+		req.Header.Set(WebhookSignatureHeader, signWebhook(n.secret, body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// The error quotes the URL, token included.
+		return true, fmt.Errorf("request failed: %s", strings.ReplaceAll(err.Error(), u, d.Target))
+	}
+	resp.Body.Close()
+	d.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, fmt.Errorf("HTTP %d", resp.StatusCode)
+}
+
+// recordWebhookDelivery adds d to the job's delivery log and output, and
+// persists the job.
+func (jm *JobManager) recordWebhookDelivery(jobID string, d WebhookDelivery) {
+	job, exists := jm.GetJob(jobID)
+	if !exists {
+		return
+	}
+	job.mu.Lock()
+	job.WebhookDeliveries = append(job.WebhookDeliveries, d)
+	if over := len(job.WebhookDeliveries) - maxWebhookDeliveries; over > 0 {
+		job.WebhookDeliveries = append(job.WebhookDeliveries[:0:0], job.WebhookDeliveries[over:]...)
+	}
+	job.mu.Unlock()
+
+	if d.Delivered {
+		jm.AppendOutput(jobID, fmt.Sprintf("Webhook %s delivered to %s (HTTP %d)", d.Event, d.Target, d.StatusCode))
+	} else {
+		jm.AppendOutput(jobID, fmt.Sprintf("Webhook %s to %s failed (%d attempts): %s", d.Event, d.Target, d.Attempts, d.Error))
+		slog.Warn("Webhooks: delivery failed", "event", d.Event, "job_id", jobID, "target", d.Target, "attempts", d.Attempts, "error", d.Error)
+	}
+	if err := jm.SaveJob(jobID); err != nil {
+		slog.Error("Webhooks: failed to save job", "job_id", jobID, "error", err)
+	}
+}
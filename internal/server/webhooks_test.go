@@ -0,0 +1,214 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver records the requests it gets and answers with the statuses
+// given, then 200.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	statuses []int
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) (*webhookReceiver, string) {
+	rec := &webhookReceiver{statuses: statuses}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.bodies = append(rec.bodies, body)
+		rec.headers = append(rec.headers, r.Header.Clone())
+		if len(rec.statuses) > 0 {
+			w.WriteHeader(rec.statuses[0])
+			rec.statuses = rec.statuses[1:]
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return rec, srv.URL
+}
+
+func (rec *webhookReceiver) requests() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.bodies)
+}
+
+func testWebhookNotifier(jm *JobManager, urls []string) *WebhookNotifier {
+	n := NewWebhookNotifier(jm, urls, "s3cret", "https://easylab.example.com/")
+	n.retryDelay = time.Millisecond
+	return n
+}
+
+func TestWebhooks_SignedPayloadOnCompletion(t *testing.T) {
+	jm := NewJobManager("")
+	rec, url := newWebhookReceiver(t)
+	stop := testWebhookNotifier(jm, []string{url}).Start()
+
+	id := jm.CreateJob(&LabConfig{StackName: "workshop"})
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	stop()
+
+	require.Equal(t, 1, rec.requests(), "only the completion is notified")
+	body := rec.bodies[0]
+	assert.Equal(t, signWebhook("s3cret", body), rec.headers[0].Get(WebhookSignatureHeader))
+	assert.Equal(t, "job.completed", rec.headers[0].Get("X-EasyLab-Event"))
+
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "job.completed", payload.Event)
+	assert.Equal(t, id, payload.JobID)
+	assert.Equal(t, "workshop", payload.StackName)
+	assert.Equal(t, JobStatusCompleted, payload.Status)
+	assert.Empty(t, payload.Error)
+	assert.Equal(t, "https://easylab.example.com/admin?job="+id, payload.Link)
+
+	job, _ := jm.GetJob(id)
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	require.Len(t, job.WebhookDeliveries, 1)
+	delivery := job.WebhookDeliveries[0]
+	assert.True(t, delivery.Delivered)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.StatusCode)
+	assert.Contains(t, job.Output, "Webhook job.completed delivered to "+url+" (HTTP 200)")
+}
+
+func TestWebhooks_FailureCarriesTheError(t *testing.T) {
+	jm := NewJobManager("")
+	rec, url := newWebhookReceiver(t)
+	stop := testWebhookNotifier(jm, []string{url}).Start()
+
+	id := jm.CreateJob(&LabConfig{StackName: "workshop"})
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.SetError(id, assert.AnError)
+	stop()
+
+	require.Equal(t, 1, rec.requests())
+	var payload WebhookPayload
+	require.NoError(t, json.Unmarshal(rec.bodies[0], &payload))
+	assert.Equal(t, "job.failed", payload.Event)
+	assert.Equal(t, assert.AnError.Error(), payload.Error)
+}
+
+func TestWebhooks_LabURLsReplaceTheServers(t *testing.T) {
+	jm := NewJobManager("")
+	server, serverURL := newWebhookReceiver(t)
+	lab, labURL := newWebhookReceiver(t)
+	stop := testWebhookNotifier(jm, []string{serverURL}).Start()
+
+	id := jm.CreateJob(&LabConfig{StackName: "workshop", WebhookURLs: []string{labURL}})
+	jm.UpdateJobStatus(id, JobStatusDestroyed)
+	stop()
+
+	assert.Equal(t, 0, server.requests())
+	assert.Equal(t, 1, lab.requests())
+}
+
+func TestWebhooks_RetriesServerErrors(t *testing.T) {
+	jm := NewJobManager("")
+	rec, url := newWebhookReceiver(t, http.StatusBadGateway, http.StatusTooManyRequests)
+	stop := testWebhookNotifier(jm, []string{url}).Start()
+
+	id := jm.CreateJob(&LabConfig{StackName: "workshop"})
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	stop()
+
+	assert.Equal(t, 3, rec.requests())
+	job, _ := jm.GetJob(id)
+	require.Len(t, job.WebhookDeliveries, 1)
+	assert.True(t, job.WebhookDeliveries[0].Delivered)
+	assert.Equal(t, 3, job.WebhookDeliveries[0].Attempts)
+}
+
+func TestWebhooks_GivesUpOnClientErrors(t *testing.T) {
+	jm := NewJobManager("")
+	rec, url := newWebhookReceiver(t, http.StatusNotFound)
+	stop := testWebhookNotifier(jm, []string{url + "/hooks/T000/token"}).Start()
+
+	id := jm.CreateJob(&LabConfig{StackName: "workshop"})
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	stop()
+
+	assert.Equal(t, 1, rec.requests(), "a 4xx is not retried")
+	job, _ := jm.GetJob(id)
+	require.Len(t, job.WebhookDeliveries, 1)
+	delivery := job.WebhookDeliveries[0]
+	assert.False(t, delivery.Delivered)
+	assert.Equal(t, http.StatusNotFound, delivery.StatusCode)
+	assert.Equal(t, "HTTP 404", delivery.Error)
+	assert.Equal(t, url+"/…", delivery.Target, "the token in the path is not logged")
+}
+
+func TestWebhooks_OtherStatusesAreNotNotified(t *testing.T) {
+	jm := NewJobManager("")
+	rec, url := newWebhookReceiver(t)
+	stop := testWebhookNotifier(jm, []string{url}).Start()
+
+	id := jm.CreateJob(&LabConfig{StackName: "workshop"})
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.UpdateJobStatus(id, JobStatusDryRunCompleted)
+	stop()
+
+	assert.Equal(t, 0, rec.requests())
+}
+
+func TestWebhooks_OnlyTheFirstCompletionIsNotified(t *testing.T) {
+	jm := NewJobManager("")
+	rec, url := newWebhookReceiver(t)
+	stop := testWebhookNotifier(jm, []string{url}).Start()
+
+	id := jm.CreateJob(&LabConfig{StackName: "workshop"})
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	// A node pool scale runs the deployed lab again.
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	// Once destroyed, a new deployment is notified again.
+	jm.UpdateJobStatus(id, JobStatusDestroyed)
+	jm.UpdateJobStatus(id, JobStatusRunning)
+	jm.UpdateJobStatus(id, JobStatusCompleted)
+	stop()
+
+	require.Equal(t, 3, rec.requests())
+	events := make([]string, 0, 3)
+	for _, h := range rec.headers {
+		events = append(events, h.Get("X-EasyLab-Event"))
+	}
+	assert.ElementsMatch(t, []string{"job.completed", "job.destroyed", "job.completed"}, events)
+}
+
+func TestParseWebhookURLs(t *testing.T) {
+	urls, err := ParseWebhookURLs(" https://a.example.com/hook ,\nhttp://b.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://a.example.com/hook", "http://b.example.com"}, urls)
+
+	urls, err = ParseWebhookURLs("")
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+
+	for _, invalid := range []string{"hooks.example.com", "ftp://example.com/hook", "https://"} {
+		_, err := ParseWebhookURLs(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLabConfigValidate_WebhookURLs(t *testing.T) {
+	config, err := ParseLabConfig([]byte(validLabConfigYAML + "webhook_urls:\n  - not-a-url\n"))
+	require.NoError(t, err)
+	errs := config.Validate()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "invalid webhook URL")
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"fmt"
+
+	"easylab/internal/providers/workspace"
+)
+
+// validateMaxWorkspaces checks a lab's workspace capacity; 0 is no limit.
+func validateMaxWorkspaces(n int) error {
+	if n < 0 {
+		return fmt.Errorf("max_workspaces cannot be negative (got %d)", n)
+	}
+	return nil
+}
+
+// workspaceSeat reports whether a lab holding existing workspaces, at most
+// maxWorkspaces of them, has room for the workspace a student asks for, and
+// whether it is the last seat. The workspace the student already has, for the
+// same template or under the name they ask for, takes no new seat.
+func workspaceSeat(existing []workspace.Workspace, maxWorkspaces int, owner, template, name string) (allowed, last bool) {
+	for _, ws := range existing {
+		if ws.Owner != owner {
+			continue
+		}
+		if (name != "" && ws.Name == name) || (name == "" && ws.Template == template) {
+			return true, false
+		}
+	}
+	if len(existing) >= maxWorkspaces {
+		return false, false
+	}
+	return true, len(existing)+1 == maxWorkspaces
+}
+
+// ownWorkspace returns the workspace owner already has for template among the
+// existing workspaces of a lab, whatever it was named.
+func ownWorkspace(existing []workspace.Workspace, owner, template string) (workspace.Workspace, bool) {
+	for _, ws := range existing {
+		if ws.Owner == owner && ws.Template == template {
+			return ws, true
+		}
+	}
+	return workspace.Workspace{}, false
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+)
+
+// DeleteOwnWorkspace lets a student delete one of their own workspaces in a lab,
+// to hand its resources back once they are done. The student confirms by typing
+// the workspace name again in the confirm field. Only a workspace of the lab
+// owned by the authenticated student is deleted; any other name is answered like
+// a workspace that does not exist, so the endpoint reveals nothing of others.
+// Route: POST /api/student/workspace/delete
+func (h *Handler) DeleteOwnWorkspace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.parseForm(w, r, 1<<20); err != nil {
+		return
+	}
+
+	// The owner is always the authenticated student — never trusted from the client.
+	email := studentEmailFromContext(r)
+	owner := usernameFromEmail(email)
+	if owner == "" {
+		http.Error(w, "Session email not found, please log in again", http.StatusUnauthorized)
+		return
+	}
+	labID := getFormValue(r, "lab_id")
+	name := getFormValue(r, "workspace_name")
+	if labID == "" || name == "" {
+		http.Error(w, "Lab ID and workspace name are required", http.StatusBadRequest)
+		return
+	}
+	if getFormValue(r, "confirm") != name {
+		writeHTMLFragment(w, http.StatusBadRequest, `<div class="error-message">Type the workspace name to confirm its deletion.</div>`)
+		return
+	}
+
+	if reqErr := h.deleteStudentWorkspace(r.Context(), email, labID, name); reqErr != nil {
+		if reqErr.Plain {
+			http.Error(w, reqErr.Message, reqErr.Status)
+			return
+		}
+		status := http.StatusOK
+		if reqErr.Status == http.StatusNotFound {
+			status = http.StatusNotFound
+		}
+		writeHTMLFragment(w, status, studentErrorHTML(reqErr))
+		return
+	}
+	writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="success-message">Workspace <strong>%s</strong> deleted. Thank you for freeing its resources.</div>`, template.HTMLEscapeString(name)))
+}
+
+// deleteStudentWorkspace deletes the workspace name of the student with email
+// in a lab. It is shared by the student portal and the JSON API.
+func (h *Handler) deleteStudentWorkspace(ctx context.Context, email, labID, name string) *requestError {
+	owner := usernameFromEmail(email)
+	job, exists := h.jobManager.GetJob(labID)
+	if !exists {
+		return &requestError{Status: http.StatusNotFound, Title: "Lab Not Found", Message: "Lab not found", Plain: true}
+	}
+
+	job.mu.RLock()
+	status := job.Status
+	kubeconfig := extractStringFromConfigValue(job.Kubeconfig)
+	namespace := job.workspaceNamespace()
+	job.mu.RUnlock()
+
+	if status != JobStatusCompleted || kubeconfig == "" {
+		return &requestError{Status: http.StatusBadRequest, Title: "Lab Not Ready", Message: "Lab is not ready yet", Plain: true}
+	}
+
+	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
+	if err != nil {
+		slog.ErrorContext(ctx, "DeleteOwnWorkspace: failed to build backend for lab", "job_id", labID, "error", err)
+		return errClusterUnreachable
+	}
+	// Listing the lab's workspaces, rather than looking the name up, also makes
+	// sure the workspace belongs to this lab.
+	all, err := backend.ListWorkspaces(ctx, labID)
+	if err != nil {
+		slog.ErrorContext(ctx, "DeleteOwnWorkspace: failed to list workspaces in lab", "job_id", labID, "error", err)
+		return errClusterUnreachable
+	}
+	wsID := ""
+	for _, ws := range all {
+		if ws.Name == name && ws.Owner == owner {
+			wsID = ws.ID
+			break
+		}
+	}
+	if wsID == "" {
+		slog.WarnContext(ctx, "DeleteOwnWorkspace: no such workspace for the student", "email", email, "workspace", name, "job_id", labID)
+		return &requestError{Status: http.StatusNotFound, Title: "Workspace Not Found", Message: "You have no workspace with this name in this lab."}
+	}
+
+	if err := backend.DeleteWorkspace(ctx, labID, wsID); err != nil {
+		slog.ErrorContext(ctx, "DeleteOwnWorkspace: failed to delete workspace", "workspace_id", wsID, "job_id", labID, "error", err)
+		return &requestError{Status: http.StatusBadGateway, Title: "Workspace Error", Message: "The workspace could not be deleted. Please try again or contact the lab administrator."}
+	}
+	slog.InfoContext(ctx, "Workspace deleted by its owner", "component", "audit", "workspace_id", wsID, "job_id", labID, "email", email)
+	return nil
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deleteOwnRequest(t *testing.T, labID, name, confirm string) *http.Request {
+	t.Helper()
+	req := postForm(t, "/api/student/workspace/delete", url.Values{"lab_id": {labID}, "workspace_name": {name}, "confirm": {confirm}})
+	return req.WithContext(context.WithValue(req.Context(), studentEmailContextKey, "student@example.com"))
+}
+
+func labWorkspaces() []workspace.Workspace {
+	return []workspace.Workspace{
+		{ID: "student-docker", Name: "student-docker", Owner: "student"},
+		{ID: "other-docker", Name: "other-docker", Owner: "other"},
+	}
+}
+
+func TestDeleteOwnWorkspace_DeletesTheStudentsWorkspace(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{workspaces: labWorkspaces()}
+	useFakeBackend(h, fb)
+
+	rec := httptest.NewRecorder()
+	h.DeleteOwnWorkspace(rec, deleteOwnRequest(t, labID, "student-docker", "student-docker"))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "student-docker")
+	assert.Equal(t, []string{"student-docker"}, fb.DeleteCalls)
+}
+
+func TestDeleteOwnWorkspace_RejectsAnotherStudentsWorkspace(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{workspaces: labWorkspaces()}
+	useFakeBackend(h, fb)
+
+	rec := httptest.NewRecorder()
+	h.DeleteOwnWorkspace(rec, deleteOwnRequest(t, labID, "other-docker", "other-docker"))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, fb.DeleteCalls, "another student's workspace must not be deleted")
+}
+
+func TestDeleteOwnWorkspace_RequiresConfirmation(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{workspaces: labWorkspaces()}
+	useFakeBackend(h, fb)
+
+	for _, confirm := range []string{"", "yes", "student"} {
+		rec := httptest.NewRecorder()
+		h.DeleteOwnWorkspace(rec, deleteOwnRequest(t, labID, "student-docker", confirm))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, confirm)
+	}
+	assert.Empty(t, fb.DeleteCalls)
+}
@@ -17,6 +17,7 @@ type fakeBackend struct {
 	ensureErr  error
 	listErr    error
 	deleteErr  error
+	rotateErr  error
 
 	// routingDomain/routingScheme stand in for the backend's fallback resolution
 	// when the lab has no domain of its own.
@@ -25,6 +26,7 @@ type fakeBackend struct {
 
 	DeleteCalls []string
 	Ensured     []workspace.Spec
+	Rotated     map[string]string // workspace id -> new token
 }
 
 func (f *fakeBackend) EnsureWorkspace(_ context.Context, spec workspace.Spec) (workspace.Workspace, error) {
@@ -60,6 +62,23 @@ func (f *fakeBackend) DeleteWorkspace(_ context.Context, _ , id string) error {
 	return f.deleteErr
 }
 
+func (f *fakeBackend) RotateToken(_ context.Context, _, id, token string) (workspace.Workspace, error) {
+	if f.rotateErr != nil {
+		return workspace.Workspace{}, f.rotateErr
+	}
+	if f.Rotated == nil {
+		f.Rotated = make(map[string]string)
+	}
+	f.Rotated[id] = token
+	for _, ws := range f.workspaces {
+		if ws.ID == id {
+			ws.Token = token
+			return ws, nil
+		}
+	}
+	return workspace.Workspace{}, fmt.Errorf("not found")
+}
+
 func (f *fakeBackend) Reachable(_ context.Context) bool { return f.reachable }
 
 func (f *fakeBackend) Routing(_ context.Context, labDomain string) (string, string) {
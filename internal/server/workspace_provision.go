@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"easylab/internal/providers/workspace"
+)
+
+// maxProvisionStudents bounds one bulk provisioning request: each student is a
+// workspace created on the lab's cluster while the request waits, a listing and
+// four creations that take a few seconds on a busy cluster. It keeps the request
+// well within the server's 5-minute write timeout; a larger class is provisioned
+// in several batches.
+const maxProvisionStudents = 40
+
+// Placeholders of a bulk provisioning name pattern, such as "{template}-{username}".
+const (
+	placeholderUsername = "{username}" // the student's username, from their email
+	placeholderTemplate = "{template}" // the template's name
+	placeholderIndex    = "{n}"        // the student's position in the list, from 1
+)
+
+// expandWorkspaceName fills in a name pattern for the nth student of a bulk
+// provisioning request. An empty pattern gives "", the derived default name.
+func expandWorkspaceName(pattern, username, templateName string, n int) (string, error) {
+	if pattern == "" {
+		return "", nil
+	}
+	name := strings.NewReplacer(
+		placeholderUsername, username,
+		placeholderTemplate, templateName,
+		placeholderIndex, strconv.Itoa(n),
+	).Replace(pattern)
+	name = strings.ToLower(name)
+	if err := workspace.ValidateName(name); err != nil {
+		return "", fmt.Errorf("%q: %w", name, err)
+	}
+	return name, nil
+}
+
+// parseProvisionEmails reads the students of a bulk provisioning request, one
+// email per line or separated by commas. Duplicates are dropped.
+func parseProvisionEmails(s string) ([]string, error) {
+	seen := make(map[string]bool)
+	var emails []string
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		email := strings.ToLower(strings.TrimSpace(field))
+		if email == "" || seen[email] {
+			continue
+		}
+		if !strings.Contains(email, "@") || usernameFromEmail(email) == "" {
+			return nil, fmt.Errorf("invalid email %q", email)
+		}
+		seen[email] = true
+		emails = append(emails, email)
+	}
+	if len(emails) == 0 {
+		return nil, fmt.Errorf("at least one student email is required")
+	}
+	if len(emails) > maxProvisionStudents {
+		return nil, fmt.Errorf("at most %d students can be provisioned at once (got %d)", maxProvisionStudents, len(emails))
+	}
+	return emails, nil
+}
+
+// provisionResult is the outcome of one student's workspace in a bulk
+// provisioning request, with the password for the admin to hand out. A
+// workspace that already existed is reported without its password.
+type provisionResult struct {
+	Email         string `json:"email"`
+	WorkspaceName string `json:"workspace_name,omitempty"`
+	WorkspaceURL  string `json:"workspace_url,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Reused        bool   `json:"reused,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ProvisionWorkspaces creates a workspace for each student of a list ahead of a
+// workshop, named from a pattern with the {username}, {template} and {n}
+// placeholders. Each workspace is created as if the student had requested it,
+// so asking for it later without a name hands it back. Every name is checked
+// before anything is created; a student whose workspace fails is reported and
+// the others go on.
+//
+//	POST /api/labs/{id}/workspaces/provision  emails=...&template_id=...&name_pattern=...
+func (h *Handler) ProvisionWorkspaces(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 5 || pathParts[0] != "api" || (pathParts[1] != "jobs" && pathParts[1] != "labs") || pathParts[3] != "workspaces" || pathParts[4] != "provision" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	labID := pathParts[2]
+	fail := func(status int, msg string) {
+		if isHTMXRequest(r) {
+			writeHTMLFragment(w, status, `<div class="error-message">`+template.HTMLEscapeString(msg)+`</div>`)
+			return
+		}
+		writeJSONError(w, status, msg)
+	}
+
+	job, exists := h.jobManager.GetJob(labID)
+	if !exists {
+		fail(http.StatusNotFound, "Lab not found")
+		return
+	}
+	job.mu.RLock()
+	var templates []WorkspaceTemplate
+	if job.Config != nil {
+		templates = job.Config.GetWorkspaceTemplates()
+	}
+	job.mu.RUnlock()
+
+	emails, err := parseProvisionEmails(r.FormValue("emails"))
+	if err != nil {
+		fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	templateName := r.FormValue("template_id")
+	if templateName == "" && len(templates) > 0 {
+		templateName = templates[0].Name
+	}
+	pattern := strings.TrimSpace(r.FormValue("name_pattern"))
+
+	names := make([]string, len(emails))
+	taken := make(map[string]string)
+	for i, email := range emails {
+		name, err := expandWorkspaceName(pattern, usernameFromEmail(email), templateName, i+1)
+		if err != nil {
+			fail(http.StatusBadRequest, "Invalid name pattern: "+err.Error())
+			return
+		}
+		if other, dup := taken[name]; dup && name != "" {
+			fail(http.StatusBadRequest, fmt.Sprintf("The name pattern gives %s and %s the same workspace name %q: add {username} or {n}", other, email, name))
+			return
+		}
+		taken[name] = email
+		names[i] = name
+	}
+
+	results := make([]provisionResult, 0, len(emails))
+	created := 0
+	for i, email := range emails {
+		ws, reqErr := h.createStudentWorkspace(r.Context(), email, studentWorkspaceRequest{LabID: labID, Template: templateName, WorkspaceName: names[i]})
+		if reqErr != nil {
+			results = append(results, provisionResult{Email: email, WorkspaceName: names[i], Error: reqErr.Message})
+			continue
+		}
+		created++
+		results = append(results, provisionResult{Email: email, WorkspaceName: ws.WorkspaceName, WorkspaceURL: ws.WorkspaceURL, Password: ws.Password, Reused: ws.Reused})
+	}
+	slog.InfoContext(r.Context(), "Workspaces provisioned", "job_id", labID, "students", len(emails), "provisioned", created)
+
+	if isHTMXRequest(r) {
+		var b strings.Builder
+		b.WriteString(`<table class="provision-results"><thead><tr><th>Email</th><th>Workspace</th><th>Password</th><th>Result</th></tr></thead><tbody>`)
+		for _, res := range results {
+			outcome := "Provisioned"
+			switch {
+			case res.Error != "":
+				outcome = res.Error
+			case res.Reused:
+				outcome = "Already provisioned, password not shown again"
+			}
+			b.WriteString(fmt.Sprintf(`<tr><td>%s</td><td>%s</td><td><code>%s</code></td><td>%s</td></tr>`,
+				template.HTMLEscapeString(res.Email), template.HTMLEscapeString(res.WorkspaceName),
+				template.HTMLEscapeString(res.Password), template.HTMLEscapeString(outcome)))
+		}
+		b.WriteString(`</tbody></table>`)
+		writeHTMLFragment(w, http.StatusOK, b.String())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workspaces":  results,
+		"provisioned": created,
+	})
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandWorkspaceName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{pattern: "", want: ""},
+		{pattern: "{template}-{username}", want: "docker-alice"},
+		{pattern: "Lab-{n}", want: "lab-3"},
+		{pattern: "{username}_{n}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := expandWorkspaceName(tt.pattern, "alice", "docker", 3)
+		if tt.wantErr {
+			assert.Error(t, err, tt.pattern)
+			continue
+		}
+		require.NoError(t, err, tt.pattern)
+		assert.Equal(t, tt.want, got, tt.pattern)
+	}
+}
+
+func TestParseProvisionEmails(t *testing.T) {
+	t.Parallel()
+	emails, err := parseProvisionEmails("Alice@example.com\r\nbob@example.com, alice@example.com\n\n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, emails)
+
+	_, err = parseProvisionEmails("alice@example.com\nnot-an-email")
+	assert.ErrorContains(t, err, "not-an-email")
+	_, err = parseProvisionEmails(" \n ")
+	assert.Error(t, err)
+
+	var many []string
+	for i := 0; i <= maxProvisionStudents; i++ {
+		many = append(many, fmt.Sprintf("student%d@example.com", i))
+	}
+	_, err = parseProvisionEmails(strings.Join(many[:maxProvisionStudents], "\n"))
+	assert.NoError(t, err)
+	_, err = parseProvisionEmails(strings.Join(many, "\n"))
+	assert.ErrorContains(t, err, "at most")
+}
+
+func TestProvisionWorkspaces(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{reachable: true}
+	useFakeBackend(h, fb)
+	labID := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(labID)
+	job.mu.Lock()
+	job.Config.WorkspaceTemplates = []WorkspaceTemplate{{Name: "default"}, {Name: "docker"}}
+	job.mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	h.ProvisionWorkspaces(rec, postForm(t, "/api/labs/"+labID+"/workspaces/provision", url.Values{
+		"emails":       {"alice@example.com\nbob@example.com"},
+		"template_id":  {"docker"},
+		"name_pattern": {"{template}-{username}"},
+	}))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, fb.Ensured, 2)
+	assert.Equal(t, "docker-alice", fb.Ensured[0].Name)
+	assert.Equal(t, "docker-bob", fb.Ensured[1].Name)
+	assert.Equal(t, "docker", fb.Ensured[1].Template)
+
+	var body struct {
+		Workspaces  []provisionResult `json:"workspaces"`
+		Provisioned int               `json:"provisioned"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, 2, body.Provisioned)
+	require.Len(t, body.Workspaces, 2)
+	assert.NotEmpty(t, body.Workspaces[0].Password, "the admin hands the password out")
+	assert.Empty(t, body.Workspaces[0].Error)
+}
+
+func TestProvisionWorkspaces_CollidingPattern(t *testing.T) {
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{reachable: true}
+	useFakeBackend(h, fb)
+	labID := completedLabWithKubeconfig(jm, 0)
+
+	rec := httptest.NewRecorder()
+	h.ProvisionWorkspaces(rec, postForm(t, "/api/labs/"+labID+"/workspaces/provision", url.Values{
+		"emails":       {"alice@example.com\nbob@example.com"},
+		"name_pattern": {"workshop"},
+	}))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "same workspace name")
+	assert.Empty(t, fb.Ensured, "nothing is created when a name is wrong")
+}
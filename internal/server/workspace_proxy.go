@@ -0,0 +1,171 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Workspace proxy mode: /labs/{labID}/coder/{workspace}/... is forwarded to the
+// student's workspace through the lab server. Some venues firewall arbitrary
+// external IPs but allow the lab server's domain, and on those networks the
+// workspace host ("{workspace}.{domain}") is unreachable.
+//
+// The proxy is off unless the admin enables it on the lab. It only strips the
+// path prefix — nothing is injected into the request — and it streams bodies in
+// both directions, so large uploads and code-server's terminal websocket pass
+// through unchanged.
+
+// workspaceProxySegment is the path segment that marks a proxied request.
+const workspaceProxySegment = "coder"
+
+// IsWorkspaceProxyPath reports whether path is a workspace proxy request, so the
+// router can send it through student authentication rather than the admin check
+// that guards the rest of /labs/.
+func IsWorkspaceProxyPath(path string) bool {
+	_, _, _, ok := parseWorkspaceProxyPath(path)
+	return ok
+}
+
+// workspaceProxyURL is the server-relative URL a student opens to reach a
+// workspace through the proxy. The trailing slash matters: code-server resolves
+// its assets relative to it.
+func workspaceProxyURL(labID, workspaceName string) string {
+	return "/labs/" + url.PathEscape(labID) + "/" + workspaceProxySegment + "/" + url.PathEscape(workspaceName) + "/"
+}
+
+// parseWorkspaceProxyPath splits /labs/{labID}/coder/{workspace}/{rest...} into
+// its parts. rest always starts with "/"; a bare workspace path maps to "/".
+func parseWorkspaceProxyPath(path string) (labID, workspaceName, rest string, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 5)
+	if len(parts) < 4 || parts[0] != "labs" || parts[2] != workspaceProxySegment {
+		return "", "", "", false
+	}
+	if parts[1] == "" || parts[3] == "" {
+		return "", "", "", false
+	}
+	rest = "/"
+	if len(parts) == 5 {
+		rest += parts[4]
+	}
+	return parts[1], parts[3], rest, true
+}
+
+// ProxyWorkspace forwards a student's request to their own workspace.
+// Route: ANY /labs/{labID}/coder/{workspace}/...
+func (h *Handler) ProxyWorkspace(w http.ResponseWriter, r *http.Request) {
+	labID, workspaceName, rest, ok := parseWorkspaceProxyPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	// The owner is always the authenticated student — never trusted from the client.
+	owner := usernameFromEmail(studentEmailFromContext(r))
+	if owner == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, exists := h.jobManager.GetJob(labID)
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	job.mu.RLock()
+	status := job.Status
+	enabled := job.Config != nil && job.Config.WorkspaceProxyEnabled
+	kubeconfig := extractStringFromConfigValue(job.Kubeconfig)
+	namespace := job.workspaceNamespace()
+	job.mu.RUnlock()
+
+	// A lab without proxy mode must look exactly like a lab with no such route.
+	if !enabled {
+		http.NotFound(w, r)
+		return
+	}
+	if status != JobStatusCompleted || kubeconfig == "" {
+		http.Error(w, "lab is not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "ProxyWorkspace: failed to build backend for lab", "job_id", labID, "error", err)
+		http.Error(w, "lab is not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	ws, err := backend.GetWorkspace(r.Context(), workspaceName)
+	// Authorization: a student may only reach their own workspace.
+	if err != nil || ws.Owner != owner || ws.URL == "" {
+		slog.ErrorContext(r.Context(), "ProxyWorkspace: lookup/authz failed in lab", "workspace", workspaceName, "owner", owner, "job_id", labID, "error", err)
+		http.Error(w, "workspace not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	target, err := url.Parse(ws.URL)
+	if err != nil || target.Host == "" {
+		slog.ErrorContext(r.Context(), "ProxyWorkspace: invalid workspace URL", "url", ws.URL, "job_id", labID, "error", err)
+		http.Error(w, "workspace not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The server's read/write timeouts are sized for ordinary page loads and would
+	// cut a terminal session or a long upload mid-stream. Lift them for this
+	// connection only; the workspace enforces its own idle handling.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	newWorkspaceProxy(target, rest).ServeHTTP(w, r)
+}
+
+// newWorkspaceProxy builds the reverse proxy for one request. Websocket upgrades
+// are handled by httputil.ReverseProxy itself; FlushInterval -1 flushes every
+// write so streamed responses are not held back in a buffer.
+func newWorkspaceProxy(target *url.URL, rest string) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.URL.Path = singleJoiningSlash(target.Path, rest)
+			pr.Out.URL.RawPath = ""
+			// The portal session belongs to EasyLab, not to the workspace.
+			stripCookie(pr.Out, StudentSessionCookieName)
+			stripCookie(pr.Out, SessionCookieName)
+		},
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.Warn("ProxyWorkspace: upstream failed", "upstream", target.Host, "error", err)
+			http.Error(w, "workspace not reachable", http.StatusBadGateway)
+		},
+	}
+}
+
+// singleJoiningSlash joins a base path and a request path with exactly one "/".
+func singleJoiningSlash(a, b string) string {
+	switch aslash, bslash := strings.HasSuffix(a, "/"), strings.HasPrefix(b, "/"); {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+// stripCookie removes the named cookie from an outgoing request, keeping the rest.
+func stripCookie(r *http.Request, name string) {
+	cookies := r.Cookies()
+	if len(cookies) == 0 {
+		return
+	}
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != name {
+			r.AddCookie(c)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"easylab/internal/providers/workspace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkspaceProxyPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		path      string
+		wantLab   string
+		wantWS    string
+		wantRest  string
+		wantMatch bool
+	}{
+		{name: "workspace root", path: "/labs/job-1/coder/ws-alice", wantLab: "job-1", wantWS: "ws-alice", wantRest: "/", wantMatch: true},
+		{name: "workspace root with slash", path: "/labs/job-1/coder/ws-alice/", wantLab: "job-1", wantWS: "ws-alice", wantRest: "/", wantMatch: true},
+		{name: "nested path", path: "/labs/job-1/coder/ws-alice/static/app.js", wantLab: "job-1", wantWS: "ws-alice", wantRest: "/static/app.js", wantMatch: true},
+		{name: "no workspace", path: "/labs/job-1/coder/", wantMatch: false},
+		{name: "admin workspaces page", path: "/labs/job-1/workspaces", wantMatch: false},
+		{name: "other prefix", path: "/api/labs/job-1/coder/ws-alice", wantMatch: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			lab, ws, rest, ok := parseWorkspaceProxyPath(tt.path)
+			assert.Equal(t, tt.wantMatch, ok)
+			assert.Equal(t, tt.wantMatch, IsWorkspaceProxyPath(tt.path))
+			if !tt.wantMatch {
+				return
+			}
+			assert.Equal(t, tt.wantLab, lab)
+			assert.Equal(t, tt.wantWS, ws)
+			assert.Equal(t, tt.wantRest, rest)
+		})
+	}
+}
+
+func TestWorkspaceProxyURL_RoundTrips(t *testing.T) {
+	t.Parallel()
+
+	u := workspaceProxyURL("job-1", "ws-alice")
+	assert.Equal(t, "/labs/job-1/coder/ws-alice/", u)
+	lab, ws, rest, ok := parseWorkspaceProxyPath(u)
+	require.True(t, ok)
+	assert.Equal(t, "job-1", lab)
+	assert.Equal(t, "ws-alice", ws)
+	assert.Equal(t, "/", rest)
+}
+
+// newProxyTestHandler wires a handler to a completed lab whose single workspace,
+// owned by "student", is served by upstream.
+func newProxyTestHandler(t *testing.T, proxyEnabled bool, upstream string) (*Handler, string) {
+	t.Helper()
+	jm := NewJobManager("")
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{getWS: &workspace.Workspace{ID: "ws-student", Owner: "student", URL: upstream + "/"}})
+
+	id := completedLabWithKubeconfig(jm, 0)
+	job, _ := jm.GetJob(id)
+	job.mu.Lock()
+	job.Config.WorkspaceProxyEnabled = proxyEnabled
+	job.mu.Unlock()
+	return h, id
+}
+
+func proxyRequest(path, email string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	return req.WithContext(context.WithValue(req.Context(), studentEmailContextKey, email))
+}
+
+func TestProxyWorkspace_DisabledByDefault(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("upstream must not be reached when proxy mode is off")
+	}))
+	defer upstream.Close()
+
+	h, id := newProxyTestHandler(t, false, upstream.URL)
+	rec := httptest.NewRecorder()
+	h.ProxyWorkspace(rec, proxyRequest("/labs/"+id+"/coder/ws-student/", "student@example.com"))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestProxyWorkspace_ForwardsStrippedPath(t *testing.T) {
+	var gotPath, gotCookies string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.RequestURI()
+		gotCookies = r.Header.Get("Cookie")
+		io.WriteString(w, "code-server")
+	}))
+	defer upstream.Close()
+
+	h, id := newProxyTestHandler(t, true, upstream.URL)
+	req := proxyRequest("/labs/"+id+"/coder/ws-student/static/app.js?v=1", "student@example.com")
+	req.AddCookie(&http.Cookie{Name: StudentSessionCookieName, Value: "portal-session"})
+	req.AddCookie(&http.Cookie{Name: "code-server-session", Value: "ide-session"})
+	rec := httptest.NewRecorder()
+	h.ProxyWorkspace(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "code-server", rec.Body.String())
+	assert.Equal(t, "/static/app.js?v=1", gotPath)
+	assert.Contains(t, gotCookies, "code-server-session=ide-session")
+	assert.NotContains(t, gotCookies, StudentSessionCookieName, "the portal session must not reach the workspace")
+}
+
+func TestProxyWorkspace_RejectsOtherStudents(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("upstream must not be reached for another student's workspace")
+	}))
+	defer upstream.Close()
+
+	h, id := newProxyTestHandler(t, true, upstream.URL)
+
+	tests := []struct {
+		name  string
+		email string
+		want  int
+	}{
+		{name: "not logged in", email: "", want: http.StatusUnauthorized},
+		{name: "someone else's workspace", email: "mallory@example.com", want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ProxyWorkspace(rec, proxyRequest("/labs/"+id+"/coder/ws-student/", tt.email))
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Per-container defaults of a workspace quota, for containers that declare no
+// resources: workspaces sized by their template set their own, so these mostly
+// size sidecars and init containers.
+const (
+	defaultQuotaCPURequest    = "250m"
+	defaultQuotaMemoryRequest = "512Mi"
+	defaultQuotaCPULimit      = "1"
+	defaultQuotaMemoryLimit   = "2Gi"
+)
+
+// quotaNodeShare is the share of the node pool the workspace namespace may take
+// when the totals are derived from the flavor. The rest is left to the system
+// pods, the ingress controller and the lab's other add-ons.
+const quotaNodeShare = 0.8
+
+// parseWorkspaceQuota reads the wizard's quota fields, returning nil when the
+// quota is not enabled. Empty defaults get sensible values; empty totals are
+// left for sizeWorkspaceQuota to derive from the node pool.
+func parseWorkspaceQuota(r *http.Request) *WorkspaceQuota {
+	if r.FormValue("workspace_quota_enabled") != "true" {
+		return nil
+	}
+	valueOr := func(key, fallback string) string {
+		if v := strings.TrimSpace(r.FormValue(key)); v != "" {
+			return v
+		}
+		return fallback
+	}
+	return &WorkspaceQuota{
+		CPU:                  strings.TrimSpace(r.FormValue("quota_cpu")),
+		Memory:               strings.TrimSpace(r.FormValue("quota_memory")),
+		DefaultCPURequest:    valueOr("quota_default_cpu_request", defaultQuotaCPURequest),
+		DefaultMemoryRequest: valueOr("quota_default_memory_request", defaultQuotaMemoryRequest),
+		DefaultCPULimit:      valueOr("quota_default_cpu_limit", defaultQuotaCPULimit),
+		DefaultMemoryLimit:   valueOr("quota_default_memory_limit", defaultQuotaMemoryLimit),
+	}
+}
+
+// sizeWorkspaceQuota fills the totals the admin left empty from the node pool:
+// quotaNodeShare of its vCPUs and RAM. The wizard pre-fills the same values.
+func sizeWorkspaceQuota(quota *WorkspaceQuota, flavor ovhFlavor, nodes int) {
+	if quota == nil || nodes < 1 {
+		return
+	}
+	if quota.CPU == "" && flavor.VCPUs > 0 {
+		quota.CPU = fmt.Sprintf("%dm", int(float64(flavor.VCPUs*nodes*1000)*quotaNodeShare))
+	}
+	if quota.Memory == "" && flavor.RAM > 0 {
+		quota.Memory = fmt.Sprintf("%dMi", int(float64(flavor.RAM*nodes*1024)*quotaNodeShare))
+	}
+}
+
+// validateWorkspaceQuota rejects values Kubernetes would refuse, so a typo
+// fails at creation time rather than in the middle of pulumi up.
+func validateWorkspaceQuota(quota *WorkspaceQuota) error {
+	if quota == nil {
+		return nil
+	}
+	fields := []struct{ name, value string }{
+		{"total CPU", quota.CPU},
+		{"total memory", quota.Memory},
+		{"default CPU request", quota.DefaultCPURequest},
+		{"default memory request", quota.DefaultMemoryRequest},
+		{"default CPU limit", quota.DefaultCPULimit},
+		{"default memory limit", quota.DefaultMemoryLimit},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(f.value); err != nil {
+			return fmt.Errorf("invalid %s %q: use a Kubernetes quantity such as 2, 500m or 4Gi", f.name, f.value)
+		}
+	}
+	if exceeds(quota.DefaultCPURequest, quota.DefaultCPULimit) {
+		return fmt.Errorf("default CPU request %s is above the default CPU limit %s", quota.DefaultCPURequest, quota.DefaultCPULimit)
+	}
+	if exceeds(quota.DefaultMemoryRequest, quota.DefaultMemoryLimit) {
+		return fmt.Errorf("default memory request %s is above the default memory limit %s", quota.DefaultMemoryRequest, quota.DefaultMemoryLimit)
+	}
+	return nil
+}
+
+// exceeds reports whether quantity a is above b, both being set and valid.
+func exceeds(a, b string) bool {
+	qa, errA := resource.ParseQuantity(a)
+	qb, errB := resource.ParseQuantity(b)
+	return a != "" && b != "" && errA == nil && errB == nil && qa.Cmp(qb) > 0
+}
+
+// workspaceQuotaSummary describes the workspace quota for the dry-run output,
+// or returns "" when the lab sets none.
+func workspaceQuotaSummary(config *LabConfig) string {
+	if config == nil || config.WorkspaceQuota == nil {
+		return ""
+	}
+	q := config.WorkspaceQuota
+	orNone := func(v string) string {
+		if v == "" {
+			return "unlimited"
+		}
+		return v
+	}
+	return fmt.Sprintf("Workspace quota: CPU %s, memory %s; per container requests %s CPU / %s, limits %s CPU / %s",
+		orNone(q.CPU), orNone(q.Memory), q.DefaultCPURequest, q.DefaultMemoryRequest, q.DefaultCPULimit, q.DefaultMemoryLimit)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkspaceQuota(t *testing.T) {
+	parse := func(form url.Values) *WorkspaceQuota {
+		req := httptest.NewRequest("POST", "/api/labs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return parseWorkspaceQuota(req)
+	}
+
+	assert.Nil(t, parse(url.Values{"quota_cpu": {"4"}}), "the quota is off unless enabled")
+
+	quota := parse(url.Values{
+		"workspace_quota_enabled":   {"true"},
+		"quota_cpu":                 {" 4 "},
+		"quota_default_cpu_limit":   {"2"},
+		"quota_default_cpu_request": {""},
+	})
+	assert.Equal(t, &WorkspaceQuota{
+		CPU:                  "4",
+		DefaultCPURequest:    defaultQuotaCPURequest,
+		DefaultMemoryRequest: defaultQuotaMemoryRequest,
+		DefaultCPULimit:      "2",
+		DefaultMemoryLimit:   defaultQuotaMemoryLimit,
+	}, quota)
+}
+
+func TestSizeWorkspaceQuota(t *testing.T) {
+	flavor := ovhFlavor{Name: "b3-16", VCPUs: 4, RAM: 16}
+
+	quota := &WorkspaceQuota{}
+	sizeWorkspaceQuota(quota, flavor, 3)
+	assert.Equal(t, "9600m", quota.CPU, "80% of 3 nodes x 4 vCPU")
+	assert.Equal(t, "39321Mi", quota.Memory, "80% of 3 nodes x 16 GB")
+
+	quota = &WorkspaceQuota{CPU: "2", Memory: "8Gi"}
+	sizeWorkspaceQuota(quota, flavor, 3)
+	assert.Equal(t, &WorkspaceQuota{CPU: "2", Memory: "8Gi"}, quota, "the admin's totals are kept")
+
+	sizeWorkspaceQuota(nil, flavor, 3)
+}
+
+func TestValidateWorkspaceQuota(t *testing.T) {
+	assert.NoError(t, validateWorkspaceQuota(nil))
+	assert.NoError(t, validateWorkspaceQuota(&WorkspaceQuota{
+		CPU: "9600m", Memory: "39321Mi",
+		DefaultCPURequest: "250m", DefaultMemoryRequest: "512Mi",
+		DefaultCPULimit: "1", DefaultMemoryLimit: "2Gi",
+	}))
+
+	err := validateWorkspaceQuota(&WorkspaceQuota{Memory: "16 GB"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "total memory")
+	}
+	err = validateWorkspaceQuota(&WorkspaceQuota{DefaultCPURequest: "2", DefaultCPULimit: "500m"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "above the default CPU limit")
+	}
+	err = validateWorkspaceQuota(&WorkspaceQuota{DefaultMemoryRequest: "4Gi", DefaultMemoryLimit: "1Gi"})
+	assert.Error(t, err)
+}
+
+func TestWorkspaceQuotaSummary(t *testing.T) {
+	assert.Empty(t, workspaceQuotaSummary(&LabConfig{}))
+	summary := workspaceQuotaSummary(&LabConfig{WorkspaceQuota: &WorkspaceQuota{
+		CPU:               "9600m",
+		DefaultCPURequest: "250m", DefaultMemoryRequest: "512Mi",
+		DefaultCPULimit: "1", DefaultMemoryLimit: "2Gi",
+	}})
+	assert.Equal(t, "Workspace quota: CPU 9600m, memory unlimited; per container requests 250m CPU / 512Mi, limits 1 CPU / 2Gi", summary)
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// workspaceResendInterval is how often a student may ask for their workspace
+// details again, per lab. Each request lists the lab's workspaces on the cluster.
+const workspaceResendInterval = time.Minute
+
+// resendLimiter remembers when each (student, lab) last asked for their details.
+type resendLimiter struct {
+	last map[string]time.Time
+	mu   sync.Mutex
+}
+
+func newResendLimiter() *resendLimiter {
+	return &resendLimiter{last: make(map[string]time.Time)}
+}
+
+// allow reports whether key may be served at now, and records it if so. Entries
+// older than the interval are dropped on the way, so the map stays small.
+func (l *resendLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, t := range l.last {
+		if now.Sub(t) >= workspaceResendInterval {
+			delete(l.last, k)
+		}
+	}
+	if _, limited := l.last[key]; limited {
+		return false
+	}
+	l.last[key] = now
+	return true
+}
+
+// ResendWorkspaceInfo re-displays the URL and login of the authenticated
+// student's workspaces in a lab, for students who closed the tab before copying
+// them. The password is never shown again: it is the one secret here, and anyone
+// at an unlocked laptop could otherwise read it back.
+// Route: POST /api/student/workspace/resend
+func (h *Handler) ResendWorkspaceInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.parseForm(w, r, 1<<20); err != nil {
+		return
+	}
+
+	// The owner is always the authenticated student — never trusted from the client.
+	email := studentEmailFromContext(r)
+	owner := usernameFromEmail(email)
+	if owner == "" {
+		http.Error(w, "Session email not found, please log in again", http.StatusUnauthorized)
+		return
+	}
+	labID := getFormValue(r, "lab_id")
+	if labID == "" {
+		http.Error(w, "Lab ID is required", http.StatusBadRequest)
+		return
+	}
+
+	job, exists := h.jobManager.GetJob(labID)
+	if !exists {
+		http.Error(w, "Lab not found", http.StatusNotFound)
+		return
+	}
+
+	job.mu.RLock()
+	status := job.Status
+	kubeconfig := extractStringFromConfigValue(job.Kubeconfig)
+	namespace := job.workspaceNamespace()
+	proxyEnabled := job.Config != nil && job.Config.WorkspaceProxyEnabled
+	job.mu.RUnlock()
+
+	if status != JobStatusCompleted || kubeconfig == "" {
+		http.Error(w, "Lab is not ready yet", http.StatusBadRequest)
+		return
+	}
+
+	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "ResendWorkspaceInfo: failed to build backend for lab", "job_id", labID, "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<div class="error-message">Unable to reach the lab cluster. Please contact the lab administrator.</div>`)
+		return
+	}
+	// Only a request that reaches the cluster spends the student's turn: a
+	// mistyped or unready lab does not lock them out for a minute.
+	if !h.resendLimiter.allow(owner+"\x00"+labID, time.Now()) {
+		writeHTMLFragment(w, http.StatusTooManyRequests, `<div class="error-message">You just asked for these details. Please wait a minute before trying again.</div>`)
+		return
+	}
+	all, err := backend.ListWorkspaces(r.Context(), labID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "ResendWorkspaceInfo: failed to list workspaces in lab", "job_id", labID, "error", err)
+		writeHTMLFragment(w, http.StatusOK, `<div class="error-message">Unable to reach the lab cluster. Please contact the lab administrator.</div>`)
+		return
+	}
+
+	var b strings.Builder
+	count := 0
+	for _, ws := range all {
+		if ws.Owner != owner {
+			continue
+		}
+		count++
+		wsURL := ws.URL
+		if proxyEnabled {
+			wsURL = workspaceProxyURL(labID, ws.Name)
+		}
+		fmt.Fprintf(&b, `<div class="workspace-resend-item"><strong>%s</strong>`, template.HTMLEscapeString(ws.Name))
+		if wsURL != "" {
+			// The link goes through OpenWorkspace so the connection token is added at
+			// click time; the address shown is the one to bookmark.
+			openURL := fmt.Sprintf("/api/student/workspace/open?lab_id=%s&workspace_name=%s",
+				url.QueryEscape(labID), url.QueryEscape(ws.Name))
+			fmt.Fprintf(&b, `<div>URL: <a href="%s" target="_blank" rel="noopener">%s</a></div>`,
+				template.HTMLEscapeString(openURL), template.HTMLEscapeString(wsURL))
+		}
+		fmt.Fprintf(&b, `<div>Login: %s</div>`, template.HTMLEscapeString(email))
+		fmt.Fprintf(&b, `<button type="button" class="student-btn student-btn-small" data-lab-id="%s" data-workspace-name="%s" onclick="resetWorkspacePassword(this)">Reset password</button><div class="workspace-reset-response"></div></div>`,
+			template.HTMLEscapeString(labID), template.HTMLEscapeString(ws.Name))
+	}
+	slog.InfoContext(r.Context(), "Workspace details re-displayed", "email", email, "job_id", labID, "workspaces", count)
+	if count > 0 {
+		if marked, err := h.jobManager.RecordDetailsResend(labID, email, time.Now()); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to record the details resend on the roster", "email", email, "job_id", labID, "error", err)
+		} else if marked > 0 {
+			if err := h.jobManager.SaveJob(labID); err != nil {
+				slog.ErrorContext(r.Context(), "Failed to persist the roster of lab", "job_id", labID, "error", err)
+			}
+		}
+	}
+
+	if count == 0 {
+		writeHTMLFragment(w, http.StatusOK, `<div class="error-message">You have no workspace in this lab. You can request one from the <a href="/student/dashboard">Request a workspace</a> page.</div>`)
+		return
+	}
+	writeHTMLFragment(w, http.StatusOK, fmt.Sprintf(`<div class="workspace-resend">%s<p class="hint-text">Your password is not shown here. If you have lost it, use <strong>Reset password</strong>: the workspace gets a new password, shown once, and keeps its files.</p></div>`, b.String()))
+}
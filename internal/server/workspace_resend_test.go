@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resendRequest(t *testing.T, labID string) *http.Request {
+	t.Helper()
+	req := postForm(t, "/api/student/workspace/resend", url.Values{"lab_id": {labID}})
+	return req.WithContext(context.WithValue(req.Context(), studentEmailContextKey, "student@example.com"))
+}
+
+func TestResendWorkspaceInfo_ListsOwnWorkspacesWithoutPassword(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{workspaces: []workspace.Workspace{
+		{Name: "student-docker", Owner: "student", URL: "https://student-docker.lab.example.com", Token: "s3cret-token"},
+		{Name: "other-docker", Owner: "other", URL: "https://other-docker.lab.example.com"},
+	}})
+
+	rec := httptest.NewRecorder()
+	h.ResendWorkspaceInfo(rec, resendRequest(t, labID))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "student-docker")
+	assert.Contains(t, body, "https://student-docker.lab.example.com")
+	assert.Contains(t, body, "student@example.com")
+	assert.NotContains(t, body, "other-docker", "another student's workspace must not be listed")
+	assert.NotContains(t, body, "s3cret-token")
+	assert.Contains(t, body, `data-workspace-name="student-docker" onclick="resetWorkspacePassword(this)"`, "a lost password can be reset from here")
+}
+
+func TestResendWorkspaceInfo_RecordedOnTheRoster(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	require.NoError(t, jm.RecordRosterEntry(labID, RosterEntry{Email: "student@example.com", Username: "student", WorkspaceName: "student-docker"}))
+	require.NoError(t, jm.RecordRosterEntry(labID, RosterEntry{Email: "other@example.com", Username: "other", WorkspaceName: "other-docker"}))
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{workspaces: []workspace.Workspace{{Name: "student-docker", Owner: "student"}}})
+
+	h.ResendWorkspaceInfo(httptest.NewRecorder(), resendRequest(t, labID))
+
+	job, _ := jm.GetJob(labID)
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	require.NotNil(t, job.Roster[0].DetailsResentAt)
+	assert.Nil(t, job.Roster[1].DetailsResentAt, "only the student's own entries")
+}
+
+func TestResendWorkspaceInfo_RateLimited(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{})
+
+	rec := httptest.NewRecorder()
+	h.ResendWorkspaceInfo(rec, resendRequest(t, labID))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ResendWorkspaceInfo(rec, resendRequest(t, labID))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func TestResendWorkspaceInfo_InvalidLabDoesNotSpendTheTurn(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	deploying := jm.CreateJob(&LabConfig{StackName: "deploying"})
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	useFakeBackend(h, &fakeBackend{})
+
+	rec := httptest.NewRecorder()
+	h.ResendWorkspaceInfo(rec, resendRequest(t, "job-missing"))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = httptest.NewRecorder()
+	h.ResendWorkspaceInfo(rec, resendRequest(t, deploying))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = httptest.NewRecorder()
+	h.ResendWorkspaceInfo(rec, resendRequest(t, deploying))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "still not ready, not rate limited")
+
+	rec = httptest.NewRecorder()
+	h.ResendWorkspaceInfo(rec, resendRequest(t, labID))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestResendWorkspaceInfo_RequiresLabID(t *testing.T) {
+	h := NewHandler(NewJobManager(""), &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	rec := httptest.NewRecorder()
+	h.ResendWorkspaceInfo(rec, resendRequest(t, ""))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestResendLimiter_AllowsAgainAfterInterval(t *testing.T) {
+	t.Parallel()
+	l := newResendLimiter()
+	now := time.Now()
+	assert.True(t, l.allow("a", now))
+	assert.False(t, l.allow("a", now.Add(time.Second)))
+	assert.True(t, l.allow("b", now.Add(time.Second)), "keys are limited independently")
+	assert.True(t, l.allow("a", now.Add(workspaceResendInterval)))
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"easylab/internal/providers/workspace"
+)
+
+// ResetWorkspacePassword gives one of the authenticated student's workspaces a
+// new password, for a student who lost the one shown when it was created. The
+// old password stops working and the new one is shown once, like at creation;
+// the existing one is never shown. The student confirms by typing the workspace
+// name again, since the IDE restarts to pick the new password up. As with
+// deletion, a workspace the student does not own is answered like one that does
+// not exist.
+// Route: POST /api/student/workspace/reset-password
+func (h *Handler) ResetWorkspacePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.parseForm(w, r, 1<<20); err != nil {
+		return
+	}
+
+	// The owner is always the authenticated student — never trusted from the client.
+	email := studentEmailFromContext(r)
+	if usernameFromEmail(email) == "" {
+		http.Error(w, "Session email not found, please log in again", http.StatusUnauthorized)
+		return
+	}
+	labID := getFormValue(r, "lab_id")
+	name := getFormValue(r, "workspace_name")
+	if labID == "" || name == "" {
+		http.Error(w, "Lab ID and workspace name are required", http.StatusBadRequest)
+		return
+	}
+	if getFormValue(r, "confirm") != name {
+		writeHTMLFragment(w, http.StatusBadRequest, `<div class="error-message">Type the workspace name to confirm the password reset.</div>`)
+		return
+	}
+
+	ws, reqErr := h.resetStudentWorkspacePassword(r.Context(), email, labID, name)
+	if reqErr != nil {
+		if reqErr.Plain {
+			http.Error(w, reqErr.Message, reqErr.Status)
+			return
+		}
+		status := http.StatusOK
+		if reqErr.Status == http.StatusNotFound {
+			status = http.StatusNotFound
+		}
+		writeHTMLFragment(w, status, studentErrorHTML(reqErr))
+		return
+	}
+	setWorkspaceInfoCookie(w, r, ws)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<div class="success-message">The password of <strong>%s</strong> was reset.`, template.HTMLEscapeString(name))
+	fmt.Fprintf(&b, `<div class="credential-item"><label>New connection token:</label><div class="value">%s</div></div>`, template.HTMLEscapeString(ws.Password))
+	b.WriteString(`<p><strong>Important:</strong> Please save it now: it is not shown again. The workspace restarts to use it, which may take a minute.</p></div>`)
+	writeHTMLFragment(w, http.StatusOK, b.String())
+}
+
+// resetStudentWorkspacePassword rotates the password of the workspace name of
+// the student with email in a lab, and returns the workspace with its new
+// password.
+func (h *Handler) resetStudentWorkspacePassword(ctx context.Context, email, labID, name string) (studentWorkspace, *requestError) {
+	owner := usernameFromEmail(email)
+	job, exists := h.jobManager.GetJob(labID)
+	if !exists {
+		return studentWorkspace{}, &requestError{Status: http.StatusNotFound, Title: "Lab Not Found", Message: "Lab not found", Plain: true}
+	}
+
+	job.mu.RLock()
+	status := job.Status
+	kubeconfig := extractStringFromConfigValue(job.Kubeconfig)
+	namespace := job.workspaceNamespace()
+	proxyEnabled := job.Config != nil && job.Config.WorkspaceProxyEnabled
+	labName := ""
+	if job.Config != nil {
+		labName = job.Config.StackName
+	}
+	job.mu.RUnlock()
+
+	if status != JobStatusCompleted || kubeconfig == "" {
+		return studentWorkspace{}, &requestError{Status: http.StatusBadRequest, Title: "Lab Not Ready", Message: "Lab is not ready yet", Plain: true}
+	}
+
+	backend, err := h.newWorkspaceBackend(kubeconfig, namespace)
+	if err != nil {
+		slog.ErrorContext(ctx, "ResetWorkspacePassword: failed to build backend for lab", "job_id", labID, "error", err)
+		return studentWorkspace{}, errClusterUnreachable
+	}
+	rotator, ok := backend.(workspace.TokenRotator)
+	if !ok {
+		return studentWorkspace{}, &requestError{Status: http.StatusNotImplemented, Title: "Reset Unavailable", Message: "The password of a workspace in this lab cannot be reset. Delete the workspace and request a new one instead."}
+	}
+	all, err := backend.ListWorkspaces(ctx, labID)
+	if err != nil {
+		slog.ErrorContext(ctx, "ResetWorkspacePassword: failed to list workspaces in lab", "job_id", labID, "error", err)
+		return studentWorkspace{}, errClusterUnreachable
+	}
+	var own *workspace.Workspace
+	for i := range all {
+		if all[i].Name == name && all[i].Owner == owner {
+			own = &all[i]
+			break
+		}
+	}
+	if own == nil {
+		slog.WarnContext(ctx, "ResetWorkspacePassword: no such workspace for the student", "email", email, "workspace", name, "job_id", labID)
+		return studentWorkspace{}, &requestError{Status: http.StatusNotFound, Title: "Workspace Not Found", Message: "You have no workspace with this name in this lab."}
+	}
+
+	password, err := GenerateWorkspaceToken()
+	if err != nil {
+		slog.ErrorContext(ctx, "ResetWorkspacePassword: failed to generate a password", "error", err)
+		return studentWorkspace{}, &requestError{Status: http.StatusInternalServerError, Title: "Workspace Error", Message: "The password could not be reset. Please try again."}
+	}
+	ws, err := rotator.RotateToken(ctx, labID, own.ID, password)
+	if err != nil {
+		slog.ErrorContext(ctx, "ResetWorkspacePassword: failed to rotate the token", "workspace_id", own.ID, "job_id", labID, "error", err)
+		return studentWorkspace{}, &requestError{Status: http.StatusBadGateway, Title: "Workspace Error", Message: "The password could not be reset. Please try again or contact the lab administrator."}
+	}
+	slog.InfoContext(ctx, "Workspace password reset by its owner", "component", "audit", "workspace_id", own.ID, "job_id", labID, "email", email)
+
+	workspaceURL := ws.URL
+	if proxyEnabled {
+		workspaceURL = workspaceProxyURL(labID, ws.Name)
+	}
+	createdAt := ws.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	return studentWorkspace{
+		Email:         email,
+		WorkspaceURL:  workspaceURL,
+		Password:      password,
+		WorkspaceName: ws.Name,
+		LabID:         labID,
+		LabName:       labName,
+		Template:      ws.Template,
+		CreatedAt:     createdAt.Format(time.RFC3339),
+	}, nil
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"easylab/internal/providers/workspace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetPasswordRequest(t *testing.T, labID, name, confirm string) *http.Request {
+	t.Helper()
+	req := postForm(t, "/api/student/workspace/reset-password", url.Values{"lab_id": {labID}, "workspace_name": {name}, "confirm": {confirm}})
+	return req.WithContext(context.WithValue(req.Context(), studentEmailContextKey, "student@example.com"))
+}
+
+func TestResetWorkspacePassword_ShowsOnlyTheNewPassword(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{workspaces: []workspace.Workspace{{ID: "student-docker", Name: "student-docker", Owner: "student", Token: "old-token"}}}
+	useFakeBackend(h, fb)
+
+	rec := httptest.NewRecorder()
+	h.ResetWorkspacePassword(rec, resetPasswordRequest(t, labID, "student-docker", "student-docker"))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	newToken := fb.Rotated["student-docker"]
+	require.NotEmpty(t, newToken)
+	assert.NotEqual(t, "old-token", newToken)
+	assert.Contains(t, rec.Body.String(), newToken)
+	assert.NotContains(t, rec.Body.String(), "old-token")
+	assert.NotEmpty(t, rec.Result().Cookies(), "the saved workspace info gets the new password")
+}
+
+func TestResetWorkspacePassword_RejectsAnotherStudentsWorkspace(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{workspaces: labWorkspaces()}
+	useFakeBackend(h, fb)
+
+	rec := httptest.NewRecorder()
+	h.ResetWorkspacePassword(rec, resetPasswordRequest(t, labID, "other-docker", "other-docker"))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, fb.Rotated, "another student's password must not be reset")
+}
+
+func TestResetWorkspacePassword_RequiresConfirmation(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+	fb := &fakeBackend{workspaces: labWorkspaces()}
+	useFakeBackend(h, fb)
+
+	for _, confirm := range []string{"", "yes", "student"} {
+		rec := httptest.NewRecorder()
+		h.ResetWorkspacePassword(rec, resetPasswordRequest(t, labID, "student-docker", confirm))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, confirm)
+	}
+	assert.Empty(t, fb.Rotated)
+}
+
+func TestResetWorkspacePassword_Failures(t *testing.T) {
+	jm := NewJobManager("")
+	labID := completedLabWithKubeconfig(jm, 0)
+	h := NewHandler(jm, &PulumiExecutor{}, NewCredentialsManager(), nil, nil, nil)
+
+	// A backend that cannot rotate tokens points the student to a new workspace.
+	fb := &fakeBackend{workspaces: labWorkspaces()}
+	h.newWorkspaceBackend = func(_, _ string) (workspace.Backend, error) { return struct{ workspace.Backend }{fb}, nil }
+	rec := httptest.NewRecorder()
+	h.ResetWorkspacePassword(rec, resetPasswordRequest(t, labID, "student-docker", "student-docker"))
+	assert.Contains(t, rec.Body.String(), "request a new one")
+
+	// A failed rotation does not leak the cause.
+	fb = &fakeBackend{workspaces: labWorkspaces(), rotateErr: errors.New("deployments.apps is forbidden")}
+	useFakeBackend(h, fb)
+	rec = httptest.NewRecorder()
+	h.ResetWorkspacePassword(rec, resetPasswordRequest(t, labID, "student-docker", "student-docker"))
+	assert.Contains(t, rec.Body.String(), "could not be reset")
+	assert.NotContains(t, rec.Body.String(), "forbidden")
+}
@@ -8,7 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -33,6 +33,7 @@ workspace_templates:
     # cpu: 500m
     # memory: 1Gi
     # disk_size: 5Gi
+    # gpu: 1                                     # NVIDIA GPUs; needs a GPU node pool flavor
     # startup_script: |                          # runs (best-effort) before the IDE starts
     #   apt-get update && apt-get install -y jq
     # dotfiles_repo: https://github.com/you/dotfiles
@@ -254,6 +255,9 @@ func validateWorkspaceTemplates(templates []WorkspaceTemplate) error {
 		if t.IDE != "" && t.IDE != workspace.IDEOpenVSCode && t.IDE != workspace.IDECodeServer {
 			return fmt.Errorf("%s: ide must be %q, got %q", where, workspace.IDECodeServer, t.IDE)
 		}
+		if t.GPU < 0 {
+			return fmt.Errorf("%s: gpu must not be negative, got %d", where, t.GPU)
+		}
 		if t.GitRepo != "" && !validateURL(t.GitRepo) {
 			return fmt.Errorf("%s: git_repo %q is not a valid URL", where, t.GitRepo)
 		}
@@ -461,7 +465,7 @@ func (h *Handler) exportWorkspaceTemplatesYAML(w http.ResponseWriter, r *http.Re
 
 	rendered, err := marshalWorkspaceTemplatesYAML(templates)
 	if err != nil {
-		log.Printf("Failed to render workspace templates YAML for lab %s: %v", labID, err)
+		slog.ErrorContext(r.Context(), "Failed to render workspace templates YAML for lab", "job_id", labID, "error", err)
 		http.Error(w, "Failed to export workspace templates", http.StatusInternalServerError)
 		return
 	}
@@ -482,7 +486,7 @@ func (h *Handler) exportWorkspaceTemplatesYAML(w http.ResponseWriter, r *http.Re
 // yet the admin gets the commented skeleton instead.
 func (h *Handler) seedWorkspaceTemplatesYAML(w http.ResponseWriter, r *http.Request) {
 	if err := h.parseForm(w, r, 0); err != nil {
-		log.Printf("Failed to parse form while seeding templates YAML: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse form while seeding templates YAML", "error", err)
 		http.Error(w, "Failed to read the form", http.StatusBadRequest)
 		return
 	}
@@ -496,7 +500,7 @@ func (h *Handler) seedWorkspaceTemplatesYAML(w http.ResponseWriter, r *http.Requ
 
 	rendered, err := marshalWorkspaceTemplatesYAML(templates)
 	if err != nil {
-		log.Printf("Failed to render workspace templates YAML from form: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to render workspace templates YAML from form", "error", err)
 		http.Error(w, "Failed to build YAML from the form", http.StatusInternalServerError)
 		return
 	}
@@ -511,7 +515,7 @@ func (h *Handler) ValidateWorkspaceTemplatesYAML(w http.ResponseWriter, r *http.
 		return
 	}
 	if err := h.parseForm(w, r, 0); err != nil {
-		log.Printf("Failed to parse form while validating templates YAML: %v", err)
+		slog.WarnContext(r.Context(), "Failed to parse form while validating templates YAML", "error", err)
 		writeToast(w, false, "Could not read the submitted YAML.")
 		return
 	}
@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	"easylab/utils"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// PinnedChart is a chart the labs install: its repository and the version
+// EasyLab is tested with.
+type PinnedChart struct {
+	Repo    string
+	Version string
+}
+
+// PinnedCharts are the charts the labs install, by chart name. Pinning them
+// keeps two labs built a month apart alike; a lab can still install another
+// version with helm:chartVersions. Bump them together with a test lab.
+var PinnedCharts = map[string]PinnedChart{
+	"cert-manager":          {Repo: "https://charts.jetstack.io", Version: "v1.16.2"},
+	"external-dns":          {Repo: "https://kubernetes-sigs.github.io/external-dns/", Version: "1.15.0"},
+	"ingress-nginx":         {Repo: "https://kubernetes.github.io/ingress-nginx", Version: "4.11.3"},
+	"kube-prometheus-stack": {Repo: "https://prometheus-community.github.io/helm-charts", Version: "66.3.1"},
+	"nvidia-device-plugin":  {Repo: "https://nvidia.github.io/k8s-device-plugin", Version: "0.17.0"},
+}
+
+// WithPinnedVersion returns the chart with its repository and version filled
+// in from PinnedCharts where it sets none, and the version the lab pins for it
+// in versions, if any, over both.
+func (chart HelmChartInfo) WithPinnedVersion(versions map[string]string) HelmChartInfo {
+	pinned := PinnedCharts[chart.ChartName]
+	if chart.Url == "" {
+		chart.Url = pinned.Repo
+	}
+	if chart.Version == "" {
+		chart.Version = pinned.Version
+	}
+	if v, ok := versions[chart.ChartName]; ok && v != "" {
+		chart.Version = v
+	}
+	return chart
+}
+
+// ChartVersion returns the version of chartName to install: the lab's
+// helm:chartVersions entry, or the pinned one.
+func ChartVersion(ctx *pulumi.Context, chartName string) (string, error) {
+	versions, err := utils.HelmChartVersionsConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	return HelmChartInfo{ChartName: chartName}.WithPinnedVersion(versions).Version, nil
+}
@@ -0,0 +1,47 @@
+package k8s
+
+import (
+	"fmt"
+
+	k8s "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	k8score "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	helmv3 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	k8smeta "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const nvidiaDevicePluginNamespace = "nvidia-device-plugin"
+
+// InitNvidiaDevicePlugin installs the NVIDIA device plugin DaemonSet, which
+// advertises the GPUs of each node as the nvidia.com/gpu resource. Without it a
+// GPU node pool comes up fine but no pod can request a GPU.
+func InitNvidiaDevicePlugin(ctx *pulumi.Context, provider *k8s.Provider) (*helmv3.Release, error) {
+	ns, err := k8score.NewNamespace(ctx, "nvidia-device-plugin-ns", &k8score.NamespaceArgs{
+		Metadata: &k8smeta.ObjectMetaArgs{Name: pulumi.String(nvidiaDevicePluginNamespace)},
+	}, pulumi.Provider(provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nvidia-device-plugin namespace: %w", err)
+	}
+
+	release, err := InitHelm(ctx, provider, HelmChartInfo{
+		Name:        "nvidia-device-plugin",
+		ChartName:   "nvidia-device-plugin",
+		ReleaseName: "nvidia-device-plugin",
+		Values:      nvidiaDevicePluginValues(),
+	}, ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to install nvidia-device-plugin: %w", err)
+	}
+	return release, nil
+}
+
+// nvidiaDevicePluginValues lets the plugin run on every node whatever its
+// taints: it is a per-node agent, and GPU pools are often tainted to keep
+// other workloads off.
+func nvidiaDevicePluginValues() pulumi.Map {
+	return pulumi.Map{
+		"tolerations": pulumi.Array{
+			pulumi.StringMap{"operator": pulumi.String("Exists")},
+		},
+	}
+}
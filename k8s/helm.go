@@ -1,6 +1,8 @@
 package k8s
 
 import (
+	"easylab/utils"
+
 	k8s "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
 	v1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	helmv3 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
@@ -27,7 +29,25 @@ func helmTimeout(t int) int {
 	return 1800
 }
 
+// WithRepoOverride returns the chart pulled from the repository overrides maps
+// its chart name to, such as an internal mirror, or the chart unchanged.
+func (chart HelmChartInfo) WithRepoOverride(overrides map[string]string) HelmChartInfo {
+	if url, ok := overrides[chart.ChartName]; ok && url != "" {
+		chart.Url = url
+	}
+	return chart
+}
+
 func InitHelm(ctx *pulumi.Context, provider *k8s.Provider, chart HelmChartInfo, namespace *v1.Namespace) (*helmv3.Release, error) {
+	overrides, err := utils.HelmRepoOverridesConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := utils.HelmChartVersionsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chart = chart.WithPinnedVersion(versions).WithRepoOverride(overrides)
 
 	releaseArgs := &helmv3.ReleaseArgs{
 		Chart:           pulumi.String(chart.ChartName),
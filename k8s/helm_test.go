@@ -0,0 +1,41 @@
+package k8s
+
+import "testing"
+
+func TestHelmChartInfo_WithRepoOverride(t *testing.T) {
+	chart := HelmChartInfo{Name: "ingress-nginx", ChartName: "ingress-nginx", Url: "https://kubernetes.github.io/ingress-nginx"}
+
+	mirrored := chart.WithRepoOverride(map[string]string{"ingress-nginx": "https://charts.example.internal/ingress-nginx"})
+	if mirrored.Url != "https://charts.example.internal/ingress-nginx" {
+		t.Errorf("WithRepoOverride() Url = %q, want the mirror", mirrored.Url)
+	}
+	if chart.Url != "https://kubernetes.github.io/ingress-nginx" {
+		t.Errorf("WithRepoOverride() changed the original chart to %q", chart.Url)
+	}
+
+	for _, overrides := range []map[string]string{nil, {"cert-manager": "https://charts.example.internal/cert-manager"}, {"ingress-nginx": ""}} {
+		if got := chart.WithRepoOverride(overrides).Url; got != chart.Url {
+			t.Errorf("WithRepoOverride(%v) Url = %q, want the upstream %q", overrides, got, chart.Url)
+		}
+	}
+}
+
+func TestHelmChartInfo_WithPinnedVersion(t *testing.T) {
+	chart := HelmChartInfo{Name: "ingress-nginx", ChartName: "ingress-nginx"}
+
+	pinned := chart.WithPinnedVersion(nil)
+	if pinned.Version != PinnedCharts["ingress-nginx"].Version || pinned.Url != PinnedCharts["ingress-nginx"].Repo {
+		t.Errorf("WithPinnedVersion(nil) = %s from %q, want the pinned %+v", pinned.Version, pinned.Url, PinnedCharts["ingress-nginx"])
+	}
+	if got := chart.WithPinnedVersion(map[string]string{"ingress-nginx": "4.12.0"}).Version; got != "4.12.0" {
+		t.Errorf("WithPinnedVersion() Version = %q, want the lab's 4.12.0", got)
+	}
+	if got := chart.WithPinnedVersion(map[string]string{"cert-manager": "v1.17.0"}).Version; got != PinnedCharts["ingress-nginx"].Version {
+		t.Errorf("WithPinnedVersion() Version = %q, want the pinned one for another chart's pin", got)
+	}
+
+	unknown := HelmChartInfo{ChartName: "my-chart", Url: "https://charts.example.com", Version: "1.0.0"}.WithPinnedVersion(nil)
+	if unknown.Version != "1.0.0" || unknown.Url != "https://charts.example.com" {
+		t.Errorf("WithPinnedVersion() changed a chart that is not pinned: %+v", unknown)
+	}
+}
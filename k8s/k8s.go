@@ -20,14 +20,14 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-func InitK8sProvider(ctx *pulumi.Context, kubeCluster *cloudproject.Kube, nodePools []*cloudproject.KubeNodePool) (*k8s.Provider, error) {
+func InitK8sProvider(ctx *pulumi.Context, kubeconfig pulumi.StringOutput, kubeCluster *cloudproject.Kube, nodePools []*cloudproject.KubeNodePool) (*k8s.Provider, error) {
 	dependencies := []pulumi.Resource{kubeCluster}
 	for _, np := range nodePools {
 		dependencies = append(dependencies, np)
 	}
 
 	provider, err := k8s.NewProvider(ctx, "k8sProvider", &k8s.ProviderArgs{
-		Kubeconfig: kubeCluster.Kubeconfig,
+		Kubeconfig: kubeconfig,
 		KubeClientSettings: &k8s.KubeClientSettingsArgs{
 			Timeout: pulumi.Int(900), // 15 min - avoid "context deadline exceeded" during Helm installs
 		},
@@ -99,13 +99,25 @@ func InitNamespace(ctx *pulumi.Context, provider *k8s.Provider) (*k8score.Namesp
 // kubeconfigOut is the kubeconfig content as a StringOutput.
 // trigger is any Pulumi output whose resolution gates this lookup, ensuring the
 // service exists before the read (e.g. a Helm release's ResourceNames output).
-func GetServiceIP(kubeconfigOut pulumi.StringOutput, trigger interface{}, namespace, name string) pulumi.StringOutput {
+// With opts.NodePortFallback, a service that gets no IP within opts.Timeout is
+// returned as "{node IP}:{NodePort}" instead, and the fallback is logged.
+func GetServiceIP(ctx *pulumi.Context, kubeconfigOut pulumi.StringOutput, trigger interface{}, namespace, name string, opts ServiceAddressOptions) pulumi.StringOutput {
 	return pulumi.All(kubeconfigOut, trigger).ApplyT(func(args []interface{}) (string, error) {
 		kubeconfig, _ := args[0].(string)
 		if kubeconfig == "" {
 			return "", fmt.Errorf("kubeconfig is empty")
 		}
-		return fetchServiceIP(kubeconfig, namespace, name)
+		return resolveServiceAddress(
+			func() (string, error) { return fetchServiceIP(kubeconfig, namespace, name, opts.Timeout) },
+			func() (string, error) {
+				client, err := clientFromKubeconfig(kubeconfig)
+				if err != nil {
+					return "", err
+				}
+				return nodePortAddress(client, namespace, name)
+			},
+			opts.NodePortFallback,
+			func(msg string) { utils.LogInfo(ctx, msg) })
 	}).(pulumi.StringOutput)
 }
 
@@ -138,9 +150,10 @@ type kubeconfigYAML struct {
 
 // fetchServiceIP reads a service's LoadBalancer IP via a direct HTTPS call to the
 // Kubernetes API, using client-certificate credentials from the kubeconfig content.
-// It retries for up to 10 minutes to handle the race condition where the cloud
-// provider (e.g. OVHcloud) has not yet assigned the LoadBalancer IP.
-func fetchServiceIP(kubeconfigContent, namespace, serviceName string) (string, error) {
+// It retries for up to timeout (10 minutes when 0) to handle the race condition
+// where the cloud provider (e.g. OVHcloud) has not yet assigned the LoadBalancer
+// IP. Running out of time returns errNoLoadBalancerIP.
+func fetchServiceIP(kubeconfigContent, namespace, serviceName string, timeout time.Duration) (string, error) {
 	var kc kubeconfigYAML
 	if err := yaml.Unmarshal([]byte(kubeconfigContent), &kc); err != nil {
 		return "", fmt.Errorf("failed to parse kubeconfig: %w", err)
@@ -214,13 +227,14 @@ func fetchServiceIP(kubeconfigContent, namespace, serviceName string) (string, e
 
 	apiURL := fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s", serverURL, namespace, serviceName)
 
-	// Retry for up to 10 minutes (40 × 15 s). This handles two cases:
+	// Retry for up to timeout, every 15 s. This handles two cases:
 	//   1. The cloud provider hasn't assigned the LoadBalancer IP yet.
 	//   2. The API server is temporarily unreachable (transient network blip).
+	attempts := serviceIPAttempts(timeout)
 	var lastErr error
-	for attempt := 0; attempt < 40; attempt++ {
+	for attempt := 0; attempt < attempts; attempt++ {
 		if attempt > 0 {
-			time.Sleep(15 * time.Second)
+			time.Sleep(serviceIPPollInterval)
 		}
 
 		req, reqErr := http.NewRequest(http.MethodGet, apiURL, nil)
@@ -265,5 +279,6 @@ func fetchServiceIP(kubeconfigContent, namespace, serviceName string) (string, e
 		}
 		lastErr = fmt.Errorf("attempt %d: service %s/%s has no LoadBalancer IP yet", attempt+1, namespace, serviceName)
 	}
-	return "", fmt.Errorf("timed out waiting 10 min for LoadBalancer IP of service %s/%s: %w", namespace, serviceName, lastErr)
+	return "", fmt.Errorf("timed out waiting %s for LoadBalancer IP of service %s/%s: %w (%w)",
+		time.Duration(attempts)*serviceIPPollInterval, namespace, serviceName, errNoLoadBalancerIP, lastErr)
 }
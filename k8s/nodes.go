@@ -0,0 +1,147 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"easylab/utils"
+
+	"github.com/ovh/pulumi-ovh/sdk/v2/go/ovh/cloudproject"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// DefaultNodeReadyTimeout is how long the nodes of a new cluster get to become
+// Ready when the lab does not set nodepool:readyTimeout.
+const DefaultNodeReadyTimeout = 20 * time.Minute
+
+const nodeReadyPollInterval = 15 * time.Second
+
+// nodeState is what one poll reports of a node.
+type nodeState struct {
+	Name  string
+	Ready bool
+	// Reason and Message come from the node's Ready condition.
+	Reason  string
+	Message string
+}
+
+// waitForNodes polls the cluster's nodes until expected of them are Ready,
+// logging each change in the count. On timeout the error names every node that
+// is not Ready and why, or says how many never registered. Poll errors are
+// retried: the API server is often flaky while the nodes join.
+func waitForNodes(expected int, list func() ([]nodeState, error), interval, timeout time.Duration, sleep func(time.Duration), logf func(string)) error {
+	var nodes []nodeState
+	var lastErr error
+	lastReady := -1
+	for waited := time.Duration(0); ; waited += interval {
+		listed, err := list()
+		if err != nil {
+			lastErr = err
+		} else {
+			nodes, lastErr = listed, nil
+			ready := 0
+			for _, n := range nodes {
+				if n.Ready {
+					ready++
+				}
+			}
+			if ready != lastReady {
+				logf(fmt.Sprintf("%d/%d nodes ready", ready, expected))
+				lastReady = ready
+			}
+			if ready >= expected {
+				return nil
+			}
+		}
+		if waited >= timeout {
+			break
+		}
+		sleep(interval)
+	}
+
+	if lastErr != nil && nodes == nil {
+		return fmt.Errorf("nodes not ready after %s: %w", timeout, lastErr)
+	}
+	var problems []string
+	for _, n := range nodes {
+		if n.Ready {
+			continue
+		}
+		problem := fmt.Sprintf("node %s is not Ready", n.Name)
+		if n.Reason != "" || n.Message != "" {
+			problem += fmt.Sprintf(" (%s: %s)", n.Reason, n.Message)
+		}
+		problems = append(problems, problem)
+	}
+	if missing := expected - len(nodes); missing > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d nodes never registered", missing, expected))
+	}
+	return fmt.Errorf("nodes not ready after %s: %s", timeout, strings.Join(problems, "; "))
+}
+
+// listNodes reads the nodes of the cluster and their Ready condition.
+func listNodes(client kubernetes.Interface) ([]nodeState, error) {
+	list, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	nodes := make([]nodeState, 0, len(list.Items))
+	for _, item := range list.Items {
+		n := nodeState{Name: item.Name, Reason: "NoReadyCondition"}
+		for _, c := range item.Status.Conditions {
+			if c.Type == corev1.NodeReady {
+				n.Ready, n.Reason, n.Message = c.Status == corev1.ConditionTrue, c.Reason, c.Message
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes, nil
+}
+
+// clientFromKubeconfig builds a client for the checks the program makes on the
+// cluster it deploys to.
+func clientFromKubeconfig(kubeconfig string) (kubernetes.Interface, error) {
+	cfg, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	cfg.Timeout = 30 * time.Second
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubernetes client: %w", err)
+	}
+	return client, nil
+}
+
+// WaitForNodesReady gates the kubeconfig on the cluster's nodes: it resolves
+// once the node pools exist and expected nodes are Ready, and logs the progress
+// in between. Resources built on it (the Kubernetes provider, then the Helm
+// releases) wait for a usable cluster instead of failing on a Helm timeout,
+// and a node stuck installing fails the update with its own condition.
+func WaitForNodesReady(ctx *pulumi.Context, kubeconfig pulumi.StringOutput, expected int, timeout time.Duration, nodePools []*cloudproject.KubeNodePool) pulumi.StringOutput {
+	inputs := []interface{}{kubeconfig}
+	for _, np := range nodePools {
+		inputs = append(inputs, np.ID())
+	}
+	return pulumi.All(inputs...).ApplyT(func(args []interface{}) (string, error) {
+		content := args[0].(string)
+		client, err := clientFromKubeconfig(content)
+		if err != nil {
+			return "", err
+		}
+		utils.LogInfo(ctx, fmt.Sprintf("Waiting up to %s for %d nodes to be ready...", timeout, expected))
+		if err := waitForNodes(expected, func() ([]nodeState, error) { return listNodes(client) },
+			nodeReadyPollInterval, timeout, time.Sleep, func(msg string) { utils.LogInfo(ctx, msg) }); err != nil {
+			return "", err
+		}
+		return content, nil
+	}).(pulumi.StringOutput)
+}
@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// pollSequence returns a node list function that replays polls, repeating the
+// last one once they run out.
+func pollSequence(polls ...[]nodeState) func() ([]nodeState, error) {
+	i := 0
+	return func() ([]nodeState, error) {
+		p := polls[min(i, len(polls)-1)]
+		i++
+		return p, nil
+	}
+}
+
+func TestWaitForNodes_LogsProgressUntilReady(t *testing.T) {
+	list := pollSequence(
+		nil,
+		[]nodeState{{Name: "node-a"}, {Name: "node-b"}},
+		[]nodeState{{Name: "node-a", Ready: true}, {Name: "node-b"}},
+		[]nodeState{{Name: "node-a", Ready: true}, {Name: "node-b"}},
+		[]nodeState{{Name: "node-a", Ready: true}, {Name: "node-b", Ready: true}},
+	)
+	var logs []string
+	sleeps := 0
+	err := waitForNodes(2, list, time.Second, time.Minute, func(time.Duration) { sleeps++ }, func(msg string) { logs = append(logs, msg) })
+	if err != nil {
+		t.Fatalf("waitForNodes() error = %v", err)
+	}
+	if want := []string{"0/2 nodes ready", "1/2 nodes ready", "2/2 nodes ready"}; strings.Join(logs, "|") != strings.Join(want, "|") {
+		t.Errorf("waitForNodes() logged %q, want %q", logs, want)
+	}
+	if sleeps != 4 {
+		t.Errorf("waitForNodes() slept %d times, want 4", sleeps)
+	}
+}
+
+func TestWaitForNodes_TimeoutNamesTheNodes(t *testing.T) {
+	list := pollSequence([]nodeState{
+		{Name: "node-a", Ready: true},
+		{Name: "node-b", Reason: "KubeletNotReady", Message: "container runtime network not ready"},
+	})
+	err := waitForNodes(3, list, time.Second, 3*time.Second, func(time.Duration) {}, func(string) {})
+	if err == nil {
+		t.Fatal("waitForNodes() error = nil, want a timeout")
+	}
+	for _, want := range []string{"node node-b is not Ready (KubeletNotReady: container runtime network not ready)", "1 of 3 nodes never registered"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("waitForNodes() error = %q, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "node-a") {
+		t.Errorf("waitForNodes() error = %q names a Ready node", err)
+	}
+}
+
+func TestWaitForNodes_RetriesListErrors(t *testing.T) {
+	calls := 0
+	list := func() ([]nodeState, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return []nodeState{{Name: "node-a", Ready: true}}, nil
+	}
+	if err := waitForNodes(1, list, time.Second, time.Minute, func(time.Duration) {}, func(string) {}); err != nil {
+		t.Fatalf("waitForNodes() error = %v", err)
+	}
+
+	never := func() ([]nodeState, error) { return nil, errors.New("connection refused") }
+	err := waitForNodes(1, never, time.Second, 2*time.Second, func(time.Duration) {}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("waitForNodes() error = %v, want the last list error", err)
+	}
+}
+
+func TestListNodes(t *testing.T) {
+	node := func(name string, conditions ...corev1.NodeCondition) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: corev1.NodeStatus{Conditions: conditions}}
+	}
+	client := fake.NewSimpleClientset(
+		node("node-b", corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Reason: "KubeletNotReady", Message: "PLEG is not healthy"}),
+		node("node-a", corev1.NodeCondition{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse}, corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue}),
+		node("node-c"),
+	)
+
+	got, err := listNodes(client)
+	if err != nil {
+		t.Fatalf("listNodes() error = %v", err)
+	}
+	want := []nodeState{
+		{Name: "node-a", Ready: true},
+		{Name: "node-b", Reason: "KubeletNotReady", Message: "PLEG is not healthy"},
+		{Name: "node-c", Reason: "NoReadyCondition"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("listNodes() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("listNodes()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
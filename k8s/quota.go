@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"fmt"
+
+	"easylab/utils"
+
+	k8s "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	k8score "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	k8smeta "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// InitWorkspaceQuota caps what the workspace namespace may use, so one
+// student's runaway build cannot starve the cluster. The ResourceQuota bounds
+// the namespace totals; the LimitRange gives every container that declares no
+// resources (sidecars, init containers) the default requests and limits, which
+// the quota needs to admit it. It does nothing when the lab sets no quota.
+func InitWorkspaceQuota(ctx *pulumi.Context, provider *k8s.Provider, namespace *k8score.Namespace) error {
+	quota, err := utils.WorkspaceQuotaConfig(ctx)
+	if err != nil || quota == nil {
+		return err
+	}
+
+	if hard := quotaHard(quota); len(hard) > 0 {
+		if _, err := k8score.NewResourceQuota(ctx, "workspace-quota", &k8score.ResourceQuotaArgs{
+			Metadata: &k8smeta.ObjectMetaArgs{
+				Name:      pulumi.String("workspace-quota"),
+				Namespace: namespace.Metadata.Name(),
+			},
+			Spec: &k8score.ResourceQuotaSpecArgs{Hard: hard},
+		}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{namespace})); err != nil {
+			return fmt.Errorf("failed to create workspace ResourceQuota: %w", err)
+		}
+	}
+
+	if limit, ok := limitRangeItem(quota); ok {
+		if _, err := k8score.NewLimitRange(ctx, "workspace-limits", &k8score.LimitRangeArgs{
+			Metadata: &k8smeta.ObjectMetaArgs{
+				Name:      pulumi.String("workspace-limits"),
+				Namespace: namespace.Metadata.Name(),
+			},
+			Spec: &k8score.LimitRangeSpecArgs{Limits: k8score.LimitRangeItemArray{limit}},
+		}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{namespace})); err != nil {
+			return fmt.Errorf("failed to create workspace LimitRange: %w", err)
+		}
+	}
+	return nil
+}
+
+// quotaHard maps the namespace totals onto the quota. CPU bounds the requests
+// only: a CPU limit just throttles, so overcommitting it is harmless. Memory
+// bounds both, as memory past the node's is what gets pods killed.
+func quotaHard(quota *utils.WorkspaceQuota) pulumi.StringMap {
+	hard := pulumi.StringMap{}
+	if quota.CPU != "" {
+		hard["requests.cpu"] = pulumi.String(quota.CPU)
+	}
+	if quota.Memory != "" {
+		hard["requests.memory"] = pulumi.String(quota.Memory)
+		hard["limits.memory"] = pulumi.String(quota.Memory)
+	}
+	return hard
+}
+
+// limitRangeItem is the per-container defaults, or false when none is set.
+func limitRangeItem(quota *utils.WorkspaceQuota) (k8score.LimitRangeItemArgs, bool) {
+	requests, limits := pulumi.StringMap{}, pulumi.StringMap{}
+	if quota.DefaultCPURequest != "" {
+		requests["cpu"] = pulumi.String(quota.DefaultCPURequest)
+	}
+	if quota.DefaultMemoryRequest != "" {
+		requests["memory"] = pulumi.String(quota.DefaultMemoryRequest)
+	}
+	if quota.DefaultCPULimit != "" {
+		limits["cpu"] = pulumi.String(quota.DefaultCPULimit)
+	}
+	if quota.DefaultMemoryLimit != "" {
+		limits["memory"] = pulumi.String(quota.DefaultMemoryLimit)
+	}
+	if len(requests) == 0 && len(limits) == 0 {
+		return k8score.LimitRangeItemArgs{}, false
+	}
+	item := k8score.LimitRangeItemArgs{Type: pulumi.String("Container")}
+	if len(requests) > 0 {
+		item.DefaultRequest = requests
+	}
+	if len(limits) > 0 {
+		item.Default = limits
+	}
+	return item, true
+}
@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"easylab/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NodePortFallbackAnnotation marks a LoadBalancer service that may be reached on
+// a node's external IP and NodePort while it has no LoadBalancer IP. The
+// workspace backend reads it to route workspaces the same way.
+const NodePortFallbackAnnotation = "easylab.io/node-port-fallback"
+
+// serviceIPPollInterval is how often the LoadBalancer IP of a service is polled.
+const serviceIPPollInterval = 15 * time.Second
+
+// errNoLoadBalancerIP is returned when a service got no LoadBalancer IP in time.
+var errNoLoadBalancerIP = errors.New("no LoadBalancer IP")
+
+// ServiceAddressOptions sets how long GetServiceIP waits for a LoadBalancer IP,
+// and what it does when none comes.
+type ServiceAddressOptions struct {
+	// Timeout is how long to wait; 0 is utils.DefaultIngressIPTimeout.
+	Timeout time.Duration
+	// NodePortFallback returns "{node IP}:{NodePort}" instead of failing when the
+	// wait times out.
+	NodePortFallback bool
+}
+
+// resolveServiceAddress waits for a LoadBalancer IP and, when the wait times
+// out and the fallback is on, returns the NodePort address instead. Other
+// errors, such as a missing service, are returned as they are.
+func resolveServiceAddress(waitIP func() (string, error), nodePort func() (string, error), fallback bool, logf func(string)) (string, error) {
+	ip, err := waitIP()
+	if err == nil {
+		return ip, nil
+	}
+	if !fallback || !errors.Is(err, errNoLoadBalancerIP) {
+		return "", err
+	}
+	address, npErr := nodePort()
+	if npErr != nil {
+		return "", fmt.Errorf("%w; NodePort fallback failed: %v", err, npErr)
+	}
+	logf(fmt.Sprintf("No LoadBalancer IP in time, falling back to NodePort: serving on %s", address))
+	return address, nil
+}
+
+// nodePortAddress returns "{ip}:{port}" where the service's HTTP port is reached
+// without its load balancer: the external IP of a Ready node, the first by
+// name, and the NodePort of the service's "http" port (or of port 80).
+func nodePortAddress(client kubernetes.Interface, namespace, serviceName string) (string, error) {
+	ctx := context.Background()
+	svc, err := client.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read service %s/%s: %w", namespace, serviceName, err)
+	}
+	port := httpNodePort(svc)
+	if port == 0 {
+		return "", fmt.Errorf("service %s/%s has no NodePort for HTTP", namespace, serviceName)
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list nodes: %w", err)
+	}
+	ip := nodeExternalIP(nodes.Items)
+	if ip == "" {
+		return "", fmt.Errorf("no Ready node has an external IP")
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(int(port))), nil
+}
+
+// httpNodePort returns the NodePort of the service's "http" port, or of its
+// port 80, or 0.
+func httpNodePort(svc *corev1.Service) int32 {
+	var byNumber int32
+	for _, p := range svc.Spec.Ports {
+		if p.Name == "http" && p.NodePort != 0 {
+			return p.NodePort
+		}
+		if p.Port == 80 && byNumber == 0 {
+			byNumber = p.NodePort
+		}
+	}
+	return byNumber
+}
+
+// nodeExternalIP returns the external IP of the first Ready node by name, or "".
+func nodeExternalIP(nodes []corev1.Node) string {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, n := range nodes {
+		ready := false
+		for _, c := range n.Status.Conditions {
+			if c.Type == corev1.NodeReady && c.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+		if !ready {
+			continue
+		}
+		for _, a := range n.Status.Addresses {
+			if a.Type == corev1.NodeExternalIP && a.Address != "" {
+				return a.Address
+			}
+		}
+	}
+	return ""
+}
+
+// serviceIPAttempts is how many polls fit in timeout, at least one.
+func serviceIPAttempts(timeout time.Duration) int {
+	if timeout <= 0 {
+		timeout = utils.DefaultIngressIPTimeout
+	}
+	return max(1, int(timeout/serviceIPPollInterval))
+}
@@ -0,0 +1,161 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolveServiceAddress(t *testing.T) {
+	timedOut := fmt.Errorf("timed out waiting 10m0s for LoadBalancer IP of service ingress-nginx/ingress-nginx-controller: %w", errNoLoadBalancerIP)
+	tests := []struct {
+		name        string
+		ipErr       error
+		fallback    bool
+		nodePortErr error
+		want        string
+		wantErr     string
+		wantLog     bool
+	}{
+		{name: "the LoadBalancer IP comes", want: "51.178.10.20"},
+		{name: "the LoadBalancer IP comes, fallback unused", fallback: true, want: "51.178.10.20"},
+		{name: "timeout without fallback", ipErr: timedOut, wantErr: "timed out"},
+		{name: "timeout with fallback", ipErr: timedOut, fallback: true, want: "57.128.1.2:31080", wantLog: true},
+		{name: "timeout with failing fallback", ipErr: timedOut, fallback: true, nodePortErr: errors.New("no Ready node has an external IP"), wantErr: "NodePort fallback failed: no Ready node"},
+		{name: "other errors do not fall back", ipErr: errors.New("service ingress-nginx/ingress-nginx-controller not found in cluster"), fallback: true, wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodePortCalls := 0
+			var logs []string
+			got, err := resolveServiceAddress(
+				func() (string, error) {
+					if tt.ipErr != nil {
+						return "", tt.ipErr
+					}
+					return "51.178.10.20", nil
+				},
+				func() (string, error) {
+					nodePortCalls++
+					if tt.nodePortErr != nil {
+						return "", tt.nodePortErr
+					}
+					return "57.128.1.2:31080", nil
+				},
+				tt.fallback,
+				func(msg string) { logs = append(logs, msg) })
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveServiceAddress() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("resolveServiceAddress() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveServiceAddress() = %q, want %q", got, tt.want)
+			}
+			if tt.ipErr == nil && nodePortCalls != 0 {
+				t.Error("resolveServiceAddress() looked up the NodePort with a LoadBalancer IP")
+			}
+			if (len(logs) > 0) != tt.wantLog {
+				t.Errorf("resolveServiceAddress() logged %q, want a log: %v", logs, tt.wantLog)
+			}
+		})
+	}
+}
+
+func fallbackNode(name, externalIP string, ready bool) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+		},
+	}
+	if externalIP != "" {
+		node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: externalIP})
+	}
+	return node
+}
+
+func controllerService(ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress-nginx-controller", Namespace: "ingress-nginx"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: ports},
+	}
+}
+
+func TestNodePortAddress(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		controllerService(
+			corev1.ServicePort{Name: "https", Port: 443, NodePort: 31443},
+			corev1.ServicePort{Name: "http", Port: 80, NodePort: 31080},
+		),
+		fallbackNode("node-c", "57.128.1.3", true),
+		fallbackNode("node-a", "57.128.1.1", false),
+		fallbackNode("node-b", "57.128.1.2", true),
+	)
+	got, err := nodePortAddress(client, "ingress-nginx", "ingress-nginx-controller")
+	if err != nil {
+		t.Fatalf("nodePortAddress() error = %v", err)
+	}
+	if want := "57.128.1.2:31080"; got != want {
+		t.Errorf("nodePortAddress() = %q, want %q (the first Ready node, the http port)", got, want)
+	}
+}
+
+func TestNodePortAddress_Failures(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []*corev1.Node
+		service *corev1.Service
+		wantErr string
+	}{
+		{name: "no service", nodes: []*corev1.Node{fallbackNode("node-a", "57.128.1.1", true)}, wantErr: "failed to read service"},
+		{name: "no NodePort", service: controllerService(corev1.ServicePort{Name: "http", Port: 80}), nodes: []*corev1.Node{fallbackNode("node-a", "57.128.1.1", true)}, wantErr: "has no NodePort"},
+		{name: "no external IP", service: controllerService(corev1.ServicePort{Name: "web", Port: 80, NodePort: 30080}), nodes: []*corev1.Node{fallbackNode("node-a", "", true), fallbackNode("node-b", "57.128.1.2", false)}, wantErr: "no Ready node has an external IP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if tt.service != nil {
+				client = fake.NewSimpleClientset(tt.service)
+			}
+			for _, n := range tt.nodes {
+				if err := client.Tracker().Add(n); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := nodePortAddress(client, "ingress-nginx", "ingress-nginx-controller")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("nodePortAddress() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServiceIPAttempts(t *testing.T) {
+	for _, tt := range []struct {
+		timeout time.Duration
+		want    int
+	}{
+		{0, 40},
+		{3 * time.Minute, 12},
+		{time.Second, 1},
+	} {
+		if got := serviceIPAttempts(tt.timeout); got != tt.want {
+			t.Errorf("serviceIPAttempts(%s) = %d, want %d", tt.timeout, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"easylab/utils"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// checkStorageClass fails when the cluster has no StorageClass called name,
+// with the classes it does have.
+func checkStorageClass(client kubernetes.Interface, name string) error {
+	_, err := client.StorageV1().StorageClasses().Get(context.Background(), name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to read storage class %s: %w", name, err)
+	}
+	list, err := client.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("storage class %s does not exist in the cluster", name)
+	}
+	var available []string
+	for _, sc := range list.Items {
+		available = append(available, sc.Name)
+	}
+	if len(available) == 0 {
+		return fmt.Errorf("storage class %s does not exist in the cluster, which has none", name)
+	}
+	sort.Strings(available)
+	return fmt.Errorf("storage class %s does not exist in the cluster (available: %s)", name, strings.Join(available, ", "))
+}
+
+// CheckStorageClass gates the kubeconfig on the cluster having the storage
+// class the lab's persistent volumes ask for. Without the check, a missing
+// class only shows once a student's workspace stays Pending on its volume.
+func CheckStorageClass(ctx *pulumi.Context, kubeconfig pulumi.StringOutput, name string) pulumi.StringOutput {
+	return kubeconfig.ApplyT(func(content string) (string, error) {
+		client, err := clientFromKubeconfig(content)
+		if err != nil {
+			return "", err
+		}
+		if err := checkStorageClass(client, name); err != nil {
+			return "", err
+		}
+		utils.LogInfo(ctx, fmt.Sprintf("Persistent volumes will use the %s storage class", name))
+		return content, nil
+	}).(pulumi.StringOutput)
+}
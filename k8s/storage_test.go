@@ -0,0 +1,29 @@
+package k8s
+
+import (
+	"testing"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckStorageClass(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "csi-cinder-high-speed"}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "csi-cinder-classic"}},
+	)
+	if err := checkStorageClass(client, "csi-cinder-high-speed"); err != nil {
+		t.Errorf("checkStorageClass() error = %v", err)
+	}
+
+	err := checkStorageClass(client, "fast")
+	want := "storage class fast does not exist in the cluster (available: csi-cinder-classic, csi-cinder-high-speed)"
+	if err == nil || err.Error() != want {
+		t.Errorf("checkStorageClass() error = %v, want %q", err, want)
+	}
+
+	if err := checkStorageClass(fake.NewSimpleClientset(), "fast"); err == nil {
+		t.Error("checkStorageClass() on a cluster without storage classes should fail")
+	}
+}
@@ -33,7 +33,7 @@ func main() {
 			return fmt.Errorf("failed to create gateway: %w", err)
 		}
 
-		kubeCluster, err := ovh.InitManagedKubernetesCluster(ctx, serviceName, privateNetwork, subnet, gateway)
+		kubeCluster, kubeconfig, err := ovh.InitManagedKubernetesCluster(ctx, serviceName, privateNetwork, subnet, gateway)
 		if err != nil {
 			return fmt.Errorf("failed to create Kubernetes cluster: %w", err)
 		}
@@ -43,7 +43,7 @@ func main() {
 			return fmt.Errorf("failed to create node pools: %w", err)
 		}
 
-		k8sProvider, err := k8s.InitK8sProvider(ctx, kubeCluster, nodepool)
+		k8sProvider, err := k8s.InitK8sProvider(ctx, kubeconfig, kubeCluster, nodepool)
 		if err != nil {
 			return fmt.Errorf("failed to create Kubernetes provider: %w", err)
 		}
@@ -54,7 +54,8 @@ func main() {
 			return fmt.Errorf("failed to create namespace: %w", err)
 		}
 
-		ctx.Export("kubeconfig", kubeCluster.Kubeconfig)
+		// InitManagedKubernetesCluster already exports the kubeconfig, as a
+		// secret: exporting it again here would replace that export.
 		utils.LogInfo(ctx, "Setup completed successfully!")
 
 		return nil
@@ -0,0 +1,92 @@
+package ovh
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FlavorPricing is what the flavor catalog adds to a Managed Kubernetes flavor:
+// its disk and what a node of it costs per hour.
+type FlavorPricing struct {
+	Disk        int     // GB
+	HourlyPrice float64 // in Currency, taxes excluded
+	Currency    string
+}
+
+// projectFlavor is the part of a /cloud/project/{serviceName}/flavor entry the
+// catalog reads. A flavor is listed once per OS type, with the same plan codes.
+type projectFlavor struct {
+	Name      string `json:"name"`
+	Disk      int    `json:"disk"`
+	OSType    string `json:"osType"`
+	PlanCodes struct {
+		Hourly string `json:"hourly"`
+	} `json:"planCodes"`
+}
+
+// publicCloudCatalog is the part of the public cloud order catalog the catalog
+// reads. Prices are integers in 10^-8 of the currency.
+type publicCloudCatalog struct {
+	Locale struct {
+		CurrencyCode string `json:"currencyCode"`
+	} `json:"locale"`
+	Addons []struct {
+		PlanCode string `json:"planCode"`
+		Pricings []struct {
+			Price int64 `json:"price"`
+		} `json:"pricings"`
+	} `json:"addons"`
+}
+
+// catalogPriceUnit converts catalog prices to the currency.
+const catalogPriceUnit = 1e8
+
+// FetchFlavorPricing looks up, for the flavors of region, their disk and their
+// hourly price in the public cloud catalog of subsidiary (see
+// SubsidiaryForEndpoint). Flavors the catalog does not price have a zero price.
+func FetchFlavorPricing(api APIGetter, serviceName, region, subsidiary string) (map[string]FlavorPricing, error) {
+	var flavors []projectFlavor
+	endpoint := fmt.Sprintf("/cloud/project/%s/flavor?region=%s", serviceName, url.QueryEscape(region))
+	if err := api.Get(endpoint, &flavors); err != nil {
+		return nil, fmt.Errorf("failed to fetch flavors: %w", err)
+	}
+	var catalog publicCloudCatalog
+	if err := api.Get("/order/catalog/public/cloud?ovhSubsidiary="+url.QueryEscape(subsidiary), &catalog); err != nil {
+		return nil, fmt.Errorf("failed to fetch the price catalog: %w", err)
+	}
+
+	prices := make(map[string]int64, len(catalog.Addons))
+	for _, addon := range catalog.Addons {
+		if len(addon.Pricings) > 0 {
+			prices[addon.PlanCode] = addon.Pricings[0].Price
+		}
+	}
+	pricing := make(map[string]FlavorPricing, len(flavors))
+	for _, f := range flavors {
+		if _, seen := pricing[f.Name]; seen && f.OSType != "linux" {
+			continue
+		}
+		p := FlavorPricing{Disk: f.Disk}
+		if price, ok := prices[f.PlanCodes.Hourly]; ok {
+			p.HourlyPrice = float64(price) / catalogPriceUnit
+			p.Currency = catalog.Locale.CurrencyCode
+		}
+		pricing[f.Name] = p
+	}
+	return pricing, nil
+}
+
+// SubsidiaryForEndpoint returns the OVH subsidiary whose catalog prices the
+// account of an API endpoint ("ovh-eu", "ovh-ca"...). Accounts of the same
+// endpoint share a currency, and the catalog is only used for estimates.
+func SubsidiaryForEndpoint(endpoint string) string {
+	switch {
+	case strings.HasPrefix(endpoint, "ovh-ca") || strings.HasPrefix(endpoint, "https://ca."):
+		return "CA"
+	case strings.HasPrefix(endpoint, "ovh-us") || strings.HasPrefix(endpoint, "https://api.us."):
+		return "US"
+	default:
+		return "FR"
+	}
+}
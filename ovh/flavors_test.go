@@ -0,0 +1,50 @@
+package ovh
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// routeAPI answers each GET with the body registered for the URL's path.
+type routeAPI map[string]string
+
+func (r routeAPI) Get(url string, resType interface{}) error {
+	path, _, _ := strings.Cut(url, "?")
+	return json.Unmarshal([]byte(r[path]), resType)
+}
+
+func TestFetchFlavorPricing(t *testing.T) {
+	api := routeAPI{
+		"/cloud/project/project-1/flavor": `[
+			{"name": "b2-7", "disk": 50, "osType": "windows", "planCodes": {"hourly": "b2-7.consumption.win"}},
+			{"name": "b2-7", "disk": 50, "osType": "linux", "planCodes": {"hourly": "b2-7.consumption"}},
+			{"name": "t1-45", "disk": 400, "osType": "linux", "planCodes": {"hourly": "t1-45.consumption"}}
+		]`,
+		"/order/catalog/public/cloud": `{
+			"locale": {"currencyCode": "EUR"},
+			"addons": [
+				{"planCode": "b2-7.consumption", "pricings": [{"price": 6810000}]},
+				{"planCode": "b2-7.consumption.win", "pricings": [{"price": 9999999}]}
+			]
+		}`,
+	}
+	pricing, err := FetchFlavorPricing(api, "project-1", "GRA11", "FR")
+	if err != nil {
+		t.Fatalf("FetchFlavorPricing() error = %v", err)
+	}
+	if got, want := pricing["b2-7"], (FlavorPricing{Disk: 50, HourlyPrice: 0.0681, Currency: "EUR"}); got != want {
+		t.Errorf("b2-7 = %+v, want %+v", got, want)
+	}
+	if got, want := pricing["t1-45"], (FlavorPricing{Disk: 400}); got != want {
+		t.Errorf("t1-45, absent from the catalog, = %+v, want %+v", got, want)
+	}
+}
+
+func TestSubsidiaryForEndpoint(t *testing.T) {
+	for endpoint, want := range map[string]string{"ovh-eu": "FR", "ovh-ca": "CA", "ovh-us": "US", "": "FR"} {
+		if got := SubsidiaryForEndpoint(endpoint); got != want {
+			t.Errorf("SubsidiaryForEndpoint(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package ovh
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GatewayModels are the gateway sizes OVH sells, cheapest first.
+var GatewayModels = []string{"s", "m", "l"}
+
+// APIGetter is the part of the OVH API client the capability lookups need.
+// *ovh.Client of github.com/ovh/go-ovh satisfies it; tests pass a fake.
+type APIGetter interface {
+	Get(url string, resType interface{}) error
+}
+
+// GatewayAvailability maps a region to the gateway models OVH offers there,
+// cheapest first.
+type GatewayAvailability map[string][]string
+
+// Models returns the gateway models offered in region, cheapest first.
+func (a GatewayAvailability) Models(region string) []string {
+	return a[region]
+}
+
+// productAvailability is the part of the capabilities/productAvailability
+// answer the gateway lookup reads. Gateway plan codes read
+// "gateway.<model>.<billing>", e.g. "gateway.s.hour.consumption".
+type productAvailability struct {
+	Plans []struct {
+		Code    string `json:"code"`
+		Regions []struct {
+			Name string `json:"name"`
+		} `json:"regions"`
+	} `json:"plans"`
+}
+
+// FetchGatewayAvailability asks the OVH capabilities API which gateway models
+// each region offers. Not every region sells every size, and a gateway model
+// the region lacks only fails once pulumi up creates the gateway.
+func FetchGatewayAvailability(api APIGetter, serviceName string) (GatewayAvailability, error) {
+	var avail productAvailability
+	endpoint := fmt.Sprintf("/cloud/project/%s/capabilities/productAvailability?addonFamily=gateway", serviceName)
+	if err := api.Get(endpoint, &avail); err != nil {
+		return nil, fmt.Errorf("failed to fetch gateway availability: %w", err)
+	}
+
+	models := GatewayAvailability{}
+	for _, plan := range avail.Plans {
+		parts := strings.Split(plan.Code, ".")
+		if len(parts) < 2 || parts[0] != "gateway" || parts[1] == "" {
+			continue
+		}
+		for _, region := range plan.Regions {
+			if !slices.Contains(models[region.Name], parts[1]) {
+				models[region.Name] = append(models[region.Name], parts[1])
+			}
+		}
+	}
+	for _, m := range models {
+		sortGatewayModels(m)
+	}
+	return models, nil
+}
+
+// sortGatewayModels orders models cheapest first. Models this code does not
+// know of yet go last, by name.
+func sortGatewayModels(models []string) {
+	rank := func(m string) int {
+		if i := slices.Index(GatewayModels, m); i >= 0 {
+			return i
+		}
+		return len(GatewayModels)
+	}
+	sort.SliceStable(models, func(i, j int) bool {
+		ri, rj := rank(models[i]), rank(models[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return models[i] < models[j]
+	})
+}
+
+// CheapestGatewayModel returns the cheapest of the available models, or ""
+// when there is none.
+func CheapestGatewayModel(available []string) string {
+	if len(available) == 0 {
+		return ""
+	}
+	return available[0]
+}
+
+// ValidateGatewayModel checks that region offers the gateway model, given the
+// models FetchGatewayAvailability found there.
+func ValidateGatewayModel(model, region string, available []string) error {
+	if len(available) == 0 {
+		return &SubnetError{Field: "network_gateway_model", Label: "Gateway model", Msg: fmt.Sprintf("OVH offers no gateway in region %s", region)}
+	}
+	if !slices.Contains(available, model) {
+		return &SubnetError{Field: "network_gateway_model", Label: "Gateway model", Msg: fmt.Sprintf("%q is not available in region %s, use one of: %s", model, region, strings.Join(available, ", "))}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package ovh
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeAPI answers every GET with body, or fails with err, and records the
+// requested URL.
+type fakeAPI struct {
+	body string
+	err  error
+	url  string
+}
+
+func (f *fakeAPI) Get(url string, resType interface{}) error {
+	f.url = url
+	if f.err != nil {
+		return f.err
+	}
+	return json.Unmarshal([]byte(f.body), resType)
+}
+
+const gatewayAvailabilityBody = `{
+	"plans": [
+		{"code": "gateway.l.hour.consumption", "regions": [{"name": "GRA11"}]},
+		{"code": "gateway.s.hour.consumption", "regions": [{"name": "GRA11"}, {"name": "BHS5"}]},
+		{"code": "gateway.s.month.consumption", "regions": [{"name": "GRA11"}, {"name": "BHS5"}]},
+		{"code": "gateway.m.hour.consumption", "regions": [{"name": "GRA11"}]},
+		{"code": "gateway.xl.hour.consumption", "regions": [{"name": "GRA11"}]},
+		{"code": "floatingip.hour.consumption", "regions": [{"name": "SBG5"}]}
+	]
+}`
+
+func TestFetchGatewayAvailability(t *testing.T) {
+	api := &fakeAPI{body: gatewayAvailabilityBody}
+	avail, err := FetchGatewayAvailability(api, "project-1")
+	if err != nil {
+		t.Fatalf("FetchGatewayAvailability() error = %v", err)
+	}
+	if want := "/cloud/project/project-1/capabilities/productAvailability?addonFamily=gateway"; api.url != want {
+		t.Errorf("requested %q, want %q", api.url, want)
+	}
+
+	tests := []struct {
+		region string
+		want   []string
+	}{
+		{"GRA11", []string{"s", "m", "l", "xl"}},
+		{"BHS5", []string{"s"}},
+		{"SBG5", nil},
+	}
+	for _, tt := range tests {
+		if got := avail.Models(tt.region); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Models(%q) = %v, want %v", tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestFetchGatewayAvailability_APIError(t *testing.T) {
+	_, err := FetchGatewayAvailability(&fakeAPI{err: errors.New("403 Forbidden")}, "project-1")
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden") {
+		t.Fatalf("FetchGatewayAvailability() error = %v, want the API error", err)
+	}
+}
+
+func TestCheapestGatewayModel(t *testing.T) {
+	if got := CheapestGatewayModel([]string{"m", "l"}); got != "m" {
+		t.Errorf("CheapestGatewayModel() = %q, want m", got)
+	}
+	if got := CheapestGatewayModel(nil); got != "" {
+		t.Errorf("CheapestGatewayModel(nil) = %q, want empty", got)
+	}
+}
+
+func TestValidateGatewayModel(t *testing.T) {
+	if err := ValidateGatewayModel("m", "GRA11", []string{"s", "m", "l"}); err != nil {
+		t.Errorf("ValidateGatewayModel(m) = %v, want nil", err)
+	}
+
+	err := ValidateGatewayModel("l", "BHS5", []string{"s", "m"})
+	var subnetErr *SubnetError
+	if !errors.As(err, &subnetErr) || subnetErr.Field != "network_gateway_model" {
+		t.Fatalf("ValidateGatewayModel(l) = %v, want a network_gateway_model error", err)
+	}
+	if !strings.Contains(err.Error(), "s, m") {
+		t.Errorf("error %q should list the valid models", err)
+	}
+
+	if err := ValidateGatewayModel("s", "SBG5", nil); err == nil || !strings.Contains(err.Error(), "no gateway") {
+		t.Errorf("ValidateGatewayModel in a region without gateways = %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package ovh
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ovh/pulumi-ovh/sdk/v2/go/ovh/cloudproject"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// kubeconfigWaitTimeout bounds how long a freshly created cluster may report an
+// empty kubeconfig. OVH usually fills it in within a few minutes of the cluster
+// reaching READY.
+const kubeconfigWaitTimeout = 15 * time.Minute
+
+const kubeconfigPollInterval = 15 * time.Second
+
+// kubeState is what one poll of the cluster reports.
+type kubeState struct {
+	Status     string
+	Kubeconfig string
+}
+
+// waitForKubeconfig returns kubeconfig if it is already set, otherwise polls the
+// cluster until it reports one. Poll errors are retried, since the API is often
+// flaky while the cluster is still being installed; the last one is kept for the
+// timeout error.
+func waitForKubeconfig(kubeconfig string, poll func() (kubeState, error), interval, timeout time.Duration, sleep func(time.Duration)) (string, error) {
+	if kubeconfig != "" {
+		return kubeconfig, nil
+	}
+	var last kubeState
+	var lastErr error
+	for waited := time.Duration(0); waited < timeout; waited += interval {
+		sleep(interval)
+		state, err := poll()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if state.Kubeconfig != "" {
+			return state.Kubeconfig, nil
+		}
+		last, lastErr = state, nil
+	}
+	if lastErr != nil {
+		return "", fmt.Errorf("kubeconfig still empty after %s: %w", timeout, lastErr)
+	}
+	return "", fmt.Errorf("kubeconfig still empty after %s (cluster status %q)", timeout, last.Status)
+}
+
+// readyKubeconfig resolves to the cluster's kubeconfig once OVH has issued it.
+// Early in provisioning the Kube resource can come back with an empty
+// kubeconfig, which would otherwise only surface later as a Kubernetes provider
+// failure; the cluster is polled instead, and the update fails with a clear
+// error if it never shows up.
+func readyKubeconfig(ctx *pulumi.Context, serviceName string, kubeCluster *cloudproject.Kube) pulumi.StringOutput {
+	return pulumi.All(kubeCluster.ID(), kubeCluster.Kubeconfig).ApplyT(func(args []interface{}) (string, error) {
+		kubeID := string(args[0].(pulumi.ID))
+		kubeconfig, err := waitForKubeconfig(args[1].(string), func() (kubeState, error) {
+			res, err := cloudproject.LookupKube(ctx, &cloudproject.LookupKubeArgs{ServiceName: serviceName, KubeId: kubeID})
+			if err != nil {
+				return kubeState{}, err
+			}
+			return kubeState{Status: res.Status, Kubeconfig: res.Kubeconfig}, nil
+		}, kubeconfigPollInterval, kubeconfigWaitTimeout, time.Sleep)
+		if err != nil {
+			return "", fmt.Errorf("cluster %s: %w", kubeID, err)
+		}
+		return kubeconfig, nil
+	}).(pulumi.StringOutput)
+}
+
+// APIPoster is the part of the OVH API client the kubeconfig reset needs.
+// *ovh.Client of github.com/ovh/go-ovh satisfies it; tests pass a fake.
+type APIPoster interface {
+	Post(url string, reqBody, resType interface{}) error
+}
+
+// ResetKubeconfig resets the cluster's kubeconfig, which revokes every copy of
+// the previous one, and returns the new kubeconfig.
+func ResetKubeconfig(api APIPoster, serviceName, kubeID string) (string, error) {
+	if err := api.Post(fmt.Sprintf("/cloud/project/%s/kube/%s/kubeconfig/reset", serviceName, kubeID), nil, nil); err != nil {
+		return "", fmt.Errorf("failed to reset the kubeconfig of cluster %s: %w", kubeID, err)
+	}
+	var kubeconfig struct {
+		Content string `json:"content"`
+	}
+	if err := api.Post(fmt.Sprintf("/cloud/project/%s/kube/%s/kubeconfig", serviceName, kubeID), nil, &kubeconfig); err != nil {
+		return "", fmt.Errorf("failed to fetch the new kubeconfig of cluster %s: %w", kubeID, err)
+	}
+	if kubeconfig.Content == "" {
+		return "", fmt.Errorf("cluster %s returned an empty kubeconfig after the reset", kubeID)
+	}
+	return kubeconfig.Content, nil
+}
@@ -0,0 +1,111 @@
+package ovh
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeKube replays one poll result per call, repeating the last one.
+type fakeKube struct {
+	states []kubeState
+	errs   []error
+	polls  int
+}
+
+func (f *fakeKube) poll() (kubeState, error) {
+	i := min(f.polls, len(f.states)-1)
+	f.polls++
+	return f.states[i], f.errs[i]
+}
+
+func noSleep(time.Duration) {}
+
+func TestWaitForKubeconfig_AlreadySet(t *testing.T) {
+	f := &fakeKube{states: []kubeState{{}}, errs: []error{nil}}
+	got, err := waitForKubeconfig("apiVersion: v1", f.poll, time.Second, time.Minute, noSleep)
+	if err != nil || got != "apiVersion: v1" {
+		t.Fatalf("waitForKubeconfig() = %q, %v", got, err)
+	}
+	if f.polls != 0 {
+		t.Errorf("polled %d times, want 0 when the kubeconfig is already set", f.polls)
+	}
+}
+
+func TestWaitForKubeconfig_PollsUntilIssued(t *testing.T) {
+	f := &fakeKube{
+		states: []kubeState{{Status: "INSTALLING"}, {}, {Status: "READY", Kubeconfig: "apiVersion: v1"}},
+		errs:   []error{nil, errors.New("503 Service Unavailable"), nil},
+	}
+	got, err := waitForKubeconfig("", f.poll, time.Second, time.Minute, noSleep)
+	if err != nil || got != "apiVersion: v1" {
+		t.Fatalf("waitForKubeconfig() = %q, %v", got, err)
+	}
+	if f.polls != 3 {
+		t.Errorf("polled %d times, want 3", f.polls)
+	}
+}
+
+func TestWaitForKubeconfig_Timeout(t *testing.T) {
+	f := &fakeKube{states: []kubeState{{Status: "INSTALLING"}}, errs: []error{nil}}
+	var slept time.Duration
+	_, err := waitForKubeconfig("", f.poll, 15*time.Second, time.Minute, func(d time.Duration) { slept += d })
+	if err == nil {
+		t.Fatal("waitForKubeconfig() error = nil, want a timeout")
+	}
+	if !strings.Contains(err.Error(), `"INSTALLING"`) {
+		t.Errorf("error %q should report the last cluster status", err)
+	}
+	if f.polls != 4 || slept != time.Minute {
+		t.Errorf("polled %d times over %s, want 4 over 1m0s", f.polls, slept)
+	}
+}
+
+func TestWaitForKubeconfig_TimeoutKeepsLastError(t *testing.T) {
+	apiErr := errors.New("403 Forbidden")
+	f := &fakeKube{states: []kubeState{{}}, errs: []error{apiErr}}
+	_, err := waitForKubeconfig("", f.poll, time.Second, 3*time.Second, noSleep)
+	if !errors.Is(err, apiErr) {
+		t.Errorf("waitForKubeconfig() error = %v, want it to wrap %v", err, apiErr)
+	}
+}
+
+// fakePoster records the URLs posted to and answers the kubeconfig request.
+type fakePoster struct {
+	urls       []string
+	kubeconfig string
+	err        error
+}
+
+func (f *fakePoster) Post(url string, reqBody, resType interface{}) error {
+	f.urls = append(f.urls, url)
+	if f.err != nil {
+		return f.err
+	}
+	if res, ok := resType.(*struct {
+		Content string `json:"content"`
+	}); ok {
+		res.Content = f.kubeconfig
+	}
+	return nil
+}
+
+func TestResetKubeconfig(t *testing.T) {
+	api := &fakePoster{kubeconfig: "apiVersion: v1"}
+	got, err := ResetKubeconfig(api, "project-1", "kube-1")
+	if err != nil || got != "apiVersion: v1" {
+		t.Fatalf("ResetKubeconfig() = %q, %v", got, err)
+	}
+	want := []string{"/cloud/project/project-1/kube/kube-1/kubeconfig/reset", "/cloud/project/project-1/kube/kube-1/kubeconfig"}
+	if strings.Join(api.urls, " ") != strings.Join(want, " ") {
+		t.Errorf("posted to %v, want %v", api.urls, want)
+	}
+
+	if _, err := ResetKubeconfig(&fakePoster{}, "project-1", "kube-1"); err == nil || !strings.Contains(err.Error(), "empty kubeconfig") {
+		t.Errorf("ResetKubeconfig() with an empty answer = %v", err)
+	}
+	if _, err := ResetKubeconfig(&fakePoster{err: errors.New("403 Forbidden")}, "project-1", "kube-1"); err == nil || !strings.Contains(err.Error(), "403 Forbidden") {
+		t.Errorf("ResetKubeconfig() with an API error = %v", err)
+	}
+}
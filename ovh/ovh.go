@@ -12,12 +12,103 @@ import (
 // NetworkInfrastructure holds created network infrastructure
 type NetworkInfrastructure struct {
 	PrivateNetwork *cloudproject.NetworkPrivate
-	Subnet         *cloudproject.NetworkPrivateSubnet
-	Gateway        *cloudproject.Gateway
+	// Subnet is a NetworkPrivateSubnet, or a NetworkPrivateSubnetV2 when the lab
+	// sets a gateway IP (see InitSubnet).
+	Subnet  pulumi.CustomResource
+	Gateway *cloudproject.Gateway
+}
+
+// SubnetOptions are the optional subnet settings. A nil DHCP and an empty
+// GatewayIP keep OVH's defaults.
+type SubnetOptions struct {
+	DHCP      *bool
+	GatewayIP string
+}
+
+// SubnetOptionsFromConfig reads the subnet options from the stack config.
+func SubnetOptionsFromConfig(ctx *pulumi.Context) (SubnetOptions, error) {
+	opts := SubnetOptions{GatewayIP: utils.OvhConfigOptional(ctx, utils.OvhNetworkGatewayIP)}
+	if v := utils.OvhConfigOptional(ctx, utils.OvhNetworkDHCP); v != "" {
+		dhcp, err := strconv.ParseBool(v)
+		if err != nil {
+			return SubnetOptions{}, fmt.Errorf("invalid %s %q: %w", utils.OvhNetworkDHCP, v, err)
+		}
+		opts.DHCP = &dhcp
+	}
+	return opts, nil
+}
+
+// ImportIDs holds the OVH IDs of pre-existing resources a lab adopts instead of
+// creating them. Adopted resources are managed by the stack from then on, so
+// destroying the lab deletes them too. An empty ID means "create as usual".
+type ImportIDs struct {
+	PrivateNetwork string // e.g. pn-123456_0
+	Subnet         string // OpenStack subnet ID, requires PrivateNetwork
+	Cluster        string // managed Kubernetes cluster ID
+}
+
+// ImportIDsFromConfig reads the import IDs from the stack config.
+func ImportIDsFromConfig(ctx *pulumi.Context) ImportIDs {
+	return ImportIDs{
+		PrivateNetwork: utils.OvhConfigOptional(ctx, utils.OvhImportPrivateNetworkId),
+		Subnet:         utils.OvhConfigOptional(ctx, utils.OvhImportSubnetId),
+		Cluster:        utils.K8sConfigOptional(ctx, utils.K8sImportClusterId),
+	}
+}
+
+// Validate checks that the IDs can be turned into provider import IDs: a subnet
+// is addressed through its network, so it cannot be adopted on its own.
+func (ids ImportIDs) Validate() error {
+	if ids.Subnet != "" && ids.PrivateNetwork == "" {
+		return fmt.Errorf("importing subnet %q requires the ID of its private network", ids.Subnet)
+	}
+	return nil
+}
+
+// The provider import IDs are all scoped to the cloud project; they are empty
+// when the matching resource is not imported.
+
+func (ids ImportIDs) privateNetworkImportID(serviceName string) string {
+	if ids.PrivateNetwork == "" {
+		return ""
+	}
+	return serviceName + "/" + ids.PrivateNetwork
+}
+
+func (ids ImportIDs) subnetImportID(serviceName string) string {
+	if ids.Subnet == "" || ids.PrivateNetwork == "" {
+		return ""
+	}
+	return serviceName + "/" + ids.PrivateNetwork + "/" + ids.Subnet
+}
+
+func (ids ImportIDs) clusterImportID(serviceName string) string {
+	if ids.Cluster == "" {
+		return ""
+	}
+	return serviceName + "/" + ids.Cluster
+}
+
+// importOptions returns the resource options that make `up` adopt importID
+// rather than create a new resource, followed by opts.
+func importOptions(importID string, opts ...pulumi.ResourceOption) []pulumi.ResourceOption {
+	if importID == "" {
+		return opts
+	}
+	return append([]pulumi.ResourceOption{pulumi.Import(pulumi.ID(importID))}, opts...)
 }
 
 // InitNetworkInfrastructure creates network infrastructure
 func InitNetworkInfrastructure(ctx *pulumi.Context, serviceName string) (*NetworkInfrastructure, error) {
+	// Checked before anything is created, so a bad range leaves no orphan network.
+	cidr, start, end := utils.OvhConfig(ctx, utils.OvhNetworkMask), utils.OvhConfig(ctx, utils.OvhNetworkStartIP), utils.OvhConfig(ctx, utils.OvhNetworkEndIP)
+	if err := ValidateSubnet(cidr, start, end); err != nil {
+		return nil, fmt.Errorf("invalid private network: %w", err)
+	}
+	if err := ValidateGatewayIP(cidr, start, end, utils.OvhConfigOptional(ctx, utils.OvhNetworkGatewayIP)); err != nil {
+		return nil, fmt.Errorf("invalid private network: %w", err)
+	}
+
 	privateNetwork, err := InitPrivateNetwork(ctx, serviceName)
 	if err != nil {
 		return nil, err
@@ -40,29 +131,92 @@ func InitNetworkInfrastructure(ctx *pulumi.Context, serviceName string) (*Networ
 	}, nil
 }
 
-func InitSubnet(ctx *pulumi.Context, serviceName string, privateNetwork *cloudproject.NetworkPrivate) (*cloudproject.NetworkPrivateSubnet, error) {
-	subnet, _ := cloudproject.NewNetworkPrivateSubnet(ctx, "subnet", &cloudproject.NetworkPrivateSubnetArgs{
+// The subnet constructors are variables so a test can make them fail the way
+// the SDK does when it refuses a resource before registering it.
+var (
+	newNetworkPrivateSubnet   = cloudproject.NewNetworkPrivateSubnet
+	newNetworkPrivateSubnetV2 = cloudproject.NewNetworkPrivateSubnetV2
+)
+
+// InitSubnet creates the lab's subnet. The original subnet resource has no
+// gateway IP setting (OVH puts the gateway on the network's first address), so
+// a lab that sets one gets a NetworkPrivateSubnetV2 instead; other labs keep
+// the original resource, and so keep their existing subnet.
+func InitSubnet(ctx *pulumi.Context, serviceName string, privateNetwork *cloudproject.NetworkPrivate) (pulumi.CustomResource, error) {
+	ids := ImportIDsFromConfig(ctx)
+	if err := ids.Validate(); err != nil {
+		return nil, err
+	}
+	opts, err := SubnetOptionsFromConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts.GatewayIP != "" {
+		if ids.Subnet != "" {
+			return nil, fmt.Errorf("importing subnet %q cannot be combined with a custom gateway IP", ids.Subnet)
+		}
+		return initSubnetV2(ctx, serviceName, privateNetwork, opts)
+	}
+
+	args := &cloudproject.NetworkPrivateSubnetArgs{
 		ServiceName: pulumi.String(serviceName),
 		NetworkId:   privateNetwork.ID(),
 		Network:     pulumi.String(utils.OvhConfig(ctx, utils.OvhNetworkMask)),
 		Region:      pulumi.String(utils.OvhConfig(ctx, utils.OvhRegion)),
 		Start:       pulumi.String(utils.OvhConfig(ctx, utils.OvhNetworkStartIP)),
 		End:         pulumi.String(utils.OvhConfig(ctx, utils.OvhNetworkEndIP)),
-	})
+	}
+	if opts.DHCP != nil {
+		args.Dhcp = pulumi.BoolPtr(*opts.DHCP)
+	}
+	subnet, err := newNetworkPrivateSubnet(ctx, "subnet", args, importOptions(ids.subnetImportID(serviceName))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subnet: %w", err)
+	}
 	ctx.Export("subnetId", subnet.ID())
 	return subnet, nil
 }
 
-func InitPrivateNetwork(ctx *pulumi.Context, serviceName string) (*cloudproject.NetworkPrivate, error) {
+// initSubnetV2 creates the subnet with a custom gateway IP. This resource
+// addresses the network by its OpenStack ID and takes the DHCP range as an
+// allocation pool.
+func initSubnetV2(ctx *pulumi.Context, serviceName string, privateNetwork *cloudproject.NetworkPrivate, opts SubnetOptions) (pulumi.CustomResource, error) {
+	args := &cloudproject.NetworkPrivateSubnetV2Args{
+		ServiceName: pulumi.String(serviceName),
+		NetworkId:   getNetworkId(ctx, privateNetwork),
+		Cidr:        pulumi.String(utils.OvhConfig(ctx, utils.OvhNetworkMask)),
+		Region:      pulumi.String(utils.OvhConfig(ctx, utils.OvhRegion)),
+		AllocationPools: cloudproject.NetworkPrivateSubnetV2AllocationPoolArray{
+			cloudproject.NetworkPrivateSubnetV2AllocationPoolArgs{
+				Start: pulumi.String(utils.OvhConfig(ctx, utils.OvhNetworkStartIP)),
+				End:   pulumi.String(utils.OvhConfig(ctx, utils.OvhNetworkEndIP)),
+			},
+		},
+		GatewayIp: pulumi.String(opts.GatewayIP),
+	}
+	if opts.DHCP != nil {
+		args.Dhcp = pulumi.BoolPtr(*opts.DHCP)
+	}
+	subnet, err := newNetworkPrivateSubnetV2(ctx, "subnet", args, pulumi.DependsOn([]pulumi.Resource{privateNetwork}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subnet: %w", err)
+	}
+	ctx.Export("subnetId", subnet.ID())
+	return subnet, nil
+}
 
-	networkId, _ := strconv.Atoi(utils.OvhConfig(ctx, utils.OvhNetworkId))
+func InitPrivateNetwork(ctx *pulumi.Context, serviceName string) (*cloudproject.NetworkPrivate, error) {
+	networkId, err := ParseVlanID(utils.OvhConfig(ctx, utils.OvhNetworkId))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private network: %w", err)
+	}
 
 	privateNetwork, err := cloudproject.NewNetworkPrivate(ctx, "privateNetwork-v2", &cloudproject.NetworkPrivateArgs{
 		VlanId:      pulumi.Int(networkId),
 		ServiceName: pulumi.String(serviceName),
 		Name:        pulumi.String(utils.OvhConfig(ctx, utils.OvhPrivateNetworkName)),
 		Regions:     pulumi.StringArray{pulumi.String(utils.OvhConfig(ctx, utils.OvhRegion))},
-	})
+	}, importOptions(ImportIDsFromConfig(ctx).privateNetworkImportID(serviceName))...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create private network: %w", err)
 	}
@@ -78,7 +232,7 @@ func getNetworkId(ctx *pulumi.Context, privateNetwork *cloudproject.NetworkPriva
 	}).(pulumi.StringOutput)
 }
 
-func InitGateway(ctx *pulumi.Context, serviceName string, privateNetwork *cloudproject.NetworkPrivate, subnet *cloudproject.NetworkPrivateSubnet) (*cloudproject.Gateway, error) {
+func InitGateway(ctx *pulumi.Context, serviceName string, privateNetwork *cloudproject.NetworkPrivate, subnet pulumi.CustomResource) (*cloudproject.Gateway, error) {
 	gateway, err := cloudproject.NewGateway(ctx, "gateway", &cloudproject.GatewayArgs{
 		ServiceName: pulumi.String(serviceName),
 		Name:        pulumi.String(utils.OvhConfig(ctx, utils.OvhGatewayName)),
@@ -93,52 +247,121 @@ func InitGateway(ctx *pulumi.Context, serviceName string, privateNetwork *cloudp
 	return gateway, nil
 }
 
-// InitManagedKubernetesClusterWithNetwork creates a K8s cluster using the network infrastructure
-func InitManagedKubernetesClusterWithNetwork(ctx *pulumi.Context, serviceName string, netInfra *NetworkInfrastructure) (*cloudproject.Kube, error) {
-	kubeCluster, err := cloudproject.NewKube(ctx, "kubeCluster", &cloudproject.KubeArgs{
+// kubeArgs builds the cluster arguments. The Kubernetes version and the
+// advanced options are only set when the lab asks for them; otherwise OVH picks
+// its defaults, so the labs that set none keep the cluster they always had.
+func kubeArgs(ctx *pulumi.Context, serviceName string, privateNetworkID pulumi.StringInput) *cloudproject.KubeArgs {
+	args := &cloudproject.KubeArgs{
 		ServiceName:      pulumi.String(serviceName),
 		Name:             pulumi.String(utils.K8sConfig(ctx, utils.K8sClusterName)),
 		Region:           pulumi.String(utils.OvhConfig(ctx, utils.OvhRegion)),
-		PrivateNetworkId: getNetworkId(ctx, netInfra.PrivateNetwork),
-	}, pulumi.DependsOn([]pulumi.Resource{netInfra.Gateway}))
+		PrivateNetworkId: privateNetworkID,
+	}
+	if version := utils.K8sConfigOptional(ctx, utils.K8sVersion); version != "" {
+		args.Version = pulumi.String(version)
+	}
+	if mode := utils.K8sConfigOptional(ctx, utils.K8sKubeProxyMode); mode != "" {
+		args.KubeProxyMode = pulumi.String(mode)
+	}
+	if scheduler := utils.K8sConfigOptional(ctx, utils.K8sIPVSScheduler); scheduler != "" {
+		args.CustomizationKubeProxy = cloudproject.KubeCustomizationKubeProxyArgs{
+			Ipvs: cloudproject.KubeCustomizationKubeProxyIpvsArgs{Scheduler: pulumi.String(scheduler)},
+		}
+	}
+	if utils.K8sConfigBool(ctx, utils.K8sPrivateNetworkRoutingAsDefault) {
+		// Egress goes through the lab's gateway: the IP the lab sets, or else the
+		// one the subnet's DHCP advertises (an empty DefaultVrackGateway).
+		args.PrivateNetworkConfiguration = cloudproject.KubePrivateNetworkConfigurationArgs{
+			DefaultVrackGateway:            pulumi.String(utils.OvhConfigOptional(ctx, utils.OvhNetworkGatewayIP)),
+			PrivateNetworkRoutingAsDefault: pulumi.Bool(true),
+		}
+	}
+	if plan := utils.K8sConfigOptional(ctx, utils.K8sPlan); plan != "" {
+		args.Plan = pulumi.String(plan)
+	}
+	return args
+}
+
+// InitManagedKubernetesClusterWithNetwork creates a K8s cluster using the network
+// infrastructure. It also returns the cluster's kubeconfig, which only resolves
+// once OVH has issued it.
+func InitManagedKubernetesClusterWithNetwork(ctx *pulumi.Context, serviceName string, netInfra *NetworkInfrastructure) (*cloudproject.Kube, pulumi.StringOutput, error) {
+	kubeCluster, err := cloudproject.NewKube(ctx, "kubeCluster", kubeArgs(ctx, serviceName, getNetworkId(ctx, netInfra.PrivateNetwork)),
+		importOptions(ImportIDsFromConfig(ctx).clusterImportID(serviceName), pulumi.DependsOn([]pulumi.Resource{netInfra.Gateway}))...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Kubernetes cluster: %w", err)
+		return nil, pulumi.StringOutput{}, fmt.Errorf("failed to create Kubernetes cluster: %w", err)
 	}
 
+	kubeconfig := readyKubeconfig(ctx, serviceName, kubeCluster)
 	ctx.Export("kubeClusterId", kubeCluster.ID())
 	ctx.Export("kubeClusterName", kubeCluster.Name)
-	ctx.Export("kubeconfig", pulumi.ToSecret(kubeCluster.Kubeconfig))
+	ctx.Export("kubeVersion", kubeCluster.Version)
+	ctx.Export("kubeClusterUrl", kubeCluster.Url)
+	ctx.Export("kubeconfig", pulumi.ToSecret(kubeconfig))
 
-	return kubeCluster, nil
+	return kubeCluster, kubeconfig, nil
 }
 
 // InitManagedKubernetesCluster creates a K8s cluster (legacy function for backward compatibility)
-func InitManagedKubernetesCluster(ctx *pulumi.Context, serviceName string, privateNetwork *cloudproject.NetworkPrivate, subnet *cloudproject.NetworkPrivateSubnet, gateway *cloudproject.Gateway) (*cloudproject.Kube, error) {
+func InitManagedKubernetesCluster(ctx *pulumi.Context, serviceName string, privateNetwork *cloudproject.NetworkPrivate, subnet pulumi.CustomResource, gateway *cloudproject.Gateway) (*cloudproject.Kube, pulumi.StringOutput, error) {
 	// Create managed Kubernetes cluster
-	kubeCluster, err := cloudproject.NewKube(ctx, "kubeCluster", &cloudproject.KubeArgs{
-		ServiceName:      pulumi.String(serviceName),
-		Name:             pulumi.String(utils.K8sConfig(ctx, utils.K8sClusterName)),
-		Region:           pulumi.String(utils.OvhConfig(ctx, utils.OvhRegion)),
-		PrivateNetworkId: getNetworkId(ctx, privateNetwork),
-	}, pulumi.DependsOn([]pulumi.Resource{gateway}))
+	kubeCluster, err := cloudproject.NewKube(ctx, "kubeCluster", kubeArgs(ctx, serviceName, getNetworkId(ctx, privateNetwork)),
+		importOptions(ImportIDsFromConfig(ctx).clusterImportID(serviceName), pulumi.DependsOn([]pulumi.Resource{gateway}))...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Kubernetes cluster: %w", err)
+		return nil, pulumi.StringOutput{}, fmt.Errorf("failed to create Kubernetes cluster: %w", err)
 	}
 
+	kubeconfig := readyKubeconfig(ctx, serviceName, kubeCluster)
 	ctx.Export("kubeClusterId", kubeCluster.ID())
 	ctx.Export("kubeClusterName", kubeCluster.Name)
-	ctx.Export("kubeconfig", pulumi.ToSecret(kubeCluster.Kubeconfig))
+	ctx.Export("kubeVersion", kubeCluster.Version)
+	ctx.Export("kubeClusterUrl", kubeCluster.Url)
+	ctx.Export("kubeconfig", pulumi.ToSecret(kubeconfig))
 
-	return kubeCluster, nil
+	return kubeCluster, kubeconfig, nil
+}
+
+// nodePoolTemplate builds the node template that labels and taints every node of
+// the pool. OVH requires every template field, so the unused ones are sent empty.
+func nodePoolTemplate(labels map[string]string, taints []utils.NodeTaint) cloudproject.KubeNodePoolTemplateArgs {
+	taintMaps := pulumi.StringMapArray{}
+	for _, t := range taints {
+		taintMaps = append(taintMaps, pulumi.StringMap{
+			"key":    pulumi.String(t.Key),
+			"value":  pulumi.String(t.Value),
+			"effect": pulumi.String(t.Effect),
+		})
+	}
+	return cloudproject.KubeNodePoolTemplateArgs{
+		Metadata: cloudproject.KubeNodePoolTemplateMetadataArgs{
+			Annotations: pulumi.StringMap{},
+			Finalizers:  pulumi.StringArray{},
+			Labels:      pulumi.ToStringMap(labels),
+		},
+		Spec: cloudproject.KubeNodePoolTemplateSpecArgs{
+			Taints:        taintMaps,
+			Unschedulable: pulumi.Bool(false),
+		},
+	}
 }
 
 func InitNodePools(ctx *pulumi.Context, serviceName string, kubeCluster *cloudproject.Kube) ([]*cloudproject.KubeNodePool, error) {
 	// Create node pools
 	nodePoolIds := pulumi.StringArray{}
 	var nodePools []*cloudproject.KubeNodePool
-	for i := 0; i < 1; i++ {
+	labels, taints, err := utils.NodePoolScheduling(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// One pool per availability zone, as OVH takes a single zone per pool, or
+	// a single pool OVH places itself.
+	zones, err := utils.NodePoolAvailabilityZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < max(1, len(zones)); i++ {
 		nodePoolName := fmt.Sprintf("%s-%d", utils.NodePoolConfig(ctx, utils.NodePoolName), i+1)
-		nodePool, err := cloudproject.NewKubeNodePool(ctx, fmt.Sprintf("nodePool%d", i+1), &cloudproject.KubeNodePoolArgs{
+		args := &cloudproject.KubeNodePoolArgs{
 			ServiceName:  pulumi.String(serviceName),
 			KubeId:       kubeCluster.ID(),
 			Name:         pulumi.String(nodePoolName),
@@ -146,7 +369,24 @@ func InitNodePools(ctx *pulumi.Context, serviceName string, kubeCluster *cloudpr
 			DesiredNodes: pulumi.Int(utils.NodePoolConfigInt(ctx, utils.NodePoolDesiredNodeCount)),
 			MaxNodes:     pulumi.Int(utils.NodePoolConfigInt(ctx, utils.NodePoolMaxNodeCount)),
 			MinNodes:     pulumi.Int(utils.NodePoolConfigInt(ctx, utils.NodePoolMinNodeCount)),
-		}, pulumi.DependsOn([]pulumi.Resource{kubeCluster}))
+		}
+		// Options are only sent when enabled, so existing stacks see no diff.
+		if utils.NodePoolConfigBool(ctx, utils.NodePoolAutoscale) {
+			args.Autoscale = pulumi.Bool(true)
+		}
+		if utils.NodePoolConfigBool(ctx, utils.NodePoolMonthlyBilled) {
+			args.MonthlyBilled = pulumi.Bool(true)
+		}
+		if utils.NodePoolConfigBool(ctx, utils.NodePoolAntiAffinity) {
+			args.AntiAffinity = pulumi.Bool(true)
+		}
+		if len(labels) > 0 || len(taints) > 0 {
+			args.Template = nodePoolTemplate(labels, taints)
+		}
+		if len(zones) > 0 {
+			args.AvailabilityZones = pulumi.StringArray{pulumi.String(zones[i])}
+		}
+		nodePool, err := cloudproject.NewKubeNodePool(ctx, fmt.Sprintf("nodePool%d", i+1), args, pulumi.DependsOn([]pulumi.Resource{kubeCluster}))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create node pool %d: %w", i+1, err)
 		}
@@ -155,6 +395,10 @@ func InitNodePools(ctx *pulumi.Context, serviceName string, kubeCluster *cloudpr
 		nodePools = append(nodePools, nodePool)
 		ctx.Export(fmt.Sprintf("nodePool%dId", i+1), nodePool.ID())
 		ctx.Export(fmt.Sprintf("nodePool%dName", i+1), nodePool.Name)
+		ctx.Export(fmt.Sprintf("nodePool%dStatus", i+1), nodePool.Status)
+		ctx.Export(fmt.Sprintf("nodePool%dCurrentNodes", i+1), nodePool.CurrentNodes)
+		ctx.Export(fmt.Sprintf("nodePool%dAvailableNodes", i+1), nodePool.AvailableNodes)
+		ctx.Export(fmt.Sprintf("nodePool%dUpToDateNodes", i+1), nodePool.UpToDateNodes)
 	}
 
 	ctx.Export("nodePoolIds", nodePoolIds)
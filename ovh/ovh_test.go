@@ -0,0 +1,293 @@
+package ovh
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ovh/pulumi-ovh/sdk/v2/go/ovh/cloudproject"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestImportIDs_ProviderIDs(t *testing.T) {
+	tests := []struct {
+		name        string
+		ids         ImportIDs
+		wantNetwork string
+		wantSubnet  string
+		wantCluster string
+	}{
+		{
+			name: "nothing imported",
+		},
+		{
+			name:        "full stack",
+			ids:         ImportIDs{PrivateNetwork: "pn-123456_0", Subnet: "subnet-1", Cluster: "kube-1"},
+			wantNetwork: "project/pn-123456_0",
+			wantSubnet:  "project/pn-123456_0/subnet-1",
+			wantCluster: "project/kube-1",
+		},
+		{
+			name:        "network only",
+			ids:         ImportIDs{PrivateNetwork: "pn-123456_0"},
+			wantNetwork: "project/pn-123456_0",
+		},
+		{
+			name: "subnet without network",
+			ids:  ImportIDs{Subnet: "subnet-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ids.privateNetworkImportID("project"); got != tt.wantNetwork {
+				t.Errorf("privateNetworkImportID() = %q, want %q", got, tt.wantNetwork)
+			}
+			if got := tt.ids.subnetImportID("project"); got != tt.wantSubnet {
+				t.Errorf("subnetImportID() = %q, want %q", got, tt.wantSubnet)
+			}
+			if got := tt.ids.clusterImportID("project"); got != tt.wantCluster {
+				t.Errorf("clusterImportID() = %q, want %q", got, tt.wantCluster)
+			}
+		})
+	}
+}
+
+func TestImportIDs_Validate(t *testing.T) {
+	if err := (ImportIDs{}).Validate(); err != nil {
+		t.Errorf("Validate() on empty IDs = %v, want nil", err)
+	}
+	if err := (ImportIDs{PrivateNetwork: "pn-1_0", Subnet: "subnet-1"}).Validate(); err != nil {
+		t.Errorf("Validate() with network and subnet = %v, want nil", err)
+	}
+	if err := (ImportIDs{Subnet: "subnet-1"}).Validate(); err == nil {
+		t.Error("Validate() should reject a subnet without its private network")
+	}
+}
+
+func TestImportOptions(t *testing.T) {
+	if got := importOptions(""); len(got) != 0 {
+		t.Errorf("importOptions(\"\") returned %d options, want 0", len(got))
+	}
+	if got := importOptions("project/kube-1"); len(got) != 1 {
+		t.Errorf("importOptions(id) returned %d options, want 1", len(got))
+	}
+	if got := importOptions("", pulumi.Protect(false)); len(got) != 1 {
+		t.Errorf("importOptions(\"\", opt) returned %d options, want the 1 passed through", len(got))
+	}
+}
+
+// subnetMocks creates every resource with its inputs as outputs.
+type subnetMocks struct{}
+
+func (subnetMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	return args.Name + "-id", args.Inputs, nil
+}
+
+func (subnetMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+// TestInitSubnet_ProviderError makes the subnet constructors fail on the spot,
+// as the SDK does for a resource it refuses to register: InitSubnet must return
+// that error rather than go on with a nil subnet.
+func TestInitSubnet_ProviderError(t *testing.T) {
+	refused := errors.New("subnet quota exceeded")
+	origV1, origV2 := newNetworkPrivateSubnet, newNetworkPrivateSubnetV2
+	t.Cleanup(func() { newNetworkPrivateSubnet, newNetworkPrivateSubnetV2 = origV1, origV2 })
+	newNetworkPrivateSubnet = func(*pulumi.Context, string, *cloudproject.NetworkPrivateSubnetArgs, ...pulumi.ResourceOption) (*cloudproject.NetworkPrivateSubnet, error) {
+		return nil, refused
+	}
+	newNetworkPrivateSubnetV2 = func(*pulumi.Context, string, *cloudproject.NetworkPrivateSubnetV2Args, ...pulumi.ResourceOption) (*cloudproject.NetworkPrivateSubnetV2, error) {
+		return nil, refused
+	}
+
+	tests := []struct {
+		name      string
+		gatewayIP string
+	}{
+		{name: "subnet", gatewayIP: ""},
+		{name: "subnet v2", gatewayIP: "10.0.0.254"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := map[string]string{
+				"network:region":         "GRA9",
+				"network:networkMask":    "10.0.0.0/24",
+				"network:networkStartIp": "10.0.0.10",
+				"network:networkEndIp":   "10.0.0.200",
+			}
+			if tt.gatewayIP != "" {
+				cfg["network:networkGatewayIp"] = tt.gatewayIP
+			}
+			var subnetErr error
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				network, err := cloudproject.NewNetworkPrivate(ctx, "privateNetwork-v2", &cloudproject.NetworkPrivateArgs{
+					ServiceName: pulumi.String("project"),
+					VlanId:      pulumi.Int(10),
+				})
+				if err != nil {
+					return err
+				}
+				_, subnetErr = InitSubnet(ctx, "project", network)
+				return nil
+			}, pulumi.WithMocks("easylab", "lab", subnetMocks{}), func(info *pulumi.RunInfo) { info.Config = cfg })
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if !errors.Is(subnetErr, refused) || !strings.Contains(subnetErr.Error(), "failed to create subnet") {
+				t.Fatalf("want the wrapped constructor error, got %v", subnetErr)
+			}
+		})
+	}
+}
+
+// nodePoolMocks records the availability zones of each node pool created.
+type nodePoolMocks struct {
+	mu    sync.Mutex
+	zones map[string][]string
+}
+
+func (m *nodePoolMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	if strings.Contains(args.TypeToken, "KubeNodePool") {
+		var zones []string
+		if v, ok := args.Inputs["availabilityZones"]; ok {
+			for _, z := range v.ArrayValue() {
+				zones = append(zones, z.StringValue())
+			}
+		}
+		m.mu.Lock()
+		m.zones[args.Name] = zones
+		m.mu.Unlock()
+	}
+	return args.Name + "-id", args.Inputs, nil
+}
+
+func (m *nodePoolMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+func TestInitNodePools_Zones(t *testing.T) {
+	tests := []struct {
+		name  string
+		zones string
+		want  map[string][]string
+	}{
+		{name: "single zone region", zones: "", want: map[string][]string{"nodePool1": nil}},
+		{
+			name:  "one pool per zone",
+			zones: `["eu-west-par-a","eu-west-par-b","eu-west-par-c"]`,
+			want: map[string][]string{
+				"nodePool1": {"eu-west-par-a"},
+				"nodePool2": {"eu-west-par-b"},
+				"nodePool3": {"eu-west-par-c"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := map[string]string{
+				"nodepool:name":             "pool",
+				"nodepool:flavor":           "b3-8",
+				"nodepool:desiredNodeCount": "2",
+				"nodepool:minNodeCount":     "1",
+				"nodepool:maxNodeCount":     "3",
+			}
+			if tt.zones != "" {
+				cfg["nodepool:zones"] = tt.zones
+			}
+			mocks := &nodePoolMocks{zones: map[string][]string{}}
+			var pools int
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				kube, err := cloudproject.NewKube(ctx, "kubeCluster", &cloudproject.KubeArgs{ServiceName: pulumi.String("project"), Region: pulumi.String("EU-WEST-PAR")})
+				if err != nil {
+					return err
+				}
+				nodePools, err := InitNodePools(ctx, "project", kube)
+				pools = len(nodePools)
+				return err
+			}, pulumi.WithMocks("easylab", "lab", mocks), func(info *pulumi.RunInfo) { info.Config = cfg })
+			if err != nil {
+				t.Fatalf("InitNodePools() error = %v", err)
+			}
+			if pools != len(tt.want) {
+				t.Errorf("created %d node pools, want %d", pools, len(tt.want))
+			}
+			if !reflect.DeepEqual(mocks.zones, tt.want) {
+				t.Errorf("node pool zones = %v, want %v", mocks.zones, tt.want)
+			}
+		})
+	}
+}
+
+// kubeMocks records the inputs of the cluster created.
+type kubeMocks struct {
+	mu     sync.Mutex
+	inputs resource.PropertyMap
+}
+
+func (m *kubeMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	if strings.HasSuffix(args.TypeToken, ":Kube") {
+		m.mu.Lock()
+		m.inputs = args.Inputs
+		m.mu.Unlock()
+	}
+	return args.Name + "-id", args.Inputs, nil
+}
+
+func (m *kubeMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+func TestKubeArgs_AdvancedOptions(t *testing.T) {
+	advanced := []string{"kubeProxyMode", "customizationKubeProxy", "privateNetworkConfiguration", "plan"}
+	tests := []struct {
+		name string
+		cfg  map[string]string
+		want map[string]interface{}
+	}{
+		{name: "defaults set nothing", cfg: map[string]string{}, want: map[string]interface{}{}},
+		{
+			name: "every option",
+			cfg: map[string]string{
+				"k8s:kubeProxyMode":                  "ipvs",
+				"k8s:ipvsScheduler":                  "lc",
+				"k8s:privateNetworkRoutingAsDefault": "true",
+				"k8s:plan":                           "standard",
+				"network:networkGatewayIp":           "10.0.0.1",
+			},
+			want: map[string]interface{}{
+				"kubeProxyMode":               "ipvs",
+				"customizationKubeProxy":      map[string]interface{}{"ipvs": map[string]interface{}{"scheduler": "lc"}},
+				"privateNetworkConfiguration": map[string]interface{}{"defaultVrackGateway": "10.0.0.1", "privateNetworkRoutingAsDefault": true},
+				"plan":                        "standard",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg["k8s:clusterName"] = "cluster"
+			tt.cfg["network:region"] = "GRA11"
+			mocks := &kubeMocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := cloudproject.NewKube(ctx, "kubeCluster", kubeArgs(ctx, "project", pulumi.String("network-id")))
+				return err
+			}, pulumi.WithMocks("easylab", "lab", mocks), func(info *pulumi.RunInfo) { info.Config = tt.cfg })
+			if err != nil {
+				t.Fatalf("NewKube() error = %v", err)
+			}
+			got := map[string]interface{}{}
+			for _, key := range advanced {
+				if v, ok := mocks.inputs[resource.PropertyKey(key)]; ok {
+					got[key] = v.Mappable()
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cluster options = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
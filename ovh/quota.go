@@ -0,0 +1,87 @@
+package ovh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RegionQuota is the part of the /cloud/project/{serviceName}/quota answer the
+// pre-flight check reads: the limits and usage of one region. A section OVH
+// leaves out is nil, and is not checked.
+type RegionQuota struct {
+	Region   string `json:"region"`
+	Instance *struct {
+		MaxCores      int `json:"maxCores"`
+		UsedCores     int `json:"usedCores"`
+		MaxInstances  int `json:"maxInstances"`
+		UsedInstances int `json:"usedInstances"`
+		MaxRAM        int `json:"maxRam"` // MB
+		UsedRAM       int `json:"usedRAM"`
+	} `json:"instance"`
+	Network *struct {
+		MaxGateways  int `json:"maxGateways"`
+		UsedGateways int `json:"usedGateways"`
+	} `json:"network"`
+	LoadBalancer *struct {
+		MaxLoadbalancers  int `json:"maxLoadbalancers"`
+		UsedLoadbalancers int `json:"usedLoadbalancers"`
+	} `json:"loadbalancer"`
+}
+
+// FetchRegionQuota asks the OVH API for the quota of a region of the project.
+func FetchRegionQuota(api APIGetter, serviceName, region string) (*RegionQuota, error) {
+	var quotas []RegionQuota
+	endpoint := fmt.Sprintf("/cloud/project/%s/quota", serviceName)
+	if err := api.Get(endpoint, &quotas); err != nil {
+		return nil, fmt.Errorf("failed to fetch the quota of project %s: %w", serviceName, err)
+	}
+	for i := range quotas {
+		if strings.EqualFold(quotas[i].Region, region) {
+			return &quotas[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no quota for region %s in project %s", region, serviceName)
+}
+
+// QuotaNeeds is what a lab takes from the project's quota.
+type QuotaNeeds struct {
+	Instances     int `json:"instances"`
+	Cores         int `json:"cores"`
+	RAMMB         int `json:"ram_mb"`
+	Gateways      int `json:"gateways"`
+	LoadBalancers int `json:"load_balancers"`
+}
+
+// QuotaShortfall is one quota too small for a lab.
+type QuotaShortfall struct {
+	Quota     string `json:"quota"`
+	Needed    int    `json:"needed"`
+	Available int    `json:"available"`
+}
+
+func (s QuotaShortfall) String() string {
+	return fmt.Sprintf("%s: needs %d, %d available", s.Quota, s.Needed, s.Available)
+}
+
+// Shortfalls lists each quota of the region the needs do not fit in, in a
+// fixed order. None means the lab fits.
+func (q *RegionQuota) Shortfalls(needs QuotaNeeds) []QuotaShortfall {
+	var short []QuotaShortfall
+	check := func(quota string, needed, limit, used int) {
+		if available := limit - used; needed > available {
+			short = append(short, QuotaShortfall{Quota: quota, Needed: needed, Available: max(available, 0)})
+		}
+	}
+	if i := q.Instance; i != nil {
+		check("instances", needs.Instances, i.MaxInstances, i.UsedInstances)
+		check("vCPU cores", needs.Cores, i.MaxCores, i.UsedCores)
+		check("RAM (MB)", needs.RAMMB, i.MaxRAM, i.UsedRAM)
+	}
+	if n := q.Network; n != nil {
+		check("gateways", needs.Gateways, n.MaxGateways, n.UsedGateways)
+	}
+	if lb := q.LoadBalancer; lb != nil {
+		check("load balancers", needs.LoadBalancers, lb.MaxLoadbalancers, lb.UsedLoadbalancers)
+	}
+	return short
+}
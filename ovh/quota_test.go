@@ -0,0 +1,71 @@
+package ovh
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const quotaBody = `[
+	{"region": "BHS5", "instance": {"maxCores": 10, "usedCores": 0, "maxInstances": 10, "usedInstances": 0, "maxRam": 10240, "usedRAM": 0}},
+	{"region": "GRA11",
+		"instance": {"maxCores": 20, "usedCores": 16, "maxInstances": 20, "usedInstances": 4, "maxRam": 81920, "usedRAM": 65536},
+		"network": {"maxNetworks": 100, "usedNetworks": 3, "maxGateways": 2, "usedGateways": 2},
+		"loadbalancer": {"maxLoadbalancers": 10, "usedLoadbalancers": 1}}
+]`
+
+func TestFetchRegionQuota(t *testing.T) {
+	api := &fakeAPI{body: quotaBody}
+	quota, err := FetchRegionQuota(api, "project-1", "gra11")
+	if err != nil {
+		t.Fatalf("FetchRegionQuota() error = %v", err)
+	}
+	if want := "/cloud/project/project-1/quota"; api.url != want {
+		t.Errorf("requested %q, want %q", api.url, want)
+	}
+	if quota.Region != "GRA11" || quota.Instance.MaxCores != 20 || quota.Network.UsedGateways != 2 {
+		t.Errorf("FetchRegionQuota() = %+v, want the GRA11 quota", quota)
+	}
+}
+
+func TestFetchRegionQuota_Errors(t *testing.T) {
+	if _, err := FetchRegionQuota(&fakeAPI{err: errors.New("boom")}, "project-1", "GRA11"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("FetchRegionQuota() error = %v, want the API error", err)
+	}
+	if _, err := FetchRegionQuota(&fakeAPI{body: quotaBody}, "project-1", "SBG5"); err == nil || !strings.Contains(err.Error(), "no quota for region SBG5") {
+		t.Errorf("FetchRegionQuota() error = %v, want the missing region", err)
+	}
+}
+
+func TestRegionQuota_Shortfalls(t *testing.T) {
+	quota, err := FetchRegionQuota(&fakeAPI{body: quotaBody}, "project-1", "GRA11")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fits := QuotaNeeds{Instances: 1, Cores: 4, RAMMB: 8192, LoadBalancers: 1}
+	if got := quota.Shortfalls(fits); len(got) != 0 {
+		t.Errorf("Shortfalls(%+v) = %v, want none", fits, got)
+	}
+
+	needs := QuotaNeeds{Instances: 3, Cores: 12, RAMMB: 24576, Gateways: 1, LoadBalancers: 1}
+	want := []QuotaShortfall{
+		{Quota: "vCPU cores", Needed: 12, Available: 4},
+		{Quota: "RAM (MB)", Needed: 24576, Available: 16384},
+		{Quota: "gateways", Needed: 1, Available: 0},
+	}
+	if got := quota.Shortfalls(needs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Shortfalls(%+v) = %v, want %v", needs, got, want)
+	}
+}
+
+func TestRegionQuota_ShortfallsSkipsMissingSections(t *testing.T) {
+	quota, err := FetchRegionQuota(&fakeAPI{body: quotaBody}, "project-1", "BHS5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := quota.Shortfalls(QuotaNeeds{Instances: 1, Gateways: 5, LoadBalancers: 5}); len(got) != 0 {
+		t.Errorf("Shortfalls() = %v, want none: BHS5 reports no network or load balancer quota", got)
+	}
+}
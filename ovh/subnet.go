@@ -0,0 +1,120 @@
+package ovh
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// VLAN IDs OVH accepts for a private network.
+const (
+	MinVlanID = 1
+	MaxVlanID = 4000
+)
+
+// SubnetError reports a private network field OVH would reject. Field is the
+// wizard's form field, so the server can tell the admin which one to fix.
+type SubnetError struct {
+	Field string
+	Label string
+	Msg   string
+}
+
+func (e *SubnetError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Label, e.Msg)
+}
+
+// ValidateSubnet checks the private network CIDR and the DHCP range handed to
+// OVH. A range outside the network or running backwards otherwise only fails
+// deep inside the provider, minutes into pulumi up. The server runs it before
+// creating the job and the Pulumi program again, for API clients.
+func ValidateSubnet(cidr, start, end string) error {
+	cidrErr := func(msg string, args ...any) error {
+		return &SubnetError{Field: "network_mask", Label: "Network mask", Msg: fmt.Sprintf(msg, args...)}
+	}
+	network, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil || !network.Addr().Is4() {
+		return cidrErr("%q is not an IPv4 CIDR such as 10.0.0.0/24", cidr)
+	}
+	if network.Bits() > 30 {
+		return cidrErr("%s leaves no room for a DHCP range, use /30 or larger", network)
+	}
+	if network.Masked() != network {
+		return cidrErr("%s is not a network address, use %s", network, network.Masked())
+	}
+
+	startIP, err := rangeAddr("network_start_ip", "Start IP", start, network)
+	if err != nil {
+		return err
+	}
+	endIP, err := rangeAddr("network_end_ip", "End IP", end, network)
+	if err != nil {
+		return err
+	}
+	if !startIP.Less(endIP) {
+		return &SubnetError{Field: "network_end_ip", Label: "End IP", Msg: fmt.Sprintf("%s must be above the start IP %s", endIP, startIP)}
+	}
+	return nil
+}
+
+// rangeAddr parses one end of the DHCP range and checks it is a host address
+// of the network: neither the network address nor the broadcast address.
+func rangeAddr(field, label, value string, network netip.Prefix) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil || !addr.Is4() {
+		return netip.Addr{}, &SubnetError{Field: field, Label: label, Msg: fmt.Sprintf("%q is not an IPv4 address", value)}
+	}
+	if !network.Contains(addr) {
+		return netip.Addr{}, &SubnetError{Field: field, Label: label, Msg: fmt.Sprintf("%s is outside the network %s", addr, network)}
+	}
+	if addr == network.Addr() || addr == broadcastAddr(network) {
+		return netip.Addr{}, &SubnetError{Field: field, Label: label, Msg: fmt.Sprintf("%s is the network or broadcast address of %s", addr, network)}
+	}
+	return addr, nil
+}
+
+// ValidateGatewayIP checks a custom gateway IP for the subnet: a host address
+// of the network, outside the DHCP range that OpenStack allocates from. The
+// CIDR and range are expected to have passed ValidateSubnet.
+func ValidateGatewayIP(cidr, start, end, gatewayIP string) error {
+	if strings.TrimSpace(gatewayIP) == "" {
+		return nil
+	}
+	network, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return err
+	}
+	gateway, err := rangeAddr("network_gateway_ip", "Gateway IP", gatewayIP, network)
+	if err != nil {
+		return err
+	}
+	startIP, errStart := netip.ParseAddr(strings.TrimSpace(start))
+	endIP, errEnd := netip.ParseAddr(strings.TrimSpace(end))
+	if errStart == nil && errEnd == nil && !gateway.Less(startIP) && !endIP.Less(gateway) {
+		return &SubnetError{Field: "network_gateway_ip", Label: "Gateway IP", Msg: fmt.Sprintf("%s is inside the DHCP range %s-%s", gateway, startIP, endIP)}
+	}
+	return nil
+}
+
+// broadcastAddr is the last address of an IPv4 network.
+func broadcastAddr(network netip.Prefix) netip.Addr {
+	a := network.Addr().As4()
+	hostBits := 32 - network.Bits()
+	v := uint32(a[0])<<24 | uint32(a[1])<<16 | uint32(a[2])<<8 | uint32(a[3])
+	v |= 1<<hostBits - 1
+	return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+}
+
+// ParseVlanID parses the private network's VLAN ID, which OVH takes in
+// [MinVlanID, MaxVlanID].
+func ParseVlanID(value string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, &SubnetError{Field: "network_id", Label: "Network ID", Msg: fmt.Sprintf("%q is not a VLAN ID, use a number from %d to %d", value, MinVlanID, MaxVlanID)}
+	}
+	if id < MinVlanID || id > MaxVlanID {
+		return 0, &SubnetError{Field: "network_id", Label: "Network ID", Msg: fmt.Sprintf("VLAN ID %d is out of range, use a number from %d to %d", id, MinVlanID, MaxVlanID)}
+	}
+	return id, nil
+}
@@ -0,0 +1,120 @@
+package ovh
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateSubnet(t *testing.T) {
+	tests := []struct {
+		name      string
+		cidr      string
+		start     string
+		end       string
+		wantField string
+	}{
+		{name: "wizard defaults", cidr: "10.0.0.0/24", start: "10.0.0.100", end: "10.0.0.254"},
+		{name: "smallest network", cidr: "192.168.1.0/30", start: "192.168.1.1", end: "192.168.1.2"},
+		{name: "range across octets", cidr: "172.16.0.0/16", start: "172.16.0.10", end: "172.16.255.200"},
+		{name: "surrounding spaces", cidr: " 10.0.0.0/24 ", start: " 10.0.0.10", end: "10.0.0.20 "},
+		{name: "empty CIDR", cidr: "", start: "10.0.0.100", end: "10.0.0.254", wantField: "network_mask"},
+		{name: "bare address", cidr: "10.0.0.0", start: "10.0.0.100", end: "10.0.0.254", wantField: "network_mask"},
+		{name: "IPv6 CIDR", cidr: "fd00::/64", start: "fd00::10", end: "fd00::20", wantField: "network_mask"},
+		{name: "prefix too long", cidr: "10.0.0.0/31", start: "10.0.0.0", end: "10.0.0.1", wantField: "network_mask"},
+		{name: "host bits set", cidr: "10.0.0.5/24", start: "10.0.0.100", end: "10.0.0.254", wantField: "network_mask"},
+		{name: "invalid start", cidr: "10.0.0.0/24", start: "10.0.0.300", end: "10.0.0.254", wantField: "network_start_ip"},
+		{name: "start outside the network", cidr: "10.0.0.0/24", start: "10.0.1.100", end: "10.0.0.254", wantField: "network_start_ip"},
+		{name: "start is the network address", cidr: "10.0.0.0/24", start: "10.0.0.0", end: "10.0.0.254", wantField: "network_start_ip"},
+		{name: "empty end", cidr: "10.0.0.0/24", start: "10.0.0.100", end: "", wantField: "network_end_ip"},
+		{name: "end is the broadcast address", cidr: "10.0.0.0/24", start: "10.0.0.100", end: "10.0.0.255", wantField: "network_end_ip"},
+		{name: "end outside the network", cidr: "10.0.0.0/24", start: "10.0.0.100", end: "10.0.1.10", wantField: "network_end_ip"},
+		{name: "range runs backwards", cidr: "10.0.0.0/24", start: "10.0.0.200", end: "10.0.0.100", wantField: "network_end_ip"},
+		{name: "single address range", cidr: "10.0.0.0/24", start: "10.0.0.100", end: "10.0.0.100", wantField: "network_end_ip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSubnet(tt.cidr, tt.start, tt.end)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("ValidateSubnet(%q, %q, %q) = %v, want nil", tt.cidr, tt.start, tt.end, err)
+				}
+				return
+			}
+			var subnetErr *SubnetError
+			if !errors.As(err, &subnetErr) {
+				t.Fatalf("ValidateSubnet(%q, %q, %q) = %v, want a *SubnetError", tt.cidr, tt.start, tt.end, err)
+			}
+			if subnetErr.Field != tt.wantField {
+				t.Errorf("error on field %q, want %q (%v)", subnetErr.Field, tt.wantField, err)
+			}
+		})
+	}
+}
+
+func TestParseVlanID(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "1", want: 1},
+		{value: "4000", want: 4000},
+		{value: " 42 ", want: 42},
+		{value: "", wantErr: true},
+		{value: "vlan-5", wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "4001", wantErr: true},
+		{value: "-3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseVlanID(tt.value)
+			if tt.wantErr {
+				var subnetErr *SubnetError
+				if !errors.As(err, &subnetErr) || subnetErr.Field != "network_id" {
+					t.Fatalf("ParseVlanID(%q) error = %v, want a network_id *SubnetError", tt.value, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseVlanID(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGatewayIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		gateway string
+		wantErr bool
+	}{
+		{name: "unset", gateway: ""},
+		{name: "first address", gateway: "10.0.0.1"},
+		{name: "just below the range", gateway: "10.0.0.99"},
+		{name: "start of the range", gateway: "10.0.0.100", wantErr: true},
+		{name: "inside the range", gateway: "10.0.0.200", wantErr: true},
+		{name: "end of the range", gateway: "10.0.0.254", wantErr: true},
+		{name: "network address", gateway: "10.0.0.0", wantErr: true},
+		{name: "outside the network", gateway: "10.0.1.1", wantErr: true},
+		{name: "not an address", gateway: "gateway", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGatewayIP("10.0.0.0/24", "10.0.0.100", "10.0.0.254", tt.gateway)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("ValidateGatewayIP(%q) = %v, want nil", tt.gateway, err)
+				}
+				return
+			}
+			var subnetErr *SubnetError
+			if !errors.As(err, &subnetErr) || subnetErr.Field != "network_gateway_ip" {
+				t.Fatalf("ValidateGatewayIP(%q) = %v, want a network_gateway_ip *SubnetError", tt.gateway, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package ovh
+
+import (
+	"fmt"
+	"slices"
+)
+
+// regionDetails is the part of the /cloud/project/{serviceName}/region/{region}
+// answer the zone lookup reads. Multi-zone regions (type "region-3-az") list
+// their zones, e.g. "eu-west-par-a"; the others list none.
+type regionDetails struct {
+	Type              string   `json:"type"`
+	AvailabilityZones []string `json:"availabilityZones"`
+}
+
+// FetchAvailabilityZones asks the OVH API for the availability zones of a
+// region. It returns none for single-zone regions.
+func FetchAvailabilityZones(api APIGetter, serviceName, region string) ([]string, error) {
+	var details regionDetails
+	endpoint := fmt.Sprintf("/cloud/project/%s/region/%s", serviceName, region)
+	if err := api.Get(endpoint, &details); err != nil {
+		return nil, fmt.Errorf("failed to fetch the availability zones of region %s: %w", region, err)
+	}
+	return details.AvailabilityZones, nil
+}
+
+// ValidateAvailabilityZones rejects zones the region does not have, and a zone
+// listed twice: OVH takes a single zone per node pool, so each zone gets its
+// own pool.
+func ValidateAvailabilityZones(zones []string, region string, available []string) error {
+	for i, zone := range zones {
+		if !slices.Contains(available, zone) {
+			return fmt.Errorf("availability zone %q is not in region %s (zones: %v)", zone, region, available)
+		}
+		if slices.Contains(zones[:i], zone) {
+			return fmt.Errorf("availability zone %q is listed twice", zone)
+		}
+	}
+	return nil
+}
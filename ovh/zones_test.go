@@ -0,0 +1,64 @@
+package ovh
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFetchAvailabilityZones(t *testing.T) {
+	api := &fakeAPI{body: `{"name": "EU-WEST-PAR", "type": "region-3-az", "availabilityZones": ["eu-west-par-a", "eu-west-par-b", "eu-west-par-c"]}`}
+	zones, err := FetchAvailabilityZones(api, "project-1", "EU-WEST-PAR")
+	if err != nil {
+		t.Fatalf("FetchAvailabilityZones() error = %v", err)
+	}
+	if want := "/cloud/project/project-1/region/EU-WEST-PAR"; api.url != want {
+		t.Errorf("requested %q, want %q", api.url, want)
+	}
+	if want := []string{"eu-west-par-a", "eu-west-par-b", "eu-west-par-c"}; !reflect.DeepEqual(zones, want) {
+		t.Errorf("zones = %v, want %v", zones, want)
+	}
+}
+
+func TestFetchAvailabilityZones_SingleZoneRegion(t *testing.T) {
+	zones, err := FetchAvailabilityZones(&fakeAPI{body: `{"name": "GRA11", "type": "region"}`}, "project-1", "GRA11")
+	if err != nil || len(zones) != 0 {
+		t.Errorf("FetchAvailabilityZones() = %v, %v, want no zones", zones, err)
+	}
+}
+
+func TestFetchAvailabilityZones_Error(t *testing.T) {
+	_, err := FetchAvailabilityZones(&fakeAPI{err: errors.New("boom")}, "project-1", "GRA11")
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("FetchAvailabilityZones() error = %v, want the API error", err)
+	}
+}
+
+func TestValidateAvailabilityZones(t *testing.T) {
+	available := []string{"eu-west-par-a", "eu-west-par-b", "eu-west-par-c"}
+	tests := []struct {
+		name    string
+		zones   []string
+		wantErr string
+	}{
+		{"all zones", available, ""},
+		{"two zones", []string{"eu-west-par-c", "eu-west-par-a"}, ""},
+		{"unknown zone", []string{"eu-west-par-a", "eu-west-gra-a"}, `"eu-west-gra-a" is not in region EU-WEST-PAR`},
+		{"duplicate zone", []string{"eu-west-par-a", "eu-west-par-a"}, "listed twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAvailabilityZones(tt.zones, "EU-WEST-PAR", available)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateAvailabilityZones() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateAvailabilityZones() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
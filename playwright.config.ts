@@ -41,7 +41,7 @@ export default defineConfig({
 
   /* Run your local dev server before starting the tests */
   webServer: {
-    command: 'rm -rf test-results/ && go build -o test-results/build/easylab-server cmd/server/main.go && ./test-results/build/easylab-server -env-file=env.test.sh',
+    command: 'rm -rf test-results/ && go build -o test-results/build/easylab-server ./cmd/server && ./test-results/build/easylab-server -env-file=env.test.sh -lenient-pulumi-check',
     url: 'http://localhost:8080/health',
     reuseExistingServer: !process.env.CI,
     timeout: 120000, // 2 minutes for Go build + server start
@@ -66,6 +66,14 @@ const OvhNetworkMask = "networkMask"
 const OvhNetworkStartIP = "networkStartIp"
 const OvhNetworkEndIP = "networkEndIp"
 
+// Optional subnet settings; unset keeps OVH's defaults.
+const OvhNetworkDHCP = "networkDhcp"
+const OvhNetworkGatewayIP = "networkGatewayIp"
+
+// OVH IDs of existing resources to adopt instead of creating (see ovh.ImportIDs)
+const OvhImportPrivateNetworkId = "importPrivateNetworkId"
+const OvhImportSubnetId = "importSubnetId"
+
 func OvhConfig(ctx *pulumi.Context, key string) string {
 	return getConfig(ctx, OvhGroup, key)
 }
@@ -83,6 +91,34 @@ const NodePoolDesiredNodeCount = "desiredNodeCount"
 const NodePoolMinNodeCount = "minNodeCount"
 const NodePoolMaxNodeCount = "maxNodeCount"
 
+// Optional OVH node pool options, all off unless set to true
+const NodePoolAutoscale = "autoscale"
+const NodePoolMonthlyBilled = "monthlyBilled"
+const NodePoolAntiAffinity = "antiAffinity"
+
+// NodePoolReadyTimeout is how long, in minutes, the nodes get to become Ready
+// before the deployment fails.
+const NodePoolReadyTimeout = "readyTimeout"
+
+// NodePoolGPU is set by the server when the flavor has GPUs, so the program
+// installs the NVIDIA device plugin
+const NodePoolGPU = "gpu"
+
+// Optional node labels and taints (JSON) that pin workloads to the lab's node pool
+const NodePoolLabels = "labels"
+const NodePoolTaints = "taints"
+
+// NodePoolZones optionally lists (JSON) the availability zones of a multi-zone
+// region to spread the lab over: one node pool is created in each.
+const NodePoolZones = "zones"
+
+// NodeTaint is a Kubernetes taint as passed in nodepool:taints.
+type NodeTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
 func NodePoolConfig(ctx *pulumi.Context, key string) string {
 	return getConfig(ctx, NodePoolGroup, key)
 }
@@ -91,11 +127,62 @@ func NodePoolConfigInt(ctx *pulumi.Context, key string) int {
 	return config.RequireInt(ctx, fmt.Sprintf("%s:%s", NodePoolGroup, key))
 }
 
+// NodePoolReadyTimeoutOrDefault reads nodepool:readyTimeout, def when unset.
+func NodePoolReadyTimeoutOrDefault(ctx *pulumi.Context, def time.Duration) time.Duration {
+	if minutes := config.New(ctx, NodePoolGroup).GetInt(NodePoolReadyTimeout); minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return def
+}
+
+// NodePoolConfigBool returns an optional boolean config value (false if not set)
+func NodePoolConfigBool(ctx *pulumi.Context, key string) bool {
+	return config.New(ctx, NodePoolGroup).GetBool(key)
+}
+
+// NodePoolScheduling returns the optional node labels and taints of the lab's
+// node pool (both empty if not set).
+func NodePoolScheduling(ctx *pulumi.Context) (map[string]string, []NodeTaint, error) {
+	var labels map[string]string
+	var taints []NodeTaint
+	cfg := config.New(ctx, NodePoolGroup)
+	if err := cfg.GetObject(NodePoolLabels, &labels); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s:%s: %w", NodePoolGroup, NodePoolLabels, err)
+	}
+	if err := cfg.GetObject(NodePoolTaints, &taints); err != nil {
+		return nil, nil, fmt.Errorf("invalid %s:%s: %w", NodePoolGroup, NodePoolTaints, err)
+	}
+	return labels, taints, nil
+}
+
+// NodePoolAvailabilityZones reads nodepool:zones. None means a single node pool
+// that OVH places itself.
+func NodePoolAvailabilityZones(ctx *pulumi.Context) ([]string, error) {
+	var zones []string
+	if err := config.New(ctx, NodePoolGroup).GetObject(NodePoolZones, &zones); err != nil {
+		return nil, fmt.Errorf("invalid %s:%s: %w", NodePoolGroup, NodePoolZones, err)
+	}
+	return zones, nil
+}
+
 // K8s config group
 const K8sGroup = "k8s"
 const K8sClusterName = "clusterName"
 const K8sUseExistingCluster = "useExistingCluster"
 const K8sExternalKubeconfigPath = "externalKubeconfigPath"
+const K8sImportClusterId = "importClusterId"
+const K8sVersion = "version"
+
+// Advanced cluster options. Unset keeps OVH's defaults (iptables, public
+// routing, free plan).
+const K8sKubeProxyMode = "kubeProxyMode"                                   // "iptables" or "ipvs"
+const K8sIPVSScheduler = "ipvsScheduler"                                   // kube-proxy IPVS scheduler, e.g. "rr"
+const K8sPrivateNetworkRoutingAsDefault = "privateNetworkRoutingAsDefault" // route node egress through the private network
+const K8sPlan = "plan"                                                     // "free" or "standard"
+
+// K8sStorageClass is the StorageClass of the lab's persistent volumes. Unset
+// uses the cluster's default class.
+const K8sStorageClass = "storageClass"
 
 func K8sConfig(ctx *pulumi.Context, key string) string {
 	return getConfig(ctx, K8sGroup, key)
@@ -105,6 +192,11 @@ func K8sConfigOptional(ctx *pulumi.Context, key string) string {
 	return config.New(ctx, K8sGroup).Get(key)
 }
 
+// K8sConfigBool returns an optional boolean config value (false if not set)
+func K8sConfigBool(ctx *pulumi.Context, key string) bool {
+	return config.New(ctx, K8sGroup).GetBool(key)
+}
+
 // Coder config group
 const CoderGroup = "coder"
 const CoderAdminEmail = "adminEmail"
@@ -128,6 +220,10 @@ const CoderInstallCertManager = "installCertManager"
 const CoderNginxIngressNamespace = "nginxIngressNamespace"
 const CoderNginxIngressServiceName = "nginxIngressServiceName"
 const CoderCertManagerNamespace = "certManagerNamespace"
+const CoderLoadBalancerAnnotations = "loadBalancerAnnotations" // JSON object, set on the ingress-nginx controller service
+const CoderLoadBalancerIP = "loadBalancerIP"                   // reserved IP for the ingress-nginx controller service
+const CoderIngressIPTimeout = "ingressIPTimeout"               // minutes to wait for the controller's LoadBalancer IP
+const CoderNodePortFallback = "nodePortFallback"               // "true": serve on a node's IP and NodePort when that wait times out
 const CoderGithubLoginEnabled = "githubLoginEnabled"
 const CoderSessionDuration = "sessionDuration"             // maps to CODER_SESSION_DURATION (e.g. "24h")
 const CoderDormancyThreshold = "dormancyThreshold"         // maps to CODER_DORMANCY_THRESHOLD (e.g. "168h")
@@ -180,11 +276,120 @@ func CoderConfigOptional(ctx *pulumi.Context, key string) string {
 	return config.New(ctx, CoderGroup).Get(key)
 }
 
+// DefaultIngressIPTimeout is how long a lab waits for the LoadBalancer IP of the
+// ingress-nginx controller when coder:ingressIPTimeout is not set.
+const DefaultIngressIPTimeout = 10 * time.Minute
+
+// IngressIPTimeoutConfig reads coder:ingressIPTimeout, in minutes, or returns
+// DefaultIngressIPTimeout when it is not set.
+func IngressIPTimeoutConfig(ctx *pulumi.Context) time.Duration {
+	if minutes := config.New(ctx, CoderGroup).GetInt(CoderIngressIPTimeout); minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultIngressIPTimeout
+}
+
+// NodePortFallbackConfig reports whether coder:nodePortFallback is set.
+func NodePortFallbackConfig(ctx *pulumi.Context) bool {
+	return config.New(ctx, CoderGroup).GetBool(CoderNodePortFallback)
+}
+
+// LoadBalancerAnnotations returns the optional annotations of the ingress-nginx
+// controller's LoadBalancer service (nil if not set).
+func LoadBalancerAnnotations(ctx *pulumi.Context) (map[string]string, error) {
+	var annotations map[string]string
+	if err := config.New(ctx, CoderGroup).GetObject(CoderLoadBalancerAnnotations, &annotations); err != nil {
+		return nil, fmt.Errorf("invalid %s:%s: %w", CoderGroup, CoderLoadBalancerAnnotations, err)
+	}
+	return annotations, nil
+}
+
 // DNSConfigOptional returns an optional dns: config value (empty string if not set)
 func DNSConfigOptional(ctx *pulumi.Context, key string) string {
 	return config.New(ctx, DNSGroup).Get(key)
 }
 
+// Optional ResourceQuota/LimitRange (JSON) of the workspace namespace
+const CoderWorkspaceQuota = "workspaceQuota"
+
+// WorkspaceQuota is the workspace namespace quota as passed in
+// coder:workspaceQuota. CPU and Memory are namespace totals; the defaults are
+// the requests and limits of containers that declare none. Values are
+// Kubernetes quantities ("2", "500m", "4Gi").
+type WorkspaceQuota struct {
+	CPU                  string `json:"cpu,omitempty"`
+	Memory               string `json:"memory,omitempty"`
+	DefaultCPURequest    string `json:"default_cpu_request,omitempty"`
+	DefaultMemoryRequest string `json:"default_memory_request,omitempty"`
+	DefaultCPULimit      string `json:"default_cpu_limit,omitempty"`
+	DefaultMemoryLimit   string `json:"default_memory_limit,omitempty"`
+}
+
+// WorkspaceQuotaConfig returns the workspace namespace quota, or nil when the
+// lab sets none.
+func WorkspaceQuotaConfig(ctx *pulumi.Context) (*WorkspaceQuota, error) {
+	var quota *WorkspaceQuota
+	if err := config.New(ctx, CoderGroup).GetObject(CoderWorkspaceQuota, &quota); err != nil {
+		return nil, fmt.Errorf("invalid %s:%s: %w", CoderGroup, CoderWorkspaceQuota, err)
+	}
+	return quota, nil
+}
+
+// Monitoring config group: the optional kube-prometheus-stack install
+const MonitoringGroup = "monitoring"
+const MonitoringEnabled = "enabled"
+const MonitoringGrafanaAdminPassword = "grafanaAdminPassword" // secret
+
+// MonitoringEnabledConfig reports whether the lab installs the monitoring stack.
+func MonitoringEnabledConfig(ctx *pulumi.Context) bool {
+	return config.New(ctx, MonitoringGroup).GetBool(MonitoringEnabled)
+}
+
+// GrafanaAdminPassword returns the Grafana admin password, kept secret in the
+// stack outputs and state.
+func GrafanaAdminPassword(ctx *pulumi.Context) pulumi.StringOutput {
+	return config.New(ctx, MonitoringGroup).RequireSecret(MonitoringGrafanaAdminPassword)
+}
+
+// Helm config group: where the charts a lab installs are pulled from
+const HelmGroup = "helm"
+const HelmRepoOverrides = "repoOverrides" // JSON object, chart name -> repository URL
+
+// HelmRepoOverridesConfig returns the chart repositories the lab overrides, by
+// chart name, e.g. to pull from an internal mirror (nil if not set).
+func HelmRepoOverridesConfig(ctx *pulumi.Context) (map[string]string, error) {
+	var overrides map[string]string
+	if err := config.New(ctx, HelmGroup).GetObject(HelmRepoOverrides, &overrides); err != nil {
+		return nil, fmt.Errorf("invalid %s:%s: %w", HelmGroup, HelmRepoOverrides, err)
+	}
+	return overrides, nil
+}
+
+const HelmChartVersions = "chartVersions" // JSON object, chart name -> chart version
+
+// HelmChartVersionsConfig returns the chart versions the lab pins, by chart
+// name, over the ones EasyLab pins (nil if not set).
+func HelmChartVersionsConfig(ctx *pulumi.Context) (map[string]string, error) {
+	var versions map[string]string
+	if err := config.New(ctx, HelmGroup).GetObject(HelmChartVersions, &versions); err != nil {
+		return nil, fmt.Errorf("invalid %s:%s: %w", HelmGroup, HelmChartVersions, err)
+	}
+	return versions, nil
+}
+
+// HelmRepoURL returns the repository to pull chartName from: the lab's override
+// if it sets one, defaultURL otherwise.
+func HelmRepoURL(ctx *pulumi.Context, chartName, defaultURL string) (string, error) {
+	overrides, err := HelmRepoOverridesConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if url, ok := overrides[chartName]; ok && url != "" {
+		return url, nil
+	}
+	return defaultURL, nil
+}
+
 const CoderTemplatesKey = "templates"
 
 // CoderTemplateConfig holds the configuration for a single Coder template.
@@ -0,0 +1,11 @@
+// Package web holds the pages and static files of the web UI. They are
+// embedded into the server binary, so it runs from any directory.
+package web
+
+import "embed"
+
+// FS holds the page templates (*.html, each rendered within base.html) and
+// static/, the files served under /static/.
+//
+//go:embed *.html static
+var FS embed.FS
@@ -98,11 +98,13 @@ const wizard = {
         const installBtn = document.getElementById('ingress-install-btn');
         const existingBtn = document.getElementById('ingress-existing-btn');
         const fields = document.getElementById('ingress-existing-fields');
+        const installFields = document.getElementById('ingress-install-fields');
         const hidden = document.getElementById('install_nginx_ingress');
 
         installBtn.classList.toggle('selected', mode === 'install');
         existingBtn.classList.toggle('selected', mode === 'existing');
         fields.style.display = mode === 'existing' ? '' : 'none';
+        if (installFields) installFields.style.display = mode === 'install' ? '' : 'none';
         hidden.value = mode === 'install' ? 'true' : 'false';
     },
 
@@ -382,6 +384,7 @@ const wizard = {
                 }
             } else {
                 loadOVHFlavors();
+                loadOVHKubeVersions();
             }
         }
     }
@@ -598,8 +601,9 @@ function loadOVHRegions() {
         .then(html => {
             regionSelect.innerHTML = html;
             wizard._ovhRegionsLoaded = true;
-            // Trigger flavor load for the initially selected region
+            // Trigger flavor and gateway model loads for the initially selected region
             loadOVHFlavors();
+            loadOVHGatewayModels();
         })
         .catch(err => {
             console.error('Error loading OVH regions:', err);
@@ -638,6 +642,7 @@ function loadOVHFlavors() {
         })
         .then(html => {
             flavorSelect.innerHTML = html;
+            prefillWorkspaceQuota();
             const hint = document.getElementById('flavor-filter-hint');
             if (hint) {
                 const opts = flavorSelect.options;
@@ -653,6 +658,53 @@ function loadOVHFlavors() {
         });
 }
 
+// Fetch the Kubernetes versions OVH offers in the selected region
+function loadOVHKubeVersions() {
+    const regionSelect = document.getElementById('network_region');
+    const versionSelect = document.getElementById('k8s_version');
+    if (!regionSelect || !versionSelect) return;
+
+    const region = regionSelect.value;
+    if (!region) {
+        versionSelect.innerHTML = '<option value="" selected>OVH default</option>';
+        return;
+    }
+
+    fetch('/api/ovh/kube-versions?region=' + encodeURIComponent(region))
+        .then(response => {
+            if (!response.ok) throw new Error('Failed to load Kubernetes versions');
+            return response.text();
+        })
+        .then(html => {
+            versionSelect.innerHTML = html;
+        })
+        .catch(err => {
+            console.error('Error loading OVH Kubernetes versions:', err);
+            versionSelect.innerHTML = '<option value="" selected>OVH default (failed to load versions)</option>';
+        });
+}
+
+// Fetch the gateway models OVH offers in the selected region; the server
+// selects the cheapest one
+function loadOVHGatewayModels() {
+    const regionSelect = document.getElementById('network_region');
+    const modelSelect = document.getElementById('network_gateway_model');
+    if (!regionSelect || !modelSelect || !regionSelect.value) return;
+
+    fetch('/api/ovh/gateway-models?region=' + encodeURIComponent(regionSelect.value))
+        .then(response => {
+            if (!response.ok) throw new Error('Failed to load gateway models');
+            return response.text();
+        })
+        .then(html => {
+            modelSelect.innerHTML = html;
+        })
+        .catch(err => {
+            // Keep the current options: the dry run still checks the model
+            console.error('Error loading OVH gateway models:', err);
+        });
+}
+
 // Toggle flavor filters section visibility (lab creation form)
 function toggleFlavorFiltersSection() {
     var body = document.getElementById('flavor-filters-body');
@@ -722,6 +774,24 @@ function handleProviderChange() {
     const azureFields = document.getElementById('azure-network-fields');
     if (ovhFields) ovhFields.style.display = isAzure ? 'none' : '';
     if (azureFields) azureFields.style.display = isAzure ? '' : 'none';
+    const ovhNodePoolOptions = document.getElementById('ovh-nodepool-options');
+    if (ovhNodePoolOptions) ovhNodePoolOptions.style.display = isAzure ? 'none' : '';
+    const ovhNodePoolScheduling = document.getElementById('ovh-nodepool-scheduling');
+    if (ovhNodePoolScheduling) ovhNodePoolScheduling.style.display = isAzure ? 'none' : '';
+    const ovhNodePoolZones = document.getElementById('ovh-nodepool-zones');
+    if (ovhNodePoolZones) ovhNodePoolZones.style.display = isAzure ? 'none' : '';
+    const ovhNodePoolReadyTimeout = document.getElementById('ovh-nodepool-ready-timeout');
+    if (ovhNodePoolReadyTimeout) ovhNodePoolReadyTimeout.style.display = isAzure ? 'none' : '';
+    const ovhK8sVersion = document.getElementById('ovh-k8s-version-group');
+    if (ovhK8sVersion) ovhK8sVersion.style.display = isAzure ? 'none' : '';
+    const ovhClusterOptions = document.getElementById('ovh-cluster-options');
+    if (ovhClusterOptions) ovhClusterOptions.style.display = isAzure ? 'none' : '';
+    if (ovhClusterOptions && isAzure) {
+        ovhClusterOptions.querySelectorAll('select').forEach(select => { select.value = ''; });
+        document.getElementById('k8s_private_network_routing').checked = false;
+    }
+    const k8sVersionSelect = document.getElementById('k8s_version');
+    if (k8sVersionSelect && isAzure) k8sVersionSelect.value = '';
 
     // Update step 3 header
     const stepTitle = document.getElementById('network-step-title');
@@ -768,6 +838,29 @@ function handleProviderChange() {
 }
 
 // Show/hide DNS provider-specific credential fields when dns_provider changes.
+// Pre-fill the workspace quota totals with 80% of the node pool, as the server
+// does when they are left empty. Needs the vCPU/RAM data attributes of the OVH
+// flavor options; Azure VM sizes carry none, so their totals stay as typed.
+function prefillWorkspaceQuota() {
+    const flavor = document.getElementById('nodepool_flavor');
+    const count = document.getElementById('nodepool_desired_node_count');
+    if (!flavor || !count || flavor.selectedIndex < 0) return;
+    const option = flavor.options[flavor.selectedIndex];
+    const vcpus = parseInt(option.dataset.vcpus, 10);
+    const ram = parseInt(option.dataset.ram, 10);
+    const nodes = parseInt(count.value, 10);
+    if (!(nodes > 0)) return;
+
+    const cpuInput = document.getElementById('quota_cpu');
+    if (cpuInput && !cpuInput.dataset.edited && vcpus > 0) {
+        cpuInput.value = Math.floor(vcpus * nodes * 1000 * 0.8) + 'm';
+    }
+    const memoryInput = document.getElementById('quota_memory');
+    if (memoryInput && !memoryInput.dataset.edited && ram > 0) {
+        memoryInput.value = Math.floor(ram * nodes * 1024 * 0.8) + 'Mi';
+    }
+}
+
 function handleDNSProviderChange() {
     const select = document.getElementById('dns_provider');
     if (!select) return;
@@ -1094,10 +1187,12 @@ document.addEventListener('DOMContentLoaded', function() {
         domainInput.addEventListener('input', updateDNSManualWarning);
     }
 
-    // Reload flavors when region selection changes (OVH)
+    // Reload flavors, Kubernetes versions and gateway models when region selection changes (OVH)
     const regionSelect = document.getElementById('network_region');
     if (regionSelect) {
         regionSelect.addEventListener('change', loadOVHFlavors);
+        regionSelect.addEventListener('change', loadOVHKubeVersions);
+        regionSelect.addEventListener('change', loadOVHGatewayModels);
     }
 
     // Reload flavors when flavor filter inputs change (provider-aware)
@@ -1121,6 +1216,25 @@ document.addEventListener('DOMContentLoaded', function() {
         flavorFilterClearBtn.addEventListener('click', clearFlavorFilters);
     }
 
+    // Workspace quota: reveal the fields, and pre-fill the totals from the node
+    // pool until the admin types their own.
+    const quotaToggle = document.getElementById('workspace_quota_enabled');
+    if (quotaToggle) {
+        quotaToggle.addEventListener('change', function () {
+            const fields = document.getElementById('workspace-quota-fields');
+            if (fields) fields.style.display = quotaToggle.checked ? '' : 'none';
+            prefillWorkspaceQuota();
+        });
+        ['quota_cpu', 'quota_memory'].forEach(function (id) {
+            const el = document.getElementById(id);
+            if (el) el.addEventListener('input', function () { el.dataset.edited = 'true'; });
+        });
+        ['nodepool_flavor', 'nodepool_desired_node_count'].forEach(function (id) {
+            const el = document.getElementById(id);
+            if (el) el.addEventListener('change', prefillWorkspaceQuota);
+        });
+    }
+
     // Set up network mask calculation
     const maskInput = document.getElementById('network_mask');
     if (maskInput) {
@@ -3,7 +3,9 @@ const providerConfig = {
     ovh: {
         name: "OVHcloud",
         enabled: true,
-        statusFields: ['service_name', 'endpoint']
+        statusFields: ['service_name', 'endpoint', 'connection', 'gateway_regions'],
+        // Check Status also tests the connection to the OVH API
+        testConnection: true
     },
     aws: {
         name: "Amazon Web Services",
@@ -64,7 +66,8 @@ function checkCredentials(provider) {
     statusContainer.style.display = 'block';
     statusContent.innerHTML = '<p>Checking...</p>';
     
-    fetch(`/api/credentials?provider=${provider}`)
+    const test = providerConfig[provider]?.testConnection ? '&test=true' : '';
+    fetch(`/api/credentials?provider=${provider}${test}`)
         .then(response => {
             if (response.ok) {
                 return response.json();
@@ -114,9 +117,25 @@ function checkCredentials(provider) {
         });
 }
 
+// enableOVHPartialUpdate relaxes the OVH form once credentials are stored: empty
+// fields keep their current value server-side, so none of them is required any
+// more, and the "Replace all" option is offered for a full rotation.
+function enableOVHPartialUpdate() {
+    ['ovh_application_key', 'ovh_application_secret', 'ovh_consumer_key', 'ovh_service_name'].forEach(id => {
+        const input = document.getElementById(id);
+        if (input) {
+            input.removeAttribute('required');
+            input.placeholder = 'Leave empty to keep the current value';
+        }
+    });
+    const replaceAll = document.querySelector('.ovh-replace-all-group');
+    if (replaceAll) replaceAll.style.display = '';
+}
+
 // Load current credentials into form if they exist
 function loadCurrentCredentials(provider) {
-    fetch(`/api/credentials?provider=${provider}`)
+    const test = providerConfig[provider]?.testConnection ? '&test=true' : '';
+    fetch(`/api/credentials?provider=${provider}${test}`)
         .then(response => {
             if (response.ok) {
                 return response.json();
@@ -137,6 +156,7 @@ function loadCurrentCredentials(provider) {
                     if (endpointSelect && data.endpoint) {
                         endpointSelect.value = data.endpoint;
                     }
+                    enableOVHPartialUpdate();
                 } else if (provider === 'azure') {
                     const tenantInput = document.getElementById('azure_tenant_id');
                     const subscriptionInput = document.getElementById('azure_subscription_id');
@@ -153,6 +173,9 @@ function loadCurrentCredentials(provider) {
 
                     let infoHTML = `<h3>📝 Update Existing Credentials</h3>
                         <p>Credentials are currently configured. Fill in the fields below to update them.</p>`;
+                    if (provider === 'ovh') {
+                        infoHTML += `<p>Leave a field empty to keep its current value.</p>`;
+                    }
 
                     if (provider === 'ovh') {
                         infoHTML += `<p><strong>Service Name:</strong> ${data.service_name || 'N/A'}</p>
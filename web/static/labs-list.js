@@ -12,8 +12,9 @@ function destroyStack(labId) {
             // Follow the redirect
             window.location.href = response.url;
         } else {
-            // Handle error
-            console.error('Destroy failed:', response.status);
+            response.text().then(text => {
+                alert('Failed to destroy lab: ' + text);
+            });
         }
     })
     .catch(error => {
@@ -143,6 +144,90 @@ function removeLab(labId) {
     });
 }
 
+// Turn a lab's deletion protection on or off. Turning it off asks first, and
+// for a reason that goes to the lab's audit log.
+function toggleProtection(labId, protect) {
+    let reason = '';
+    if (!protect) {
+        reason = prompt('Remove the protection of this lab? It can then be destroyed, including on its deletion date. Reason (optional):');
+        if (reason === null) return;
+    }
+    const body = new URLSearchParams({ protected: protect ? 'true' : 'false', reason: reason });
+    fetch('/api/labs/' + encodeURIComponent(labId) + '/protect', {
+        method: 'PATCH',
+        headers: {
+            'Content-Type': 'application/x-www-form-urlencoded',
+        },
+        body: body.toString()
+    })
+    .then(response => {
+        if (response.ok) {
+            window.location.reload();
+        } else {
+            response.text().then(text => {
+                alert('Failed to change the protection of the lab: ' + text);
+            });
+        }
+    })
+    .catch(error => {
+        alert('Error changing the protection of the lab: ' + error.message);
+    });
+}
+
+function toggleTemplate(labId, isTemplate) {
+    const question = isTemplate
+        ? 'Keep this lab as a template? It leaves the labs list and new labs can be created from its configuration.'
+        : 'Turn this template back into a plain lab?';
+    if (!confirm(question)) return;
+    const body = new URLSearchParams({ template: isTemplate ? 'true' : 'false' });
+    fetch('/api/labs/' + encodeURIComponent(labId) + '/template', {
+        method: 'PATCH',
+        headers: {
+            'Content-Type': 'application/x-www-form-urlencoded',
+        },
+        body: body.toString()
+    })
+    .then(response => {
+        if (response.ok) {
+            window.location.reload();
+        } else {
+            response.text().then(text => {
+                alert('Failed to change the template state of the lab: ' + text);
+            });
+        }
+    })
+    .catch(error => {
+        alert('Error changing the template state of the lab: ' + error.message);
+    });
+}
+
+function labFromTemplate(templateId, templateName) {
+    const stackName = prompt('Name of the new lab:', templateName);
+    if (stackName === null) return;
+    const body = new URLSearchParams({ stack_name: stackName.trim() });
+    fetch('/api/labs/from-template/' + encodeURIComponent(templateId), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+        body: body.toString()
+    })
+    .then(response => {
+        // Like recreate, success is a redirect to the new lab; anything else
+        // carries an error, as an HTML fragment or plain text.
+        if (response.redirected) {
+            window.location.href = response.url;
+            return;
+        }
+        return response.text().then(text => {
+            const box = document.createElement('div');
+            box.innerHTML = text;
+            alert('Could not create a lab from the template: ' + (box.textContent.trim() || response.statusText));
+        });
+    })
+    .catch(error => {
+        alert('Error creating a lab from the template: ' + error.message);
+    });
+}
+
 function retryLab(labId) {
     // Send POST request to retry endpoint
     fetch('/api/labs/' + encodeURIComponent(labId) + '/retry', {
@@ -47,6 +47,21 @@ function checkCredentials() {
         });
 }
 
+// enableOVHPartialUpdate relaxes the OVH form once credentials are stored: empty
+// fields keep their current value server-side, so none of them is required any
+// more, and the "Replace all" option is offered for a full rotation.
+function enableOVHPartialUpdate() {
+    ['ovh_application_key', 'ovh_application_secret', 'ovh_consumer_key', 'ovh_service_name'].forEach(id => {
+        const input = document.getElementById(id);
+        if (input) {
+            input.removeAttribute('required');
+            input.placeholder = 'Leave empty to keep the current value';
+        }
+    });
+    const replaceAll = document.querySelector('.ovh-replace-all-group');
+    if (replaceAll) replaceAll.style.display = '';
+}
+
 // Load current credentials into form if they exist
 function loadCurrentCredentials() {
     fetch('/api/ovh-credentials')
@@ -69,6 +84,7 @@ function loadCurrentCredentials() {
                 if (endpointSelect && data.endpoint) {
                     endpointSelect.value = data.endpoint;
                 }
+                enableOVHPartialUpdate();
                 
                 // Show info that credentials exist
                 const formSection = document.querySelector('.form-section');
@@ -77,7 +93,7 @@ function loadCurrentCredentials() {
                     existingInfo.className = 'info-box credentials-info--populated';
                     existingInfo.innerHTML = `
                         <h3>📝 Update Existing Credentials</h3>
-                        <p>Credentials are currently configured. Fill in the fields below to update them.</p>
+                        <p>Credentials are currently configured. Fill in the fields below to update them; leave a field empty to keep its current value.</p>
                         <p><strong>Service Name:</strong> ${data.service_name || 'N/A'}</p>
                         <p><strong>Endpoint:</strong> ${data.endpoint || 'N/A'}</p>
                     `;
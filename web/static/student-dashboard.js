@@ -130,6 +130,11 @@ function loadLabs() {
                 const option = document.createElement('option');
                 option.value = lab.id;
                 option.textContent = `${lab.config.stack_name || lab.id}`;
+                // A lab whose cluster does not answer yet cannot take a workspace.
+                if (lab.status === 'starting') {
+                    option.disabled = true;
+                    option.textContent += ' (starting…)';
+                }
                 select.appendChild(option);
             });
         })
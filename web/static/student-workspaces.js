@@ -14,10 +14,41 @@ function fetchLabNames() {
             (labs || []).forEach(lab => {
                 _labNames[lab.id] = (lab.config && lab.config.stack_name) || lab.id;
             });
+            fillResendLabSelect(labs || []);
         })
         .catch(() => {});
 }
 
+// fillResendLabSelect lists the labs in the "Lost your workspace details?" form.
+function fillResendLabSelect(labs) {
+    const select = document.getElementById('resend_lab_id');
+    if (!select) return;
+    if (labs.length === 0) {
+        select.innerHTML = '<option value="">No environment available</option>';
+        return;
+    }
+    select.innerHTML = labs.map(lab =>
+        `<option value="${escapeHtml(lab.id)}">${escapeHtml(_labNames[lab.id] || lab.id)}</option>`
+    ).join('');
+}
+
+// resendWorkspaceInfo asks the server for the student's workspace details in the
+// selected lab. The server answers with an HTML fragment (or a rate-limit notice).
+function resendWorkspaceInfo(event) {
+    event.preventDefault();
+    const form = event.target;
+    const target = document.getElementById('workspace-resend-response');
+    fetch('/api/student/workspace/resend', {
+        method: 'POST',
+        credentials: 'same-origin',
+        headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+        body: new URLSearchParams(new FormData(form)),
+    })
+        .then(r => r.text())
+        .then(html => { target.innerHTML = html; })
+        .catch(() => { target.innerHTML = '<div class="error-message">Request failed, please try again.</div>'; });
+}
+
 document.addEventListener('DOMContentLoaded', function() {
     // Render once the lab names are in (or the lookup has failed), so cards show a
     // readable lab name from the first paint rather than flashing the job id.
@@ -163,6 +194,7 @@ function renderWorkspaceCard(info, labId) {
                     <div class="workspace-card-footer-actions">
                         ${isEncrypted ? '' : `<button onclick="encryptSingleWorkspace('${safeLab}')" class="student-btn student-btn-small" title="Encrypt password">Encrypt</button>`}
                         <button onclick="clearWorkspaceInfo('${safeLab}')" class="student-btn student-btn-danger student-btn-small" title="Remove">Clear</button>
+                        <button onclick="deleteOwnWorkspace('${safeLab}', '${escapeHtml(info.lab_id)}', '${safeName}')" class="student-btn student-btn-danger student-btn-small" title="Delete the workspace from the lab">Delete workspace</button>
                     </div>
                 </div>
             </div>
@@ -240,6 +272,55 @@ function clearWorkspaceInfo(labId) {
     }
 }
 
+// deleteOwnWorkspace deletes the workspace on the lab's cluster once the student
+// has typed its name again, then forgets its saved details.
+async function deleteOwnWorkspace(cardId, labId, workspaceName) {
+    const typed = prompt(`This deletes the workspace and everything in it. Type ${workspaceName} to confirm:`);
+    if (typed === null) return;
+    try {
+        const resp = await fetch('/api/student/workspace/delete', {
+            method: 'POST',
+            credentials: 'same-origin',
+            headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+            body: new URLSearchParams({ lab_id: labId, workspace_name: workspaceName, confirm: typed }),
+        });
+        const tmp = document.createElement('div');
+        tmp.innerHTML = await resp.text();
+        alert(tmp.textContent.trim());
+        if (resp.ok && tmp.querySelector('.success-message')) {
+            deleteCookie(`workspace_info_${cardId}`);
+            loadAllWorkspaceInfos();
+        }
+    } catch (e) {
+        console.error('Failed to delete workspace:', e);
+        alert('Request failed, please try again.');
+    }
+}
+
+// resetWorkspacePassword gives a workspace listed by "Lost your workspace
+// details?" a new password once the student has typed its name again, and shows
+// the new password under it.
+async function resetWorkspacePassword(button) {
+    const labId = button.dataset.labId;
+    const workspaceName = button.dataset.workspaceName;
+    const typed = prompt(`The old password stops working and the workspace restarts. Type ${workspaceName} to confirm:`);
+    if (typed === null) return;
+    const target = button.parentElement.querySelector('.workspace-reset-response');
+    try {
+        const resp = await fetch('/api/student/workspace/reset-password', {
+            method: 'POST',
+            credentials: 'same-origin',
+            headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+            body: new URLSearchParams({ lab_id: labId, workspace_name: workspaceName, confirm: typed }),
+        });
+        target.innerHTML = await resp.text();
+        if (resp.ok) loadAllWorkspaceInfos();
+    } catch (e) {
+        console.error('Failed to reset workspace password:', e);
+        target.innerHTML = '<div class="error-message">Request failed, please try again.</div>';
+    }
+}
+
 function clearAllWorkspaceInfos() {
     if (!confirm('Are you sure you want to clear all saved workspace information?')) return;
     const workspaces = getAllWorkspaceCookies();